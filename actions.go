@@ -0,0 +1,338 @@
+package selenium
+
+import (
+	"fmt"
+	"time"
+)
+
+// Identifiers of the input sources used by the methods of ActionChain that
+// do not take a source, and by the mouse and keyboard methods of WebDriver
+// in W3C sessions.
+const (
+	DefaultMouse    = "default mouse"
+	DefaultKeyboard = "default keyboard"
+	DefaultWheel    = "default wheel"
+)
+
+// ActionChain builds the input of the W3C Perform Actions command, which
+// WebDriver.PerformActionChain sends. The actions are grouped in ticks: the
+// remote end performs the actions of a tick together, and starts the next
+// tick when they are all done. Sources without an action in a tick pause for
+// it.
+//
+// Each method of ActionChain adds one or more ticks and returns the chain, so
+// that calls can be chained:
+//
+//	chain := selenium.NewActionChain().
+//		MoveToElement(source, 0, 0).
+//		PointerDown(selenium.LeftButton).
+//		MoveToElement(target, 0, 0).
+//		PointerUp(selenium.LeftButton)
+//	err := wd.PerformActionChain(chain)
+//
+// Actions performed together, such as the two fingers of a pinch, are added
+// with Tick:
+//
+//	f1 := chain.Pointer("finger1", selenium.TouchPointer)
+//	f2 := chain.Pointer("finger2", selenium.TouchPointer)
+//	chain.Tick(f1.Down(selenium.LeftButton), f2.Down(selenium.LeftButton))
+//
+// Errors, such as a key action on a pointer, are reported by
+// PerformActionChain.
+type ActionChain struct {
+	sources []*InputSource
+	ticks   int
+	err     error
+}
+
+// NewActionChain returns an empty ActionChain.
+func NewActionChain() *ActionChain {
+	return &ActionChain{}
+}
+
+// InputSource is a virtual input device of an ActionChain, whose methods
+// return its actions, to add to a tick with ActionChain.Tick.
+type InputSource struct {
+	chain       *ActionChain
+	id          string
+	typ         string
+	pointerType PointerType
+	actions     []map[string]interface{}
+}
+
+// TickAction is an action of an InputSource.
+type TickAction struct {
+	source *InputSource
+	action map[string]interface{}
+	err    error
+}
+
+// source returns the source with the ID, adding it if it does not exist.
+func (c *ActionChain) source(id, typ string, pointerType PointerType) *InputSource {
+	for _, s := range c.sources {
+		if s.id == id {
+			if s.typ != typ && c.err == nil {
+				c.err = fmt.Errorf("actions: input source %q is a %s source, not a %s source", id, s.typ, typ)
+			}
+			return s
+		}
+	}
+	s := &InputSource{chain: c, id: id, typ: typ, pointerType: pointerType}
+	// Pause the new source for the ticks added before it.
+	for i := 0; i < c.ticks; i++ {
+		s.actions = append(s.actions, pauseAction(0))
+	}
+	c.sources = append(c.sources, s)
+	return s
+}
+
+// Keyboard returns the key input source with the ID, adding it if needed.
+func (c *ActionChain) Keyboard(id string) *InputSource {
+	return c.source(id, "key", "")
+}
+
+// Pointer returns the pointer input source with the ID, adding it with the
+// pointer type if needed.
+func (c *ActionChain) Pointer(id string, pointerType PointerType) *InputSource {
+	return c.source(id, "pointer", pointerType)
+}
+
+// Wheel returns the wheel input source with the ID, adding it if needed.
+func (c *ActionChain) Wheel(id string) *InputSource {
+	return c.source(id, "wheel", "")
+}
+
+// Tick adds a tick in which the actions, each of a different source, are
+// performed together.
+func (c *ActionChain) Tick(actions ...TickAction) *ActionChain {
+	seen := make(map[*InputSource]bool)
+	for _, a := range actions {
+		switch {
+		case c.err != nil:
+		case a.err != nil:
+			c.err = a.err
+		case a.source.chain != c:
+			c.err = fmt.Errorf("actions: input source %q belongs to another chain", a.source.id)
+		case seen[a.source]:
+			c.err = fmt.Errorf("actions: input source %q has several actions in a tick", a.source.id)
+		}
+		if c.err != nil {
+			return c
+		}
+		seen[a.source] = true
+	}
+	for _, a := range actions {
+		a.source.actions = append(a.source.actions, a.action)
+	}
+	for _, s := range c.sources {
+		if !seen[s] {
+			s.actions = append(s.actions, pauseAction(0))
+		}
+	}
+	c.ticks++
+	return c
+}
+
+// Pause adds a tick that lasts for d.
+func (c *ActionChain) Pause(d time.Duration) *ActionChain {
+	if len(c.sources) == 0 {
+		c.Keyboard(DefaultKeyboard)
+	}
+	return c.Tick(c.sources[0].Pause(d))
+}
+
+// KeyDown adds a tick that presses key on the default keyboard.
+func (c *ActionChain) KeyDown(key string) *ActionChain {
+	return c.Tick(c.Keyboard(DefaultKeyboard).KeyDown(key))
+}
+
+// KeyUp adds a tick that releases key on the default keyboard.
+func (c *ActionChain) KeyUp(key string) *ActionChain {
+	return c.Tick(c.Keyboard(DefaultKeyboard).KeyUp(key))
+}
+
+// SendKeys adds ticks that press and release each character of keys in turn
+// on the default keyboard.
+func (c *ActionChain) SendKeys(keys string) *ActionChain {
+	kb := c.Keyboard(DefaultKeyboard)
+	for _, r := range keys {
+		c.Tick(kb.KeyDown(string(r))).Tick(kb.KeyUp(string(r)))
+	}
+	return c
+}
+
+// MoveTo adds a tick that moves the default mouse to x, y in the viewport.
+func (c *ActionChain) MoveTo(x, y int) *ActionChain {
+	return c.Tick(c.Pointer(DefaultMouse, MousePointer).Move(0, Point{X: x, Y: y}, FromViewport))
+}
+
+// MoveBy adds a tick that moves the default mouse by x, y from its position.
+func (c *ActionChain) MoveBy(x, y int) *ActionChain {
+	return c.Tick(c.Pointer(DefaultMouse, MousePointer).Move(0, Point{X: x, Y: y}, FromPointer))
+}
+
+// MoveToElement adds a tick that moves the default mouse to x, y from the
+// center of elem, which is scrolled into view.
+func (c *ActionChain) MoveToElement(elem WebElement, x, y int) *ActionChain {
+	return c.Tick(c.Pointer(DefaultMouse, MousePointer).MoveToElement(0, elem, Point{X: x, Y: y}))
+}
+
+// PointerDown adds a tick that presses button on the default mouse.
+func (c *ActionChain) PointerDown(button MouseButton) *ActionChain {
+	return c.Tick(c.Pointer(DefaultMouse, MousePointer).Down(button))
+}
+
+// PointerUp adds a tick that releases button on the default mouse.
+func (c *ActionChain) PointerUp(button MouseButton) *ActionChain {
+	return c.Tick(c.Pointer(DefaultMouse, MousePointer).Up(button))
+}
+
+// Click adds the ticks that press and release button on the default mouse.
+func (c *ActionChain) Click(button MouseButton) *ActionChain {
+	return c.PointerDown(button).PointerUp(button)
+}
+
+// DoubleClick adds the ticks of two clicks of the left button of the default
+// mouse.
+func (c *ActionChain) DoubleClick() *ActionChain {
+	return c.Click(LeftButton).Click(LeftButton)
+}
+
+// DragAndDrop adds the ticks that drag source onto target with the left
+// button of the default mouse.
+func (c *ActionChain) DragAndDrop(source, target WebElement) *ActionChain {
+	return c.MoveToElement(source, 0, 0).
+		PointerDown(LeftButton).
+		MoveToElement(target, 0, 0).
+		PointerUp(LeftButton)
+}
+
+// Scroll adds a tick that scrolls by dx, dy with the default wheel, at x, y
+// in the viewport.
+func (c *ActionChain) Scroll(x, y, dx, dy int) *ActionChain {
+	return c.Tick(c.Wheel(DefaultWheel).Scroll(0, Point{X: x, Y: y}, dx, dy))
+}
+
+// ScrollFromElement adds a tick that scrolls by dx, dy with the default
+// wheel, at the center of elem, which is scrolled into view first.
+func (c *ActionChain) ScrollFromElement(elem WebElement, dx, dy int) *ActionChain {
+	return c.Tick(c.Wheel(DefaultWheel).ScrollFromElement(0, elem, Point{}, dx, dy))
+}
+
+// Actions returns the input sources of the chain and their actions, as
+// sent by PerformActionChain, or the first error in building the chain.
+func (c *ActionChain) Actions() (Actions, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	actions := make(Actions, 0, len(c.sources))
+	for _, s := range c.sources {
+		source := map[string]interface{}{
+			"type":    s.typ,
+			"id":      s.id,
+			"actions": s.actions,
+		}
+		if s.typ == "pointer" {
+			source["parameters"] = map[string]string{"pointerType": string(s.pointerType)}
+		}
+		actions = append(actions, source)
+	}
+	return actions, nil
+}
+
+// action returns an action of the source, which must be of one of the types.
+func (s *InputSource) action(action map[string]interface{}, types ...string) TickAction {
+	for _, typ := range types {
+		if s.typ == typ {
+			return TickAction{source: s, action: action}
+		}
+	}
+	return TickAction{source: s, err: fmt.Errorf("actions: %s is not an action of the %s source %q", action["type"], s.typ, s.id)}
+}
+
+func pauseAction(d time.Duration) map[string]interface{} {
+	return map[string]interface{}{"type": "pause", "duration": uint(d / time.Millisecond)}
+}
+
+// elementOrigin returns the origin of a move or scroll from the center of
+// elem.
+func elementOrigin(elem WebElement) map[string]interface{} {
+	return map[string]interface{}{webElementIdentifier: elem.ID()}
+}
+
+// Pause returns an action that lasts for d.
+func (s *InputSource) Pause(d time.Duration) TickAction {
+	return s.action(pauseAction(d), "key", "pointer", "wheel")
+}
+
+// KeyDown returns an action of a key source that presses key.
+func (s *InputSource) KeyDown(key string) TickAction {
+	return s.action(KeyDownAction(key), "key")
+}
+
+// KeyUp returns an action of a key source that releases key.
+func (s *InputSource) KeyUp(key string) TickAction {
+	return s.action(KeyUpAction(key), "key")
+}
+
+// Down returns an action of a pointer source that presses button.
+func (s *InputSource) Down(button MouseButton) TickAction {
+	return s.action(PointerDownAction(button), "pointer")
+}
+
+// Up returns an action of a pointer source that releases button.
+func (s *InputSource) Up(button MouseButton) TickAction {
+	return s.action(PointerUpAction(button), "pointer")
+}
+
+// Move returns an action of a pointer source that moves it to offset from
+// origin over duration.
+func (s *InputSource) Move(duration time.Duration, offset Point, origin PointerMoveOrigin) TickAction {
+	return s.action(PointerMoveAction(duration, offset, origin), "pointer")
+}
+
+// MoveToElement returns an action of a pointer source that moves it to
+// offset from the center of elem over duration.
+func (s *InputSource) MoveToElement(duration time.Duration, elem WebElement, offset Point) TickAction {
+	action := PointerMoveAction(duration, offset, "")
+	action["origin"] = elementOrigin(elem)
+	return s.action(action, "pointer")
+}
+
+// Scroll returns an action of a wheel source that scrolls by dx, dy over
+// duration, at offset in the viewport.
+func (s *InputSource) Scroll(duration time.Duration, offset Point, dx, dy int) TickAction {
+	return s.action(scrollAction(duration, offset, dx, dy, FromViewport), "wheel")
+}
+
+// ScrollFromElement returns an action of a wheel source that scrolls by
+// dx, dy over duration, at offset from the center of elem.
+func (s *InputSource) ScrollFromElement(duration time.Duration, elem WebElement, offset Point, dx, dy int) TickAction {
+	return s.action(scrollAction(duration, offset, dx, dy, elementOrigin(elem)), "wheel")
+}
+
+func scrollAction(duration time.Duration, offset Point, dx, dy int, origin interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "scroll",
+		"duration": uint(duration / time.Millisecond),
+		"origin":   origin,
+		"x":        offset.X,
+		"y":        offset.Y,
+		"deltaX":   dx,
+		"deltaY":   dy,
+	}
+}
+
+// PerformActionChain performs the actions of chain.
+func (wd *remoteWD) PerformActionChain(chain *ActionChain) error {
+	actions, err := chain.Actions()
+	if err != nil {
+		return err
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	return wd.voidCommand("/session/%s/actions", map[string]interface{}{
+		"actions": actions,
+	})
+}
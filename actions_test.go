@@ -0,0 +1,137 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestActionChainTicks(t *testing.T) {
+	chain := NewActionChain()
+	f1 := chain.Pointer("finger1", TouchPointer)
+	chain.Tick(f1.Move(0, Point{X: 10, Y: 10}, FromViewport))
+	f2 := chain.Pointer("finger2", TouchPointer)
+	chain.Tick(f1.Down(LeftButton), f2.Down(LeftButton)).
+		Pause(50*time.Millisecond).
+		Scroll(1, 2, 0, 100)
+
+	actions, err := chain.Actions()
+	if err != nil {
+		t.Fatalf("chain.Actions() returned error: %v", err)
+	}
+	got, _ := json.Marshal(actions)
+	want := `[` +
+		`{"actions":[{"duration":0,"origin":"viewport","type":"pointerMove","x":10,"y":10},{"button":0,"type":"pointerDown"},{"duration":50,"type":"pause"},{"duration":0,"type":"pause"}],"id":"finger1","parameters":{"pointerType":"touch"},"type":"pointer"},` +
+		`{"actions":[{"duration":0,"type":"pause"},{"button":0,"type":"pointerDown"},{"duration":0,"type":"pause"},{"duration":0,"type":"pause"}],"id":"finger2","parameters":{"pointerType":"touch"},"type":"pointer"},` +
+		`{"actions":[{"duration":0,"type":"pause"},{"duration":0,"type":"pause"},{"duration":0,"type":"pause"},{"deltaX":0,"deltaY":100,"duration":0,"origin":"viewport","type":"scroll","x":1,"y":2}],"id":"default wheel","type":"wheel"}` +
+		`]`
+	if string(got) != want {
+		t.Errorf("chain.Actions() = %s, want %s", got, want)
+	}
+}
+
+func TestActionChainErrors(t *testing.T) {
+	other := NewActionChain().Keyboard("kb")
+	for _, test := range []struct {
+		desc  string
+		chain *ActionChain
+		want  string
+	}{
+		{
+			desc: "key action on a pointer",
+			chain: func() *ActionChain {
+				c := NewActionChain()
+				return c.Tick(c.Pointer("p", MousePointer).KeyDown("a"))
+			}(),
+			want: "keyDown is not an action of the pointer source",
+		},
+		{
+			desc: "two actions of a source in a tick",
+			chain: func() *ActionChain {
+				c := NewActionChain()
+				kb := c.Keyboard("kb")
+				return c.Tick(kb.KeyDown("a"), kb.KeyUp("a"))
+			}(),
+			want: "several actions in a tick",
+		},
+		{
+			desc:  "source of another chain",
+			chain: NewActionChain().Tick(other.KeyDown("a")),
+			want:  "belongs to another chain",
+		},
+		{
+			desc: "reused identifier",
+			chain: func() *ActionChain {
+				c := NewActionChain().KeyDown("a")
+				c.Pointer(DefaultKeyboard, MousePointer)
+				return c
+			}(),
+			want: "is a key source, not a pointer source",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			wd := d.newRemote(t)
+			err := wd.PerformActionChain(test.chain)
+			if err == nil || !strings.Contains(err.Error(), test.want) {
+				t.Errorf("wd.PerformActionChain() returned error %v, want one containing %q", err, test.want)
+			}
+			if n := d.count("POST", "/actions"); n != 0 {
+				t.Errorf("wd.PerformActionChain() sent %d commands, want 0", n)
+			}
+		})
+	}
+}
+
+func TestMouseActions(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var bodies []string
+	d.handle("POST", "/actions", func(body []byte) (int, interface{}) {
+		bodies = append(bodies, string(body))
+		return http.StatusOK, nil
+	})
+	d.handle("DELETE", "/actions", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	if err := wd.Click(int(RightButton)); err != nil {
+		t.Fatalf("wd.Click() returned error: %v", err)
+	}
+	if err := wd.ButtonDown(); err != nil {
+		t.Fatalf("wd.ButtonDown() returned error: %v", err)
+	}
+	if err := wd.ElementFromID("e1").MoveTo(3, 4); err != nil {
+		t.Fatalf("elem.MoveTo() returned error: %v", err)
+	}
+	if err := wd.ButtonUp(); err != nil {
+		t.Fatalf("wd.ButtonUp() returned error: %v", err)
+	}
+	if err := wd.ReleaseActions(); err != nil {
+		t.Fatalf("wd.ReleaseActions() returned error: %v", err)
+	}
+
+	for _, legacy := range []string{"/click", "/buttondown", "/buttonup", "/moveto"} {
+		if n := d.count("POST", legacy); n != 0 {
+			t.Errorf("the session sent %d legacy %s commands, want 0", n, legacy)
+		}
+	}
+	if len(bodies) != 4 {
+		t.Fatalf("the session sent %d Perform Actions commands, want 4", len(bodies))
+	}
+	for i, want := range []string{
+		`"button":2,"type":"pointerDown"},{"button":2,"type":"pointerUp"}`,
+		`"button":0,"type":"pointerDown"}]`,
+		`"origin":{"` + webElementIdentifier + `":"e1"},"type":"pointerMove","x":3,"y":4}`,
+		`"button":0,"type":"pointerUp"}]`,
+	} {
+		if !strings.Contains(bodies[i], want) || !strings.Contains(bodies[i], `"id":"default mouse"`) {
+			t.Errorf("Perform Actions command %d has body %s, want it to contain %s for the default mouse", i, bodies[i], want)
+		}
+	}
+	if n := d.count("DELETE", "/actions"); n != 1 {
+		t.Errorf("wd.ReleaseActions() sent %d commands, want 1", n)
+	}
+}
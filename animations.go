@@ -0,0 +1,50 @@
+package selenium
+
+// disableAnimationsScript adds a style sheet that turns off CSS transitions
+// and animations and hides the text caret, and finishes running Web
+// Animations. It is safe to evaluate more than once in a document.
+const disableAnimationsScript = `(function() {
+	var id = '__selenium_disable_animations';
+	var css = '*, *::before, *::after {' +
+		' transition: none !important;' +
+		' animation: none !important;' +
+		' caret-color: transparent !important;' +
+		' scroll-behavior: auto !important;' +
+		' }';
+	var add = function() {
+		if (document.getElementById(id)) {
+			return;
+		}
+		var style = document.createElement('style');
+		style.id = id;
+		style.textContent = css;
+		(document.head || document.documentElement).appendChild(style);
+		if (document.getAnimations) {
+			document.getAnimations().forEach(function(a) {
+				try {
+					a.finish();
+				} catch (e) {
+					// Infinite animations cannot be finished.
+					a.cancel();
+				}
+			});
+		}
+	};
+	if (document.documentElement) {
+		add();
+	} else {
+		document.addEventListener('DOMContentLoaded', add);
+	}
+})();`
+
+// DisableAnimations turns off animations in the current page and, where
+// supported, in pages loaded afterwards.
+func (wd *remoteWD) DisableAnimations() error {
+	if wd.Supports(FeatureCDP) {
+		if _, err := wd.addInitScript(disableAnimationsScript); err != nil {
+			return err
+		}
+	}
+	_, err := wd.ExecuteScript(disableAnimationsScript, nil)
+	return err
+}
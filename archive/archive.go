@@ -0,0 +1,255 @@
+// Package archive creates the Zip files sent to the remote end: Chrome and
+// Firefox extensions, Firefox profiles and file uploads.
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SymlinkPolicy tells how symbolic links are archived.
+type SymlinkPolicy int
+
+const (
+	// SkipSymlinks leaves symbolic links out of the archive. It is the
+	// default.
+	SkipSymlinks SymlinkPolicy = iota
+	// FollowSymlinks archives the targets of the links to regular files.
+	// Links to directories are skipped, so that cycles cannot occur.
+	FollowSymlinks
+	// RejectSymlinks makes the archive fail when it finds a link.
+	RejectSymlinks
+)
+
+// modTime is the modification time of the files of deterministic archives,
+// the earliest time that Zip files can hold.
+var modTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type options struct {
+	excludes      []string
+	deterministic bool
+	symlinks      SymlinkPolicy
+	singleFile    bool
+	only          string
+}
+
+// Option configures an archive.
+type Option func(*options)
+
+// Exclude leaves out the files and directories matching any of the patterns,
+// in the syntax of path.Match. A pattern without a slash matches the base
+// name at any depth, e.g. "*.pem"; a pattern with a slash matches the path
+// relative to the root, e.g. "_metadata/*".
+func Exclude(patterns ...string) Option {
+	return func(o *options) {
+		o.excludes = append(o.excludes, patterns...)
+	}
+}
+
+// Deterministic makes archives of the same files identical, whatever their
+// modification times and permissions, e.g. so that they hash the same.
+func Deterministic() Option {
+	return func(o *options) {
+		o.deterministic = true
+	}
+}
+
+// Symlinks sets how symbolic links are archived.
+func Symlinks(policy SymlinkPolicy) Option {
+	return func(o *options) {
+		o.symlinks = policy
+	}
+}
+
+// SingleFile makes the archive fail unless it holds exactly one file, at its
+// root.
+func SingleFile() Option {
+	return func(o *options) {
+		o.singleFile = true
+	}
+}
+
+// ForCRX configures the archive of an unpacked Chrome extension: version
+// control data, packed extensions and private keys are left out, and the
+// archive is deterministic so that the ID and fingerprint of the extension
+// only depend on its files.
+func ForCRX() Option {
+	return func(o *options) {
+		Exclude(".git", ".svn", ".hg", "*.crx", "*.pem")(o)
+		Deterministic()(o)
+	}
+}
+
+// ForXPI configures the archive of an unpacked Firefox extension: version
+// control data, packed extensions and web-ext artifacts are left out, and the
+// archive is deterministic.
+func ForXPI() Option {
+	return func(o *options) {
+		Exclude(".git", ".svn", ".hg", "*.xpi", "web-ext-artifacts")(o)
+		Deterministic()(o)
+	}
+}
+
+// ForFirefoxProfile configures the archive of a Firefox profile directory:
+// the lock files of a running browser and its caches are left out, since
+// Firefox refuses or rebuilds them.
+func ForFirefoxProfile() Option {
+	return func(o *options) {
+		Exclude("lock", ".parentlock", "parent.lock", "cache2", "startupCache")(o)
+	}
+}
+
+// ForGridUpload configures the archive of a file uploaded to a Selenium
+// server, which requires exactly one file at the root of the archive.
+func ForGridUpload() Option {
+	return func(o *options) {
+		SingleFile()(o)
+	}
+}
+
+func (o *options) excluded(name string) bool {
+	for _, p := range o.excludes {
+		target := path.Base(name)
+		if strings.Contains(p, "/") {
+			target = name
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Write streams a Zip file of the files of fsys to w. The files are written
+// in lexical order, without the directories themselves.
+func Write(w io.Writer, fsys fs.FS, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	zw := zip.NewWriter(w)
+	files := 0
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name == "." {
+			return nil
+		}
+		if o.excluded(name) || (o.only != "" && name != o.only) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			switch o.symlinks {
+			case SkipSymlinks:
+				return nil
+			case RejectSymlinks:
+				return fmt.Errorf("%s is a symbolic link", name)
+			}
+		}
+		if d.IsDir() {
+			return nil
+		}
+		// Stat follows links, unlike d.Info.
+		info, err := fs.Stat(fsys, name)
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		files++
+		if o.singleFile && (files > 1 || path.Dir(name) != ".") {
+			return errors.New("the archive must hold a single file at its root")
+		}
+		return addFile(zw, fsys, name, info, o.deterministic)
+	})
+	if err != nil {
+		return err
+	}
+	if o.singleFile && files == 0 {
+		return errors.New("the archive must hold a single file at its root")
+	}
+	return zw.Close()
+}
+
+// addFile writes the file name of fsys to the archive.
+func addFile(zw *zip.Writer, fsys fs.FS, name string, info fs.FileInfo, deterministic bool) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	// Without this, the Java zip reader throws a java.util.zip.ZipException:
+	// "only DEFLATED entries can have EXT descriptor".
+	header.Method = zip.Deflate
+	if deterministic {
+		header.Modified = modTime
+		header.SetMode(0644)
+	}
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// New returns a buffer holding a Zip file of the files of fsys.
+func New(fsys fs.FS, opts ...Option) (*bytes.Buffer, error) {
+	buf := new(bytes.Buffer)
+	if err := Write(buf, fsys, opts...); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// Dir returns a buffer holding a Zip file of the files of a directory, at the
+// root of the archive.
+func Dir(dir string, opts ...Option) (*bytes.Buffer, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("path %q is not a directory", dir)
+	}
+	return New(os.DirFS(dir), opts...)
+}
+
+// File returns a buffer holding a Zip file of a single file, at the root of
+// the archive.
+func File(file string, opts ...Option) (*bytes.Buffer, error) {
+	fi, err := os.Stat(file)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("path %q is not a regular file", file)
+	}
+	opts = append(opts, func(o *options) {
+		o.only = filepath.Base(file)
+		if o.symlinks == SkipSymlinks {
+			// The file was named explicitly.
+			o.symlinks = FollowSymlinks
+		}
+	})
+	return New(os.DirFS(filepath.Dir(file)), opts...)
+}
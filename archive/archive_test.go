@@ -0,0 +1,144 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func names(t *testing.T, buf *bytes.Buffer) []string {
+	t.Helper()
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("zip.NewReader() returned error: %v", err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+	}
+	return names
+}
+
+func TestNewExclude(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json":        {Data: []byte("{}")},
+		"key.pem":              {Data: []byte("key")},
+		"js/background.js":     {Data: []byte("")},
+		".git/HEAD":            {Data: []byte("ref")},
+		"_metadata/hashes.txt": {Data: []byte("")},
+	}
+	buf, err := New(fsys, ForCRX(), Exclude("_metadata/*"))
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	want := []string{"js/background.js", "manifest.json"}
+	if got := names(t, buf); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() archived %q, want %q", got, want)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	archive := func(modTime time.Time, mode os.FileMode) []byte {
+		fsys := fstest.MapFS{"a.txt": {Data: []byte("a"), ModTime: modTime, Mode: mode}}
+		buf, err := New(fsys, Deterministic())
+		if err != nil {
+			t.Fatalf("New() returned error: %v", err)
+		}
+		return buf.Bytes()
+	}
+	if !bytes.Equal(archive(time.Now(), 0600), archive(time.Now().Add(-time.Hour), 0755)) {
+		t.Error("New(Deterministic()) depends on the modification times and permissions")
+	}
+}
+
+func TestForGridUpload(t *testing.T) {
+	if _, err := New(fstest.MapFS{"a.txt": {}, "b.txt": {}}, ForGridUpload()); err == nil {
+		t.Error("New(ForGridUpload()) with two files returned no error")
+	}
+	if _, err := New(fstest.MapFS{"dir/a.txt": {}}, ForGridUpload()); err == nil {
+		t.Error("New(ForGridUpload()) with a file in a directory returned no error")
+	}
+	if _, err := New(fstest.MapFS{}, ForGridUpload()); err == nil {
+		t.Error("New(ForGridUpload()) without files returned no error")
+	}
+}
+
+func TestForXPI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"manifest.json":                 {Data: []byte("{}")},
+		"content.js":                    {Data: []byte("")},
+		"old.xpi":                       {Data: []byte("")},
+		"web-ext-artifacts/ext-1.0.zip": {Data: []byte("")},
+		".hg/store":                     {Data: []byte("")},
+	}
+	buf, err := New(fsys, ForXPI())
+	if err != nil {
+		t.Fatalf("New() returned error: %v", err)
+	}
+	want := []string{"content.js", "manifest.json"}
+	if got := names(t, buf); !reflect.DeepEqual(got, want) {
+		t.Errorf("New(ForXPI()) archived %q, want %q", got, want)
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"upload.txt", "other.txt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf, err := File(filepath.Join(dir, "upload.txt"), ForGridUpload())
+	if err != nil {
+		t.Fatalf("File() returned error: %v", err)
+	}
+	if got, want := names(t, buf), []string{"upload.txt"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("File() archived %q, want %q", got, want)
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "user.js"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "user.js"), filepath.Join(dir, "link.js")); err != nil {
+		t.Skipf("os.Symlink() returned error: %v", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(dir, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		policy SymlinkPolicy
+		want   []string
+	}{
+		{SkipSymlinks, []string{"user.js"}},
+		{FollowSymlinks, []string{"link.js", "user.js"}},
+	} {
+		buf, err := Dir(dir, Symlinks(tc.policy))
+		if err != nil {
+			t.Fatalf("Dir(Symlinks(%d)) returned error: %v", tc.policy, err)
+		}
+		if got := names(t, buf); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Dir(Symlinks(%d)) archived %q, want %q", tc.policy, got, tc.want)
+		}
+	}
+	if _, err := Dir(dir, Symlinks(RejectSymlinks)); err == nil {
+		t.Error("Dir(Symlinks(RejectSymlinks)) returned no error")
+	}
+}
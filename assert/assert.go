@@ -0,0 +1,335 @@
+// Package assert provides polling assertions on the state of a page, for use
+// in tests that drive a browser through a selenium.WebDriver.
+//
+// Each assertion retries its condition until it holds or a timeout expires.
+// On failure the report includes the locator, the condition, the last value
+// observed and the time spent waiting:
+//
+//	assert.Eventually(t, wd).Element(selenium.ByCSS(".total")).HasText("$42.00")
+//
+// Errors that cannot be resolved by waiting, such as an invalid selector or a
+// lost session, fail the assertion immediately rather than being retried.
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LoveOyy/selenium"
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// TestingT is the subset of testing.TB used to report failures. Custom
+// reporters only need to implement these two methods.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Defaults for the polling of assertions.
+var (
+	// DefaultTimeout is how long an assertion waits for its condition to hold.
+	DefaultTimeout = 5 * time.Second
+	// DefaultInterval is the delay between evaluations of the condition.
+	DefaultInterval = 100 * time.Millisecond
+)
+
+// Failure describes an assertion that did not hold before the timeout.
+type Failure struct {
+	// Locator identifies the element or elements the assertion applied to.
+	Locator selenium.Locator
+	// Condition describes what was expected, e.g. `has text "$42.00"`.
+	Condition string
+	// LastObserved is the last value observed before giving up.
+	LastObserved string
+	// Elapsed is the time spent waiting for the condition.
+	Elapsed time.Duration
+	// Err is set if the assertion was aborted by an error from the driver.
+	Err error
+	// Attachments lists artifacts, such as screenshot files, recorded by
+	// failure hooks.
+	Attachments []string
+}
+
+// String returns the multi-line failure report.
+func (f *Failure) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "element %s %s: ", f.Locator, f.Condition)
+	if f.Err != nil {
+		fmt.Fprintf(&b, "aborted after %v: %v", f.Elapsed, f.Err)
+	} else {
+		fmt.Fprintf(&b, "condition not met after %v", f.Elapsed)
+	}
+	fmt.Fprintf(&b, "\n\tlast observed: %s", f.LastObserved)
+	for _, a := range f.Attachments {
+		fmt.Fprintf(&b, "\n\tattachment: %s", a)
+	}
+	return b.String()
+}
+
+// FailureHook is called when an assertion fails, before the failure is
+// reported. Hooks may collect diagnostics and record them in f.Attachments.
+type FailureHook func(wd selenium.WebDriver, f *Failure)
+
+// Screenshot returns a FailureHook that saves a screenshot of the page to a
+// new file in dir.
+func Screenshot(dir string) FailureHook {
+	return func(wd selenium.WebDriver, f *Failure) {
+		img, err := wd.Screenshot()
+		if err != nil {
+			f.Attachments = append(f.Attachments, fmt.Sprintf("screenshot failed: %v", err))
+			return
+		}
+		file, err := ioutil.TempFile(dir, "assert-*.png")
+		if err != nil {
+			f.Attachments = append(f.Attachments, fmt.Sprintf("screenshot failed: %v", err))
+			return
+		}
+		defer file.Close()
+		if _, err := file.Write(img); err != nil {
+			f.Attachments = append(f.Attachments, fmt.Sprintf("screenshot failed: %v", err))
+			return
+		}
+		f.Attachments = append(f.Attachments, filepath.Clean(file.Name()))
+		wd.RecordArtifact("screenshot", filepath.Clean(file.Name()))
+	}
+}
+
+// Asserter makes polling assertions against a WebDriver.
+type Asserter struct {
+	t        TestingT
+	wd       selenium.WebDriver
+	timeout  time.Duration
+	interval time.Duration
+	fatal    bool
+	hooks    []FailureHook
+}
+
+// Eventually returns an Asserter that reports failures to t with Errorf. It
+// waits for DefaultTimeout and polls every DefaultInterval, unless the
+// session was given another wait timeout or poll interval with
+// selenium.WithDefaults.
+func Eventually(t TestingT, wd selenium.WebDriver) *Asserter {
+	defaults := wd.Defaults()
+	timeout, interval := DefaultTimeout, DefaultInterval
+	if defaults.WaitTimeout != selenium.DefaultWaitTimeout {
+		timeout = defaults.WaitTimeout
+	}
+	if defaults.PollInterval != selenium.DefaultWaitInterval {
+		interval = defaults.PollInterval
+	}
+	return &Asserter{
+		t:        t,
+		wd:       wd,
+		timeout:  timeout,
+		interval: interval,
+	}
+}
+
+// Within sets how long the assertions wait for their condition to hold.
+func (a *Asserter) Within(timeout time.Duration) *Asserter {
+	a.timeout = timeout
+	return a
+}
+
+// PollEvery sets the delay between evaluations of the condition.
+func (a *Asserter) PollEvery(interval time.Duration) *Asserter {
+	a.interval = interval
+	return a
+}
+
+// Must makes failures stop the test by reporting them with Fatalf.
+func (a *Asserter) Must() *Asserter {
+	a.fatal = true
+	return a
+}
+
+// OnFailure adds a hook that is called when an assertion fails.
+func (a *Asserter) OnFailure(hook FailureHook) *Asserter {
+	a.hooks = append(a.hooks, hook)
+	return a
+}
+
+// Element returns assertions on the elements matched by l.
+func (a *Asserter) Element(l selenium.Locator) *ElementAssertion {
+	return &ElementAssertion{a: a, l: l}
+}
+
+// check is evaluated repeatedly by poll. It returns a description of the
+// value it observed and whether the condition holds.
+type check func() (observed string, ok bool, err error)
+
+// poll evaluates c until it holds or the timeout expires and reports a
+// failure in the latter case.
+func (a *Asserter) poll(l selenium.Locator, condition string, c check) bool {
+	if h, ok := a.t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	start := time.Now()
+	var observed string
+	for {
+		o, ok, err := c()
+		switch {
+		case err != nil && isRetryable(err):
+			observed = err.Error()
+		case err != nil:
+			return a.fail(&Failure{
+				Locator:      l,
+				Condition:    condition,
+				LastObserved: observed,
+				Elapsed:      time.Since(start),
+				Err:          err,
+			})
+		case ok:
+			return true
+		default:
+			observed = o
+		}
+
+		if time.Since(start) >= a.timeout {
+			return a.fail(&Failure{
+				Locator:      l,
+				Condition:    condition,
+				LastObserved: observed,
+				Elapsed:      time.Since(start),
+			})
+		}
+		time.Sleep(a.interval)
+	}
+}
+
+func (a *Asserter) fail(f *Failure) bool {
+	if h, ok := a.t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if f.LastObserved == "" {
+		f.LastObserved = "nothing"
+	}
+	for _, hook := range a.hooks {
+		hook(a.wd, f)
+	}
+	if a.fatal {
+		a.t.Fatalf("%s", f)
+	} else {
+		a.t.Errorf("%s", f)
+	}
+	return false
+}
+
+// isRetryable reports whether err may go away if the page changes, in which
+// case it counts as the condition not being met yet.
+func isRetryable(err error) bool {
+	return errors.Is(err, werror.NoSuchElement) || errors.Is(err, werror.StaleElementReference)
+}
+
+// ElementAssertion makes assertions about the elements matched by a locator.
+type ElementAssertion struct {
+	a *Asserter
+	l selenium.Locator
+}
+
+// HasText asserts that the first matching element's visible text is want.
+func (e *ElementAssertion) HasText(want string) bool {
+	return e.a.poll(e.l, fmt.Sprintf("has text %q", want), e.text(func(got string) bool {
+		return got == want
+	}))
+}
+
+// ContainsText asserts that the first matching element's visible text
+// contains substr.
+func (e *ElementAssertion) ContainsText(substr string) bool {
+	return e.a.poll(e.l, fmt.Sprintf("contains text %q", substr), e.text(func(got string) bool {
+		return strings.Contains(got, substr)
+	}))
+}
+
+func (e *ElementAssertion) text(match func(string) bool) check {
+	return func() (string, bool, error) {
+		elem, err := e.a.wd.FindElement(e.l.By, e.l.Value)
+		if err != nil {
+			return "", false, err
+		}
+		got, err := elem.Text()
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("text %q", got), match(got), nil
+	}
+}
+
+// HasAttribute asserts that the first matching element's attribute name
+// has the value want.
+func (e *ElementAssertion) HasAttribute(name, want string) bool {
+	return e.a.poll(e.l, fmt.Sprintf("has attribute %s=%q", name, want), func() (string, bool, error) {
+		elem, err := e.a.wd.FindElement(e.l.By, e.l.Value)
+		if err != nil {
+			return "", false, err
+		}
+		got, err := elem.GetAttribute(name)
+		if err != nil {
+			if isRetryable(err) {
+				return "", false, err
+			}
+			// GetAttribute reports a missing attribute as an error.
+			return fmt.Sprintf("no attribute %s", name), false, nil
+		}
+		return fmt.Sprintf("attribute %s=%q", name, got), got == want, nil
+	})
+}
+
+// IsVisible asserts that the first matching element is displayed.
+func (e *ElementAssertion) IsVisible() bool {
+	return e.a.poll(e.l, "is visible", e.displayed(true))
+}
+
+// IsHidden asserts that the first matching element exists and is not
+// displayed.
+func (e *ElementAssertion) IsHidden() bool {
+	return e.a.poll(e.l, "is hidden", e.displayed(false))
+}
+
+func (e *ElementAssertion) displayed(want bool) check {
+	return func() (string, bool, error) {
+		elem, err := e.a.wd.FindElement(e.l.By, e.l.Value)
+		if err != nil {
+			return "", false, err
+		}
+		got, err := elem.IsDisplayed()
+		if err != nil {
+			return "", false, err
+		}
+		if got {
+			return "visible", got == want, nil
+		}
+		return "hidden", got == want, nil
+	}
+}
+
+// Exists asserts that at least one element matches.
+func (e *ElementAssertion) Exists() bool {
+	return e.a.poll(e.l, "exists", e.count(func(n int) bool { return n > 0 }))
+}
+
+// NotExists asserts that no element matches.
+func (e *ElementAssertion) NotExists() bool {
+	return e.a.poll(e.l, "does not exist", e.count(func(n int) bool { return n == 0 }))
+}
+
+// CountIs asserts that exactly n elements match.
+func (e *ElementAssertion) CountIs(n int) bool {
+	return e.a.poll(e.l, fmt.Sprintf("count is %d", n), e.count(func(got int) bool { return got == n }))
+}
+
+func (e *ElementAssertion) count(match func(int) bool) check {
+	return func() (string, bool, error) {
+		elems, err := e.a.wd.FindElements(e.l.By, e.l.Value)
+		if err != nil {
+			return "", false, err
+		}
+		return fmt.Sprintf("%d matching elements", len(elems)), match(len(elems)), nil
+	}
+}
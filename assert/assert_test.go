@@ -0,0 +1,164 @@
+package assert
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// fakeT records reported failures.
+type fakeT struct {
+	errors, fatals []string
+}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func (t *fakeT) Fatalf(format string, args ...interface{}) {
+	t.fatals = append(t.fatals, fmt.Sprintf(format, args...))
+}
+
+// fakeWD serves FindElement and FindElements from a function returning the
+// texts of the matching elements, and the library Defaults. The embedded
+// interface is nil, so calling any other method panics.
+type fakeWD struct {
+	selenium.WebDriver
+	find     func() ([]string, error)
+	defaults *selenium.Defaults
+}
+
+func (wd *fakeWD) FindElement(by, value string) (selenium.WebElement, error) {
+	texts, err := wd.find()
+	if err != nil {
+		return nil, err
+	}
+	if len(texts) == 0 {
+		return nil, &selenium.Error{Err: "no such element"}
+	}
+	return &fakeWE{text: texts[0]}, nil
+}
+
+func (wd *fakeWD) Defaults() selenium.Defaults {
+	if wd.defaults != nil {
+		return *wd.defaults
+	}
+	return selenium.Defaults{WaitTimeout: selenium.DefaultWaitTimeout, PollInterval: selenium.DefaultWaitInterval}
+}
+
+func (wd *fakeWD) FindElements(by, value string) ([]selenium.WebElement, error) {
+	texts, err := wd.find()
+	if err != nil {
+		return nil, err
+	}
+	var elems []selenium.WebElement
+	for _, text := range texts {
+		elems = append(elems, &fakeWE{text: text})
+	}
+	return elems, nil
+}
+
+type fakeWE struct {
+	selenium.WebElement
+	text string
+}
+
+func (we *fakeWE) Text() (string, error) { return we.text, nil }
+
+func (we *fakeWE) IsDisplayed() (bool, error) { return we.text != "", nil }
+
+// after returns a find function that matches no element for the first n
+// calls and then the given texts.
+func after(n int, texts ...string) func() ([]string, error) {
+	calls := 0
+	return func() ([]string, error) {
+		calls++
+		if calls <= n {
+			return nil, nil
+		}
+		return texts, nil
+	}
+}
+
+func TestHasTextEventuallySucceeds(t *testing.T) {
+	ft := new(fakeT)
+	wd := &fakeWD{find: after(3, "$42.00")}
+	if !Eventually(ft, wd).PollEvery(time.Millisecond).Element(selenium.ByCSS(".total")).HasText("$42.00") {
+		t.Errorf("HasText() = false, want true; failures: %v", ft.errors)
+	}
+}
+
+func TestEventuallyUsesSessionDefaults(t *testing.T) {
+	defaults := &selenium.Defaults{WaitTimeout: 30 * time.Millisecond, PollInterval: time.Millisecond}
+	wd := &fakeWD{find: after(0, "$41.00"), defaults: defaults}
+	a := Eventually(new(fakeT), wd)
+	if a.timeout != defaults.WaitTimeout || a.interval != defaults.PollInterval {
+		t.Errorf("Eventually() waits %v polling every %v, want the session defaults %v and %v", a.timeout, a.interval, defaults.WaitTimeout, defaults.PollInterval)
+	}
+	if a.Within(time.Second).PollEvery(time.Second); a.timeout != time.Second || a.interval != time.Second {
+		t.Errorf("Within() and PollEvery() set %v and %v, want 1s and 1s", a.timeout, a.interval)
+	}
+
+	a = Eventually(new(fakeT), &fakeWD{})
+	if a.timeout != DefaultTimeout || a.interval != DefaultInterval {
+		t.Errorf("Eventually() with the library defaults waits %v polling every %v, want %v and %v", a.timeout, a.interval, DefaultTimeout, DefaultInterval)
+	}
+}
+
+func TestHasTextReportsLastObservedValue(t *testing.T) {
+	ft := new(fakeT)
+	wd := &fakeWD{find: after(0, "$41.00")}
+	a := Eventually(ft, wd).Within(20 * time.Millisecond).PollEvery(time.Millisecond)
+	if a.Element(selenium.ByCSS(".total")).HasText("$42.00") {
+		t.Fatalf("HasText() = true, want false")
+	}
+	if len(ft.errors) != 1 || len(ft.fatals) != 0 {
+		t.Fatalf("got errors %q and fatals %q, want exactly one error", ft.errors, ft.fatals)
+	}
+	for _, want := range []string{`css selector ".total"`, `has text "$42.00"`, `last observed: text "$41.00"`, "condition not met after"} {
+		if !strings.Contains(ft.errors[0], want) {
+			t.Errorf("failure %q does not contain %q", ft.errors[0], want)
+		}
+	}
+}
+
+func TestInvalidSelectorIsNotRetried(t *testing.T) {
+	ft := new(fakeT)
+	calls := 0
+	wd := &fakeWD{find: func() ([]string, error) {
+		calls++
+		return nil, &selenium.Error{Err: "invalid selector", Message: "bad selector"}
+	}}
+	if Eventually(ft, wd).Must().Element(selenium.ByCSS("[")).IsVisible() {
+		t.Fatalf("IsVisible() = true, want false")
+	}
+	if calls != 1 {
+		t.Errorf("FindElement was called %d times, want 1", calls)
+	}
+	if len(ft.fatals) != 1 || !strings.Contains(ft.fatals[0], "invalid selector") {
+		t.Errorf("got fatals %q, want one mentioning the invalid selector", ft.fatals)
+	}
+}
+
+func TestCountIsRunsFailureHooks(t *testing.T) {
+	ft := new(fakeT)
+	wd := &fakeWD{find: after(0, "a", "b")}
+	hook := func(wd selenium.WebDriver, f *Failure) {
+		f.Attachments = append(f.Attachments, "hook.png")
+	}
+	a := Eventually(ft, wd).Within(0).OnFailure(hook)
+	if a.Element(selenium.ByCSS("li")).CountIs(3) {
+		t.Fatalf("CountIs(3) = true, want false")
+	}
+	if len(ft.errors) != 1 {
+		t.Fatalf("got errors %q, want exactly one", ft.errors)
+	}
+	for _, want := range []string{"2 matching elements", "attachment: hook.png"} {
+		if !strings.Contains(ft.errors[0], want) {
+			t.Errorf("failure %q does not contain %q", ft.errors[0], want)
+		}
+	}
+}
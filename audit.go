@@ -0,0 +1,226 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// PageRule is a structural check of the current page, run by AuditPage. The
+// rules of an audit are evaluated by a single script, so that the audit takes
+// one round trip whatever the number of rules.
+type PageRule interface {
+	// Name identifies the rule in its violations.
+	Name() string
+	// Script returns the body of a JavaScript function called with two
+	// parameters: arg, the JSON encoding of which is passed to the browser,
+	// and cssPath, a function that returns a CSS selector for an element. The
+	// function returns an array of violations, objects with a message and,
+	// optionally, the offending element.
+	Script() (body string, arg interface{})
+}
+
+// Violation is a failed check of a PageRule.
+type Violation struct {
+	// Rule is the name of the rule.
+	Rule string
+	// Message describes the violation.
+	Message string
+	// Selector is a CSS selector that matches the offending element in its
+	// document. It is empty when the violation is about the whole page.
+	Selector string
+}
+
+func (v Violation) String() string {
+	if v.Selector == "" {
+		return fmt.Sprintf("%s: %s", v.Rule, v.Message)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.Rule, v.Message, v.Selector)
+}
+
+// scriptRule is a PageRule defined by its script.
+type scriptRule struct {
+	name   string
+	script string
+	arg    interface{}
+}
+
+func (r scriptRule) Name() string                  { return r.name }
+func (r scriptRule) Script() (string, interface{}) { return r.script, r.arg }
+
+// NewPageRule returns a PageRule with the given name that runs script, as
+// described by PageRule.Script, with the given argument.
+func NewPageRule(name, script string, arg interface{}) PageRule {
+	return scriptRule{name: name, script: script, arg: arg}
+}
+
+// MaxDOMNodes checks that the page has at most n elements.
+func MaxDOMNodes(n int) PageRule {
+	return NewPageRule("max-dom-nodes", `
+		var count = document.getElementsByTagName('*').length;
+		if (count <= arg) {
+			return [];
+		}
+		return [{message: 'the page has ' + count + ' elements, more than ' + arg}];`, n)
+}
+
+// ImagesHaveDimensions checks that every image has width and height
+// attributes, which let the browser lay out the page before the images are
+// loaded.
+func ImagesHaveDimensions() PageRule {
+	return NewPageRule("image-dimensions", `
+		var violations = [];
+		Array.prototype.forEach.call(document.images, function(img) {
+			if (!img.hasAttribute('width') || !img.hasAttribute('height')) {
+				violations.push({
+					element: img,
+					message: 'image ' + (img.currentSrc || img.src) + ' has no width or height attribute'
+				});
+			}
+		});
+		return violations;`, nil)
+}
+
+// NoRenderBlockingScripts checks that the head of the document has no
+// external scripts that are neither async, deferred nor modules, which block
+// the rendering of the page while they load.
+func NoRenderBlockingScripts() PageRule {
+	return NewPageRule("render-blocking-scripts", `
+		var scripts = document.head ? document.head.querySelectorAll('script[src]') : [];
+		var violations = [];
+		Array.prototype.forEach.call(scripts, function(s) {
+			if (!s.async && !s.defer && s.type !== 'module') {
+				violations.push({element: s, message: 'script ' + s.src + ' blocks rendering'});
+			}
+		});
+		return violations;`, nil)
+}
+
+// MaxDocumentDepth checks that no element is nested more than n levels below
+// the root element. The violation points at the deepest element.
+func MaxDocumentDepth(n int) PageRule {
+	return NewPageRule("max-document-depth", `
+		var deepest = null, max = 0;
+		var walk = function(el, depth) {
+			if (depth > max) {
+				deepest = el;
+				max = depth;
+			}
+			for (var c = el.firstElementChild; c; c = c.nextElementSibling) {
+				walk(c, depth + 1);
+			}
+		};
+		if (document.documentElement) {
+			walk(document.documentElement, 0);
+		}
+		if (max <= arg) {
+			return [];
+		}
+		return [{element: deepest, message: 'an element is nested ' + max + ' levels deep, more than ' + arg}];`, n)
+}
+
+// MaxInlineStyleSize checks that the style elements and style attributes of
+// the page add up to at most n characters.
+func MaxInlineStyleSize(n int) PageRule {
+	return NewPageRule("max-inline-style-size", `
+		var size = 0;
+		Array.prototype.forEach.call(document.querySelectorAll('style'), function(s) {
+			size += s.textContent.length;
+		});
+		Array.prototype.forEach.call(document.querySelectorAll('[style]'), function(el) {
+			size += el.getAttribute('style').length;
+		});
+		if (size <= arg) {
+			return [];
+		}
+		return [{message: 'the inline styles have ' + size + ' characters, more than ' + arg}];`, n)
+}
+
+// auditScriptHeader defines cssPath, which builds the same selectors as
+// TabOrder, and runs the rules, the functions of which follow it. A rule that
+// throws is reported with its error.
+const auditScriptHeader = `
+	var cssPath = function(el) {
+		if (el.id && document.querySelectorAll('#' + CSS.escape(el.id)).length === 1) {
+			return '#' + CSS.escape(el.id);
+		}
+		var parts = [];
+		for (var node = el; node && node.nodeType === 1 && node !== document.documentElement; node = node.parentElement) {
+			var index = 1;
+			for (var sib = node.previousElementSibling; sib; sib = sib.previousElementSibling) {
+				if (sib.tagName === node.tagName) {
+					index++;
+				}
+			}
+			parts.unshift(node.tagName.toLowerCase() + ':nth-of-type(' + index + ')');
+			if (node.id && document.querySelectorAll('#' + CSS.escape(node.id)).length === 1) {
+				parts[0] = '#' + CSS.escape(node.id);
+				break;
+			}
+		}
+		return parts.length ? parts.join(' > ') : 'html';
+	};
+	var args = arguments[0];
+	var run = function(i, rule) {
+		try {
+			var found = rule(args[i], cssPath) || [];
+			return {violations: found.map(function(v) {
+				return {message: String(v.message), selector: v.element ? cssPath(v.element) : ''};
+			})};
+		} catch (e) {
+			return {error: String(e)};
+		}
+	};
+	var results = [];`
+
+// auditScript returns the script that runs rules.
+func auditScript(rules []PageRule) (string, []interface{}) {
+	var b strings.Builder
+	b.WriteString(auditScriptHeader)
+	args := make([]interface{}, len(rules))
+	for i, r := range rules {
+		body, arg := r.Script()
+		args[i] = arg
+		fmt.Fprintf(&b, "\n\tresults.push(run(%d, function(arg, cssPath) {%s\n\t}));", i, body)
+	}
+	b.WriteString("\n\treturn results;")
+	return b.String(), args
+}
+
+// AuditPage checks the current page against rules.
+func (wd *remoteWD) AuditPage(rules ...PageRule) ([]Violation, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	script, args := auditScript(rules)
+	data, err := wd.ExecuteScriptRaw(script, []interface{}{args})
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct {
+		Value []struct {
+			Violations []struct {
+				Message  string
+				Selector string
+			}
+			Error string
+		}
+	})
+	if err := json.Unmarshal(data, reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Value) != len(rules) {
+		return nil, fmt.Errorf("the audit script returned %d results for %d rules", len(reply.Value), len(rules))
+	}
+	var violations []Violation
+	for i, result := range reply.Value {
+		name := rules[i].Name()
+		if result.Error != "" {
+			return nil, fmt.Errorf("page rule %q: %s", name, result.Error)
+		}
+		for _, v := range result.Violations {
+			violations = append(violations, Violation{Rule: name, Message: v.Message, Selector: v.Selector})
+		}
+	}
+	return violations, nil
+}
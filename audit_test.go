@@ -0,0 +1,76 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAuditPage(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var script string
+	var args []interface{}
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		req := new(struct {
+			Script string
+			Args   []interface{}
+		})
+		json.Unmarshal(body, req)
+		script, args = req.Script, req.Args
+		return http.StatusOK, []interface{}{
+			map[string]interface{}{
+				"violations": []interface{}{
+					map[string]interface{}{"message": "too many", "selector": ""},
+				},
+			},
+			map[string]interface{}{
+				"violations": []interface{}{
+					map[string]interface{}{"message": "no size", "selector": "#logo"},
+				},
+			},
+			map[string]interface{}{"violations": []interface{}{}},
+		}
+	})
+	wd := d.newRemote(t)
+
+	custom := NewPageRule("no-marquee", "return [];", "marquee")
+	got, err := wd.AuditPage(MaxDOMNodes(100), ImagesHaveDimensions(), custom)
+	if err != nil {
+		t.Fatalf("wd.AuditPage() returned error: %v", err)
+	}
+	want := []Violation{
+		{Rule: "max-dom-nodes", Message: "too many"},
+		{Rule: "image-dimensions", Message: "no size", Selector: "#logo"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wd.AuditPage() = %v, want %v", got, want)
+	}
+	if n := d.count("POST", "/execute/sync"); n != 1 {
+		t.Errorf("wd.AuditPage() executed %d scripts, want 1", n)
+	}
+	if want := []interface{}{[]interface{}{float64(100), nil, "marquee"}}; !reflect.DeepEqual(args, want) {
+		t.Errorf("wd.AuditPage() passed arguments %v, want %v", args, want)
+	}
+	for _, r := range []PageRule{MaxDOMNodes(100), ImagesHaveDimensions(), custom} {
+		if body, _ := r.Script(); !strings.Contains(script, body) {
+			t.Errorf("the audit script does not contain the script of rule %q", r.Name())
+		}
+	}
+}
+
+func TestAuditPageRuleError(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, []interface{}{
+			map[string]interface{}{"error": "ReferenceError: foo is not defined"},
+		}
+	})
+	wd := d.newRemote(t)
+
+	_, err := wd.AuditPage(NewPageRule("broken", "return foo;", nil))
+	if err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("wd.AuditPage() returned error %v, want an error naming the rule", err)
+	}
+}
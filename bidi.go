@@ -0,0 +1,27 @@
+package selenium
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LoveOyy/selenium/bidi"
+)
+
+// BiDiCapability is the capability that asks the remote end for a WebDriver
+// BiDi endpoint, and under which it returns the URL of the endpoint.
+const BiDiCapability = "webSocketUrl"
+
+// EnableBiDi asks the remote end to open a WebDriver BiDi endpoint for the
+// session, to which WebDriver.BiDi connects.
+func (c Capabilities) EnableBiDi() {
+	c[BiDiCapability] = true
+}
+
+// BiDi connects to the WebDriver BiDi endpoint of the session.
+func (wd *remoteWD) BiDi() (*bidi.Conn, error) {
+	u, _ := wd.sessionCapabilities[BiDiCapability].(string)
+	if !strings.HasPrefix(u, "ws") {
+		return nil, fmt.Errorf("connecting to the BiDi endpoint: %w", ErrUnsupported)
+	}
+	return bidi.Dial(wd.Context(), u)
+}
@@ -0,0 +1,471 @@
+// Package bidi provides a client for the WebDriver BiDi protocol, which
+// remote ends serve over a WebSocket for the sessions created with the
+// webSocketUrl capability, and through which commands are sent and events,
+// such as log entries, network requests and navigations, are received
+// without polling.
+package bidi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// Events of the modules of the protocol, to pass to Conn.Subscribe. A module
+// name, e.g. "log", subscribes to all the events of the module.
+const (
+	EventLogEntryAdded = "log.entryAdded"
+
+	EventBeforeRequestSent = "network.beforeRequestSent"
+	EventResponseStarted   = "network.responseStarted"
+	EventResponseCompleted = "network.responseCompleted"
+	EventFetchError        = "network.fetchError"
+
+	EventContextCreated    = "browsingContext.contextCreated"
+	EventContextDestroyed  = "browsingContext.contextDestroyed"
+	EventNavigationStarted = "browsingContext.navigationStarted"
+	EventDOMContentLoaded  = "browsingContext.domContentLoaded"
+	EventLoad              = "browsingContext.load"
+
+	EventScriptMessage = "script.message"
+)
+
+// DialTimeout limits the time that Dial takes to connect when its context has
+// no deadline.
+var DialTimeout = 30 * time.Second
+
+// ErrClosed is returned by the commands sent on, or pending on, a closed
+// connection.
+var ErrClosed = errors.New("bidi: connection closed")
+
+// Error is an error returned by the remote end for a command.
+type Error struct {
+	// Code is the error code, e.g. "no such frame" or "unknown command".
+	Code       string `json:"error"`
+	Message    string `json:"message"`
+	Stacktrace string `json:"stacktrace"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("bidi: %s: %s", e.Code, e.Message)
+}
+
+// Event is an event received from the remote end.
+type Event struct {
+	// Method is the name of the event, e.g. "log.entryAdded".
+	Method string
+	// Params are the parameters of the event, in JSON.
+	Params json.RawMessage
+}
+
+// Decode decodes the parameters of the event into v, e.g. a *LogEntry for
+// the log.entryAdded event.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Params, v)
+}
+
+// LogEntry is the parameters of the log.entryAdded event.
+type LogEntry struct {
+	// Type is "console" or "javascript", for uncaught errors.
+	Type string `json:"type"`
+	// Level is "debug", "info", "warn" or "error".
+	Level string `json:"level"`
+	Text  string `json:"text"`
+	// Timestamp is the time of the entry, in milliseconds since the epoch.
+	Timestamp float64 `json:"timestamp"`
+	// Method is the method of console, e.g. "log", for console entries.
+	Method string `json:"method,omitempty"`
+	Source struct {
+		Realm   string `json:"realm"`
+		Context string `json:"context,omitempty"`
+	} `json:"source"`
+}
+
+// Time returns the time of the entry.
+func (e LogEntry) Time() time.Time {
+	return time.Unix(0, int64(e.Timestamp*float64(time.Millisecond)))
+}
+
+// command is a command sent to the remote end.
+type command struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// message is a message received from the remote end: the reply to a command,
+// or an event.
+type message struct {
+	ID     *int64          `json:"id,omitempty"`
+	Type   string          `json:"type,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error
+}
+
+// Conn is a connection to the WebDriver BiDi endpoint of a session. Its
+// methods may be called from several goroutines.
+type Conn struct {
+	ws *websocket.Conn
+
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan message
+	subs    map[*Subscription]bool
+	err     error
+	done    chan struct{}
+}
+
+// Dial connects to the WebDriver BiDi endpoint at url, the value of the
+// webSocketUrl capability returned by the remote end for a session created
+// with the webSocketUrl capability set to true.
+func Dial(ctx context.Context, url string) (*Conn, error) {
+	config, err := websocket.NewConfig(url, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: DialTimeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	config.Dialer = dialer
+	type result struct {
+		ws  *websocket.Conn
+		err error
+	}
+	dialed := make(chan result, 1)
+	go func() {
+		ws, err := websocket.DialConfig(config)
+		dialed <- result{ws, err}
+	}()
+	var r result
+	select {
+	case r = <-dialed:
+	case <-ctx.Done():
+		go func() {
+			if r := <-dialed; r.ws != nil {
+				r.ws.Close()
+			}
+		}()
+		return nil, ctx.Err()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("bidi: connecting to %s: %v", url, r.err)
+	}
+	c := &Conn{
+		ws:      r.ws,
+		pending: make(map[int64]chan message),
+		subs:    make(map[*Subscription]bool),
+		done:    make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// read dispatches the messages received until the connection is closed.
+func (c *Conn) read() {
+	var err error
+	for {
+		var data []byte
+		if err = websocket.Message.Receive(c.ws, &data); err != nil {
+			break
+		}
+		var m message
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		if m.ID != nil && m.Type != "event" {
+			c.mu.Lock()
+			reply, ok := c.pending[*m.ID]
+			delete(c.pending, *m.ID)
+			c.mu.Unlock()
+			if ok {
+				reply <- m
+			}
+			continue
+		}
+		if m.Method == "" {
+			continue
+		}
+		e := Event{Method: m.Method, Params: m.Params}
+		c.mu.Lock()
+		for s := range c.subs {
+			if s.matches(e.Method) {
+				s.push(e)
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClosed
+		if err != nil {
+			c.err = fmt.Errorf("%w: %v", ErrClosed, err)
+		}
+	}
+	for id, reply := range c.pending {
+		close(reply)
+		delete(c.pending, id)
+	}
+	for s := range c.subs {
+		s.end()
+	}
+	c.subs = nil
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// Send sends the command method with params, e.g. "browsingContext.navigate",
+// and decodes its result into result, unless result is nil. It returns an
+// *Error if the remote end reports an error, and ctx.Err() if ctx is done
+// first.
+func (c *Conn) Send(ctx context.Context, method string, params interface{}, result interface{}) error {
+	if params == nil {
+		params = struct{}{}
+	}
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan message, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+
+	data, err := json.Marshal(command{ID: id, Method: method, Params: p})
+	if err != nil {
+		forget()
+		return err
+	}
+	c.sendMu.Lock()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.ws.SetWriteDeadline(deadline)
+	} else {
+		c.ws.SetWriteDeadline(time.Time{})
+	}
+	err = websocket.Message.Send(c.ws, string(data))
+	c.sendMu.Unlock()
+	if err != nil {
+		forget()
+		return fmt.Errorf("bidi: sending %s: %v", method, err)
+	}
+
+	select {
+	case m, ok := <-reply:
+		if !ok {
+			return c.closeErr()
+		}
+		if m.Type == "error" {
+			e := m.Error
+			return &e
+		}
+		if result == nil || len(m.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-ctx.Done():
+		forget()
+		return ctx.Err()
+	}
+}
+
+func (c *Conn) closeErr() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close closes the connection. The session stays open: it is ended with
+// the Quit method of the WebDriver, or with the session.end command.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.mu.Unlock()
+	err := c.ws.Close()
+	<-c.done
+	return err
+}
+
+// Subscribe subscribes to the given events, or modules of events, in the
+// given browsing contexts, or in all of them if there are none. The events
+// are delivered to the returned subscription, in order, until it is closed,
+// or until the connection is.
+func (c *Conn) Subscribe(ctx context.Context, events []string, contexts ...string) (*Subscription, error) {
+	if len(events) == 0 {
+		return nil, errors.New("bidi: no events to subscribe to")
+	}
+	s := &Subscription{
+		conn:   c,
+		events: events,
+		notify: make(chan struct{}, 1),
+		out:    make(chan Event),
+		stop:   make(chan struct{}),
+	}
+	s.Events = s.out
+	// Register the subscription first so that no event sent before the
+	// reply of the remote end is missed.
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.subs[s] = true
+	c.mu.Unlock()
+	go s.deliver()
+
+	params := map[string]interface{}{"events": events}
+	if len(contexts) > 0 {
+		params["contexts"] = contexts
+	}
+	s.contexts = contexts
+	var reply struct {
+		Subscription string `json:"subscription"`
+	}
+	if err := c.Send(ctx, "session.subscribe", params, &reply); err != nil {
+		c.remove(s)
+		return nil, err
+	}
+	s.id = reply.Subscription
+	return s, nil
+}
+
+// remove stops the delivery of events to s.
+func (c *Conn) remove(s *Subscription) {
+	c.mu.Lock()
+	if c.subs[s] {
+		delete(c.subs, s)
+		s.end()
+	}
+	c.mu.Unlock()
+}
+
+// Subscription delivers the events subscribed to with Conn.Subscribe.
+type Subscription struct {
+	// Events receives the events, and is closed when the subscription or the
+	// connection is closed. The events are queued until they are received,
+	// so that reading the channel late loses none.
+	Events <-chan Event
+
+	conn     *Conn
+	id       string
+	events   []string
+	contexts []string
+
+	mu     sync.Mutex
+	queue  []Event
+	ended  bool
+	notify chan struct{}
+	out    chan Event
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// matches reports whether the subscription is to the event method.
+func (s *Subscription) matches(method string) bool {
+	for _, e := range s.events {
+		if e == method || strings.HasPrefix(method, e+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// push queues an event.
+func (s *Subscription) push(e Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// end makes the subscription close Events once the queued events are
+// delivered.
+func (s *Subscription) end() {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued events to Events.
+func (s *Subscription) deliver() {
+	defer close(s.out)
+	for {
+		s.mu.Lock()
+		queue, ended := s.queue, s.ended
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case s.out <- e:
+			case <-s.stop:
+				return
+			}
+		}
+		if ended && len(queue) == 0 {
+			return
+		}
+		if len(queue) > 0 {
+			continue
+		}
+		select {
+		case <-s.notify:
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// Close unsubscribes from the events and closes Events, dropping the events
+// not received yet.
+func (s *Subscription) Close(ctx context.Context) error {
+	s.once.Do(func() { close(s.stop) })
+	s.conn.remove(s)
+	var params map[string]interface{}
+	if s.id != "" {
+		params = map[string]interface{}{"subscriptions": []string{s.id}}
+	} else {
+		params = map[string]interface{}{"events": s.events}
+		if len(s.contexts) > 0 {
+			params["contexts"] = s.contexts
+		}
+	}
+	err := s.conn.Send(ctx, "session.unsubscribe", params, nil)
+	if errors.Is(err, ErrClosed) {
+		return nil
+	}
+	return err
+}
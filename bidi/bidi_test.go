@@ -0,0 +1,202 @@
+package bidi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeRemote is a remote end that replies to the commands it receives with
+// the reply function, and sends the events queued on its events channel.
+type fakeRemote struct {
+	*httptest.Server
+	commands chan map[string]interface{}
+	events   chan map[string]interface{}
+	reply    func(method string, params map[string]interface{}) map[string]interface{}
+}
+
+func newFakeRemote(t *testing.T) *fakeRemote {
+	r := &fakeRemote{
+		commands: make(chan map[string]interface{}, 16),
+		events:   make(chan map[string]interface{}, 16),
+		reply: func(string, map[string]interface{}) map[string]interface{} {
+			return map[string]interface{}{"type": "success", "result": map[string]interface{}{}}
+		},
+	}
+	r.Server = httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			for {
+				select {
+				case e := <-r.events:
+					e["type"] = "event"
+					websocket.JSON.Send(conn, e)
+				case <-done:
+					return
+				}
+			}
+		}()
+		for {
+			var cmd map[string]interface{}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			r.commands <- cmd
+			params, _ := cmd["params"].(map[string]interface{})
+			reply := r.reply(cmd["method"].(string), params)
+			reply["id"] = cmd["id"]
+			websocket.JSON.Send(conn, reply)
+		}
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *fakeRemote) dial(t *testing.T) *Conn {
+	t.Helper()
+	c, err := Dial(context.Background(), "ws"+strings.TrimPrefix(r.URL, "http")+"/session")
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestSend(t *testing.T) {
+	r := newFakeRemote(t)
+	r.reply = func(method string, params map[string]interface{}) map[string]interface{} {
+		if method == "browsingContext.navigate" {
+			return map[string]interface{}{"type": "success", "result": map[string]interface{}{"url": params["url"]}}
+		}
+		return map[string]interface{}{"type": "error", "error": "unknown command", "message": method}
+	}
+	c := r.dial(t)
+
+	var result struct {
+		URL string `json:"url"`
+	}
+	if err := c.Send(context.Background(), "browsingContext.navigate", map[string]string{"url": "https://example.com/"}, &result); err != nil {
+		t.Fatalf("c.Send() returned error: %v", err)
+	}
+	if result.URL != "https://example.com/" {
+		t.Errorf("c.Send() returned URL %q, want %q", result.URL, "https://example.com/")
+	}
+	if cmd := <-r.commands; cmd["method"] != "browsingContext.navigate" || cmd["id"] == nil {
+		t.Errorf("the remote end received %v", cmd)
+	}
+
+	err := c.Send(context.Background(), "foo.bar", nil, nil)
+	var e *Error
+	if !errors.As(err, &e) || e.Code != "unknown command" || e.Message != "foo.bar" {
+		t.Errorf("c.Send() returned error %v, want an *Error with code %q", err, "unknown command")
+	}
+}
+
+func TestSendClosed(t *testing.T) {
+	r := newFakeRemote(t)
+	c := r.dial(t)
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() returned error: %v", err)
+	}
+	if err := c.Send(context.Background(), "session.status", nil, nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("c.Send() on a closed connection returned error %v, want ErrClosed", err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	r := newFakeRemote(t)
+	r.reply = func(method string, params map[string]interface{}) map[string]interface{} {
+		result := map[string]interface{}{}
+		if method == "session.subscribe" {
+			result["subscription"] = "sub-1"
+		}
+		return map[string]interface{}{"type": "success", "result": result}
+	}
+	c := r.dial(t)
+
+	s, err := c.Subscribe(context.Background(), []string{"log", EventLoad})
+	if err != nil {
+		t.Fatalf("c.Subscribe() returned error: %v", err)
+	}
+	if cmd := <-r.commands; cmd["method"] != "session.subscribe" {
+		t.Errorf("the remote end received %v, want session.subscribe", cmd)
+	}
+
+	r.events <- map[string]interface{}{"method": EventLogEntryAdded, "params": map[string]interface{}{
+		"type": "console", "level": "info", "text": "hello", "timestamp": 1500,
+	}}
+	r.events <- map[string]interface{}{"method": EventNavigationStarted, "params": map[string]interface{}{}}
+	r.events <- map[string]interface{}{"method": EventLoad, "params": map[string]interface{}{"context": "c1"}}
+
+	timeout := time.After(5 * time.Second)
+	var got []Event
+	for len(got) < 2 {
+		select {
+		case e := <-s.Events:
+			got = append(got, e)
+		case <-timeout:
+			t.Fatalf("received events %v, want 2", got)
+		}
+	}
+	if got[0].Method != EventLogEntryAdded || got[1].Method != EventLoad {
+		t.Errorf("received events %v, want %s and %s", got, EventLogEntryAdded, EventLoad)
+	}
+	var entry LogEntry
+	if err := got[0].Decode(&entry); err != nil {
+		t.Fatalf("Decode() returned error: %v", err)
+	}
+	if entry.Text != "hello" || entry.Level != "info" || !entry.Time().Equal(time.Unix(1, 500*int64(time.Millisecond))) {
+		t.Errorf("the log entry is %+v", entry)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatalf("s.Close() returned error: %v", err)
+	}
+	cmd := <-r.commands
+	params, _ := cmd["params"].(map[string]interface{})
+	if subs, _ := json.Marshal(params["subscriptions"]); cmd["method"] != "session.unsubscribe" || string(subs) != `["sub-1"]` {
+		t.Errorf("the remote end received %v, want session.unsubscribe of sub-1", cmd)
+	}
+	select {
+	case _, ok := <-s.Events:
+		if ok {
+			t.Error("s.Events received an event after s.Close()")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("s.Events is not closed after s.Close()")
+	}
+}
+
+func TestSubscriptionEndsWithConn(t *testing.T) {
+	r := newFakeRemote(t)
+	c := r.dial(t)
+	s, err := c.Subscribe(context.Background(), []string{EventLogEntryAdded})
+	if err != nil {
+		t.Fatalf("c.Subscribe() returned error: %v", err)
+	}
+	r.events <- map[string]interface{}{"method": EventLogEntryAdded, "params": map[string]interface{}{"text": "queued"}}
+	select {
+	case e := <-s.Events:
+		if e.Method != EventLogEntryAdded {
+			t.Errorf("received event %v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("received no event")
+	}
+	c.Close()
+	select {
+	case _, ok := <-s.Events:
+		if ok {
+			t.Error("s.Events received an event after the connection was closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("s.Events is not closed after the connection was closed")
+	}
+}
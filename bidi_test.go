@@ -0,0 +1,79 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestBiDi(t *testing.T) {
+	endpoint := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			websocket.JSON.Send(conn, map[string]interface{}{
+				"id":     cmd["id"],
+				"type":   "success",
+				"result": map[string]interface{}{"ready": true},
+			})
+		}
+	}))
+	defer endpoint.Close()
+
+	d := newFakeDriver(t, nil)
+	var request struct {
+		Capabilities struct {
+			AlwaysMatch map[string]json.RawMessage
+		}
+	}
+	d.handlers["POST /session"] = func(body []byte) (int, interface{}) {
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		return http.StatusOK, map[string]interface{}{
+			"sessionId": fakeSessionID,
+			"capabilities": map[string]interface{}{
+				BiDiCapability: "ws" + strings.TrimPrefix(endpoint.URL, "http") + "/session/" + fakeSessionID,
+			},
+		}
+	}
+	caps := Capabilities{}
+	caps.EnableBiDi()
+	wd, err := NewRemote(caps, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	if got := string(request.Capabilities.AlwaysMatch[BiDiCapability]); got != "true" {
+		t.Errorf("alwaysMatch has %s = %s, want true", BiDiCapability, got)
+	}
+	if !wd.Supports(FeatureBiDi) {
+		t.Error("wd.Supports(FeatureBiDi) = false, want true")
+	}
+
+	conn, err := wd.BiDi()
+	if err != nil {
+		t.Fatalf("wd.BiDi() returned error: %v", err)
+	}
+	defer conn.Close()
+	var status struct {
+		Ready bool `json:"ready"`
+	}
+	if err := conn.Send(context.Background(), "session.status", nil, &status); err != nil || !status.Ready {
+		t.Errorf("session.status returned %+v, %v, want ready", status, err)
+	}
+}
+
+func TestBiDiUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.BiDi(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.BiDi() returned error %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,316 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultMaxFetchBody is the default limit on the size of the bodies of the
+// requests and responses of a BrowserTransport.
+const DefaultMaxFetchBody = 16 << 20
+
+// BrowserTransportOption configures a BrowserTransport.
+type BrowserTransportOption func(*browserTransport)
+
+// MaxFetchBody limits the size of the bodies of the requests and responses
+// of a BrowserTransport to n bytes. It defaults to DefaultMaxFetchBody.
+// Bodies are sent through the script commands, encoded in base64, so large
+// ones are slow and take memory on both ends.
+func MaxFetchBody(n int64) BrowserTransportOption {
+	return func(t *browserTransport) {
+		t.maxBody = n
+	}
+}
+
+// FetchCredentials sets the credentials mode of the fetch requests:
+// "include", the default, sends the cookies of the browser to every origin,
+// "same-origin" only to the origin of the page, and "omit" never.
+func FetchCredentials(mode string) BrowserTransportOption {
+	return func(t *browserTransport) {
+		t.credentials = mode
+	}
+}
+
+// FetchFailedError is returned by a BrowserTransport when the browser
+// rejects a request without a response. The browser does not tell the causes
+// apart: a network error, a request or response blocked by CORS, mixed
+// content or a content security policy.
+type FetchFailedError struct {
+	Method, URL string
+	// Message is the message of the exception thrown by fetch.
+	Message string
+}
+
+func (e *FetchFailedError) Error() string {
+	return fmt.Sprintf("the browser failed to fetch %s %s, which may be a network error or a request blocked by CORS: %s", e.Method, e.URL, e.Message)
+}
+
+// FetchHeaderError is returned by a BrowserTransport for a request with
+// headers that the browser controls itself, e.g. Cookie or Origin, and would
+// ignore.
+type FetchHeaderError struct {
+	Headers []string
+}
+
+func (e *FetchHeaderError) Error() string {
+	return fmt.Sprintf("the browser does not let scripts set the headers %s", strings.Join(e.Headers, ", "))
+}
+
+// FetchBodyTooLargeError is returned by a BrowserTransport when the body of a
+// request or a response exceeds the limit set with MaxFetchBody.
+type FetchBodyTooLargeError struct {
+	Method, URL string
+	// Response is true if the body is that of the response.
+	Response bool
+	Limit    int64
+}
+
+func (e *FetchBodyTooLargeError) Error() string {
+	what := "request"
+	if e.Response {
+		what = "response"
+	}
+	return fmt.Sprintf("the body of the %s of %s %s exceeds %d bytes", what, e.Method, e.URL, e.Limit)
+}
+
+// forbiddenHeaders are the request headers that the browser controls, per
+// the Fetch standard, besides those starting with "Proxy-" or "Sec-".
+var forbiddenHeaders = map[string]bool{
+	"Accept-Charset":                 true,
+	"Accept-Encoding":                true,
+	"Access-Control-Request-Headers": true,
+	"Access-Control-Request-Method":  true,
+	"Connection":                     true,
+	"Content-Length":                 true,
+	"Cookie":                         true,
+	"Cookie2":                        true,
+	"Date":                           true,
+	"Dnt":                            true,
+	"Expect":                         true,
+	"Host":                           true,
+	"Keep-Alive":                     true,
+	"Origin":                         true,
+	"Referer":                        true,
+	"Set-Cookie":                     true,
+	"Te":                             true,
+	"Trailer":                        true,
+	"Transfer-Encoding":              true,
+	"Upgrade":                        true,
+	"Via":                            true,
+}
+
+// fetchScript sends a request with fetch and returns the response, with its
+// body encoded in base64, or the reason of the failure.
+const fetchScript = `
+	var req = arguments[0], done = arguments[arguments.length - 1];
+	var decode = function(b64) {
+		var s = atob(b64), a = new Uint8Array(s.length);
+		for (var i = 0; i < s.length; i++) {
+			a[i] = s.charCodeAt(i);
+		}
+		return a;
+	};
+	var encode = function(chunks) {
+		var s = '';
+		chunks.forEach(function(c) {
+			for (var i = 0; i < c.length; i += 0x8000) {
+				s += String.fromCharCode.apply(null, c.subarray(i, i + 0x8000));
+			}
+		});
+		return btoa(s);
+	};
+	var init = {method: req.method, headers: req.headers, credentials: req.credentials, redirect: 'follow'};
+	if (req.body !== null) {
+		init.body = decode(req.body);
+	}
+	fetch(req.url, init).then(function(resp) {
+		var headers = [];
+		resp.headers.forEach(function(v, k) { headers.push([k, v]); });
+		var reply = function(chunks) {
+			done({status: resp.status, statusText: resp.statusText, url: resp.url, headers: headers, body: encode(chunks)});
+		};
+		if (Number(resp.headers.get('content-length')) > req.max) {
+			done({tooLarge: true});
+			return;
+		}
+		if (!resp.body || !resp.body.getReader) {
+			return resp.arrayBuffer().then(function(b) {
+				if (b.byteLength > req.max) {
+					done({tooLarge: true});
+					return;
+				}
+				reply([new Uint8Array(b)]);
+			});
+		}
+		var reader = resp.body.getReader(), chunks = [], size = 0;
+		var read = function() {
+			return reader.read().then(function(r) {
+				if (r.done) {
+					reply(chunks);
+					return;
+				}
+				size += r.value.length;
+				if (size > req.max) {
+					reader.cancel();
+					done({tooLarge: true});
+					return;
+				}
+				chunks.push(r.value);
+				return read();
+			});
+		};
+		return read();
+	}).catch(function(e) {
+		done({failure: String(e && e.message || e)});
+	});`
+
+type browserTransport struct {
+	wd          WebDriver
+	maxBody     int64
+	credentials string
+}
+
+// BrowserTransport returns an http.RoundTripper that sends the requests from
+// the current page of wd, with fetch, so that they carry the cookies, the
+// TLS client state and the origin of the page, and are subject to CORS like
+// the requests of the application:
+//
+//	client := &http.Client{Transport: selenium.BrowserTransport(wd)}
+//	resp, err := client.Get("https://api.example.com/me")
+//
+// Redirects are followed by the browser, and Response.Request has the final
+// URL. The bodies are sent whole through script commands, so they are not
+// streamed and are limited by MaxFetchBody, and the request waits for the
+// script timeout of the session at most. The response has no Set-Cookie
+// headers, which the browser hides, and its body is decompressed. Requests
+// with headers that the browser controls return a *FetchHeaderError, and
+// requests that the browser rejects, e.g. by CORS, a *FetchFailedError.
+func BrowserTransport(wd WebDriver, opts ...BrowserTransportOption) http.RoundTripper {
+	t := &browserTransport{wd: wd, maxBody: DefaultMaxFetchBody, credentials: "include"}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *browserTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		defer req.Body.Close()
+	}
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return nil, fmt.Errorf("the browser cannot fetch %q: want an http or https URL", req.URL)
+	}
+	if err := req.Context().Err(); err != nil {
+		return nil, err
+	}
+	method := req.Method
+	if method == "" {
+		method = "GET"
+	}
+	url := req.URL.String()
+
+	var forbidden []string
+	headers := [][]string{}
+	for name, values := range req.Header {
+		name = http.CanonicalHeaderKey(name)
+		if forbiddenHeaders[name] || strings.HasPrefix(name, "Proxy-") || strings.HasPrefix(name, "Sec-") {
+			forbidden = append(forbidden, name)
+			continue
+		}
+		headers = append(headers, []string{name, strings.Join(values, ", ")})
+	}
+	if len(forbidden) > 0 {
+		sort.Strings(forbidden)
+		return nil, &FetchHeaderError{Headers: forbidden}
+	}
+
+	var body *string
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(io.LimitReader(req.Body, t.maxBody+1))
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) > t.maxBody {
+			return nil, &FetchBodyTooLargeError{Method: method, URL: url, Limit: t.maxBody}
+		}
+		if len(data) > 0 {
+			b64 := base64.StdEncoding.EncodeToString(data)
+			body = &b64
+		}
+	}
+
+	data, err := t.wd.ExecuteScriptAsyncRaw(fetchScript, []interface{}{map[string]interface{}{
+		"method":      method,
+		"url":         url,
+		"headers":     headers,
+		"body":        body,
+		"credentials": t.credentials,
+		"max":         t.maxBody,
+	}})
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct {
+		Value struct {
+			Failure    *string
+			TooLarge   bool
+			Status     int
+			StatusText string
+			URL        string
+			Headers    [][]string
+			Body       string
+		}
+	})
+	if err := json.Unmarshal(data, reply); err != nil {
+		return nil, err
+	}
+	v := reply.Value
+	switch {
+	case v.Failure != nil:
+		return nil, &FetchFailedError{Method: method, URL: url, Message: *v.Failure}
+	case v.TooLarge:
+		return nil, &FetchBodyTooLargeError{Method: method, URL: url, Response: true, Limit: t.maxBody}
+	}
+	respBody, err := base64.StdEncoding.DecodeString(v.Body)
+	if err != nil {
+		return nil, fmt.Errorf("decoding the body of the response to %s %s: %v", method, url, err)
+	}
+
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", v.Status, v.StatusText),
+		StatusCode:    v.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(respBody)),
+		ContentLength: int64(len(respBody)),
+		Request:       req,
+	}
+	for _, h := range v.Headers {
+		if len(h) == 2 {
+			resp.Header.Add(h[0], h[1])
+		}
+	}
+	// The browser has decoded the body.
+	if resp.Header.Get("Content-Encoding") != "" {
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.Uncompressed = true
+	}
+	if v.URL != "" && v.URL != url {
+		final := req.Clone(req.Context())
+		if final.URL, err = final.URL.Parse(v.URL); err != nil {
+			return nil, err
+		}
+		resp.Request = final
+	}
+	return resp, nil
+}
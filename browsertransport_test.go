@@ -0,0 +1,97 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestBrowserTransport(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var fetched map[string]interface{}
+	reply := map[string]interface{}{
+		"status":     201,
+		"statusText": "Created",
+		"url":        "https://api.example.com/items/1",
+		"headers":    [][]string{{"content-type", "application/octet-stream"}, {"content-encoding", "gzip"}, {"content-length", "20"}},
+		"body":       base64.StdEncoding.EncodeToString([]byte{0, 1, 2, 0xff}),
+	}
+	d.handle("POST", "/execute/async", func(body []byte) (int, interface{}) {
+		var params struct{ Args []map[string]interface{} }
+		json.Unmarshal(body, &params)
+		fetched = params.Args[0]
+		return http.StatusOK, reply
+	})
+	wd := d.newRemote(t)
+	client := &http.Client{Transport: BrowserTransport(wd, FetchCredentials("same-origin"))}
+
+	req, err := http.NewRequest("POST", "https://api.example.com/items", strings.NewReader("\x00binary\xff"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Token", "secret")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do() returned error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 201 || resp.Status != "201 Created" || string(body) != "\x00\x01\x02\xff" {
+		t.Errorf("client.Do() returned %q with body %q, want %q with body %q", resp.Status, body, "201 Created", "\x00\x01\x02\xff")
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/octet-stream" {
+		t.Errorf("the response has Content-Type %q, want %q", got, "application/octet-stream")
+	}
+	if !resp.Uncompressed || resp.Header.Get("Content-Encoding") != "" || resp.ContentLength != 4 {
+		t.Errorf("the response of the browser, which decodes the body, has Uncompressed %t, Content-Encoding %q and length %d", resp.Uncompressed, resp.Header.Get("Content-Encoding"), resp.ContentLength)
+	}
+	if got := resp.Request.URL.String(); got != "https://api.example.com/items/1" {
+		t.Errorf("resp.Request.URL = %q, want the URL after redirects", got)
+	}
+	if fetched["method"] != "POST" || fetched["url"] != "https://api.example.com/items" || fetched["credentials"] != "same-origin" {
+		t.Errorf("the script was passed %v", fetched)
+	}
+	if got, _ := base64.StdEncoding.DecodeString(fetched["body"].(string)); string(got) != "\x00binary\xff" {
+		t.Errorf("the script was passed the body %q, want %q", got, "\x00binary\xff")
+	}
+	if h, _ := json.Marshal(fetched["headers"]); string(h) != `[["X-Token","secret"]]` {
+		t.Errorf("the script was passed the headers %s", h)
+	}
+
+	reply = map[string]interface{}{"failure": "Failed to fetch"}
+	_, err = client.Get("https://other.example.com/")
+	var failed *FetchFailedError
+	if !errors.As(err, &failed) || failed.Message != "Failed to fetch" {
+		t.Errorf("client.Get() of a request blocked by CORS returned error %v, want a *FetchFailedError", err)
+	}
+
+	reply = map[string]interface{}{"tooLarge": true}
+	_, err = client.Get("https://api.example.com/huge")
+	var tooLarge *FetchBodyTooLargeError
+	if !errors.As(err, &tooLarge) || !tooLarge.Response || tooLarge.Limit != DefaultMaxFetchBody {
+		t.Errorf("client.Get() of a large response returned error %v, want a *FetchBodyTooLargeError", err)
+	}
+
+	calls := d.count("POST", "/execute/async")
+	small := &http.Client{Transport: BrowserTransport(wd, MaxFetchBody(4))}
+	_, err = small.Post("https://api.example.com/items", "text/plain", strings.NewReader("12345"))
+	if !errors.As(err, &tooLarge) || tooLarge.Response || tooLarge.Limit != 4 {
+		t.Errorf("client.Post() of a large body returned error %v, want a *FetchBodyTooLargeError", err)
+	}
+
+	req, _ = http.NewRequest("GET", "https://api.example.com/", nil)
+	req.Header.Set("Cookie", "a=b")
+	req.Header.Set("Sec-Fetch-Mode", "cors")
+	_, err = client.Do(req)
+	var forbidden *FetchHeaderError
+	if !errors.As(err, &forbidden) || strings.Join(forbidden.Headers, ",") != "Cookie,Sec-Fetch-Mode" {
+		t.Errorf("client.Do() with forbidden headers returned error %v, want a *FetchHeaderError", err)
+	}
+	if n := d.count("POST", "/execute/async"); n != calls {
+		t.Errorf("requests that cannot be fetched ran %d scripts", n-calls)
+	}
+}
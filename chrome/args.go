@@ -0,0 +1,74 @@
+package chrome
+
+import "strings"
+
+// listSwitches are the Chrome switches whose value is a comma-separated list.
+// Chrome only honors the last occurrence of a switch, so values for these are
+// merged into a single argument rather than appended.
+var listSwitches = map[string]bool{
+	"--disable-features":       true,
+	"--enable-features":        true,
+	"--disable-blink-features": true,
+	"--enable-blink-features":  true,
+}
+
+// switchName returns the name of a command-line switch, without its value.
+func switchName(arg string) string {
+	if i := strings.Index(arg, "="); i >= 0 {
+		return arg[:i]
+	}
+	return arg
+}
+
+// addDefaultArgs adds command-line arguments to c.Args, leaving alone the
+// switches that are already set. List-valued switches are merged instead.
+func (c *Capabilities) addDefaultArgs(args ...string) {
+	for _, arg := range args {
+		name := switchName(arg)
+		i := -1
+		for j, existing := range c.Args {
+			if switchName(existing) == name {
+				i = j
+			}
+		}
+		switch {
+		case i < 0:
+			c.Args = append(c.Args, arg)
+		case listSwitches[name]:
+			c.Args[i] = mergeListSwitch(c.Args[i], arg)
+		}
+	}
+}
+
+// mergeListSwitch returns a switch with the values of both a and b, which
+// must be occurrences of the same list-valued switch.
+func mergeListSwitch(a, b string) string {
+	name := switchName(a)
+	var values []string
+	seen := make(map[string]bool)
+	for _, arg := range []string{a, b} {
+		for _, v := range strings.Split(strings.TrimPrefix(arg, name+"="), ",") {
+			if v == "" || v == name || seen[v] {
+				continue
+			}
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	return name + "=" + strings.Join(values, ",")
+}
+
+// setSwitch sets a command-line switch to value, replacing any occurrence of
+// the switch in c.Args. An empty value removes the switch.
+func (c *Capabilities) setSwitch(name, value string) {
+	var args []string
+	for _, arg := range c.Args {
+		if switchName(arg) != name {
+			args = append(args, arg)
+		}
+	}
+	if value != "" {
+		args = append(args, name+"="+value)
+	}
+	c.Args = args
+}
@@ -0,0 +1,77 @@
+package chrome
+
+import (
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestAddDefaultArgs(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		args, add []string
+		want      []string
+	}{
+		{
+			desc: "new switches are appended",
+			args: []string{"--headless"},
+			add:  []string{"--hide-scrollbars", "--force-device-scale-factor=1"},
+			want: []string{"--headless", "--hide-scrollbars", "--force-device-scale-factor=1"},
+		},
+		{
+			desc: "existing switches take precedence",
+			args: []string{"--force-device-scale-factor=2", "--hide-scrollbars"},
+			add:  []string{"--force-device-scale-factor=1", "--hide-scrollbars"},
+			want: []string{"--force-device-scale-factor=2", "--hide-scrollbars"},
+		},
+		{
+			desc: "list switches are merged",
+			args: []string{"--disable-blink-features=AutomationControlled", "--headless"},
+			add:  []string{"--disable-blink-features=ScrollAnimator,AutomationControlled"},
+			want: []string{"--disable-blink-features=AutomationControlled,ScrollAnimator", "--headless"},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			c := Capabilities{Args: test.args}
+			c.addDefaultArgs(test.add...)
+			if !reflect.DeepEqual(c.Args, test.want) {
+				t.Errorf("c.addDefaultArgs(%q) resulted in %q, want %q", test.add, c.Args, test.want)
+			}
+		})
+	}
+}
+
+func TestForScreenshotStabilityIsIdempotent(t *testing.T) {
+	var c Capabilities
+	c.ForScreenshotStability()
+	want := append([]string(nil), c.Args...)
+	c.ForScreenshotStability()
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("after a second call to c.ForScreenshotStability(), c.Args = %q, want %q", c.Args, want)
+	}
+}
+
+func TestDisableTabThrottlingComposes(t *testing.T) {
+	c := Capabilities{Args: []string{"--disable-features=Translate", "--disable-renderer-backgrounding"}}
+	c.ForScreenshotStability()
+	c.DisableTabThrottling()
+	c.DisableTabThrottling()
+
+	count := make(map[string]int)
+	for _, arg := range c.Args {
+		count[switchName(arg)]++
+	}
+	for name, n := range count {
+		if n > 1 {
+			t.Errorf("c.Args has %d %s arguments, want 1: %q", n, name, c.Args)
+		}
+	}
+	for _, want := range []string{"--disable-background-timer-throttling", "--disable-lcd-text"} {
+		if count[want] != 1 {
+			t.Errorf("c.Args = %q, want it to contain %s", c.Args, want)
+		}
+	}
+	if got, want := c.Args[0], "--disable-features=Translate,HighEfficiencyModeAvailable"; runtime.GOOS != "windows" && got != want {
+		t.Errorf("c.Args[0] = %q, want %q", got, want)
+	}
+}
@@ -14,7 +14,7 @@ import (
 	"io"
 	"os"
 
-	"github.com/LoveOyy/selenium/internal/zip"
+	"github.com/LoveOyy/selenium/archive"
 	"github.com/golang/protobuf/proto"
 	"github.com/mediabuyerbot/go-crx3/pb"
 )
@@ -51,7 +51,9 @@ type Capabilities struct {
 	// user profile in use.
 	Prefs map[string]interface{} `json:"prefs,omitempty"`
 	// Detatch, if true, will cause the browser to not be killed when
-	// ChromeDriver quits if the session was not terminated.
+	// ChromeDriver quits if the session was not terminated. WebDriver.Quit
+	// then leaves the browser running; use WebDriver.QuitAndCloseBrowser to
+	// close it.
 	Detach *bool `json:"detach,omitempty"`
 	// DebuggerAddr is the TCP/IP address of a Chrome debugger server to connect
 	// to.
@@ -71,8 +73,41 @@ type Capabilities struct {
 	AndroidPackage string `json:"androidPackage,omitempty"`
 	// Use W3C mode, if true.
 	W3C bool `json:"w3c"`
+	// KeyMode selects the capability keys under which the options are sent to
+	// ChromeDriver. It is not itself sent.
+	KeyMode KeyMode `json:"-"`
+
+	// initScripts are evaluated in every new document once the session has
+	// been created. See SeedLocalStorage and AddOriginTrialToken.
+	initScripts []string
+	// proxyURL and proxyBypass are the settings passed to SetProxy.
+	proxyURL    string
+	proxyBypass []string
+	// policies are the policies written by WritePolicies.
+	policies map[string]interface{}
 }
 
+// KeyMode selects the capability keys under which the Chrome options are sent
+// to the remote end. Whatever the mode, DeprecatedCapabilitiesKey is only ever
+// sent in the legacy "desiredCapabilities" section of a new session request,
+// where W3C-compliant remote ends ignore it.
+type KeyMode int
+
+const (
+	// ModernKey, the default, sends the options only under CapabilitiesKey.
+	ModernKey KeyMode = iota
+	// BothKeys sends the options under both CapabilitiesKey and
+	// DeprecatedCapabilitiesKey, which doubles their size in the request.
+	BothKeys
+	// LegacyKey sends the options only under DeprecatedCapabilitiesKey, which
+	// is the only key read by ChromeDriver 2.x releases.
+	LegacyKey
+	// AutoKey queries the version of ChromeDriver before creating the session
+	// and uses LegacyKey for 2.x releases and ModernKey otherwise. If the
+	// version cannot be determined, the options are sent under both keys.
+	AutoKey
+)
+
 // TODO(minusnine): https://bugs.chromium.org/p/chromedriver/issues/detail?id=1625
 // mentions "experimental options". Implement that.
 
@@ -181,7 +216,7 @@ func NewExtension(basePath string) ([]byte, *rsa.PrivateKey, error) {
 // NewExtensionWithKey creates the payload of a Chrome extension file which is
 // signed by the provided private key.
 func NewExtensionWithKey(basePath string, key *rsa.PrivateKey) ([]byte, error) {
-	archiveBuf, err := zip.New(basePath)
+	archiveBuf, err := archive.Dir(basePath, archive.ForCRX())
 	if err != nil {
 		return nil, err
 	}
@@ -249,7 +284,7 @@ func crx3Header(archiveData []byte, key *rsa.PrivateKey) ([]byte, error) {
 
 	header := &pb.CrxFileHeader{
 		Sha256WithRsa: []*pb.AsymmetricKeyProof{
-			&pb.AsymmetricKeyProof{
+			{
 				PublicKey: pubKey,
 				Signature: signature,
 			},
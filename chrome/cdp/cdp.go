@@ -0,0 +1,439 @@
+// Package cdp provides a client for the Chrome DevTools Protocol, connected
+// to the browser of a ChromeDriver session through the DevTools endpoint that
+// ChromeDriver reports, so that WebDriver and CDP commands can be mixed on
+// the same browser:
+//
+//	info, _ := chrome.SessionInfo(wd.SessionCapabilities())
+//	conn, err := cdp.Dial(ctx, info.DebuggerAddress)
+//	...
+//	defer conn.Close()
+//	err = conn.Network().SetExtraHTTPHeaders(ctx, map[string]string{"X-Test": "1"})
+//
+// To attach to an existing browser, start the session with
+// chrome.Capabilities.DebuggerAddr set to the same address.
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"golang.org/x/net/websocket"
+)
+
+// DialTimeout limits the time that Dial and DialTarget take to connect when
+// their context has no deadline.
+var DialTimeout = 10 * time.Second
+
+// ErrClosed is returned by the commands sent on, or pending on, a closed
+// connection.
+var ErrClosed = errors.New("cdp: connection closed")
+
+// ErrNoTarget is returned by Dial and DialSession when the browser has no
+// page to connect to.
+var ErrNoTarget = errors.New("cdp: no page target")
+
+// Error is an error returned by the browser for a command.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    string `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Data != "" {
+		return fmt.Sprintf("cdp: %s (%d): %s", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("cdp: %s (%d)", e.Message, e.Code)
+}
+
+// Target is an entry of the /json/list endpoint of the browser.
+type Target struct {
+	// ID identifies the target. For pages, ChromeDriver uses it as the
+	// handle of the window, so that the target of the current window of a
+	// session is the one whose ID is its WindowHandle.
+	ID string `json:"id"`
+	// Type is e.g. "page", "iframe", "service_worker" or "background_page".
+	Type                 string `json:"type"`
+	Title                string `json:"title"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// Targets lists the targets of the browser whose DevTools endpoint is at
+// debuggerAddr, e.g. "localhost:9222".
+func Targets(ctx context.Context, debuggerAddr string) ([]Target, error) {
+	ctx, cancel := withDialTimeout(ctx)
+	defer cancel()
+	req, err := http.NewRequest("GET", "http://"+debuggerAddr+"/json/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("cdp: listing the targets: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cdp: listing the targets: %s", resp.Status)
+	}
+	var targets []Target
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("cdp: listing the targets: %v", err)
+	}
+	return targets, nil
+}
+
+// Dial connects to the first page of the browser whose DevTools endpoint is
+// at debuggerAddr. Use Targets and DialTarget to choose another target, such
+// as the current window of a session.
+func Dial(ctx context.Context, debuggerAddr string) (*Conn, error) {
+	targets, err := Targets(ctx, debuggerAddr)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		if t.Type == "page" && t.WebSocketDebuggerURL != "" {
+			return DialTarget(ctx, t)
+		}
+	}
+	return nil, ErrNoTarget
+}
+
+// DialSession connects to the first page of the browser of the ChromeDriver
+// session with the capabilities caps, such as those returned by
+// selenium.WebDriver.SessionCapabilities. It returns an error if the driver
+// did not report the debuggerAddress of the browser.
+func DialSession(ctx context.Context, caps map[string]interface{}) (*Conn, error) {
+	info, err := chrome.SessionInfo(caps)
+	if err != nil {
+		return nil, err
+	}
+	if info.DebuggerAddress == "" {
+		return nil, errors.New("cdp: the session has no debuggerAddress")
+	}
+	return Dial(ctx, info.DebuggerAddress)
+}
+
+// DialTarget connects to a target of the browser.
+func DialTarget(ctx context.Context, target Target) (*Conn, error) {
+	if target.WebSocketDebuggerURL == "" {
+		return nil, fmt.Errorf("cdp: target %s has no webSocketDebuggerUrl; is another client attached?", target.ID)
+	}
+	config, err := websocket.NewConfig(target.WebSocketDebuggerURL, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	dialer := &net.Dialer{Timeout: DialTimeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	config.Dialer = dialer
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("cdp: connecting to %s: %v", target.URL, err)
+	}
+	c := &Conn{
+		Target:  target,
+		ws:      ws,
+		pending: make(map[int64]chan reply),
+		subs:    make(map[*Listener]bool),
+		done:    make(chan struct{}),
+	}
+	go c.read()
+	return c, nil
+}
+
+// withDialTimeout bounds ctx with DialTimeout if it has no deadline.
+func withDialTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DialTimeout)
+}
+
+// Event is an event sent by the browser, once its domain is enabled.
+type Event struct {
+	// Method is the name of the event, e.g. "Network.requestWillBeSent".
+	Method string
+	// Params are the parameters of the event, in JSON.
+	Params json.RawMessage
+}
+
+// Decode decodes the parameters of the event into v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Params, v)
+}
+
+// reply is a message received from the browser: the reply to a command, or
+// an event.
+type reply struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *Error          `json:"error"`
+}
+
+// Conn is a connection to a DevTools target. Its methods may be called from
+// several goroutines.
+type Conn struct {
+	// Target is the target of the connection.
+	Target Target
+
+	ws     *websocket.Conn
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan reply
+	subs    map[*Listener]bool
+	err     error
+	done    chan struct{}
+}
+
+// read dispatches the messages received until the connection is closed.
+func (c *Conn) read() {
+	var err error
+	for {
+		var data []byte
+		if err = websocket.Message.Receive(c.ws, &data); err != nil {
+			break
+		}
+		var m reply
+		if json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		c.mu.Lock()
+		if m.ID != 0 {
+			if ch, ok := c.pending[m.ID]; ok {
+				delete(c.pending, m.ID)
+				ch <- m
+			}
+		} else if m.Method != "" {
+			e := Event{Method: m.Method, Params: m.Params}
+			for l := range c.subs {
+				if l.matches(e.Method) {
+					l.push(e)
+				}
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = fmt.Errorf("%w: %v", ErrClosed, err)
+	}
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	for l := range c.subs {
+		l.end()
+	}
+	c.subs = nil
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// Execute sends the command method with params, e.g. "Page.reload", and
+// decodes its result into result, unless result is nil. It returns an *Error
+// if the browser reports an error, and ctx.Err() if ctx is done first.
+func (c *Conn) Execute(ctx context.Context, method string, params, result interface{}) error {
+	if params == nil {
+		params = struct{}{}
+	}
+	p, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan reply, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+	forget := func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}
+
+	data, err := json.Marshal(struct {
+		ID     int64           `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}{id, method, p})
+	if err != nil {
+		forget()
+		return err
+	}
+	c.sendMu.Lock()
+	deadline, _ := ctx.Deadline()
+	c.ws.SetWriteDeadline(deadline)
+	err = websocket.Message.Send(c.ws, string(data))
+	c.sendMu.Unlock()
+	if err != nil {
+		forget()
+		return fmt.Errorf("cdp: sending %s: %v", method, err)
+	}
+
+	select {
+	case m, ok := <-ch:
+		if !ok {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			return c.err
+		}
+		if m.Error != nil {
+			return m.Error
+		}
+		if result == nil || len(m.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(m.Result, result)
+	case <-ctx.Done():
+		forget()
+		return ctx.Err()
+	}
+}
+
+// Close closes the connection. The browser and the target stay open.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = ErrClosed
+	}
+	c.mu.Unlock()
+	err := c.ws.Close()
+	<-c.done
+	return err
+}
+
+// Listen returns a listener of the given events, or domains of events, e.g.
+// "Network" for all the events of the Network domain. The browser only sends
+// the events of the domains that are enabled, e.g. with Network.Enable.
+func (c *Conn) Listen(events ...string) *Listener {
+	l := &Listener{
+		conn:   c,
+		events: events,
+		notify: make(chan struct{}, 1),
+		out:    make(chan Event),
+		stop:   make(chan struct{}),
+	}
+	l.Events = l.out
+	c.mu.Lock()
+	if c.err != nil {
+		l.ended = true
+	} else {
+		c.subs[l] = true
+	}
+	c.mu.Unlock()
+	go l.deliver()
+	return l
+}
+
+// Listener delivers the events listened to with Conn.Listen.
+type Listener struct {
+	// Events receives the events, and is closed when the listener or the
+	// connection is closed. The events are queued until they are received,
+	// so that reading the channel late loses none.
+	Events <-chan Event
+
+	conn   *Conn
+	events []string
+
+	mu     sync.Mutex
+	queue  []Event
+	ended  bool
+	notify chan struct{}
+	out    chan Event
+	stop   chan struct{}
+	once   sync.Once
+}
+
+// matches reports whether the listener is for the event method.
+func (l *Listener) matches(method string) bool {
+	if len(l.events) == 0 {
+		return true
+	}
+	for _, e := range l.events {
+		if e == method || strings.HasPrefix(method, e+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Listener) push(e Event) {
+	l.mu.Lock()
+	l.queue = append(l.queue, e)
+	l.mu.Unlock()
+	l.wake()
+}
+
+func (l *Listener) end() {
+	l.mu.Lock()
+	l.ended = true
+	l.mu.Unlock()
+	l.wake()
+}
+
+func (l *Listener) wake() {
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the queued events to Events.
+func (l *Listener) deliver() {
+	defer close(l.out)
+	for {
+		l.mu.Lock()
+		queue, ended := l.queue, l.ended
+		l.queue = nil
+		l.mu.Unlock()
+		for _, e := range queue {
+			select {
+			case l.out <- e:
+			case <-l.stop:
+				return
+			}
+		}
+		if len(queue) > 0 {
+			continue
+		}
+		if ended {
+			return
+		}
+		select {
+		case <-l.notify:
+		case <-l.stop:
+			return
+		}
+	}
+}
+
+// Close stops the listener and closes Events, dropping the events not
+// received yet.
+func (l *Listener) Close() {
+	l.once.Do(func() { close(l.stop) })
+	l.conn.mu.Lock()
+	delete(l.conn.subs, l)
+	l.conn.mu.Unlock()
+}
@@ -0,0 +1,153 @@
+package cdp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeBrowser serves a DevTools endpoint with one page target, which replies
+// to commands with reply and sends an event after Network.enable.
+type fakeBrowser struct {
+	*httptest.Server
+	commands chan map[string]interface{}
+}
+
+func newFakeBrowser(t *testing.T) *fakeBrowser {
+	b := &fakeBrowser{commands: make(chan map[string]interface{}, 16)}
+	mux := http.NewServeMux()
+	b.Server = httptest.NewServer(mux)
+	t.Cleanup(b.Close)
+	ws := "ws" + strings.TrimPrefix(b.URL, "http")
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]Target{
+			{ID: "W1", Type: "service_worker", URL: "chrome-extension://x/bg.js", WebSocketDebuggerURL: ws + "/devtools/worker"},
+			{ID: "P1", Type: "page", URL: "about:blank", WebSocketDebuggerURL: ws + "/devtools/page/P1"},
+		})
+	})
+	mux.Handle("/devtools/page/P1", websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			b.commands <- cmd
+			reply := map[string]interface{}{"id": cmd["id"], "result": map[string]interface{}{}}
+			switch cmd["method"] {
+			case "Runtime.evaluate":
+				params := cmd["params"].(map[string]interface{})
+				if params["expression"] == "throw" {
+					reply["result"] = map[string]interface{}{
+						"result":           map[string]interface{}{"type": "object"},
+						"exceptionDetails": map[string]interface{}{"text": "Uncaught", "exception": map[string]interface{}{"description": "Error: boom"}},
+					}
+				} else {
+					reply["result"] = map[string]interface{}{"result": map[string]interface{}{"type": "number", "value": 2}}
+				}
+			case "Page.captureScreenshot":
+				reply["result"] = map[string]interface{}{"data": "iVBORw=="}
+			case "Foo.bar":
+				delete(reply, "result")
+				reply["error"] = map[string]interface{}{"code": -32601, "message": "'Foo.bar' wasn't found"}
+			}
+			websocket.JSON.Send(conn, reply)
+			if cmd["method"] == "Network.enable" {
+				websocket.JSON.Send(conn, map[string]interface{}{
+					"method": "Network.requestWillBeSent",
+					"params": map[string]interface{}{"requestId": "r1", "request": map[string]interface{}{"url": "https://example.com/", "method": "GET"}},
+				})
+			}
+		}
+	}))
+	return b
+}
+
+func (b *fakeBrowser) addr() string {
+	return strings.TrimPrefix(b.URL, "http://")
+}
+
+func TestDialSession(t *testing.T) {
+	b := newFakeBrowser(t)
+	caps := map[string]interface{}{
+		"goog:chromeOptions": map[string]interface{}{"debuggerAddress": b.addr()},
+	}
+	conn, err := DialSession(context.Background(), caps)
+	if err != nil {
+		t.Fatalf("DialSession() returned error: %v", err)
+	}
+	defer conn.Close()
+	if conn.Target.ID != "P1" {
+		t.Errorf("DialSession() connected to target %q, want the page P1", conn.Target.ID)
+	}
+
+	if _, err := DialSession(context.Background(), map[string]interface{}{}); err == nil {
+		t.Error("DialSession() without a debuggerAddress returned no error")
+	}
+}
+
+func TestDomains(t *testing.T) {
+	b := newFakeBrowser(t)
+	conn, err := Dial(context.Background(), b.addr())
+	if err != nil {
+		t.Fatalf("Dial() returned error: %v", err)
+	}
+	defer conn.Close()
+	ctx := context.Background()
+
+	requests := conn.Listen("Network")
+	defer requests.Close()
+	if err := conn.Network().Enable(ctx); err != nil {
+		t.Fatalf("Network().Enable() returned error: %v", err)
+	}
+	select {
+	case e := <-requests.Events:
+		var r Request
+		if err := e.Decode(&r); err != nil || e.Method != "Network.requestWillBeSent" || r.Request.URL != "https://example.com/" {
+			t.Errorf("received event %s %s, %v", e.Method, e.Params, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("received no Network event")
+	}
+	if err := conn.Network().SetExtraHTTPHeaders(ctx, map[string]string{"X-Test": "1"}); err != nil {
+		t.Errorf("Network().SetExtraHTTPHeaders() returned error: %v", err)
+	}
+
+	var v int
+	if err := conn.Runtime().Evaluate(ctx, "1 + 1", &v); err != nil || v != 2 {
+		t.Errorf("Runtime().Evaluate() = %d, %v, want 2", v, err)
+	}
+	var exception *ExceptionError
+	if err := conn.Runtime().Evaluate(ctx, "throw", nil); !errors.As(err, &exception) || exception.Exception.Description != "Error: boom" {
+		t.Errorf("Runtime().Evaluate() of a throwing expression returned error %v, want an *ExceptionError", err)
+	}
+
+	png, err := conn.Page().CaptureScreenshot(ctx)
+	if err != nil || len(png) == 0 {
+		t.Errorf("Page().CaptureScreenshot() = %d bytes, %v", len(png), err)
+	}
+
+	var cdpErr *Error
+	if err := conn.Execute(ctx, "Foo.bar", nil, nil); !errors.As(err, &cdpErr) || cdpErr.Code != -32601 {
+		t.Errorf("conn.Execute() of an unknown command returned error %v, want an *Error", err)
+	}
+
+	conn.Close()
+	if err := conn.Page().Reload(ctx, false); !errors.Is(err, ErrClosed) {
+		t.Errorf("Page().Reload() on a closed connection returned error %v, want ErrClosed", err)
+	}
+	select {
+	case _, ok := <-requests.Events:
+		if ok {
+			t.Error("the listener received an event after the connection was closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the events of the listener are not closed after the connection was closed")
+	}
+}
@@ -0,0 +1,232 @@
+package cdp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// Network wraps the commands of the Network domain.
+type Network struct{ c *Conn }
+
+// Network returns the commands of the Network domain.
+func (c *Conn) Network() Network { return Network{c} }
+
+// Enable makes the browser send the events of the Network domain, such as
+// Network.requestWillBeSent and Network.responseReceived.
+func (n Network) Enable(ctx context.Context) error {
+	return n.c.Execute(ctx, "Network.enable", nil, nil)
+}
+
+// Disable stops the events of the Network domain.
+func (n Network) Disable(ctx context.Context) error {
+	return n.c.Execute(ctx, "Network.disable", nil, nil)
+}
+
+// SetExtraHTTPHeaders adds headers to the requests of the target. Enable
+// must have been called.
+func (n Network) SetExtraHTTPHeaders(ctx context.Context, headers map[string]string) error {
+	return n.c.Execute(ctx, "Network.setExtraHTTPHeaders", map[string]interface{}{"headers": headers}, nil)
+}
+
+// SetCacheDisabled turns the cache of the target off or on.
+func (n Network) SetCacheDisabled(ctx context.Context, disabled bool) error {
+	return n.c.Execute(ctx, "Network.setCacheDisabled", map[string]interface{}{"cacheDisabled": disabled}, nil)
+}
+
+// SetBlockedURLs blocks the requests whose URL matches one of the patterns,
+// in which "*" matches any sequence of characters.
+func (n Network) SetBlockedURLs(ctx context.Context, patterns []string) error {
+	if patterns == nil {
+		patterns = []string{}
+	}
+	return n.c.Execute(ctx, "Network.setBlockedURLs", map[string]interface{}{"urls": patterns}, nil)
+}
+
+// ClearBrowserCookies deletes the cookies of all the origins.
+func (n Network) ClearBrowserCookies(ctx context.Context) error {
+	return n.c.Execute(ctx, "Network.clearBrowserCookies", nil, nil)
+}
+
+// GetResponseBody returns the body of the response to a request, identified
+// by the requestId of its Network events.
+func (n Network) GetResponseBody(ctx context.Context, requestID string) ([]byte, error) {
+	var r struct {
+		Body          string `json:"body"`
+		Base64Encoded bool   `json:"base64Encoded"`
+	}
+	if err := n.c.Execute(ctx, "Network.getResponseBody", map[string]interface{}{"requestId": requestID}, &r); err != nil {
+		return nil, err
+	}
+	if r.Base64Encoded {
+		return base64.StdEncoding.DecodeString(r.Body)
+	}
+	return []byte(r.Body), nil
+}
+
+// Request is the parameters of the Network.requestWillBeSent event, in part.
+type Request struct {
+	RequestID string `json:"requestId"`
+	Request   struct {
+		URL     string                 `json:"url"`
+		Method  string                 `json:"method"`
+		Headers map[string]interface{} `json:"headers"`
+	} `json:"request"`
+	// Type is the type of resource, e.g. "Document" or "XHR".
+	Type string `json:"type"`
+}
+
+// Response is the parameters of the Network.responseReceived event, in part.
+type Response struct {
+	RequestID string `json:"requestId"`
+	Response  struct {
+		URL      string                 `json:"url"`
+		Status   int                    `json:"status"`
+		MimeType string                 `json:"mimeType"`
+		Headers  map[string]interface{} `json:"headers"`
+	} `json:"response"`
+	Type string `json:"type"`
+}
+
+// Page wraps the commands of the Page domain.
+type Page struct{ c *Conn }
+
+// Page returns the commands of the Page domain.
+func (c *Conn) Page() Page { return Page{c} }
+
+// Enable makes the browser send the events of the Page domain, such as
+// Page.loadEventFired.
+func (p Page) Enable(ctx context.Context) error {
+	return p.c.Execute(ctx, "Page.enable", nil, nil)
+}
+
+// Disable stops the events of the Page domain.
+func (p Page) Disable(ctx context.Context) error {
+	return p.c.Execute(ctx, "Page.disable", nil, nil)
+}
+
+// Navigate navigates the target to url, without waiting for the page to
+// load, and returns the ID of its frame.
+func (p Page) Navigate(ctx context.Context, url string) (string, error) {
+	var r struct {
+		FrameID   string `json:"frameId"`
+		ErrorText string `json:"errorText"`
+	}
+	if err := p.c.Execute(ctx, "Page.navigate", map[string]interface{}{"url": url}, &r); err != nil {
+		return "", err
+	}
+	if r.ErrorText != "" {
+		return r.FrameID, fmt.Errorf("cdp: navigating to %s: %s", url, r.ErrorText)
+	}
+	return r.FrameID, nil
+}
+
+// Reload reloads the page, bypassing the cache if ignoreCache is true.
+func (p Page) Reload(ctx context.Context, ignoreCache bool) error {
+	return p.c.Execute(ctx, "Page.reload", map[string]interface{}{"ignoreCache": ignoreCache}, nil)
+}
+
+// AddScriptToEvaluateOnNewDocument makes the browser run source in every new
+// document of the target before its own scripts, and returns the identifier
+// of the script for RemoveScriptToEvaluateOnNewDocument.
+func (p Page) AddScriptToEvaluateOnNewDocument(ctx context.Context, source string) (string, error) {
+	var r struct {
+		Identifier string `json:"identifier"`
+	}
+	err := p.c.Execute(ctx, "Page.addScriptToEvaluateOnNewDocument", map[string]interface{}{"source": source}, &r)
+	return r.Identifier, err
+}
+
+// RemoveScriptToEvaluateOnNewDocument removes a script added with
+// AddScriptToEvaluateOnNewDocument.
+func (p Page) RemoveScriptToEvaluateOnNewDocument(ctx context.Context, identifier string) error {
+	return p.c.Execute(ctx, "Page.removeScriptToEvaluateOnNewDocument", map[string]interface{}{"identifier": identifier}, nil)
+}
+
+// CaptureScreenshot returns a PNG screenshot of the viewport.
+func (p Page) CaptureScreenshot(ctx context.Context) ([]byte, error) {
+	var r struct {
+		Data string `json:"data"`
+	}
+	if err := p.c.Execute(ctx, "Page.captureScreenshot", map[string]interface{}{"format": "png"}, &r); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(r.Data)
+}
+
+// Runtime wraps the commands of the Runtime domain.
+type Runtime struct{ c *Conn }
+
+// Runtime returns the commands of the Runtime domain.
+func (c *Conn) Runtime() Runtime { return Runtime{c} }
+
+// Enable makes the browser send the events of the Runtime domain, such as
+// Runtime.consoleAPICalled and Runtime.exceptionThrown, starting with the
+// console messages logged so far.
+func (r Runtime) Enable(ctx context.Context) error {
+	return r.c.Execute(ctx, "Runtime.enable", nil, nil)
+}
+
+// Disable stops the events of the Runtime domain.
+func (r Runtime) Disable(ctx context.Context) error {
+	return r.c.Execute(ctx, "Runtime.disable", nil, nil)
+}
+
+// ExceptionError is returned by Evaluate when the expression throws.
+type ExceptionError struct {
+	Text         string `json:"text"`
+	LineNumber   int    `json:"lineNumber"`
+	ColumnNumber int    `json:"columnNumber"`
+	Exception    struct {
+		Description string `json:"description"`
+	} `json:"exception"`
+}
+
+func (e *ExceptionError) Error() string {
+	if e.Exception.Description != "" {
+		return "cdp: " + e.Exception.Description
+	}
+	return "cdp: " + e.Text
+}
+
+// Evaluate evaluates the JavaScript expression in the page, awaiting it if
+// it is a promise, and decodes its value, which must be serializable to
+// JSON, into result, unless result is nil.
+func (r Runtime) Evaluate(ctx context.Context, expression string, result interface{}) error {
+	var reply struct {
+		Result struct {
+			Value json.RawMessage `json:"value"`
+		} `json:"result"`
+		ExceptionDetails *ExceptionError `json:"exceptionDetails"`
+	}
+	err := r.c.Execute(ctx, "Runtime.evaluate", map[string]interface{}{
+		"expression":    expression,
+		"returnByValue": true,
+		"awaitPromise":  true,
+	}, &reply)
+	if err != nil {
+		return err
+	}
+	if reply.ExceptionDetails != nil {
+		return reply.ExceptionDetails
+	}
+	if result == nil || len(reply.Result.Value) == 0 {
+		return nil
+	}
+	return json.Unmarshal(reply.Result.Value, result)
+}
+
+// ConsoleMessage is the parameters of the Runtime.consoleAPICalled event, in
+// part.
+type ConsoleMessage struct {
+	// Type is the method of console, e.g. "log", "warning" or "error".
+	Type string `json:"type"`
+	Args []struct {
+		Type        string          `json:"type"`
+		Value       json.RawMessage `json:"value"`
+		Description string          `json:"description"`
+	} `json:"args"`
+	// Timestamp is in milliseconds since the epoch.
+	Timestamp float64 `json:"timestamp"`
+}
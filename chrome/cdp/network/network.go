@@ -0,0 +1,446 @@
+// Package network intercepts the HTTP requests of a Chrome target with the
+// Fetch domain of the DevTools protocol, so that a test can let them through
+// modified, block them or answer them with a mock response:
+//
+//	conn, err := cdp.DialSession(ctx, wd.SessionCapabilities())
+//	...
+//	defer conn.Close()
+//	i := network.NewInterceptor(conn)
+//	i.Handle(network.Pattern{URL: "*/api/user"}, func(ctx context.Context, r *network.Request) network.Action {
+//		return network.Fulfill(network.Response{Body: []byte(`{"name":"gopher"}`)})
+//	})
+//	i.Handle(network.Pattern{ResourceType: "Image"}, func(context.Context, *network.Request) network.Action {
+//		return network.Block()
+//	})
+//	if err := i.Start(ctx); err != nil {
+//		...
+//	}
+//	defer i.Stop(ctx)
+//
+// While the interceptor is started, the browser pauses the requests that
+// match a pattern until their handler returns.
+package network
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/LoveOyy/selenium/chrome/cdp"
+)
+
+// Stage is the stage at which a request is intercepted.
+type Stage string
+
+// Stages of interception.
+const (
+	// StageRequest intercepts requests before they are sent.
+	StageRequest Stage = "Request"
+	// StageResponse intercepts requests once the headers of their response
+	// are received, before the browser reads it.
+	StageResponse Stage = "Response"
+)
+
+// ErrorReason is the network error with which a request fails, as reported
+// to the page.
+type ErrorReason string
+
+// Network errors of Fail.
+const (
+	ReasonFailed               ErrorReason = "Failed"
+	ReasonAborted              ErrorReason = "Aborted"
+	ReasonTimedOut             ErrorReason = "TimedOut"
+	ReasonAccessDenied         ErrorReason = "AccessDenied"
+	ReasonConnectionRefused    ErrorReason = "ConnectionRefused"
+	ReasonNameNotResolved      ErrorReason = "NameNotResolved"
+	ReasonInternetDisconnected ErrorReason = "InternetDisconnected"
+	ReasonBlockedByClient      ErrorReason = "BlockedByClient"
+)
+
+// Pattern selects the requests passed to a handler.
+type Pattern struct {
+	// URL matches the URL of the request, in which "*" matches any sequence
+	// of characters, "?" any character and a backslash escapes the next
+	// character. Empty matches all the URLs.
+	URL string
+	// ResourceType matches the type of resource, e.g. "Document", "XHR",
+	// "Fetch", "Script" or "Image". Empty matches all the types.
+	ResourceType string
+	// Stage is StageRequest if empty.
+	Stage Stage
+}
+
+func (p Pattern) stage() Stage {
+	if p.Stage == "" {
+		return StageRequest
+	}
+	return p.Stage
+}
+
+// params returns the pattern as a Fetch.RequestPattern.
+func (p Pattern) params() map[string]interface{} {
+	params := map[string]interface{}{"requestStage": p.stage()}
+	if p.URL != "" {
+		params["urlPattern"] = p.URL
+	}
+	if p.ResourceType != "" {
+		params["resourceType"] = p.ResourceType
+	}
+	return params
+}
+
+// matches reports whether the browser paused r for the pattern. The browser
+// does not tell which pattern matched, so the patterns are matched again.
+func (p Pattern) matches(r *Request) bool {
+	return p.stage() == r.Stage &&
+		(p.ResourceType == "" || p.ResourceType == r.ResourceType) &&
+		(p.URL == "" || match(p.URL, r.URL))
+}
+
+// match reports whether s matches the wildcard pattern of Pattern.URL.
+func match(pattern, s string) bool {
+	p, i := 0, 0
+	star, mark := -1, 0
+	for i < len(s) {
+		if p < len(pattern) {
+			switch c := pattern[p]; {
+			case c == '*':
+				star, mark = p, i
+				p++
+				continue
+			case c == '\\' && p+1 < len(pattern):
+				if pattern[p+1] == s[i] {
+					p += 2
+					i++
+					continue
+				}
+			case c == '?' || c == s[i]:
+				p++
+				i++
+				continue
+			}
+		}
+		if star < 0 {
+			return false
+		}
+		// Let the last star match one more character.
+		mark++
+		p, i = star+1, mark
+	}
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// Header is an HTTP header of a request or a response.
+type Header struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Request is a request paused by the browser.
+type Request struct {
+	// ID identifies the request for the commands of the Fetch domain.
+	ID       string
+	URL      string
+	Method   string
+	Headers  map[string]string
+	PostData string
+	// ResourceType is the type of resource, e.g. "Document" or "XHR".
+	ResourceType string
+	// FrameID is the frame that made the request.
+	FrameID string
+	// Stage is the stage at which the request is paused.
+	Stage Stage
+
+	// The status and headers of the response, at StageResponse.
+	// ResponseErrorReason is set instead if the request failed.
+	StatusCode          int
+	StatusText          string
+	ResponseHeaders     []Header
+	ResponseErrorReason ErrorReason
+
+	conn *cdp.Conn
+}
+
+// requestPaused is the parameters of the Fetch.requestPaused event.
+type requestPaused struct {
+	RequestID string `json:"requestId"`
+	Request   struct {
+		URL      string            `json:"url"`
+		Method   string            `json:"method"`
+		Headers  map[string]string `json:"headers"`
+		PostData string            `json:"postData"`
+	} `json:"request"`
+	FrameID             string      `json:"frameId"`
+	ResourceType        string      `json:"resourceType"`
+	ResponseErrorReason ErrorReason `json:"responseErrorReason"`
+	ResponseStatusCode  int         `json:"responseStatusCode"`
+	ResponseStatusText  string      `json:"responseStatusText"`
+	ResponseHeaders     []Header    `json:"responseHeaders"`
+}
+
+// ResponseBody returns the body of the response, at StageResponse.
+func (r *Request) ResponseBody(ctx context.Context) ([]byte, error) {
+	if r.Stage != StageResponse {
+		return nil, errors.New("network: the response of a request is only available at StageResponse")
+	}
+	var reply struct {
+		Body          string `json:"body"`
+		Base64Encoded bool   `json:"base64Encoded"`
+	}
+	if err := r.conn.Execute(ctx, "Fetch.getResponseBody", map[string]interface{}{"requestId": r.ID}, &reply); err != nil {
+		return nil, err
+	}
+	if reply.Base64Encoded {
+		return base64.StdEncoding.DecodeString(reply.Body)
+	}
+	return []byte(reply.Body), nil
+}
+
+// Handler decides what becomes of a paused request. The context is canceled
+// when the interceptor is stopped.
+type Handler func(ctx context.Context, r *Request) Action
+
+// Action is what becomes of a paused request, returned by a Handler. The
+// zero Action is Continue.
+type Action struct {
+	method string
+	params map[string]interface{}
+}
+
+// Continue lets the request, or its response, through unchanged.
+func Continue() Action {
+	return Action{method: "Fetch.continueRequest"}
+}
+
+// Override are the changes made to a request by ContinueWith. The empty
+// fields leave the request unchanged.
+type Override struct {
+	URL    string
+	Method string
+	// Headers replace all the headers of the request; copy
+	// Request.Headers to change some of them only.
+	Headers map[string]string
+	// PostData replaces the body of the request.
+	PostData []byte
+}
+
+// ContinueWith sends the request with the changes of o. It is only valid at
+// StageRequest, and the new URL must have the same scheme as the original.
+func ContinueWith(o Override) Action {
+	params := map[string]interface{}{}
+	if o.URL != "" {
+		params["url"] = o.URL
+	}
+	if o.Method != "" {
+		params["method"] = o.Method
+	}
+	if o.Headers != nil {
+		params["headers"] = headerEntries(o.Headers)
+	}
+	if o.PostData != nil {
+		params["postData"] = base64.StdEncoding.EncodeToString(o.PostData)
+	}
+	return Action{method: "Fetch.continueRequest", params: params}
+}
+
+// Fail makes the request fail with the network error reason.
+func Fail(reason ErrorReason) Action {
+	return Action{method: "Fetch.failRequest", params: map[string]interface{}{"errorReason": reason}}
+}
+
+// Block makes the request fail as if it was blocked by an extension, like
+// cdp.Network.SetBlockedURLs does.
+func Block() Action {
+	return Fail(ReasonBlockedByClient)
+}
+
+// Response is a response of Fulfill.
+type Response struct {
+	// Status is 200 if zero.
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// Fulfill answers the request with the response r, without sending it, or,
+// at StageResponse, replaces its response.
+func Fulfill(r Response) Action {
+	status := r.Status
+	if status == 0 {
+		status = 200
+	}
+	return Action{method: "Fetch.fulfillRequest", params: map[string]interface{}{
+		"responseCode":    status,
+		"responseHeaders": headerEntries(r.Headers),
+		"body":            base64.StdEncoding.EncodeToString(r.Body),
+	}}
+}
+
+// headerEntries returns headers as Fetch.HeaderEntry values, sorted by name.
+func headerEntries(headers map[string]string) []Header {
+	entries := make([]Header, 0, len(headers))
+	for name, value := range headers {
+		entries = append(entries, Header{Name: name, Value: value})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries
+}
+
+type route struct {
+	pattern Pattern
+	handler Handler
+}
+
+// Interceptor intercepts the requests of the target of a connection.
+type Interceptor struct {
+	conn *cdp.Conn
+
+	mu       sync.Mutex
+	routes   []route
+	listener *cdp.Listener
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	err      error
+}
+
+// NewInterceptor returns an interceptor of the requests of the target of
+// conn. Only one interceptor of a target may be started at a time.
+func NewInterceptor(conn *cdp.Conn) *Interceptor {
+	return &Interceptor{conn: conn}
+}
+
+// Handle passes the requests that match p to h. When several patterns match
+// a request, the handler registered first is called. The handlers of a
+// started interceptor take effect when Start is called again.
+func (i *Interceptor) Handle(p Pattern, h Handler) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.routes = append(i.routes, route{pattern: p, handler: h})
+}
+
+// Start makes the browser pause the requests that match the patterns of the
+// handlers, which are called concurrently for each request.
+func (i *Interceptor) Start(ctx context.Context) error {
+	i.mu.Lock()
+	patterns := make([]map[string]interface{}, len(i.routes))
+	for n, r := range i.routes {
+		patterns[n] = r.pattern.params()
+	}
+	started := i.listener != nil
+	if !started && len(patterns) > 0 {
+		var handlerCtx context.Context
+		handlerCtx, i.cancel = context.WithCancel(context.Background())
+		i.listener = i.conn.Listen("Fetch.requestPaused")
+		i.wg.Add(1)
+		go i.run(handlerCtx, i.listener)
+	}
+	i.mu.Unlock()
+	if len(patterns) == 0 {
+		return errors.New("network: no handler")
+	}
+
+	if err := i.conn.Execute(ctx, "Fetch.enable", map[string]interface{}{"patterns": patterns}, nil); err != nil {
+		if !started {
+			i.stop()
+		}
+		return err
+	}
+	return nil
+}
+
+// Stop stops intercepting the requests, which lets the paused ones through,
+// and waits for the handlers to return. It returns the first error of the
+// commands sent for the actions of the handlers, if any.
+func (i *Interceptor) Stop(ctx context.Context) error {
+	err := i.conn.Execute(ctx, "Fetch.disable", nil, nil)
+	i.stop()
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.err != nil {
+		err, i.err = i.err, nil
+	}
+	return err
+}
+
+// stop stops the listener and the handlers.
+func (i *Interceptor) stop() {
+	i.mu.Lock()
+	l, cancel := i.listener, i.cancel
+	i.listener, i.cancel = nil, nil
+	i.mu.Unlock()
+	if l == nil {
+		return
+	}
+	l.Close()
+	cancel()
+	i.wg.Wait()
+}
+
+// run dispatches the paused requests until the listener is closed.
+func (i *Interceptor) run(ctx context.Context, l *cdp.Listener) {
+	defer i.wg.Done()
+	for e := range l.Events {
+		var p requestPaused
+		if err := e.Decode(&p); err != nil {
+			continue
+		}
+		r := &Request{
+			ID:                  p.RequestID,
+			URL:                 p.Request.URL,
+			Method:              p.Request.Method,
+			Headers:             p.Request.Headers,
+			PostData:            p.Request.PostData,
+			ResourceType:        p.ResourceType,
+			FrameID:             p.FrameID,
+			Stage:               StageRequest,
+			StatusCode:          p.ResponseStatusCode,
+			StatusText:          p.ResponseStatusText,
+			ResponseHeaders:     p.ResponseHeaders,
+			ResponseErrorReason: p.ResponseErrorReason,
+			conn:                i.conn,
+		}
+		if p.ResponseStatusCode != 0 || p.ResponseErrorReason != "" {
+			r.Stage = StageResponse
+		}
+		i.wg.Add(1)
+		go i.serve(ctx, r)
+	}
+}
+
+// serve applies the action of the handler of r.
+func (i *Interceptor) serve(ctx context.Context, r *Request) {
+	defer i.wg.Done()
+	var h Handler
+	i.mu.Lock()
+	for _, route := range i.routes {
+		if route.pattern.matches(r) {
+			h = route.handler
+			break
+		}
+	}
+	i.mu.Unlock()
+
+	a := Continue()
+	if h != nil {
+		if a = h(ctx, r); a.method == "" {
+			a = Continue()
+		}
+	}
+	params := map[string]interface{}{"requestId": r.ID}
+	for k, v := range a.params {
+		params[k] = v
+	}
+	if err := i.conn.Execute(ctx, a.method, params, nil); err != nil && ctx.Err() == nil {
+		i.mu.Lock()
+		if i.err == nil {
+			i.err = fmt.Errorf("network: %s of %s: %w", a.method, r.URL, err)
+		}
+		i.mu.Unlock()
+	}
+}
@@ -0,0 +1,161 @@
+package network
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome/cdp"
+	"golang.org/x/net/websocket"
+)
+
+// fakeBrowser serves a DevTools target that pauses the requests of events
+// once Fetch is enabled, and sends the Fetch commands it receives to
+// commands.
+func fakeBrowser(t *testing.T, events []map[string]interface{}) (*cdp.Conn, chan map[string]interface{}) {
+	commands := make(chan map[string]interface{}, 16)
+	s := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		for {
+			var cmd map[string]interface{}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			reply := map[string]interface{}{"id": cmd["id"], "result": map[string]interface{}{}}
+			if cmd["method"] == "Fetch.getResponseBody" {
+				reply["result"] = map[string]interface{}{"body": base64.StdEncoding.EncodeToString([]byte("original")), "base64Encoded": true}
+			}
+			websocket.JSON.Send(conn, reply)
+			if cmd["method"] == "Fetch.enable" {
+				for _, e := range events {
+					websocket.JSON.Send(conn, map[string]interface{}{"method": "Fetch.requestPaused", "params": e})
+				}
+			}
+			commands <- cmd
+		}
+	}))
+	t.Cleanup(s.Close)
+	conn, err := cdp.DialTarget(context.Background(), cdp.Target{
+		ID:                   "P1",
+		WebSocketDebuggerURL: "ws" + strings.TrimPrefix(s.URL, "http"),
+	})
+	if err != nil {
+		t.Fatalf("cdp.DialTarget() returned error: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, commands
+}
+
+func paused(id, url, resourceType string, status int) map[string]interface{} {
+	e := map[string]interface{}{
+		"requestId":    id,
+		"request":      map[string]interface{}{"url": url, "method": "GET", "headers": map[string]string{"Accept": "*/*"}},
+		"resourceType": resourceType,
+	}
+	if status != 0 {
+		e["responseStatusCode"] = status
+	}
+	return e
+}
+
+func TestInterceptor(t *testing.T) {
+	conn, commands := fakeBrowser(t, []map[string]interface{}{
+		paused("1", "https://example.com/api/user", "XHR", 0),
+		paused("2", "https://example.com/logo.png", "Image", 0),
+		paused("3", "https://example.com/", "Document", 0),
+		paused("4", "https://example.com/app.js", "Script", 0),
+		paused("5", "https://example.com/api/items", "XHR", 200),
+	})
+	i := NewInterceptor(conn)
+	i.Handle(Pattern{URL: "*/api/user"}, func(_ context.Context, r *Request) Action {
+		return Fulfill(Response{Headers: map[string]string{"Content-Type": "application/json"}, Body: []byte(`{}`)})
+	})
+	i.Handle(Pattern{ResourceType: "Image"}, func(context.Context, *Request) Action {
+		return Block()
+	})
+	i.Handle(Pattern{ResourceType: "Document"}, func(_ context.Context, r *Request) Action {
+		headers := map[string]string{"X-Test": "1"}
+		for k, v := range r.Headers {
+			headers[k] = v
+		}
+		return ContinueWith(Override{Headers: headers})
+	})
+	var body string
+	i.Handle(Pattern{URL: "*/api/*", Stage: StageResponse}, func(ctx context.Context, r *Request) Action {
+		b, err := r.ResponseBody(ctx)
+		if err != nil {
+			t.Errorf("r.ResponseBody() returned error: %v", err)
+		}
+		body = string(b)
+		return Fulfill(Response{Status: r.StatusCode, Body: []byte("changed")})
+	})
+	ctx := context.Background()
+	if err := i.Start(ctx); err != nil {
+		t.Fatalf("i.Start() returned error: %v", err)
+	}
+
+	got := map[string]map[string]interface{}{}
+	for len(got) < 5 {
+		select {
+		case cmd := <-commands:
+			params := cmd["params"].(map[string]interface{})
+			switch cmd["method"] {
+			case "Fetch.enable":
+				if patterns := params["patterns"].([]interface{}); len(patterns) != 4 {
+					t.Errorf("Fetch.enable received %d patterns, want 4", len(patterns))
+				}
+			case "Fetch.getResponseBody":
+			default:
+				params["method"] = cmd["method"]
+				got[params["requestId"].(string)] = params
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received the actions of %d requests, want 5", len(got))
+		}
+	}
+	if err := i.Stop(ctx); err != nil {
+		t.Errorf("i.Stop() returned error: %v", err)
+	}
+
+	if p := got["1"]; p["method"] != "Fetch.fulfillRequest" || p["responseCode"] != 200.0 || p["body"] != base64.StdEncoding.EncodeToString([]byte(`{}`)) {
+		t.Errorf("the mocked request received %v", p)
+	}
+	if p := got["2"]; p["method"] != "Fetch.failRequest" || p["errorReason"] != "BlockedByClient" {
+		t.Errorf("the blocked request received %v", p)
+	}
+	headers, _ := json.Marshal(got["3"]["headers"])
+	if p := got["3"]; p["method"] != "Fetch.continueRequest" || string(headers) != `[{"name":"Accept","value":"*/*"},{"name":"X-Test","value":"1"}]` {
+		t.Errorf("the modified request received %v", p)
+	}
+	if p := got["4"]; p["method"] != "Fetch.continueRequest" || len(p) != 2 {
+		t.Errorf("the request without a handler received %v, want to continue unchanged", p)
+	}
+	if p := got["5"]; p["method"] != "Fetch.fulfillRequest" || p["body"] != base64.StdEncoding.EncodeToString([]byte("changed")) || body != "original" {
+		t.Errorf("the response received %v, with the original body %q", p, body)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"*", "https://example.com/", true},
+		{"*/api/*", "https://example.com/api/user", true},
+		{"*/api/*", "https://example.com/apis", false},
+		{"*.png", "https://example.com/a.png", true},
+		{"*.png", "https://example.com/a.png?x", false},
+		{"https://example.com/?", "https://example.com/a", true},
+		{"https://example.com/?", "https://example.com/", false},
+		{`*\?x`, "https://example.com/a?x", true},
+		{`*\?x`, "https://example.com/a/x", false},
+		{"*a*b", "xaxxbxb", true},
+	} {
+		if got := match(tc.pattern, tc.s); got != tc.want {
+			t.Errorf("match(%q, %q) = %t, want %t", tc.pattern, tc.s, got, tc.want)
+		}
+	}
+}
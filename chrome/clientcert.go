@@ -0,0 +1,53 @@
+package chrome
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// autoSelectCertificatePref is the profile content setting that the
+// AutoSelectCertificateForUrls policy also sets.
+const autoSelectCertificatePref = "profile.content_settings.exceptions.auto_select_certificate"
+
+// AutoSelectClientCertificate makes Chrome present a client certificate to
+// the sites matching urlPattern, e.g. "https://[*.]staging.example.com",
+// without showing the certificate picker: the first certificate issued by
+// the CA named issuerCN, or any certificate if issuerCN is empty. It sets
+// the content setting of the profile, which Chrome honors from the
+// Preferences written by ChromeDriver, and the AutoSelectCertificateForUrls
+// policy written by WritePolicies, which takes precedence.
+//
+// The certificate and its key must be in the certificate store of the
+// operating system: the keychain on macOS, the user store on Windows, and on
+// Linux the NSS database of the user, $HOME/.pki/nssdb, where pk12util
+// imports PKCS#12 files:
+//
+//	pk12util -i client.p12 -d sql:$HOME/.pki/nssdb -W password
+func (c *Capabilities) AutoSelectClientCertificate(urlPattern, issuerCN string) error {
+	if urlPattern == "" {
+		return errors.New("empty URL pattern")
+	}
+	filter := map[string]interface{}{}
+	if issuerCN != "" {
+		filter["ISSUER"] = map[string]string{"CN": issuerCN}
+	}
+	rule, err := json.Marshal(map[string]interface{}{"pattern": urlPattern, "filter": filter})
+	if err != nil {
+		return err
+	}
+	list, _ := c.policies["AutoSelectCertificateForUrls"].([]string)
+	c.setPolicy("AutoSelectCertificateForUrls", append(list, string(rule)))
+
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]interface{})
+	}
+	exceptions, _ := c.Prefs[autoSelectCertificatePref].(map[string]interface{})
+	if exceptions == nil {
+		exceptions = make(map[string]interface{})
+		c.Prefs[autoSelectCertificatePref] = exceptions
+	}
+	exceptions[urlPattern+",*"] = map[string]interface{}{
+		"setting": map[string]interface{}{"filters": []interface{}{filter}},
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package chrome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAutoSelectClientCertificate(t *testing.T) {
+	var c Capabilities
+	if err := c.AutoSelectClientCertificate("https://[*.]staging.example.com", "Staging CA"); err != nil {
+		t.Fatalf("c.AutoSelectClientCertificate() returned error: %v", err)
+	}
+	if err := c.AutoSelectClientCertificate("https://mtls.example.com", ""); err != nil {
+		t.Fatalf("c.AutoSelectClientCertificate() without issuer returned error: %v", err)
+	}
+
+	wantPolicy := []string{
+		`{"filter":{"ISSUER":{"CN":"Staging CA"}},"pattern":"https://[*.]staging.example.com"}`,
+		`{"filter":{},"pattern":"https://mtls.example.com"}`,
+	}
+	if got := c.Policies()["AutoSelectCertificateForUrls"]; !reflect.DeepEqual(got, wantPolicy) {
+		t.Errorf("the AutoSelectCertificateForUrls policy is %q, want %q", got, wantPolicy)
+	}
+	wantPref := map[string]interface{}{
+		"https://[*.]staging.example.com,*": map[string]interface{}{
+			"setting": map[string]interface{}{"filters": []interface{}{
+				map[string]interface{}{"ISSUER": map[string]string{"CN": "Staging CA"}},
+			}},
+		},
+		"https://mtls.example.com,*": map[string]interface{}{
+			"setting": map[string]interface{}{"filters": []interface{}{map[string]interface{}{}}},
+		},
+	}
+	if got := c.Prefs[autoSelectCertificatePref]; !reflect.DeepEqual(got, wantPref) {
+		t.Errorf("the %s preference is %v, want %v", autoSelectCertificatePref, got, wantPref)
+	}
+
+	if err := c.AutoSelectClientCertificate("", "Staging CA"); err == nil {
+		t.Error("c.AutoSelectClientCertificate() of an empty pattern returned no error")
+	}
+}
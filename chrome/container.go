@@ -0,0 +1,16 @@
+package chrome
+
+// containerArgs let Chrome run in a container, where it usually runs as
+// root, without user namespaces, and with a small /dev/shm.
+var containerArgs = []string{
+	"--no-sandbox",
+	"--disable-dev-shm-usage",
+}
+
+// ForContainers adds the command-line arguments that Chrome needs to run in a
+// Docker or Kubernetes container: the sandbox is disabled, which is unsafe
+// for untrusted pages outside of a container, and shared memory files are
+// written to /tmp. Arguments already present in c.Args take precedence.
+func (c *Capabilities) ForContainers() {
+	c.addDefaultArgs(containerArgs...)
+}
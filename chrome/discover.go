@@ -0,0 +1,302 @@
+package chrome
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Flavor is a kind of Chrome build.
+type Flavor int
+
+// Flavors of Chrome builds.
+const (
+	UnknownFlavor Flavor = iota
+	// Stable is the Google Chrome release installed on the system.
+	Stable
+	// Beta is the Google Chrome Beta release installed on the system.
+	Beta
+	// ChromeForTesting is a Chrome for Testing build, which does not update
+	// itself and is published with a matching ChromeDriver.
+	ChromeForTesting
+	// Chromium is an open-source Chromium build, e.g. from a Linux
+	// distribution or a snapshot.
+	Chromium
+)
+
+func (f Flavor) String() string {
+	switch f {
+	case Stable:
+		return "Chrome"
+	case Beta:
+		return "Chrome Beta"
+	case ChromeForTesting:
+		return "Chrome for Testing"
+	case Chromium:
+		return "Chromium"
+	}
+	return "unknown"
+}
+
+// Installation is a Chrome binary found by FindInstallations.
+type Installation struct {
+	// Path is the path to the binary.
+	Path string
+	// Flavor is the kind of build.
+	Flavor Flavor
+	// Version is the version of the build, if known from its location. Use
+	// BinaryVersion to query the binary.
+	Version string
+}
+
+// systemInstallations lists the well-known locations of Chrome binaries on
+// each operating system.
+var systemInstallations = map[string][]Installation{
+	"linux": {
+		{Path: "/usr/bin/google-chrome-stable", Flavor: Stable},
+		{Path: "/usr/bin/google-chrome", Flavor: Stable},
+		{Path: "/usr/bin/google-chrome-beta", Flavor: Beta},
+		{Path: "/usr/bin/chromium", Flavor: Chromium},
+		{Path: "/usr/bin/chromium-browser", Flavor: Chromium},
+		{Path: "/snap/bin/chromium", Flavor: Chromium},
+	},
+	"darwin": {
+		{Path: "/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", Flavor: Stable},
+		{Path: "/Applications/Google Chrome Beta.app/Contents/MacOS/Google Chrome Beta", Flavor: Beta},
+		{Path: "/Applications/Google Chrome for Testing.app/Contents/MacOS/Google Chrome for Testing", Flavor: ChromeForTesting},
+		{Path: "/Applications/Chromium.app/Contents/MacOS/Chromium", Flavor: Chromium},
+	},
+	"windows": {
+		{Path: `C:\Program Files\Google\Chrome\Application\chrome.exe`, Flavor: Stable},
+		{Path: `C:\Program Files (x86)\Google\Chrome\Application\chrome.exe`, Flavor: Stable},
+		{Path: `C:\Program Files\Google\Chrome Beta\Application\chrome.exe`, Flavor: Beta},
+		{Path: `C:\Program Files\Chromium\Application\chrome.exe`, Flavor: Chromium},
+	},
+}
+
+// chromeForTestingExecutables are the names of the Chrome for Testing
+// binaries, relative to the directory of a build.
+var chromeForTestingExecutables = []string{
+	"chrome",
+	"chrome.exe",
+	filepath.Join("Google Chrome for Testing.app", "Contents", "MacOS", "Google Chrome for Testing"),
+}
+
+// chromeForTestingCaches returns the directories in which tools store the
+// Chrome for Testing builds they download:
+//
+//   - @puppeteer/browsers: <cache>/chrome/<platform>-<version>/chrome-<platform>/
+//   - Selenium Manager: <cache>/chrome/<platform>/<version>/
+func chromeForTestingCaches() []string {
+	return toolCaches("chrome")
+}
+
+// chromeDriverCaches returns the directories in which the same tools store
+// the ChromeDriver builds they download, with the same layouts.
+func chromeDriverCaches() []string {
+	return toolCaches("chromedriver")
+}
+
+// toolCaches returns the subdirectory name of the caches of
+// @puppeteer/browsers and Selenium Manager.
+func toolCaches(name string) []string {
+	var dirs []string
+	if dir := os.Getenv("PUPPETEER_CACHE_DIR"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, name))
+	}
+	if dir := os.Getenv("SE_CACHE_PATH"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, name))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs,
+			filepath.Join(home, ".cache", "puppeteer", name),
+			filepath.Join(home, ".cache", "selenium", name))
+	}
+	return dirs
+}
+
+var versionRE = regexp.MustCompile(`\d+(\.\d+){1,3}`)
+
+// findChromeForTesting returns the Chrome for Testing builds in the cache
+// directories.
+func findChromeForTesting(caches []string) []Installation {
+	found := findCached(caches, chromeForTestingExecutables)
+	for i := range found {
+		found[i].Flavor = ChromeForTesting
+	}
+	return found
+}
+
+// findCached returns the binaries with one of the names of executables in the
+// cache directories, newest first.
+func findCached(caches, executables []string) []Installation {
+	var found []Installation
+	for _, cache := range caches {
+		for _, exe := range executables {
+			// Both cache layouts place the binary two levels below the cache.
+			matches, _ := filepath.Glob(filepath.Join(cache, "*", "*", exe))
+			for _, path := range matches {
+				if info, err := os.Stat(path); err != nil || info.IsDir() {
+					continue
+				}
+				rel, err := filepath.Rel(cache, path)
+				if err != nil {
+					continue
+				}
+				found = append(found, Installation{
+					Path:    path,
+					Version: versionRE.FindString(rel),
+				})
+			}
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return compareVersions(found[i].Version, found[j].Version) > 0
+	})
+	return found
+}
+
+// FindInstallations returns the Chrome binaries found in the well-known
+// locations of the operating system and in the caches of the tools that
+// download Chrome for Testing builds, @puppeteer/browsers and Selenium
+// Manager. The system installations come first, then the Chrome for Testing
+// builds, newest first.
+func FindInstallations() []Installation {
+	var found []Installation
+	for _, inst := range systemInstallations[runtime.GOOS] {
+		if info, err := os.Stat(inst.Path); err == nil && !info.IsDir() {
+			found = append(found, inst)
+		}
+	}
+	return append(found, findChromeForTesting(chromeForTestingCaches())...)
+}
+
+// BinaryVersion runs the Chrome binary at path to query its version, e.g.
+// "120.0.6099.109".
+func BinaryVersion(path string) (string, error) {
+	out, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("running %q --version: %v", path, err)
+	}
+	v := versionRE.FindString(string(out))
+	if v == "" {
+		return "", fmt.Errorf("no version in the output of %q --version: %q", path, out)
+	}
+	return v, nil
+}
+
+// UseChromeForTesting sets Path to a Chrome for Testing build of the version.
+// version is either a full version, e.g. "120.0.6099.109", or a prefix of
+// one, e.g. "120", in which case the newest matching build is used.
+//
+// The build is looked up in the caches searched by FindInstallations and, if
+// none matches, downloaded from the Chrome for Testing site to the cache of
+// @puppeteer/browsers.
+func (c *Capabilities) UseChromeForTesting(version string) error {
+	for _, inst := range findChromeForTesting(chromeForTestingCaches()) {
+		if inst.Version == version || strings.HasPrefix(inst.Version, version+".") {
+			c.Path = inst.Path
+			return nil
+		}
+	}
+	path, err := downloadChromeForTesting(version)
+	if err != nil {
+		return fmt.Errorf("downloading Chrome for Testing %s: %v", version, err)
+	}
+	c.Path = path
+	return nil
+}
+
+// VersionsCompatible reports whether ChromeDriver of version driver supports
+// Chrome of version browser. Both are version strings, possibly within
+// surrounding text, as printed with --version.
+//
+// Since version 73, ChromeDriver supports the Chrome release with the same
+// major version. The 2.x releases support Chrome releases older than 73.
+func VersionsCompatible(browser, driver string) bool {
+	b := majorVersion(browser)
+	d := majorVersion(driver)
+	if b < 0 || d < 0 {
+		return false
+	}
+	if d == 2 {
+		return b < 73
+	}
+	return b == d
+}
+
+// majorVersion returns the major version in s, or -1.
+func majorVersion(s string) int {
+	v := versionRE.FindString(s)
+	if v == "" {
+		return -1
+	}
+	major, err := strconv.Atoi(strings.SplitN(v, ".", 2)[0])
+	if err != nil {
+		return -1
+	}
+	return major
+}
+
+// compareVersions compares dotted versions numerically. Unknown versions sort
+// first.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+// chromeDriverExecutables are the names of the ChromeDriver binaries.
+var chromeDriverExecutables = []string{"chromedriver", "chromedriver.exe"}
+
+// FindDriver returns the path to a ChromeDriver binary that supports Chrome of
+// the given version, as returned by BinaryVersion, or any ChromeDriver if the
+// version is empty. It looks for chromedriver in the PATH, then in the caches
+// of @puppeteer/browsers and Selenium Manager, newest first. The
+// drivermanager package downloads it otherwise.
+func FindDriver(browserVersion string) (string, error) {
+	var candidates []Installation
+	for _, exe := range chromeDriverExecutables {
+		if path, err := exec.LookPath(exe); err == nil {
+			candidates = append(candidates, Installation{Path: path})
+			break
+		}
+	}
+	candidates = append(candidates, findCached(chromeDriverCaches(), chromeDriverExecutables)...)
+	for _, c := range candidates {
+		if browserVersion == "" {
+			return c.Path, nil
+		}
+		version := c.Version
+		if version == "" {
+			var err error
+			if version, err = BinaryVersion(c.Path); err != nil {
+				continue
+			}
+		}
+		if VersionsCompatible(browserVersion, version) {
+			return c.Path, nil
+		}
+	}
+	return "", fmt.Errorf("no ChromeDriver for Chrome %q found in the PATH or in %s", browserVersion, strings.Join(chromeDriverCaches(), ", "))
+}
@@ -0,0 +1,117 @@
+package chrome
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindChromeForTesting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-discover-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	puppeteer := filepath.Join(dir, "puppeteer", "chrome")
+	selenium := filepath.Join(dir, "selenium", "chrome")
+	for _, path := range []string{
+		filepath.Join(puppeteer, "linux-119.0.6045.105", "chrome-linux64", "chrome"),
+		filepath.Join(selenium, "linux64", "120.0.6099.109", "chrome"),
+		filepath.Join(selenium, "linux64", "120.0.6099.71", "chrome"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	found := findChromeForTesting([]string{puppeteer, selenium})
+	var versions []string
+	for _, inst := range found {
+		if inst.Flavor != ChromeForTesting {
+			t.Errorf("%s has flavor %s, want %s", inst.Path, inst.Flavor, ChromeForTesting)
+		}
+		versions = append(versions, inst.Version)
+	}
+	want := []string{"120.0.6099.109", "120.0.6099.71", "119.0.6045.105"}
+	if len(versions) != len(want) {
+		t.Fatalf("findChromeForTesting() found versions %q, want %q", versions, want)
+	}
+	for i := range want {
+		if versions[i] != want[i] {
+			t.Fatalf("findChromeForTesting() found versions %q, want %q", versions, want)
+		}
+	}
+
+	os.Setenv("PUPPETEER_CACHE_DIR", filepath.Join(dir, "puppeteer"))
+	defer os.Unsetenv("PUPPETEER_CACHE_DIR")
+	var c Capabilities
+	if err := c.UseChromeForTesting("119"); err != nil {
+		t.Fatalf("c.UseChromeForTesting(%q) returned error: %v", "119", err)
+	}
+	if want := filepath.Join(puppeteer, "linux-119.0.6045.105", "chrome-linux64", "chrome"); c.Path != want {
+		t.Errorf("c.UseChromeForTesting(%q) set Path to %q, want %q", "119", c.Path, want)
+	}
+}
+
+func TestVersionsCompatible(t *testing.T) {
+	for _, test := range []struct {
+		browser, driver string
+		want            bool
+	}{
+		{"Google Chrome 120.0.6099.109", "ChromeDriver 120.0.6099.71 (a5d0f4c1)", true},
+		{"Google Chrome for Testing 120.0.6099.109", "ChromeDriver 119.0.6045.105", false},
+		{"Chromium 72.0.3626.121", "ChromeDriver 2.46.628388", true},
+		{"Google Chrome 76.0.3809.100", "ChromeDriver 2.46.628388", false},
+		{"", "ChromeDriver 120.0.6099.71", false},
+	} {
+		if got := VersionsCompatible(test.browser, test.driver); got != test.want {
+			t.Errorf("VersionsCompatible(%q, %q) = %t, want %t", test.browser, test.driver, got, test.want)
+		}
+	}
+}
+
+func TestFindDriver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-discover-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache := filepath.Join(dir, "puppeteer", "chromedriver")
+	for _, path := range []string{
+		filepath.Join(cache, "linux-119.0.6045.105", "chromedriver-linux64", "chromedriver"),
+		filepath.Join(cache, "linux-120.0.6099.109", "chromedriver-linux64", "chromedriver"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for k, v := range map[string]string{"PUPPETEER_CACHE_DIR": filepath.Join(dir, "puppeteer"), "PATH": dir, "HOME": dir} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	for version, want := range map[string]string{
+		"119.0.6045.200": "linux-119.0.6045.105",
+		"":               "linux-120.0.6099.109",
+	} {
+		got, err := FindDriver(version)
+		if err != nil {
+			t.Fatalf("FindDriver(%q) returned error: %v", version, err)
+		}
+		if !strings.Contains(got, want) {
+			t.Errorf("FindDriver(%q) = %q, want the build in %s", version, got, want)
+		}
+	}
+	if _, err := FindDriver("121.0.6167.85"); err == nil {
+		t.Error("FindDriver() of a version without a driver returned no error")
+	}
+}
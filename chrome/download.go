@@ -0,0 +1,228 @@
+package chrome
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// knownGoodVersionsURL lists the Chrome for Testing builds and their
+// downloads. It is replaced in tests.
+var knownGoodVersionsURL = "https://googlechromelabs.github.io/chrome-for-testing/known-good-versions-with-downloads.json"
+
+// The platform that the builds are downloaded for, replaced in tests.
+var goos, goarch = runtime.GOOS, runtime.GOARCH
+
+// chromeForTestingPlatforms are the names of the platforms of the Chrome for
+// Testing builds and of their directories in the cache of
+// @puppeteer/browsers, by GOOS/GOARCH.
+var chromeForTestingPlatforms = map[string][2]string{
+	"linux/amd64":   {"linux64", "linux"},
+	"darwin/amd64":  {"mac-x64", "mac"},
+	"darwin/arm64":  {"mac-arm64", "mac_arm"},
+	"windows/amd64": {"win64", "win64"},
+	"windows/386":   {"win32", "win32"},
+}
+
+// downloadChromeForTesting downloads the newest Chrome for Testing build of
+// version, a full version or a prefix of one, to the cache of
+// @puppeteer/browsers, where FindInstallations finds it, and returns the path
+// to its binary.
+func downloadChromeForTesting(version string) (string, error) {
+	platforms, ok := chromeForTestingPlatforms[goos+"/"+goarch]
+	if !ok {
+		return "", fmt.Errorf("Chrome for Testing is not available for %s/%s", goos, goarch)
+	}
+	platform := platforms[0]
+	build, url, err := findChromeForTestingDownload(version, platform)
+	if err != nil {
+		return "", err
+	}
+
+	cache, err := puppeteerCache()
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(cache, platforms[1]+"-"+build)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		if err := os.MkdirAll(cache, 0755); err != nil {
+			return "", err
+		}
+		tmp, err := ioutil.TempDir(cache, ".download-")
+		if err != nil {
+			return "", err
+		}
+		defer os.RemoveAll(tmp)
+		if err := downloadZip(url, tmp); err != nil {
+			return "", err
+		}
+		// Another process may have downloaded the build in the meantime.
+		if err := os.Rename(tmp, dir); err != nil {
+			if _, statErr := os.Stat(dir); statErr != nil {
+				return "", err
+			}
+		}
+	}
+
+	for _, exe := range chromeForTestingExecutables {
+		path := filepath.Join(dir, "chrome-"+platform, exe)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no Chrome binary in %s", dir)
+}
+
+// findChromeForTestingDownload returns the newest Chrome for Testing build of
+// version and the URL of its archive for platform.
+func findChromeForTestingDownload(version, platform string) (build, url string, err error) {
+	resp, err := http.Get(knownGoodVersionsURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("getting %s: %s", knownGoodVersionsURL, resp.Status)
+	}
+	var versions struct {
+		Versions []struct {
+			Version   string `json:"version"`
+			Downloads map[string][]struct {
+				Platform string `json:"platform"`
+				URL      string `json:"url"`
+			} `json:"downloads"`
+		} `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
+		return "", "", fmt.Errorf("decoding %s: %v", knownGoodVersionsURL, err)
+	}
+	for _, v := range versions.Versions {
+		if v.Version != version && !strings.HasPrefix(v.Version, version+".") {
+			continue
+		}
+		if build != "" && compareVersions(v.Version, build) <= 0 {
+			continue
+		}
+		for _, dl := range v.Downloads["chrome"] {
+			if dl.Platform == platform {
+				build, url = v.Version, dl.URL
+			}
+		}
+	}
+	if build == "" {
+		return "", "", fmt.Errorf("no Chrome for Testing build of version %q for %s", version, platform)
+	}
+	return build, url, nil
+}
+
+// puppeteerCache returns the directory of the Chrome builds in the cache of
+// @puppeteer/browsers.
+func puppeteerCache() (string, error) {
+	if dir := os.Getenv("PUPPETEER_CACHE_DIR"); dir != "" {
+		return filepath.Join(dir, "chrome"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cache", "puppeteer", "chrome"), nil
+}
+
+// downloadZip downloads the ZIP archive at url and extracts it to dir.
+func downloadZip(url, dir string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("getting %s: %s", url, resp.Status)
+	}
+	f, err := ioutil.TempFile("", "chrome-for-testing-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	size, err := io.Copy(f, resp.Body)
+	if err != nil {
+		return fmt.Errorf("getting %s: %v", url, err)
+	}
+	if err := extractZip(f, size, dir); err != nil {
+		return fmt.Errorf("extracting %s: %v", url, err)
+	}
+	return nil
+}
+
+// extractZip extracts the files, directories and symbolic links of a ZIP
+// archive to dir.
+func extractZip(r io.ReaderAt, size int64, dir string) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range z.File {
+		path := filepath.Join(dir, filepath.FromSlash(f.Name))
+		if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			return fmt.Errorf("invalid file name %q", f.Name)
+		}
+		mode := f.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if mode&os.ModeSymlink != 0 {
+			// The macOS builds link the current version of their frameworks.
+			target, err := readZipFile(f)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(string(target), path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := extractZipFile(f, path, mode.Perm()|0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+func extractZipFile(f *zip.File, path string, perm os.FileMode) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,135 @@
+package chrome
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestUseChromeForTestingDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-download-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var archive bytes.Buffer
+	z := zip.NewWriter(&archive)
+	for name, mode := range map[string]os.FileMode{
+		"chrome-linux64/chrome":        0755,
+		"chrome-linux64/resources.pak": 0644,
+	} {
+		h := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		h.SetMode(mode)
+		w, err := z.CreateHeader(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(name))
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var downloads int32
+	var s *httptest.Server
+	s = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/known-good-versions-with-downloads.json":
+			versions := []map[string]interface{}{}
+			for _, v := range []string{"120.0.6099.71", "120.0.6099.109", "121.0.6167.85"} {
+				versions = append(versions, map[string]interface{}{
+					"version": v,
+					"downloads": map[string]interface{}{"chrome": []map[string]string{
+						{"platform": "linux64", "url": s.URL + "/" + v + "/linux64/chrome-linux64.zip"},
+						{"platform": "win64", "url": s.URL + "/" + v + "/win64/chrome-win64.zip"},
+					}},
+				})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"versions": versions})
+		case "/120.0.6099.109/linux64/chrome-linux64.zip":
+			atomic.AddInt32(&downloads, 1)
+			w.Write(archive.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	savedURL, savedOS, savedArch := knownGoodVersionsURL, goos, goarch
+	knownGoodVersionsURL, goos, goarch = s.URL+"/known-good-versions-with-downloads.json", "linux", "amd64"
+	defer func() { knownGoodVersionsURL, goos, goarch = savedURL, savedOS, savedArch }()
+	for k, v := range map[string]string{"PUPPETEER_CACHE_DIR": filepath.Join(dir, "puppeteer"), "SE_CACHE_PATH": "", "HOME": dir} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+
+	want := filepath.Join(dir, "puppeteer", "chrome", "linux-120.0.6099.109", "chrome-linux64", "chrome")
+	for i := 0; i < 2; i++ {
+		var c Capabilities
+		if err := c.UseChromeForTesting("120"); err != nil {
+			t.Fatalf("c.UseChromeForTesting(%q) returned error: %v", "120", err)
+		}
+		if c.Path != want {
+			t.Errorf("c.UseChromeForTesting(%q) set Path to %q, want %q", "120", c.Path, want)
+		}
+	}
+	if n := atomic.LoadInt32(&downloads); n != 1 {
+		t.Errorf("the build was downloaded %d times, want once", n)
+	}
+	info, err := os.Stat(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0100 == 0 {
+		t.Errorf("the downloaded binary has mode %v, want it executable", info.Mode())
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(want), "resources.pak")); err != nil {
+		t.Errorf("the other files of the build were not extracted: %v", err)
+	}
+
+	for _, test := range []struct {
+		desc, version, goos string
+	}{
+		{"unknown version", "1", "linux"},
+		{"build without the archive", "121", "linux"},
+		{"unsupported platform", "121", "plan9"},
+	} {
+		goos = test.goos
+		var c Capabilities
+		if err := c.UseChromeForTesting(test.version); err == nil {
+			t.Errorf("%s: c.UseChromeForTesting(%q) returned no error", test.desc, test.version)
+		}
+	}
+}
+
+func TestExtractZipInvalidName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-download-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var archive bytes.Buffer
+	z := zip.NewWriter(&archive)
+	if _, err := z.Create("../escaped"); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := bytes.NewReader(archive.Bytes())
+	if err := extractZip(r, r.Size(), filepath.Join(dir, "build")); err == nil {
+		t.Error("extractZip() of a file outside the directory returned no error")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escaped")); !os.IsNotExist(err) {
+		t.Errorf("extractZip() wrote a file outside the directory: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package chrome
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Feature switches of Chrome, which EnableFeatures and DisableFeatures
+// manage.
+const (
+	enableFeaturesSwitch  = "--enable-features"
+	disableFeaturesSwitch = "--disable-features"
+)
+
+// originTrialPublicKeySwitch replaces the public keys with which Chrome
+// validates origin trial tokens.
+const originTrialPublicKeySwitch = "--origin-trial-public-key"
+
+// featureName returns the name of a feature in a feature list, without the
+// field trial group ("<Trial") or the parameters (":name/value").
+func featureName(f string) string {
+	if i := strings.IndexAny(f, "<:"); i >= 0 {
+		return f[:i]
+	}
+	return f
+}
+
+// listSwitchValues returns the values of all the occurrences of a
+// list-valued switch in c.Args, deduplicated.
+func (c *Capabilities) listSwitchValues(name string) []string {
+	merged := name + "="
+	for _, arg := range c.Args {
+		if switchName(arg) == name {
+			merged = mergeListSwitch(merged, arg)
+		}
+	}
+	if merged == name+"=" {
+		return nil
+	}
+	return strings.Split(strings.TrimPrefix(merged, name+"="), ",")
+}
+
+// updateFeatures adds features to the list-valued switch add, after
+// checking that none of them is in the switch other.
+func (c *Capabilities) updateFeatures(add, other string, features []string) error {
+	conflicting := make(map[string]bool)
+	for _, f := range c.listSwitchValues(other) {
+		conflicting[featureName(f)] = true
+	}
+	for _, f := range features {
+		if f == "" || strings.ContainsAny(f, ", \t\n") {
+			return fmt.Errorf("invalid feature name %q", f)
+		}
+		if conflicting[featureName(f)] {
+			return fmt.Errorf("feature %q is already in %s", featureName(f), other)
+		}
+	}
+	merged := mergeListSwitch(add+"="+strings.Join(c.listSwitchValues(add), ","), add+"="+strings.Join(features, ","))
+	c.setSwitch(add, strings.TrimPrefix(merged, add+"="))
+	return nil
+}
+
+// EnableFeatures enables the given Chrome features, e.g. "WebGPU" or
+// "Feature:param/value" with field trial parameters. The features are merged
+// into a single --enable-features argument, with those already in Args,
+// since Chrome only honors the last occurrence of the switch. It returns an
+// error, and changes nothing, if a feature is in --disable-features.
+func (c *Capabilities) EnableFeatures(features ...string) error {
+	return c.updateFeatures(enableFeaturesSwitch, disableFeaturesSwitch, features)
+}
+
+// DisableFeatures disables the given Chrome features, like EnableFeatures
+// with the --disable-features argument.
+func (c *Capabilities) DisableFeatures(features ...string) error {
+	return c.updateFeatures(disableFeaturesSwitch, enableFeaturesSwitch, features)
+}
+
+// originTrialScript declares an origin trial token in every document with an
+// origin-trial meta tag, as soon as the document has a root element.
+const originTrialScript = `(function(token) {
+	var add = function(root) {
+		var meta = document.createElement('meta');
+		meta.httpEquiv = 'origin-trial';
+		meta.content = token;
+		(document.head || root).appendChild(meta);
+	};
+	if (document.documentElement) {
+		add(document.documentElement);
+		return;
+	}
+	var observer = new MutationObserver(function() {
+		if (document.documentElement) {
+			observer.disconnect();
+			add(document.documentElement);
+		}
+	});
+	observer.observe(document, {childList: true});
+})(%s);`
+
+// AddOriginTrialToken enables the origin trial of token in the pages of the
+// origin it was issued for, by adding the origin-trial meta tag that the
+// pages would carry to every document, before its own scripts run. Like
+// SeedLocalStorage, it is only supported by ChromeDriver.
+//
+// Trials that must be enabled before the document loads, e.g. those read
+// from the Origin-Trial header by the network stack, need the header to be
+// served; tokens signed with a test key need SetOriginTrialPublicKeys.
+func (c *Capabilities) AddOriginTrialToken(token string) error {
+	if _, err := base64.StdEncoding.DecodeString(token); err != nil || token == "" {
+		return fmt.Errorf("invalid origin trial token %q: want the base64 token issued for the origin", token)
+	}
+	t, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	c.initScripts = append(c.initScripts, fmt.Sprintf(originTrialScript, t))
+	return nil
+}
+
+// SetOriginTrialPublicKeys makes Chrome validate origin trial tokens with the
+// given public keys, base64-encoded Ed25519 keys, instead of those of
+// Google, e.g. to test tokens generated with a local key. No keys removes
+// the setting.
+func (c *Capabilities) SetOriginTrialPublicKeys(keys ...string) error {
+	for _, k := range keys {
+		if b, err := base64.StdEncoding.DecodeString(k); err != nil || len(b) != 32 {
+			return fmt.Errorf("invalid origin trial public key %q: want a base64-encoded 32-byte Ed25519 key", k)
+		}
+	}
+	c.setSwitch(originTrialPublicKeySwitch, strings.Join(keys, ","))
+	return nil
+}
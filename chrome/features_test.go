@@ -0,0 +1,94 @@
+package chrome
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnableFeatures(t *testing.T) {
+	c := Capabilities{Args: []string{
+		"--enable-features=WebGPU",
+		"--headless",
+		"--enable-features=Vulkan,WebGPU",
+		"--disable-features=Translate",
+	}}
+	c.DisableTabThrottling()
+	if err := c.EnableFeatures("FencedFrames", "Vulkan", "PrivacySandboxAdsAPIsOverride:level/2"); err != nil {
+		t.Fatalf("c.EnableFeatures() returned error: %v", err)
+	}
+	if err := c.DisableFeatures("AutofillServerCommunication", "Translate"); err != nil {
+		t.Fatalf("c.DisableFeatures() returned error: %v", err)
+	}
+	disabled := "--disable-features=Translate,HighEfficiencyModeAvailable,AutofillServerCommunication"
+	if runtime.GOOS == "windows" {
+		disabled = "--disable-features=Translate,HighEfficiencyModeAvailable,CalculateNativeWinOcclusion,AutofillServerCommunication"
+	}
+	want := []string{
+		"--headless",
+		"--disable-background-timer-throttling",
+		"--disable-backgrounding-occluded-windows",
+		"--disable-renderer-backgrounding",
+		"--enable-features=WebGPU,Vulkan,FencedFrames,PrivacySandboxAdsAPIsOverride:level/2",
+		disabled,
+	}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.Args = %q, want %q", c.Args, want)
+	}
+
+	for _, test := range []struct {
+		enable   bool
+		features []string
+	}{
+		{true, []string{"Translate"}},
+		{true, []string{"Translate<Study"}},
+		{false, []string{"WebGPU:param/1"}},
+		{true, []string{"A,B"}},
+		{false, []string{""}},
+	} {
+		before := append([]string(nil), c.Args...)
+		var err error
+		if test.enable {
+			err = c.EnableFeatures(test.features...)
+		} else {
+			err = c.DisableFeatures(test.features...)
+		}
+		if err == nil {
+			t.Errorf("enabling %t of %q returned no error", test.enable, test.features)
+		}
+		if !reflect.DeepEqual(c.Args, before) {
+			t.Errorf("enabling %t of %q failed but changed Args to %q", test.enable, test.features, c.Args)
+		}
+	}
+}
+
+func TestOriginTrials(t *testing.T) {
+	var c Capabilities
+	const token = "A1b2C3d4E5f6G7h8I9j0KkLlMmNnOoPp+/=="
+	if err := c.AddOriginTrialToken(token); err != nil {
+		t.Fatalf("c.AddOriginTrialToken() returned error: %v", err)
+	}
+	if s := c.InitScripts(); len(s) != 1 || !strings.Contains(s[0], `"`+token+`"`) {
+		t.Errorf("c.InitScripts() = %q, want a script adding the token", s)
+	}
+	for _, token := range []string{"", "not base64!"} {
+		if err := c.AddOriginTrialToken(token); err == nil {
+			t.Errorf("c.AddOriginTrialToken(%q) returned no error", token)
+		}
+	}
+
+	const key = "dRCs+TocuKkocNKa0AtZ4awrt9XKH2SQCI6o4FY6BNA="
+	if err := c.SetOriginTrialPublicKeys(key); err != nil {
+		t.Fatalf("c.SetOriginTrialPublicKeys() returned error: %v", err)
+	}
+	if want := []string{"--origin-trial-public-key=" + key}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetOriginTrialPublicKeys() set Args to %q, want %q", c.Args, want)
+	}
+	if err := c.SetOriginTrialPublicKeys("c2hvcnQ="); err == nil {
+		t.Error("c.SetOriginTrialPublicKeys() of a short key returned no error")
+	}
+	if err := c.SetOriginTrialPublicKeys(); err != nil || len(c.Args) != 0 {
+		t.Errorf("c.SetOriginTrialPublicKeys() returned %v and left Args %q, want the key removed", err, c.Args)
+	}
+}
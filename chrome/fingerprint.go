@@ -0,0 +1,157 @@
+package chrome
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/mediabuyerbot/go-crx3/pb"
+)
+
+// Fingerprint returns a hash of the capabilities that is stable across
+// processes and equal for capabilities that configure the browser in the same
+// way. It is the SHA-256 of CanonicalJSON, in hexadecimal.
+func (c *Capabilities) Fingerprint() (string, error) {
+	data, err := c.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CanonicalJSON returns the capabilities hashed by Fingerprint, as indented
+// JSON with the keys of every object sorted, so that the canonical forms of
+// two capabilities can be compared to find why their fingerprints differ.
+//
+// Extensions are represented by their IDs, which are derived from their
+// signing key, so that an extension rebuilt and signed with the same key does
+// not change the fingerprint. Args are normalized: only the last occurrence
+// of a switch is kept, as Chrome does, the values of list-valued switches such
+// as --enable-features are merged and sorted, and the arguments are sorted.
+// The scripts added by SeedLocalStorage and AddOriginTrialToken are included.
+func (c *Capabilities) CanonicalJSON() ([]byte, error) {
+	canonical := *c
+	canonical.Args = normalizeArgs(c.Args)
+	canonical.Extensions = nil
+	canonical.initScripts = nil
+
+	var ids []string
+	for i, ext := range c.Extensions {
+		crx, err := base64.StdEncoding.DecodeString(ext)
+		if err != nil {
+			return nil, fmt.Errorf("decoding extension %d: %v", i, err)
+		}
+		id, err := ExtensionID(crx)
+		if err != nil {
+			return nil, fmt.Errorf("extension %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	data, err := json.Marshal(struct {
+		Capabilities
+		ExtensionIDs []string `json:"extensionIds,omitempty"`
+		InitScripts  []string `json:"initScripts,omitempty"`
+	}{canonical, ids, c.initScripts})
+	if err != nil {
+		return nil, err
+	}
+	// Decoding into generic values and encoding again sorts the keys of the
+	// maps, such as Prefs and LocalState, at every level.
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// normalizeArgs returns args with the duplicate switches that Chrome ignores
+// removed, the values of list-valued switches merged and sorted, and the
+// arguments sorted.
+func normalizeArgs(args []string) []string {
+	// Adding the arguments from last to first keeps the last occurrence of
+	// each switch, which is the one that Chrome honors.
+	var c Capabilities
+	for i := len(args) - 1; i >= 0; i-- {
+		c.addDefaultArgs(args[i])
+	}
+	for i, arg := range c.Args {
+		name := switchName(arg)
+		if !listSwitches[name] {
+			continue
+		}
+		merged := strings.TrimPrefix(mergeListSwitch(arg, arg), name+"=")
+		values := strings.Split(merged, ",")
+		sort.Strings(values)
+		c.Args[i] = name + "=" + strings.Join(values, ",")
+	}
+	sort.Strings(c.Args)
+	return c.Args
+}
+
+// ExtensionID returns the ID that Chrome assigns to the extension in a CRX
+// file: the first 128 bits of the SHA-256 of its public key, written with the
+// letters a to p.
+func ExtensionID(crx []byte) (string, error) {
+	if len(crx) < 12 || string(crx[:4]) != "Cr24" {
+		return "", errors.New("not a CRX file")
+	}
+	var id []byte
+	switch version := binary.LittleEndian.Uint32(crx[4:8]); version {
+	case 2:
+		if len(crx) < 16 {
+			return "", errors.New("truncated CRX2 header")
+		}
+		keyLen := binary.LittleEndian.Uint32(crx[8:12])
+		if uint64(len(crx)) < 16+uint64(keyLen) {
+			return "", errors.New("truncated CRX2 public key")
+		}
+		sum := sha256.Sum256(crx[16 : 16+keyLen])
+		id = sum[:16]
+	case 3:
+		headerLen := binary.LittleEndian.Uint32(crx[8:12])
+		if uint64(len(crx)) < 12+uint64(headerLen) {
+			return "", errors.New("truncated CRX3 header")
+		}
+		header := new(pb.CrxFileHeader)
+		if err := proto.Unmarshal(crx[12:12+headerLen], header); err != nil {
+			return "", fmt.Errorf("parsing the CRX3 header: %v", err)
+		}
+		signed := new(pb.SignedData)
+		if err := proto.Unmarshal(header.SignedHeaderData, signed); err != nil {
+			return "", fmt.Errorf("parsing the CRX3 signed data: %v", err)
+		}
+		id = signed.CrxId
+		if len(id) == 0 && len(header.Sha256WithRsa) > 0 {
+			sum := sha256.Sum256(header.Sha256WithRsa[0].PublicKey)
+			id = sum[:16]
+		}
+		if len(id) != 16 {
+			return "", errors.New("the CRX3 header has no extension ID")
+		}
+	default:
+		return "", fmt.Errorf("unsupported CRX version %d", version)
+	}
+
+	b := make([]byte, 0, 2*len(id))
+	for _, c := range hex.EncodeToString(id) {
+		if c <= '9' {
+			b = append(b, byte('a'+c-'0'))
+		} else {
+			b = append(b, byte('a'+10+c-'a'))
+		}
+	}
+	return string(b), nil
+}
@@ -0,0 +1,138 @@
+package chrome
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFingerprintNormalizesArgsAndMaps(t *testing.T) {
+	a := Capabilities{
+		Args: []string{"--headless", "--window-size=800,600", "--enable-features=B,A", "--window-size=1024,768"},
+		Prefs: map[string]interface{}{
+			"profile": map[string]interface{}{"b": 2, "a": 1},
+			"intl":    "en",
+		},
+	}
+	b := Capabilities{
+		Args: []string{"--enable-features=A", "--window-size=1024,768", "--headless", "--enable-features=B"},
+		Prefs: map[string]interface{}{
+			"intl":    "en",
+			"profile": map[string]interface{}{"a": 1, "b": 2},
+		},
+	}
+	fa, err := a.Fingerprint()
+	if err != nil {
+		t.Fatalf("a.Fingerprint() returned error: %v", err)
+	}
+	fb, err := b.Fingerprint()
+	if err != nil {
+		t.Fatalf("b.Fingerprint() returned error: %v", err)
+	}
+	if fa != fb {
+		ja, _ := a.CanonicalJSON()
+		jb, _ := b.CanonicalJSON()
+		t.Fatalf("fingerprints differ for equivalent capabilities:\n%s\n%s", ja, jb)
+	}
+
+	b.Args = append(b.Args, "--window-size=640,480")
+	fb, err = b.Fingerprint()
+	if err != nil {
+		t.Fatalf("b.Fingerprint() returned error: %v", err)
+	}
+	if fa == fb {
+		t.Error("fingerprints are equal for different window sizes")
+	}
+}
+
+func TestFingerprintUsesExtensionIDs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-fingerprint-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := `{"name": "test", "version": "1.0", "manifest_version": 2}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fingerprint := func(key *rsa.PrivateKey) string {
+		t.Helper()
+		crx, err := NewExtensionWithKey(dir, key)
+		if err != nil {
+			t.Fatalf("NewExtensionWithKey() returned error: %v", err)
+		}
+		c := Capabilities{Extensions: []string{base64.StdEncoding.EncodeToString(crx)}}
+		f, err := c.Fingerprint()
+		if err != nil {
+			t.Fatalf("c.Fingerprint() returned error: %v", err)
+		}
+		return f
+	}
+
+	first := fingerprint(key)
+	// Signatures are randomized, so the second file differs from the first.
+	if second := fingerprint(key); second != first {
+		t.Error("re-signing the extension with the same key changed the fingerprint")
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other := fingerprint(otherKey); other == first {
+		t.Error("signing the extension with another key did not change the fingerprint")
+	}
+}
+
+func TestExtensionID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-fingerprint-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crx, err := NewExtensionWithKey(dir, key)
+	if err != nil {
+		t.Fatalf("NewExtensionWithKey() returned error: %v", err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(pub)
+	var want strings.Builder
+	for _, b := range sum[:16] {
+		want.WriteByte('a' + b>>4)
+		want.WriteByte('a' + b&0xF)
+	}
+
+	got, err := ExtensionID(crx)
+	if err != nil {
+		t.Fatalf("ExtensionID() returned error: %v", err)
+	}
+	if got != want.String() {
+		t.Errorf("ExtensionID() = %q, want %q", got, want.String())
+	}
+
+	if _, err := ExtensionID([]byte("PK\x03\x04")); err == nil {
+		t.Error("ExtensionID() of a ZIP file returned no error")
+	}
+}
@@ -0,0 +1,71 @@
+package chrome
+
+import "strconv"
+
+// HeadlessMode selects whether and how Chrome runs without a window; see
+// SetHeadless.
+type HeadlessMode int
+
+const (
+	// HeadlessOff runs Chrome with a window.
+	HeadlessOff HeadlessMode = iota
+	// HeadlessNew runs the regular browser without a window, which behaves
+	// like a headful Chrome. It requires Chrome 109 or later, and is the
+	// default headless mode since Chrome 112.
+	HeadlessNew
+	// HeadlessOld runs the former, separate implementation of headless
+	// Chrome, which starts faster but differs from the regular browser. It
+	// was removed from Chrome 132, after which it is the
+	// chrome-headless-shell binary, which is headless without any argument.
+	HeadlessOld
+)
+
+// headlessSwitch is the command-line switch of the headless modes.
+const headlessSwitch = "--headless"
+
+// SetHeadless sets the headless mode of Chrome, replacing any headless
+// switch in c.Args. Headless Chrome has a window size of 800x600 unless
+// SetWindowSize is used.
+func (c *Capabilities) SetHeadless(mode HeadlessMode) {
+	switch mode {
+	case HeadlessNew:
+		c.setSwitch(headlessSwitch, "new")
+	case HeadlessOld:
+		c.setSwitch(headlessSwitch, "old")
+	default:
+		c.setSwitch(headlessSwitch, "")
+	}
+}
+
+// WindowSize is the size of the browser window, in CSS pixels.
+type WindowSize struct {
+	Width, Height int
+}
+
+// Common window sizes.
+var (
+	WindowSizeLaptop = WindowSize{Width: 1366, Height: 768}
+	WindowSizeHD     = WindowSize{Width: 1280, Height: 720}
+	WindowSizeFullHD = WindowSize{Width: 1920, Height: 1080}
+)
+
+// SetWindowSize sets the initial size of the browser window, replacing any
+// --window-size switch in c.Args. It also applies to headless Chrome, whose
+// screen is resized to match.
+func (c *Capabilities) SetWindowSize(s WindowSize) {
+	c.setSwitch("--window-size", strconv.Itoa(s.Width)+","+strconv.Itoa(s.Height))
+}
+
+// DisableGPU disables the hardware acceleration of Chrome, for machines
+// without a GPU where its initialization fails or is slow, such as CI
+// runners. Rendering is done in software.
+func (c *Capabilities) DisableGPU() {
+	c.addDefaultArgs("--disable-gpu")
+}
+
+// DisableSandbox disables the sandbox of Chrome's processes, which cannot
+// start when Chrome runs as root, e.g. in a CI job, or without user
+// namespaces. It is unsafe for untrusted pages; see also ForContainers.
+func (c *Capabilities) DisableSandbox() {
+	c.addDefaultArgs("--no-sandbox")
+}
@@ -0,0 +1,35 @@
+package chrome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetHeadless(t *testing.T) {
+	c := Capabilities{Args: []string{"--headless", "--lang=fr"}}
+	c.SetHeadless(HeadlessNew)
+	if want := []string{"--lang=fr", "--headless=new"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("after c.SetHeadless(HeadlessNew), c.Args = %q, want %q", c.Args, want)
+	}
+	c.SetHeadless(HeadlessOld)
+	if want := []string{"--lang=fr", "--headless=old"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("after c.SetHeadless(HeadlessOld), c.Args = %q, want %q", c.Args, want)
+	}
+	c.SetHeadless(HeadlessOff)
+	if want := []string{"--lang=fr"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("after c.SetHeadless(HeadlessOff), c.Args = %q, want %q", c.Args, want)
+	}
+}
+
+func TestCIPresets(t *testing.T) {
+	c := Capabilities{Args: []string{"--window-size=800,600", "--no-sandbox"}}
+	c.SetHeadless(HeadlessNew)
+	c.SetWindowSize(WindowSizeFullHD)
+	c.DisableGPU()
+	c.DisableSandbox()
+	c.DisableGPU()
+	want := []string{"--no-sandbox", "--headless=new", "--window-size=1920,1080", "--disable-gpu"}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.Args = %q, want %q", c.Args, want)
+	}
+}
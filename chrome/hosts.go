@@ -0,0 +1,90 @@
+package chrome
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// hostPatternRE matches the host patterns of the host resolver rules: a host
+// name, optionally prefixed by a "*." wildcard, or "*" alone.
+var hostPatternRE = regexp.MustCompile(`^(\*|(\*\.)?[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*)$`)
+
+// hostNameRE matches the host names and IPv4 addresses that hosts can be
+// mapped to.
+var hostNameRE = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+
+// MapHosts makes Chrome resolve the host names matching the keys of rules to
+// their values, with the --host-resolver-rules switch, e.g. to serve
+// "www.example.test" from a test server:
+//
+//	c.MapHosts(map[string]string{"www.example.test": "127.0.0.1"})
+//
+// A key is a host name, "*.example.test" for its subdomains or "*" for all
+// hosts. A value is a host name or an IP address, bracketed for IPv6, with
+// an optional port that replaces the port of the URL, or "~NOTFOUND" to fail
+// the resolution. localhost is excluded from the rules, as ChromeDriver
+// connects to Chrome through it, and cannot be mapped. No rules removes the
+// setting, which replaces the --host-resolver-rules switch in Args,
+// including the rules of SetSOCKSRemoteDNS.
+func (c *Capabilities) MapHosts(rules map[string]string) error {
+	if len(rules) == 0 {
+		c.setSwitch(hostResolverRulesSwitch, "")
+		return nil
+	}
+	hosts := make([]string, 0, len(rules))
+	for host, target := range rules {
+		if !hostPatternRE.MatchString(host) {
+			return fmt.Errorf("invalid host pattern %q: want a host name, %q or %q", host, "*.example.com", "*")
+		}
+		if strings.EqualFold(host, "localhost") {
+			return fmt.Errorf("cannot map localhost: ChromeDriver connects to Chrome through it")
+		}
+		if err := checkHostTarget(target); err != nil {
+			return fmt.Errorf("invalid address %q for host %q: %v", target, host, err)
+		}
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var mappings []string
+	for _, host := range hosts {
+		mappings = append(mappings, "MAP "+host+" "+rules[host])
+	}
+	mappings = append(mappings, "EXCLUDE localhost")
+	c.setSwitch(hostResolverRulesSwitch, strings.Join(mappings, " , "))
+	return nil
+}
+
+// checkHostTarget checks the address a host is mapped to.
+func checkHostTarget(target string) error {
+	if target == "~NOTFOUND" {
+		return nil
+	}
+	host, port := target, ""
+	if strings.Contains(target, ":") && !strings.HasSuffix(target, "]") {
+		var err error
+		if host, port, err = net.SplitHostPort(target); err != nil {
+			return fmt.Errorf("%v; bracket IPv6 addresses", err)
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return fmt.Errorf("invalid port %q", port)
+		}
+		if strings.Contains(host, ":") {
+			target = "[" + host + "]"
+		}
+	}
+	if strings.HasPrefix(target, "[") {
+		ip := net.ParseIP(strings.Trim(target[:strings.Index(target, "]")+1], "[]"))
+		if ip == nil || ip.To4() != nil {
+			return fmt.Errorf("want an IPv6 address between the brackets")
+		}
+		return nil
+	}
+	if !hostNameRE.MatchString(host) {
+		return fmt.Errorf("want a host name or an IP address")
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package chrome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapHosts(t *testing.T) {
+	c := Capabilities{Args: []string{"--headless"}}
+	c.SetSOCKSRemoteDNS("proxy.example")
+	if err := c.MapHosts(map[string]string{
+		"www.example.test":   "127.0.0.1",
+		"*.cdn.example.test": "[::1]:8443",
+		"api.example.test":   "backend.internal:8080",
+	}); err != nil {
+		t.Fatalf("c.MapHosts() returned error: %v", err)
+	}
+	want := []string{"--headless", "--host-resolver-rules=MAP *.cdn.example.test [::1]:8443 , MAP api.example.test backend.internal:8080 , MAP www.example.test 127.0.0.1 , EXCLUDE localhost"}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.MapHosts() set Args to %q, want %q", c.Args, want)
+	}
+
+	if err := c.MapHosts(nil); err != nil {
+		t.Fatalf("c.MapHosts(nil) returned error: %v", err)
+	}
+	if want := []string{"--headless"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.MapHosts(nil) set Args to %q, want %q", c.Args, want)
+	}
+
+	for _, rule := range []map[string]string{
+		{"localhost": "127.0.0.1"},
+		{"www.example.test,evil": "127.0.0.1"},
+		{"http://www.example.test": "127.0.0.1"},
+		{"www.*.test": "127.0.0.1"},
+		{"www.example.test": ""},
+		{"www.example.test": "127.0.0.1 , MAP * ~NOTFOUND"},
+		{"www.example.test": "::1"},
+		{"www.example.test": "[127.0.0.1]"},
+		{"www.example.test": "127.0.0.1:0"},
+		{"www.example.test": "127.0.0.1:http"},
+	} {
+		c := Capabilities{}
+		if err := c.MapHosts(rule); err == nil {
+			t.Errorf("c.MapHosts(%q) returned no error", rule)
+		}
+		if len(c.Args) != 0 {
+			t.Errorf("c.MapHosts(%q) failed but set Args to %q", rule, c.Args)
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package chrome
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Driver is the subset of selenium.WebDriver used to read Chrome's internal
+// pages; any selenium.WebDriver can be passed.
+type Driver interface {
+	Get(url string) error
+	ExecuteScriptRaw(script string, args []interface{}) ([]byte, error)
+}
+
+// PageParseError is returned when an internal page was loaded, but its
+// content could not be fully parsed, e.g. because its layout changed or the
+// browser is not Chrome. Text holds the text of the page, so that it can
+// still be logged.
+type PageParseError struct {
+	// URL is the URL of the page.
+	URL string
+	// Text is the text of the page, including its shadow trees.
+	Text string
+	// Missing describes what could not be found on the page.
+	Missing []string
+}
+
+func (e *PageParseError) Error() string {
+	return fmt.Sprintf("parsing %s: %s not found", e.URL, strings.Join(e.Missing, ", "))
+}
+
+// readPageScript returns the text of the page, including the shadow trees,
+// the text of the elements with an ID and the fields of the elements whose
+// tag name is the first argument, found within their shadow trees by class.
+const readPageScript = `
+	var rowTag = arguments[0], fields = arguments[1];
+	var roots = [document];
+	var byID = {}, rows = [];
+	var text = function(root) {
+		var parts = [];
+		var walk = function(node) {
+			if (node.nodeType === Node.TEXT_NODE) {
+				parts.push(node.textContent);
+				return;
+			}
+			if (node.shadowRoot) {
+				walk(node.shadowRoot);
+			}
+			for (var c = node.firstChild; c; c = c.nextSibling) {
+				walk(c);
+			}
+		};
+		walk(root);
+		return parts.join(' ').replace(/\s+/g, ' ').trim();
+	};
+	var find = function(root, selector) {
+		var found = root.querySelector(selector);
+		if (found) {
+			return found;
+		}
+		var all = root.querySelectorAll('*');
+		for (var i = 0; i < all.length; i++) {
+			if (all[i].shadowRoot) {
+				found = find(all[i].shadowRoot, selector);
+				if (found) {
+					return found;
+				}
+			}
+		}
+		return null;
+	};
+	for (var i = 0; i < roots.length; i++) {
+		var all = roots[i].querySelectorAll('*');
+		for (var j = 0; j < all.length; j++) {
+			var el = all[j];
+			if (el.id && !(el.id in byID)) {
+				byID[el.id] = text(el);
+			}
+			if (rowTag && el.tagName.toLowerCase() === rowTag) {
+				var row = {};
+				fields.forEach(function(f) {
+					var cell = find(el.shadowRoot || el, '.' + f);
+					if (cell) {
+						row[f] = text(cell);
+					}
+				});
+				rows.push(row);
+			}
+			if (el.shadowRoot) {
+				roots.push(el.shadowRoot);
+			}
+		}
+	}
+	return {text: text(document.documentElement), ids: byID, rows: rows};`
+
+type pageContent struct {
+	Text string
+	IDs  map[string]string
+	Rows []map[string]string
+}
+
+// readPage loads the page and extracts its content with readPageScript.
+func readPage(wd Driver, url, rowTag string, fields []string) (*pageContent, error) {
+	if err := wd.Get(url); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		fields = []string{}
+	}
+	data, err := wd.ExecuteScriptRaw(readPageScript, []interface{}{rowTag, fields})
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value pageContent })
+	if err := json.Unmarshal(data, reply); err != nil {
+		return nil, err
+	}
+	return &reply.Value, nil
+}
+
+// VersionInfo is the content of chrome://version.
+type VersionInfo struct {
+	// Version is the version of Chrome and its channel, e.g.
+	// "114.0.5735.198 (Official Build) (64-bit)".
+	Version string
+	// Revision is the source revision of the build.
+	Revision string
+	// OS is the operating system and its version.
+	OS string
+	// JavaScript is the version of V8.
+	JavaScript string
+	// UserAgent is the user agent string of the browser.
+	UserAgent string
+	// CommandLine is the command line of the browser.
+	CommandLine string
+	// ExecutablePath is the path to the browser binary.
+	ExecutablePath string
+	// ProfilePath is the path to the profile directory.
+	ProfilePath string
+}
+
+// versionPageIDs maps the IDs of the elements of chrome://version to the
+// fields of VersionInfo.
+var versionPageIDs = []struct {
+	id    string
+	field func(*VersionInfo) *string
+}{
+	{"version", func(v *VersionInfo) *string { return &v.Version }},
+	{"revision", func(v *VersionInfo) *string { return &v.Revision }},
+	{"os_type", func(v *VersionInfo) *string { return &v.OS }},
+	{"js_engine", func(v *VersionInfo) *string { return &v.JavaScript }},
+	{"useragent", func(v *VersionInfo) *string { return &v.UserAgent }},
+	{"command_line", func(v *VersionInfo) *string { return &v.CommandLine }},
+	{"executable_path", func(v *VersionInfo) *string { return &v.ExecutablePath }},
+	{"profile_path", func(v *VersionInfo) *string { return &v.ProfilePath }},
+}
+
+// VersionURL is the URL of Chrome's version page.
+const VersionURL = "chrome://version"
+
+// ReadVersionPage loads chrome://version in the current tab and returns its
+// content, e.g. to log the exact binary used by a test. Fields missing from
+// the page are left empty; if the version itself is missing, the error is a
+// *PageParseError holding the text of the page.
+func ReadVersionPage(wd Driver) (VersionInfo, error) {
+	var info VersionInfo
+	page, err := readPage(wd, VersionURL, "", nil)
+	if err != nil {
+		return info, err
+	}
+	for _, f := range versionPageIDs {
+		*f.field(&info) = page.IDs[f.id]
+	}
+	if info.Version == "" {
+		return info, &PageParseError{URL: VersionURL, Text: page.Text, Missing: []string{"version"}}
+	}
+	return info, nil
+}
+
+// PolicyValue is a policy listed by chrome://policy.
+type PolicyValue struct {
+	// Value is the value of the policy, as displayed; lists and dictionaries
+	// are shown as JSON.
+	Value string
+	// Scope is "Machine" or "User".
+	Scope string
+	// Level is "Mandatory" or "Recommended".
+	Level string
+	// Source tells where the policy was set, e.g. "Platform".
+	Source string
+	// Status is "OK" or describes why the policy was not applied.
+	Status string
+}
+
+// PolicyURL is the URL of Chrome's policy page.
+const PolicyURL = "chrome://policy"
+
+// policyRowTag is the tag name of the rows of chrome://policy, whose cells
+// are in their shadow trees.
+const policyRowTag = "policy-row"
+
+// ReadPolicyPage loads chrome://policy in the current tab and returns the
+// policies it lists by name, e.g. to check that enterprise policies were
+// applied. If the page lists no policy row that could be parsed, the error is
+// a *PageParseError holding the text of the page. Chrome displays the page
+// under automation without additional command-line arguments.
+func ReadPolicyPage(wd Driver) (map[string]PolicyValue, error) {
+	fields := []string{"name", "value", "scope", "level", "source", "messages"}
+	page, err := readPage(wd, PolicyURL, policyRowTag, fields)
+	if err != nil {
+		return nil, err
+	}
+	policies := make(map[string]PolicyValue)
+	malformed := 0
+	for _, row := range page.Rows {
+		name := row["name"]
+		if name == "" {
+			malformed++
+			continue
+		}
+		policies[name] = PolicyValue{
+			Value:  row["value"],
+			Scope:  row["scope"],
+			Level:  row["level"],
+			Source: row["source"],
+			Status: row["messages"],
+		}
+	}
+	switch {
+	case len(page.Rows) == 0 && !strings.Contains(page.Text, "No policies set"):
+		return policies, &PageParseError{URL: PolicyURL, Text: page.Text, Missing: []string{"policy rows"}}
+	case malformed > 0:
+		return policies, &PageParseError{URL: PolicyURL, Text: page.Text, Missing: []string{fmt.Sprintf("the names of %d policies", malformed)}}
+	}
+	return policies, nil
+}
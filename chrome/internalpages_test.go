@@ -0,0 +1,111 @@
+package chrome
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeDriver serves the content of a page to readPageScript.
+type fakeDriver struct {
+	url  string
+	page pageContent
+}
+
+func (d *fakeDriver) Get(url string) error {
+	d.url = url
+	return nil
+}
+
+func (d *fakeDriver) ExecuteScriptRaw(script string, args []interface{}) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{"value": d.page})
+}
+
+func TestReadVersionPage(t *testing.T) {
+	d := &fakeDriver{page: pageContent{
+		IDs: map[string]string{
+			"version":         "114.0.5735.198 (Official Build) (64-bit)",
+			"os_type":         "Linux",
+			"command_line":    "/opt/google/chrome/chrome --headless",
+			"executable_path": "/opt/google/chrome/chrome",
+		},
+	}}
+	got, err := ReadVersionPage(d)
+	if err != nil {
+		t.Fatalf("ReadVersionPage() returned error: %v", err)
+	}
+	if d.url != VersionURL {
+		t.Errorf("ReadVersionPage() loaded %q, want %q", d.url, VersionURL)
+	}
+	want := VersionInfo{
+		Version:        "114.0.5735.198 (Official Build) (64-bit)",
+		OS:             "Linux",
+		CommandLine:    "/opt/google/chrome/chrome --headless",
+		ExecutablePath: "/opt/google/chrome/chrome",
+	}
+	if got != want {
+		t.Errorf("ReadVersionPage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadVersionPageUnknownLayout(t *testing.T) {
+	d := &fakeDriver{page: pageContent{Text: "Firefox 115.0"}}
+	_, err := ReadVersionPage(d)
+	var parseErr *PageParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ReadVersionPage() returned error %v, want a *PageParseError", err)
+	}
+	if parseErr.Text != "Firefox 115.0" {
+		t.Errorf("PageParseError.Text = %q, want the text of the page", parseErr.Text)
+	}
+}
+
+func TestReadPolicyPage(t *testing.T) {
+	d := &fakeDriver{page: pageContent{
+		Rows: []map[string]string{
+			{"name": "HomepageLocation", "value": "https://example.com", "scope": "Machine", "level": "Mandatory", "source": "Platform", "messages": "OK"},
+			{"name": "URLBlocklist", "value": `["example.org"]`, "scope": "User", "level": "Recommended", "source": "Cloud"},
+		},
+	}}
+	got, err := ReadPolicyPage(d)
+	if err != nil {
+		t.Fatalf("ReadPolicyPage() returned error: %v", err)
+	}
+	want := map[string]PolicyValue{
+		"HomepageLocation": {Value: "https://example.com", Scope: "Machine", Level: "Mandatory", Source: "Platform", Status: "OK"},
+		"URLBlocklist":     {Value: `["example.org"]`, Scope: "User", Level: "Recommended", Source: "Cloud"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadPolicyPage() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReadPolicyPageEmpty(t *testing.T) {
+	d := &fakeDriver{page: pageContent{Text: "Chrome Policies No policies set"}}
+	got, err := ReadPolicyPage(d)
+	if err != nil {
+		t.Fatalf("ReadPolicyPage() returned error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("ReadPolicyPage() = %+v, want no policies", got)
+	}
+}
+
+func TestReadPolicyPagePartial(t *testing.T) {
+	d := &fakeDriver{page: pageContent{
+		Text: "HomepageLocation https://example.com ???",
+		Rows: []map[string]string{
+			{"name": "HomepageLocation", "value": "https://example.com"},
+			{"value": "???"},
+		},
+	}}
+	got, err := ReadPolicyPage(d)
+	var parseErr *PageParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("ReadPolicyPage() returned error %v, want a *PageParseError", err)
+	}
+	if _, ok := got["HomepageLocation"]; !ok {
+		t.Errorf("ReadPolicyPage() = %+v, want the policies that could be parsed", got)
+	}
+}
@@ -0,0 +1,224 @@
+package chrome
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LinuxPolicyDir is the directory from which Chrome on Linux reads mandatory
+// policies, in JSON files. Chromium reads /etc/chromium/policies/managed.
+const LinuxPolicyDir = "/etc/opt/chrome/policies/managed"
+
+// policyFileName is the name of the file written by WritePolicies.
+const policyFileName = "selenium.json"
+
+// validateExtensionID checks that id is an extension ID: 32 letters from a to
+// p, as returned by ExtensionID.
+func validateExtensionID(id string) error {
+	if len(id) == 32 && strings.Trim(id, "abcdefghijklmnop") == "" {
+		return nil
+	}
+	if strings.ContainsAny(id, `/\.`) {
+		return fmt.Errorf("%q is not an extension ID but looks like a path; use ExtensionID to compute the ID of a CRX file", id)
+	}
+	return fmt.Errorf("%q is not an extension ID, which is made of 32 letters from a to p", id)
+}
+
+// setPolicy sets a policy to be written by WritePolicies.
+func (c *Capabilities) setPolicy(name string, value interface{}) {
+	if c.policies == nil {
+		c.policies = make(map[string]interface{})
+	}
+	c.policies[name] = value
+}
+
+// extensionSettings returns the settings of an extension in the
+// ExtensionSettings policy, creating them if needed.
+func (c *Capabilities) extensionSettings(id string) map[string]interface{} {
+	all, _ := c.policies["ExtensionSettings"].(map[string]interface{})
+	if all == nil {
+		all = make(map[string]interface{})
+		c.setPolicy("ExtensionSettings", all)
+	}
+	settings, _ := all[id].(map[string]interface{})
+	if settings == nil {
+		settings = make(map[string]interface{})
+		all[id] = settings
+	}
+	return settings
+}
+
+// ForceInstallExtension makes Chrome install the extension with the given ID
+// from updateURL, the URL of its update manifest, and keep the user from
+// disabling or removing it. Use NewExtensionUpdateServer to serve a local CRX
+// file.
+//
+// Chrome only honors this through policies, which cannot be passed to
+// ChromeDriver: the policies are written by WritePolicies, which must be
+// called before the browser starts.
+func (c *Capabilities) ForceInstallExtension(id, updateURL string) error {
+	if err := validateExtensionID(id); err != nil {
+		return err
+	}
+	if updateURL == "" {
+		return errors.New("empty update URL")
+	}
+	entry := id + ";" + updateURL
+	list, _ := c.policies["ExtensionInstallForcelist"].([]string)
+	found := false
+	for i, e := range list {
+		if strings.HasPrefix(e, id+";") {
+			list[i], found = entry, true
+		}
+	}
+	if !found {
+		list = append(list, entry)
+	}
+	c.setPolicy("ExtensionInstallForcelist", list)
+	settings := c.extensionSettings(id)
+	settings["installation_mode"] = "force_installed"
+	settings["update_url"] = updateURL
+	return nil
+}
+
+// PinExtensionToToolbar makes Chrome show the icon of the extension with the
+// given ID in the toolbar, rather than in the extensions menu. It sets the
+// preference of the profile, which Chrome honors for any extension, and the
+// policy written by WritePolicies, which keeps the user from unpinning it.
+func (c *Capabilities) PinExtensionToToolbar(id string) error {
+	if err := validateExtensionID(id); err != nil {
+		return err
+	}
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]interface{})
+	}
+	pinned, _ := c.Prefs["extensions.pinned_extensions"].([]interface{})
+	for _, p := range pinned {
+		if p == id {
+			return nil
+		}
+	}
+	c.Prefs["extensions.pinned_extensions"] = append(pinned, id)
+	c.extensionSettings(id)["toolbar_pin"] = "force_pinned"
+	return nil
+}
+
+// Policies returns the policies set by ForceInstallExtension,
+// PinExtensionToToolbar and AutoSelectClientCertificate, by name.
+func (c *Capabilities) Policies() map[string]interface{} {
+	return c.policies
+}
+
+// WritePolicies writes the policies set by ForceInstallExtension,
+// PinExtensionToToolbar and AutoSelectClientCertificate to a JSON file in
+// dir, and returns its path. Pass LinuxPolicyDir, which requires root
+// privileges, when Chrome runs on this Linux machine; on other systems,
+// apply Policies with the platform tools. Chrome reads the policies when it
+// starts.
+func (c *Capabilities) WritePolicies(dir string) (string, error) {
+	data, err := json.MarshalIndent(c.policies, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, policyFileName)
+	return path, ioutil.WriteFile(path, data, 0644)
+}
+
+// ExtensionUpdateServer serves a CRX file and its update manifest, so that a
+// locally built extension can be force-installed without the Chrome Web
+// Store. Close it when done.
+type ExtensionUpdateServer struct {
+	*httptest.Server
+	// ID and Version are the ID and the version of the extension.
+	ID      string
+	Version string
+}
+
+// UpdateURL returns the URL of the update manifest, to pass to
+// ForceInstallExtension.
+func (s *ExtensionUpdateServer) UpdateURL() string {
+	return s.URL + "/update.xml"
+}
+
+// updateManifest is the update manifest served by ExtensionUpdateServer, in
+// the format of the Chrome Web Store.
+const updateManifest = `<?xml version='1.0' encoding='UTF-8'?>
+<gupdate xmlns='http://www.google.com/update2/response' protocol='2.0'>
+  <app appid='%s'>
+    <updatecheck codebase='%s/extension.crx' version='%s' />
+  </app>
+</gupdate>
+`
+
+// NewExtensionUpdateServer starts a server on the loopback interface that
+// serves crx, the content of a CRX file as returned by NewExtension.
+func NewExtensionUpdateServer(crx []byte) (*ExtensionUpdateServer, error) {
+	id, err := ExtensionID(crx)
+	if err != nil {
+		return nil, err
+	}
+	version, err := crxVersion(crx)
+	if err != nil {
+		return nil, err
+	}
+	s := &ExtensionUpdateServer{ID: id, Version: version}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/update.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, updateManifest, s.ID, s.URL, s.Version)
+	})
+	mux.HandleFunc("/extension.crx", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-chrome-extension")
+		w.Write(crx)
+	})
+	s.Server = httptest.NewServer(mux)
+	return s, nil
+}
+
+// crxVersion returns the version in the manifest of a CRX3 file.
+func crxVersion(crx []byte) (string, error) {
+	if len(crx) < 12 || string(crx[:4]) != "Cr24" || binary.LittleEndian.Uint32(crx[4:8]) != 3 {
+		return "", errors.New("not a CRX3 file")
+	}
+	start := 12 + uint64(binary.LittleEndian.Uint32(crx[8:12]))
+	if uint64(len(crx)) < start {
+		return "", errors.New("truncated CRX3 header")
+	}
+	archive := crx[start:]
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return "", err
+	}
+	for _, f := range r.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+		manifest := new(struct{ Version string })
+		if err := json.NewDecoder(rc).Decode(manifest); err != nil {
+			return "", fmt.Errorf("parsing manifest.json: %v", err)
+		}
+		if manifest.Version == "" {
+			return "", errors.New("manifest.json has no version")
+		}
+		return manifest.Version, nil
+	}
+	return "", errors.New("the CRX file has no manifest.json")
+}
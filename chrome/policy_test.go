@@ -0,0 +1,119 @@
+package chrome
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testExtensionID = "abcdefghijklmnopabcdefghijklmnop"
+
+func TestForceInstallExtension(t *testing.T) {
+	var c Capabilities
+	if err := c.ForceInstallExtension(testExtensionID, "https://example.com/old.xml"); err != nil {
+		t.Fatalf("c.ForceInstallExtension() returned error: %v", err)
+	}
+	if err := c.ForceInstallExtension(testExtensionID, "https://example.com/update.xml"); err != nil {
+		t.Fatalf("c.ForceInstallExtension() returned error: %v", err)
+	}
+	if err := c.PinExtensionToToolbar(testExtensionID); err != nil {
+		t.Fatalf("c.PinExtensionToToolbar() returned error: %v", err)
+	}
+
+	dir, err := ioutil.TempDir("", "chrome-policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path, err := c.WritePolicies(dir)
+	if err != nil {
+		t.Fatalf("c.WritePolicies() returned error: %v", err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("the policy file is not JSON: %v", err)
+	}
+	want := map[string]interface{}{
+		"ExtensionInstallForcelist": []interface{}{testExtensionID + ";https://example.com/update.xml"},
+		"ExtensionSettings": map[string]interface{}{
+			testExtensionID: map[string]interface{}{
+				"installation_mode": "force_installed",
+				"update_url":        "https://example.com/update.xml",
+				"toolbar_pin":       "force_pinned",
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("c.WritePolicies() wrote %s, want %v", data, want)
+	}
+	if pinned := c.Prefs["extensions.pinned_extensions"]; !reflect.DeepEqual(pinned, []interface{}{testExtensionID}) {
+		t.Errorf("c.Prefs[extensions.pinned_extensions] = %v, want [%s]", pinned, testExtensionID)
+	}
+}
+
+func TestValidateExtensionID(t *testing.T) {
+	for id, hint := range map[string]string{
+		"./build/extension.crx": "looks like a path",
+		"abc":                   "32 letters",
+		strings.Repeat("z", 32): "32 letters",
+	} {
+		err := validateExtensionID(id)
+		if err == nil || !strings.Contains(err.Error(), hint) {
+			t.Errorf("validateExtensionID(%q) returned error %v, want it to mention %q", id, err, hint)
+		}
+	}
+	if err := validateExtensionID(testExtensionID); err != nil {
+		t.Errorf("validateExtensionID(%q) returned error: %v", testExtensionID, err)
+	}
+}
+
+func TestExtensionUpdateServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-policy-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	manifest := `{"name": "test", "version": "1.2.3", "manifest_version": 3}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crx, err := NewExtensionWithKey(dir, key)
+	if err != nil {
+		t.Fatalf("NewExtensionWithKey() returned error: %v", err)
+	}
+
+	s, err := NewExtensionUpdateServer(crx)
+	if err != nil {
+		t.Fatalf("NewExtensionUpdateServer() returned error: %v", err)
+	}
+	defer s.Close()
+	if s.Version != "1.2.3" {
+		t.Errorf("s.Version = %q, want %q", s.Version, "1.2.3")
+	}
+	resp, err := http.Get(s.UpdateURL())
+	if err != nil {
+		t.Fatalf("fetching the update manifest returned error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	for _, want := range []string{"appid='" + s.ID + "'", "codebase='" + s.URL + "/extension.crx'", "version='1.2.3'"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("the update manifest %s does not contain %s", body, want)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package chrome
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	userDataDirFlag      = "--user-data-dir="
+	profileDirectoryFlag = "--profile-directory="
+)
+
+// UseTempUserDataDir creates a new temporary directory and makes Chrome use it
+// as its user data directory. The path of the directory is returned; the
+// caller is responsible for removing it once the browser has exited.
+//
+// Any user data directory already set in Args is replaced.
+func (c *Capabilities) UseTempUserDataDir() (string, error) {
+	dir, err := ioutil.TempDir("", tempUserDataDirPrefix)
+	if err != nil {
+		return "", err
+	}
+	c.SetUserDataDir(dir)
+	return dir, nil
+}
+
+// SetUserDataDir makes Chrome use dir as its user data directory. Any user
+// data directory already set in Args is replaced.
+func (c *Capabilities) SetUserDataDir(dir string) {
+	var args []string
+	for _, arg := range c.Args {
+		if !strings.HasPrefix(arg, userDataDirFlag) {
+			args = append(args, arg)
+		}
+	}
+	c.Args = append(args, userDataDirFlag+dir)
+}
+
+// UserDataDir returns the user data directory set in Args, or the empty string
+// if Chrome will use a default one.
+func (c *Capabilities) UserDataDir() string {
+	return c.flagValue(userDataDirFlag)
+}
+
+// flagValue returns the value of the last argument with the given prefix.
+func (c *Capabilities) flagValue(prefix string) string {
+	var value string
+	for _, arg := range c.Args {
+		if strings.HasPrefix(arg, prefix) {
+			value = strings.TrimPrefix(arg, prefix)
+		}
+	}
+	return value
+}
+
+// profileDir returns the directory of the profile that Chrome will use,
+// creating a temporary user data directory if none was configured.
+func (c *Capabilities) profileDir() (string, error) {
+	dir := c.UserDataDir()
+	if dir == "" {
+		var err error
+		if dir, err = c.UseTempUserDataDir(); err != nil {
+			return "", err
+		}
+	}
+	profile := c.flagValue(profileDirectoryFlag)
+	if profile == "" {
+		profile = "Default"
+	}
+	return filepath.Join(dir, profile), nil
+}
+
+// Bookmark is an entry in the bookmark bar. A Bookmark without a URL is a
+// folder containing Children.
+type Bookmark struct {
+	Name     string
+	URL      string
+	Children []Bookmark
+}
+
+// SeedBookmarks writes bookmarks into the bookmark bar of the profile, so that
+// they are present when Chrome starts. If no user data directory was
+// configured, a temporary one is created as if by UseTempUserDataDir.
+//
+// The file is written to the local disk, so the browser must be launched on
+// this machine. Bookmarks already in the profile are replaced.
+func (c *Capabilities) SeedBookmarks(bookmarks []Bookmark) error {
+	dir, err := c.profileDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	id := 3 // IDs 1 to 3 are taken by the roots.
+	var nodes func([]Bookmark) ([]interface{}, error)
+	nodes = func(bookmarks []Bookmark) ([]interface{}, error) {
+		list := []interface{}{}
+		for _, b := range bookmarks {
+			id++
+			node := map[string]interface{}{
+				"id":         strconv.Itoa(id),
+				"name":       b.Name,
+				"date_added": "0",
+			}
+			if b.URL == "" {
+				children, err := nodes(b.Children)
+				if err != nil {
+					return nil, err
+				}
+				node["type"] = "folder"
+				node["date_modified"] = "0"
+				node["children"] = children
+			} else {
+				if len(b.Children) > 0 {
+					return nil, fmt.Errorf("bookmark %q has both a URL and children", b.Name)
+				}
+				node["type"] = "url"
+				node["url"] = b.URL
+			}
+			list = append(list, node)
+		}
+		return list, nil
+	}
+	children, err := nodes(bookmarks)
+	if err != nil {
+		return err
+	}
+
+	root := func(id, name string, children []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":            id,
+			"name":          name,
+			"type":          "folder",
+			"date_added":    "0",
+			"date_modified": "0",
+			"children":      children,
+		}
+	}
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"version": 1,
+		"roots": map[string]interface{}{
+			"bookmark_bar": root("1", "Bookmarks bar", children),
+			"other":        root("2", "Other bookmarks", []interface{}{}),
+			"synced":       root("3", "Mobile bookmarks", []interface{}{}),
+		},
+	}, "", "   ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "Bookmarks"), data, 0600)
+}
+
+// seedLocalStorageScript sets the keys of a localStorage seed that are not yet
+// present, the first time a document of the origin is loaded in a tab. The
+// sessionStorage marker keeps the seed from being reapplied after the test
+// has modified the storage.
+const seedLocalStorageScript = `(function(origin, kv) {
+	if (window.location.origin !== origin) {
+		return;
+	}
+	try {
+		var marker = '__selenium_seeded_local_storage__';
+		if (window.sessionStorage.getItem(marker) !== null) {
+			return;
+		}
+		window.sessionStorage.setItem(marker, '1');
+		Object.keys(kv).forEach(function(k) {
+			if (window.localStorage.getItem(k) === null) {
+				window.localStorage.setItem(k, kv[k]);
+			}
+		});
+	} catch (e) {
+		// Storage is unavailable, e.g. in sandboxed frames.
+	}
+})(%s, %s);`
+
+// SeedLocalStorage arranges for the key/value pairs in kv to be present in the
+// localStorage of origin (e.g. "https://example.com") when the browser first
+// loads a page of that origin, before any of the page's scripts run. Keys that
+// the page has already set are left untouched.
+//
+// Seeding is only supported by ChromeDriver; creating a session with another
+// driver fails with an error.
+func (c *Capabilities) SeedLocalStorage(origin string, kv map[string]string) error {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return err
+	}
+	if u.Scheme == "" || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q: must be of the form scheme://host[:port]", origin)
+	}
+	if len(kv) == 0 {
+		return errors.New("no localStorage values to seed")
+	}
+	o, err := json.Marshal(u.Scheme + "://" + u.Host)
+	if err != nil {
+		return err
+	}
+	values, err := json.Marshal(kv)
+	if err != nil {
+		return err
+	}
+	c.initScripts = append(c.initScripts, fmt.Sprintf(seedLocalStorageScript, o, values))
+	return nil
+}
+
+// InitScripts returns the scripts that must be evaluated in every new document
+// of the session for the seeding requested with SeedLocalStorage and the
+// tokens added with AddOriginTrialToken to take effect. They are installed
+// automatically by selenium.NewRemote.
+func (c *Capabilities) InitScripts() []string {
+	return c.initScripts
+}
@@ -0,0 +1,91 @@
+package chrome
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedBookmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "chrome-profile-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Capabilities{Args: []string{"--user-data-dir=" + dir, "--profile-directory=Profile 1"}}
+	bookmarks := []Bookmark{
+		{Name: "Example", URL: "https://example.com/"},
+		{Name: "Folder", Children: []Bookmark{{Name: "Go", URL: "https://go.dev/"}}},
+	}
+	if err := c.SeedBookmarks(bookmarks); err != nil {
+		t.Fatalf("c.SeedBookmarks() returned error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "Profile 1", "Bookmarks"))
+	if err != nil {
+		t.Fatalf("reading the bookmarks file returned error: %v", err)
+	}
+	var file struct {
+		Roots map[string]struct {
+			Children []struct {
+				Name, Type, URL string
+				Children        []struct{ Name, URL string }
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("json.Unmarshal(%s) returned error: %v", data, err)
+	}
+	bar := file.Roots["bookmark_bar"].Children
+	if len(bar) != 2 {
+		t.Fatalf("bookmark bar has %d entries, want 2:\n%s", len(bar), data)
+	}
+	if bar[0].Type != "url" || bar[0].URL != "https://example.com/" {
+		t.Errorf("first bookmark = %+v, want a URL to https://example.com/", bar[0])
+	}
+	if bar[1].Type != "folder" || len(bar[1].Children) != 1 || bar[1].Children[0].URL != "https://go.dev/" {
+		t.Errorf("second bookmark = %+v, want a folder containing https://go.dev/", bar[1])
+	}
+}
+
+func TestSeedBookmarksCreatesUserDataDir(t *testing.T) {
+	var c Capabilities
+	if err := c.SeedBookmarks([]Bookmark{{Name: "Example", URL: "https://example.com/"}}); err != nil {
+		t.Fatalf("c.SeedBookmarks() returned error: %v", err)
+	}
+	dir := c.UserDataDir()
+	if dir == "" {
+		t.Fatalf("c.UserDataDir() is empty after c.SeedBookmarks(), want a temporary directory")
+	}
+	defer os.RemoveAll(dir)
+	if _, err := os.Stat(filepath.Join(dir, "Default", "Bookmarks")); err != nil {
+		t.Errorf("bookmarks file not written: %v", err)
+	}
+}
+
+func TestSeedLocalStorage(t *testing.T) {
+	var c Capabilities
+	for _, origin := range []string{"", "example.com", "https://example.com/path", "https://example.com/?q"} {
+		if err := c.SeedLocalStorage(origin, map[string]string{"k": "v"}); err == nil {
+			t.Errorf("c.SeedLocalStorage(%q, _) returned nil error, want an invalid origin error", origin)
+		}
+	}
+	if err := c.SeedLocalStorage("https://example.com:8443/", map[string]string{"k": "v"}); err != nil {
+		t.Fatalf("c.SeedLocalStorage() returned error: %v", err)
+	}
+	if got := len(c.InitScripts()); got != 1 {
+		t.Fatalf("len(c.InitScripts()) = %d, want 1", got)
+	}
+
+	// The init scripts must not leak into the capabilities sent to the driver.
+	data, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("json.Marshal(c) returned error: %v", err)
+	}
+	if got, want := string(data), `{"w3c":false}`; got != want {
+		t.Errorf("json.Marshal(c) = %q, want %q", got, want)
+	}
+}
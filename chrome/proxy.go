@@ -0,0 +1,124 @@
+package chrome
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Proxy switches of Chrome, which SetProxy manages.
+const (
+	proxyServerSwitch = "--proxy-server"
+	proxyBypassSwitch = "--proxy-bypass-list"
+)
+
+// hostResolverRulesSwitch maps host names to addresses, or to failures.
+const hostResolverRulesSwitch = "--host-resolver-rules"
+
+// proxyRuleSchemes are the URL schemes of the per-scheme proxy rules that
+// Chrome accepts; "socks" is the proxy for the schemes without their own.
+var proxyRuleSchemes = map[string]bool{"http": true, "https": true, "ftp": true, "socks": true}
+
+// checkProxyURL checks a proxy URL, which must have a scheme unless it is the
+// value of a per-scheme rule.
+func checkProxyURL(proxyURL string, rule bool) error {
+	if rule && !strings.Contains(proxyURL, "://") {
+		proxyURL = "http://" + proxyURL
+	}
+	u, err := url.Parse(proxyURL)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks4", "socks5":
+	default:
+		return fmt.Errorf("the scheme must be http, https, socks4 or socks5")
+	}
+	if u.Hostname() == "" || strings.Trim(u.Path, "/") != "" {
+		return fmt.Errorf("want a scheme, a host and a port only")
+	}
+	return nil
+}
+
+// SetProxy makes Chrome send its traffic through the proxy at proxyURL, e.g.
+// "http://proxy.example:3128" or "socks5://localhost:1080", except for the
+// hosts matching one of the bypass patterns, e.g. "localhost",
+// "*.corp.example", "10.0.0.0/8" or "<local>". Bypass patterns are host
+// patterns: they cannot contain a scheme. An empty proxyURL removes the
+// proxy settings.
+//
+// proxyURL may instead be a list of per-scheme rules, separated by
+// semicolons, e.g. "http=proxy.example:3128;socks=socks5://localhost:1080":
+// the proxy of a rule without a scheme is an HTTP proxy, and the "socks" rule
+// applies to the schemes without their own rule.
+//
+// SetProxy replaces the proxy switches in Args. When the capabilities are
+// added to a selenium.Capabilities with AddChrome, its W3C proxy capability
+// is set to match, as ChromeDriver fails if the two disagree.
+func (c *Capabilities) SetProxy(proxyURL string, bypass []string) error {
+	switch {
+	case proxyURL == "":
+	case strings.Contains(proxyURL, "="):
+		for _, rule := range strings.Split(proxyURL, ";") {
+			i := strings.Index(rule, "=")
+			if i < 0 || !proxyRuleSchemes[rule[:i]] {
+				return fmt.Errorf("invalid proxy rule %q: want a scheme among http, https, ftp and socks, and a proxy", rule)
+			}
+			if err := checkProxyURL(rule[i+1:], true); err != nil {
+				return fmt.Errorf("invalid proxy rule %q: %v", rule, err)
+			}
+		}
+	default:
+		if err := checkProxyURL(proxyURL, false); err != nil {
+			return fmt.Errorf("invalid proxy URL %q: %v", proxyURL, err)
+		}
+	}
+	for _, b := range bypass {
+		switch {
+		case strings.TrimSpace(b) == "":
+			return fmt.Errorf("empty proxy bypass pattern in %q", bypass)
+		case strings.Contains(b, "://"):
+			return fmt.Errorf("invalid proxy bypass pattern %q: Chrome expects a host pattern without a scheme", b)
+		case strings.ContainsAny(b, ";,"):
+			return fmt.Errorf("invalid proxy bypass pattern %q: pass each pattern separately", b)
+		}
+	}
+
+	var args []string
+	for _, arg := range c.Args {
+		switch switchName(arg) {
+		case proxyServerSwitch, proxyBypassSwitch:
+		default:
+			args = append(args, arg)
+		}
+	}
+	c.Args = args
+	c.proxyURL = proxyURL
+	c.proxyBypass = append([]string(nil), bypass...)
+	if proxyURL == "" {
+		return nil
+	}
+	c.Args = append(c.Args, proxyServerSwitch+"="+proxyURL)
+	if len(bypass) > 0 {
+		c.Args = append(c.Args, proxyBypassSwitch+"="+strings.Join(bypass, ";"))
+	}
+	return nil
+}
+
+// Proxy returns the settings passed to SetProxy.
+func (c *Capabilities) Proxy() (proxyURL string, bypass []string) {
+	return c.proxyURL, c.proxyBypass
+}
+
+// SetSOCKSRemoteDNS makes Chrome fail to resolve host names itself, except
+// for proxyHost, the host of its SOCKS5 proxy, so that no name is resolved
+// locally: Chrome sends host names to SOCKS5 proxies, but may still resolve
+// them, e.g. to prefetch. An empty proxyHost removes the setting, which
+// replaces the --host-resolver-rules switch in Args.
+func (c *Capabilities) SetSOCKSRemoteDNS(proxyHost string) {
+	if proxyHost == "" {
+		c.setSwitch(hostResolverRulesSwitch, "")
+		return
+	}
+	c.setSwitch(hostResolverRulesSwitch, "MAP * ~NOTFOUND , EXCLUDE "+proxyHost)
+}
@@ -0,0 +1,83 @@
+package chrome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetProxy(t *testing.T) {
+	c := Capabilities{Args: []string{"--headless", "--proxy-server=http://old:1"}}
+	if err := c.SetProxy("http://proxy.example:3128", []string{"localhost", "*.corp.example", "<local>"}); err != nil {
+		t.Fatalf("c.SetProxy() returned error: %v", err)
+	}
+	want := []string{"--headless", "--proxy-server=http://proxy.example:3128", "--proxy-bypass-list=localhost;*.corp.example;<local>"}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetProxy() set Args to %q, want %q", c.Args, want)
+	}
+	if u, bypass := c.Proxy(); u != "http://proxy.example:3128" || len(bypass) != 3 {
+		t.Errorf("c.Proxy() = %q, %q, want the settings passed to SetProxy", u, bypass)
+	}
+
+	if err := c.SetProxy("", nil); err != nil {
+		t.Fatalf("c.SetProxy(%q, nil) returned error: %v", "", err)
+	}
+	if want := []string{"--headless"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetProxy(%q, nil) set Args to %q, want %q", "", c.Args, want)
+	}
+
+	for _, test := range []struct {
+		proxyURL string
+		bypass   []string
+	}{
+		{"proxy.example:3128", nil},
+		{"ftp://proxy.example:21", nil},
+		{"http://proxy.example:3128/path", nil},
+		{"http://proxy.example:3128", []string{"http://localhost"}},
+		{"http://proxy.example:3128", []string{"localhost;127.0.0.1"}},
+		{"http://proxy.example:3128", []string{""}},
+	} {
+		c := Capabilities{}
+		if err := c.SetProxy(test.proxyURL, test.bypass); err == nil {
+			t.Errorf("c.SetProxy(%q, %q) returned no error", test.proxyURL, test.bypass)
+		}
+		if len(c.Args) != 0 {
+			t.Errorf("c.SetProxy(%q, %q) failed but set Args to %q", test.proxyURL, test.bypass, c.Args)
+		}
+	}
+}
+
+func TestSetProxyRules(t *testing.T) {
+	var c Capabilities
+	rules := "http=proxy.example:3128;https=proxy.example:3129;socks=socks5://localhost:1080"
+	if err := c.SetProxy(rules, nil); err != nil {
+		t.Fatalf("c.SetProxy(%q) returned error: %v", rules, err)
+	}
+	if want := []string{"--proxy-server=" + rules}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetProxy(%q) set Args to %q, want %q", rules, c.Args, want)
+	}
+
+	for _, rules := range []string{
+		"gopher=proxy.example:70",
+		"http=",
+		"http=ftp://proxy.example:21",
+		"http=proxy.example:3128;proxy.example:3129",
+	} {
+		var c Capabilities
+		if err := c.SetProxy(rules, nil); err == nil {
+			t.Errorf("c.SetProxy(%q) returned no error", rules)
+		}
+	}
+}
+
+func TestSetSOCKSRemoteDNS(t *testing.T) {
+	c := Capabilities{Args: []string{"--headless"}}
+	c.SetSOCKSRemoteDNS("127.0.0.1")
+	want := []string{"--headless", "--host-resolver-rules=MAP * ~NOTFOUND , EXCLUDE 127.0.0.1"}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetSOCKSRemoteDNS(%q) set Args to %q, want %q", "127.0.0.1", c.Args, want)
+	}
+	c.SetSOCKSRemoteDNS("")
+	if want := []string{"--headless"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.SetSOCKSRemoteDNS(%q) set Args to %q, want %q", "", c.Args, want)
+	}
+}
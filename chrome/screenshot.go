@@ -0,0 +1,29 @@
+package chrome
+
+// screenshotStabilityArgs make Chrome render a page identically from one run
+// to the next on the same machine.
+var screenshotStabilityArgs = []string{
+	// Use grayscale antialiasing, which does not depend on the subpixel
+	// layout of the display.
+	"--disable-lcd-text",
+	"--font-render-hinting=none",
+	"--hide-scrollbars",
+	"--force-device-scale-factor=1",
+	"--force-color-profile=srgb",
+	// Rasterize whole tiles in a single pass, on the CPU, without runtime
+	// optimizations that depend on timing.
+	"--disable-partial-raster",
+	"--disable-skia-runtime-opts",
+	"--disable-gpu-rasterization",
+	// Make Blink jump to scroll positions instead of animating to them.
+	"--disable-smooth-scrolling",
+}
+
+// ForScreenshotStability adds the command-line arguments that make Chrome
+// render pages deterministically, so that screenshots of the same page can be
+// compared byte for byte. Arguments already present in c.Args take
+// precedence. Pair it with selenium.WebDriver.DisableAnimations to also stop
+// animations and the blinking text caret.
+func (c *Capabilities) ForScreenshotStability() {
+	c.addDefaultArgs(screenshotStabilityArgs...)
+}
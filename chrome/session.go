@@ -0,0 +1,111 @@
+package chrome
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Info describes the browser and the driver of a ChromeDriver session, for
+// bug reports and cleanup. Fields that the driver did not report are empty.
+type Info struct {
+	// ChromedriverVersion is the version of ChromeDriver, e.g. "114.0.5735.90".
+	ChromedriverVersion string
+	// BrowserVersion is the version of the browser.
+	BrowserVersion string
+	// BinaryPath is the path to the browser binary, if set in the
+	// capabilities or reported by the driver.
+	BinaryPath string
+	// UserDataDir is the user data directory of the browser, which
+	// ChromeDriver creates unless one is set in Args.
+	UserDataDir string
+	// DebuggerAddress is the host and port of the DevTools endpoint of the
+	// browser.
+	DebuggerAddress string
+}
+
+// SessionInfo returns the information reported by ChromeDriver in the
+// capabilities negotiated for a session, such as those returned by
+// selenium.WebDriver.SessionCapabilities:
+//
+//	info, err := chrome.SessionInfo(wd.SessionCapabilities())
+//
+// It returns an error only if the capabilities are malformed.
+func SessionInfo(caps map[string]interface{}) (Info, error) {
+	var info Info
+	driver, err := objectCapability(caps, "chrome")
+	if err != nil {
+		return info, err
+	}
+	options, err := objectCapability(caps, CapabilitiesKey)
+	if err != nil {
+		return info, err
+	}
+
+	info.ChromedriverVersion = stringField(driver, "chromedriverVersion")
+	// The version is followed by the commit it was built from.
+	if i := strings.Index(info.ChromedriverVersion, " "); i >= 0 {
+		info.ChromedriverVersion = info.ChromedriverVersion[:i]
+	}
+	info.BrowserVersion = stringField(caps, "browserVersion")
+	if info.BrowserVersion == "" {
+		info.BrowserVersion = stringField(caps, "version")
+	}
+	info.BinaryPath = stringField(driver, "binary")
+	if info.BinaryPath == "" {
+		info.BinaryPath = stringField(options, "binary")
+	}
+	info.UserDataDir = stringField(driver, "userDataDir")
+	info.DebuggerAddress = stringField(options, "debuggerAddress")
+	return info, nil
+}
+
+// objectCapability returns the capability named key, which must be an object
+// if it is present.
+func objectCapability(caps map[string]interface{}, key string) (map[string]interface{}, error) {
+	v, ok := caps[key]
+	if !ok || v == nil {
+		return nil, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("capability %q is %T, not an object", key, v)
+	}
+	return m, nil
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// tempUserDataDirPrefix starts the names of the directories created by
+// UseTempUserDataDir.
+const tempUserDataDirPrefix = "selenium-chrome-profile-"
+
+// tempUserDataDirPrefixes start the names of the temporary user data
+// directories created by UseTempUserDataDir and by ChromeDriver.
+var tempUserDataDirPrefixes = []string{
+	tempUserDataDirPrefix,
+	".com.google.Chrome.",
+	".org.chromium.Chromium.",
+	"scoped_dir",
+}
+
+// IsTempUserDataDir reports whether dir is a temporary user data directory
+// created by UseTempUserDataDir or by ChromeDriver, which can be removed once
+// the browser has exited. Directories outside of the temporary directory of
+// the system are never reported as temporary.
+func IsTempUserDataDir(dir string) bool {
+	if dir == "" || filepath.Dir(filepath.Clean(dir)) != filepath.Clean(os.TempDir()) {
+		return false
+	}
+	name := filepath.Base(dir)
+	for _, prefix := range tempUserDataDirPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
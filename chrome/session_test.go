@@ -0,0 +1,87 @@
+package chrome
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionInfo(t *testing.T) {
+	tests := []struct {
+		desc string
+		caps map[string]interface{}
+		want Info
+	}{
+		{
+			desc: "ChromeDriver",
+			caps: map[string]interface{}{
+				"browserName":    "chrome",
+				"browserVersion": "114.0.5735.198",
+				"chrome": map[string]interface{}{
+					"chromedriverVersion": "114.0.5735.90 (386bc09e8f4f2e025eddae123f36f6263096ae49-refs/branch-heads/5735@{#1052})",
+					"userDataDir":         "/tmp/.com.google.Chrome.W4mbQ1",
+				},
+				CapabilitiesKey: map[string]interface{}{
+					"debuggerAddress": "localhost:40325",
+				},
+			},
+			want: Info{
+				ChromedriverVersion: "114.0.5735.90",
+				BrowserVersion:      "114.0.5735.198",
+				UserDataDir:         "/tmp/.com.google.Chrome.W4mbQ1",
+				DebuggerAddress:     "localhost:40325",
+			},
+		},
+		{
+			desc: "legacy driver",
+			caps: map[string]interface{}{
+				"version": "60.0.3112.78",
+				CapabilitiesKey: map[string]interface{}{
+					"binary": "/opt/chrome/chrome",
+				},
+			},
+			want: Info{
+				BrowserVersion: "60.0.3112.78",
+				BinaryPath:     "/opt/chrome/chrome",
+			},
+		},
+		{
+			desc: "not Chrome",
+			caps: map[string]interface{}{"browserName": "firefox"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := SessionInfo(tc.caps)
+			if err != nil {
+				t.Fatalf("SessionInfo() returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("SessionInfo() = %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSessionInfoMalformed(t *testing.T) {
+	if _, err := SessionInfo(map[string]interface{}{"chrome": "114"}); err == nil {
+		t.Error("SessionInfo() with a string chrome capability returned no error")
+	}
+}
+
+func TestIsTempUserDataDir(t *testing.T) {
+	tmp := os.TempDir()
+	for dir, want := range map[string]bool{
+		filepath.Join(tmp, ".com.google.Chrome.W4mbQ1"):           true,
+		filepath.Join(tmp, ".org.chromium.Chromium.a1b2c3"):       true,
+		filepath.Join(tmp, tempUserDataDirPrefix+"123"):           true,
+		filepath.Join(tmp, "my-profile"):                          false,
+		filepath.Join(tmp, "nested", ".com.google.Chrome.W4mbQ1"): false,
+		filepath.Join("/home/user", ".com.google.Chrome.W4mbQ1"):  false,
+		"": false,
+	} {
+		if got := IsTempUserDataDir(dir); got != want {
+			t.Errorf("IsTempUserDataDir(%q) = %t, want %t", dir, got, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package chrome
+
+import "runtime"
+
+// tabThrottlingArgs keep Chrome from slowing down or freezing the tabs and
+// windows that are not in the foreground.
+var tabThrottlingArgs = []string{
+	"--disable-background-timer-throttling",
+	"--disable-backgrounding-occluded-windows",
+	"--disable-renderer-backgrounding",
+	// Memory saver discards background tabs to reclaim their memory.
+	"--disable-features=HighEfficiencyModeAvailable",
+}
+
+// windowsTabThrottlingArgs are added to tabThrottlingArgs on Windows, where
+// Chrome also throttles the windows it computes to be covered by others.
+var windowsTabThrottlingArgs = []string{
+	"--disable-features=CalculateNativeWinOcclusion",
+}
+
+// DisableTabThrottling adds the command-line arguments that keep Chrome from
+// throttling the timers of background tabs and windows and from discarding
+// them, so that tests which switch between tabs find them as they left them.
+// Arguments already present in c.Args take precedence, and features are
+// merged into any existing --disable-features argument. The Windows-specific
+// arguments are added when the library runs on Windows, which is where the
+// browser is expected to run.
+//
+// Use selenium.WebDriver.SetTabActive to make a background tab also behave as
+// if it had focus.
+func (c *Capabilities) DisableTabThrottling() {
+	c.addDefaultArgs(tabThrottlingArgs...)
+	if runtime.GOOS == "windows" {
+		c.addDefaultArgs(windowsTabThrottlingArgs...)
+	}
+}
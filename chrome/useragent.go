@@ -0,0 +1,28 @@
+package chrome
+
+import "strings"
+
+// SetUserAgent makes Chrome send ua as its user agent, in the User-Agent
+// header and navigator.userAgent. An empty ua restores the default. The
+// User-Agent Client Hints are not affected; see
+// selenium.WebDriver.SetUserAgentOverride to change them too.
+func (c *Capabilities) SetUserAgent(ua string) {
+	c.setSwitch("--user-agent", ua)
+}
+
+// SetAcceptLanguage makes Chrome prefer the given languages, e.g. "fr-CH",
+// "fr", in order: they are sent in the Accept-Language header and returned
+// by navigator.languages. The first one is also the language of the user
+// interface. No languages restores the default.
+func (c *Capabilities) SetAcceptLanguage(langs ...string) {
+	if len(langs) == 0 {
+		c.setSwitch("--lang", "")
+		delete(c.Prefs, "intl.accept_languages")
+		return
+	}
+	c.setSwitch("--lang", langs[0])
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]interface{})
+	}
+	c.Prefs["intl.accept_languages"] = strings.Join(langs, ",")
+}
@@ -0,0 +1,28 @@
+package chrome
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetUserAgentAndAcceptLanguage(t *testing.T) {
+	c := Capabilities{Args: []string{"--headless", "--user-agent=old", "--lang=de"}}
+	c.SetUserAgent("test-agent/1.0")
+	c.SetAcceptLanguage("fr-CH", "fr")
+	want := []string{"--headless", "--user-agent=test-agent/1.0", "--lang=fr-CH"}
+	if !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("c.Args = %q, want %q", c.Args, want)
+	}
+	if got := c.Prefs["intl.accept_languages"]; got != "fr-CH,fr" {
+		t.Errorf("c.Prefs[%q] = %v, want %q", "intl.accept_languages", got, "fr-CH,fr")
+	}
+
+	c.SetUserAgent("")
+	c.SetAcceptLanguage()
+	if want := []string{"--headless"}; !reflect.DeepEqual(c.Args, want) {
+		t.Errorf("after resetting, c.Args = %q, want %q", c.Args, want)
+	}
+	if _, ok := c.Prefs["intl.accept_languages"]; ok {
+		t.Errorf("after resetting, c.Prefs still sets intl.accept_languages")
+	}
+}
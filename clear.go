@@ -0,0 +1,86 @@
+package selenium
+
+// clearWatchScript records the value of the element passed as the first
+// argument and listens for the input and change events fired while it is
+// cleared.
+const clearWatchScript = `
+var el = arguments[0];
+var state = {input: false, change: false, value: 'value' in el ? el.value : el.textContent};
+state.listener = function(e) { state[e.type] = true; };
+el.addEventListener('input', state.listener, true);
+el.addEventListener('change', state.listener, true);
+el.__seleniumClear = state;
+`
+
+// clearEventsScript stops listening for the events of the element passed as
+// the first argument and, if the second argument is true and the value
+// changed, dispatches the input and change events that were not fired. It
+// returns the types of the events dispatched.
+const clearEventsScript = `
+var el = arguments[0], dispatch = arguments[1], state = el.__seleniumClear;
+delete el.__seleniumClear;
+if (!state) {
+	return [];
+}
+el.removeEventListener('input', state.listener, true);
+el.removeEventListener('change', state.listener, true);
+var value = 'value' in el ? el.value : el.textContent;
+if (!dispatch || value === state.value) {
+	return [];
+}
+var dispatched = [];
+if (!state.input) {
+	var proto = null;
+	if (el instanceof HTMLTextAreaElement) {
+		proto = HTMLTextAreaElement.prototype;
+	} else if (el instanceof HTMLInputElement) {
+		proto = HTMLInputElement.prototype;
+	}
+	if (proto) {
+		// Frameworks that track the value through the value property (e.g.
+		// React) ignore an input event if the tracked value is current: track
+		// the previous value, then restore the cleared one with the native
+		// setter, which they do not intercept.
+		el.value = state.value;
+		Object.getOwnPropertyDescriptor(proto, 'value').set.call(el, value);
+	}
+	var input;
+	if (typeof InputEvent === 'function') {
+		input = new InputEvent('input', {bubbles: true, inputType: 'deleteContent'});
+	} else {
+		input = document.createEvent('Event');
+		input.initEvent('input', true, false);
+	}
+	el.dispatchEvent(input);
+	dispatched.push('input');
+}
+if (!state.change) {
+	var change = document.createEvent('Event');
+	change.initEvent('change', true, false);
+	el.dispatchEvent(change);
+	dispatched.push('change');
+}
+return dispatched;
+`
+
+// ClearWithEvents clears the element and dispatches the events the driver did
+// not fire.
+func (elem *remoteWE) ClearWithEvents() error {
+	wd := elem.parent
+	if _, err := wd.ExecuteScript(clearWatchScript, []interface{}{elem}); err != nil {
+		return err
+	}
+	if err := elem.Clear(); err != nil {
+		// Stop listening, without dispatching events.
+		wd.ExecuteScript(clearEventsScript, []interface{}{elem, false})
+		return err
+	}
+	dispatched, err := wd.ExecuteScript(clearEventsScript, []interface{}{elem, true})
+	if err != nil {
+		return err
+	}
+	if events, ok := dispatched.([]interface{}); ok && len(events) > 0 {
+		debugLog("the driver did not fire %v when clearing element %s, dispatched them by script", events, elem.id)
+	}
+	return nil
+}
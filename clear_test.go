@@ -0,0 +1,70 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestClearWithEvents(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		clearStatus int
+		want        []string
+		wantErr     bool
+	}{
+		{
+			desc:        "cleared",
+			clearStatus: http.StatusOK,
+			want:        []string{"watch", "clear", "dispatch"},
+		},
+		{
+			desc:        "clear failed",
+			clearStatus: http.StatusBadRequest,
+			want:        []string{"watch", "clear", "stop"},
+			wantErr:     true,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			var calls []string
+			d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+				params := new(struct {
+					Script string
+					Args   []interface{}
+				})
+				json.Unmarshal(body, params)
+				switch {
+				case params.Script == clearWatchScript:
+					calls = append(calls, "watch")
+				case params.Script == clearEventsScript && params.Args[1] == true:
+					calls = append(calls, "dispatch")
+					return http.StatusOK, []string{"input"}
+				case params.Script == clearEventsScript:
+					calls = append(calls, "stop")
+				default:
+					t.Errorf("unexpected script %q", params.Script)
+				}
+				return http.StatusOK, nil
+			})
+			d.handle("POST", "/element/input/clear", func([]byte) (int, interface{}) {
+				calls = append(calls, "clear")
+				if test.clearStatus != http.StatusOK {
+					return test.clearStatus, map[string]string{"error": "invalid element state", "message": "not editable"}
+				}
+				return http.StatusOK, nil
+			})
+			wd := d.newRemote(t)
+			elem := &remoteWE{parent: wd, id: "input"}
+
+			err := elem.ClearWithEvents()
+			if (err != nil) != test.wantErr {
+				t.Errorf("elem.ClearWithEvents() returned error %v, want error: %t", err, test.wantErr)
+			}
+			if !reflect.DeepEqual(calls, test.want) {
+				t.Errorf("elem.ClearWithEvents() sent %v, want %v", calls, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package selenium
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+)
+
+// ErrClientCertificateMismatch is matched by the errors of
+// VerifyClientCertificate when the browser reached the test endpoint without
+// presenting the expected client certificate.
+var ErrClientCertificateMismatch = errors.New("the browser did not present the expected client certificate")
+
+// VerifyClientCertificate checks the client certificate settings of the
+// session, e.g. chrome.Capabilities.AutoSelectClientCertificate or
+// firefox.Capabilities.AddClientCertificate: it serves a test endpoint over
+// TLS on the loopback interface, asking for a client certificate, navigates
+// wd to it and returns the certificate that the browser presented. If
+// issuerCN is not empty, the certificate must be issued by the CA of that
+// common name. The certificate of the endpoint is self-signed, so the
+// session must accept insecure certificates. The errors about the
+// certificate match ErrClientCertificateMismatch. The browser is left on the
+// test endpoint, which is stopped.
+func VerifyClientCertificate(wd WebDriver, issuerCN string) (*x509.Certificate, error) {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	path := "/client-cert-check-" + hex.EncodeToString(token)
+
+	var mu sync.Mutex
+	var reached bool
+	var presented []*x509.Certificate
+	endpoint := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		reached, presented = true, r.TLS.PeerCertificates
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<title>client certificate check</title>")
+	}))
+	endpoint.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	endpoint.StartTLS()
+	defer endpoint.Close()
+
+	if err := wd.Get(endpoint.URL + path); err != nil {
+		return nil, fmt.Errorf("navigating to the client certificate check endpoint: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reached {
+		return nil, fmt.Errorf("the browser did not reach the client certificate check endpoint at %s; the session must accept insecure certificates", endpoint.URL)
+	}
+	if len(presented) == 0 {
+		return nil, fmt.Errorf("%w: the browser presented no certificate", ErrClientCertificateMismatch)
+	}
+	cert := presented[0]
+	if issuerCN != "" && cert.Issuer.CommonName != issuerCN {
+		return cert, fmt.Errorf("%w: the certificate of %q is issued by %q, want %q", ErrClientCertificateMismatch, cert.Subject.CommonName, cert.Issuer.CommonName, issuerCN)
+	}
+	return cert, nil
+}
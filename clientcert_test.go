@@ -0,0 +1,79 @@
+package selenium
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// testClientCertificate returns a client certificate issued by a CA named
+// issuerCN.
+func testClientCertificate(t *testing.T, issuerCN string) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test client"},
+		Issuer:       pkix.Name{CommonName: issuerCN},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	parent := &x509.Certificate{Subject: pkix.Name{CommonName: issuerCN}}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// fakeTLSBrowser returns a fake driver that navigates with an HTTP client
+// presenting certs.
+func fakeTLSBrowser(t *testing.T, certs ...tls.Certificate) *fakeDriver {
+	d := newFakeDriver(t, nil)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		Certificates:       certs,
+		InsecureSkipVerify: true,
+	}}}
+	d.handle("POST", "/url", func(body []byte) (int, interface{}) {
+		var params struct{ URL string }
+		json.Unmarshal(body, &params)
+		resp, err := client.Get(params.URL)
+		if err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "unknown error", "message": err.Error()}
+		}
+		resp.Body.Close()
+		return http.StatusOK, nil
+	})
+	return d
+}
+
+func TestVerifyClientCertificate(t *testing.T) {
+	wd := fakeTLSBrowser(t, testClientCertificate(t, "Staging CA")).newRemote(t)
+	cert, err := VerifyClientCertificate(wd, "Staging CA")
+	if err != nil {
+		t.Fatalf("VerifyClientCertificate() returned error: %v", err)
+	}
+	if cert.Subject.CommonName != "test client" {
+		t.Errorf("VerifyClientCertificate() returned the certificate of %q, want %q", cert.Subject.CommonName, "test client")
+	}
+	if _, err := VerifyClientCertificate(wd, "Other CA"); !errors.Is(err, ErrClientCertificateMismatch) {
+		t.Errorf("VerifyClientCertificate() of another issuer returned error %v, want ErrClientCertificateMismatch", err)
+	}
+
+	wd = fakeTLSBrowser(t).newRemote(t)
+	if _, err := VerifyClientCertificate(wd, ""); !errors.Is(err, ErrClientCertificateMismatch) {
+		t.Errorf("VerifyClientCertificate() without a certificate returned error %v, want ErrClientCertificateMismatch", err)
+	}
+}
@@ -3,6 +3,7 @@ package selenium
 import (
 	"log"
 	"net/url"
+	"strings"
 )
 
 var debugFlag = false
@@ -33,3 +34,34 @@ func filteredURL(u string) string {
 	}
 	return m.String()
 }
+
+// joinURL returns the URL of the endpoint at path p, which starts with a slash
+// and may be escaped already, under the remote end at prefix. The path of
+// prefix is kept whether or not it ends with a slash, and so is its query,
+// which some gateways use for authentication.
+func joinURL(prefix, p string) string {
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return strings.TrimSuffix(prefix, "/") + p
+	}
+	query := u.RawQuery
+	u.RawQuery, u.ForceQuery, u.Fragment = "", false, ""
+	joined := strings.TrimSuffix(u.String(), "/") + p
+	if query != "" {
+		joined += "?" + query
+	}
+	return joined
+}
+
+// withBasePath returns prefix with its path replaced by basePath.
+func withBasePath(prefix, basePath string) (string, error) {
+	u, err := url.Parse(prefix)
+	if err != nil {
+		return "", err
+	}
+	if basePath != "" && !strings.HasPrefix(basePath, "/") {
+		basePath = "/" + basePath
+	}
+	u.Path, u.RawPath = basePath, ""
+	return u.String(), nil
+}
@@ -0,0 +1,375 @@
+package selenium
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// CompatAction is what TranslateLegacyCapabilities did with a capability.
+type CompatAction string
+
+// Actions of TranslateLegacyCapabilities.
+const (
+	// CompatRenamed is a legacy capability renamed to its W3C equivalent.
+	CompatRenamed CompatAction = "renamed"
+	// CompatMoved is a loose browser-specific capability moved into the
+	// options of the browser.
+	CompatMoved CompatAction = "moved"
+	// CompatDropped is a capability without W3C meaning, removed.
+	CompatDropped CompatAction = "dropped"
+)
+
+// CompatChange is a transformation of a capability by
+// TranslateLegacyCapabilities.
+type CompatChange struct {
+	// Key is the legacy capability, e.g. "platform".
+	Key    string
+	Action CompatAction
+	// To is the W3C capability or the browser option that replaces Key,
+	// e.g. "platformName" or "goog:chromeOptions.binary", if it was renamed
+	// or moved.
+	To string
+	// Reason explains why the capability was dropped.
+	Reason string
+}
+
+func (c CompatChange) String() string {
+	switch c.Action {
+	case CompatRenamed, CompatMoved:
+		return fmt.Sprintf("%q %s to %q", c.Key, c.Action, c.To)
+	}
+	return fmt.Sprintf("%q dropped: %s", c.Key, c.Reason)
+}
+
+// CompatReport lists the transformations of the capabilities of a session
+// by TranslateLegacyCapabilities, so that the code that sets the legacy
+// capabilities can be fixed over time.
+type CompatReport struct {
+	Changes []CompatChange
+}
+
+// Empty reports whether the capabilities needed no transformation.
+func (r CompatReport) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+func (r CompatReport) String() string {
+	var lines []string
+	for _, c := range r.Changes {
+		lines = append(lines, c.String())
+	}
+	return strings.Join(lines, "; ")
+}
+
+// CompatError is returned by NewRemoteContext, for a session created with
+// StrictCapabilities, when the capabilities use legacy names.
+type CompatError struct {
+	Report CompatReport
+}
+
+func (e *CompatError) Error() string {
+	return fmt.Sprintf("legacy capabilities: %s", e.Report)
+}
+
+// WithoutCapabilityTranslation sends the capabilities of the session as they
+// are, without translating the legacy names with
+// TranslateLegacyCapabilities.
+func WithoutCapabilityTranslation() SessionOption {
+	return func(o *sessionOptions) {
+		o.noCompat = true
+	}
+}
+
+// StrictCapabilities makes NewRemoteContext return a *CompatError, instead of
+// creating the session, if the capabilities need any transformation by
+// TranslateLegacyCapabilities, e.g. on CI once a suite is migrated.
+func StrictCapabilities() SessionOption {
+	return func(o *sessionOptions) {
+		o.strictCompat = true
+	}
+}
+
+// WithCompatReport passes the report of the translation of the capabilities
+// to fn when the session is created, if it is not empty, instead of logging
+// it.
+func WithCompatReport(fn func(CompatReport)) SessionOption {
+	return func(o *sessionOptions) {
+		o.compatReport = fn
+	}
+}
+
+// legacyCapabilityNames are the legacy capabilities with a W3C equivalent.
+var legacyCapabilityNames = map[string]string{
+	"platform":                 "platformName",
+	"version":                  "browserVersion",
+	"acceptSslCerts":           "acceptInsecureCerts",
+	"unexpectedAlertBehaviour": "unhandledPromptBehavior",
+}
+
+// obsoleteCapabilityNames are the legacy capabilities without W3C meaning,
+// which W3C remote ends reject or ignore.
+var obsoleteCapabilityNames = map[string]bool{
+	"javascriptEnabled":        true,
+	"cssSelectorsEnabled":      true,
+	"takesScreenshot":          true,
+	"takesHeapSnapshot":        true,
+	"handlesAlerts":            true,
+	"databaseEnabled":          true,
+	"locationContextEnabled":   true,
+	"applicationCacheEnabled":  true,
+	"browserConnectionEnabled": true,
+	"webStorageEnabled":        true,
+	"nativeEvents":             true,
+	"rotatable":                true,
+	"hasTouchScreen":           true,
+	"marionette":               true,
+}
+
+// TranslateLegacyCapabilities returns a copy of caps in which the legacy
+// capabilities, which W3C remote ends reject, are replaced by their W3C
+// equivalents, with the report of the transformations:
+//
+//   - "platform", "version", "acceptSslCerts" and "unexpectedAlertBehaviour"
+//     are renamed to "platformName", "browserVersion", "acceptInsecureCerts"
+//     and "unhandledPromptBehavior", unless those are set already;
+//   - "chrome.binary", "chrome.switches" and "chrome.extensions" are moved
+//     into the Chrome options, and "firefox_binary" and "firefox_profile"
+//     into the Firefox options;
+//   - the other capabilities that are neither W3C capabilities nor
+//     extensions, whose names contain a colon, are dropped.
+//
+// NewRemoteContext translates the capabilities sent to W3C remote ends,
+// unless WithoutCapabilityTranslation is passed.
+func TranslateLegacyCapabilities(caps Capabilities) (Capabilities, CompatReport) {
+	out := make(Capabilities, len(caps))
+	for k, v := range caps {
+		out[k] = v
+	}
+	var r CompatReport
+	keys := make([]string, 0, len(caps))
+	for k := range caps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := caps[k]
+		switch {
+		case legacyCapabilityNames[k] != "":
+			to := legacyCapabilityNames[k]
+			delete(out, k)
+			if _, ok := caps[to]; ok {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: fmt.Sprintf("superseded by %q", to)})
+				continue
+			}
+			v, ok := translateLegacyValue(k, v)
+			if !ok {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: fmt.Sprintf("%v has no W3C equivalent", caps[k])})
+				continue
+			}
+			out[to] = v
+			r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatRenamed, To: to})
+
+		case strings.HasPrefix(k, "chrome."):
+			delete(out, k)
+			if to, err := moveChromeCapability(out, k, v); err != nil {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: err.Error()})
+			} else {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatMoved, To: to})
+			}
+
+		case k == "firefox_binary" || k == "firefox_profile":
+			delete(out, k)
+			if to, err := moveFirefoxCapability(out, k, v); err != nil {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: err.Error()})
+			} else {
+				r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatMoved, To: to})
+			}
+
+		case obsoleteCapabilityNames[k]:
+			delete(out, k)
+			r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: "no W3C meaning"})
+
+		case !isW3CCapability(caps, k) && k != chrome.DeprecatedCapabilitiesKey:
+			// The legacy key of the Chrome options is the library's own
+			// duplicate, for ChromeDriver 2, and is not reported.
+			delete(out, k)
+			r.Changes = append(r.Changes, CompatChange{Key: k, Action: CompatDropped, Reason: "not a W3C capability nor an extension"})
+		}
+	}
+	return out, r
+}
+
+// isW3CCapability reports whether name is a capability that newW3CCapabilities
+// sends to W3C remote ends.
+func isW3CCapability(caps Capabilities, name string) bool {
+	if strings.Contains(name, ":") {
+		return true
+	}
+	for _, n := range w3cCapabilityNames {
+		if n == name {
+			return true
+		}
+	}
+	if b, ok := caps["browserName"]; ok && b == "chrome" {
+		for _, n := range chromeCapabilityNames {
+			if n == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// translateLegacyValue returns the W3C value of the legacy capability k, or
+// false if it has none.
+func translateLegacyValue(k string, v interface{}) (interface{}, bool) {
+	switch k {
+	case "platform":
+		s, ok := v.(string)
+		if !ok {
+			return nil, false
+		}
+		switch p := strings.ToUpper(s); p {
+		case "", "ANY":
+			return nil, false
+		case "WINDOWS", "XP", "VISTA", "WIN8", "WIN8_1", "WIN10", "WIN11":
+			return "windows", true
+		case "MAC", "MAC OS X":
+			return "mac", true
+		default:
+			return strings.ToLower(p), true
+		}
+	case "version":
+		switch v := v.(type) {
+		case string:
+			return v, v != ""
+		case float64, int:
+			return fmt.Sprint(v), true
+		}
+		return nil, false
+	}
+	return v, true
+}
+
+// chromeOptions returns a copy of the Chrome options of caps, and whether they
+// can be edited, i.e. are not a raw map.
+func chromeOptions(caps Capabilities) (chrome.Capabilities, bool) {
+	switch v := caps[chrome.CapabilitiesKey].(type) {
+	case nil:
+		return chrome.Capabilities{W3C: true}, true
+	case chrome.Capabilities:
+		return v, true
+	case *chrome.Capabilities:
+		return *v, true
+	}
+	return chrome.Capabilities{}, false
+}
+
+// stringList returns v as a list of strings.
+func stringList(v interface{}) ([]string, bool) {
+	switch v := v.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		list := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			list[i] = s
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// moveChromeCapability moves the loose Chrome capability k into the Chrome
+// options of caps, and returns the name of the option.
+func moveChromeCapability(caps Capabilities, k string, v interface{}) (string, error) {
+	c, ok := chromeOptions(caps)
+	if !ok {
+		return "", fmt.Errorf("the Chrome options are %T and cannot be edited", caps[chrome.CapabilitiesKey])
+	}
+	var to string
+	switch k {
+	case "chrome.binary":
+		s, ok := v.(string)
+		if !ok {
+			return "", fmt.Errorf("the value is %T, not a string", v)
+		}
+		c.Path, to = s, "binary"
+	case "chrome.switches":
+		args, ok := stringList(v)
+		if !ok {
+			return "", fmt.Errorf("the value is %T, not a list of strings", v)
+		}
+		c.Args, to = append(append([]string(nil), c.Args...), args...), "args"
+	case "chrome.extensions":
+		exts, ok := stringList(v)
+		if !ok {
+			return "", fmt.Errorf("the value is %T, not a list of strings", v)
+		}
+		c.Extensions, to = append(append([]string(nil), c.Extensions...), exts...), "extensions"
+	default:
+		return "", fmt.Errorf("unknown Chrome capability")
+	}
+	caps[chrome.CapabilitiesKey] = c
+	return chrome.CapabilitiesKey + "." + to, nil
+}
+
+// moveFirefoxCapability moves the loose Firefox capability k into the Firefox
+// options of caps, and returns the name of the option.
+func moveFirefoxCapability(caps Capabilities, k string, v interface{}) (string, error) {
+	var c firefox.Capabilities
+	switch o := caps[firefox.CapabilitiesKey].(type) {
+	case nil:
+	case firefox.Capabilities:
+		c = o
+	case *firefox.Capabilities:
+		c = *o
+	default:
+		return "", fmt.Errorf("the Firefox options are %T and cannot be edited", o)
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("the value is %T, not a string", v)
+	}
+	field, to := &c.Binary, "binary"
+	if k == "firefox_profile" {
+		field, to = &c.Profile, "profile"
+	}
+	to = firefox.CapabilitiesKey + "." + to
+	if *field != "" {
+		return "", fmt.Errorf("superseded by %q", to)
+	}
+	*field = s
+	caps[firefox.CapabilitiesKey] = c
+	return to, nil
+}
+
+// translateCapabilities returns the capabilities to send to W3C remote ends,
+// translated unless the session was created with
+// WithoutCapabilityTranslation, and reports the translation as configured.
+func translateCapabilities(caps Capabilities, o sessionOptions) (Capabilities, error) {
+	if o.noCompat {
+		return caps, nil
+	}
+	translated, r := TranslateLegacyCapabilities(caps)
+	switch {
+	case r.Empty():
+	case o.strictCompat:
+		return nil, &CompatError{Report: r}
+	case o.compatReport != nil:
+		o.compatReport(r)
+	default:
+		log.Printf("selenium: legacy capabilities translated: %s", r)
+	}
+	return translated, nil
+}
@@ -0,0 +1,106 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+func TestTranslateLegacyCapabilities(t *testing.T) {
+	caps := Capabilities{
+		"browserName":                    "chrome",
+		"platform":                       "LINUX",
+		"version":                        "103",
+		"acceptSslCerts":                 true,
+		"acceptInsecureCerts":            false,
+		"javascriptEnabled":              true,
+		"chrome.switches":                []interface{}{"--headless"},
+		"chrome.binary":                  "/opt/chrome",
+		"firefox_profile":                "UEsFBgAAAAAAAAAAAAAAAAAAAAAAAA==",
+		"build":                          "1234",
+		"loggingPrefs":                   map[string]string{"browser": "ALL"},
+		"se:recordVideo":                 true,
+		chrome.CapabilitiesKey:           chrome.Capabilities{Args: []string{"--no-sandbox"}},
+		chrome.DeprecatedCapabilitiesKey: chrome.Capabilities{},
+	}
+	got, report := TranslateLegacyCapabilities(caps)
+
+	want := Capabilities{
+		"browserName":         "chrome",
+		"platformName":        "linux",
+		"browserVersion":      "103",
+		"acceptInsecureCerts": false,
+		"loggingPrefs":        map[string]string{"browser": "ALL"},
+		"se:recordVideo":      true,
+		chrome.CapabilitiesKey: chrome.Capabilities{
+			Path: "/opt/chrome",
+			Args: []string{"--no-sandbox", "--headless"},
+		},
+		chrome.DeprecatedCapabilitiesKey: chrome.Capabilities{},
+		firefox.CapabilitiesKey:          firefox.Capabilities{Profile: "UEsFBgAAAAAAAAAAAAAAAAAAAAAAAA=="},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TranslateLegacyCapabilities() returned\n%v\nwant\n%v", got, want)
+	}
+	wantReport := []CompatChange{
+		{Key: "acceptSslCerts", Action: CompatDropped, Reason: `superseded by "acceptInsecureCerts"`},
+		{Key: "build", Action: CompatDropped, Reason: "not a W3C capability nor an extension"},
+		{Key: "chrome.binary", Action: CompatMoved, To: "goog:chromeOptions.binary"},
+		{Key: "chrome.switches", Action: CompatMoved, To: "goog:chromeOptions.args"},
+		{Key: "firefox_profile", Action: CompatMoved, To: "moz:firefoxOptions.profile"},
+		{Key: "javascriptEnabled", Action: CompatDropped, Reason: "no W3C meaning"},
+		{Key: "platform", Action: CompatRenamed, To: "platformName"},
+		{Key: "version", Action: CompatRenamed, To: "browserVersion"},
+	}
+	if !reflect.DeepEqual(report.Changes, wantReport) {
+		t.Errorf("TranslateLegacyCapabilities() reported\n%v\nwant\n%v", report.Changes, wantReport)
+	}
+	if _, ok := caps["platformName"]; ok {
+		t.Error("TranslateLegacyCapabilities() changed its argument")
+	}
+
+	if _, report := TranslateLegacyCapabilities(Capabilities{"browserName": "firefox", "platformName": "linux"}); !report.Empty() {
+		t.Errorf("TranslateLegacyCapabilities() of W3C capabilities reported %s", report)
+	}
+	for _, platform := range []string{"ANY", ""} {
+		got, _ := TranslateLegacyCapabilities(Capabilities{"platform": platform})
+		if len(got) != 0 {
+			t.Errorf("TranslateLegacyCapabilities() of platform %q returned %v, want no capabilities", platform, got)
+		}
+	}
+}
+
+func TestCompatSessionOptions(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	caps := Capabilities{"browserName": "chrome", "platform": "WIN10"}
+
+	var report CompatReport
+	wd, err := NewRemoteContext(context.Background(), caps, d.URL, WithCompatReport(func(r CompatReport) { report = r }))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	wd.Quit()
+	if want := []CompatChange{{Key: "platform", Action: CompatRenamed, To: "platformName"}}; !reflect.DeepEqual(report.Changes, want) {
+		t.Errorf("WithCompatReport() got %v, want %v", report.Changes, want)
+	}
+
+	_, err = NewRemoteContext(context.Background(), caps, d.URL, StrictCapabilities())
+	var cerr *CompatError
+	if !errors.As(err, &cerr) || len(cerr.Report.Changes) != 1 {
+		t.Errorf("NewRemoteContext() with StrictCapabilities returned error %v, want a *CompatError with one change", err)
+	}
+
+	report = CompatReport{}
+	wd, err = NewRemoteContext(context.Background(), caps, d.URL, StrictCapabilities(), WithoutCapabilityTranslation(), WithCompatReport(func(r CompatReport) { report = r }))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() without translation returned error: %v", err)
+	}
+	wd.Quit()
+	if !report.Empty() {
+		t.Errorf("NewRemoteContext() without translation reported %s", report)
+	}
+}
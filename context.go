@@ -0,0 +1,39 @@
+package selenium
+
+import (
+	"context"
+	"time"
+)
+
+// WithContext returns a view of the session whose commands are canceled when
+// ctx is done.
+func (wd *remoteWD) WithContext(ctx context.Context) WebDriver {
+	if ctx == nil {
+		panic("selenium: nil context")
+	}
+	return &remoteWD{remoteSession: wd.remoteSession, ctx: ctx}
+}
+
+// Context returns the context of the view of the session.
+func (wd *remoteWD) Context() context.Context {
+	if wd.ctx == nil {
+		return context.Background()
+	}
+	return wd.ctx
+}
+
+// sleep pauses for d, or until the context of the view is done.
+func (wd *remoteWD) sleep(d time.Duration) error {
+	if wd.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-wd.ctx.Done():
+		return wd.ctx.Err()
+	}
+}
@@ -0,0 +1,104 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithContext(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	release := make(chan struct{})
+	defer close(release)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		<-release
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "title"
+	})
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{webElementIdentifier: "e"}
+	})
+	d.handle("GET", "/element/e/text", func([]byte) (int, interface{}) {
+		return http.StatusOK, "text"
+	})
+	wd := d.newRemote(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	view := wd.WithContext(ctx)
+	if view.Context() != ctx {
+		t.Errorf("view.Context() = %v, want %v", view.Context(), ctx)
+	}
+	if wd.Context() != context.Background() {
+		t.Errorf("wd.Context() = %v, want context.Background()", wd.Context())
+	}
+	if view.SessionID() != wd.SessionID() {
+		t.Errorf("view.SessionID() = %q, want %q", view.SessionID(), wd.SessionID())
+	}
+	elem, err := view.FindElement(ByID, "e")
+	if err != nil {
+		t.Fatalf("view.FindElement() returned error: %v", err)
+	}
+
+	start := time.Now()
+	if err := view.Get("https://example.com/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("view.Get() returned error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("view.Get() returned after %v, want it to return at the deadline", elapsed)
+	}
+	if _, err := elem.Text(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("elem.Text() on an element found through the view returned error %v, want context.DeadlineExceeded", err)
+	}
+	polls := 0
+	err = view.WaitWithTimeout(func(WebDriver) (bool, error) {
+		polls++
+		return false, nil
+	}, time.Minute)
+	if !errors.Is(err, context.DeadlineExceeded) || polls != 1 {
+		t.Errorf("view.WaitWithTimeout() returned error %v after %d polls, want context.DeadlineExceeded after 1", err, polls)
+	}
+
+	if title, err := wd.Title(); err != nil || title != "title" {
+		t.Errorf("wd.Title() = %q, %v after the deadline of a view, want %q", title, err, "title")
+	}
+}
+
+func TestWithContextWaitingForSession(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	sent := make(chan struct{})
+	release := make(chan struct{})
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		close(sent)
+		<-release
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	// Another goroutine holds the session with a slow navigation.
+	done := make(chan error, 1)
+	go func() { done <- wd.Get("https://example.com/") }()
+	<-sent
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := wd.WithContext(ctx).Title(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("view.Title() returned error %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("view.Title() returned after %v, want it to stop waiting for the session at the deadline", elapsed)
+	}
+	if n := d.count("GET", "/title"); n != 0 {
+		t.Errorf("view.Title() was sent %d times, want 0", n)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("wd.Get() returned error: %v", err)
+	}
+}
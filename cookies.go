@@ -0,0 +1,172 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ErrInvalidCookie is matched by the errors returned by AddCookie for cookies
+// that are rejected before being sent to the driver.
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// noSuchCookie returns the error of the W3C specification for a missing
+// cookie.
+func noSuchCookie(name string) error {
+	return &Error{Err: string(werror.NoSuchCookie), Message: fmt.Sprintf("no cookie named %q", name)}
+}
+
+// findCookie returns the named cookie among all the cookies.
+func (wd *remoteWD) findCookie(name string) (Cookie, error) {
+	cs, err := wd.GetCookies()
+	if err != nil {
+		return Cookie{}, err
+	}
+	for _, c := range cs {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return Cookie{}, noSuchCookie(name)
+}
+
+// ExpiryTime returns the time when the cookie expires, or the zero time for
+// a session cookie.
+func (c Cookie) ExpiryTime() time.Time {
+	if c.Expiry == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(c.Expiry), 0)
+}
+
+// SetExpiryTime sets the time when the cookie expires, truncated to the
+// second. The zero time makes it a session cookie.
+func (c *Cookie) SetExpiryTime(t time.Time) {
+	if t.IsZero() || t.Unix() <= 0 {
+		c.Expiry = 0
+		return
+	}
+	c.Expiry = uint(t.Unix())
+}
+
+// normalizeCookies checks the cookies and returns them normalized as
+// described by AddCookie. The current URL is fetched once, if a cookie has a
+// domain or a path.
+func (wd *remoteWD) normalizeCookies(cookies []Cookie) ([]Cookie, error) {
+	var page *url.URL
+	for _, c := range cookies {
+		if c.Domain == "" && c.Path == "" {
+			continue
+		}
+		current, err := wd.CurrentURL()
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(current); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			page = u
+		}
+		break
+	}
+	normalized := make([]Cookie, len(cookies))
+	for i, c := range cookies {
+		c, warnings, err := normalizeCookie(c, page)
+		if err != nil {
+			return nil, err
+		}
+		for _, w := range warnings {
+			debugLog("adding cookie %q: %s", c.Name, w)
+		}
+		normalized[i] = c
+	}
+	return normalized, nil
+}
+
+// AddCookies adds cookies to the browser's jar.
+func (wd *remoteWD) AddCookies(cookies []Cookie) error {
+	normalized, err := wd.normalizeCookies(cookies)
+	if err != nil {
+		return err
+	}
+	for i := range normalized {
+		if err := wd.voidCommand("/session/%s/cookie", map[string]*Cookie{
+			"cookie": &normalized[i],
+		}); err != nil {
+			return fmt.Errorf("adding cookie %q: %w", normalized[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// normalizeCookie checks c against page, the URL of the current page if it is
+// an HTTP one, and returns it normalized along with warnings about the
+// differences between drivers that may affect it.
+func normalizeCookie(c Cookie, page *url.URL) (Cookie, []string, error) {
+	invalid := func(format string, args ...interface{}) (Cookie, []string, error) {
+		return Cookie{}, nil, fmt.Errorf("%w %q: %s", ErrInvalidCookie, c.Name, fmt.Sprintf(format, args...))
+	}
+	switch {
+	case c.Name == "":
+		return invalid("the name is empty")
+	case strings.ContainsAny(c.Name, "=;\r\n"):
+		return invalid("the name contains '=', ';' or a line break")
+	case strings.ContainsAny(c.Value, ";\r\n"):
+		return invalid("the value contains ';' or a line break")
+	}
+
+	var warnings []string
+	if c.Domain != "" {
+		domain := strings.ToLower(strings.TrimSpace(c.Domain))
+		if strings.HasPrefix(domain, ".") {
+			domain = strings.TrimPrefix(domain, ".")
+			warnings = append(warnings, fmt.Sprintf("removed the leading dot of domain %q, which GeckoDriver rejects", c.Domain))
+		}
+		if domain == "" {
+			return invalid("the domain %q is empty", c.Domain)
+		}
+		if page != nil {
+			host := strings.ToLower(page.Hostname())
+			matches := host == domain
+			if !matches && net.ParseIP(host) == nil {
+				matches = strings.HasSuffix(host, "."+domain)
+			}
+			if !matches {
+				return invalid("the domain %q does not match the host %q of the current page", c.Domain, host)
+			}
+		}
+		c.Domain = domain
+	}
+
+	if c.Path != "" {
+		if !strings.HasPrefix(c.Path, "/") {
+			return invalid("the path %q does not start with a slash", c.Path)
+		}
+		if c.Path = strings.TrimRight(c.Path, "/"); c.Path == "" {
+			c.Path = "/"
+		}
+		if page != nil && !pathMatches(page.EscapedPath(), c.Path) {
+			warnings = append(warnings, fmt.Sprintf("the path %q does not match the current page %q: the cookie is not visible there, and some drivers reject it", c.Path, page.EscapedPath()))
+		}
+	}
+
+	if c.SameSite == SameSiteNone && !c.Secure {
+		warnings = append(warnings, "a SameSite=None cookie that is not Secure is dropped by Chrome")
+	}
+	return c, warnings, nil
+}
+
+// pathMatches implements the path-match algorithm of RFC 6265, section
+// 5.1.4.
+func pathMatches(requestPath, cookiePath string) bool {
+	if requestPath == "" {
+		requestPath = "/"
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return len(requestPath) == len(cookiePath) || strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
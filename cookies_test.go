@@ -0,0 +1,281 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNormalizeCookie(t *testing.T) {
+	page, _ := url.Parse("https://www.example.com/account/settings")
+	for _, test := range []struct {
+		desc         string
+		in           Cookie
+		page         *url.URL
+		want         Cookie
+		wantInvalid  bool
+		wantWarnings int
+	}{
+		{
+			desc: "no domain or path",
+			in:   Cookie{Name: "a", Value: "b"},
+			page: page,
+			want: Cookie{Name: "a", Value: "b"},
+		},
+		{
+			desc: "host domain",
+			in:   Cookie{Name: "a", Domain: "www.example.com"},
+			page: page,
+			want: Cookie{Name: "a", Domain: "www.example.com"},
+		},
+		{
+			desc:         "leading dot and upper case",
+			in:           Cookie{Name: "a", Domain: ".Example.COM"},
+			page:         page,
+			want:         Cookie{Name: "a", Domain: "example.com"},
+			wantWarnings: 1,
+		},
+		{
+			desc:        "other domain",
+			in:          Cookie{Name: "a", Domain: "example.org"},
+			page:        page,
+			wantInvalid: true,
+		},
+		{
+			desc:        "suffix that is not a parent domain",
+			in:          Cookie{Name: "a", Domain: "ample.com"},
+			page:        page,
+			wantInvalid: true,
+		},
+		{
+			desc: "domain without a page",
+			in:   Cookie{Name: "a", Domain: "example.org"},
+			want: Cookie{Name: "a", Domain: "example.org"},
+		},
+		{
+			desc: "trailing slash",
+			in:   Cookie{Name: "a", Path: "/account/"},
+			page: page,
+			want: Cookie{Name: "a", Path: "/account"},
+		},
+		{
+			desc: "root path",
+			in:   Cookie{Name: "a", Path: "/"},
+			page: page,
+			want: Cookie{Name: "a", Path: "/"},
+		},
+		{
+			desc:         "path of another page",
+			in:           Cookie{Name: "a", Path: "/acc"},
+			page:         page,
+			want:         Cookie{Name: "a", Path: "/acc"},
+			wantWarnings: 1,
+		},
+		{
+			desc:        "relative path",
+			in:          Cookie{Name: "a", Path: "account"},
+			page:        page,
+			wantInvalid: true,
+		},
+		{
+			desc:        "empty name",
+			in:          Cookie{Value: "b"},
+			wantInvalid: true,
+		},
+		{
+			desc:        "semicolon in the value",
+			in:          Cookie{Name: "a", Value: "b; c=d"},
+			wantInvalid: true,
+		},
+		{
+			desc:         "SameSite=None without Secure",
+			in:           Cookie{Name: "a", SameSite: SameSiteNone},
+			want:         Cookie{Name: "a", SameSite: SameSiteNone},
+			wantWarnings: 1,
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, warnings, err := normalizeCookie(test.in, test.page)
+			if test.wantInvalid {
+				if !errors.Is(err, ErrInvalidCookie) {
+					t.Fatalf("normalizeCookie(%+v) returned error %v, want ErrInvalidCookie", test.in, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalizeCookie(%+v) returned error: %v", test.in, err)
+			}
+			if diff := cmp.Diff(test.want, got); diff != "" {
+				t.Errorf("normalizeCookie(%+v) returned diff (-want/+got):\n%s", test.in, diff)
+			}
+			if len(warnings) != test.wantWarnings {
+				t.Errorf("normalizeCookie(%+v) returned warnings %q, want %d", test.in, warnings, test.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestPathMatches(t *testing.T) {
+	for _, test := range []struct {
+		request, cookie string
+		want            bool
+	}{
+		{"/", "/", true},
+		{"", "/", true},
+		{"/a/b", "/a", true},
+		{"/a/b", "/a/", true},
+		{"/a", "/a", true},
+		{"/ab", "/a", false},
+		{"/a", "/a/b", false},
+	} {
+		if got := pathMatches(test.request, test.cookie); got != test.want {
+			t.Errorf("pathMatches(%q, %q) = %t, want %t", test.request, test.cookie, got, test.want)
+		}
+	}
+}
+
+func TestAddCookieNormalizes(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "http://www.example.com/a/b"
+	})
+	var sent Cookie
+	d.handle("POST", "/cookie", func(body []byte) (int, interface{}) {
+		params := new(struct{ Cookie Cookie })
+		json.Unmarshal(body, params)
+		sent = params.Cookie
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	c := &Cookie{Name: "a", Value: "b", Domain: ".example.com", Path: "/a/"}
+	if err := wd.AddCookie(c); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	want := Cookie{Name: "a", Value: "b", Domain: "example.com", Path: "/a"}
+	if diff := cmp.Diff(want, sent); diff != "" {
+		t.Errorf("wd.AddCookie() sent diff (-want/+got):\n%s", diff)
+	}
+	if c.Domain != ".example.com" || c.Path != "/a/" {
+		t.Errorf("wd.AddCookie() changed the cookie passed to %+v", c)
+	}
+
+	if err := wd.AddCookie(&Cookie{Name: "a", Domain: "example.org"}); !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("wd.AddCookie() with another domain returned error %v, want ErrInvalidCookie", err)
+	}
+	if n := d.count("POST", "/cookie"); n != 1 {
+		t.Errorf("%d cookies were sent, want the invalid cookie not sent", n)
+	}
+}
+
+func TestGetCookie(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/cookie/a%20b", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{"name": "a b", "value": "1"}
+	})
+	d.handle("GET", "/cookie/missing", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{"error": "no such cookie", "message": "no such cookie"}
+	})
+	wd := d.newRemote(t)
+
+	got, err := wd.GetCookie("a b")
+	if err != nil {
+		t.Fatalf("wd.GetCookie(%q) returned error: %v", "a b", err)
+	}
+	if got.Value != "1" {
+		t.Errorf("wd.GetCookie(%q) = %+v, want the value 1", "a b", got)
+	}
+	if n := d.count("GET", "/cookie"); n != 0 {
+		t.Errorf("wd.GetCookie() fetched all the cookies %d times, want 0", n)
+	}
+	_, err = wd.GetCookie("missing")
+	if e, ok := err.(*Error); !ok || e.Err != "no such cookie" {
+		t.Errorf("wd.GetCookie(%q) returned error %v, want a no such cookie *Error", "missing", err)
+	}
+}
+
+func TestGetCookieFallback(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/cookie", func([]byte) (int, interface{}) {
+		return http.StatusOK, []map[string]interface{}{
+			{"name": "a", "value": "1"},
+			{"name": "b", "value": "2"},
+		}
+	})
+	wd := d.newRemote(t)
+
+	got, err := wd.GetCookie("b")
+	if err != nil {
+		t.Fatalf("wd.GetCookie(%q) returned error: %v", "b", err)
+	}
+	if got.Value != "2" {
+		t.Errorf("wd.GetCookie(%q) = %+v, want the value 2", "b", got)
+	}
+	_, err = wd.GetCookie("c")
+	if e, ok := err.(*Error); !ok || e.Err != "no such cookie" {
+		t.Errorf("wd.GetCookie(%q) returned error %v, want a no such cookie *Error", "c", err)
+	}
+}
+
+func TestAddCookies(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "http://www.example.com/"
+	})
+	var sent []string
+	d.handle("POST", "/cookie", func(body []byte) (int, interface{}) {
+		sent = append(sent, string(body))
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	session := Cookie{Name: "session", Value: "1", SameSite: SameSiteStrict, HTTPOnly: true}
+	persistent := Cookie{Name: "pref", Value: "dark", Domain: "example.com", Secure: true}
+	persistent.SetExpiryTime(time.Unix(1893456000, 500))
+	if err := wd.AddCookies([]Cookie{session, persistent}); err != nil {
+		t.Fatalf("wd.AddCookies() returned error: %v", err)
+	}
+	want := []string{
+		`{"cookie":{"name":"session","value":"1","secure":false,"httpOnly":true,"sameSite":"Strict"}}`,
+		`{"cookie":{"name":"pref","value":"dark","domain":"example.com","secure":true,"expiry":1893456000,"httpOnly":false}}`,
+	}
+	if diff := cmp.Diff(want, sent); diff != "" {
+		t.Errorf("wd.AddCookies() sent diff (-want/+got):\n%s", diff)
+	}
+	if n := d.count("GET", "/url"); n != 1 {
+		t.Errorf("the current URL was fetched %d times, want once", n)
+	}
+
+	sent = nil
+	err := wd.AddCookies([]Cookie{session, {Name: "other", Domain: "example.org"}})
+	if !errors.Is(err, ErrInvalidCookie) {
+		t.Errorf("wd.AddCookies() with an invalid cookie returned error %v, want ErrInvalidCookie", err)
+	}
+	if len(sent) != 0 {
+		t.Errorf("wd.AddCookies() with an invalid cookie sent %q, want no cookie added", sent)
+	}
+}
+
+func TestCookieExpiryTime(t *testing.T) {
+	var c Cookie
+	if got := c.ExpiryTime(); !got.IsZero() {
+		t.Errorf("ExpiryTime() of a session cookie = %v, want the zero time", got)
+	}
+	expiry := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.SetExpiryTime(expiry.Add(time.Millisecond))
+	if c.Expiry != uint(expiry.Unix()) {
+		t.Errorf("SetExpiryTime() set Expiry to %d, want %d", c.Expiry, expiry.Unix())
+	}
+	if got := c.ExpiryTime(); !got.Equal(expiry) {
+		t.Errorf("ExpiryTime() = %v, want %v", got, expiry)
+	}
+	c.SetExpiryTime(time.Time{})
+	if c.Expiry != 0 {
+		t.Errorf("SetExpiryTime() of the zero time set Expiry to %d, want 0", c.Expiry)
+	}
+}
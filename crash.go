@@ -0,0 +1,173 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// ErrBrowserCrashed is matched by the *CrashError returned when the browser,
+// or the driver controlling it, is gone.
+var ErrBrowserCrashed = errors.New("browser crashed")
+
+// ErrTabCrashed is matched by the *CrashError returned when the renderer of
+// the current tab crashed, while the browser may still be running.
+var ErrTabCrashed = errors.New("tab crashed")
+
+// CrashError is returned by the commands of a session whose browser or tab
+// crashed, in place of the error reported by the driver.
+type CrashError struct {
+	// Kind is ErrBrowserCrashed or ErrTabCrashed.
+	Kind error
+	// Err is the error reported by the driver.
+	Err error
+	// DriverOutput holds the last lines written by the driver, if the session
+	// was created with WithService for a service started with KeepOutput.
+	DriverOutput []string
+	// Minidumps are the paths of the Chrome minidumps written since the
+	// session was created, if chrome.Capabilities.MinidumpPath is set.
+	Minidumps []string
+}
+
+func (e *CrashError) Error() string {
+	msg := fmt.Sprintf("%v: %v", e.Kind, e.Err)
+	if len(e.Minidumps) > 0 {
+		msg += fmt.Sprintf(" (minidumps: %s)", strings.Join(e.Minidumps, ", "))
+	}
+	if len(e.DriverOutput) > 0 {
+		msg += "\nlast driver output:\n" + strings.Join(e.DriverOutput, "\n")
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, e.Kind) true.
+func (e *CrashError) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *CrashError) Unwrap() error {
+	return e.Err
+}
+
+// crashMessages maps the messages by which drivers report crashes to their
+// kind. "no such window" is not among them: closing a window is not a crash.
+var crashMessages = []struct {
+	text string
+	kind error
+}{
+	{"tab crashed", ErrTabCrashed},
+	{"page crash", ErrTabCrashed},
+	{"target crashed", ErrTabCrashed},
+	{"chrome not reachable", ErrBrowserCrashed},
+	{"not connected to devtools", ErrBrowserCrashed},
+	{"browser has closed the connection", ErrBrowserCrashed},
+	{"failed to decode response from marionette", ErrBrowserCrashed},
+	{"tried to run command without establishing a connection", ErrBrowserCrashed},
+}
+
+// crashKind returns ErrBrowserCrashed or ErrTabCrashed if err reports a
+// crash, and nil otherwise.
+func crashKind(err error) error {
+	if e, ok := err.(*Error); ok {
+		msg := strings.ToLower(e.Err + ": " + e.Message)
+		for _, m := range crashMessages {
+			if strings.Contains(msg, m.text) {
+				return m.kind
+			}
+		}
+		return nil
+	}
+	// The driver itself is gone.
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return ErrBrowserCrashed
+	}
+	return nil
+}
+
+// crashError returns err as a *CrashError if it reports a crash, with the
+// details available to the session.
+func (wd *remoteWD) crashError(err error) error {
+	kind := crashKind(err)
+	if kind == nil {
+		return err
+	}
+	e := &CrashError{Kind: kind, Err: err, Minidumps: wd.minidumps()}
+	if wd.service != nil {
+		e.DriverOutput = wd.service.RecentOutput()
+	}
+	return e
+}
+
+// minidumps returns the minidumps written in the directory set by
+// chrome.Capabilities.MinidumpPath since the session was created, newest
+// first.
+func (wd *remoteWD) minidumps() []string {
+	var dir string
+	switch c := wd.capabilities[chrome.CapabilitiesKey].(type) {
+	case chrome.Capabilities:
+		dir = c.MinidumpPath
+	case *chrome.Capabilities:
+		dir = c.MinidumpPath
+	}
+	if dir == "" {
+		return nil
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+	// File systems may record modification times coarsely.
+	since := wd.created.Truncate(time.Second)
+	var paths []string
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".dmp" || info.ModTime().Before(since) {
+			continue
+		}
+		paths = append(paths, filepath.Join(dir, info.Name()))
+	}
+	return paths
+}
+
+// aliveTimeout bounds the probe of IsAlive.
+const aliveTimeout = 5 * time.Second
+
+// IsAlive reports whether the session still responds to commands, by asking
+// for the current window handle. The probe is not recorded in the session
+// summary.
+func (wd *remoteWD) IsAlive() bool {
+	if wd.id == "" {
+		return false
+	}
+	url := "/session/%s/window"
+	if !wd.w3cCompatible {
+		url = "/session/%s/window_handle"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), aliveTimeout)
+	defer cancel()
+	_, err := executeCommandContext(ctx, wd.client, joinLabels(wd.currentTestName(), wd.label), "GET", wd.requestURL(url, wd.id), nil)
+	return err == nil
+}
+
+// WithService associates the session with the service that runs its driver,
+// so that the errors reporting a crash include the last lines of the driver's
+// output, see KeepOutput, and that Service.Stop spares the browser if the
+// session was created with Chrome's Detach option. Without it, the session
+// is associated with the running service whose URLPrefix is the URL prefix
+// of the session, if any, so that the option is only needed when the
+// session reaches the service through another URL, e.g. through a proxy.
+func WithService(s *Service) SessionOption {
+	return func(o *sessionOptions) {
+		o.service = s
+	}
+}
@@ -0,0 +1,102 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+func TestCrashError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-crash-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusInternalServerError, map[string]string{
+			"error":   "unknown error",
+			"message": "session deleted because of page crash\nfrom tab crashed",
+		}
+	})
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{
+			"error":   "no such window",
+			"message": "target window already closed",
+		}
+	})
+	caps := Capabilities{}
+	caps.AddChrome(chrome.Capabilities{MinidumpPath: dir})
+	s := &Service{recent: &lineRing{max: 2}}
+	wd, err := NewRemoteContext(context.Background(), caps, d.URL, WithService(s))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	dump := filepath.Join(dir, "crash.dmp")
+	if err := ioutil.WriteFile(dump, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s.recent.Write([]byte("[1.0][INFO]: first\n[2.0][SEVERE]: Tab crashed\n[3.0][INFO]: done\n"))
+
+	_, err = wd.Title()
+	if !errors.Is(err, ErrTabCrashed) || errors.Is(err, ErrBrowserCrashed) {
+		t.Fatalf("wd.Title() returned %v, want an error matching ErrTabCrashed only", err)
+	}
+	var e *Error
+	if !errors.As(err, &e) || e.Err != "unknown error" {
+		t.Errorf("errors.As(%v) did not find the driver's error", err)
+	}
+	crash := err.(*CrashError)
+	if len(crash.Minidumps) != 1 || crash.Minidumps[0] != dump {
+		t.Errorf("crash.Minidumps = %q, want [%q]", crash.Minidumps, dump)
+	}
+	if want := []string{"[2.0][SEVERE]: Tab crashed", "[3.0][INFO]: done"}; len(crash.DriverOutput) != 2 || crash.DriverOutput[0] != want[0] || crash.DriverOutput[1] != want[1] {
+		t.Errorf("crash.DriverOutput = %q, want %q", crash.DriverOutput, want)
+	}
+
+	if _, err := wd.CurrentURL(); errors.Is(err, ErrTabCrashed) || errors.Is(err, ErrBrowserCrashed) {
+		t.Errorf("wd.CurrentURL() returned %v, want an error not reporting a crash", err)
+	}
+}
+
+func TestCrashKind(t *testing.T) {
+	for _, test := range []struct {
+		err  error
+		want error
+	}{
+		{&Error{Err: "unknown error", Message: "chrome not reachable"}, ErrBrowserCrashed},
+		{&Error{Err: "unknown error", Message: "unknown error: cannot determine loading status\nfrom tab crashed"}, ErrTabCrashed},
+		{&Error{Err: "unknown error", Message: "Failed to decode response from marionette"}, ErrBrowserCrashed},
+		{&Error{Err: "no such element", Message: "no such element"}, nil},
+		{errors.New("some error"), nil},
+	} {
+		if got := crashKind(test.err); got != test.want {
+			t.Errorf("crashKind(%v) = %v, want %v", test.err, got, test.want)
+		}
+	}
+}
+
+func TestIsAlive(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		return http.StatusOK, "CDwindow-1"
+	})
+	wd := d.newRemote(t)
+	if !wd.IsAlive() {
+		t.Error("wd.IsAlive() = false for a responsive session")
+	}
+	d.Close()
+	if wd.IsAlive() {
+		t.Error("wd.IsAlive() = true after the driver stopped")
+	}
+	if _, err := wd.Title(); !errors.Is(err, ErrBrowserCrashed) {
+		t.Errorf("wd.Title() after the driver stopped returned %v, want an error matching ErrBrowserCrashed", err)
+	}
+}
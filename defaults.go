@@ -0,0 +1,71 @@
+package selenium
+
+import (
+	"errors"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// Defaults are the timeouts and polling settings of a session, set with
+// WithDefaults. A zero field selects the library default, so only the fields
+// to change need to be set. Per-call arguments and options, such as the
+// timeout of WaitWithTimeout, take precedence.
+type Defaults struct {
+	// ImplicitWait, if not zero, is set as the implicit wait timeout of the
+	// session when it is created; see WebDriver.SetImplicitWaitTimeout. By
+	// default, the remote end's setting is kept.
+	ImplicitWait time.Duration
+	// WaitTimeout is the timeout of Wait. It defaults to DefaultWaitTimeout.
+	WaitTimeout time.Duration
+	// PollInterval is the delay between evaluations of a condition by Wait
+	// and WaitWithTimeout, between the checks of WithPrecheckTimeout, between
+	// the lookups of a LazyElement and between the retries of a click. It
+	// defaults to DefaultWaitInterval.
+	PollInterval time.Duration
+	// CommandTimeout, if not zero, limits the time that each command sent to
+	// the remote end may take. By default, commands are not limited.
+	CommandTimeout time.Duration
+	// ClickRetries is the number of times WebElement.Click is retried when
+	// the remote end reports that the click was intercepted by another
+	// element, e.g. an overlay that is fading out. By default, clicks are not
+	// retried. WithClickRetries overrides it for one click.
+	ClickRetries int
+}
+
+// WithDefaults sets the timeouts and polling settings of the session.
+func WithDefaults(d Defaults) SessionOption {
+	return func(o *sessionOptions) {
+		o.defaults = d
+	}
+}
+
+// withLibraryDefaults returns d with its zero fields set to the library
+// defaults.
+func (d Defaults) withLibraryDefaults() Defaults {
+	if d.WaitTimeout == 0 {
+		d.WaitTimeout = DefaultWaitTimeout
+	}
+	if d.PollInterval == 0 {
+		d.PollInterval = DefaultWaitInterval
+	}
+	return d
+}
+
+func (wd *remoteWD) Defaults() Defaults {
+	return wd.defaults
+}
+
+// WithClickRetries sets the number of times a click is retried when it is
+// intercepted by another element, overriding Defaults.ClickRetries.
+func WithClickRetries(n int) ActionOption {
+	return func(o *actionOptions) {
+		o.clickRetries = &n
+	}
+}
+
+// isClickInterceptedError reports whether err indicates that another element
+// would have received a click.
+func isClickInterceptedError(err error) bool {
+	return errors.Is(err, werror.ElementClickIntercepted)
+}
@@ -0,0 +1,99 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestDefaults(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var implicit float64
+	d.handle("POST", "/timeouts", func(body []byte) (int, interface{}) {
+		var params map[string]float64
+		if err := json.Unmarshal(body, &params); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		implicit = params["implicit"]
+		return http.StatusOK, nil
+	})
+
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithDefaults(Defaults{
+		ImplicitWait: 2 * time.Second,
+		WaitTimeout:  15 * time.Second,
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	want := Defaults{
+		ImplicitWait: 2 * time.Second,
+		WaitTimeout:  15 * time.Second,
+		PollInterval: DefaultWaitInterval,
+	}
+	if got := wd.Defaults(); got != want {
+		t.Errorf("wd.Defaults() = %+v, want %+v", got, want)
+	}
+	if implicit != 2000 {
+		t.Errorf("the implicit wait timeout was set to %vms, want 2000ms", implicit)
+	}
+
+	if got, want := d.newRemote(t).Defaults(), (Defaults{WaitTimeout: DefaultWaitTimeout, PollInterval: DefaultWaitInterval}); got != want {
+		t.Errorf("without WithDefaults, wd.Defaults() = %+v, want %+v", got, want)
+	}
+	if n := d.count("POST", "/timeouts"); n != 1 {
+		t.Errorf("sent %d Set Timeouts requests, want 1", n)
+	}
+}
+
+func TestClickRetries(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	clicks := 0
+	d.handle("POST", "/element/e/click", func([]byte) (int, interface{}) {
+		clicks++
+		if clicks <= 2 {
+			return http.StatusBadRequest, map[string]string{
+				"error":   "element click intercepted",
+				"message": "Other element would receive the click: <div class=\"overlay\">",
+			}
+		}
+		return http.StatusOK, nil
+	})
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithDefaults(Defaults{
+		PollInterval: time.Millisecond,
+		ClickRetries: 2,
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	if err := wd.ElementFromID("e").Click(); err != nil {
+		t.Errorf("Click() returned error: %v", err)
+	}
+	if clicks != 3 {
+		t.Errorf("Click() sent %d requests, want 3", clicks)
+	}
+
+	clicks = 0
+	if err := wd.ElementFromID("e").Click(WithClickRetries(0)); !isClickInterceptedError(err) {
+		t.Errorf("Click(WithClickRetries(0)) returned %v, want an element click intercepted error", err)
+	}
+}
+
+func TestCommandTimeout(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		time.Sleep(200 * time.Millisecond)
+		return http.StatusOK, "slow"
+	})
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithDefaults(Defaults{
+		CommandTimeout: 20 * time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if _, err := wd.Title(); err == nil {
+		t.Error("wd.Title() returned no error for a command slower than the command timeout")
+	}
+}
@@ -0,0 +1,439 @@
+// Package dockerservice runs a browser and its driver in a Docker container
+// from the selenium/standalone-* images, so that tests need neither the
+// browser nor a driver installed locally:
+//
+//	s, err := dockerservice.Start(ctx, dockerservice.Chrome, nil)
+//	if err != nil {
+//		...
+//	}
+//	defer s.Stop()
+//	wd, err := selenium.NewRemote(caps, s.URLPrefix())
+//
+// The container is created through the Docker Engine API, at the address in
+// the DOCKER_HOST environment variable or on the local socket of the Docker
+// daemon, and removed by Stop.
+package dockerservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Browser is a browser of the standalone images.
+type Browser string
+
+// Browsers supported by Start.
+const (
+	Chrome  Browser = "chrome"
+	Firefox Browser = "firefox"
+	Edge    Browser = "edge"
+)
+
+// Options configures the container started by Start.
+type Options struct {
+	// Image is the image of the container, e.g. a mirror of the standalone
+	// images or an arm64 build such as "seleniarm/standalone-chromium". It
+	// is "selenium/standalone-" followed by the browser if empty.
+	Image string
+	// Tag is the tag of the image, "latest" if empty. It is best pinned to
+	// a version, e.g. "4.21.0", for repeatable tests.
+	Tag string
+	// Env holds the environment variables of the container, e.g.
+	// "SE_NODE_MAX_SESSIONS=4".
+	Env []string
+	// ShmSize is the size of /dev/shm in the container, in bytes, 2GiB if
+	// zero. The browsers crash with the default of Docker, 64MiB.
+	ShmSize int64
+	// StartTimeout bounds the time that the server in the container takes
+	// to be ready, after the image was pulled. It is one minute if zero.
+	StartTimeout time.Duration
+	// DockerHost is the address of the Docker daemon, such as
+	// "unix:///var/run/docker.sock" or "tcp://10.0.0.1:2375". It is the value
+	// of DOCKER_HOST if empty, or the local socket if that is not set either.
+	DockerHost string
+	// Client makes the requests to a Docker daemon listening on TCP.
+	// http.DefaultClient is used if nil.
+	Client *http.Client
+}
+
+const (
+	defaultTag          = "latest"
+	defaultShmSize      = 2 << 30
+	defaultStartTimeout = time.Minute
+	defaultDockerHost   = "unix:///var/run/docker.sock"
+	// seleniumPort is the port of the server in the container.
+	seleniumPort = "4444/tcp"
+)
+
+// pollInterval is the interval between the checks of the readiness of the
+// server. It is a variable for tests.
+var pollInterval = 500 * time.Millisecond
+
+// Service is a browser running in a Docker container.
+type Service struct {
+	// ID is the ID of the container.
+	ID string
+	// Image is the image of the container, with its tag.
+	Image string
+
+	docker *dockerClient
+	addr   string
+}
+
+// URLPrefix returns the address of the server in the container, to create
+// sessions on it with selenium.NewRemote.
+func (s *Service) URLPrefix() string {
+	return s.addr
+}
+
+// Stop stops and removes the container.
+func (s *Service) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return s.docker.remove(ctx, s.ID)
+}
+
+// Start starts a container running browser and its driver, behind a
+// Selenium server, and waits until the server is ready to create sessions.
+// The image is pulled first if it is not present. opts may be nil.
+func Start(ctx context.Context, browser Browser, opts *Options) (*Service, error) {
+	if opts == nil {
+		opts = new(Options)
+	}
+	image := opts.Image
+	if image == "" {
+		if browser != Chrome && browser != Firefox && browser != Edge {
+			return nil, fmt.Errorf("unsupported browser %q", browser)
+		}
+		image = "selenium/standalone-" + string(browser)
+	}
+	tag := opts.Tag
+	if tag == "" {
+		tag = defaultTag
+	}
+	image += ":" + tag
+
+	docker, err := newDockerClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := docker.pullIfMissing(ctx, image); err != nil {
+		return nil, err
+	}
+	id, err := docker.create(ctx, image, opts)
+	if err != nil {
+		return nil, err
+	}
+	s := &Service{ID: id, Image: image, docker: docker}
+	if err := s.start(ctx, opts); err != nil {
+		if rmErr := docker.remove(context.Background(), id); rmErr != nil {
+			return nil, fmt.Errorf("%v; removing the container: %v", err, rmErr)
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// start starts the created container and waits for its server to be ready.
+func (s *Service) start(ctx context.Context, opts *Options) error {
+	if err := s.docker.do(ctx, "POST", "/containers/"+s.ID+"/start", nil, nil); err != nil {
+		return err
+	}
+	port, err := s.docker.hostPort(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+	s.addr = "http://" + net.JoinHostPort(s.docker.host, port) + "/wd/hub"
+
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var lastErr error
+	for {
+		ready, err := serverReady(ctx, s.docker.serverClient, s.addr+"/status")
+		if ready {
+			return nil
+		}
+		// The error of a request interrupted by the timeout says less than
+		// the previous one.
+		if err != nil && ctx.Err() == nil {
+			lastErr = err
+		}
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if lastErr != nil {
+				return fmt.Errorf("the server in container %s was not ready: %v", s.ID, lastErr)
+			}
+			return fmt.Errorf("the server in container %s was not ready: %v", s.ID, ctx.Err())
+		}
+	}
+}
+
+// serverReady reports whether the Selenium server answering statusURL is
+// ready to create sessions. The error explains why it is not, if known.
+func serverReady(ctx context.Context, client *http.Client, statusURL string) (bool, error) {
+	req, err := http.NewRequest("GET", statusURL, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	// The server replies 503 until its node is registered.
+	reply := new(struct {
+		Value struct {
+			Ready   bool
+			Message string
+		}
+	})
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(reply); err != nil {
+		return false, fmt.Errorf("unexpected reply %s: %v", resp.Status, err)
+	}
+	if !reply.Value.Ready {
+		return false, errors.New(reply.Value.Message)
+	}
+	return true, nil
+}
+
+// dockerClient sends requests to the Docker Engine API.
+type dockerClient struct {
+	client *http.Client
+	// base is the URL of the API.
+	base string
+	// host is the host on which the ports of the containers are published,
+	// and serverClient the client that reaches them.
+	host         string
+	serverClient *http.Client
+}
+
+// newDockerClient returns a client of the Docker daemon of opts.
+func newDockerClient(opts *Options) (*dockerClient, error) {
+	dockerHost := opts.DockerHost
+	if dockerHost == "" {
+		dockerHost = os.Getenv("DOCKER_HOST")
+	}
+	if dockerHost == "" {
+		dockerHost = defaultDockerHost
+	}
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Docker host %q: %v", dockerHost, err)
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	switch u.Scheme {
+	case "unix":
+		socket := u.Path
+		var dialer net.Dialer
+		return &dockerClient{
+			client: &http.Client{Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					return dialer.DialContext(ctx, "unix", socket)
+				},
+			}},
+			base:         "http://docker",
+			host:         "127.0.0.1",
+			serverClient: client,
+		}, nil
+	case "tcp", "http":
+		return &dockerClient{
+			client:       client,
+			base:         "http://" + u.Host,
+			host:         u.Hostname(),
+			serverClient: client,
+		}, nil
+	}
+	return nil, fmt.Errorf("unsupported Docker host %q; TLS and SSH are not supported", dockerHost)
+}
+
+// dockerError is the body of the replies of the API to failed requests.
+type dockerError struct {
+	Message string `json:"message"`
+}
+
+// do sends a request to the API, with in encoded in JSON as its body if not
+// nil, and decodes the JSON reply into out if not nil.
+func (d *dockerClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, d.base+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		e := new(dockerError)
+		if json.Unmarshal(data, e) != nil || e.Message == "" {
+			e.Message = strings.TrimSpace(string(data))
+		}
+		return &apiError{Method: method, Path: path, StatusCode: resp.StatusCode, Message: e.Message}
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("%s %s: invalid reply %q: %v", method, path, data, err)
+	}
+	return nil
+}
+
+// apiError is a request that the Docker daemon failed.
+type apiError struct {
+	Method, Path string
+	StatusCode   int
+	Message      string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s %s: %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// isStatus reports whether err is an apiError with the status code.
+func isStatus(err error, code int) bool {
+	var e *apiError
+	return errors.As(err, &e) && e.StatusCode == code
+}
+
+// pullIfMissing pulls image unless it is present.
+func (d *dockerClient) pullIfMissing(ctx context.Context, image string) error {
+	err := d.do(ctx, "GET", "/images/"+image+"/json", nil, nil)
+	if !isStatus(err, http.StatusNotFound) {
+		return err
+	}
+	name, tag := image, defaultTag
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		name, tag = image[:i], image[i+1:]
+	}
+	path := "/images/create?" + url.Values{"fromImage": {name}, "tag": {tag}}.Encode()
+	req, err := http.NewRequest("POST", d.base+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := d.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("pulling %s: %w", image, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("pulling %s: %s: %s", image, resp.Status, bytes.TrimSpace(data))
+	}
+	// The progress of the pull is streamed until it completes, including
+	// its errors.
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var progress struct {
+			Error string `json:"error"`
+		}
+		if err := dec.Decode(&progress); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("pulling %s: %v", image, err)
+		}
+		if progress.Error != "" {
+			return fmt.Errorf("pulling %s: %s", image, progress.Error)
+		}
+	}
+}
+
+// create creates a container of image, publishing the port of the server on
+// a random port of the loopback interface of the host.
+func (d *dockerClient) create(ctx context.Context, image string, opts *Options) (string, error) {
+	shmSize := opts.ShmSize
+	if shmSize <= 0 {
+		shmSize = defaultShmSize
+	}
+	hostIP := "127.0.0.1"
+	if d.host != "127.0.0.1" {
+		// The port must be reachable from this machine.
+		hostIP = ""
+	}
+	config := map[string]interface{}{
+		"Image":        image,
+		"Env":          opts.Env,
+		"ExposedPorts": map[string]interface{}{seleniumPort: struct{}{}},
+		"HostConfig": map[string]interface{}{
+			"PortBindings": map[string]interface{}{
+				seleniumPort: []map[string]string{{"HostIp": hostIP, "HostPort": ""}},
+			},
+			"ShmSize": shmSize,
+		},
+	}
+	reply := new(struct{ ID string })
+	if err := d.do(ctx, "POST", "/containers/create", config, reply); err != nil {
+		return "", err
+	}
+	return reply.ID, nil
+}
+
+// hostPort returns the port of the host on which the port of the server in
+// the container is published.
+func (d *dockerClient) hostPort(ctx context.Context, id string) (string, error) {
+	reply := new(struct {
+		NetworkSettings struct {
+			Ports map[string][]struct{ HostIP, HostPort string }
+		}
+	})
+	if err := d.do(ctx, "GET", "/containers/"+id+"/json", nil, reply); err != nil {
+		return "", err
+	}
+	for _, b := range reply.NetworkSettings.Ports[seleniumPort] {
+		if b.HostPort != "" {
+			return b.HostPort, nil
+		}
+	}
+	return "", fmt.Errorf("port %s of container %s is not published", seleniumPort, id)
+}
+
+// remove stops and removes the container id.
+func (d *dockerClient) remove(ctx context.Context, id string) error {
+	// The browsers are given a few seconds to exit before the container is
+	// killed.
+	err := d.do(ctx, "POST", "/containers/"+id+"/stop?t=5", nil, nil)
+	if err != nil && !isStatus(err, http.StatusNotModified) && !isStatus(err, http.StatusNotFound) {
+		return err
+	}
+	err = d.do(ctx, "DELETE", "/containers/"+id+"?force=true&v=true", nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
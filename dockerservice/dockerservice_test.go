@@ -0,0 +1,167 @@
+package dockerservice
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDocker is a Docker daemon whose containers run a Selenium server that
+// is ready after a few requests to its status.
+type fakeDocker struct {
+	mu       sync.Mutex
+	requests []string
+	images   map[string]bool
+	created  map[string]interface{}
+	removed  bool
+	// notReady is the number of requests to the status of the server before
+	// it is ready.
+	notReady int
+}
+
+func newFakeDocker(t *testing.T) (*fakeDocker, *Options) {
+	d := &fakeDocker{images: make(map[string]bool), notReady: 2}
+	selenium := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if r.URL.Path != "/wd/hub/status" {
+			http.NotFound(w, r)
+			return
+		}
+		if d.notReady > 0 {
+			d.notReady--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"value": {"ready": false, "message": "Selenium Grid not ready."}}`))
+			return
+		}
+		w.Write([]byte(`{"value": {"ready": true, "message": "Selenium Grid ready."}}`))
+	}))
+	t.Cleanup(selenium.Close)
+	u, err := url.Parse(selenium.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docker := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		d.requests = append(d.requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/images/"):
+			if !d.images[strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/images/"), "/json")] {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"message": "No such image"}`))
+				return
+			}
+			w.Write([]byte(`{}`))
+		case r.URL.Path == "/images/create":
+			q := r.URL.Query()
+			d.images[q.Get("fromImage")+":"+q.Get("tag")] = true
+			w.Write([]byte(`{"status": "Pulling from selenium/standalone-chrome"}` + "\n" + `{"status": "Download complete"}`))
+		case r.URL.Path == "/containers/create":
+			body, _ := ioutil.ReadAll(r.Body)
+			json.Unmarshal(body, &d.created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"Id": "c0ffee", "Warnings": []}`))
+		case r.URL.Path == "/containers/c0ffee/start":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/containers/c0ffee/json":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id": "c0ffee",
+				"NetworkSettings": map[string]interface{}{"Ports": map[string]interface{}{
+					"4444/tcp": []map[string]string{{"HostIp": "127.0.0.1", "HostPort": u.Port()}},
+				}},
+			})
+		case r.URL.Path == "/containers/c0ffee/stop":
+			w.WriteHeader(http.StatusNoContent)
+		case r.Method == "DELETE" && r.URL.Path == "/containers/c0ffee":
+			d.removed = true
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message": "page not found"}`))
+		}
+	}))
+	t.Cleanup(docker.Close)
+
+	saved := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = saved })
+	return d, &Options{DockerHost: "tcp://" + strings.TrimPrefix(docker.URL, "http://")}
+}
+
+func TestStart(t *testing.T) {
+	d, opts := newFakeDocker(t)
+	opts.Tag = "4.21.0"
+	opts.Env = []string{"SE_NODE_MAX_SESSIONS=2"}
+	s, err := Start(context.Background(), Chrome, opts)
+	if err != nil {
+		t.Fatalf("Start() returned error: %v", err)
+	}
+	if s.Image != "selenium/standalone-chrome:4.21.0" {
+		t.Errorf("s.Image = %q, want selenium/standalone-chrome:4.21.0", s.Image)
+	}
+	if !strings.HasPrefix(s.URLPrefix(), "http://127.0.0.1:") || !strings.HasSuffix(s.URLPrefix(), "/wd/hub") {
+		t.Errorf("s.URLPrefix() = %q, want the published port", s.URLPrefix())
+	}
+
+	d.mu.Lock()
+	if !d.images["selenium/standalone-chrome:4.21.0"] {
+		t.Errorf("the image was not pulled; requests: %v", d.requests)
+	}
+	host, _ := d.created["HostConfig"].(map[string]interface{})
+	if host["ShmSize"] != float64(defaultShmSize) {
+		t.Errorf("the container was created with ShmSize %v, want %d", host["ShmSize"], defaultShmSize)
+	}
+	if env, _ := d.created["Env"].([]interface{}); len(env) != 1 || env[0] != "SE_NODE_MAX_SESSIONS=2" {
+		t.Errorf("the container was created with Env %v, want %v", env, opts.Env)
+	}
+	if d.notReady != 0 {
+		t.Errorf("Start() returned before the server was ready")
+	}
+	d.mu.Unlock()
+
+	if err := s.Stop(); err != nil {
+		t.Fatalf("s.Stop() returned error: %v", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.removed {
+		t.Errorf("s.Stop() did not remove the container; requests: %v", d.requests)
+	}
+}
+
+func TestStartNotReady(t *testing.T) {
+	d, opts := newFakeDocker(t)
+	d.notReady = 1 << 30
+	d.images["selenium/standalone-firefox:latest"] = true
+	opts.StartTimeout = 20 * time.Millisecond
+	_, err := Start(context.Background(), Firefox, opts)
+	if err == nil || !strings.Contains(err.Error(), "Selenium Grid not ready") {
+		t.Fatalf("Start() returned error %v, want the server not ready", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, r := range d.requests {
+		if r == "POST /images/create" {
+			t.Errorf("Start() pulled the image, which was present")
+		}
+	}
+	if !d.removed {
+		t.Errorf("Start() did not remove the container it failed to start")
+	}
+}
+
+func TestStartUnsupportedHost(t *testing.T) {
+	if _, err := Start(context.Background(), Chrome, &Options{DockerHost: "ssh://user@host"}); err == nil {
+		t.Fatal("Start() with an SSH Docker host returned no error")
+	}
+}
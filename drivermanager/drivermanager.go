@@ -0,0 +1,473 @@
+// Package drivermanager downloads the driver that matches a browser installed
+// locally, like Selenium Manager, so that tests do not have to ship or
+// install drivers:
+//
+//	d, err := drivermanager.Install(ctx, drivermanager.Chrome, nil)
+//	if err != nil {
+//		...
+//	}
+//	service, err := selenium.NewChromeDriverService(d.Path, port)
+//
+// msedgedriver runs with edge.NewService.
+//
+// The drivers are downloaded from Chrome for Testing, the GitHub releases of
+// geckodriver and the Microsoft Edge driver site, and cached by version, so
+// that a driver is only downloaded once. The archives are verified against
+// their SHA-256 checksum when the source publishes one, as GitHub does, or
+// when Options.SHA256 is set, and the cached drivers against the checksum
+// recorded when they were downloaded. Chrome for Testing and the Edge driver
+// site publish no checksums, so that chromedriver and msedgedriver are not
+// verified unless Options.SHA256 is set.
+package drivermanager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Browser is a browser whose driver can be installed, named like the
+// browserName capability.
+type Browser string
+
+// Browsers supported by Install.
+const (
+	Chrome  Browser = "chrome"
+	Firefox Browser = "firefox"
+	Edge    Browser = "MicrosoftEdge"
+)
+
+// The sources of the drivers, replaced in tests.
+var (
+	chromeForTestingURL   = "https://googlechromelabs.github.io/chrome-for-testing/latest-patch-versions-per-build-with-downloads.json"
+	chromeDriverURL       = "https://storage.googleapis.com/chrome-for-testing-public"
+	geckoDriverReleaseURL = "https://api.github.com/repos/mozilla/geckodriver/releases"
+	edgeDriverURL         = "https://msedgedriver.microsoft.com"
+)
+
+// The platform that the drivers are installed for, replaced in tests.
+var goos, goarch = runtime.GOOS, runtime.GOARCH
+
+// platforms are the names of the platforms in the archives of the drivers,
+// by browser and GOOS/GOARCH.
+var platforms = map[Browser]map[string]string{
+	Chrome: {
+		"linux/amd64":   "linux64",
+		"darwin/amd64":  "mac-x64",
+		"darwin/arm64":  "mac-arm64",
+		"windows/amd64": "win64",
+		"windows/386":   "win32",
+	},
+	Firefox: {
+		"linux/amd64":   "linux64",
+		"linux/386":     "linux32",
+		"linux/arm64":   "linux-aarch64",
+		"darwin/amd64":  "macos",
+		"darwin/arm64":  "macos-aarch64",
+		"windows/amd64": "win64",
+		"windows/386":   "win32",
+		"windows/arm64": "win-aarch64",
+	},
+	Edge: {
+		"linux/amd64":   "linux64",
+		"darwin/amd64":  "mac64",
+		"darwin/arm64":  "mac64_m1",
+		"windows/amd64": "win64",
+		"windows/386":   "win32",
+		"windows/arm64": "arm64",
+	},
+}
+
+// drivers are the names of the drivers, by browser.
+var drivers = map[Browser]string{
+	Chrome:  "chromedriver",
+	Firefox: "geckodriver",
+	Edge:    "msedgedriver",
+}
+
+// Options configure Install. The zero value installs the driver of the
+// browser found in the usual locations to the user's cache directory.
+type Options struct {
+	// CacheDir is the directory of the downloaded drivers. It is
+	// "selenium/drivers" in the directory of os.UserCacheDir if empty.
+	CacheDir string
+	// BrowserPath is the binary of the browser whose version the driver
+	// must match, e.g. to choose between several installations.
+	BrowserPath string
+	// BrowserVersion is the version of the browser, e.g. "120.0.6099.109",
+	// in which case the browser is not looked for.
+	BrowserVersion string
+	// DriverVersion is the version of the driver to install, e.g. "0.35.0"
+	// for geckodriver, instead of the one that matches the browser. The
+	// latest geckodriver is installed if empty, as it supports the recent
+	// versions of Firefox.
+	DriverVersion string
+	// SHA256 is the hex-encoded SHA-256 checksum of the archive of the
+	// driver, verified instead of the one published by its source. It is
+	// only useful with DriverVersion. The archives of chromedriver and
+	// msedgedriver, whose sources publish no checksum, are downloaded
+	// unverified if it is empty.
+	SHA256 string
+	// Client makes the requests. http.DefaultClient is used if nil.
+	Client *http.Client
+	// Verbose, if not nil, receives a line for each download, noting the
+	// archives downloaded unverified.
+	Verbose io.Writer
+}
+
+func (o *Options) logf(format string, args ...interface{}) {
+	if o.Verbose != nil {
+		fmt.Fprintf(o.Verbose, "drivermanager: "+format+"\n", args...)
+	}
+}
+
+// Driver is an installed driver.
+type Driver struct {
+	Browser Browser
+	// BrowserVersion is the version of the browser that the driver was
+	// chosen for. It may be empty for Firefox, whose driver does not depend
+	// on the version, if Firefox was not found.
+	BrowserVersion string
+	// Version is the version of the driver.
+	Version string
+	// Path is the binary of the driver, to pass to the service API.
+	Path string
+}
+
+// download is an archive of a driver.
+type download struct {
+	version string
+	url     string
+	// sha256 is the expected checksum of the archive, if known.
+	sha256 string
+}
+
+// Install returns the driver that matches the version of browser, after
+// downloading it to the cache directory if needed. opts may be nil.
+func Install(ctx context.Context, browser Browser, opts *Options) (*Driver, error) {
+	var o Options
+	if opts != nil {
+		o = *opts
+	}
+	if o.Client == nil {
+		o.Client = http.DefaultClient
+	}
+	if o.CacheDir == "" {
+		dir, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("drivermanager: %v", err)
+		}
+		o.CacheDir = filepath.Join(dir, "selenium", "drivers")
+	}
+	name, ok := drivers[browser]
+	if !ok {
+		return nil, fmt.Errorf("drivermanager: unsupported browser %q", browser)
+	}
+	platform, ok := platforms[browser][goos+"/"+goarch]
+	if !ok {
+		return nil, fmt.Errorf("drivermanager: %s is not available for %s/%s", name, goos, goarch)
+	}
+
+	d := &Driver{Browser: browser, BrowserVersion: o.BrowserVersion, Version: o.DriverVersion}
+	if d.BrowserVersion == "" {
+		v, err := BrowserVersion(ctx, browser, o.BrowserPath)
+		if err != nil && browser != Firefox && d.Version == "" {
+			return nil, err
+		}
+		d.BrowserVersion = v
+	}
+
+	var dl *download
+	if d.Version == "" {
+		var err error
+		switch browser {
+		case Chrome:
+			dl, err = latestChromeDriver(ctx, o.Client, d.BrowserVersion, platform)
+		case Firefox:
+			dl, err = geckoDriver(ctx, o.Client, "latest", platform)
+		case Edge:
+			d.Version = d.BrowserVersion
+		}
+		if err != nil {
+			return nil, err
+		}
+		if dl != nil {
+			d.Version = dl.version
+		}
+	}
+	binary := name
+	if goos == "windows" {
+		binary += ".exe"
+	}
+	d.Path = filepath.Join(o.CacheDir, name, d.Version, platform, binary)
+	if verifyCached(d.Path) == nil {
+		return d, nil
+	}
+
+	if dl == nil {
+		switch browser {
+		case Chrome:
+			dl = &download{
+				version: d.Version,
+				url:     fmt.Sprintf("%s/%s/%s/chromedriver-%s.zip", chromeDriverURL, d.Version, platform, platform),
+			}
+		case Firefox:
+			var err error
+			if dl, err = geckoDriver(ctx, o.Client, "tags/v"+d.Version, platform); err != nil {
+				return nil, err
+			}
+		case Edge:
+			dl = &download{
+				version: d.Version,
+				url:     fmt.Sprintf("%s/%s/edgedriver_%s.zip", edgeDriverURL, d.Version, platform),
+			}
+		}
+	}
+	if o.SHA256 != "" {
+		dl.sha256 = strings.ToLower(o.SHA256)
+	}
+	if dl.sha256 == "" {
+		o.logf("downloading %s unverified: its source publishes no checksum; set Options.SHA256 to verify it", dl.url)
+	} else {
+		o.logf("downloading %s", dl.url)
+	}
+	if err := fetch(ctx, o.Client, dl, binary, d.Path); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// latestChromeDriver returns the latest chromedriver of the build of the
+// version of Chrome, e.g. "120.0.6099" for "120.0.6099.109", as drivers are
+// compatible across the patches of a build.
+func latestChromeDriver(ctx context.Context, client *http.Client, version, platform string) (*download, error) {
+	parts := strings.Split(version, ".")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("drivermanager: invalid Chrome version %q", version)
+	}
+	build := strings.Join(parts[:3], ".")
+	var versions struct {
+		Builds map[string]struct {
+			Version   string `json:"version"`
+			Downloads map[string][]struct {
+				Platform string `json:"platform"`
+				URL      string `json:"url"`
+			} `json:"downloads"`
+		} `json:"builds"`
+	}
+	if err := getJSON(ctx, client, chromeForTestingURL, &versions); err != nil {
+		return nil, err
+	}
+	b, ok := versions.Builds[build]
+	if !ok {
+		return nil, fmt.Errorf("drivermanager: no chromedriver for Chrome %s; versions before 115 are not supported", version)
+	}
+	for _, dl := range b.Downloads["chromedriver"] {
+		if dl.Platform == platform {
+			return &download{version: b.Version, url: dl.URL}, nil
+		}
+	}
+	return nil, fmt.Errorf("drivermanager: no chromedriver %s for %s", b.Version, platform)
+}
+
+// geckoDriver returns the geckodriver of the GitHub release, e.g. "latest"
+// or "tags/v0.35.0".
+func geckoDriver(ctx context.Context, client *http.Client, release, platform string) (*download, error) {
+	var r struct {
+		TagName string `json:"tag_name"`
+		Assets  []struct {
+			Name        string `json:"name"`
+			DownloadURL string `json:"browser_download_url"`
+			// Digest is e.g. "sha256:<hex>".
+			Digest string `json:"digest"`
+		} `json:"assets"`
+	}
+	if err := getJSON(ctx, client, geckoDriverReleaseURL+"/"+release, &r); err != nil {
+		return nil, err
+	}
+	ext := ".tar.gz"
+	if goos == "windows" {
+		ext = ".zip"
+	}
+	name := fmt.Sprintf("geckodriver-%s-%s%s", r.TagName, platform, ext)
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return &download{
+				version: strings.TrimPrefix(r.TagName, "v"),
+				url:     a.DownloadURL,
+				sha256:  strings.TrimPrefix(a.Digest, "sha256:"),
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("drivermanager: geckodriver release %s has no asset %s", r.TagName, name)
+}
+
+// get returns the response to a GET request of url, which must succeed.
+func get(ctx context.Context, client *http.Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		// Avoids the rate limit of anonymous requests.
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("drivermanager: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("drivermanager: getting %s: %s", url, resp.Status)
+	}
+	return resp, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	resp, err := get(ctx, client, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("drivermanager: decoding %s: %v", url, err)
+	}
+	return nil
+}
+
+// fetch downloads the archive dl, verifies it and extracts binary to dest,
+// along with its checksum.
+func fetch(ctx context.Context, client *http.Client, dl *download, binary, dest string) error {
+	resp, err := get(ctx, client, dl.url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	archive, err := ioutil.TempFile("", "drivermanager-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(archive, h), resp.Body)
+	if err != nil {
+		return fmt.Errorf("drivermanager: downloading %s: %v", dl.url, err)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); dl.sha256 != "" && sum != dl.sha256 {
+		return fmt.Errorf("drivermanager: the SHA-256 checksum of %s is %s, want %s", dl.url, sum, dl.sha256)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	out, err := ioutil.TempFile(filepath.Dir(dest), binary+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	defer out.Close()
+	h = sha256.New()
+	w := io.MultiWriter(out, h)
+	if strings.HasSuffix(dl.url, ".zip") {
+		err = extractZip(archive, size, binary, w)
+	} else {
+		if _, err = archive.Seek(0, io.SeekStart); err == nil {
+			err = extractTarGz(archive, binary, w)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("drivermanager: extracting %s from %s: %v", binary, dl.url, err)
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(out.Name(), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), dest); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest+".sha256", []byte(hex.EncodeToString(h.Sum(nil))+"\n"), 0644)
+}
+
+// errNotInArchive is returned when the archive of a driver has no binary.
+var errNotInArchive = errors.New("no such file in the archive")
+
+func extractZip(r io.ReaderAt, size int64, binary string, w io.Writer) error {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, f := range z.File {
+		if path.Base(f.Name) != binary || f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		_, err = io.Copy(w, rc)
+		return err
+	}
+	return errNotInArchive
+}
+
+func extractTarGz(r io.Reader, binary string, w io.Writer) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	t := tar.NewReader(gz)
+	for {
+		hdr, err := t.Next()
+		if err == io.EOF {
+			return errNotInArchive
+		}
+		if err != nil {
+			return err
+		}
+		if path.Base(hdr.Name) == binary && hdr.Typeflag == tar.TypeReg {
+			_, err = io.Copy(w, t)
+			return err
+		}
+	}
+}
+
+// verifyCached returns an error unless the driver at path exists and matches
+// the checksum recorded when it was downloaded.
+func verifyCached(path string) error {
+	want, err := ioutil.ReadFile(path + ".sha256")
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != strings.TrimSpace(string(want)) {
+		return fmt.Errorf("drivermanager: the SHA-256 checksum of %s is %s, want %s", path, sum, want)
+	}
+	return nil
+}
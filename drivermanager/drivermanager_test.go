@@ -0,0 +1,256 @@
+package drivermanager
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func zipArchive(t *testing.T, name, content string) []byte {
+	var b bytes.Buffer
+	z := zip.NewWriter(&b)
+	w, err := z.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(content))
+	if err := z.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func tarGzArchive(t *testing.T, name, content string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+	tw.Write([]byte(content))
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return b.Bytes()
+}
+
+// fakeSources serves the sources of the drivers for linux/amd64, and
+// returns the number of requests received by path.
+func fakeSources(t *testing.T) (requests func(path string) int) {
+	var mu sync.Mutex
+	counts := map[string]int{}
+	mux := http.NewServeMux()
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		counts[r.URL.Path]++
+		mu.Unlock()
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(s.Close)
+
+	serve := func(path string, data []byte) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) { w.Write(data) })
+	}
+	serveJSON := func(path string, v interface{}) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		serve(path, data)
+	}
+	serveJSON("/cft.json", map[string]interface{}{
+		"builds": map[string]interface{}{
+			"120.0.6099": map[string]interface{}{
+				"version": "120.0.6099.109",
+				"downloads": map[string]interface{}{
+					"chromedriver": []map[string]string{
+						{"platform": "mac-x64", "url": s.URL + "/mac.zip"},
+						{"platform": "linux64", "url": s.URL + "/cft/120.0.6099.109/linux64/chromedriver-linux64.zip"},
+					},
+				},
+			},
+		},
+	})
+	serve("/cft/120.0.6099.109/linux64/chromedriver-linux64.zip", zipArchive(t, "chromedriver-linux64/chromedriver", "chromedriver 120"))
+
+	gecko := tarGzArchive(t, "geckodriver", "geckodriver 0.35")
+	sum := sha256.Sum256(gecko)
+	release := map[string]interface{}{
+		"tag_name": "v0.35.0",
+		"assets": []map[string]string{
+			{"name": "geckodriver-v0.35.0-win64.zip", "browser_download_url": s.URL + "/win64.zip"},
+			{
+				"name":                 "geckodriver-v0.35.0-linux64.tar.gz",
+				"browser_download_url": s.URL + "/geckodriver-v0.35.0-linux64.tar.gz",
+				"digest":               "sha256:" + hex.EncodeToString(sum[:]),
+			},
+		},
+	}
+	serveJSON("/releases/latest", release)
+	serveJSON("/releases/tags/v0.35.0", release)
+	serve("/geckodriver-v0.35.0-linux64.tar.gz", gecko)
+
+	serve("/edge/120.0.2210.91/edgedriver_linux64.zip", zipArchive(t, "msedgedriver", "msedgedriver 120"))
+
+	saved := []string{chromeForTestingURL, chromeDriverURL, geckoDriverReleaseURL, edgeDriverURL, goos, goarch}
+	chromeForTestingURL = s.URL + "/cft.json"
+	chromeDriverURL = s.URL + "/cft"
+	geckoDriverReleaseURL = s.URL + "/releases"
+	edgeDriverURL = s.URL + "/edge"
+	goos, goarch = "linux", "amd64"
+	t.Cleanup(func() {
+		chromeForTestingURL, chromeDriverURL, geckoDriverReleaseURL, edgeDriverURL, goos, goarch = saved[0], saved[1], saved[2], saved[3], saved[4], saved[5]
+	})
+	return func(path string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return counts[path]
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "drivermanager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func checkDriver(t *testing.T, d *Driver, version, content string) {
+	t.Helper()
+	if d.Version != version {
+		t.Errorf("Install() returned version %q, want %q", d.Version, version)
+	}
+	got, err := ioutil.ReadFile(d.Path)
+	if err != nil {
+		t.Fatalf("reading the driver: %v", err)
+	}
+	if string(got) != content {
+		t.Errorf("the driver at %s contains %q, want %q", d.Path, got, content)
+	}
+}
+
+func TestInstallChrome(t *testing.T) {
+	requests := fakeSources(t)
+	const archive = "/cft/120.0.6099.109/linux64/chromedriver-linux64.zip"
+	ctx := context.Background()
+	opts := &Options{CacheDir: tempDir(t), BrowserVersion: "120.0.6099.71"}
+
+	d, err := Install(ctx, Chrome, opts)
+	if err != nil {
+		t.Fatalf("Install() returned error: %v", err)
+	}
+	checkDriver(t, d, "120.0.6099.109", "chromedriver 120")
+	if want := filepath.Join(opts.CacheDir, "chromedriver", "120.0.6099.109", "linux64", "chromedriver"); d.Path != want {
+		t.Errorf("Install() installed the driver to %s, want %s", d.Path, want)
+	}
+	if info, err := os.Stat(d.Path); err != nil || (runtime.GOOS != "windows" && info.Mode()&0100 == 0) {
+		t.Errorf("the driver is not executable: %v, %v", info.Mode(), err)
+	}
+
+	if _, err := Install(ctx, Chrome, opts); err != nil {
+		t.Fatalf("Install() of a cached driver returned error: %v", err)
+	}
+	if n := requests(archive); n != 1 {
+		t.Errorf("the driver was downloaded %d times, want once", n)
+	}
+
+	if err := ioutil.WriteFile(d.Path, []byte("corrupted"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	pinned := *opts
+	pinned.DriverVersion = "120.0.6099.109"
+	d, err = Install(ctx, Chrome, &pinned)
+	if err != nil {
+		t.Fatalf("Install() of a corrupted driver returned error: %v", err)
+	}
+	checkDriver(t, d, "120.0.6099.109", "chromedriver 120")
+	if n := requests(archive); n != 2 {
+		t.Errorf("the driver was downloaded %d times, want twice after it was corrupted", n)
+	}
+
+	old := *opts
+	old.BrowserVersion = "114.0.5735.90"
+	if _, err := Install(ctx, Chrome, &old); err == nil || !strings.Contains(err.Error(), "115") {
+		t.Errorf("Install() for Chrome 114 returned error %v, want versions before 115 unsupported", err)
+	}
+}
+
+func TestInstallFirefox(t *testing.T) {
+	requests := fakeSources(t)
+	ctx := context.Background()
+	opts := &Options{CacheDir: tempDir(t), BrowserPath: filepath.Join(tempDir(t), "missing")}
+
+	d, err := Install(ctx, Firefox, opts)
+	if err != nil {
+		t.Fatalf("Install() returned error: %v", err)
+	}
+	checkDriver(t, d, "0.35.0", "geckodriver 0.35")
+	if d.BrowserVersion != "" {
+		t.Errorf("Install() returned browser version %q for a missing Firefox", d.BrowserVersion)
+	}
+
+	pinned := &Options{
+		CacheDir:       tempDir(t),
+		BrowserVersion: "128.0",
+		DriverVersion:  "0.35.0",
+		SHA256:         strings.Repeat("0", 64),
+	}
+	if _, err := Install(ctx, Firefox, pinned); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("Install() with a wrong checksum returned error %v, want a checksum error", err)
+	}
+	if n := requests("/releases/tags/v0.35.0"); n != 1 {
+		t.Errorf("the release of the pinned version was requested %d times, want once", n)
+	}
+}
+
+func TestInstallEdge(t *testing.T) {
+	requests := fakeSources(t)
+	var verbose bytes.Buffer
+	opts := &Options{CacheDir: tempDir(t), BrowserVersion: "120.0.2210.91", Verbose: &verbose}
+	d, err := Install(context.Background(), Edge, opts)
+	if err != nil {
+		t.Fatalf("Install() returned error: %v", err)
+	}
+	checkDriver(t, d, "120.0.2210.91", "msedgedriver 120")
+	if n := requests("/edge/120.0.2210.91/edgedriver_linux64.zip"); n != 1 {
+		t.Errorf("the driver was downloaded %d times, want once", n)
+	}
+	if !strings.Contains(verbose.String(), "unverified") {
+		t.Errorf("Install() reported %q, want the download reported as unverified", verbose.String())
+	}
+}
+
+func TestBrowserVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the browser is a shell script")
+	}
+	path := filepath.Join(tempDir(t), "google-chrome")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\necho 'Google Chrome 120.0.6099.109 '\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	v, err := BrowserVersion(context.Background(), Chrome, path)
+	if err != nil {
+		t.Fatalf("BrowserVersion() returned error: %v", err)
+	}
+	if v != "120.0.6099.109" {
+		t.Errorf("BrowserVersion() = %q, want %q", v, "120.0.6099.109")
+	}
+	if _, err := BrowserVersion(context.Background(), Chrome, path+"-missing"); err == nil {
+		t.Error("BrowserVersion() of a missing browser returned no error")
+	}
+}
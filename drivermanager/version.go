@@ -0,0 +1,92 @@
+package drivermanager
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// versionRE matches a version in the output of the browser or the registry.
+var versionRE = regexp.MustCompile(`\d+\.\d+(\.\d+)*`)
+
+// linuxBinaries are the names of the browsers in the PATH on Linux and BSD.
+var linuxBinaries = map[Browser][]string{
+	Chrome:  {"google-chrome", "google-chrome-stable", "chromium", "chromium-browser"},
+	Firefox: {"firefox"},
+	Edge:    {"microsoft-edge", "microsoft-edge-stable"},
+}
+
+// macBinaries are the binaries of the browsers on macOS.
+var macBinaries = map[Browser][]string{
+	Chrome:  {"/Applications/Google Chrome.app/Contents/MacOS/Google Chrome", "/Applications/Chromium.app/Contents/MacOS/Chromium"},
+	Firefox: {"/Applications/Firefox.app/Contents/MacOS/firefox"},
+	Edge:    {"/Applications/Microsoft Edge.app/Contents/MacOS/Microsoft Edge"},
+}
+
+// windowsRegistry are the registry keys and values of the versions of the
+// browsers on Windows, whose binaries do not print their version.
+var windowsRegistry = map[Browser][][2]string{
+	Chrome: {
+		{`HKCU\Software\Google\Chrome\BLBeacon`, "version"},
+		{`HKLM\Software\Wow6432Node\Microsoft\Windows\CurrentVersion\Uninstall\Google Chrome`, "DisplayVersion"},
+	},
+	Firefox: {
+		{`HKLM\Software\Mozilla\Mozilla Firefox`, "CurrentVersion"},
+		{`HKCU\Software\Mozilla\Mozilla Firefox`, "CurrentVersion"},
+	},
+	Edge: {
+		{`HKCU\Software\Microsoft\Edge\BLBeacon`, "version"},
+	},
+}
+
+// versionCommands returns the commands that may print the version of the
+// browser installed at path, or in the usual locations.
+func versionCommands(browser Browser, path string) [][]string {
+	if path != "" {
+		if goos == "windows" {
+			script := fmt.Sprintf("(Get-Item -LiteralPath '%s').VersionInfo.ProductVersion", strings.Replace(path, "'", "''", -1))
+			return [][]string{{"powershell", "-NoProfile", "-Command", script}}
+		}
+		return [][]string{{path, "--version"}}
+	}
+	var cmds [][]string
+	switch goos {
+	case "windows":
+		for _, r := range windowsRegistry[browser] {
+			cmds = append(cmds, []string{"reg", "query", r[0], "/v", r[1]})
+		}
+	case "darwin":
+		for _, b := range macBinaries[browser] {
+			cmds = append(cmds, []string{b, "--version"})
+		}
+	default:
+		for _, b := range linuxBinaries[browser] {
+			cmds = append(cmds, []string{b, "--version"})
+		}
+	}
+	return cmds
+}
+
+// BrowserVersion returns the version of browser, e.g. "120.0.6099.109", that
+// is installed at path or, if path is empty, in the usual locations.
+func BrowserVersion(ctx context.Context, browser Browser, path string) (string, error) {
+	cmds := versionCommands(browser, path)
+	if len(cmds) == 0 {
+		return "", fmt.Errorf("drivermanager: unsupported browser %q", browser)
+	}
+	var lastErr error
+	for _, args := range cmds {
+		out, err := exec.CommandContext(ctx, args[0], args[1:]...).Output()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if v := versionRE.FindString(string(out)); v != "" {
+			return v, nil
+		}
+		lastErr = fmt.Errorf("no version in %q", strings.TrimSpace(string(out)))
+	}
+	return "", fmt.Errorf("drivermanager: %s not found: %v", browser, lastErr)
+}
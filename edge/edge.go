@@ -0,0 +1,62 @@
+// Package edge provides Microsoft Edge-specific options for WebDriver.
+//
+// Edge is based on Chromium and its driver, msedgedriver, on ChromeDriver, so
+// the options of Edge have the format of Chrome's and are those of the chrome
+// package, under another capability key:
+//
+//	caps := selenium.Capabilities{"browserName": edge.BrowserName}
+//	caps.AddEdge(edge.Capabilities{
+//		Args:            []string{"--headless=new"},
+//		MobileEmulation: &edge.MobileEmulation{DeviceName: "Pixel 7"},
+//	})
+//	service, err := edge.NewService("", port)
+//	...
+//	wd, err := selenium.NewRemote(caps, service.URLPrefix())
+package edge
+
+import (
+	"os/exec"
+
+	"github.com/LoveOyy/selenium"
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// CapabilitiesKey is the key in the top-level Capabilities map under which
+// msedgedriver expects the Edge-specific options to be set.
+const CapabilitiesKey = selenium.EdgeCapabilitiesKey
+
+// BrowserName is the value of the browserName capability for Edge.
+const BrowserName = "MicrosoftEdge"
+
+// Capabilities defines the Edge-specific desired capabilities: the binary,
+// arguments, extensions, preferences, mobile emulation, etc. As they are
+// Chrome's, the methods of chrome.Capabilities, such as AddExtension, and the
+// helpers of selenium.Capabilities that change the browser options, such as
+// SetUserAgent, apply to Edge too. Add them with
+// selenium.Capabilities.AddEdge.
+type Capabilities = chrome.Capabilities
+
+// MobileEmulation provides options for mobile emulation.
+type MobileEmulation = chrome.MobileEmulation
+
+// DeviceMetrics specifies device attributes for emulation.
+type DeviceMetrics = chrome.DeviceMetrics
+
+// PerfLoggingPreferences specifies configuration options for performance
+// logging.
+type PerfLoggingPreferences = chrome.PerfLoggingPreferences
+
+// NewService starts msedgedriver in the background, listening on port. It
+// takes the flags of ChromeDriver. If path is empty, msedgedriver is looked
+// up in the PATH; the drivermanager package downloads the driver that
+// matches the installed Edge.
+func NewService(path string, port int, opts ...selenium.ServiceOption) (*selenium.Service, error) {
+	if path == "" {
+		p, err := exec.LookPath("msedgedriver")
+		if err != nil {
+			return nil, err
+		}
+		path = p
+	}
+	return selenium.NewChromeDriverService(path, port, opts...)
+}
@@ -0,0 +1,42 @@
+package edge
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+func TestAddEdge(t *testing.T) {
+	caps := selenium.Capabilities{"browserName": BrowserName}
+	caps.AddEdge(Capabilities{
+		Args:            []string{"--headless=new"},
+		Prefs:           map[string]interface{}{"intl.accept_languages": "fr"},
+		MobileEmulation: &MobileEmulation{DeviceName: "Pixel 7"},
+	})
+	if err := caps.SetUserAgent("test-agent"); err != nil {
+		t.Fatalf("caps.SetUserAgent() returned error: %v", err)
+	}
+
+	data, err := json.Marshal(caps[CapabilitiesKey])
+	if err != nil {
+		t.Fatalf("json.Marshal() returned error: %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) returned error: %v", data, err)
+	}
+	want := map[string]interface{}{
+		"args":            []interface{}{"--headless=new", "--user-agent=test-agent"},
+		"prefs":           map[string]interface{}{"intl.accept_languages": "fr"},
+		"mobileEmulation": map[string]interface{}{"deviceName": "Pixel 7"},
+		"w3c":             true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("the Edge options are %s, want %v", data, want)
+	}
+	if _, ok := caps["goog:chromeOptions"]; ok {
+		t.Error("caps.AddEdge() added Chrome options")
+	}
+}
@@ -0,0 +1,193 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ElementList is a list of elements, such as the result of FindElements,
+// with operations on all of them. Convert a []WebElement with
+// ElementList(elems), or wrap a call with Elements.
+//
+// Texts, Attributes and Visible read all the elements with a single script
+// when they belong to the same session, and fall back to one command per
+// element otherwise, or when the script fails. Operations on an empty list
+// return empty results. Errors about an element are *ElementListError.
+type ElementList []WebElement
+
+// Elements returns elems as an ElementList, e.g.
+//
+//	items, err := selenium.Elements(wd.FindElements(selenium.ByCSSSelector, "li"))
+func Elements(elems []WebElement, err error) (ElementList, error) {
+	return ElementList(elems), err
+}
+
+// ElementListError reports the failure of an operation on an element of an
+// ElementList.
+type ElementListError struct {
+	// Index is the index of the element in the list.
+	Index int
+	// Len is the length of the list.
+	Len int
+	// Err is the error returned for the element.
+	Err error
+}
+
+func (e *ElementListError) Error() string {
+	return fmt.Sprintf("element %d of %d failed: %v", e.Index, e.Len, e.Err)
+}
+
+func (e *ElementListError) Unwrap() error {
+	return e.Err
+}
+
+func (l ElementList) errorAt(i int, err error) error {
+	return &ElementListError{Index: i, Len: len(l), Err: err}
+}
+
+// Each calls fn with each element and its index, in order, until fn returns
+// an error.
+func (l ElementList) Each(fn func(i int, elem WebElement) error) error {
+	for i, elem := range l {
+		if err := fn(i, elem); err != nil {
+			return l.errorAt(i, err)
+		}
+	}
+	return nil
+}
+
+// Filter returns the elements for which pred returns true.
+func (l ElementList) Filter(pred func(WebElement) (bool, error)) (ElementList, error) {
+	var matched ElementList
+	for i, elem := range l {
+		ok, err := pred(elem)
+		if err != nil {
+			return nil, l.errorAt(i, err)
+		}
+		if ok {
+			matched = append(matched, elem)
+		}
+	}
+	return matched, nil
+}
+
+// First returns the first element for which pred returns true, or nil if
+// there is none.
+func (l ElementList) First(pred func(WebElement) (bool, error)) (WebElement, error) {
+	for i, elem := range l {
+		ok, err := pred(elem)
+		if err != nil {
+			return nil, l.errorAt(i, err)
+		}
+		if ok {
+			return elem, nil
+		}
+	}
+	return nil, nil
+}
+
+// Texts returns the visible text of each element. The batch script reads
+// innerText, which is what the drivers return for rendered elements.
+func (l ElementList) Texts() ([]string, error) {
+	if values, ok := l.batch("return arguments[0].map(function(e) { return e.innerText; });"); ok {
+		texts := make([]string, len(values))
+		for i, v := range values {
+			if err := json.Unmarshal(v, &texts[i]); err != nil {
+				return nil, l.errorAt(i, err)
+			}
+		}
+		return texts, nil
+	}
+	texts := make([]string, len(l))
+	return texts, l.Each(func(i int, elem WebElement) error {
+		var err error
+		texts[i], err = elem.Text()
+		return err
+	})
+}
+
+// Attributes returns the value of the named attribute of each element. As
+// with GetAttribute, it fails for an element without the attribute.
+func (l ElementList) Attributes(name string) ([]string, error) {
+	script := "var name = arguments[1]; return arguments[0].map(function(e) { return e.getAttribute(name); });"
+	if values, ok := l.batch(script, name); ok {
+		attrs := make([]string, len(values))
+		missing := false
+		for i, v := range values {
+			var attr *string
+			if err := json.Unmarshal(v, &attr); err != nil {
+				return nil, l.errorAt(i, err)
+			}
+			if attr == nil {
+				missing = true
+				break
+			}
+			attrs[i] = *attr
+		}
+		// Report a missing attribute by its index below.
+		if !missing {
+			return attrs, nil
+		}
+	}
+	attrs := make([]string, len(l))
+	return attrs, l.Each(func(i int, elem WebElement) error {
+		var err error
+		attrs[i], err = elem.GetAttribute(name)
+		return err
+	})
+}
+
+// Visible returns the elements that are displayed. The batch script checks
+// the same properties as the CheckDisplayed check of EnsureInteractable;
+// the fallback uses IsDisplayed.
+func (l ElementList) Visible() (ElementList, error) {
+	script := `return arguments[0].map(function(el) {
+		var style = window.getComputedStyle(el);
+		return el.checkVisibility ?
+			el.checkVisibility({opacityProperty: true, visibilityProperty: true}) :
+			el.getClientRects().length > 0 && style.visibility === 'visible' && style.opacity !== '0';
+	});`
+	if values, ok := l.batch(script); ok {
+		var matched ElementList
+		for i, v := range values {
+			var visible bool
+			if err := json.Unmarshal(v, &visible); err != nil {
+				return nil, l.errorAt(i, err)
+			}
+			if visible {
+				matched = append(matched, l[i])
+			}
+		}
+		return matched, nil
+	}
+	return l.Filter(WebElement.IsDisplayed)
+}
+
+// batch runs script with the elements as its first argument, followed by
+// args, and returns its result, a value per element. It returns false if the
+// elements cannot be read by a single script, or if the script failed, so
+// that the caller reads them one by one and reports the failing element.
+func (l ElementList) batch(script string, args ...interface{}) ([]json.RawMessage, bool) {
+	if len(l) == 0 {
+		return nil, false
+	}
+	var wd *remoteWD
+	elems := make([]*remoteWE, len(l))
+	for i, elem := range l {
+		e, ok := elem.(*remoteWE)
+		if !ok || (wd != nil && e.parent != wd) {
+			return nil, false
+		}
+		wd, elems[i] = e.parent, e
+	}
+	data, err := wd.ExecuteScriptRaw(script, append([]interface{}{elems}, args...))
+	if err != nil {
+		debugLog("error reading %d elements by script, reading them one by one: %v", len(l), err)
+		return nil, false
+	}
+	reply := new(struct{ Value []json.RawMessage })
+	if err := json.Unmarshal(data, reply); err != nil || len(reply.Value) != len(l) {
+		return nil, false
+	}
+	return reply.Value, true
+}
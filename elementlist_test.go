@@ -0,0 +1,129 @@
+package selenium
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// fakeList registers a Find Elements handler on d returning elements with
+// the given IDs, and returns them as an ElementList.
+func fakeList(t *testing.T, d *fakeDriver, ids ...string) ElementList {
+	t.Helper()
+	d.handle("POST", "/elements", func([]byte) (int, interface{}) {
+		var refs []map[string]string
+		for _, id := range ids {
+			refs = append(refs, map[string]string{webElementIdentifier: id})
+		}
+		return http.StatusOK, refs
+	})
+	wd := d.newRemote(t)
+	l, err := Elements(wd.FindElements(ByCSSSelector, "li"))
+	if err != nil {
+		t.Fatalf("wd.FindElements() returned error: %v", err)
+	}
+	return l
+}
+
+func TestElementListTextsBatch(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, []string{"one", "two", "three"}
+	})
+	l := fakeList(t, d, "a", "b", "c")
+
+	got, err := l.Texts()
+	if err != nil {
+		t.Fatalf("l.Texts() returned error: %v", err)
+	}
+	if want := []string{"one", "two", "three"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("l.Texts() = %q, want %q", got, want)
+	}
+	if n := d.count("POST", "/execute/sync"); n != 1 {
+		t.Errorf("l.Texts() sent %d scripts, want 1", n)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if n := d.count("GET", "/element/"+id+"/text"); n != 0 {
+			t.Errorf("l.Texts() sent %d Get Element Text requests for %q, want 0", n, id)
+		}
+	}
+}
+
+func TestElementListFallbackIndex(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return staleElementReply()
+	})
+	d.handle("GET", "/element/a/text", func([]byte) (int, interface{}) {
+		return http.StatusOK, "one"
+	})
+	d.handle("GET", "/element/b/text", func([]byte) (int, interface{}) {
+		return staleElementReply()
+	})
+	l := fakeList(t, d, "a", "b", "c")
+
+	_, err := l.Texts()
+	var listErr *ElementListError
+	if !errors.As(err, &listErr) {
+		t.Fatalf("l.Texts() returned error %v, want an *ElementListError", err)
+	}
+	if listErr.Index != 1 || listErr.Len != 3 {
+		t.Errorf("l.Texts() failed for element %d of %d, want 1 of 3", listErr.Index, listErr.Len)
+	}
+	if !isStaleElementError(err) {
+		t.Errorf("l.Texts() returned error %v, want a stale element error", err)
+	}
+}
+
+func TestElementListVisibleAndFirst(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, []bool{false, true, true}
+	})
+	d.handle("GET", "/element/c/attribute/class", func([]byte) (int, interface{}) {
+		return http.StatusOK, "selected"
+	})
+	d.handle("GET", "/element/b/attribute/class", func([]byte) (int, interface{}) {
+		return http.StatusOK, ""
+	})
+	l := fakeList(t, d, "a", "b", "c")
+
+	visible, err := l.Visible()
+	if err != nil {
+		t.Fatalf("l.Visible() returned error: %v", err)
+	}
+	if len(visible) != 2 || visible[0].(*remoteWE).id != "b" || visible[1].(*remoteWE).id != "c" {
+		t.Fatalf("l.Visible() = %v, want elements b and c", visible)
+	}
+	selected, err := visible.First(func(e WebElement) (bool, error) {
+		class, err := e.GetAttribute("class")
+		return class == "selected", err
+	})
+	if err != nil {
+		t.Fatalf("visible.First() returned error: %v", err)
+	}
+	if selected == nil || selected.(*remoteWE).id != "c" {
+		t.Errorf("visible.First() = %v, want element c", selected)
+	}
+}
+
+func TestElementListEmpty(t *testing.T) {
+	var l ElementList
+	if texts, err := l.Texts(); err != nil || len(texts) != 0 {
+		t.Errorf("l.Texts() = %q, %v, want no texts and no error", texts, err)
+	}
+	if attrs, err := l.Attributes("href"); err != nil || len(attrs) != 0 {
+		t.Errorf("l.Attributes() = %q, %v, want no values and no error", attrs, err)
+	}
+	if visible, err := l.Visible(); err != nil || len(visible) != 0 {
+		t.Errorf("l.Visible() = %v, %v, want no elements and no error", visible, err)
+	}
+	first, err := l.First(func(WebElement) (bool, error) { return true, nil })
+	if err != nil || first != nil {
+		t.Errorf("l.First() = %v, %v, want nil and no error", first, err)
+	}
+	if err := l.Each(func(int, WebElement) error { return errors.New("called") }); err != nil {
+		t.Errorf("l.Each() returned error: %v", err)
+	}
+}
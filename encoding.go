@@ -0,0 +1,112 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/LoveOyy/selenium/log"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// PageEncoding returns the name of the character encoding of the current
+// page, as determined by the browser, e.g. "windows-1251".
+func (wd *remoteWD) PageEncoding() (string, error) {
+	v, err := wd.ExecuteScript("return document.characterSet;", nil)
+	if err != nil {
+		return "", err
+	}
+	charset, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("document.characterSet is %v, not a string", v)
+	}
+	return charset, nil
+}
+
+// PageSourceRaw returns the bytes of the current document as received by the
+// browser, and the name of their character encoding.
+func (wd *remoteWD) PageSourceRaw() ([]byte, string, error) {
+	if !wd.Supports(FeatureCDP) || !wd.performanceLogEnabled() {
+		return nil, "", fmt.Errorf("reading the raw page source: %w", ErrUnsupported)
+	}
+	url, err := wd.CurrentURL()
+	if err != nil {
+		return nil, "", err
+	}
+	requestID, err := wd.documentRequestID(url)
+	if err != nil {
+		return nil, "", err
+	}
+	charset, err := wd.PageEncoding()
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := wd.executeCDP("Network.getResponseBody", map[string]interface{}{
+		"requestId": requestID,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("getting the body of %s: %v", url, err)
+	}
+	return decodeResponseBody(data, charset)
+}
+
+// documentRequestID returns the ID of the DevTools Network request that
+// fetched the document at url, from the performance log. Reading the log
+// consumes its entries.
+func (wd *remoteWD) documentRequestID(url string) (string, error) {
+	messages, err := wd.Log(log.Performance)
+	if err != nil {
+		return "", err
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		entry := new(struct {
+			Message struct {
+				Method string
+				Params struct {
+					RequestID string `json:"requestId"`
+					Type      string
+					Response  struct {
+						URL string
+					}
+				}
+			}
+		})
+		if err := json.Unmarshal([]byte(messages[i].Message), entry); err != nil {
+			continue
+		}
+		m := entry.Message
+		if m.Method == "Network.responseReceived" && m.Params.Type == "Document" && m.Params.Response.URL == url {
+			return m.Params.RequestID, nil
+		}
+	}
+	return "", fmt.Errorf("no response for %s in the performance log; it may have been read already", url)
+}
+
+// decodeResponseBody returns the bytes of the reply to Network.getResponseBody.
+// DevTools sends text bodies decoded, so they are encoded back with charset.
+func decodeResponseBody(data []byte, charset string) ([]byte, string, error) {
+	body := new(struct {
+		Body          string
+		Base64Encoded bool
+	})
+	if err := json.Unmarshal(data, body); err != nil {
+		return nil, "", err
+	}
+	if body.Base64Encoded {
+		b, err := base64.StdEncoding.DecodeString(body.Body)
+		return b, charset, err
+	}
+	if strings.EqualFold(charset, "utf-8") {
+		return []byte(body.Body), charset, nil
+	}
+	enc, err := htmlindex.Get(charset)
+	if err != nil {
+		return nil, "", fmt.Errorf("unknown page encoding %q: %v", charset, err)
+	}
+	b, err := enc.NewEncoder().Bytes([]byte(body.Body))
+	if err != nil {
+		return nil, "", fmt.Errorf("the page source cannot be encoded back to %s: %v", charset, err)
+	}
+	return b, charset, nil
+}
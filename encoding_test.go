@@ -0,0 +1,104 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// Pages served in legacy encodings.
+var (
+	windows1251Page = []byte("<html><head><meta charset=\"windows-1251\"><title>\xcf\xf0\xe8\xe2\xe5\xf2</title></head><body><p id=\"greeting\">\xcf\xf0\xe8\xe2\xe5\xf2, \xec\xe8\xf0</p></body></html>")
+	shiftJISPage    = []byte("<html><head><meta charset=\"shift_jis\"><title>\x88\xa5\x8eA</title></head><body><p id=\"greeting\">\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x90\xa2\x8aE</p></body></html>")
+)
+
+func TestDecodeResponseBody(t *testing.T) {
+	for _, test := range []struct {
+		charset string
+		text    string
+		want    []byte
+	}{
+		{
+			charset: "windows-1251",
+			text:    `<html><head><meta charset="windows-1251"><title>Привет</title></head><body><p id="greeting">Привет, мир</p></body></html>`,
+			want:    windows1251Page,
+		},
+		{
+			charset: "Shift_JIS",
+			text:    `<html><head><meta charset="shift_jis"><title>挨拶</title></head><body><p id="greeting">こんにちは世界</p></body></html>`,
+			want:    shiftJISPage,
+		},
+		{
+			charset: "UTF-8",
+			text:    "<p>Grüße</p>",
+			want:    []byte("<p>Grüße</p>"),
+		},
+	} {
+		data, err := json.Marshal(map[string]interface{}{"body": test.text, "base64Encoded": false})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, charset, err := decodeResponseBody(data, test.charset)
+		if err != nil {
+			t.Errorf("decodeResponseBody(_, %q) returned error: %v", test.charset, err)
+			continue
+		}
+		if !bytes.Equal(got, test.want) || charset != test.charset {
+			t.Errorf("decodeResponseBody(_, %q) = %q, %q, want %q, %q", test.charset, got, charset, test.want, test.charset)
+		}
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{"body": shiftJISPage, "base64Encoded": true})
+	if got, _, err := decodeResponseBody(data, "Shift_JIS"); err != nil || !bytes.Equal(got, shiftJISPage) {
+		t.Errorf("decodeResponseBody() of a base64 body = %q, %v, want %q", got, err, shiftJISPage)
+	}
+}
+
+func TestPageSourceRaw(t *testing.T) {
+	const url = "http://intranet.example/report"
+	d := newFakeDriver(t, map[string]interface{}{"se:cdp": "ws://localhost:9222"})
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, url
+	})
+	d.handle("POST", "/log", func([]byte) (int, interface{}) {
+		return http.StatusOK, []map[string]interface{}{
+			{"timestamp": 1, "level": "INFO", "message": `{"message":{"method":"Network.responseReceived","params":{"requestId":"42.1","type":"Document","response":{"url":"` + url + `"}}}}`},
+		}
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, "windows-1251"
+	})
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params map[string]string
+		}
+		if err := json.Unmarshal(body, &params); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		if params.Cmd != "Network.getResponseBody" || params.Params["requestId"] != "42.1" {
+			t.Errorf("sent CDP command %s, want Network.getResponseBody for request 42.1", body)
+		}
+		return http.StatusOK, map[string]interface{}{
+			"body":          `<html><head><meta charset="windows-1251"><title>Привет</title></head><body><p id="greeting">Привет, мир</p></body></html>`,
+			"base64Encoded": false,
+		}
+	})
+	caps := Capabilities{}
+	caps.SetLogLevel(log.Performance, log.All)
+	wd, err := NewRemote(caps, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+
+	got, charset, err := wd.PageSourceRaw()
+	if err != nil {
+		t.Fatalf("wd.PageSourceRaw() returned error: %v", err)
+	}
+	if !bytes.Equal(got, windows1251Page) || charset != "windows-1251" {
+		t.Errorf("wd.PageSourceRaw() = %q, %q, want %q, %q", got, charset, windows1251Page, "windows-1251")
+	}
+}
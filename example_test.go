@@ -1,12 +1,14 @@
 package selenium_test
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/LoveOyy/selenium"
+	"github.com/LoveOyy/selenium/chrome"
 )
 
 // This example shows how to navigate to a http://play.golang.org page, input a
@@ -126,7 +128,7 @@ func Example() {
 	// selenium.MousePointer is used to identify the type of the pointer.
 	// The stored action chain will move the pointer and click on the code
 	// editor text box on the page.
-	selenium.StorePointerActions("mouse1",
+	wd.StorePointerActions("mouse1",
 		selenium.MousePointer,
 		// using selenium.FromViewport as the move origin
 		// which calculates the offset from 0,0.
@@ -143,7 +145,7 @@ func Example() {
 	// "keyboard1" is used as a unique virtual device identifier
 	// for this and future actions.
 	// The stored action chain will send keyboard inputs to the browser.
-	selenium.StoreKeyActions("keyboard1",
+	wd.StoreKeyActions("keyboard1",
 		selenium.KeyDownAction(selenium.ControlKey),
 		selenium.KeyPauseAction(50),
 		selenium.KeyDownAction("a"),
@@ -171,3 +173,52 @@ func Example() {
 	}
 
 }
+
+// This example shows how to take screenshots that are identical from one run
+// to the next on the same machine, for visual regression tests. Chrome is
+// started with the arguments that make its rendering deterministic, and the
+// page's animations are turned off before the screenshot. The window is sized
+// to the whole page, so that the screenshot covers all of it.
+func Example_screenshotStability() {
+	const chromeDriverPath = "vendor/chromedriver"
+	service, err := selenium.NewChromeDriverService(chromeDriverPath, 9515)
+	if err != nil {
+		panic(err)
+	}
+	defer service.Stop()
+
+	caps := selenium.Capabilities{"browserName": "chrome"}
+	chromeCaps := chrome.Capabilities{Args: []string{"--headless"}}
+	chromeCaps.ForScreenshotStability()
+	caps.AddChrome(chromeCaps)
+
+	screenshot := func() []byte {
+		wd, err := selenium.NewRemote(caps, "http://localhost:9515")
+		if err != nil {
+			panic(err)
+		}
+		defer wd.Quit()
+
+		if err := wd.DisableAnimations(); err != nil {
+			panic(err)
+		}
+		if err := wd.Get("https://golang.org"); err != nil {
+			panic(err)
+		}
+		height, err := wd.ExecuteScript("return document.documentElement.scrollHeight;", nil)
+		if err != nil {
+			panic(err)
+		}
+		if err := wd.ResizeWindow("", 1280, int(height.(float64))); err != nil {
+			panic(err)
+		}
+		img, err := wd.Screenshot()
+		if err != nil {
+			panic(err)
+		}
+		return img
+	}
+
+	first, second := screenshot(), screenshot()
+	fmt.Println(bytes.Equal(first, second))
+}
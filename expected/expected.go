@@ -0,0 +1,144 @@
+// Package expected provides common conditions to wait for with
+// selenium.WebDriver.Wait and its variants:
+//
+//	err := wd.Wait(expected.ElementIsClickable(selenium.ByCSS("#submit")))
+//
+// Conditions compose with And, Or and Not:
+//
+//	err := wd.WaitWithTimeout(expected.And(
+//		expected.URLMatches(regexp.MustCompile(`/orders/\d+$`)),
+//		expected.TextToBePresent(selenium.ByCSS("h1"), "Order"),
+//	), 10*time.Second)
+//
+// The errors that may go away as the page changes, such as an element not
+// being found yet or having been replaced, count as the condition not being
+// met; other errors, such as an invalid selector or a lost session, end the
+// wait.
+package expected
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/LoveOyy/selenium"
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// isRetryable reports whether err may go away if the page changes, in which
+// case it counts as the condition not being met yet.
+func isRetryable(err error) bool {
+	return errors.Is(err, werror.NoSuchElement) || errors.Is(err, werror.StaleElementReference)
+}
+
+// element returns a condition on the first element matched by l.
+func element(l selenium.Locator, f func(selenium.WebElement) (bool, error)) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		elem, err := wd.FindElement(l.By, l.Value)
+		if err == nil {
+			var ok bool
+			if ok, err = f(elem); err == nil {
+				return ok, nil
+			}
+		}
+		if isRetryable(err) {
+			return false, nil
+		}
+		return false, err
+	}
+}
+
+// ElementIsVisible is met when the first element matched by l is displayed.
+func ElementIsVisible(l selenium.Locator) selenium.Condition {
+	return element(l, func(e selenium.WebElement) (bool, error) {
+		return e.IsDisplayed()
+	})
+}
+
+// ElementIsClickable is met when the first element matched by l is displayed
+// and enabled. It does not check that another element covers it; see
+// selenium.WebElement.EnsureInteractable.
+func ElementIsClickable(l selenium.Locator) selenium.Condition {
+	return element(l, func(e selenium.WebElement) (bool, error) {
+		if ok, err := e.IsDisplayed(); !ok || err != nil {
+			return false, err
+		}
+		return e.IsEnabled()
+	})
+}
+
+// TextToBePresent is met when the visible text of the first element matched
+// by l contains text.
+func TextToBePresent(l selenium.Locator, text string) selenium.Condition {
+	return element(l, func(e selenium.WebElement) (bool, error) {
+		got, err := e.Text()
+		return err == nil && strings.Contains(got, text), err
+	})
+}
+
+// StalenessOf is met when elem is no longer attached to the page, e.g. after
+// the page it was found in is left. A selenium.LazyElement is looked up again
+// rather than going stale, so it is only met once no element matches its
+// locator.
+func StalenessOf(elem selenium.WebElement) selenium.Condition {
+	return func(selenium.WebDriver) (bool, error) {
+		_, err := elem.TagName()
+		if err == nil {
+			return false, nil
+		}
+		if isRetryable(err) {
+			return true, nil
+		}
+		return false, err
+	}
+}
+
+// TitleContains is met when the title of the page contains substr.
+func TitleContains(substr string) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		title, err := wd.Title()
+		return err == nil && strings.Contains(title, substr), err
+	}
+}
+
+// URLMatches is met when the URL of the page matches re.
+func URLMatches(re *regexp.Regexp) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		url, err := wd.CurrentURL()
+		return err == nil && re.MatchString(url), err
+	}
+}
+
+// And is met when all the conditions are met. They are evaluated in order
+// until one is not met.
+func And(conditions ...selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		for _, c := range conditions {
+			if ok, err := c(wd); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	}
+}
+
+// Or is met when one of the conditions is met. They are evaluated in order
+// until one is met.
+func Or(conditions ...selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		for _, c := range conditions {
+			if ok, err := c(wd); ok || err != nil {
+				return err == nil, err
+			}
+		}
+		return false, nil
+	}
+}
+
+// Not is met when c is not met. The errors of c still end the wait.
+func Not(c selenium.Condition) selenium.Condition {
+	return func(wd selenium.WebDriver) (bool, error) {
+		ok, err := c(wd)
+		return !ok && err == nil, err
+	}
+}
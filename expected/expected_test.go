@@ -0,0 +1,132 @@
+package expected
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// fakeWD serves FindElement from elems, keyed by CSS selector, and the
+// title and URL of the page. The embedded interface is nil, so calling any
+// other method panics.
+type fakeWD struct {
+	selenium.WebDriver
+	elems      map[string]*fakeWE
+	title, url string
+	err        error
+}
+
+func (wd *fakeWD) FindElement(by, value string) (selenium.WebElement, error) {
+	if wd.err != nil {
+		return nil, wd.err
+	}
+	e, ok := wd.elems[value]
+	if !ok {
+		return nil, &selenium.Error{Err: "no such element"}
+	}
+	return e, nil
+}
+
+func (wd *fakeWD) Title() (string, error) { return wd.title, wd.err }
+
+func (wd *fakeWD) CurrentURL() (string, error) { return wd.url, wd.err }
+
+type fakeWE struct {
+	selenium.WebElement
+	text              string
+	displayed, enable bool
+	stale             bool
+}
+
+func (we *fakeWE) check() error {
+	if we.stale {
+		return &selenium.Error{Err: "stale element reference"}
+	}
+	return nil
+}
+
+func (we *fakeWE) Text() (string, error)      { return we.text, we.check() }
+func (we *fakeWE) TagName() (string, error)   { return "div", we.check() }
+func (we *fakeWE) IsDisplayed() (bool, error) { return we.displayed, we.check() }
+func (we *fakeWE) IsEnabled() (bool, error)   { return we.enable, we.check() }
+
+func TestConditions(t *testing.T) {
+	hidden := &fakeWE{text: "Hidden"}
+	disabled := &fakeWE{text: "Disabled", displayed: true}
+	button := &fakeWE{text: "Submit order", displayed: true, enable: true}
+	stale := &fakeWE{stale: true}
+	wd := &fakeWD{
+		elems: map[string]*fakeWE{
+			"#hidden":   hidden,
+			"#disabled": disabled,
+			"#button":   button,
+			"#stale":    stale,
+		},
+		title: "Orders - Shop",
+		url:   "https://example.com/orders/42",
+	}
+	yes := func(selenium.WebDriver) (bool, error) { return true, nil }
+	no := func(selenium.WebDriver) (bool, error) { return false, nil }
+
+	for _, tc := range []struct {
+		desc string
+		c    selenium.Condition
+		want bool
+	}{
+		{"ElementIsVisible", ElementIsVisible(selenium.ByCSS("#button")), true},
+		{"ElementIsVisibleHidden", ElementIsVisible(selenium.ByCSS("#hidden")), false},
+		{"ElementIsVisibleMissing", ElementIsVisible(selenium.ByCSS("#missing")), false},
+		{"ElementIsVisibleStale", ElementIsVisible(selenium.ByCSS("#stale")), false},
+		{"ElementIsClickable", ElementIsClickable(selenium.ByCSS("#button")), true},
+		{"ElementIsClickableDisabled", ElementIsClickable(selenium.ByCSS("#disabled")), false},
+		{"ElementIsClickableHidden", ElementIsClickable(selenium.ByCSS("#hidden")), false},
+		{"TextToBePresent", TextToBePresent(selenium.ByCSS("#button"), "order"), true},
+		{"TextToBePresentOther", TextToBePresent(selenium.ByCSS("#button"), "Cancel"), false},
+		{"StalenessOf", StalenessOf(stale), true},
+		{"StalenessOfAttached", StalenessOf(button), false},
+		{"TitleContains", TitleContains("Orders"), true},
+		{"TitleContainsOther", TitleContains("Cart"), false},
+		{"URLMatches", URLMatches(regexp.MustCompile(`/orders/\d+$`)), true},
+		{"URLMatchesOther", URLMatches(regexp.MustCompile(`/cart$`)), false},
+		{"And", And(yes, yes), true},
+		{"AndNotMet", And(yes, no), false},
+		{"Or", Or(no, yes), true},
+		{"OrNotMet", Or(no, no), false},
+		{"Not", Not(no), true},
+		{"NotMet", Not(yes), false},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := tc.c(wd)
+			if err != nil {
+				t.Fatalf("the condition returned error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("the condition returned %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	sessionErr := &selenium.Error{Err: "invalid session id"}
+	wd := &fakeWD{err: sessionErr}
+	for _, tc := range []struct {
+		desc string
+		c    selenium.Condition
+	}{
+		{"ElementIsVisible", ElementIsVisible(selenium.ByCSS("#button"))},
+		{"TitleContains", TitleContains("Orders")},
+		{"URLMatches", URLMatches(regexp.MustCompile(`.`))},
+		{"And", And(TitleContains(""))},
+		{"Or", Or(TitleContains(""))},
+		{"Not", Not(TitleContains("Orders"))},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if ok, err := tc.c(wd); ok || !errors.Is(err, sessionErr) {
+				t.Errorf("the condition returned %t, %v, want false and the error of the driver", ok, err)
+			}
+		})
+	}
+}
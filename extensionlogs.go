@@ -0,0 +1,241 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/log"
+	"golang.org/x/net/websocket"
+)
+
+// ErrExtensionNotRunning is returned, possibly wrapped, by ExtensionLogs and
+// StreamExtensionLogs when the extension has no running service worker or
+// background page, e.g. because Chrome stopped its idle service worker.
+var ErrExtensionNotRunning = errors.New("extension not running")
+
+// extensionDrainQuiet is how long ExtensionLogs waits for more messages after
+// the last one before returning.
+const extensionDrainQuiet = 200 * time.Millisecond
+
+// devtoolsTimeout bounds the requests to the DevTools HTTP endpoint and the
+// connection to a target.
+const devtoolsTimeout = 10 * time.Second
+
+// devtoolsTarget is an entry of the DevTools /json/list endpoint.
+type devtoolsTarget struct {
+	Type                 string `json:"type"`
+	URL                  string `json:"url"`
+	WebSocketDebuggerURL string `json:"webSocketDebuggerUrl"`
+}
+
+// debuggerAddress returns the address of the DevTools endpoint of the browser,
+// as reported by ChromeDriver in the capabilities of the session.
+func (wd *remoteWD) debuggerAddress() string {
+	info, _ := chrome.SessionInfo(wd.sessionCapabilities)
+	return info.DebuggerAddress
+}
+
+// extensionTarget returns the service worker or background page of the
+// extension, waking a stopped service worker once.
+func (wd *remoteWD) extensionTarget(extensionID string) (*devtoolsTarget, error) {
+	addr := wd.debuggerAddress()
+	if addr == "" {
+		return nil, fmt.Errorf("reading extension logs: %w", ErrUnsupported)
+	}
+	prefix := "chrome-extension://" + extensionID + "/"
+	for attempt := 0; ; attempt++ {
+		targets, err := listDevtoolsTargets(addr)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range targets {
+			if (t.Type == "service_worker" || t.Type == "background_page") && strings.HasPrefix(t.URL, prefix) && t.WebSocketDebuggerURL != "" {
+				return &t, nil
+			}
+		}
+		if attempt > 0 {
+			return nil, fmt.Errorf("extension %s: %w", extensionID, ErrExtensionNotRunning)
+		}
+		// Service workers are stopped when idle, and are not listed then.
+		if _, err := wd.executeCDP("ServiceWorker.startWorker", map[string]interface{}{"scopeURL": prefix}); err != nil {
+			debugLog("error starting the service worker of extension %s: %v", extensionID, err)
+		}
+		time.Sleep(wd.defaults.PollInterval)
+	}
+}
+
+func listDevtoolsTargets(addr string) ([]devtoolsTarget, error) {
+	client := http.Client{Timeout: devtoolsTimeout}
+	resp, err := client.Get("http://" + addr + "/json/list")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing the DevTools targets: %s", resp.Status)
+	}
+	var targets []devtoolsTarget
+	if err := json.NewDecoder(resp.Body).Decode(&targets); err != nil {
+		return nil, fmt.Errorf("listing the DevTools targets: %v", err)
+	}
+	return targets, nil
+}
+
+// openConsole connects to the DevTools target and enables the Runtime domain,
+// which reports the console messages logged so far and then new ones.
+func openConsole(target *devtoolsTarget) (*websocket.Conn, error) {
+	config, err := websocket.NewConfig(target.WebSocketDebuggerURL, "http://localhost/")
+	if err != nil {
+		return nil, err
+	}
+	config.Dialer = &net.Dialer{Timeout: devtoolsTimeout}
+	conn, err := websocket.DialConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %v", target.URL, err)
+	}
+	conn.SetWriteDeadline(time.Now().Add(devtoolsTimeout))
+	if err := websocket.JSON.Send(conn, map[string]interface{}{"id": 1, "method": "Runtime.enable"}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// consoleMessage returns the log message of a DevTools message, if it is a
+// Runtime.consoleAPICalled event.
+func consoleMessage(data []byte) (log.Message, bool) {
+	event := new(struct {
+		Method string
+		Params struct {
+			Type string
+			Args []struct {
+				Type        string
+				Value       json.RawMessage
+				Description string
+			}
+			// Timestamp is in milliseconds since the Epoch.
+			Timestamp float64
+		}
+	})
+	if err := json.Unmarshal(data, event); err != nil || event.Method != "Runtime.consoleAPICalled" {
+		return log.Message{}, false
+	}
+	var parts []string
+	for _, arg := range event.Params.Args {
+		var s string
+		switch {
+		case arg.Type == "string" && json.Unmarshal(arg.Value, &s) == nil:
+			parts = append(parts, s)
+		case len(arg.Value) > 0:
+			parts = append(parts, string(arg.Value))
+		default:
+			parts = append(parts, arg.Description)
+		}
+	}
+	level := log.Info
+	switch event.Params.Type {
+	case "error", "assert":
+		level = log.Severe
+	case "warning":
+		level = log.Warning
+	case "debug":
+		level = log.Debug
+	}
+	return log.Message{
+		Timestamp: time.Unix(0, int64(event.Params.Timestamp*float64(time.Millisecond))),
+		Level:     level,
+		Message:   strings.Join(parts, " "),
+	}, true
+}
+
+// ExtensionLogs returns the console messages of the service worker or
+// background page of an extension.
+func (wd *remoteWD) ExtensionLogs(extensionID string) ([]log.Message, error) {
+	target, err := wd.extensionTarget(extensionID)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := openConsole(target)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var messages []log.Message
+	enabled := false
+	deadline := time.Now().Add(devtoolsTimeout)
+	for {
+		// Wait for the reply to Runtime.enable, which follows the messages
+		// logged so far, and then for a quiet period.
+		if enabled {
+			conn.SetReadDeadline(time.Now().Add(extensionDrainQuiet))
+		} else {
+			conn.SetReadDeadline(deadline)
+		}
+		var data []byte
+		if err := websocket.Message.Receive(conn, &data); err != nil {
+			if enabled {
+				return messages, nil
+			}
+			return nil, fmt.Errorf("reading the console of %s: %v", target.URL, err)
+		}
+		if m, ok := consoleMessage(data); ok {
+			messages = append(messages, m)
+			continue
+		}
+		reply := new(struct {
+			ID    int
+			Error *struct{ Message string }
+		})
+		if json.Unmarshal(data, reply) == nil && reply.ID == 1 {
+			if reply.Error != nil {
+				return nil, fmt.Errorf("enabling the console of %s: %s", target.URL, reply.Error.Message)
+			}
+			enabled = true
+		}
+	}
+}
+
+// StreamExtensionLogs sends the console messages of the service worker or
+// background page of an extension to the returned channel.
+func (wd *remoteWD) StreamExtensionLogs(ctx context.Context, extensionID string) (<-chan log.Message, error) {
+	target, err := wd.extensionTarget(extensionID)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := openConsole(target)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan log.Message, 16)
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+	go func() {
+		defer close(messages)
+		for {
+			var data []byte
+			if err := websocket.Message.Receive(conn, &data); err != nil {
+				return
+			}
+			m, ok := consoleMessage(data)
+			if !ok {
+				continue
+			}
+			select {
+			case messages <- m:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
@@ -0,0 +1,155 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/log"
+	"golang.org/x/net/websocket"
+)
+
+const fakeExtensionID = "abcdefghijklmnopabcdefghijklmnop"
+
+// newFakeDevtools serves the /json/list endpoint of a browser with the given
+// targets, whose webSocketDebuggerUrl is set to a fake extension service
+// worker that logs messages, then sends the reply to Runtime.enable, then
+// logs more. It returns the address of the endpoint.
+func newFakeDevtools(t *testing.T, targets []map[string]string, logged, more []interface{}) string {
+	t.Helper()
+	mux := http.NewServeMux()
+	s := httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	mux.HandleFunc("/json/list", func(w http.ResponseWriter, r *http.Request) {
+		for _, target := range targets {
+			target["webSocketDebuggerUrl"] = "ws://" + r.Host + "/devtools/worker"
+		}
+		json.NewEncoder(w).Encode(targets)
+	})
+	mux.Handle("/devtools/worker", websocket.Handler(func(conn *websocket.Conn) {
+		request := new(struct {
+			ID     int
+			Method string
+		})
+		if err := websocket.JSON.Receive(conn, request); err != nil || request.Method != "Runtime.enable" {
+			t.Errorf("the target received %+v, %v; want Runtime.enable", request, err)
+			return
+		}
+		for _, m := range logged {
+			websocket.JSON.Send(conn, m)
+		}
+		websocket.JSON.Send(conn, map[string]interface{}{"id": request.ID, "result": map[string]interface{}{}})
+		for _, m := range more {
+			websocket.JSON.Send(conn, m)
+		}
+		// Keep the connection open until the client closes it.
+		var data []byte
+		websocket.Message.Receive(conn, &data)
+	}))
+	return strings.TrimPrefix(s.URL, "http://")
+}
+
+func consoleEvent(typ string, timestamp float64, args ...interface{}) interface{} {
+	return map[string]interface{}{
+		"method": "Runtime.consoleAPICalled",
+		"params": map[string]interface{}{
+			"type":      typ,
+			"args":      args,
+			"timestamp": timestamp,
+		},
+	}
+}
+
+func newExtensionRemote(t *testing.T, devtools string) *remoteWD {
+	t.Helper()
+	d := newFakeDriver(t, map[string]interface{}{
+		chrome.CapabilitiesKey: map[string]interface{}{"debuggerAddress": devtools},
+	})
+	return d.newRemote(t)
+}
+
+func TestExtensionLogs(t *testing.T) {
+	devtools := newFakeDevtools(t, []map[string]string{
+		{"type": "page", "url": "https://example.com/"},
+		{"type": "service_worker", "url": "chrome-extension://" + fakeExtensionID + "/background.js"},
+	}, []interface{}{
+		consoleEvent("log", 1500, map[string]interface{}{"type": "string", "value": "started"}, map[string]interface{}{"type": "number", "value": 2}),
+		consoleEvent("error", 2000, map[string]interface{}{"type": "object", "description": "Error: boom"}),
+	}, []interface{}{
+		consoleEvent("warning", 2500, map[string]interface{}{"type": "string", "value": "late"}),
+	})
+	wd := newExtensionRemote(t, devtools)
+
+	got, err := wd.ExtensionLogs(fakeExtensionID)
+	if err != nil {
+		t.Fatalf("wd.ExtensionLogs() returned error: %v", err)
+	}
+	want := []log.Message{
+		{Timestamp: time.Unix(1, 500*int64(time.Millisecond)), Level: log.Info, Message: "started 2"},
+		{Timestamp: time.Unix(2, 0), Level: log.Severe, Message: "Error: boom"},
+		{Timestamp: time.Unix(2, 500*int64(time.Millisecond)), Level: log.Warning, Message: "late"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("wd.ExtensionLogs() returned %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if !got[i].Timestamp.Equal(want[i].Timestamp) || got[i].Level != want[i].Level || got[i].Message != want[i].Message {
+			t.Errorf("message %d is %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamExtensionLogs(t *testing.T) {
+	devtools := newFakeDevtools(t, []map[string]string{
+		{"type": "background_page", "url": "chrome-extension://" + fakeExtensionID + "/background.html"},
+	}, []interface{}{
+		consoleEvent("log", 1000, map[string]interface{}{"type": "string", "value": "first"}),
+	}, []interface{}{
+		consoleEvent("debug", 2000, map[string]interface{}{"type": "string", "value": "second"}),
+	})
+	wd := newExtensionRemote(t, devtools)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	messages, err := wd.StreamExtensionLogs(ctx, fakeExtensionID)
+	if err != nil {
+		t.Fatalf("wd.StreamExtensionLogs() returned error: %v", err)
+	}
+	for _, want := range []string{"first", "second"} {
+		select {
+		case m := <-messages:
+			if m.Message != want {
+				t.Errorf("got message %q, want %q", m.Message, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no message %q after 5s", want)
+		}
+	}
+	cancel()
+	for range messages {
+	}
+}
+
+func TestExtensionLogsNotRunning(t *testing.T) {
+	devtools := newFakeDevtools(t, []map[string]string{
+		{"type": "page", "url": "chrome-extension://" + fakeExtensionID + "/popup.html"},
+	}, nil, nil)
+	wd := newExtensionRemote(t, devtools)
+
+	if _, err := wd.ExtensionLogs(fakeExtensionID); !errors.Is(err, ErrExtensionNotRunning) {
+		t.Errorf("wd.ExtensionLogs() returned error %v, want ErrExtensionNotRunning", err)
+	}
+}
+
+func TestExtensionLogsUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.ExtensionLogs(fakeExtensionID); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.ExtensionLogs() returned error %v, want ErrUnsupported", err)
+	}
+}
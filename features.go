@@ -0,0 +1,148 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ErrUnsupported is returned, possibly wrapped, by methods that require a
+// feature that the remote end does not support.
+var ErrUnsupported = errors.New("not supported by the remote end")
+
+// isUnknownCommand reports whether err is the reply of a remote end that does
+// not implement a command.
+func isUnknownCommand(err error) bool {
+	return errors.Is(err, werror.UnknownCommand) || errors.Is(err, werror.UnknownMethod)
+}
+
+// Feature is an optional capability of a remote end that callers can test
+// for with WebDriver.Supports before using it.
+type Feature string
+
+// Optional features of remote ends.
+const (
+	// FeatureActions is support for the W3C Actions API, used by
+	// PerformActions and ReleaseActions.
+	FeatureActions Feature = "actions"
+	// FeatureElementScreenshot is support for WebElement.Screenshot.
+	FeatureElementScreenshot Feature = "element screenshot"
+	// FeatureLogs is support for the non-standard log endpoints used by
+	// WebDriver.Log.
+	FeatureLogs Feature = "logs"
+	// FeaturePrint is support for printing the page to PDF.
+	FeaturePrint Feature = "print"
+	// FeatureCDP is support for sending Chrome DevTools Protocol commands.
+	FeatureCDP Feature = "cdp"
+	// FeatureBiDi is support for the WebDriver BiDi protocol.
+	FeatureBiDi Feature = "bidi"
+	// FeatureLegacyWebStorage is support for the Selenium endpoints for
+	// localStorage and sessionStorage, which predate the W3C specification.
+	// Without it, Storage is implemented with scripts.
+	FeatureLegacyWebStorage Feature = "legacy web storage"
+	// FeatureVirtualAuthenticator is support for the WebAuthn virtual
+	// authenticator extension commands.
+	FeatureVirtualAuthenticator Feature = "virtual authenticator"
+)
+
+// probeID is an identifier that no remote end will have issued. Probes use it
+// so that a supported command fails with a "no such ..." or "invalid
+// argument" error instead of having side effects.
+const probeID = "selenium-feature-probe"
+
+// Supports reports whether the remote end supports the feature.
+func (wd *remoteWD) Supports(feature Feature) bool {
+	wd.supportMu.Lock()
+	supported, ok := wd.support[feature]
+	wd.supportMu.Unlock()
+	if ok {
+		return supported
+	}
+
+	supported, known := wd.detectSupport(feature)
+	if known {
+		wd.SetSupport(feature, supported)
+	}
+	return supported
+}
+
+// SetSupport overrides whether the remote end supports the feature.
+func (wd *remoteWD) SetSupport(feature Feature, supported bool) {
+	wd.supportMu.Lock()
+	defer wd.supportMu.Unlock()
+	if wd.support == nil {
+		wd.support = make(map[Feature]bool)
+	}
+	wd.support[feature] = supported
+}
+
+// detectSupport determines whether the feature is supported, first from the
+// capabilities negotiated for the session and then by probing the remote end.
+// known is false if this could not be determined, e.g. because of a network
+// error, in which case the result should not be cached.
+func (wd *remoteWD) detectSupport(feature Feature) (supported, known bool) {
+	caps := wd.sessionCapabilities
+	switch feature {
+	case FeatureActions:
+		return wd.w3cCompatible, true
+	case FeatureBiDi:
+		u, _ := caps[BiDiCapability].(string)
+		return strings.HasPrefix(u, "ws"), true
+	case FeatureCDP:
+		if _, ok := caps["se:cdp"]; ok {
+			return true, true
+		}
+		if _, ok := caps[chrome.CapabilitiesKey]; !ok {
+			return false, true
+		}
+		return wd.probe("POST", "/session/%s/goog/cdp/execute", map[string]interface{}{
+			"cmd":    "Browser.getVersion",
+			"params": map[string]interface{}{},
+		})
+	case FeatureElementScreenshot:
+		return wd.probe("GET", "/session/%s/element/"+probeID+"/screenshot", nil)
+	case FeatureLogs:
+		return wd.probe("GET", "/session/%s/log/types", nil)
+	case FeaturePrint:
+		// A scale outside of [0.1, 2] is rejected before anything is printed.
+		return wd.probe("POST", "/session/%s/print", map[string]interface{}{"scale": -1})
+	case FeatureLegacyWebStorage:
+		if wd.w3cCompatible {
+			return false, true
+		}
+		return wd.probe("GET", "/session/%s/local_storage/size", nil)
+	case FeatureVirtualAuthenticator:
+		return wd.probe("DELETE", "/session/%s/webauthn/authenticator/"+probeID, nil)
+	}
+	return false, true
+}
+
+// probe sends a command that is expected to fail and infers from the error
+// whether the remote end implements it.
+func (wd *remoteWD) probe(method, urlTemplate string, params interface{}) (supported, known bool) {
+	var data []byte
+	if params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			return false, false
+		}
+	}
+	_, err := wd.execute(method, wd.requestURL(urlTemplate, wd.id), data)
+	if err == nil {
+		return true, true
+	}
+	e, ok := err.(*Error)
+	if !ok {
+		// Remote ends that do not route the command at all often reply with a
+		// plain 404 page.
+		return false, strings.Contains(err.Error(), "404")
+	}
+	switch e.Err {
+	case "unknown command", "unknown method", "unsupported operation":
+		return false, true
+	}
+	return true, true
+}
@@ -0,0 +1,63 @@
+package selenium
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSupportsFromCapabilities(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{
+		"browserName":  "firefox",
+		"webSocketUrl": "ws://127.0.0.1:9222/session/fake",
+	})
+	wd := d.newRemote(t)
+
+	if !wd.Supports(FeatureBiDi) {
+		t.Errorf("wd.Supports(FeatureBiDi) = false with a webSocketUrl capability, want true")
+	}
+	if !wd.Supports(FeatureActions) {
+		t.Errorf("wd.Supports(FeatureActions) = false for a W3C session, want true")
+	}
+	if wd.Supports(FeatureCDP) {
+		t.Errorf("wd.Supports(FeatureCDP) = true without Chrome capabilities, want false")
+	}
+	if n := len(d.requests); n != 1 {
+		t.Errorf("got %d requests, want only the New Session request: %v", n, d.requests)
+	}
+}
+
+func TestSupportsProbesOnce(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "chrome"})
+	d.handle("POST", "/print", func([]byte) (int, interface{}) {
+		return http.StatusBadRequest, map[string]string{"error": "invalid argument", "message": "scale out of range"}
+	})
+	wd := d.newRemote(t)
+
+	for i := 0; i < 2; i++ {
+		if !wd.Supports(FeaturePrint) {
+			t.Errorf("wd.Supports(FeaturePrint) = false, want true")
+		}
+		if wd.Supports(FeatureVirtualAuthenticator) {
+			t.Errorf("wd.Supports(FeatureVirtualAuthenticator) = true for an unknown command, want false")
+		}
+	}
+	if n := d.count("POST", "/print"); n != 1 {
+		t.Errorf("print was probed %d times, want 1", n)
+	}
+	if n := d.count("DELETE", "/webauthn/authenticator/"+probeID); n != 1 {
+		t.Errorf("the virtual authenticator was probed %d times, want 1", n)
+	}
+}
+
+func TestSetSupportOverridesDetection(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "safari"})
+	wd := d.newRemote(t)
+
+	wd.SetSupport(FeatureLogs, true)
+	if !wd.Supports(FeatureLogs) {
+		t.Errorf("wd.Supports(FeatureLogs) = false after wd.SetSupport(FeatureLogs, true), want true")
+	}
+	if n := d.count("GET", "/log/types"); n != 0 {
+		t.Errorf("logs were probed %d times after wd.SetSupport(), want 0", n)
+	}
+}
@@ -5,7 +5,7 @@ import (
 	"bytes"
 	"encoding/base64"
 
-	"github.com/LoveOyy/selenium/internal/zip"
+	"github.com/LoveOyy/selenium/archive"
 )
 
 // CapabilitiesKey is the name of the Firefox-specific key in the WebDriver
@@ -32,6 +32,23 @@ type Capabilities struct {
 	// Map of preference name to preference value, which can be a string, a
 	// boolean or an integer.
 	Prefs map[string]interface{} `json:"prefs,omitempty"`
+	// Env are the environment variables to set for the Firefox process, e.g.
+	// {"MOZ_LOG": "nsHttp:5"}.
+	Env map[string]string `json:"env,omitempty"`
+
+	// AndroidPackage is the package name of the Firefox build to run on an
+	// Android device, e.g. "org.mozilla.firefox" or "org.mozilla.geckoview_example".
+	// The other Android fields only apply when it is set.
+	AndroidPackage string `json:"androidPackage,omitempty"`
+	// AndroidActivity is the fully qualified class name of the activity to
+	// launch, if the package has several.
+	AndroidActivity string `json:"androidActivity,omitempty"`
+	// AndroidDeviceSerial is the serial number of the device to run the
+	// browser on, as listed by "adb devices", if several are connected.
+	AndroidDeviceSerial string `json:"androidDeviceSerial,omitempty"`
+	// AndroidIntentArguments are the arguments of the intent that launches the
+	// activity, in the syntax of "am start", e.g. ["-d", "https://example.com"].
+	AndroidIntentArguments []string `json:"androidIntentArguments,omitempty"`
 }
 
 // SetProfile sets the Profile datum with a Base64-encoded zip file of a
@@ -42,7 +59,7 @@ type Capabilities struct {
 // will be base64-encoded. This will require memory at least 2x the size
 // of the data.
 func (c *Capabilities) SetProfile(basePath string) error {
-	buf, err := zip.New(basePath)
+	buf, err := archive.Dir(basePath, archive.ForFirefoxProfile())
 	if err != nil {
 		return err
 	}
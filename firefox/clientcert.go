@@ -0,0 +1,90 @@
+package firefox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// MissingToolError is returned when a command-line tool needed by a helper is
+// not in the PATH.
+type MissingToolError struct {
+	// Tool is the name of the tool, e.g. "pk12util" or "web-ext".
+	Tool string
+}
+
+func (e *MissingToolError) Error() string {
+	if e.Tool == webExt {
+		return fmt.Sprintf("%s is not in the PATH; install it with npm install --global web-ext", e.Tool)
+	}
+	return fmt.Sprintf("%s is not in the PATH; install the NSS tools, e.g. the libnss3-tools package on Debian, nss-tools on Fedora or nss on Homebrew", e.Tool)
+}
+
+// lookTool returns the path of a command-line tool.
+func lookTool(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", &MissingToolError{Tool: name}
+	}
+	return path, nil
+}
+
+// runTool runs a command-line tool, returning its output in the error if it
+// fails.
+func runTool(path string, args ...string) error {
+	out, err := exec.Command(path, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %v: %v: %s", filepath.Base(path), args, err, out)
+	}
+	return nil
+}
+
+// ImportPKCS12 imports the client certificate and key of the PKCS#12 file
+// p12File, protected by password, into the certificate database of the
+// Firefox profile in profileDir, creating the database with an empty
+// password if needed. It runs pk12util, and certutil for a new database,
+// from the NSS tools, and returns a *MissingToolError if they are not
+// installed.
+func ImportPKCS12(profileDir, p12File, password string) error {
+	pk12util, err := lookTool("pk12util")
+	if err != nil {
+		return err
+	}
+	db := "sql:" + profileDir
+	if _, err := os.Stat(filepath.Join(profileDir, "cert9.db")); os.IsNotExist(err) {
+		certutil, err := lookTool("certutil")
+		if err != nil {
+			return err
+		}
+		if err := runTool(certutil, "-N", "-d", db, "--empty-password"); err != nil {
+			return err
+		}
+	}
+	return runTool(pk12util, "-i", p12File, "-d", db, "-W", password, "-K", "")
+}
+
+// AddClientCertificate makes Firefox present the client certificate of the
+// PKCS#12 file p12File, protected by password, to the sites that ask for
+// one, without a prompt. It imports the certificate with ImportPKCS12 into a
+// new temporary profile directory, whose path is returned for the caller to
+// remove once the browser has exited, sets the security.default_personal_cert
+// preference to select the certificate automatically, and replaces Profile
+// with the directory.
+func (c *Capabilities) AddClientCertificate(p12File, password string) (string, error) {
+	dir, err := ioutil.TempDir("", "firefox-profile-")
+	if err != nil {
+		return "", err
+	}
+	if err := ImportPKCS12(dir, p12File, password); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	if err := c.SetProfile(dir); err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+	c.setPref("security.default_personal_cert", "Select Automatically")
+	return dir, nil
+}
@@ -0,0 +1,158 @@
+package firefox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeNSSTools installs fake pk12util and certutil commands in a new
+// directory, set as the PATH, which log their arguments to the returned file.
+// certutil creates the certificate database.
+func fakeNSSTools(t *testing.T, tools ...string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake NSS tools are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "firefox-clientcert-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	calls := filepath.Join(dir, "calls")
+	scripts := map[string]string{
+		"pk12util": `echo pk12util "$@" >> ` + calls,
+		"certutil": `echo certutil "$@" >> ` + calls + `; : > "${3#sql:}/cert9.db"`,
+	}
+	for _, tool := range tools {
+		if err := ioutil.WriteFile(filepath.Join(dir, tool), []byte("#!/bin/sh\n"+scripts[tool]+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := os.Getenv("PATH")
+	t.Cleanup(func() { os.Setenv("PATH", path) })
+	os.Setenv("PATH", dir)
+	return calls
+}
+
+func TestMissingToolError(t *testing.T) {
+	for _, test := range []struct {
+		tool string
+		want string
+	}{
+		{"web-ext", "npm install --global web-ext"},
+		{"pk12util", "NSS tools"},
+		{"certutil", "NSS tools"},
+	} {
+		err := &MissingToolError{Tool: test.tool}
+		if got := err.Error(); !strings.HasPrefix(got, test.tool+" is not in the PATH") || !strings.Contains(got, test.want) {
+			t.Errorf("(&MissingToolError{Tool: %q}).Error() = %q, want it to mention %q", test.tool, got, test.want)
+		}
+	}
+}
+
+func TestImportPKCS12(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		tools       []string
+		existingDB  bool
+		wantCalls   []string
+		wantMissing string
+	}{
+		{
+			desc:  "new database",
+			tools: []string{"pk12util", "certutil"},
+			wantCalls: []string{
+				"certutil -N -d sql:PROFILE --empty-password",
+				"pk12util -i client.p12 -d sql:PROFILE -W secret -K ",
+			},
+		},
+		{
+			desc:       "existing database",
+			tools:      []string{"pk12util"},
+			existingDB: true,
+			wantCalls:  []string{"pk12util -i client.p12 -d sql:PROFILE -W secret -K "},
+		},
+		{
+			desc:        "no pk12util",
+			tools:       []string{"certutil"},
+			wantMissing: "pk12util",
+		},
+		{
+			desc:        "no certutil for a new database",
+			tools:       []string{"pk12util"},
+			wantMissing: "certutil",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			calls := fakeNSSTools(t, test.tools...)
+			profile := filepath.Join(filepath.Dir(calls), "profile")
+			if err := os.Mkdir(profile, 0755); err != nil {
+				t.Fatal(err)
+			}
+			if test.existingDB {
+				if err := ioutil.WriteFile(filepath.Join(profile, "cert9.db"), nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err := ImportPKCS12(profile, "client.p12", "secret")
+			if test.wantMissing != "" {
+				if e, ok := err.(*MissingToolError); !ok || e.Tool != test.wantMissing {
+					t.Fatalf("ImportPKCS12() returned error %v, want a *MissingToolError for %s", err, test.wantMissing)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ImportPKCS12() returned error: %v", err)
+			}
+			out, err := ioutil.ReadFile(calls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := strings.Split(strings.TrimSuffix(strings.Replace(string(out), profile, "PROFILE", -1), "\n"), "\n")
+			if strings.Join(got, "\n") != strings.Join(test.wantCalls, "\n") {
+				t.Errorf("ImportPKCS12() ran %q, want %q", got, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAddClientCertificate(t *testing.T) {
+	calls := fakeNSSTools(t, "pk12util", "certutil")
+	var c Capabilities
+	dir, err := c.AddClientCertificate("client.p12", "secret")
+	if err != nil {
+		t.Fatalf("c.AddClientCertificate() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if _, err := os.Stat(filepath.Join(dir, "cert9.db")); err != nil {
+		t.Errorf("the profile directory has no certificate database: %v", err)
+	}
+	if _, ok := profileFiles(t, &c)["cert9.db"]; !ok {
+		t.Error("c.AddClientCertificate() did not set the profile with the certificate database")
+	}
+	if got := c.Prefs["security.default_personal_cert"]; got != "Select Automatically" {
+		t.Errorf("the security.default_personal_cert preference is %v, want Select Automatically", got)
+	}
+
+	// Without pk12util, the temporary profile is removed.
+	if err := os.Remove(filepath.Join(filepath.Dir(calls), "pk12util")); err != nil {
+		t.Fatal(err)
+	}
+	var failed Capabilities
+	before, _ := filepath.Glob(filepath.Join(os.TempDir(), "firefox-profile-*"))
+	if _, err := failed.AddClientCertificate("client.p12", "secret"); err == nil {
+		t.Fatal("c.AddClientCertificate() without pk12util returned no error")
+	}
+	after, _ := filepath.Glob(filepath.Join(os.TempDir(), "firefox-profile-*"))
+	if len(after) != len(before) {
+		t.Errorf("c.AddClientCertificate() left %d temporary profiles after failing", len(after)-len(before))
+	}
+	if failed.Profile != "" || failed.Prefs != nil {
+		t.Errorf("c.AddClientCertificate() set %+v after failing", failed)
+	}
+}
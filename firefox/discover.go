@@ -0,0 +1,71 @@
+package firefox
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// geckoDriverExecutables are the names of the GeckoDriver binaries.
+var geckoDriverExecutables = []string{"geckodriver", "geckodriver.exe"}
+
+// geckoDriverCaches returns the directories in which Selenium Manager stores
+// the GeckoDriver builds it downloads, as <cache>/<platform>/<version>/.
+func geckoDriverCaches() []string {
+	var dirs []string
+	if dir := os.Getenv("SE_CACHE_PATH"); dir != "" {
+		dirs = append(dirs, filepath.Join(dir, "geckodriver"))
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".cache", "selenium", "geckodriver"))
+	}
+	return dirs
+}
+
+// FindDriver returns the path to a GeckoDriver binary. It looks for
+// geckodriver in the PATH, then in the cache of Selenium Manager. The
+// drivermanager package downloads it otherwise.
+func FindDriver() (string, error) {
+	for _, exe := range geckoDriverExecutables {
+		if path, err := exec.LookPath(exe); err == nil {
+			return path, nil
+		}
+	}
+	for _, cache := range geckoDriverCaches() {
+		for _, exe := range geckoDriverExecutables {
+			matches, _ := filepath.Glob(filepath.Join(cache, "*", "*", exe))
+			var newest, newestVersion string
+			for _, path := range matches {
+				version := filepath.Base(filepath.Dir(path))
+				if newest == "" || newerVersion(version, newestVersion) {
+					newest, newestVersion = path, version
+				}
+			}
+			if newest != "" {
+				return newest, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no GeckoDriver found in the PATH or in %s", strings.Join(geckoDriverCaches(), ", "))
+}
+
+// newerVersion reports whether the dotted version a is newer than b.
+func newerVersion(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
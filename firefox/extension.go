@@ -0,0 +1,214 @@
+package firefox
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/LoveOyy/selenium/archive"
+)
+
+// webExt is the Mozilla command-line tool that signs extensions.
+const webExt = "web-ext"
+
+// Preferences that the extension helpers set.
+const (
+	// autoDisableScopesPref lists the scopes whose sideloaded extensions are
+	// disabled until the user enables them; 0 enables those of the profile.
+	autoDisableScopesPref = "extensions.autoDisableScopes"
+	// signaturesRequiredPref is only honored by the Developer Edition,
+	// Nightly, ESR and unbranded builds of Firefox.
+	signaturesRequiredPref = "xpinstall.signatures.required"
+)
+
+// ExtensionID returns the ID of a Firefox extension from the manifest.json
+// file of its XPI package: browser_specific_settings.gecko.id, or the older
+// applications.gecko.id. Firefox only installs extensions from a profile
+// when they have an ID.
+func ExtensionID(xpi []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(xpi), int64(len(xpi)))
+	if err != nil {
+		return "", fmt.Errorf("reading the extension: %v", err)
+	}
+	for _, f := range zr.File {
+		if f.Name != "manifest.json" {
+			continue
+		}
+		r, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer r.Close()
+		type settings struct {
+			Gecko struct {
+				ID string `json:"id"`
+			} `json:"gecko"`
+		}
+		var manifest struct {
+			BrowserSpecificSettings settings `json:"browser_specific_settings"`
+			Applications            settings `json:"applications"`
+		}
+		if err := json.NewDecoder(r).Decode(&manifest); err != nil {
+			return "", fmt.Errorf("reading the manifest.json of the extension: %v", err)
+		}
+		if id := manifest.BrowserSpecificSettings.Gecko.ID; id != "" {
+			return id, nil
+		}
+		if id := manifest.Applications.Gecko.ID; id != "" {
+			return id, nil
+		}
+		return "", errors.New("the manifest.json of the extension has no browser_specific_settings.gecko.id")
+	}
+	return "", errors.New("the extension has no manifest.json")
+}
+
+// NewExtension creates an unsigned XPI package of the Firefox extension whose
+// files are below basePath, with manifest.json at its root, and returns it
+// with the ID of the extension.
+func NewExtension(basePath string) ([]byte, string, error) {
+	buf, err := archive.Dir(basePath, archive.ForXPI())
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := ExtensionID(buf.Bytes())
+	if err != nil {
+		return nil, "", fmt.Errorf("extension %s: %v", basePath, err)
+	}
+	return buf.Bytes(), id, nil
+}
+
+// SignExtension signs the Firefox extension whose files are below basePath
+// as an unlisted extension of addons.mozilla.org, with the API credentials of
+// an account of the site, and returns the signed XPI package, which every
+// build of Firefox installs. It runs "web-ext sign", and returns a
+// *MissingToolError if it is not installed. Signing takes from seconds to
+// minutes, and a version of an extension can only be signed once: increase
+// the version in manifest.json to sign changed files.
+func SignExtension(basePath, apiKey, apiSecret string) ([]byte, error) {
+	path, err := lookTool(webExt)
+	if err != nil {
+		return nil, err
+	}
+	dir, err := ioutil.TempDir("", "web-ext-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	cmd := exec.Command(path, "sign", "--channel=unlisted", "--source-dir", basePath, "--artifacts-dir", dir)
+	// Pass the credentials in the environment, which does not appear in the
+	// error below.
+	cmd.Env = append(os.Environ(), "WEB_EXT_API_KEY="+apiKey, "WEB_EXT_API_SECRET="+apiSecret)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s sign --source-dir %s: %v: %s", webExt, basePath, err, out)
+	}
+	signed, err := filepath.Glob(filepath.Join(dir, "*.xpi"))
+	if err != nil {
+		return nil, err
+	}
+	if len(signed) != 1 {
+		return nil, fmt.Errorf("%s sign --source-dir %s produced %d packages, want 1", webExt, basePath, len(signed))
+	}
+	return ioutil.ReadFile(signed[0])
+}
+
+// AddExtension installs the Firefox extension of the XPI package at path,
+// which must have an ID (see ExtensionID), in Profile. Release builds of
+// Firefox only run signed extensions: see SignExtension.
+//
+// The extension is added to the profile set with SetProfile, which replaces
+// the profile, so call SetProfile first. The profile is unpacked in memory.
+func (c *Capabilities) AddExtension(path string) error {
+	xpi, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return c.addExtension(xpi)
+}
+
+// AddUnpackedExtension creates an unsigned XPI package of the Firefox
+// extension below basePath with NewExtension and installs it like
+// AddExtension. It also sets the xpinstall.signatures.required preference to
+// false, which only the Developer Edition, Nightly, ESR and unbranded builds
+// of Firefox honor; with release builds, use SignExtension and add the signed
+// package.
+func (c *Capabilities) AddUnpackedExtension(basePath string) error {
+	xpi, _, err := NewExtension(basePath)
+	if err != nil {
+		return err
+	}
+	if err := c.addExtension(xpi); err != nil {
+		return err
+	}
+	c.setPref(signaturesRequiredPref, false)
+	return nil
+}
+
+// addExtension adds the XPI package to the extensions directory of Profile,
+// creating the profile if there is none.
+func (c *Capabilities) addExtension(xpi []byte) error {
+	id, err := ExtensionID(xpi)
+	if err != nil {
+		return err
+	}
+	name := "extensions/" + id + ".xpi"
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	if c.Profile != "" {
+		profile, err := base64.StdEncoding.DecodeString(c.Profile)
+		if err != nil {
+			return fmt.Errorf("decoding the profile: %v", err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(profile), int64(len(profile)))
+		if err != nil {
+			return fmt.Errorf("reading the profile: %v", err)
+		}
+		for _, f := range zr.File {
+			if f.Name == name || strings.HasSuffix(f.Name, "/") {
+				continue
+			}
+			if err := copyZipFile(zw, f); err != nil {
+				return fmt.Errorf("reading the profile: %v", err)
+			}
+		}
+	}
+	w, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(xpi); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+
+	c.Profile = base64.StdEncoding.EncodeToString(out.Bytes())
+	c.setPref(autoDisableScopesPref, 0)
+	return nil
+}
+
+// copyZipFile copies a file of a Zip archive to another.
+func copyZipFile(zw *zip.Writer, f *zip.File) error {
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	header := f.FileHeader
+	w, err := zw.CreateHeader(&header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,351 @@
+package firefox
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// zipFiles returns a Zip archive of files, by name.
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unzipFiles returns the files of a Zip archive, by name.
+func unzipFiles(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+// profileFiles returns the files of the profile of c, by name.
+func profileFiles(t *testing.T, c *Capabilities) map[string]string {
+	t.Helper()
+	profile, err := base64.StdEncoding.DecodeString(c.Profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return unzipFiles(t, profile)
+}
+
+const (
+	testManifest       = `{"manifest_version": 2, "name": "Test", "version": "1.0", "browser_specific_settings": {"gecko": {"id": "test@example.com"}}}`
+	testLegacyManifest = `{"manifest_version": 2, "name": "Test", "version": "1.0", "applications": {"gecko": {"id": "legacy@example.com"}}}`
+)
+
+func TestExtensionID(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		xpi     []byte
+		want    string
+		wantErr string
+	}{
+		{
+			desc: "browser_specific_settings",
+			xpi:  zipFiles(t, map[string]string{"manifest.json": testManifest, "background.js": ""}),
+			want: "test@example.com",
+		},
+		{
+			desc: "applications",
+			xpi:  zipFiles(t, map[string]string{"manifest.json": testLegacyManifest}),
+			want: "legacy@example.com",
+		},
+		{
+			desc: "both",
+			xpi: zipFiles(t, map[string]string{"manifest.json": `{
+				"browser_specific_settings": {"gecko": {"id": "new@example.com"}},
+				"applications": {"gecko": {"id": "old@example.com"}}
+			}`}),
+			want: "new@example.com",
+		},
+		{
+			desc:    "no ID",
+			xpi:     zipFiles(t, map[string]string{"manifest.json": `{"manifest_version": 3, "name": "Test"}`}),
+			wantErr: "no browser_specific_settings.gecko.id",
+		},
+		{
+			desc:    "invalid manifest",
+			xpi:     zipFiles(t, map[string]string{"manifest.json": `{"name": `}),
+			wantErr: "reading the manifest.json",
+		},
+		{
+			desc:    "no manifest",
+			xpi:     zipFiles(t, map[string]string{"background.js": ""}),
+			wantErr: "no manifest.json",
+		},
+		{
+			desc:    "manifest in a subdirectory",
+			xpi:     zipFiles(t, map[string]string{"src/manifest.json": testManifest}),
+			wantErr: "no manifest.json",
+		},
+		{
+			desc:    "not a Zip archive",
+			xpi:     []byte("not a zip"),
+			wantErr: "reading the extension",
+		},
+	} {
+		got, err := ExtensionID(test.xpi)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: ExtensionID() returned error %v, want one containing %q", test.desc, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: ExtensionID() returned error: %v", test.desc, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: ExtensionID() = %q, want %q", test.desc, got, test.want)
+		}
+	}
+}
+
+func TestNewExtension(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firefox-extension-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		desc      string
+		files     map[string]string
+		wantID    string
+		wantFiles []string
+		wantErr   string
+	}{
+		{
+			desc: "extension",
+			files: map[string]string{
+				"manifest.json":      testManifest,
+				"background.js":      "console.log('test');",
+				"icons/icon.png":     "png",
+				".git/HEAD":          "ref: refs/heads/main",
+				"old-version.xpi":    "xpi",
+				"web-ext-artifacts/": "",
+			},
+			wantID:    "test@example.com",
+			wantFiles: []string{"background.js", "icons/icon.png", "manifest.json"},
+		},
+		{
+			desc:    "no manifest",
+			files:   map[string]string{"background.js": ""},
+			wantErr: "no manifest.json",
+		},
+		{
+			desc:    "no ID",
+			files:   map[string]string{"manifest.json": `{"manifest_version": 3, "name": "Test"}`},
+			wantErr: "no browser_specific_settings.gecko.id",
+		},
+	} {
+		base := filepath.Join(dir, strings.Replace(test.desc, " ", "-", -1))
+		for name, content := range test.files {
+			path := filepath.Join(base, filepath.FromSlash(name))
+			if strings.HasSuffix(name, "/") {
+				if err := os.MkdirAll(path, 0755); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		xpi, id, err := NewExtension(base)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) || !strings.Contains(err.Error(), base) {
+				t.Errorf("%s: NewExtension() returned error %v, want one containing %q and the directory", test.desc, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: NewExtension() returned error: %v", test.desc, err)
+			continue
+		}
+		if id != test.wantID {
+			t.Errorf("%s: NewExtension() returned ID %q, want %q", test.desc, id, test.wantID)
+		}
+		var names []string
+		for name := range unzipFiles(t, xpi) {
+			if !strings.HasSuffix(name, "/") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, test.wantFiles) {
+			t.Errorf("%s: NewExtension() packaged %q, want %q", test.desc, names, test.wantFiles)
+		}
+	}
+
+	if _, _, err := NewExtension(filepath.Join(dir, "missing")); err == nil {
+		t.Error("NewExtension() of a missing directory returned no error")
+	}
+}
+
+func TestAddExtension(t *testing.T) {
+	xpi := zipFiles(t, map[string]string{"manifest.json": testManifest})
+	encode := func(files map[string]string) string {
+		return base64.StdEncoding.EncodeToString(zipFiles(t, files))
+	}
+
+	for _, test := range []struct {
+		desc      string
+		profile   string
+		xpi       []byte
+		wantFiles map[string]string
+		wantErr   string
+	}{
+		{
+			desc:      "no profile",
+			xpi:       xpi,
+			wantFiles: map[string]string{"extensions/test@example.com.xpi": string(xpi)},
+		},
+		{
+			desc: "profile",
+			profile: encode(map[string]string{
+				"user.js":                          `user_pref("browser.startup.page", 0);`,
+				"extensions/":                      "",
+				"extensions/other@example.com.xpi": "other",
+			}),
+			xpi: xpi,
+			wantFiles: map[string]string{
+				"user.js":                          `user_pref("browser.startup.page", 0);`,
+				"extensions/other@example.com.xpi": "other",
+				"extensions/test@example.com.xpi":  string(xpi),
+			},
+		},
+		{
+			desc:    "extension already in the profile",
+			profile: encode(map[string]string{"extensions/test@example.com.xpi": "old version"}),
+			xpi:     xpi,
+			wantFiles: map[string]string{
+				"extensions/test@example.com.xpi": string(xpi),
+			},
+		},
+		{
+			desc:    "extension without ID",
+			xpi:     zipFiles(t, map[string]string{"manifest.json": `{"name": "Test"}`}),
+			wantErr: "no browser_specific_settings.gecko.id",
+		},
+		{
+			desc:    "invalid Base64 profile",
+			profile: "not base64!",
+			xpi:     xpi,
+			wantErr: "decoding the profile",
+		},
+		{
+			desc:    "profile not a Zip archive",
+			profile: base64.StdEncoding.EncodeToString([]byte("not a zip")),
+			xpi:     xpi,
+			wantErr: "reading the profile",
+		},
+	} {
+		c := Capabilities{Profile: test.profile}
+		err := c.addExtension(test.xpi)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("%s: c.addExtension() returned error %v, want one containing %q", test.desc, err, test.wantErr)
+			}
+			if c.Profile != test.profile || c.Prefs != nil {
+				t.Errorf("%s: c.addExtension() changed the capabilities after failing", test.desc)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: c.addExtension() returned error: %v", test.desc, err)
+			continue
+		}
+		if got := profileFiles(t, &c); !reflect.DeepEqual(got, test.wantFiles) {
+			t.Errorf("%s: the profile has the files %q, want %q", test.desc, got, test.wantFiles)
+		}
+		if got := c.Prefs[autoDisableScopesPref]; got != 0 {
+			t.Errorf("%s: the %s preference is %v, want 0", test.desc, autoDisableScopesPref, got)
+		}
+	}
+}
+
+func TestAddExtensionFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "firefox-extension-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	xpiPath := filepath.Join(dir, "test.xpi")
+	if err := ioutil.WriteFile(xpiPath, zipFiles(t, map[string]string{"manifest.json": testManifest}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	unpacked := filepath.Join(dir, "unpacked")
+	if err := os.Mkdir(unpacked, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(unpacked, "manifest.json"), []byte(testLegacyManifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c Capabilities
+	if err := c.AddExtension(xpiPath); err != nil {
+		t.Fatalf("c.AddExtension() returned error: %v", err)
+	}
+	if _, ok := c.Prefs[signaturesRequiredPref]; ok {
+		t.Errorf("c.AddExtension() set the %s preference", signaturesRequiredPref)
+	}
+	if err := c.AddUnpackedExtension(unpacked); err != nil {
+		t.Fatalf("c.AddUnpackedExtension() returned error: %v", err)
+	}
+	if got := c.Prefs[signaturesRequiredPref]; got != false {
+		t.Errorf("the %s preference is %v, want false", signaturesRequiredPref, got)
+	}
+	files := profileFiles(t, &c)
+	for _, name := range []string{"extensions/test@example.com.xpi", "extensions/legacy@example.com.xpi"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("the profile has no %s", name)
+		}
+	}
+
+	if err := c.AddExtension(filepath.Join(dir, "missing.xpi")); err == nil {
+		t.Error("c.AddExtension() of a missing file returned no error")
+	}
+}
@@ -0,0 +1,64 @@
+package firefox
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Preferences that MapHosts manages.
+const (
+	localDomainsPref = "network.dns.localDomains"
+	forceResolvePref = "network.dns.forceResolve"
+)
+
+// MapHosts makes Firefox resolve the host names that are the keys of rules
+// to their values, as far as its preferences allow:
+//
+//   - host names mapped to a loopback address, "127.0.0.1", "::1" or
+//     "localhost", are listed in the network.dns.localDomains preference;
+//   - "*", all hosts, mapped to an IP address sets the
+//     network.dns.forceResolve preference.
+//
+// Other rules, e.g. subdomain patterns, mappings to other addresses or to
+// other ports, have no preference and return an error: the fallback is a
+// proxy that maps them, set with SetProxy. No rules removes the settings.
+func (c *Capabilities) MapHosts(rules map[string]string) error {
+	var local []string
+	forceResolve := ""
+	for host, target := range rules {
+		switch {
+		case host == "*":
+			if net.ParseIP(target) == nil {
+				return fmt.Errorf("cannot map all hosts to %q: Firefox only maps them to an IP address without port", target)
+			}
+			forceResolve = target
+		case strings.ContainsAny(host, "*:/ ,"):
+			return fmt.Errorf("cannot map %q: Firefox only maps host names and %q; use a proxy instead", host, "*")
+		case !isLoopback(target):
+			return fmt.Errorf("cannot map %q to %q: Firefox only maps host names to a loopback address without port; use a proxy instead", host, target)
+		default:
+			local = append(local, host)
+		}
+	}
+	delete(c.Prefs, localDomainsPref)
+	delete(c.Prefs, forceResolvePref)
+	if len(local) > 0 {
+		sort.Strings(local)
+		c.setPref(localDomainsPref, strings.Join(local, ","))
+	}
+	if forceResolve != "" {
+		c.setPref(forceResolvePref, forceResolve)
+	}
+	return nil
+}
+
+// isLoopback reports whether target is a loopback address.
+func isLoopback(target string) bool {
+	if target == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(target)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,23 @@
+package firefox
+
+// SetSOCKSRemoteDNS makes Firefox send host names to its SOCKS proxy rather
+// than resolving them itself, with the network.proxy.socks_remote_dns
+// preference, which the W3C proxy capability cannot set.
+func (c *Capabilities) SetSOCKSRemoteDNS(enabled bool) {
+	if !enabled {
+		delete(c.Prefs, "network.proxy.socks_remote_dns")
+		return
+	}
+	c.setPref("network.proxy.socks_remote_dns", true)
+}
+
+// SetProxyLoopback makes Firefox send the requests to localhost and loopback
+// addresses through its proxy too, which it does not by default, with the
+// network.proxy.allow_hijacking_localhost preference.
+func (c *Capabilities) SetProxyLoopback(enabled bool) {
+	if !enabled {
+		delete(c.Prefs, "network.proxy.allow_hijacking_localhost")
+		return
+	}
+	c.setPref("network.proxy.allow_hijacking_localhost", true)
+}
@@ -0,0 +1,32 @@
+package firefox
+
+import "strings"
+
+// SetUserAgent makes Firefox send ua as its user agent, in the User-Agent
+// header and navigator.userAgent, with the general.useragent.override
+// preference. An empty ua restores the default.
+func (c *Capabilities) SetUserAgent(ua string) {
+	if ua == "" {
+		delete(c.Prefs, "general.useragent.override")
+		return
+	}
+	c.setPref("general.useragent.override", ua)
+}
+
+// SetAcceptLanguage makes Firefox prefer the given languages, e.g. "fr-CH",
+// "fr", in order: they are sent in the Accept-Language header and returned
+// by navigator.languages. No languages restores the default.
+func (c *Capabilities) SetAcceptLanguage(langs ...string) {
+	if len(langs) == 0 {
+		delete(c.Prefs, "intl.accept_languages")
+		return
+	}
+	c.setPref("intl.accept_languages", strings.Join(langs, ","))
+}
+
+func (c *Capabilities) setPref(name string, value interface{}) {
+	if c.Prefs == nil {
+		c.Prefs = make(map[string]interface{})
+	}
+	c.Prefs[name] = value
+}
@@ -0,0 +1,226 @@
+package selenium
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FixtureKind is a type of file generated by GenerateFixtureFile. Its value is
+// the extension of the file.
+type FixtureKind string
+
+// Kinds of fixture files.
+const (
+	FixturePNG  FixtureKind = "png"
+	FixtureJPEG FixtureKind = "jpg"
+	FixturePDF  FixtureKind = "pdf"
+	FixtureCSV  FixtureKind = "csv"
+	FixtureZIP  FixtureKind = "zip"
+)
+
+// fixtureImageSize is the width and height of the generated images.
+const fixtureImageSize = 100
+
+// GenerateFixtureFile writes a valid file of the given kind and size, in
+// bytes, to a new temporary directory, e.g. to test an upload form without
+// shipping fixture files. Files smaller than the minimal valid file of the
+// kind cannot be generated; a minimal file is written instead. Images are
+// 100x100 pixels. cleanup removes the file and its directory.
+//
+// The file is on the local disk: pass the path to WebDriver.UploadFile to
+// get a path that the remote end can use, or read the file and call
+// WebElement.UploadBytes.
+func GenerateFixtureFile(kind FixtureKind, size int) (path string, cleanup func(), err error) {
+	var data []byte
+	switch kind {
+	case FixturePNG:
+		data, err = fixturePNG(size)
+	case FixtureJPEG:
+		data, err = fixtureJPEG(size)
+	case FixturePDF:
+		data = fixturePDF(size)
+	case FixtureCSV:
+		data = fixtureCSV(size)
+	case FixtureZIP:
+		data, err = fixtureZIP(size)
+	default:
+		return "", nil, fmt.Errorf("unknown fixture kind %q", kind)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := ioutil.TempDir("", "selenium-fixture-")
+	if err != nil {
+		return "", nil, err
+	}
+	trackTempPath(dir)
+	cleanup = func() {
+		os.RemoveAll(dir)
+		untrackTempPath(dir)
+	}
+	path = filepath.Join(dir, "fixture."+string(kind))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// fixtureImage returns a gradient, so that the images are not trivially
+// compressible to nothing.
+func fixtureImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, fixtureImageSize, fixtureImageSize))
+	for y := 0; y < fixtureImageSize; y++ {
+		for x := 0; x < fixtureImageSize; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / fixtureImageSize), uint8(y * 255 / fixtureImageSize), 128, 255})
+		}
+	}
+	return img
+}
+
+// fixturePNG pads the image to size with a private ancillary chunk, which
+// decoders skip.
+func fixturePNG(size int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fixtureImage()); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	// A chunk has a length, a type and a CRC of 4 bytes each.
+	padding := size - len(data) - 12
+	if padding < 0 {
+		return data, nil
+	}
+	chunk := make([]byte, 12+padding)
+	binary.BigEndian.PutUint32(chunk, uint32(padding))
+	copy(chunk[4:], "pdNg")
+	binary.BigEndian.PutUint32(chunk[8+padding:], crc32.ChecksumIEEE(chunk[4:8+padding]))
+	// Insert the chunk before the IEND chunk, which takes the last 12 bytes.
+	iend := len(data) - 12
+	return append(append(append([]byte(nil), data[:iend]...), chunk...), data[iend:]...), nil
+}
+
+// fixtureJPEG pads the image to size with comment segments, which hold up to
+// 65533 bytes each, after the SOI marker.
+func fixtureJPEG(size int) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, fixtureImage(), nil); err != nil {
+		return nil, err
+	}
+	data := buf.Bytes()
+	var segments []byte
+	for remaining := size - len(data); remaining >= 4; {
+		n := remaining - 4
+		if n > 65533 {
+			n = 65533
+		}
+		if left := remaining - 4 - n; left > 0 && left < 4 {
+			// Leave room for a last segment.
+			n -= 4
+		}
+		segment := make([]byte, 4+n)
+		segment[0], segment[1] = 0xFF, 0xFE
+		binary.BigEndian.PutUint16(segment[2:], uint16(n+2))
+		for i := 4; i < len(segment); i++ {
+			segment[i] = ' '
+		}
+		segments = append(segments, segment...)
+		remaining -= len(segment)
+	}
+	return append(append(append([]byte(nil), data[:2]...), segments...), data[2:]...), nil
+}
+
+// fixturePDF writes a one-page document, padded to size with a comment
+// before the cross-reference table.
+func fixturePDF(size int) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		"<< /Length 38 >>\nstream\nBT /F1 24 Tf 72 720 Td (Fixture) Tj ET\nendstream",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	var trailer bytes.Buffer
+	fmt.Fprintf(&trailer, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&trailer, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&trailer, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n", len(objects)+1)
+	// The padding comment goes after the objects, so their offsets hold. The
+	// offset of the table, written after startxref, takes 10 digits.
+	const footer = "\n%EOF\n"
+	padding := size - b.Len() - trailer.Len() - len(footer) - 10
+	if padding >= 2 {
+		b.WriteString("%" + strings.Repeat(" ", padding-2) + "\n")
+	}
+	xref := b.Len()
+	b.Write(trailer.Bytes())
+	fmt.Fprintf(&b, "%010d", xref)
+	b.WriteString(footer)
+	return b.Bytes()
+}
+
+// fixtureCSV writes rows until the file has size bytes, padding the name in
+// the last row.
+func fixtureCSV(size int) []byte {
+	var b bytes.Buffer
+	b.WriteString("id,name,value\n")
+	for i := 1; ; i++ {
+		remaining := size - b.Len()
+		row := fmt.Sprintf("%d,item %d,%d\n", i, i, i*7%1000)
+		if remaining < 2*len(row) {
+			if fill := remaining - len(fmt.Sprintf("%d,,0\n", i)); fill >= 0 {
+				fmt.Fprintf(&b, "%d,%s,0\n", i, strings.Repeat("x", fill))
+			}
+			return b.Bytes()
+		}
+		b.WriteString(row)
+	}
+}
+
+// fixtureZIP writes an archive holding a stored text file, whose overhead
+// does not depend on its content.
+func fixtureZIP(size int) ([]byte, error) {
+	archive := func(content []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		f, err := w.CreateHeader(&zip.FileHeader{Name: "fixture.txt", Method: zip.Store})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(content); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	empty, err := archive(nil)
+	if err != nil {
+		return nil, err
+	}
+	n := size - len(empty)
+	if n <= 0 {
+		return empty, nil
+	}
+	return archive(bytes.Repeat([]byte("fixture\n"), n/8+1)[:n])
+}
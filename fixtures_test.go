@@ -0,0 +1,76 @@
+package selenium
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestGenerateFixtureFile(t *testing.T) {
+	valid := map[FixtureKind]func(data []byte) error{
+		FixturePNG: func(data []byte) error {
+			_, err := png.Decode(bytes.NewReader(data))
+			return err
+		},
+		FixtureJPEG: func(data []byte) error {
+			_, err := jpeg.Decode(bytes.NewReader(data))
+			return err
+		},
+		FixturePDF: func(data []byte) error {
+			m := regexp.MustCompile(`startxref\n(\d+)\n%EOF\n$`).FindSubmatch(data)
+			if !bytes.HasPrefix(data, []byte("%PDF-")) || m == nil {
+				return errors.New("no PDF header or trailer")
+			}
+			off, _ := strconv.Atoi(string(m[1]))
+			if !bytes.HasPrefix(data[off:], []byte("xref\n")) {
+				return errors.New("startxref does not point to the cross-reference table")
+			}
+			return nil
+		},
+		FixtureCSV: func(data []byte) error {
+			r := csv.NewReader(bytes.NewReader(data))
+			r.FieldsPerRecord = 3
+			_, err := r.ReadAll()
+			return err
+		},
+		FixtureZIP: func(data []byte) error {
+			r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				return err
+			}
+			f, err := r.File[0].Open()
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = ioutil.ReadAll(f)
+			return err
+		},
+	}
+	for kind, check := range valid {
+		for _, size := range []int{0, 30000, 200000} {
+			path, cleanup, err := GenerateFixtureFile(kind, size)
+			if err != nil {
+				t.Fatalf("GenerateFixtureFile(%q, %d) returned error: %v", kind, size, err)
+			}
+			data, err := ioutil.ReadFile(path)
+			cleanup()
+			if err != nil {
+				t.Fatalf("reading the %s fixture returned error: %v", kind, err)
+			}
+			if err := check(data); err != nil {
+				t.Errorf("GenerateFixtureFile(%q, %d) wrote an invalid file: %v", kind, size, err)
+			}
+			if size > 0 && len(data) != size {
+				t.Errorf("GenerateFixtureFile(%q, %d) wrote %d bytes", kind, size, len(data))
+			}
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// FramePath identifies a nested browsing context by the index of each frame,
+// in document order, starting from the top-level browsing context. An empty
+// FramePath refers to the top-level browsing context itself.
+type FramePath []int
+
+// String returns a human-readable representation of the path.
+func (p FramePath) String() string {
+	parts := []string{"top"}
+	for _, i := range p {
+		parts = append(parts, strconv.Itoa(i))
+	}
+	return strings.Join(parts, " > ")
+}
+
+// SkippedFrame describes a frame that FindElementInAnyFrame could not search.
+type SkippedFrame struct {
+	// Path is the path to the frame.
+	Path FramePath
+	// Err is the error returned when entering or searching the frame.
+	Err error
+}
+
+// FrameSearchError is returned by FindElementInAnyFrame when no frame
+// contains a matching element.
+type FrameSearchError struct {
+	// By and Value are the search parameters.
+	By, Value string
+	// Searched is the number of browsing contexts that were searched.
+	Searched int
+	// Skipped lists the frames that could not be searched.
+	Skipped []SkippedFrame
+}
+
+// Error implements the error interface.
+func (e *FrameSearchError) Error() string {
+	msg := fmt.Sprintf("no such element: %s %q not found in %d browsing contexts", e.By, e.Value, e.Searched)
+	if len(e.Skipped) == 0 {
+		return msg
+	}
+	var skipped []string
+	for _, s := range e.Skipped {
+		skipped = append(skipped, fmt.Sprintf("%s (%v)", s.Path, s.Err))
+	}
+	return msg + "; skipped frames: " + strings.Join(skipped, ", ")
+}
+
+// currentFramePathScript returns the index of each frame between the
+// top-level browsing context and the current one. The properties it uses are
+// accessible across origins.
+const currentFramePathScript = `
+var path = [], w = window;
+while (w !== w.parent) {
+	var p = w.parent, index = -1;
+	for (var i = 0; i < p.frames.length; i++) {
+		if (p.frames[i] === w) {
+			index = i;
+			break;
+		}
+	}
+	if (index < 0) {
+		return null;
+	}
+	path.unshift(index);
+	w = p;
+}
+return path;
+`
+
+// currentFramePath returns the path to the current browsing context.
+func (wd *remoteWD) currentFramePath() (FramePath, error) {
+	v, err := wd.ExecuteScript(currentFramePathScript, nil)
+	if err != nil {
+		return nil, err
+	}
+	indexes, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to determine the current frame, got %v", v)
+	}
+	path := make(FramePath, len(indexes))
+	for i, index := range indexes {
+		f, ok := scriptInt(index)
+		if !ok {
+			return nil, fmt.Errorf("unexpected frame index %v", index)
+		}
+		path[i] = int(f)
+	}
+	return path, nil
+}
+
+// switchToFramePath switches to the browsing context identified by path.
+func (wd *remoteWD) switchToFramePath(path FramePath) error {
+	if err := wd.SwitchFrame(nil); err != nil {
+		return err
+	}
+	for _, i := range path {
+		if err := wd.SwitchFrame(i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// switchToParentFrame switches to the parent of the current browsing context.
+func (wd *remoteWD) switchToParentFrame() error {
+	return wd.voidCommand("/session/%s/frame/parent", nil)
+}
+
+func (wd *remoteWD) WithFrame(path FramePath, fn func() error) (err error) {
+	previous, err := wd.currentFramePath()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if restoreErr := wd.switchToFramePath(previous); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+	if err := wd.switchToFramePath(path); err != nil {
+		return err
+	}
+	return fn()
+}
+
+func (wd *remoteWD) FindElementInAnyFrame(by, value string, maxDepth int) (WebElement, FramePath, error) {
+	var elem WebElement
+	var path FramePath
+	searchErr := &FrameSearchError{By: by, Value: value}
+	err := wd.WithFrame(nil, func() error {
+		var err error
+		elem, path, err = wd.findElementInFrames(by, value, nil, maxDepth, searchErr)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if elem == nil {
+		return nil, nil, searchErr
+	}
+	if e, ok := elem.(*remoteWE); ok {
+		e.frame = append(FramePath{}, path...)
+	}
+	return elem, path, nil
+}
+
+// findElementInFrames searches the current browsing context, whose path is
+// path, and then its descendant frames. On return, the current browsing
+// context is unchanged unless an error is returned or an element was found.
+func (wd *remoteWD) findElementInFrames(by, value string, path FramePath, maxDepth int, searchErr *FrameSearchError) (WebElement, FramePath, error) {
+	searchErr.Searched++
+	elem, err := wd.FindElement(by, value)
+	switch {
+	case err == nil:
+		return elem, path, nil
+	case isInvalidLocatorError(err):
+		return nil, nil, err
+	case !isNoSuchElementError(err):
+		if len(path) == 0 {
+			return nil, nil, err
+		}
+		searchErr.Skipped = append(searchErr.Skipped, SkippedFrame{Path: path, Err: err})
+		return nil, nil, nil
+	}
+	if len(path) >= maxDepth {
+		return nil, nil, nil
+	}
+
+	n, err := wd.ExecuteScript("return window.frames.length", nil)
+	if err != nil {
+		if len(path) == 0 {
+			return nil, nil, err
+		}
+		searchErr.Skipped = append(searchErr.Skipped, SkippedFrame{Path: path, Err: err})
+		return nil, nil, nil
+	}
+	count, _ := scriptInt(n)
+	for i := 0; i < int(count); i++ {
+		child := append(path[:len(path):len(path)], i)
+		if err := wd.SwitchFrame(i); err != nil {
+			searchErr.Skipped = append(searchErr.Skipped, SkippedFrame{Path: child, Err: err})
+			debugLog("skipping frame %s: %v", child, err)
+			continue
+		}
+		elem, found, err := wd.findElementInFrames(by, value, child, maxDepth, searchErr)
+		if err != nil || elem != nil {
+			return elem, found, err
+		}
+		if err := wd.switchToParentFrame(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return nil, nil, nil
+}
+
+// isNoSuchElementError reports whether err indicates that an element search
+// found nothing.
+func isNoSuchElementError(err error) bool {
+	return errors.Is(err, werror.NoSuchElement)
+}
+
+// isInvalidLocatorError reports whether err indicates that the locator itself
+// is invalid, in which case searching other frames is pointless.
+func isInvalidLocatorError(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return false
+	}
+	switch e.Err {
+	case "invalid selector", "invalid argument", "xpath lookup error":
+		return true
+	}
+	return false
+}
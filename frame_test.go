@@ -0,0 +1,39 @@
+package selenium
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFramePathString(t *testing.T) {
+	for _, test := range []struct {
+		path FramePath
+		want string
+	}{
+		{nil, "top"},
+		{FramePath{0}, "top > 0"},
+		{FramePath{1, 0, 3}, "top > 1 > 0 > 3"},
+	} {
+		if got := test.path.String(); got != test.want {
+			t.Errorf("FramePath(%v).String() = %q, want %q", []int(test.path), got, test.want)
+		}
+	}
+}
+
+func TestFrameSearchErrorListsSkippedFrames(t *testing.T) {
+	err := &FrameSearchError{
+		By:       ByCSSSelector,
+		Value:    "#pay",
+		Searched: 3,
+		Skipped: []SkippedFrame{
+			{Path: FramePath{1}, Err: errors.New("no such frame")},
+		},
+	}
+	msg := err.Error()
+	for _, want := range []string{`"#pay"`, "3 browsing contexts", "top > 1 (no such frame)"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("FrameSearchError.Error() = %q, want it to contain %q", msg, want)
+		}
+	}
+}
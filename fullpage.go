@@ -0,0 +1,162 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// FullPageScreenshot takes a screenshot of the whole page.
+func (wd *remoteWD) FullPageScreenshot() ([]byte, error) {
+	data, err := wd.fullPageScreenshotCDP()
+	if !isUnknownCommand(err) {
+		return data, err
+	}
+	data, err = wd.fullPageScreenshotFirefox()
+	if !isUnknownCommand(err) {
+		return data, err
+	}
+	return wd.stitchedScreenshot()
+}
+
+// fullPageScreenshotCDP captures the whole page with the DevTools Protocol,
+// which renders the parts of the page outside of the viewport.
+func (wd *remoteWD) fullPageScreenshotCDP() ([]byte, error) {
+	raw, err := wd.executeCDP("Page.getLayoutMetrics", nil)
+	if err != nil {
+		return nil, err
+	}
+	type size struct{ Width, Height float64 }
+	metrics := new(struct {
+		// CSSContentSize is in CSS pixels since Chrome 92; ContentSize was
+		// before, and is in device pixels since.
+		CSSContentSize *size
+		ContentSize    size
+	})
+	if err := json.Unmarshal(raw, metrics); err != nil {
+		return nil, err
+	}
+	content := metrics.ContentSize
+	if metrics.CSSContentSize != nil {
+		content = *metrics.CSSContentSize
+	}
+	raw, err = wd.executeCDP("Page.captureScreenshot", map[string]interface{}{
+		"format":                "png",
+		"captureBeyondViewport": true,
+		"clip": map[string]interface{}{
+			"x":      0,
+			"y":      0,
+			"width":  math.Ceil(content.Width),
+			"height": math.Ceil(content.Height),
+			"scale":  1,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Data string })
+	if err := json.Unmarshal(raw, reply); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(reply.Data)
+}
+
+// fullPageScreenshotFirefox captures the whole page with the command of
+// GeckoDriver.
+func (wd *remoteWD) fullPageScreenshotFirefox() ([]byte, error) {
+	data, err := wd.stringCommand("/session/%s/moz/screenshot/full")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// pageGeometryScript returns the size of the page and of the viewport, without
+// the scroll bars, the scroll position and the device pixel ratio.
+const pageGeometryScript = `
+	var root = document.documentElement, body = document.body || root;
+	return {
+		height: Math.max(root.scrollHeight, body.scrollHeight),
+		viewWidth: root.clientWidth || window.innerWidth,
+		viewHeight: root.clientHeight || window.innerHeight,
+		x: window.pageXOffset,
+		y: window.pageYOffset,
+		ratio: window.devicePixelRatio || 1
+	};`
+
+// scrollScript scrolls the page to the position in its arguments and returns
+// the vertical position reached, which is less at the bottom of the page.
+const scrollScript = `
+	window.scrollTo(arguments[0], arguments[1]);
+	return window.pageYOffset;`
+
+// pageGeometry is the value returned by pageGeometryScript.
+type pageGeometry struct {
+	Height, ViewWidth, ViewHeight, X, Y, Ratio float64
+}
+
+// maxStitchedScreenshots bounds the number of screenshots stitched, for pages
+// that grow as they are scrolled, such as infinite feeds.
+const maxStitchedScreenshots = 100
+
+// stitchedScreenshot captures the page by scrolling it one viewport at a
+// time and stitching the screenshots of the viewport, then restores the
+// scroll position.
+func (wd *remoteWD) stitchedScreenshot() ([]byte, error) {
+	raw, err := wd.ExecuteScriptRaw(pageGeometryScript, nil)
+	if err != nil {
+		return nil, fmt.Errorf("measuring the page: %w", err)
+	}
+	reply := new(struct{ Value pageGeometry })
+	if err := json.Unmarshal(raw, reply); err != nil {
+		return nil, err
+	}
+	g := reply.Value
+	if g.Ratio <= 0 {
+		g.Ratio = 1
+	}
+	if g.ViewHeight <= 0 {
+		return nil, fmt.Errorf("the viewport has no height")
+	}
+	defer func() {
+		if _, err := wd.ExecuteScript(scrollScript, []interface{}{g.X, g.Y}); err != nil {
+			debugLog("error restoring the scroll position: %v", err)
+		}
+	}()
+
+	px := func(v float64) int { return int(math.Round(v * g.Ratio)) }
+	img := image.NewRGBA(image.Rect(0, 0, px(g.ViewWidth), px(g.Height)))
+	for i, y := 0, 0.0; i < maxStitchedScreenshots && y < g.Height; i, y = i+1, y+g.ViewHeight {
+		result, err := wd.ExecuteScript(scrollScript, []interface{}{0, y})
+		if err != nil {
+			return nil, fmt.Errorf("scrolling the page: %w", err)
+		}
+		reached, _ := result.(float64)
+		if n, ok := result.(json.Number); ok {
+			reached, _ = n.Float64()
+		}
+		data, err := wd.Screenshot()
+		if err != nil {
+			return nil, err
+		}
+		shot, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding the screenshot: %v", err)
+		}
+		// The screenshot of the last viewport, scrolled less than asked,
+		// overlaps the previous one; it is drawn over it.
+		b := shot.Bounds()
+		draw.Draw(img, b.Sub(b.Min).Add(image.Pt(0, px(reached))), shot, b.Min, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
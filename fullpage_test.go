@@ -0,0 +1,132 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFullPageScreenshotCDP(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var clip map[string]interface{}
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params struct {
+				CaptureBeyondViewport bool
+				Clip                  map[string]interface{}
+			}
+		}
+		json.Unmarshal(body, &params)
+		switch params.Cmd {
+		case "Page.getLayoutMetrics":
+			return http.StatusOK, map[string]interface{}{
+				"contentSize":    map[string]float64{"width": 201, "height": 601},
+				"cssContentSize": map[string]float64{"width": 100.5, "height": 300},
+			}
+		case "Page.captureScreenshot":
+			if !params.Params.CaptureBeyondViewport {
+				return http.StatusBadRequest, map[string]string{"error": "invalid argument", "message": "viewport only"}
+			}
+			clip = params.Params.Clip
+			return http.StatusOK, map[string]string{"data": base64.StdEncoding.EncodeToString(whitePNG(t, 101, 300))}
+		}
+		return http.StatusNotFound, map[string]string{"error": "unknown command", "message": params.Cmd}
+	})
+	wd := d.newRemote(t)
+
+	data, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("wd.FullPageScreenshot() returned error: %v", err)
+	}
+	if !bytes.Equal(data, whitePNG(t, 101, 300)) {
+		t.Error("wd.FullPageScreenshot() did not return the screenshot of the DevTools Protocol")
+	}
+	if clip["width"] != 101.0 || clip["height"] != 300.0 || clip["scale"] != 1.0 {
+		t.Errorf("the screenshot was clipped to %v, want the CSS size of the page", clip)
+	}
+}
+
+func TestFullPageScreenshotFirefox(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/moz/screenshot/full", func([]byte) (int, interface{}) {
+		return http.StatusOK, base64.StdEncoding.EncodeToString(whitePNG(t, 10, 40))
+	})
+	wd := d.newRemote(t)
+
+	data, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("wd.FullPageScreenshot() returned error: %v", err)
+	}
+	if !bytes.Equal(data, whitePNG(t, 10, 40)) {
+		t.Error("wd.FullPageScreenshot() did not return the screenshot of GeckoDriver")
+	}
+}
+
+func TestFullPageScreenshotStitched(t *testing.T) {
+	// The page is 25 CSS pixels high and the viewport 10x10, plus a scroll
+	// bar, with a device pixel ratio of 2. The rows of the screenshots have
+	// the gray level of their position in the page.
+	const height, viewWidth, viewHeight, ratio = 25, 10, 10, 2
+	d := newFakeDriver(t, nil)
+	scrollY := 3.0
+	var scrolls []float64
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct {
+			Script string
+			Args   []float64
+		}
+		json.Unmarshal(body, &params)
+		if strings.Contains(params.Script, "scrollHeight") {
+			return http.StatusOK, map[string]float64{
+				"height": height, "viewWidth": viewWidth, "viewHeight": viewHeight,
+				"x": 0, "y": scrollY, "ratio": ratio,
+			}
+		}
+		scrollY = params.Args[1]
+		if scrollY > height-viewHeight {
+			scrollY = height - viewHeight
+		}
+		scrolls = append(scrolls, scrollY)
+		return http.StatusOK, scrollY
+	})
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		img := image.NewGray(image.Rect(0, 0, (viewWidth+2)*ratio, viewHeight*ratio))
+		for y := 0; y < viewHeight*ratio; y++ {
+			for x := 0; x < (viewWidth+2)*ratio; x++ {
+				img.SetGray(x, y, color.Gray{Y: uint8(int(scrollY)*ratio + y)})
+			}
+		}
+		var buf bytes.Buffer
+		png.Encode(&buf, img)
+		return http.StatusOK, base64.StdEncoding.EncodeToString(buf.Bytes())
+	})
+	wd := d.newRemote(t)
+
+	data, err := wd.FullPageScreenshot()
+	if err != nil {
+		t.Fatalf("wd.FullPageScreenshot() returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("the screenshot is not a PNG image: %v", err)
+	}
+	if got, want := img.Bounds().Size(), image.Pt(viewWidth*ratio, height*ratio); got != want {
+		t.Fatalf("the screenshot has size %v, want %v", got, want)
+	}
+	for y := 0; y < height*ratio; y++ {
+		if got := color.GrayModel.Convert(img.At(viewWidth*ratio-1, y)).(color.Gray).Y; int(got) != y {
+			t.Fatalf("row %d of the screenshot has gray level %d, want %d", y, got, y)
+		}
+	}
+	if want := []float64{0, 10, 15, 3}; !reflect.DeepEqual(scrolls, want) {
+		t.Errorf("the page was scrolled to %v, want %v", scrolls, want)
+	}
+}
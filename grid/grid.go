@@ -0,0 +1,251 @@
+// Package grid queries the state of a Selenium Grid 4: its nodes, their
+// slots and the sessions queued, e.g. to report why a test waits for a
+// session or to wait for a free slot before starting one.
+//
+//	g := &grid.Client{URL: "http://localhost:4444"}
+//	if _, err := g.WaitForSlot(ctx, caps); err != nil {
+//		...
+//	}
+//	wd, err := selenium.NewRemote(caps, g.URL)
+package grid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client queries a Selenium Grid.
+type Client struct {
+	// URL is the address of the grid, e.g. "http://localhost:4444". A
+	// trailing "/wd/hub" is ignored, so that the URL of the sessions can be
+	// used.
+	URL string
+	// HTTPClient makes the requests. http.DefaultClient is used if nil.
+	HTTPClient *http.Client
+}
+
+// Status is the state of the grid, as returned by its /status endpoint.
+type Status struct {
+	// Ready is true if the grid can create sessions.
+	Ready   bool   `json:"ready"`
+	Message string `json:"message"`
+	Nodes   []Node `json:"nodes"`
+}
+
+// Availabilities of nodes.
+const (
+	NodeUp       = "UP"
+	NodeDraining = "DRAINING"
+	NodeDown     = "DOWN"
+)
+
+// Node is a node of the grid, which runs sessions in its slots.
+type Node struct {
+	ID  string `json:"id"`
+	URI string `json:"uri"`
+	// Availability is NodeUp, NodeDraining or NodeDown. Only the nodes that
+	// are up accept new sessions.
+	Availability string `json:"availability"`
+	// MaxSessions is the number of sessions that the node runs at most,
+	// which may be less than the number of its slots.
+	MaxSessions int    `json:"maxSessions"`
+	Version     string `json:"version"`
+	Slots       []Slot `json:"slots"`
+}
+
+// Slot is a slot of a node, which runs one session at a time.
+type Slot struct {
+	ID SlotID `json:"id"`
+	// Stereotype is the capabilities of the sessions that the slot creates.
+	Stereotype map[string]interface{} `json:"stereotype"`
+	// Session is the session running in the slot, or nil if it is free.
+	Session *Session `json:"session"`
+}
+
+// SlotID identifies a slot.
+type SlotID struct {
+	HostID string `json:"hostId"`
+	ID     string `json:"id"`
+}
+
+// Session is a session running on a node.
+type Session struct {
+	ID           string                 `json:"sessionId"`
+	Capabilities map[string]interface{} `json:"capabilities"`
+	Start        time.Time              `json:"start"`
+}
+
+// FreeSlots returns the free slots of the node, or none if it does not accept
+// new sessions, because it is not up or runs its maximum number of sessions.
+func (n Node) FreeSlots() []Slot {
+	if n.Availability != NodeUp {
+		return nil
+	}
+	var free []Slot
+	busy := 0
+	for _, s := range n.Slots {
+		if s.Session != nil {
+			busy++
+		} else {
+			free = append(free, s)
+		}
+	}
+	if n.MaxSessions > 0 && busy >= n.MaxSessions {
+		return nil
+	}
+	return free
+}
+
+// Info is the summary of the grid, as returned by its GraphQL endpoint.
+type Info struct {
+	// MaxSessions is the number of sessions that the grid runs at most, and
+	// SessionCount the number of those running.
+	MaxSessions  int `json:"maxSession"`
+	SessionCount int `json:"sessionCount"`
+	TotalSlots   int `json:"totalSlots"`
+	NodeCount    int `json:"nodeCount"`
+	// SessionQueueSize is the number of New Session requests waiting for a
+	// free slot.
+	SessionQueueSize int `json:"sessionQueueSize"`
+}
+
+// baseURL returns the URL of the grid, without a trailing slash or /wd/hub.
+func (c *Client) baseURL() string {
+	return strings.TrimSuffix(strings.TrimSuffix(c.URL, "/"), "/wd/hub")
+}
+
+// do sends a request to the grid and decodes its JSON reply into v.
+func (c *Client) do(ctx context.Context, method, path string, body []byte, v interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL()+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s %s: invalid reply %q: %v", method, path, data, err)
+	}
+	return nil
+}
+
+// Status returns the state of the grid and of its nodes.
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	reply := new(struct{ Value *Status })
+	if err := c.do(ctx, "GET", "/status", nil, reply); err != nil {
+		return nil, err
+	}
+	if reply.Value == nil {
+		return nil, fmt.Errorf("GET /status: no status in the reply")
+	}
+	return reply.Value, nil
+}
+
+// infoQuery is the GraphQL query of Info.
+const infoQuery = `{ grid { maxSession, sessionCount, totalSlots, nodeCount, sessionQueueSize } }`
+
+// Info returns the summary of the grid, including the length of its session
+// queue, which Status does not report.
+func (c *Client) Info(ctx context.Context) (*Info, error) {
+	body, err := json.Marshal(map[string]string{"query": infoQuery})
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct {
+		Data struct {
+			Grid *Info
+		}
+		Errors []struct {
+			Message string
+		}
+	})
+	if err := c.do(ctx, "POST", "/graphql", body, reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Errors) > 0 {
+		return nil, fmt.Errorf("POST /graphql: %s", reply.Errors[0].Message)
+	}
+	if reply.Data.Grid == nil {
+		return nil, fmt.Errorf("POST /graphql: no grid in the reply")
+	}
+	return reply.Data.Grid, nil
+}
+
+// Matches reports whether the slot creates sessions with the capabilities
+// caps, comparing the browserName, browserVersion and platformName
+// capabilities as the grid does by default. The other capabilities are
+// ignored, as the grid passes them to the driver.
+func (s Slot) Matches(caps map[string]interface{}) bool {
+	for _, name := range []string{"browserName", "browserVersion", "platformName"} {
+		want, _ := caps[name].(string)
+		if want == "" || (name == "browserVersion" && (want == "stable" || want == "latest")) || (name == "platformName" && strings.EqualFold(want, "any")) {
+			continue
+		}
+		got, _ := s.Stereotype[name].(string)
+		if name == "platformName" {
+			if !strings.EqualFold(got, want) {
+				return false
+			}
+		} else if got != want {
+			return false
+		}
+	}
+	return true
+}
+
+// pollInterval is the interval between the queries of WaitForSlot. It is a
+// variable for tests.
+var pollInterval = time.Second
+
+// WaitForSlot waits until a node of the grid has a free slot that matches
+// caps, as described by Slot.Matches, and returns that node. It returns the
+// error of ctx if it is done first. The slot may still be taken by another
+// client before the session is created.
+func (c *Client) WaitForSlot(ctx context.Context, caps map[string]interface{}) (*Node, error) {
+	for {
+		status, err := c.Status(ctx)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, n := range status.Nodes {
+			for _, s := range n.FreeSlots() {
+				if s.Matches(caps) {
+					return &status.Nodes[i], nil
+				}
+			}
+		}
+		timer := time.NewTimer(pollInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package grid
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGrid serves the status of a grid with a Firefox node and a Chrome node,
+// whose slot is busy until free is called.
+func fakeGrid(t *testing.T) (c *Client, free func(), statusRequests func() int) {
+	var mu sync.Mutex
+	busy, requests := true, 0
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/status":
+			requests++
+			var session interface{}
+			if busy {
+				session = map[string]interface{}{"sessionId": "s1", "capabilities": map[string]string{"browserName": "chrome"}, "start": "2024-05-01T10:00:00Z"}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": map[string]interface{}{
+				"ready":   true,
+				"message": "Selenium Grid ready.",
+				"nodes": []interface{}{
+					map[string]interface{}{
+						"id": "firefox-node", "uri": "http://10.0.0.2:5555", "availability": "UP", "maxSessions": 1,
+						"slots": []interface{}{map[string]interface{}{
+							"id":         map[string]string{"hostId": "h1", "id": "slot1"},
+							"stereotype": map[string]string{"browserName": "firefox", "platformName": "LINUX"},
+						}},
+					},
+					map[string]interface{}{
+						"id": "chrome-node", "uri": "http://10.0.0.3:5555", "availability": "UP", "maxSessions": 1,
+						"slots": []interface{}{map[string]interface{}{
+							"id":         map[string]string{"hostId": "h2", "id": "slot2"},
+							"stereotype": map[string]string{"browserName": "chrome", "browserVersion": "120.0", "platformName": "LINUX"},
+							"session":    session,
+						}},
+					},
+				},
+			}})
+		case "/graphql":
+			body, _ := ioutil.ReadAll(r.Body)
+			if !strings.Contains(string(body), "sessionQueueSize") {
+				w.Write([]byte(`{"errors": [{"message": "unexpected query"}]}`))
+				return
+			}
+			w.Write([]byte(`{"data": {"grid": {"maxSession": 2, "sessionCount": 1, "totalSlots": 2, "nodeCount": 2, "sessionQueueSize": 3}}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	saved := pollInterval
+	pollInterval = time.Millisecond
+	t.Cleanup(func() { pollInterval = saved })
+	return &Client{URL: s.URL + "/wd/hub"}, func() {
+			mu.Lock()
+			busy = false
+			mu.Unlock()
+		}, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return requests
+		}
+}
+
+func TestStatus(t *testing.T) {
+	c, _, _ := fakeGrid(t)
+	s, err := c.Status(context.Background())
+	if err != nil {
+		t.Fatalf("c.Status() returned error: %v", err)
+	}
+	if !s.Ready || len(s.Nodes) != 2 {
+		t.Fatalf("c.Status() = %+v, want a ready grid with 2 nodes", s)
+	}
+	if n := len(s.Nodes[0].FreeSlots()); n != 1 {
+		t.Errorf("the Firefox node has %d free slots, want 1", n)
+	}
+	chrome := s.Nodes[1]
+	if n := len(chrome.FreeSlots()); n != 0 {
+		t.Errorf("the Chrome node has %d free slots, want 0", n)
+	}
+	if session := chrome.Slots[0].Session; session == nil || session.ID != "s1" || session.Start.IsZero() {
+		t.Errorf("the session of the Chrome node is %+v, want s1", session)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	c, _, _ := fakeGrid(t)
+	info, err := c.Info(context.Background())
+	if err != nil {
+		t.Fatalf("c.Info() returned error: %v", err)
+	}
+	want := Info{MaxSessions: 2, SessionCount: 1, TotalSlots: 2, NodeCount: 2, SessionQueueSize: 3}
+	if *info != want {
+		t.Errorf("c.Info() = %+v, want %+v", *info, want)
+	}
+}
+
+func TestSlotMatches(t *testing.T) {
+	s := Slot{Stereotype: map[string]interface{}{"browserName": "chrome", "browserVersion": "120.0", "platformName": "LINUX"}}
+	for _, test := range []struct {
+		caps map[string]interface{}
+		want bool
+	}{
+		{map[string]interface{}{"browserName": "chrome"}, true},
+		{map[string]interface{}{"browserName": "chrome", "browserVersion": "stable", "platformName": "linux"}, true},
+		{map[string]interface{}{"browserName": "chrome", "platformName": "any", "goog:chromeOptions": map[string]interface{}{}}, true},
+		{map[string]interface{}{"browserName": "firefox"}, false},
+		{map[string]interface{}{"browserName": "chrome", "browserVersion": "119.0"}, false},
+		{map[string]interface{}{"browserName": "chrome", "platformName": "windows"}, false},
+	} {
+		if got := s.Matches(test.caps); got != test.want {
+			t.Errorf("s.Matches(%v) = %t, want %t", test.caps, got, test.want)
+		}
+	}
+}
+
+func TestWaitForSlot(t *testing.T) {
+	c, free, requests := fakeGrid(t)
+	caps := map[string]interface{}{"browserName": "chrome"}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.WaitForSlot(ctx, caps); err != context.DeadlineExceeded {
+		t.Fatalf("c.WaitForSlot() while the slot is busy returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+	if n := requests(); n < 2 {
+		t.Errorf("c.WaitForSlot() queried the status %d times, want it polled", n)
+	}
+
+	free()
+	n, err := c.WaitForSlot(context.Background(), caps)
+	if err != nil {
+		t.Fatalf("c.WaitForSlot() returned error: %v", err)
+	}
+	if n.ID != "chrome-node" {
+		t.Errorf("c.WaitForSlot() returned node %q, want chrome-node", n.ID)
+	}
+}
@@ -0,0 +1,199 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ErrNoHistory is returned by BackAndWait and ForwardAndWait when there is no
+// history entry to go to.
+var ErrNoHistory = errors.New("no history entry in that direction")
+
+// historySettleTime is how long the URL of a loaded document must stay the
+// same for a history navigation to be considered over. It covers the pages
+// that redirect or rewrite their URL once loaded.
+const historySettleTime = 250 * time.Millisecond
+
+// HistoryResult describes the document reached by BackAndWait,
+// ForwardAndWait or RefreshAndWait.
+type HistoryResult struct {
+	// URL is the URL of the document, once it settled.
+	URL string
+	// Title is the title of the document.
+	Title string
+}
+
+// BeforeUnloadError is returned by the history navigation helpers when a
+// beforeunload prompt was raised, or when the navigation was canceled as a
+// dismissed prompt does. The WebDriver specification leaves the
+// prompt to the unhandled prompt behavior of the session, which decides
+// whether the navigation proceeds.
+type BeforeUnloadError struct {
+	// Command is the navigation: "back", "forward" or "refresh".
+	Command string
+	// Behavior is the behavior of the prompt handler of the session for the
+	// beforeunload prompts, as returned by the remote end, or empty if it was
+	// not returned.
+	Behavior string
+	// Err is the error returned by the remote end, if any.
+	Err error
+}
+
+func (e *BeforeUnloadError) Error() string {
+	behavior := "not reported by the remote end"
+	if e.Behavior != "" {
+		behavior = strconv.Quote(e.Behavior)
+	}
+	msg := fmt.Sprintf("%s: a beforeunload prompt blocked the navigation; the unhandledPromptBehavior capability of the session (%s) decides whether it proceeds", e.Command, behavior)
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *BeforeUnloadError) Unwrap() error {
+	return e.Err
+}
+
+// isUnexpectedAlertError reports whether err is the error returned when a
+// user prompt is open.
+func isUnexpectedAlertError(err error) bool {
+	return errors.Is(err, werror.UnexpectedAlertOpen)
+}
+
+// historyStateScript reports the state of the current document. The
+// document is marked with arguments[0] if arguments[1] is true, so that a
+// later call tells whether the document was replaced. canGoBack and
+// canGoForward come from the Navigation API, which not all browsers
+// implement.
+const historyStateScript = `
+	var nav = window.navigation;
+	var known = nav && typeof nav.canGoBack === 'boolean';
+	var state = {
+		url: window.location.href,
+		title: document.title,
+		ready: document.readyState,
+		marked: window.__seleniumHistoryMarker === arguments[0],
+		canGoBack: known ? nav.canGoBack : null,
+		canGoForward: known ? nav.canGoForward : null,
+		beforeUnload: typeof window.onbeforeunload === 'function'
+	};
+	if (arguments[1]) {
+		window.__seleniumHistoryMarker = arguments[0];
+	}
+	return state;`
+
+type historyState struct {
+	URL          string
+	Title        string
+	Ready        string
+	Marked       bool
+	CanGoBack    *bool
+	CanGoForward *bool
+	BeforeUnload bool
+}
+
+// historyState runs historyStateScript.
+func (wd *remoteWD) historyState(marker string, mark bool) (historyState, error) {
+	data, err := wd.ExecuteScriptRaw(historyStateScript, []interface{}{marker, mark})
+	if err != nil {
+		return historyState{}, err
+	}
+	reply := new(struct{ Value historyState })
+	if err := json.Unmarshal(data, reply); err != nil {
+		return historyState{}, err
+	}
+	return reply.Value, nil
+}
+
+// BackAndWait moves backward in history and waits for the resulting document.
+func (wd *remoteWD) BackAndWait(timeout time.Duration) (HistoryResult, error) {
+	return wd.historyNavigate("back", timeout)
+}
+
+// ForwardAndWait moves forward in history and waits for the resulting
+// document.
+func (wd *remoteWD) ForwardAndWait(timeout time.Duration) (HistoryResult, error) {
+	return wd.historyNavigate("forward", timeout)
+}
+
+// RefreshAndWait refreshes the page and waits for the new document.
+func (wd *remoteWD) RefreshAndWait(timeout time.Duration) (HistoryResult, error) {
+	return wd.historyNavigate("refresh", timeout)
+}
+
+// historyNavigate sends the given navigation command, then waits until the
+// document is loaded and its URL settled.
+func (wd *remoteWD) historyNavigate(command string, timeout time.Duration) (HistoryResult, error) {
+	if timeout == 0 {
+		timeout = wd.defaults.WaitTimeout
+	}
+	promptError := func(err error) error {
+		h := wd.PromptHandler()
+		return &BeforeUnloadError{Command: command, Behavior: string(h.behavior(h.BeforeUnload)), Err: err}
+	}
+
+	marker := strconv.FormatInt(time.Now().UnixNano(), 36)
+	before, err := wd.historyState(marker, true)
+	if err != nil {
+		return HistoryResult{}, err
+	}
+	can := before.CanGoBack
+	if command == "forward" {
+		can = before.CanGoForward
+	}
+	if command != "refresh" && can != nil && !*can {
+		return HistoryResult{}, fmt.Errorf("%s: %w", command, ErrNoHistory)
+	}
+
+	if err := wd.voidCommand("/session/%s/"+command, nil); err != nil {
+		if isUnexpectedAlertError(err) {
+			return HistoryResult{}, promptError(err)
+		}
+		return HistoryResult{}, err
+	}
+
+	var state historyState
+	lastURL := ""
+	var since time.Time
+	err = wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		s, err := wd.historyState(marker, false)
+		if err != nil {
+			if errors.Is(err, werror.JavascriptError) {
+				// The document is being replaced.
+				lastURL = ""
+				return false, nil
+			}
+			return false, err
+		}
+		now := time.Now()
+		if s.Ready != "complete" || s.URL != lastURL {
+			lastURL, since = s.URL, now
+			return false, nil
+		}
+		state = s
+		return now.Sub(since) >= historySettleTime, nil
+	}, timeout)
+	if err != nil {
+		if isUnexpectedAlertError(err) {
+			return HistoryResult{}, promptError(err)
+		}
+		return HistoryResult{}, fmt.Errorf("%s: waiting for the document: %w", command, err)
+	}
+
+	if state.Marked && state.URL == before.URL {
+		// The document was neither replaced nor moved to another entry. If
+		// there was an entry to go to, the navigation was canceled, which only
+		// a dismissed beforeunload prompt does.
+		if command == "refresh" || before.BeforeUnload || can != nil {
+			return HistoryResult{}, promptError(nil)
+		}
+		return HistoryResult{}, fmt.Errorf("%s: %w", command, ErrNoHistory)
+	}
+	return HistoryResult{URL: state.URL, Title: state.Title}, nil
+}
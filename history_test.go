@@ -0,0 +1,143 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// fakeHistory simulates the session history of a tab on a fake driver.
+type fakeHistory struct {
+	mu      sync.Mutex
+	entries []string
+	pos     int
+	marker  string
+	// navigationAPI makes the state script report canGoBack and canGoForward.
+	navigationAPI bool
+	// beforeUnload makes the page cancel the navigations, as a dismissed
+	// beforeunload prompt does.
+	beforeUnload bool
+}
+
+func (h *fakeHistory) install(d *fakeDriver) {
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		args := new(struct{ Args []interface{} })
+		json.Unmarshal(body, args)
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		marker, _ := args.Args[0].(string)
+		state := map[string]interface{}{
+			"url":          h.entries[h.pos],
+			"title":        "page " + h.entries[h.pos],
+			"ready":        "complete",
+			"marked":       h.marker == marker,
+			"canGoBack":    nil,
+			"canGoForward": nil,
+			"beforeUnload": h.beforeUnload,
+		}
+		if h.navigationAPI {
+			state["canGoBack"] = h.pos > 0
+			state["canGoForward"] = h.pos < len(h.entries)-1
+		}
+		if mark, _ := args.Args[1].(bool); mark {
+			h.marker = marker
+		}
+		return http.StatusOK, state
+	})
+	move := func(delta int) fakeHandler {
+		return func([]byte) (int, interface{}) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.beforeUnload {
+				return http.StatusOK, nil
+			}
+			if p := h.pos + delta; p >= 0 && p < len(h.entries) {
+				h.pos = p
+				h.marker = ""
+			}
+			return http.StatusOK, nil
+		}
+	}
+	d.handle("POST", "/back", move(-1))
+	d.handle("POST", "/forward", move(1))
+	d.handle("POST", "/refresh", move(0))
+}
+
+func TestHistoryNavigation(t *testing.T) {
+	for _, navigationAPI := range []bool{false, true} {
+		d := newFakeDriver(t, nil)
+		h := &fakeHistory{entries: []string{"https://a/", "https://b/"}, pos: 1, navigationAPI: navigationAPI}
+		h.install(d)
+		wd := d.newRemote(t)
+
+		got, err := wd.BackAndWait(0)
+		if err != nil {
+			t.Fatalf("navigationAPI=%t: wd.BackAndWait() returned error: %v", navigationAPI, err)
+		}
+		if want := (HistoryResult{URL: "https://a/", Title: "page https://a/"}); got != want {
+			t.Errorf("navigationAPI=%t: wd.BackAndWait() = %+v, want %+v", navigationAPI, got, want)
+		}
+
+		backs := d.count("POST", "/back")
+		if _, err := wd.BackAndWait(0); !errors.Is(err, ErrNoHistory) {
+			t.Errorf("navigationAPI=%t: wd.BackAndWait() at the first entry returned error %v, want ErrNoHistory", navigationAPI, err)
+		}
+		if sent := d.count("POST", "/back") > backs; sent == navigationAPI {
+			t.Errorf("navigationAPI=%t: Back command sent at the first entry: %t", navigationAPI, sent)
+		}
+
+		got, err = wd.ForwardAndWait(0)
+		if err != nil {
+			t.Fatalf("navigationAPI=%t: wd.ForwardAndWait() returned error: %v", navigationAPI, err)
+		}
+		if got.URL != "https://b/" {
+			t.Errorf("navigationAPI=%t: wd.ForwardAndWait().URL = %q, want %q", navigationAPI, got.URL, "https://b/")
+		}
+		if _, err := wd.ForwardAndWait(0); !errors.Is(err, ErrNoHistory) {
+			t.Errorf("navigationAPI=%t: wd.ForwardAndWait() at the last entry returned error %v, want ErrNoHistory", navigationAPI, err)
+		}
+
+		got, err = wd.RefreshAndWait(0)
+		if err != nil {
+			t.Fatalf("navigationAPI=%t: wd.RefreshAndWait() returned error: %v", navigationAPI, err)
+		}
+		if got.URL != "https://b/" {
+			t.Errorf("navigationAPI=%t: wd.RefreshAndWait().URL = %q, want %q", navigationAPI, got.URL, "https://b/")
+		}
+	}
+}
+
+func TestHistoryNavigationBeforeUnload(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"unhandledPromptBehavior": "dismiss"})
+	h := &fakeHistory{entries: []string{"https://a/", "https://b/"}, pos: 1, beforeUnload: true}
+	h.install(d)
+	wd := d.newRemote(t)
+
+	for name, navigate := range map[string]func() (HistoryResult, error){
+		"BackAndWait":    func() (HistoryResult, error) { return wd.BackAndWait(0) },
+		"RefreshAndWait": func() (HistoryResult, error) { return wd.RefreshAndWait(0) },
+	} {
+		_, err := navigate()
+		var e *BeforeUnloadError
+		if !errors.As(err, &e) {
+			t.Fatalf("wd.%s() returned error %v, want a *BeforeUnloadError", name, err)
+		}
+		if e.Behavior != "dismiss" {
+			t.Errorf("wd.%s() returned error with Behavior %q, want %q", name, e.Behavior, "dismiss")
+		}
+	}
+
+	d.handle("POST", "/back", func([]byte) (int, interface{}) {
+		return http.StatusInternalServerError, map[string]string{
+			"error":   "unexpected alert open",
+			"message": "unexpected alert open",
+		}
+	})
+	_, err := wd.BackAndWait(0)
+	var e *BeforeUnloadError
+	if !errors.As(err, &e) || e.Err == nil {
+		t.Errorf("wd.BackAndWait() with an open prompt returned error %v, want a *BeforeUnloadError wrapping the remote error", err)
+	}
+}
@@ -0,0 +1,31 @@
+package selenium
+
+import (
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// MapHosts makes the browser resolve the host names that are the keys of
+// rules to their values, e.g. to serve "www.example.test" from a test server
+// listening on 127.0.0.1, without changing the hosts file of the machine:
+// Chrome and Edge take any address, Firefox loopback addresses only. See
+// chrome.Capabilities.MapHosts and firefox.Capabilities.MapHosts for the
+// rules. It changes the browser options like SetUserAgent.
+func (c Capabilities) MapHosts(rules map[string]string) error {
+	var mapErr error
+	if err := c.updateBrowserOptions(
+		func(o *chrome.Capabilities) {
+			if err := o.MapHosts(rules); err != nil && mapErr == nil {
+				mapErr = err
+			}
+		},
+		func(o *firefox.Capabilities) {
+			if err := o.MapHosts(rules); err != nil && mapErr == nil {
+				mapErr = err
+			}
+		},
+	); err != nil {
+		return err
+	}
+	return mapErr
+}
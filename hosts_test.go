@@ -0,0 +1,41 @@
+package selenium
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+func TestCapabilitiesMapHosts(t *testing.T) {
+	f := &firefox.Capabilities{Prefs: map[string]interface{}{"network.dns.forceResolve": "10.0.0.1"}}
+	caps := Capabilities{"browserName": "firefox", firefox.CapabilitiesKey: f}
+	if err := caps.MapHosts(map[string]string{"www.example.test": "127.0.0.1", "api.example.test": "::1"}); err != nil {
+		t.Fatalf("caps.MapHosts() returned error: %v", err)
+	}
+	if want := map[string]interface{}{"network.dns.localDomains": "api.example.test,www.example.test"}; !reflect.DeepEqual(f.Prefs, want) {
+		t.Errorf("Firefox prefs = %v, want %v", f.Prefs, want)
+	}
+	if err := caps.MapHosts(map[string]string{"*": "192.0.2.1"}); err != nil {
+		t.Fatalf("caps.MapHosts() of all hosts returned error: %v", err)
+	}
+	if want := map[string]interface{}{"network.dns.forceResolve": "192.0.2.1"}; !reflect.DeepEqual(f.Prefs, want) {
+		t.Errorf("Firefox prefs = %v, want %v", f.Prefs, want)
+	}
+
+	for _, rule := range []map[string]string{
+		{"www.example.test": "192.0.2.1"},
+		{"www.example.test": "127.0.0.1:8080"},
+		{"*.example.test": "127.0.0.1"},
+		{"*": "backend.internal"},
+	} {
+		if err := caps.MapHosts(rule); err == nil {
+			t.Errorf("caps.MapHosts(%q) for Firefox returned no error", rule)
+		}
+	}
+
+	caps = Capabilities{"browserName": "chrome"}
+	if err := caps.MapHosts(map[string]string{"localhost": "127.0.0.1"}); err == nil {
+		t.Error("caps.MapHosts() of localhost for Chrome returned no error")
+	}
+}
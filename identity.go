@@ -0,0 +1,58 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+)
+
+func (elem *remoteWE) ID() string {
+	return elem.id
+}
+
+func (wd *remoteWD) ElementFromID(id string) WebElement {
+	return &remoteWE{parent: wd, id: id}
+}
+
+// SameElement reports whether a and b refer to the same DOM node. Both must
+// belong to the same session; comparing elements of different sessions is an
+// error.
+//
+// W3C remote ends assign a node the same reference for its lifetime, so the
+// references are compared without a round trip. For legacy remote ends, the
+// identity is checked by script. LazyElements are resolved first.
+func SameElement(a, b WebElement) (bool, error) {
+	a, err := unwrapLazy(a)
+	if err != nil {
+		return false, err
+	}
+	b, err = unwrapLazy(b)
+	if err != nil {
+		return false, err
+	}
+	ea, ok := a.(*remoteWE)
+	if !ok {
+		return false, fmt.Errorf("cannot compare element of type %T", a)
+	}
+	eb, ok := b.(*remoteWE)
+	if !ok {
+		return false, fmt.Errorf("cannot compare element of type %T", b)
+	}
+	wd := ea.parent
+	if wd != eb.parent && (wd.id != eb.parent.id || wd.urlPrefix != eb.parent.urlPrefix) {
+		return false, fmt.Errorf("elements %q and %q belong to different sessions, %q and %q", ea.id, eb.id, wd.id, eb.parent.id)
+	}
+	if wd.id == "" {
+		return false, errors.New("the session of the elements has ended")
+	}
+	if ea.id == eb.id {
+		return true, nil
+	}
+	if wd.w3cCompatible {
+		return false, nil
+	}
+	same, err := wd.ExecuteScript("return arguments[0] === arguments[1];", []interface{}{ea, eb})
+	if err != nil {
+		return false, err
+	}
+	return same == true, nil
+}
@@ -0,0 +1,68 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestSameElement(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	wd := d.newRemote(t)
+
+	a := wd.ElementFromID("a")
+	if got := a.ID(); got != "a" {
+		t.Errorf("ElementFromID(%q).ID() = %q, want %q", "a", got, "a")
+	}
+	for _, test := range []struct {
+		desc string
+		a, b WebElement
+		want bool
+	}{
+		{"same reference", a, wd.ElementFromID("a"), true},
+		{"different references", a, wd.ElementFromID("b"), false},
+	} {
+		got, err := SameElement(test.a, test.b)
+		if err != nil {
+			t.Fatalf("%s: SameElement() returned error: %v", test.desc, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: SameElement() = %t, want %t", test.desc, got, test.want)
+		}
+	}
+	if n := len(d.requests); n != 1 {
+		t.Errorf("SameElement() sent %d requests after New Session, want none", n-1)
+	}
+
+	other := newFakeDriver(t, nil).newRemote(t)
+	if _, err := SameElement(a, other.ElementFromID("a")); err == nil {
+		t.Error("SameElement() with elements of different sessions returned no error")
+	}
+}
+
+func TestSameElementLegacy(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.legacy = true
+	d.handle("POST", "/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Args []map[string]string
+		}
+		if err := json.Unmarshal(body, &params); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		// Both references designate the same node.
+		return http.StatusOK, len(params.Args) == 2
+	})
+	wd := d.newRemote(t)
+
+	same, err := SameElement(wd.ElementFromID("a"), wd.ElementFromID("b"))
+	if err != nil {
+		t.Fatalf("SameElement() returned error: %v", err)
+	}
+	if !same {
+		t.Error("SameElement() = false, want the result of the identity script")
+	}
+	if n := d.count("POST", "/execute"); n != 1 {
+		t.Errorf("SameElement() executed %d scripts, want 1", n)
+	}
+}
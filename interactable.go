@@ -0,0 +1,223 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Checks run by WebElement.EnsureInteractable, in order.
+const (
+	// CheckDisplayed fails if the element is not rendered or is hidden by
+	// its style.
+	CheckDisplayed = "displayed"
+	// CheckEnabled fails if the element is a disabled form control.
+	CheckEnabled = "enabled"
+	// CheckSize fails if the element has no width or no height.
+	CheckSize = "non-zero size"
+	// CheckNotCovered fails if another element, which is not one of the
+	// element's descendants, is at the center point of the element.
+	CheckNotCovered = "not covered"
+)
+
+// NotInteractableError is returned by WebElement.EnsureInteractable with the
+// first check that failed.
+type NotInteractableError struct {
+	// Check is one of the Check constants.
+	Check string
+	// Detail describes the failure, e.g. the element covering the center
+	// point of the element.
+	Detail string
+}
+
+func (e *NotInteractableError) Error() string {
+	msg := fmt.Sprintf("element not interactable: %s check failed", e.Check)
+	if e.Detail != "" {
+		msg += ": " + e.Detail
+	}
+	return msg
+}
+
+// ActionOption configures an action on an element, such as a click.
+type ActionOption func(*actionOptions)
+
+type actionOptions struct {
+	precheck     bool
+	timeout      time.Duration
+	clickRetries *int
+}
+
+func actionOptionsOf(opts []ActionOption) actionOptions {
+	var o actionOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPrecheck makes the action call EnsureInteractable first, and fail with
+// its error instead of waiting for the remote end to give up.
+func WithPrecheck() ActionOption {
+	return func(o *actionOptions) {
+		o.precheck = true
+	}
+}
+
+// WithPrecheckTimeout is like WithPrecheck, but runs the checks until they
+// pass or until timeout has elapsed, e.g. to wait for an overlay to fade out.
+func WithPrecheckTimeout(timeout time.Duration) ActionOption {
+	return func(o *actionOptions) {
+		o.precheck = true
+		o.timeout = timeout
+	}
+}
+
+// precheck runs EnsureInteractable as requested by opts.
+func (elem *remoteWE) precheck(opts []ActionOption) error {
+	o := actionOptionsOf(opts)
+	if !o.precheck {
+		return nil
+	}
+	polls := 0
+	if o.timeout > 0 {
+		end := elem.parent.startWait()
+		defer func() { end(polls) }()
+	}
+	deadline := time.Now().Add(o.timeout)
+	for {
+		polls++
+		err := elem.EnsureInteractable()
+		if _, ok := err.(*NotInteractableError); !ok || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(elem.parent.defaults.PollInterval)
+	}
+}
+
+// interactableScript checks the element passed as its argument. It returns
+// the failed check and its details, or, if all the checks passed, the center
+// point of the element in the viewport of its document.
+const interactableScript = `
+	var el = arguments[0];
+	var describe = function(e) {
+		var s = e.tagName.toLowerCase();
+		if (e.id) {
+			s += '#' + e.id;
+		}
+		if (typeof e.className === 'string' && e.className.trim()) {
+			s += '.' + e.className.trim().split(/\s+/).join('.');
+		}
+		return s;
+	};
+
+	var style = window.getComputedStyle(el);
+	var visible = el.checkVisibility ?
+		el.checkVisibility({opacityProperty: true, visibilityProperty: true}) :
+		el.getClientRects().length > 0 && style.visibility === 'visible' && style.opacity !== '0';
+	if (!visible) {
+		return {check: 'displayed', detail: 'display ' + style.display + ', visibility ' + style.visibility + ', opacity ' + style.opacity};
+	}
+	if (el.matches && el.matches(':disabled')) {
+		return {check: 'enabled'};
+	}
+	var rect = el.getBoundingClientRect();
+	if (rect.width === 0 || rect.height === 0) {
+		return {check: 'non-zero size', detail: rect.width + 'x' + rect.height};
+	}
+
+	var view = el.ownerDocument.defaultView;
+	var x = rect.left + rect.width / 2, y = rect.top + rect.height / 2;
+	if (x < 0 || y < 0 || x >= view.innerWidth || y >= view.innerHeight) {
+		el.scrollIntoView({block: 'center', inline: 'center'});
+		rect = el.getBoundingClientRect();
+		x = rect.left + rect.width / 2;
+		y = rect.top + rect.height / 2;
+	}
+	var root = el.getRootNode && el.getRootNode().elementFromPoint ? el.getRootNode() : el.ownerDocument;
+	var hit = root.elementFromPoint(x, y);
+	if (!hit) {
+		return {check: 'not covered', detail: 'the center point (' + x + ', ' + y + ') is outside of the viewport'};
+	}
+	if (hit !== el && !el.contains(hit)) {
+		return {check: 'not covered', detail: 'covered by ' + describe(hit) + ' at (' + x + ', ' + y + ')'};
+	}
+	return {x: x, y: y};`
+
+// frameCoverScript checks that the frame with the index given as the first
+// argument is not covered at the point of its viewport given by the other
+// arguments. It returns the failed check, or the point in the viewport of the
+// current document.
+const frameCoverScript = `
+	var index = arguments[0], x = arguments[1], y = arguments[2];
+	var frames = document.querySelectorAll('iframe, frame');
+	var frame = null;
+	for (var i = 0; i < frames.length; i++) {
+		if (frames[i].contentWindow === window.frames[index]) {
+			frame = frames[i];
+			break;
+		}
+	}
+	if (!frame) {
+		return {};
+	}
+	var rect = frame.getBoundingClientRect();
+	var style = window.getComputedStyle(frame);
+	x += rect.left + frame.clientLeft + parseFloat(style.paddingLeft);
+	y += rect.top + frame.clientTop + parseFloat(style.paddingTop);
+	var hit = document.elementFromPoint(x, y);
+	if (hit !== frame) {
+		var what = hit ? hit.tagName.toLowerCase() + (hit.id ? '#' + hit.id : '') : 'nothing';
+		return {check: 'not covered', detail: 'the frame is covered by ' + what + ' at (' + x + ', ' + y + ')'};
+	}
+	return {x: x, y: y};`
+
+type interactableResult struct {
+	Check, Detail string
+	X, Y          float64
+}
+
+func (elem *remoteWE) runInteractableScript(script string, args []interface{}) (interactableResult, error) {
+	data, err := elem.parent.ExecuteScriptRaw(script, args)
+	if err != nil {
+		return interactableResult{}, err
+	}
+	reply := new(struct{ Value interactableResult })
+	if err := json.Unmarshal(data, reply); err != nil {
+		return interactableResult{}, err
+	}
+	return reply.Value, nil
+}
+
+// EnsureInteractable runs the checks of the element that a user interaction
+// would require.
+func (elem *remoteWE) EnsureInteractable() error {
+	if elem.frame == nil {
+		return elem.ensureInteractable()
+	}
+	// The element is in a known frame: check it there, and check that the
+	// frames containing it are not covered in their parent documents.
+	return elem.parent.WithFrame(elem.frame, elem.ensureInteractable)
+}
+
+func (elem *remoteWE) ensureInteractable() error {
+	r, err := elem.runInteractableScript(interactableScript, []interface{}{elem})
+	if err != nil {
+		return err
+	}
+	if r.Check != "" {
+		return &NotInteractableError{Check: r.Check, Detail: r.Detail}
+	}
+	for depth := len(elem.frame); depth > 0; depth-- {
+		if err := elem.parent.switchToParentFrame(); err != nil {
+			return err
+		}
+		r, err = elem.runInteractableScript(frameCoverScript, []interface{}{elem.frame[depth-1], r.X, r.Y})
+		if err != nil {
+			return err
+		}
+		if r.Check != "" {
+			return &NotInteractableError{Check: r.Check, Detail: r.Detail}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnsureInteractable(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		result    map[string]interface{}
+		wantCheck string
+	}{
+		{"interactable", map[string]interface{}{"x": 10, "y": 10}, ""},
+		{"hidden", map[string]interface{}{"check": CheckDisplayed, "detail": "display none"}, CheckDisplayed},
+		{"covered", map[string]interface{}{"check": CheckNotCovered, "detail": "covered by div#overlay"}, CheckNotCovered},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+				return http.StatusOK, test.result
+			})
+			d.handle("POST", "/element/e/click", func([]byte) (int, interface{}) {
+				return http.StatusOK, nil
+			})
+			elem := d.newRemote(t).ElementFromID("e")
+
+			err := elem.EnsureInteractable()
+			var e *NotInteractableError
+			if test.wantCheck == "" {
+				if err != nil {
+					t.Fatalf("elem.EnsureInteractable() returned error: %v", err)
+				}
+			} else if !errors.As(err, &e) || e.Check != test.wantCheck {
+				t.Fatalf("elem.EnsureInteractable() returned error %v, want a %q check failure", err, test.wantCheck)
+			}
+
+			clickErr := elem.Click(WithPrecheck())
+			if (clickErr == nil) != (test.wantCheck == "") {
+				t.Errorf("elem.Click(WithPrecheck()) returned error %v, want %v", clickErr, err)
+			}
+			wantClicks := 0
+			if test.wantCheck == "" {
+				wantClicks = 1
+			}
+			if n := d.count("POST", "/element/e/click"); n != wantClicks {
+				t.Errorf("elem.Click(WithPrecheck()) clicked %d times, want %d", n, wantClicks)
+			}
+		})
+	}
+}
+
+func TestPrecheckTimeout(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	calls := 0
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		// The overlay goes away after two checks.
+		calls++
+		if calls <= 2 {
+			return http.StatusOK, map[string]interface{}{"check": CheckNotCovered, "detail": "covered by div#overlay"}
+		}
+		return http.StatusOK, map[string]interface{}{"x": 10, "y": 10}
+	})
+	d.handle("POST", "/element/e/value", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	elem := d.newRemote(t).ElementFromID("e")
+
+	if err := elem.SendKeys("a", WithPrecheckTimeout(time.Second)); err != nil {
+		t.Fatalf("elem.SendKeys() returned error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("elem.SendKeys() ran the checks %d times, want 3", calls)
+	}
+}
+
+func TestEnsureInteractableInFrame(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var frames []interface{}
+	d.handle("POST", "/frame", func(body []byte) (int, interface{}) {
+		var params struct{ ID interface{} }
+		json.Unmarshal(body, &params)
+		frames = append(frames, params.ID)
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/frame/parent", func([]byte) (int, interface{}) {
+		frames = append(frames, "parent")
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct {
+			Script string
+			Args   []interface{}
+		}
+		json.Unmarshal(body, &params)
+		switch {
+		case params.Script == currentFramePathScript:
+			return http.StatusOK, []int{}
+		case params.Script == frameCoverScript:
+			if got := params.Args[0]; got != float64(1) {
+				t.Errorf("frame cover script called for frame %v, want 1", got)
+			}
+			return http.StatusOK, map[string]interface{}{"check": CheckNotCovered, "detail": "the frame is covered by div#modal"}
+		}
+		return http.StatusOK, map[string]interface{}{"x": 10, "y": 10}
+	})
+	wd := d.newRemote(t)
+	elem := &remoteWE{parent: wd, id: "e", frame: FramePath{1}}
+
+	err := elem.EnsureInteractable()
+	var e *NotInteractableError
+	if !errors.As(err, &e) || !strings.Contains(e.Detail, "frame") {
+		t.Fatalf("elem.EnsureInteractable() returned error %v, want the frame to be covered", err)
+	}
+	// Switch to the element's frame, up to its parent and back to the top.
+	want := []interface{}{nil, float64(1), "parent", nil}
+	if len(frames) != len(want) {
+		t.Fatalf("switched frames %v, want %v", frames, want)
+	}
+	for i := range want {
+		if frames[i] != want[i] {
+			t.Fatalf("switched frames %v, want %v", frames, want)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package seleniumtest
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// Factory returns a new session and a function that releases whatever the
+// session needs, e.g. a driver process. The cleanup function is called after
+// the session was quit, possibly more than once. A nil session fails the
+// check that asked for it.
+type Factory func() (selenium.WebDriver, func())
+
+// RemoteFactory returns a Factory that creates sessions on the remote end
+// described by c. Errors are reported to t.
+func RemoteFactory(t *testing.T, c Config) Factory {
+	caps := newTestCapabilities(t, c)
+	return func() (selenium.WebDriver, func()) {
+		wd, err := NewRemote(t, caps, c.Addr)
+		if err != nil {
+			t.Errorf("NewRemote(%+v, %q) returned error: %v", caps, c.Addr, err)
+			return nil, func() {}
+		}
+		return wd, func() { wd.Quit() }
+	}
+}
+
+// RunConformance checks that the implementations of selenium.WebDriver
+// returned by factory behave like the remote driver of this package: wrappers
+// and fakes can run it to verify that they keep the contracts of the
+// interface. The pages are served by a server started for the run, on the
+// loopback interface.
+//
+// Cookies, windows and frames are optional: their checks are skipped if a
+// first call reports that the implementation does not support them, with
+// selenium.ErrUnsupported or an "unknown command" error. So are the checks
+// of the optional features reported by WebDriver.Supports.
+func RunConformance(t *testing.T, factory Factory) {
+	hs := httptest.NewServer(Handler)
+	defer hs.Close()
+	c := conformance{factory: factory, serverURL: hs.URL}
+	t.Run("Navigation", c.run(conformNavigation))
+	t.Run("FindElement", c.run(conformFindElement))
+	t.Run("Interaction", c.run(conformInteraction))
+	t.Run("Cookies", c.run(conformCookies))
+	t.Run("Windows", c.run(conformWindows))
+	t.Run("Frames", c.run(conformFrames))
+	t.Run("ExecuteScript", c.run(conformExecuteScript))
+	t.Run("NoSuchElementError", c.run(conformNoSuchElementError))
+	t.Run("StaleElementError", c.run(conformStaleElementError))
+	t.Run("ElementScreenshot", c.run(conformElementScreenshot))
+	t.Run("Quit", c.testQuit)
+}
+
+type conformance struct {
+	factory   Factory
+	serverURL string
+}
+
+// newSession calls the factory, and fails the test if it returned no session.
+func (c conformance) newSession(t *testing.T) (selenium.WebDriver, func()) {
+	t.Helper()
+	wd, cleanup := c.factory()
+	if wd == nil {
+		cleanup()
+		t.Fatal("the factory returned no session")
+	}
+	return wd, cleanup
+}
+
+// run returns a test that runs f on a new session, and quits it afterwards.
+func (c conformance) run(f func(*testing.T, selenium.WebDriver, string)) func(*testing.T) {
+	return func(t *testing.T) {
+		wd, cleanup := c.newSession(t)
+		defer cleanup()
+		defer quitRemote(t, wd)
+		f(t, wd, c.serverURL)
+	}
+}
+
+// isUnsupportedError reports whether err tells that the implementation does
+// not support a command.
+func isUnsupportedError(err error) bool {
+	if errors.Is(err, selenium.ErrUnsupported) {
+		return true
+	}
+	var e *selenium.Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch e.Err {
+	case "unknown command", "unknown method", "unsupported operation":
+		return true
+	}
+	return false
+}
+
+// probe skips the test if err tells that the optional feature is not
+// supported, and fails it on any other error.
+func probe(t *testing.T, feature string, err error) {
+	t.Helper()
+	if isUnsupportedError(err) {
+		t.Skipf("%s not supported: %v", feature, err)
+	}
+	if err != nil {
+		t.Fatalf("probing %s: %v", feature, err)
+	}
+}
+
+func mustGet(t *testing.T, wd selenium.WebDriver, url string) {
+	t.Helper()
+	if err := wd.Get(url); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", url, err)
+	}
+}
+
+func mustFind(t *testing.T, wd selenium.WebDriver, by, value string) selenium.WebElement {
+	t.Helper()
+	elem, err := wd.FindElement(by, value)
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", by, value, err)
+	}
+	return elem
+}
+
+func conformNavigation(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	home, other := serverURL+"/", serverURL+"/other"
+	mustGet(t, wd, home)
+	mustGet(t, wd, other)
+
+	for _, step := range []struct {
+		name    string
+		command func() error
+		url     string
+		title   string
+	}{
+		{"Back", wd.Back, home, "Go Selenium Test Suite"},
+		{"Forward", wd.Forward, other, "Go Selenium Test Suite - Other Page"},
+		{"Refresh", wd.Refresh, other, "Go Selenium Test Suite - Other Page"},
+	} {
+		if err := step.command(); err != nil {
+			t.Fatalf("wd.%s() returned error: %v", step.name, err)
+		}
+		url, err := wd.CurrentURL()
+		if err != nil {
+			t.Fatalf("wd.CurrentURL() returned error: %v", err)
+		}
+		if url != step.url {
+			t.Errorf("wd.CurrentURL() after wd.%s() = %q, want %q", step.name, url, step.url)
+		}
+		title, err := wd.Title()
+		if err != nil {
+			t.Fatalf("wd.Title() returned error: %v", err)
+		}
+		if title != step.title {
+			t.Errorf("wd.Title() after wd.%s() = %q, want %q", step.name, title, step.title)
+		}
+	}
+}
+
+func conformFindElement(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL)
+
+	form := mustFind(t, wd, selenium.ByTagName, "form")
+	option, err := form.FindElement(selenium.ByID, "secondValue")
+	if err != nil {
+		t.Fatalf("form.FindElement(%q, %q) returned error: %v", selenium.ByID, "secondValue", err)
+	}
+	if got, err := option.GetAttribute("value"); err != nil || got != "second_value" {
+		t.Errorf("option.GetAttribute(%q) = %q, %v, want %q", "value", got, err, "second_value")
+	}
+
+	options, err := wd.FindElements(selenium.ByCSSSelector, "select option")
+	if err != nil {
+		t.Fatalf("wd.FindElements(%q, %q) returned error: %v", selenium.ByCSSSelector, "select option", err)
+	}
+	if len(options) != 2 {
+		t.Errorf("wd.FindElements(%q, %q) returned %d elements, want 2", selenium.ByCSSSelector, "select option", len(options))
+	}
+
+	none, err := wd.FindElements(selenium.ByID, "no-such-element")
+	if err != nil {
+		t.Fatalf("wd.FindElements(%q, %q) returned error: %v", selenium.ByID, "no-such-element", err)
+	}
+	if len(none) != 0 {
+		t.Errorf("wd.FindElements(%q, %q) returned %d elements, want none", selenium.ByID, "no-such-element", len(none))
+	}
+}
+
+func conformInteraction(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL+"/input")
+
+	text := mustFind(t, wd, selenium.ByID, "text")
+	const input = "conformance"
+	if err := text.SendKeys(input); err != nil {
+		t.Fatalf("text.SendKeys(%q) returned error: %v", input, err)
+	}
+	mirror := mustFind(t, wd, selenium.ByID, "mirror")
+	if got, err := mirror.GetAttribute("data-value"); err != nil || got != input {
+		t.Errorf("mirror.GetAttribute(%q) = %q, %v, want %q", "data-value", got, err, input)
+	}
+
+	mustGet(t, wd, serverURL)
+	checkbox := mustFind(t, wd, selenium.ByID, "chuk")
+	if err := checkbox.Click(); err != nil {
+		t.Fatalf("checkbox.Click() returned error: %v", err)
+	}
+	if selected, err := checkbox.IsSelected(); err != nil || !selected {
+		t.Errorf("checkbox.IsSelected() after a click = %t, %v, want true", selected, err)
+	}
+}
+
+func conformCookies(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL)
+	_, err := wd.GetCookies()
+	probe(t, "cookies", err)
+
+	cookie := &selenium.Cookie{Name: "conformance", Value: "value", Path: "/"}
+	if err := wd.AddCookie(cookie); err != nil {
+		t.Fatalf("wd.AddCookie(%+v) returned error: %v", cookie, err)
+	}
+	got, err := wd.GetCookie(cookie.Name)
+	if err != nil {
+		t.Fatalf("wd.GetCookie(%q) returned error: %v", cookie.Name, err)
+	}
+	if got.Value != cookie.Value {
+		t.Errorf("wd.GetCookie(%q).Value = %q, want %q", cookie.Name, got.Value, cookie.Value)
+	}
+	if err := wd.DeleteCookie(cookie.Name); err != nil {
+		t.Fatalf("wd.DeleteCookie(%q) returned error: %v", cookie.Name, err)
+	}
+	if _, err := wd.GetCookie(cookie.Name); err == nil {
+		t.Errorf("wd.GetCookie(%q) after wd.DeleteCookie() returned no error", cookie.Name)
+	}
+}
+
+func conformWindows(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	handles, err := wd.WindowHandles()
+	probe(t, "windows", err)
+
+	current, err := wd.CurrentWindowHandle()
+	if err != nil {
+		t.Fatalf("wd.CurrentWindowHandle() returned error: %v", err)
+	}
+	found := false
+	for _, h := range handles {
+		found = found || h == current
+	}
+	if !found {
+		t.Errorf("wd.WindowHandles() = %v, want it to contain the current window %q", handles, current)
+	}
+	if err := wd.SwitchWindow(current); err != nil {
+		t.Errorf("wd.SwitchWindow(%q) returned error: %v", current, err)
+	}
+	if err := wd.SwitchWindow("no-such-window"); err == nil {
+		t.Errorf("wd.SwitchWindow(%q) returned no error", "no-such-window")
+	}
+}
+
+func conformFrames(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL+"/frame")
+	probe(t, "frames", wd.SwitchFrame(nil))
+
+	if err := wd.SwitchFrame("iframeID"); err != nil {
+		t.Fatalf("wd.SwitchFrame(%q) returned error: %v", "iframeID", err)
+	}
+	mustFind(t, wd, selenium.ByID, "chuk")
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrame"); err == nil {
+		t.Errorf("wd.FindElement(%q, %q) in the frame returned no error", selenium.ByID, "outsideOfFrame")
+	}
+	if err := wd.SwitchFrame(nil); err != nil {
+		t.Fatalf("wd.SwitchFrame(nil) returned error: %v", err)
+	}
+	mustFind(t, wd, selenium.ByID, "outsideOfFrame")
+}
+
+func conformExecuteScript(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL)
+
+	const script = "return arguments[0] + arguments[1]"
+	got, err := wd.ExecuteScript(script, []interface{}{1, 2})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	if n, ok := got.(float64); !ok || n != 3 {
+		t.Errorf("wd.ExecuteScript(%q) = %#v, want 3", script, got)
+	}
+
+	elem := mustFind(t, wd, selenium.ByID, "chuk")
+	const idScript = "return arguments[0].id"
+	got, err = wd.ExecuteScript(idScript, []interface{}{elem})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", idScript, err)
+	}
+	if got != "chuk" {
+		t.Errorf("wd.ExecuteScript(%q) = %#v, want %q", idScript, got, "chuk")
+	}
+
+	const throwScript = "throw new Error('conformance')"
+	if _, err := wd.ExecuteScript(throwScript, nil); err == nil {
+		t.Errorf("wd.ExecuteScript(%q) returned no error", throwScript)
+	}
+}
+
+// wantError checks that err is an *selenium.Error with the given code.
+func wantError(t *testing.T, call string, err error, code string) {
+	t.Helper()
+	var e *selenium.Error
+	if !errors.As(err, &e) {
+		t.Fatalf("%s returned error %v, want a *selenium.Error", call, err)
+	}
+	if e.Err != code {
+		t.Errorf("%s returned error with code %q, want %q", call, e.Err, code)
+	}
+}
+
+func conformNoSuchElementError(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL)
+	_, err := wd.FindElement(selenium.ByID, "no-such-element")
+	wantError(t, "wd.FindElement(ByID, no-such-element)", err, "no such element")
+
+	form := mustFind(t, wd, selenium.ByTagName, "form")
+	_, err = form.FindElement(selenium.ByID, "no-such-element")
+	wantError(t, "form.FindElement(ByID, no-such-element)", err, "no such element")
+}
+
+func conformStaleElementError(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	mustGet(t, wd, serverURL)
+	elem := mustFind(t, wd, selenium.ByID, "chuk")
+	mustGet(t, wd, serverURL+"/other")
+	_, err := elem.IsSelected()
+	wantError(t, "elem.IsSelected() after leaving the page", err, "stale element reference")
+}
+
+func conformElementScreenshot(t *testing.T, wd selenium.WebDriver, serverURL string) {
+	if !wd.Supports(selenium.FeatureElementScreenshot) {
+		t.Skip("element screenshots not supported")
+	}
+	mustGet(t, wd, serverURL)
+	elem := mustFind(t, wd, selenium.ByID, "chuk")
+	data, err := elem.Screenshot(false)
+	if err != nil {
+		t.Fatalf("elem.Screenshot() returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "\x89PNG") {
+		t.Errorf("elem.Screenshot() did not return a PNG image")
+	}
+}
+
+// testQuit checks that Quit can be called again once the session is over.
+func (c conformance) testQuit(t *testing.T) {
+	wd, cleanup := c.newSession(t)
+	defer cleanup()
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Errorf("wd.Quit() a second time returned error: %v", err)
+	}
+}
@@ -4,8 +4,18 @@
 package seleniumtest
 
 import (
+	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"html"
+	"image/color"
+	"image/png"
+	"io/ioutil"
 	"math"
 	"net"
 	"net/http"
@@ -16,6 +26,8 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"testing"
@@ -26,6 +38,7 @@ import (
 	"github.com/LoveOyy/selenium/firefox"
 	"github.com/LoveOyy/selenium/log"
 	"github.com/LoveOyy/selenium/sauce"
+	"github.com/LoveOyy/selenium/werror"
 	socks5 "github.com/armon/go-socks5"
 	"github.com/blang/semver"
 	"github.com/google/go-cmp/cmp"
@@ -134,16 +147,24 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("Windows", runTest(testWindows, c))
 	t.Run("Get", runTest(testGet, c))
 	t.Run("Navigation", runTest(testNavigation, c))
+	t.Run("Navigate", runTest(testNavigate, c))
 	t.Run("Title", runTest(testTitle, c))
 	t.Run("PageSource", runTest(testPageSource, c))
+	t.Run("ResourceSummary", runTest(testResourceSummary, c))
 	t.Run("FindElement", runTest(testFindElement, c))
 	t.Run("FindElements", runTest(testFindElements, c))
 	t.Run("SendKeys", runTest(testSendKeys, c))
+	t.Run("SetText", runTest(testSetText, c))
+	t.Run("ClearWithEvents", runTest(testClearWithEvents, c))
+	t.Run("FailOnJSErrors", runTest(testFailOnJSErrors, c))
 	t.Run("Click", runTest(testClick, c))
 	t.Run("GetCookies", runTest(testGetCookies, c))
 	t.Run("GetCookie", runTest(testGetCookie, c))
 	t.Run("AddCookie", runTest(testAddCookie, c))
 	t.Run("DeleteCookie", runTest(testDeleteCookie, c))
+	t.Run("NormalizeCookie", runTest(testNormalizeCookie, c))
+	t.Run("Storage", runTest(testStorage, c))
+	t.Run("StorageState", runTest(testStorageState, c))
 	t.Run("Location", runTest(testLocation, c))
 	t.Run("LocationInView", runTest(testLocationInView, c))
 	t.Run("Size", runTest(testSize, c))
@@ -151,6 +172,8 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("ExecuteScriptOnElement", runTest(testExecuteScriptOnElement, c))
 	t.Run("ExecuteScriptWithNilArgs", runTest(testExecuteScriptWithNilArgs, c))
 	t.Run("Screenshot", runTest(testScreenshot, c))
+	t.Run("ScreenshotWithMasks", runTest(testScreenshotWithMasks, c))
+	t.Run("DisableAnimations", runTest(testDisableAnimations, c))
 	t.Run("Log", runTest(testLog, c))
 	t.Run("IsSelected", runTest(testIsSelected, c))
 	t.Run("IsDisplayed", runTest(testIsDisplayed, c))
@@ -158,13 +181,38 @@ func RunCommonTests(t *testing.T, c Config) {
 	t.Run("GetProperty", runTest(testGetProperty, c))
 	t.Run("GetPropertyNotFound", runTest(testGetPropertyNotFound, c))
 	t.Run("KeyDownUp", runTest(testKeyDownUp, c))
+	t.Run("ActionChain", runTest(testActionChain, c))
+	t.Run("RelativeLocator", runTest(testRelativeLocator, c))
+	t.Run("PrintPage", runTest(testPrintPage, c))
+	t.Run("ShadowRoot", runTest(testShadowRoot, c))
+	t.Run("VirtualAuthenticator", runTest(testVirtualAuthenticator, c))
 	t.Run("CSSProperty", runTest(testCSSProperty, c))
 	if !c.SkipProxy {
 		t.Run("Proxy", runTest(testProxy, c))
+		t.Run("VerifyProxy", runTest(testVerifyProxy, c))
 	}
 	t.Run("SwitchFrame", runTest(testSwitchFrame, c))
+	t.Run("FindElementInAnyFrame", runTest(testFindElementInAnyFrame, c))
+	t.Run("SameElement", runTest(testSameElement, c))
+	t.Run("LazyElement", runTest(testLazyElement, c))
+	t.Run("ElementList", runTest(testElementList, c))
+	t.Run("Open", runTest(testOpen, c))
+	t.Run("WatchWindows", runTest(testWatchWindows, c))
+	t.Run("UnicodeText", runTest(testUnicodeText, c))
+	t.Run("PageEncoding", runTest(testPageEncoding, c))
+	t.Run("EnsureInteractable", runTest(testEnsureInteractable, c))
+	t.Run("ClickWhenReady", runTest(testClickWhenReady, c))
 	t.Run("Wait", runTest(testWait, c))
+	t.Run("WaitForText", runTest(testWaitForText, c))
+	t.Run("RouteChanges", runTest(testRouteChanges, c))
+	t.Run("ResetState", runTest(testResetState, c))
+	t.Run("IsolatedContext", runTest(testIsolatedContext, c))
 	t.Run("ActiveElement", runTest(testActiveElement, c))
+	t.Run("TabOrder", runTest(testTabOrder, c))
+	t.Run("AuditPage", runTest(testAuditPage, c))
+	t.Run("UserAgent", runTest(testUserAgent, c))
+	t.Run("MapHosts", runTest(testMapHosts, c))
+	t.Run("BrowserTransport", runTest(testBrowserTransport, c))
 	t.Run("AcceptAlert", runTest(testAcceptAlert, c))
 	t.Run("DismissAlert", runTest(testDismissAlert, c))
 }
@@ -392,6 +440,33 @@ func testWindows(t *testing.T, c Config) {
 		}
 	})
 
+	t.Run("WindowRects", func(t *testing.T) {
+		rects, err := wd.WindowRects()
+		if err != nil {
+			t.Fatalf("wd.WindowRects() returned error: %v", err)
+		}
+		if len(rects) != 2 {
+			t.Fatalf("wd.WindowRects() = %v, expected 2 windows", rects)
+		}
+		current, err := wd.CurrentWindowHandle()
+		if err != nil {
+			t.Fatalf("wd.CurrentWindowHandle() returned error: %v", err)
+		}
+		if current != firstHandle {
+			t.Errorf("wd.WindowRects() left window %q current, expected %q", current, firstHandle)
+		}
+	})
+
+	t.Run("ScreenshotAllWindows", func(t *testing.T) {
+		shots, err := wd.ScreenshotAllWindows()
+		if err != nil {
+			t.Fatalf("wd.ScreenshotAllWindows() returned error: %v", err)
+		}
+		if len(shots[firstHandle]) == 0 || len(shots[otherHandle]) == 0 {
+			t.Errorf("wd.ScreenshotAllWindows() returned %d screenshots, expected one per window", len(shots))
+		}
+	})
+
 	t.Run("CloseWindow", func(t *testing.T) {
 		if err := wd.CloseWindow(otherHandle); err != nil {
 			t.Fatalf("wd.CloseWindow(otherHandle) returned error: %v", err)
@@ -404,6 +479,27 @@ func testWindows(t *testing.T, c Config) {
 			t.Fatalf("len(wd.WindowHandles()) = %d, expected 1", len(handles))
 		}
 	})
+
+	t.Run("NewWindow", func(t *testing.T) {
+		handle, err := wd.NewWindow(selenium.WindowTypeTab)
+		if errors.Is(err, selenium.ErrUnsupported) {
+			t.Skip("the remote end does not implement the New Window command")
+		}
+		if err != nil {
+			t.Fatalf("wd.NewWindow() returned error: %v", err)
+		}
+		defer wd.CloseWindow(handle)
+		handles, err := wd.WindowHandles()
+		if err != nil {
+			t.Fatalf("wd.WindowHandles() returned error: %v", err)
+		}
+		if len(handles) != 2 || (handles[0] != handle && handles[1] != handle) {
+			t.Errorf("wd.WindowHandles() = %v, expected the original window and %q", handles, handle)
+		}
+		if current, err := wd.CurrentWindowHandle(); err != nil || current != firstHandle {
+			t.Errorf("wd.CurrentWindowHandle() = %q, %v after wd.NewWindow(), expected %q", current, err, firstHandle)
+		}
+	})
 }
 
 func testGet(t *testing.T, c Config) {
@@ -471,6 +567,35 @@ func testNavigation(t *testing.T, c Config) {
 	}
 }
 
+func testNavigate(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	r, err := wd.Navigate(c.ServerURL, selenium.FailOnHTTPError())
+	if err != nil {
+		t.Fatalf("wd.Navigate(%q, selenium.FailOnHTTPError()) returned error: %v", c.ServerURL, err)
+	}
+	if r.ErrorPage || (r.StatusCode != selenium.StatusUnknown && r.StatusCode != http.StatusOK) {
+		t.Fatalf("wd.Navigate(%q) = %+v, want status 200 without an error page", c.ServerURL, r)
+	}
+
+	missingURL := c.ServerURL + "/missing"
+	r, err = wd.Navigate(missingURL)
+	if err != nil {
+		t.Fatalf("wd.Navigate(%q) returned error: %v", missingURL, err)
+	}
+	if r.StatusCode == selenium.StatusUnknown {
+		t.Skipf("The HTTP status is not reported by %s", c.Browser)
+	}
+	if r.StatusCode != http.StatusNotFound {
+		t.Fatalf("wd.Navigate(%q) = %+v, want status 404", missingURL, r)
+	}
+	_, err = wd.Navigate(missingURL, selenium.FailOnHTTPError())
+	if _, ok := err.(*selenium.NavigationError); !ok {
+		t.Fatalf("wd.Navigate(%q, selenium.FailOnHTTPError()) returned error %v, want a *selenium.NavigationError", missingURL, err)
+	}
+}
+
 func testTitle(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -490,6 +615,34 @@ func testTitle(t *testing.T, c Config) {
 	}
 }
 
+func testResourceSummary(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	frameURL := c.ServerURL + "/frame"
+	if err := wd.Get(frameURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", frameURL, err)
+	}
+	if err := wd.EnableResourceSummary(); err != nil {
+		t.Fatalf("wd.EnableResourceSummary() returned error: %v", err)
+	}
+	// Entries cleared by the page must still be counted.
+	if _, err := wd.ExecuteScript("performance.clearResourceTimings();", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+
+	s, err := wd.ResourceSummary()
+	if err != nil {
+		t.Fatalf("wd.ResourceSummary() returned error: %v", err)
+	}
+	if s.Requests < 1 {
+		t.Errorf("wd.ResourceSummary() reported %d requests, want at least the framed page", s.Requests)
+	}
+	if s.Navigation.Load <= 0 {
+		t.Errorf("wd.ResourceSummary() reported a load time of %v, want it to be positive", s.Navigation.Load)
+	}
+}
+
 func testPageSource(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -533,6 +686,276 @@ func testFindElement(t *testing.T, c Config) {
 	}
 }
 
+func testSameElement(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	byName, err := wd.FindElement(selenium.ByName, "submit")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByName, "submit", err)
+	}
+	byCSS, err := wd.FindElement(selenium.ByCSSSelector, "input[name=submit]")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByCSSSelector, "input[name=submit]", err)
+	}
+	form, err := wd.FindElement(selenium.ByTagName, "form")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByTagName, "form", err)
+	}
+
+	if same, err := selenium.SameElement(byName, byCSS); err != nil || !same {
+		t.Errorf("selenium.SameElement() of the same input = %t, %v, want true, nil", same, err)
+	}
+	if same, err := selenium.SameElement(byName, form); err != nil || same {
+		t.Errorf("selenium.SameElement() of the input and the form = %t, %v, want false, nil", same, err)
+	}
+	rebuilt := wd.ElementFromID(byName.ID())
+	if name, err := rebuilt.GetAttribute("name"); err != nil || name != "submit" {
+		t.Errorf("wd.ElementFromID(%q).GetAttribute(%q) = %q, %v, want %q, nil", byName.ID(), "name", name, err, "submit")
+	}
+}
+
+func testWatchWindows(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	events, err := wd.WatchWindows(ctx, selenium.WatchInterval(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("wd.WatchWindows() returned error: %v", err)
+	}
+
+	for _, step := range []struct {
+		script string
+		want   selenium.WindowEventType
+	}{
+		{"window.popup = window.open('about:blank', '_blank');", selenium.WindowOpened},
+		{"window.popup.close();", selenium.WindowClosed},
+	} {
+		if _, err := wd.ExecuteScript(step.script, nil); err != nil {
+			t.Fatalf("wd.ExecuteScript(%q) returned error: %v", step.script, err)
+		}
+		e, ok := <-events
+		if !ok {
+			t.Fatalf("no window event after %q", step.script)
+		}
+		if e.Type != step.want {
+			t.Errorf("after %q, got window event %+v, want %s", step.script, e, step.want)
+		}
+	}
+}
+
+func testLazyElement(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	form := selenium.Lazy(wd, selenium.ByCSS("form"))
+	submit, err := form.FindElement(selenium.ByName, "submit")
+	if err != nil {
+		t.Fatalf("form.FindElement() returned error: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		got, err := submit.GetAttribute("type")
+		if err != nil {
+			t.Fatalf("submit.GetAttribute() returned error: %v", err)
+		}
+		if got != "submit" {
+			t.Errorf("submit.GetAttribute(%q) = %q, want %q", "type", got, "submit")
+		}
+		// Re-render the form, which makes the elements found so far stale.
+		if _, err := wd.ExecuteScript("var f = document.querySelector('form'); f.outerHTML = f.outerHTML;", nil); err != nil {
+			t.Fatalf("re-rendering the form returned error: %v", err)
+		}
+	}
+}
+
+func testElementList(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	options, err := selenium.Elements(wd.FindElements(selenium.ByCSSSelector, "option"))
+	if err != nil {
+		t.Fatalf("wd.FindElements() returned error: %v", err)
+	}
+	values, err := options.Attributes("value")
+	if err != nil {
+		t.Fatalf("options.Attributes() returned error: %v", err)
+	}
+	if want := []string{"first_value", "second_value"}; !reflect.DeepEqual(values, want) {
+		t.Errorf("options.Attributes(%q) = %q, want %q", "value", values, want)
+	}
+	links, err := selenium.Elements(wd.FindElements(selenium.ByTagName, "a"))
+	if err != nil {
+		t.Fatalf("wd.FindElements() returned error: %v", err)
+	}
+	texts, err := links.Texts()
+	if err != nil {
+		t.Fatalf("links.Texts() returned error: %v", err)
+	}
+	if want := []string{"other page", "тест", "search"}; !reflect.DeepEqual(texts, want) {
+		t.Errorf("links.Texts() = %q, want %q", texts, want)
+	}
+	visible, err := links.Visible()
+	if err != nil {
+		t.Fatalf("links.Visible() returned error: %v", err)
+	}
+	if len(visible) != len(links) {
+		t.Errorf("links.Visible() returned %d elements, want %d", len(visible), len(links))
+	}
+}
+
+func testOpen(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	form, err := wd.Open(c.ServerURL, "form", 0)
+	if err != nil {
+		t.Fatalf("wd.Open(%q) returned error: %v", c.ServerURL, err)
+	}
+	if tag, err := form.TagName(); err != nil || tag != "form" {
+		t.Errorf("wd.Open() returned a %q element, %v; want the form", tag, err)
+	}
+
+	_, err = wd.Open(c.ServerURL, "#missing", 500*time.Millisecond)
+	var openErr *selenium.OpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("wd.Open() with a missing element returned error %v, want an *OpenError", err)
+	}
+	if openErr.Title != "Go Selenium Test Suite" {
+		t.Errorf("OpenError.Title = %q, want %q", openErr.Title, "Go Selenium Test Suite")
+	}
+}
+
+func testPageEncoding(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	for path, page := range encodedPages {
+		u := c.ServerURL + path
+		if err := wd.Get(u); err != nil {
+			t.Fatalf("wd.Get(%q) returned error: %v", u, err)
+		}
+		if charset, err := wd.PageEncoding(); err != nil || !strings.EqualFold(charset, page.charset) {
+			t.Errorf("wd.PageEncoding() on %s = %q, %v, want %q, nil", path, charset, err, page.charset)
+		}
+		greeting, err := wd.FindElement(selenium.ByID, "greeting")
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "greeting", err)
+		}
+		if text, err := greeting.Text(); err != nil || text != page.greeting {
+			t.Errorf("greeting.Text() on %s = %q, %v, want %q, nil", path, text, err, page.greeting)
+		}
+		source, err := wd.PageSource()
+		if err != nil {
+			t.Fatalf("wd.PageSource() returned error: %v", err)
+		}
+		if !strings.Contains(source, page.greeting) {
+			t.Errorf("wd.PageSource() on %s = %q, want it to contain %q in UTF-8", path, source, page.greeting)
+		}
+	}
+}
+
+func testUnicodeText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	textURL := c.ServerURL + "/text"
+	if err := wd.Get(textURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", textURL, err)
+	}
+	p, err := wd.FindElement(selenium.ByID, "text")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "text", err)
+	}
+	if text, err := p.Text(); err != nil || text != unicodeText {
+		t.Errorf("p.Text() = %q, %v, want %q, nil", text, err, unicodeText)
+	}
+	if title, err := p.GetAttribute("title"); err != nil || title != unicodeText {
+		t.Errorf("p.GetAttribute(%q) = %q, %v, want %q, nil", "title", title, err, unicodeText)
+	}
+
+	lone, err := wd.FindElement(selenium.ByID, "lone")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "lone", err)
+	}
+	const want = "a\uFFFDb"
+	if got, err := lone.GetAttribute("data-text"); err != nil || got != want {
+		t.Errorf("lone.GetAttribute(%q) = %q, %v, want %q, nil", "data-text", got, err, want)
+	}
+
+	input, err := wd.FindElement(selenium.ByID, "input")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "input", err)
+	}
+	if err := input.SetText(unicodeText); err != nil {
+		t.Fatalf("input.SetText(%q) returned error: %v", unicodeText, err)
+	}
+	if value, err := input.GetProperty("value"); err != nil || value != unicodeText {
+		t.Errorf("input.GetProperty(%q) = %q, %v, want %q, nil", "value", value, err, unicodeText)
+	}
+}
+
+func testEnsureInteractable(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	interactURL := c.ServerURL + "/interact"
+	if err := wd.Get(interactURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", interactURL, err)
+	}
+	for _, tc := range []struct {
+		id, wantCheck string
+	}{
+		// The button's own text does not cover it.
+		{"ok", ""},
+		{"covered", selenium.CheckNotCovered},
+		{"disabled", selenium.CheckEnabled},
+		{"hidden", selenium.CheckDisplayed},
+		{"empty", selenium.CheckSize},
+	} {
+		elem, err := wd.FindElement(selenium.ByID, tc.id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, tc.id, err)
+		}
+		err = elem.EnsureInteractable()
+		if tc.wantCheck == "" {
+			if err != nil {
+				t.Errorf("#%s: elem.EnsureInteractable() returned error: %v", tc.id, err)
+			}
+			continue
+		}
+		var e *selenium.NotInteractableError
+		if !errors.As(err, &e) || e.Check != tc.wantCheck {
+			t.Errorf("#%s: elem.EnsureInteractable() returned error %v, want a %q check failure", tc.id, err, tc.wantCheck)
+		}
+	}
+
+	covered, err := wd.FindElement(selenium.ByID, "covered")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "covered", err)
+	}
+	start := time.Now()
+	if err := covered.Click(selenium.WithPrecheck()); err == nil {
+		t.Error("covered.Click(selenium.WithPrecheck()) returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("covered.Click(selenium.WithPrecheck()) took %s, want it to fail fast", elapsed)
+	}
+}
+
 func evaluateElement(t *testing.T, wd selenium.WebDriver, elem selenium.WebElement) {
 	if err := elem.Click(); err != nil {
 		t.Fatalf("wd.FindElement().Click() returned error: %v", err)
@@ -599,6 +1022,230 @@ func testSendKeys(t *testing.T, c Config) {
 	}
 }
 
+func testClearWithEvents(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	clearURL := c.ServerURL + "/clear"
+	ids := []string{"plain", "area", "tracked", "editable"}
+	if c.Browser == "htmlunit" {
+		// HTMLUnit does not clear content editable elements.
+		ids = ids[:3]
+	}
+	counts := func(t *testing.T, id string) (value string, input, change, onChange int) {
+		t.Helper()
+		reply, err := wd.ExecuteScript(`
+			var el = document.getElementById(arguments[0]);
+			return ['value' in el ? el.value : el.textContent,
+				+el.getAttribute('data-input'), +el.getAttribute('data-change'), +el.getAttribute('data-onchange')];`,
+			[]interface{}{id})
+		if err != nil {
+			t.Fatalf("reading the events of %q returned error: %v", id, err)
+		}
+		r := reply.([]interface{})
+		return r[0].(string), int(r[1].(float64)), int(r[2].(float64)), int(r[3].(float64))
+	}
+	for _, id := range ids {
+		t.Run(id, func(t *testing.T) {
+			// Clear fires different events on different drivers: record them.
+			if err := wd.Get(clearURL); err != nil {
+				t.Fatalf("wd.Get(%q) returned error: %v", clearURL, err)
+			}
+			elem, err := wd.FindElement(selenium.ByID, id)
+			if err != nil {
+				t.Fatalf("wd.FindElement(selenium.ByID, %q) returned error: %v", id, err)
+			}
+			if err := elem.Clear(); err != nil {
+				t.Fatalf("elem.Clear() returned error: %v", err)
+			}
+			value, input, change, onChange := counts(t, id)
+			if value != "" {
+				t.Errorf("after elem.Clear(), the value is %q, want it empty", value)
+			}
+			t.Logf("%s: elem.Clear() fired %d input and %d change events, %d tracked changes", c.Browser, input, change, onChange)
+
+			// ClearWithEvents fires each event once, whatever the driver.
+			if err := wd.Get(clearURL); err != nil {
+				t.Fatalf("wd.Get(%q) returned error: %v", clearURL, err)
+			}
+			if elem, err = wd.FindElement(selenium.ByID, id); err != nil {
+				t.Fatalf("wd.FindElement(selenium.ByID, %q) returned error: %v", id, err)
+			}
+			if err := elem.ClearWithEvents(); err != nil {
+				t.Fatalf("elem.ClearWithEvents() returned error: %v", err)
+			}
+			value, input, change, onChange = counts(t, id)
+			if value != "" || input != 1 || change != 1 {
+				t.Errorf("after elem.ClearWithEvents(), the value is %q with %d input and %d change events, want it empty with 1 of each", value, input, change)
+			}
+			if id == "tracked" && onChange != 1 {
+				t.Errorf("after elem.ClearWithEvents(), the tracked input saw %d changes, want 1", onChange)
+			}
+
+			// An empty element does not change, so no events are dispatched.
+			if err := elem.ClearWithEvents(); err != nil {
+				t.Fatalf("elem.ClearWithEvents() on an empty element returned error: %v", err)
+			}
+			if _, again, _, _ := counts(t, id); again != input {
+				t.Errorf("elem.ClearWithEvents() on an empty element fired %d input events, want none", again-input)
+			}
+		})
+	}
+}
+
+func testFailOnJSErrors(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	pageURL := c.ServerURL + "/jserrors"
+	if err := wd.Get(pageURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", pageURL, err)
+	}
+	noise := func(msg string) bool { return strings.Contains(msg, "third-party noise") }
+	if err := wd.FailOnJSErrors(noise); err != nil {
+		t.Fatalf("wd.FailOnJSErrors() returned error: %v", err)
+	}
+	click := func(id string) error {
+		t.Helper()
+		elem, err := wd.FindElement(selenium.ByID, id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(selenium.ByID, %q) returned error: %v", id, err)
+		}
+		return elem.Click()
+	}
+
+	err := click("throw")
+	var perr *selenium.PageScriptError
+	if !errors.As(err, &perr) || len(perr.Errors) != 1 || !strings.Contains(perr.Errors[0].Message, "boom") {
+		t.Fatalf("clicking the throwing button returned error %v, want a *selenium.PageScriptError with the error of the page", err)
+	}
+	if err := click("noise"); err != nil {
+		t.Errorf("clicking the button throwing ignored errors returned error: %v", err)
+	}
+	if c.Browser != "htmlunit" {
+		err := click("reject")
+		if !errors.As(err, &perr) || len(perr.Errors) != 1 || !perr.Errors[0].Rejection {
+			t.Errorf("clicking the rejecting button returned error %v, want a *selenium.PageScriptError with an unhandled rejection", err)
+		}
+	}
+	if err := click("quiet"); err != nil {
+		t.Errorf("clicking the quiet button returned error: %v", err)
+	}
+}
+
+func testClickWhenReady(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	interactURL := c.ServerURL + "/interact"
+	if err := wd.Get(interactURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", interactURL, err)
+	}
+	for _, tc := range []struct {
+		id        string
+		wantPhase selenium.ReadyPhase
+	}{
+		{"missing", selenium.PhaseFind},
+		{"covered", selenium.PhaseInteractable},
+	} {
+		err := wd.ClickWhenReady(selenium.ByID, tc.id, 500*time.Millisecond)
+		var e *selenium.NotReadyError
+		if !errors.As(err, &e) || e.Phase != tc.wantPhase {
+			t.Errorf("wd.ClickWhenReady(%q) returned error %v, want a *NotReadyError in phase %q", tc.id, err, tc.wantPhase)
+		}
+	}
+
+	script := `
+		var b = document.getElementById('covered');
+		b.addEventListener('click', function() { b.textContent = 'Clicked'; });
+		setTimeout(function() { document.getElementById('overlay').remove(); }, 500);`
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if err := wd.ClickWhenReady(selenium.ByID, "covered", 0); err != nil {
+		t.Fatalf("wd.ClickWhenReady(%q) returned error: %v", "covered", err)
+	}
+	covered, err := wd.FindElement(selenium.ByID, "covered")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q, %q) returned error: %v", selenium.ByID, "covered", err)
+	}
+	if text, err := covered.Text(); err != nil || text != "Clicked" {
+		t.Errorf("covered.Text() = %q, %v after wd.ClickWhenReady(), want %q", text, err, "Clicked")
+	}
+
+	inputURL := c.ServerURL + "/input"
+	if err := wd.Get(inputURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", inputURL, err)
+	}
+	if err := wd.TypeWhenReady(selenium.ByID, "text", "golang", 0); err != nil {
+		t.Fatalf("wd.TypeWhenReady() returned error: %v", err)
+	}
+}
+
+func testSetText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	inputURL := c.ServerURL + "/input"
+	for _, tc := range []struct {
+		desc, text string
+	}{
+		{"ASCII", "golang"},
+		{"CJK", "日本語のテキスト"},
+		{"Emoji", "thumbs \U0001F44D and family \U0001F468\u200D\U0001F469\u200D\U0001F467"},
+		{"CombiningCharacters", "cafe\u0301 n\u0303"},
+		{"LongPaste", strings.Repeat("0123456789", 1000)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := wd.Get(inputURL); err != nil {
+				t.Fatalf("wd.Get(%q) returned error: %v", inputURL, err)
+			}
+			input, err := wd.FindElement(selenium.ByID, "text")
+			if err != nil {
+				t.Fatalf("wd.FindElement(selenium.ByID, \"text\") returned error: %v", err)
+			}
+			if err := input.SendKeys("previous"); err != nil {
+				t.Fatalf("input.SendKeys() returned error: %v", err)
+			}
+			if err := input.SetText(tc.text); err != nil {
+				t.Fatalf("input.SetText(%q) returned error: %v", tc.text, err)
+			}
+			got, err := input.GetProperty("value")
+			if err != nil {
+				t.Fatalf("input.GetProperty(\"value\") returned error: %v", err)
+			}
+			if got != tc.text {
+				t.Fatalf("after input.SetText(%q), value = %q", tc.text, got)
+			}
+			// The page mirrors the value seen by its "input" event handler, which
+			// is where framework bindings observe changes.
+			mirror, err := wd.FindElement(selenium.ByID, "mirror")
+			if err != nil {
+				t.Fatalf("wd.FindElement(selenium.ByID, \"mirror\") returned error: %v", err)
+			}
+			seen, err := mirror.GetAttribute("data-value")
+			if err != nil {
+				t.Fatalf("mirror.GetAttribute(\"data-value\") returned error: %v", err)
+			}
+			if seen != tc.text {
+				t.Fatalf("after input.SetText(%q), the input event handler saw %q", tc.text, seen)
+			}
+
+			const suffix = " \U0001F600"
+			if err := input.AppendText(suffix); err != nil {
+				t.Fatalf("input.AppendText(%q) returned error: %v", suffix, err)
+			}
+			got, err = input.GetProperty("value")
+			if err != nil {
+				t.Fatalf("input.GetProperty(\"value\") returned error: %v", err)
+			}
+			if want := tc.text + suffix; got != want {
+				t.Fatalf("after input.AppendText(%q), value = %q, want %q", suffix, got, want)
+			}
+		})
+	}
+}
+
 func testClick(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -754,15 +1401,52 @@ func testAddCookie(t *testing.T, c Config) {
 			break
 		}
 	}
-	if got == nil {
-		t.Fatalf("wd.GetCookies() = %v, missing cookie %q", cookies, want.Name)
+	if got == nil {
+		t.Fatalf("wd.GetCookies() = %v, missing cookie %q", cookies, want.Name)
+	}
+
+	if c.SameSiteUnsupported {
+		want.SameSite = ""
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("wd.GetCookies() returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func testNormalizeCookie(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	otherURL := c.ServerURL + "/other"
+	if err := wd.Get(otherURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", otherURL, err)
+	}
+	u, err := url.Parse(c.ServerURL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) returned error: %v", c.ServerURL, err)
+	}
+	host := u.Hostname()
+
+	// GeckoDriver rejects the leading dot and drivers disagree on the trailing
+	// slash: both are removed.
+	if err := wd.AddCookie(&selenium.Cookie{Name: "normalized", Value: "v", Domain: "." + host, Path: "/other/"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	got, err := wd.GetCookie("normalized")
+	if err != nil {
+		t.Fatalf("wd.GetCookie(%q) returned error: %v", "normalized", err)
+	}
+	if got.Value != "v" || got.Path != "/other" || strings.TrimPrefix(got.Domain, ".") != host {
+		t.Errorf("wd.GetCookie(%q) = %+v, want the value v, the path /other and the domain %s", "normalized", got, host)
 	}
 
-	if c.SameSiteUnsupported {
-		want.SameSite = ""
+	if err := wd.AddCookie(&selenium.Cookie{Name: "elsewhere", Value: "v", Domain: "example.invalid"}); !errors.Is(err, selenium.ErrInvalidCookie) {
+		t.Errorf("wd.AddCookie() for another domain returned error %v, want ErrInvalidCookie", err)
 	}
-	if diff := cmp.Diff(want, got); diff != "" {
-		t.Fatalf("wd.GetCookies() returned diff (-want/+got):\n%s", diff)
+
+	_, err = wd.GetCookie("missing")
+	if !errors.Is(err, werror.NoSuchCookie) {
+		t.Errorf("wd.GetCookie(%q) returned error %v, want a no such cookie error", "missing", err)
 	}
 }
 
@@ -798,6 +1482,128 @@ func testDeleteCookie(t *testing.T, c Config) {
 	}
 }
 
+func testStorageState(t *testing.T, c Config) {
+	dir, err := ioutil.TempDir("", "storage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := wd.AddCookie(&selenium.Cookie{Name: "session", Value: "logged-in", Path: "/"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	if err := wd.LocalStorage().Set("token", "secret"); err != nil {
+		t.Fatalf("wd.LocalStorage().Set() returned error: %v", err)
+	}
+	if err := wd.SaveStorageState(path); err != nil {
+		t.Fatalf("wd.SaveStorageState() returned error: %v", err)
+	}
+	quitRemote(t, wd)
+
+	wd = newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+	if err := wd.RestoreStorageState(path); err != nil {
+		t.Fatalf("wd.RestoreStorageState() returned error: %v", err)
+	}
+	cookie, err := wd.GetCookie("session")
+	if err != nil || cookie.Value != "logged-in" {
+		t.Errorf("after wd.RestoreStorageState(), wd.GetCookie(%q) = %+v, %v; want the saved cookie", "session", cookie, err)
+	}
+	if token, err := wd.LocalStorage().Get("token"); err != nil || token != "secret" {
+		t.Errorf("after wd.RestoreStorageState(), the token in localStorage is %q, %v; want %q", token, err, "secret")
+	}
+}
+
+func testStorage(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	values := map[string]string{
+		"plain":             "value",
+		`quotes "'`:         `it's "quoted" \ and 'single'`,
+		"unicode 日本":        "\u00e9t\u00e9 \U0001F600 \u0645\u0631\u062d\u0628\u0627",
+		"newlines":          "line 1\nline 2\r\n\ttabbed",
+		"json-like":         `{"a": [1, 2]}`,
+		"":                  "empty key",
+		"</script><script>": "",
+	}
+	for name, s := range map[string]selenium.Storage{
+		"LocalStorage":   wd.LocalStorage(),
+		"SessionStorage": wd.SessionStorage(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Clear(); err != nil {
+				t.Fatalf("s.Clear() returned error: %v", err)
+			}
+			for k, v := range values {
+				if err := s.Set(k, v); err != nil {
+					t.Fatalf("s.Set(%q, %q) returned error: %v", k, v, err)
+				}
+			}
+			for k, want := range values {
+				got, err := s.Get(k)
+				if err != nil {
+					t.Fatalf("s.Get(%q) returned error: %v", k, err)
+				}
+				if got != want {
+					t.Errorf("s.Get(%q) = %q, want %q", k, got, want)
+				}
+			}
+			if n, err := s.Len(); err != nil || n != len(values) {
+				t.Errorf("s.Len() = %d, %v; want %d, nil", n, err, len(values))
+			}
+			keys, err := s.Keys()
+			if err != nil {
+				t.Fatalf("s.Keys() returned error: %v", err)
+			}
+			sort.Strings(keys)
+			var want []string
+			for k := range values {
+				want = append(want, k)
+			}
+			sort.Strings(want)
+			if !reflect.DeepEqual(keys, want) {
+				t.Errorf("s.Keys() = %q, want %q", keys, want)
+			}
+
+			if err := s.Remove("plain"); err != nil {
+				t.Fatalf("s.Remove(%q) returned error: %v", "plain", err)
+			}
+			if _, err := s.Get("plain"); err != selenium.ErrKeyNotFound {
+				t.Errorf("s.Get(%q) after s.Remove() returned error %v, want selenium.ErrKeyNotFound", "plain", err)
+			}
+			if err := s.Clear(); err != nil {
+				t.Fatalf("s.Clear() returned error: %v", err)
+			}
+			if n, err := s.Len(); err != nil || n != 0 {
+				t.Errorf("s.Len() after s.Clear() = %d, %v; want 0, nil", n, err)
+			}
+		})
+	}
+
+	if !wd.Supports(selenium.FeatureCDP) {
+		return
+	}
+	if err := wd.LocalStorage().Set("k", "v"); err != nil {
+		t.Fatalf("wd.LocalStorage().Set() returned error: %v", err)
+	}
+	if err := wd.ClearSiteData(selenium.SiteDataLocalStorage); err != nil {
+		t.Fatalf("wd.ClearSiteData(selenium.SiteDataLocalStorage) returned error: %v", err)
+	}
+	if n, err := wd.LocalStorage().Len(); err != nil || n != 0 {
+		t.Errorf("wd.LocalStorage().Len() after wd.ClearSiteData() = %d, %v; want 0, nil", n, err)
+	}
+}
+
 func testLocation(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
@@ -964,6 +1770,72 @@ func testScreenshot(t *testing.T, c Config) {
 	}
 }
 
+func testScreenshotWithMasks(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("Skipping on htmlunit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	pageURL := c.ServerURL + "/mask"
+	if err := wd.Get(pageURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", pageURL, err)
+	}
+	red := color.RGBA{R: 0xff, A: 0xff}
+	data, err := wd.ScreenshotWithMaskSelectors(nil, []string{"#clock", "#hidden"}, red)
+	if err != nil {
+		t.Fatalf("wd.ScreenshotWithMaskSelectors() returned error: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("wd.ScreenshotWithMaskSelectors() did not return a PNG image: %v", err)
+	}
+	var masked, green int
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			switch {
+			case r == 0xffff && g == 0 && b == 0:
+				masked++
+			case r == 0 && g == 0xffff && b == 0:
+				green++
+			}
+		}
+	}
+	if masked == 0 || green != 0 {
+		t.Errorf("wd.ScreenshotWithMaskSelectors() left %d pixels of the clock visible and masked %d pixels, want 0 and more", green, masked)
+	}
+}
+
+func testDisableAnimations(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	if err := wd.DisableAnimations(); err != nil {
+		t.Fatalf("wd.DisableAnimations() returned error: %v", err)
+	}
+	// Calling it again must not fail nor add another style sheet.
+	if err := wd.DisableAnimations(); err != nil {
+		t.Fatalf("second call to wd.DisableAnimations() returned error: %v", err)
+	}
+
+	const script = `
+		var style = window.getComputedStyle(document.getElementById('chuk'));
+		return [style.caretColor, style.transitionDuration, document.querySelectorAll('style').length];`
+	got, err := wd.ExecuteScript(script, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", script, err)
+	}
+	want := []interface{}{"rgba(0, 0, 0, 0)", "0s", float64(1)}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("after wd.DisableAnimations(), caret color, transition duration and style sheet count = %v, want %v", got, want)
+	}
+}
+
 func testLog(t *testing.T, c Config) {
 	switch {
 	case c.Browser == "htmlunit":
@@ -1071,125 +1943,502 @@ func testIsDisplayed(t *testing.T, c Config) {
 	}
 	displayed, err := elem.IsDisplayed()
 	if err != nil {
-		t.Fatalf("elem.IsDisplayed() returned error: %v", err)
+		t.Fatalf("elem.IsDisplayed() returned error: %v", err)
+	}
+
+	if !displayed {
+		t.Fatal("Not displayed")
+	}
+}
+
+func testGetAttributeNotFound(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "chuk")
+	if err != nil {
+		t.Fatal("Can't find element")
+	}
+
+	if _, err = elem.GetAttribute("no-such-attribute"); err == nil {
+		t.Fatal("Got non existing attribute")
+	}
+}
+
+func testGetProperty(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("Skipping on htmlunit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	input, err := wd.FindElement(selenium.ByName, "q")
+	if err != nil {
+		t.Fatalf("Can't find element: %v", err)
+	}
+	const query = "golang"
+	if err := input.SendKeys(query); err != nil {
+		t.Fatalf("Can't send keys: %v", err)
+	}
+
+	val, err := input.GetProperty("value")
+	if err != nil {
+		t.Fatalf("Error getting property: %v", err)
+	}
+	if val != query {
+		t.Fatalf("Unexpected property value: %q != %q", val, query)
+	}
+}
+
+func testGetPropertyNotFound(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("Skipping on htmlunit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	elem, err := wd.FindElement(selenium.ByID, "chuk")
+	if err != nil {
+		t.Fatal("Can't find element")
+	}
+
+	if _, err = elem.GetProperty("no-such-property"); err == nil {
+		t.Fatal("Got non existing property")
+	}
+}
+
+func testWaitForText(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	statusURL := c.ServerURL + "/status"
+	if err := wd.Get(statusURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", statusURL, err)
+	}
+	if err := wd.WaitForText(selenium.ByCSSSelector, "#status", "3 items imported", selenium.WaitTimeout(5*time.Second)); err != nil {
+		t.Fatalf("wd.WaitForText() returned error: %v", err)
+	}
+
+	err := wd.WaitForText(selenium.ByCSSSelector, "#status", "4 items", selenium.TextContains(), selenium.WaitTimeout(200*time.Millisecond))
+	var e *selenium.WaitTextError
+	if !errors.As(err, &e) {
+		t.Fatalf("wd.WaitForText() of a text not shown returned error %v, want a *selenium.WaitTextError", err)
+	}
+	if n := len(e.Observed); n == 0 || e.Observed[n-1].Text != "3 items imported" {
+		t.Errorf("the error reports the texts %v, want the last one to be %q", e.Observed, "3 items imported")
+	}
+}
+
+func testRouteChanges(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	routesURL := c.ServerURL + "/routes"
+	if err := wd.Get(routesURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", routesURL, err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := wd.WatchRouteChanges(ctx)
+	if err != nil {
+		t.Fatalf("wd.WatchRouteChanges() returned error: %v", err)
+	}
+	open, err := wd.FindElement(selenium.ByID, "open")
+	if err != nil {
+		t.Fatalf("wd.FindElement(%q) returned error: %v", "open", err)
+	}
+	if err := open.Click(); err != nil {
+		t.Fatalf("open.Click() returned error: %v", err)
+	}
+	if err := wd.ExpectRoute("/routes/items/*/edit", 5*time.Second); err != nil {
+		t.Fatalf("wd.ExpectRoute() returned error: %v", err)
+	}
+
+	want := []selenium.RouteMethod{selenium.RoutePush, selenium.RouteReplace}
+	for i, method := range want {
+		select {
+		case got := <-changes:
+			if got.Method != method {
+				t.Errorf("route change %d is %+v, want a %s", i, got, method)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no route change %d, want a %s", i, method)
+		}
+	}
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	select {
+	case got := <-changes:
+		if got.Method != selenium.RouteLoad || got.New != c.ServerURL+"/" {
+			t.Errorf("the navigation to %s is reported as %+v, want a load", c.ServerURL, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no route change for the navigation to a new document")
+	}
+}
+
+func testIsolatedContext(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if !wd.Supports(selenium.FeatureCDP) {
+		t.Skip("browser contexts require the Chrome DevTools Protocol")
+	}
+	statusURL := c.ServerURL + "/status"
+	if err := wd.Get(statusURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", statusURL, err)
+	}
+	if err := wd.AddCookie(&selenium.Cookie{Name: "context", Value: "default"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	main, err := wd.CurrentWindowHandle()
+	if err != nil {
+		t.Fatalf("wd.CurrentWindowHandle() returned error: %v", err)
+	}
+
+	ctx, err := wd.NewIsolatedContext()
+	if err != nil {
+		t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+	}
+	if err := ctx.Switch(); err != nil {
+		t.Fatalf("ctx.Switch() returned error: %v", err)
+	}
+	if err := wd.Get(statusURL); err != nil {
+		t.Fatalf("wd.Get(%q) in the isolated context returned error: %v", statusURL, err)
+	}
+	if cookies, err := wd.GetCookies(); err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	} else if len(cookies) != 0 {
+		t.Errorf("wd.GetCookies() in the isolated context returned %+v, want none", cookies)
+	}
+
+	if err := ctx.Close(); err != nil {
+		t.Fatalf("ctx.Close() returned error: %v", err)
+	}
+	if current, err := wd.CurrentWindowHandle(); err != nil {
+		t.Fatalf("wd.CurrentWindowHandle() after ctx.Close() returned error: %v", err)
+	} else if current != main {
+		t.Errorf("ctx.Close() left window %q current, want %q", current, main)
+	}
+	if _, err := wd.GetCookie("context"); err != nil {
+		t.Errorf("wd.GetCookie(%q) in the default context returned error: %v", "context", err)
+	}
+
+	// A context left open is disposed of with the session.
+	if _, err := wd.NewIsolatedContext(); err != nil {
+		t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+	}
+}
+
+func testResetState(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	statusURL := c.ServerURL + "/status"
+	if err := wd.Get(statusURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", statusURL, err)
+	}
+	if _, err := wd.ExecuteScript("localStorage.setItem('reset', 'left'); window.open('about:blank');", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if err := wd.AddCookie(&selenium.Cookie{Name: "reset", Value: "left"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+
+	err := wd.ResetState(selenium.ResetLight)
+	var partial *selenium.ResetError
+	if err != nil && !errors.As(err, &partial) {
+		t.Fatalf("wd.ResetState(ResetLight) returned error: %v", err)
+	}
+	if handles, err := wd.WindowHandles(); err != nil {
+		t.Fatalf("wd.WindowHandles() returned error: %v", err)
+	} else if len(handles) != 1 {
+		t.Errorf("wd.ResetState(ResetLight) left %d windows, want 1", len(handles))
+	}
+	if err := wd.Get(statusURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", statusURL, err)
+	}
+	if got, err := wd.ExecuteScript("return localStorage.getItem('reset');", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	} else if got != nil {
+		t.Errorf("wd.ResetState(ResetLight) left the local storage item %q", got)
+	}
+	if cookies, err := wd.GetCookies(); err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	} else if len(cookies) != 0 {
+		t.Errorf("wd.ResetState(ResetLight) left cookies %+v", cookies)
+	}
+}
+
+func testActiveElement(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		// TODO(minusnine): figure out why ActiveElement doesn't work in HTMLUnit.
+		t.Skip("ActiveElement does not work in HTMLUnit")
+	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+
+	e, err := wd.ActiveElement()
+	if err != nil {
+		t.Fatalf("wd.ActiveElement() returned error: %v", err)
+	}
+	name, err := e.GetAttribute("name")
+	if err != nil {
+		t.Fatalf("wd.ActiveElement().GetAttribute() returned error: %v", err)
 	}
-
-	if !displayed {
-		t.Fatal("Not displayed")
+	if name != "q" {
+		t.Fatalf("wd.ActiveElement().GetAttribute() returned element with name = %q, expected name = 'q'", name)
 	}
 }
 
-func testGetAttributeNotFound(t *testing.T, c Config) {
+func testKeyDownUp(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	elem, err := wd.FindElement(selenium.ByID, "chuk")
+
+	e, err := wd.FindElement(selenium.ByLinkText, "other page")
 	if err != nil {
-		t.Fatal("Can't find element")
+		t.Fatalf("error finding other page link: %v", err)
 	}
 
-	if _, err = elem.GetAttribute("no-such-attribute"); err == nil {
-		t.Fatal("Got non existing attribute")
+	if err := wd.KeyDown(selenium.ControlKey); err != nil {
+		t.Fatalf("error pressing control key down: %v", err)
+	}
+	if err := e.Click(); err != nil {
+		t.Fatalf("error clicking the other page link: %v", err)
+	}
+	if err := wd.KeyUp(selenium.ControlKey); err != nil {
+		t.Fatalf("error releasing control key: %v", err)
 	}
 }
 
-func testGetProperty(t *testing.T, c Config) {
+func testActionChain(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
 		t.Skip("Skipping on htmlunit")
 	}
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
-	if err := wd.Get(c.ServerURL); err != nil {
-		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	inputURL := c.ServerURL + "/input"
+	if err := wd.Get(inputURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", inputURL, err)
 	}
-	input, err := wd.FindElement(selenium.ByName, "q")
+	input, err := wd.FindElement(selenium.ByID, "text")
 	if err != nil {
-		t.Fatalf("Can't find element: %v", err)
+		t.Fatalf("wd.FindElement(selenium.ByID, \"text\") returned error: %v", err)
 	}
-	const query = "golang"
-	if err := input.SendKeys(query); err != nil {
-		t.Fatalf("Can't send keys: %v", err)
+	chain := selenium.NewActionChain().
+		MoveToElement(input, 0, 0).
+		Click(selenium.LeftButton).
+		SendKeys("go").
+		KeyDown(selenium.ShiftKey).
+		SendKeys("x").
+		KeyUp(selenium.ShiftKey)
+	if err := wd.PerformActionChain(chain); err != nil {
+		t.Fatalf("wd.PerformActionChain() returned error: %v", err)
 	}
-
-	val, err := input.GetProperty("value")
-	if err != nil {
-		t.Fatalf("Error getting property: %v", err)
+	if err := wd.ReleaseActions(); err != nil {
+		t.Fatalf("wd.ReleaseActions() returned error: %v", err)
 	}
-	if val != query {
-		t.Fatalf("Unexpected property value: %q != %q", val, query)
+	if value, err := input.GetProperty("value"); err != nil || value != "goX" {
+		t.Errorf("input.GetProperty(\"value\") = %q, %v, want %q", value, err, "goX")
 	}
 }
 
-func testGetPropertyNotFound(t *testing.T, c Config) {
+func testRelativeLocator(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
 		t.Skip("Skipping on htmlunit")
 	}
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
+	relativeURL := c.ServerURL + "/relative"
+	if err := wd.Get(relativeURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", relativeURL, err)
+	}
+	center, err := wd.FindElement(selenium.ByID, "center")
+	if err != nil {
+		t.Fatalf("wd.FindElement(selenium.ByID, \"center\") returned error: %v", err)
+	}
+	boxes := selenium.RelativeBy(selenium.ByClassName, "box")
+	for _, tc := range []struct {
+		desc    string
+		locator selenium.RelativeLocator
+		want    []string
+	}{
+		{"Above", boxes.Above(center), []string{"top"}},
+		{"Below", boxes.Below(center), []string{"bottom", "far"}},
+		{"LeftOf", boxes.LeftOf(center), []string{"left"}},
+		{"RightOf", boxes.RightOf(center), []string{"right", "far"}},
+		{"Near", boxes.Near(center), []string{"top", "left", "right", "bottom"}},
+		{"BelowAndRightOf", boxes.Below(center).RightOf(center), []string{"far"}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			elems, err := wd.FindElements(tc.locator.ByValue())
+			if err != nil {
+				t.Fatalf("wd.FindElements(%s) returned error: %v", tc.locator, err)
+			}
+			var got []string
+			for _, e := range elems {
+				id, err := e.GetAttribute("id")
+				if err != nil {
+					t.Fatalf("e.GetAttribute(\"id\") returned error: %v", err)
+				}
+				got = append(got, id)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("wd.FindElements(%s) returned %q, want %q", tc.locator, got, tc.want)
+			}
+		})
+	}
+	if _, err := wd.FindElement(boxes.Above(center).LeftOf(center).ByValue()); err == nil {
+		t.Error("wd.FindElement() of a box above and left of the center returned no error")
+	}
+}
+
+func testPrintPage(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if !wd.Supports(selenium.FeaturePrint) {
+		t.Skip("the remote end does not print pages")
+	}
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	elem, err := wd.FindElement(selenium.ByID, "chuk")
+	pdf, err := wd.PrintPage(selenium.PrintOptions{
+		Orientation: selenium.PrintLandscape,
+		Page:        selenium.PrintA4,
+		PageRanges:  []string{"1"},
+	})
 	if err != nil {
-		t.Fatal("Can't find element")
+		t.Fatalf("wd.PrintPage() returned error: %v", err)
 	}
-
-	if _, err = elem.GetProperty("no-such-property"); err == nil {
-		t.Fatal("Got non existing property")
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+		t.Errorf("wd.PrintPage() returned %d bytes that are not a PDF document", len(pdf))
 	}
 }
 
-func testActiveElement(t *testing.T, c Config) {
+func testShadowRoot(t *testing.T, c Config) {
 	if c.Browser == "htmlunit" {
-		// TODO(minusnine): figure out why ActiveElement doesn't work in HTMLUnit.
-		t.Skip("ActiveElement does not work in HTMLUnit")
+		t.Skip("Skipping on htmlunit")
 	}
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
-	if err := wd.Get(c.ServerURL); err != nil {
-		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	shadowURL := c.ServerURL + "/shadow"
+	if err := wd.Get(shadowURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", shadowURL, err)
 	}
-
-	e, err := wd.ActiveElement()
+	host, err := wd.FindElement(selenium.ByTagName, "test-widget")
 	if err != nil {
-		t.Fatalf("wd.ActiveElement() returned error: %v", err)
+		t.Fatalf("wd.FindElement(selenium.ByTagName, \"test-widget\") returned error: %v", err)
+	}
+	root, err := host.ShadowRoot()
+	if errors.Is(err, selenium.ErrUnsupported) {
+		t.Skip("the remote end does not implement the Get Element Shadow Root command")
 	}
-	name, err := e.GetAttribute("name")
 	if err != nil {
-		t.Fatalf("wd.ActiveElement().GetAttribute() returned error: %v", err)
+		t.Fatalf("host.ShadowRoot() returned error: %v", err)
 	}
-	if name != "q" {
-		t.Fatalf("wd.ActiveElement().GetAttribute() returned element with name = %q, expected name = 'q'", name)
+	button, err := root.FindElement(selenium.ByCSSSelector, "button")
+	if err != nil {
+		t.Fatalf("root.FindElement(selenium.ByCSSSelector, \"button\") returned error: %v", err)
+	}
+	if text, err := button.Text(); err != nil || text != "Inner" {
+		t.Errorf("button.Text() = %q, %v, want %q", text, err, "Inner")
+	}
+	items, err := root.FindElements(selenium.ByCSSSelector, "li")
+	if err != nil {
+		t.Fatalf("root.FindElements(selenium.ByCSSSelector, \"li\") returned error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Errorf("root.FindElements(selenium.ByCSSSelector, \"li\") returned %d elements, want 2", len(items))
+	}
+	if _, err := wd.FindElement(selenium.ByCSSSelector, "button"); err == nil {
+		t.Error("wd.FindElement(selenium.ByCSSSelector, \"button\") found the button of the shadow tree")
+	}
+
+	plain, err := wd.FindElement(selenium.ByID, "plain")
+	if err != nil {
+		t.Fatalf("wd.FindElement(selenium.ByID, \"plain\") returned error: %v", err)
+	}
+	if _, err := plain.ShadowRoot(); err == nil {
+		t.Error("plain.ShadowRoot() of an element without a shadow root returned no error")
 	}
 }
 
-func testKeyDownUp(t *testing.T, c Config) {
+func testVirtualAuthenticator(t *testing.T, c Config) {
 	wd := newRemote(t, newTestCapabilities(t, c), c)
 	defer quitRemote(t, wd)
 
 	if err := wd.Get(c.ServerURL); err != nil {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-
-	e, err := wd.FindElement(selenium.ByLinkText, "other page")
+	id, err := wd.AddVirtualAuthenticator(selenium.VirtualAuthenticatorOptions{HasResidentKey: true})
+	if errors.Is(err, selenium.ErrUnsupported) {
+		t.Skip("the remote end does not implement virtual authenticators")
+	}
 	if err != nil {
-		t.Fatalf("error finding other page link: %v", err)
+		t.Fatalf("wd.AddVirtualAuthenticator() returned error: %v", err)
 	}
+	defer wd.RemoveVirtualAuthenticator(id)
 
-	if err := wd.KeyDown(selenium.ControlKey); err != nil {
-		t.Fatalf("error pressing control key down: %v", err)
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() returned error: %v", err)
 	}
-	if err := e.Click(); err != nil {
-		t.Fatalf("error clicking the other page link: %v", err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalPKCS8PrivateKey() returned error: %v", err)
 	}
-	if err := wd.KeyUp(selenium.ControlKey); err != nil {
-		t.Fatalf("error releasing control key: %v", err)
+	u, err := url.Parse(c.ServerURL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) returned error: %v", c.ServerURL, err)
+	}
+	credential := selenium.Credential{
+		ID:                   []byte{1, 2, 3, 4},
+		IsResidentCredential: true,
+		RPID:                 u.Hostname(),
+		PrivateKey:           der,
+		UserHandle:           []byte("gopher"),
+	}
+	if err := wd.AddCredential(id, credential); err != nil {
+		t.Fatalf("wd.AddCredential() returned error: %v", err)
+	}
+	credentials, err := wd.Credentials(id)
+	if err != nil {
+		t.Fatalf("wd.Credentials() returned error: %v", err)
+	}
+	if len(credentials) != 1 || !bytes.Equal(credentials[0].ID, credential.ID) {
+		t.Fatalf("wd.Credentials() returned %+v, want the credential added", credentials)
+	}
+	if err := wd.RemoveCredential(id, credential.ID); err != nil {
+		t.Fatalf("wd.RemoveCredential() returned error: %v", err)
+	}
+	if credentials, err := wd.Credentials(id); err != nil || len(credentials) != 0 {
+		t.Errorf("wd.Credentials() = %+v, %v after the credential was removed, want none", credentials, err)
+	}
+	if err := wd.SetUserVerified(id, true); err != nil {
+		t.Errorf("wd.SetUserVerified() returned error: %v", err)
 	}
 }
 
@@ -1297,7 +2546,7 @@ func testProxy(t *testing.T, c Config) {
 			default:
 			}
 			if err != nil {
-				t.Fatalf("s.ListenAndServe(_) returned error: %v", err)
+				t.Errorf("s.ListenAndServe(_) returned error: %v", err)
 			}
 		}()
 		defer func() {
@@ -1339,6 +2588,42 @@ func runTestProxy(t *testing.T, c Config, caps selenium.Capabilities) {
 	}
 }
 
+func testVerifyProxy(t *testing.T, c Config) {
+	if c.Sauce != nil {
+		t.Skip("Testing a proxy on Sauce Labs doesn't work.")
+	}
+	p, err := selenium.NewTestProxy()
+	if err != nil {
+		t.Fatalf("selenium.NewTestProxy() returned error: %v", err)
+	}
+	defer p.Close()
+
+	for _, test := range []struct {
+		name   string
+		spec   selenium.ProxySpec
+		expect selenium.ProxyExpectation
+	}{
+		{"HTTP", p.HTTPSpec(), selenium.ProxyExpectation{Proxy: p, Protocol: "http"}},
+		{"SOCKSRemoteDNS", p.SOCKSSpec(true), selenium.ProxyExpectation{Proxy: p, Protocol: "socks5", RemoteDNS: true}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			if test.spec.SOCKS != "" && c.SeleniumVersion.Major == 3 {
+				t.Skip("Selenium 3 throws an exception with SOCKSVersion type conversion")
+			}
+			caps := newTestCapabilities(t, c)
+			if err := caps.SetProxySpec(test.spec); err != nil {
+				t.Fatalf("caps.SetProxySpec(%+v) returned error: %v", test.spec, err)
+			}
+			wd := newRemote(t, caps, c)
+			defer quitRemote(t, wd)
+
+			if err := selenium.VerifyProxy(wd, test.expect); err != nil {
+				t.Errorf("selenium.VerifyProxy() returned error: %v", err)
+			}
+		})
+	}
+}
+
 func allowProxyForLocalhost(browser string, caps selenium.Capabilities) {
 	switch browser {
 	case "firefox":
@@ -1369,52 +2654,310 @@ func testSwitchFrame(t *testing.T, c Config) {
 		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
 
-	const (
-		iframeID      = "iframeID"
-		insideFrameID = "chuk"
-		outsideDivID  = "outsideOfFrame"
-	)
+	const (
+		iframeID      = "iframeID"
+		insideFrameID = "chuk"
+		outsideDivID  = "outsideOfFrame"
+	)
+
+	// Test with the ID of the iframe.
+	if err := wd.SwitchFrame(iframeID); err != nil {
+		t.Fatalf("wd.SwitchToFrame(%q) returned error: %v", iframeID, err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, insideFrameID); err != nil {
+		t.Fatalf("After switching frames using an ID, wd.FindElement(selenium.ByID, %q) returned error: %v", insideFrameID, err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err == nil {
+		t.Fatalf("After switching frames using an ID, wd.FindElement(selenium.ByID, %q) returned nil, expected an error", outsideDivID)
+	}
+
+	// Test with nil, to return to the top-level context.
+	if err := wd.SwitchFrame(nil); err != nil {
+		t.Fatalf("wd.SwitchToFrame(nil) returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err != nil {
+		t.Fatalf("After switching frames using nil, wd.FindElement(selenium.ByID, %q) returned error: %v", outsideDivID, err)
+	}
+
+	// Test with a WebElement.
+	iframe, err := wd.FindElement(selenium.ByID, iframeID)
+	if err != nil {
+		t.Fatalf("error finding iframe: %v", err)
+	}
+	if err := wd.SwitchFrame(iframe); err != nil {
+		t.Fatalf("wd.SwitchToFrame(nil) returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, insideFrameID); err != nil {
+		t.Fatalf("After switching frames using a WebElement, wd.FindElement(selenium.ByID, %q) returned error: %v", insideFrameID, err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err == nil {
+		t.Fatalf("After switching frames using a WebElement, wd.FindElement(selenium.ByID, %q) returned nil, expected an error", outsideDivID)
+	}
+
+	// Test with the empty string, to return to the top-level context.
+	if err := wd.SwitchFrame(""); err != nil {
+		t.Fatalf(`wd.SwitchToFrame("") returned error: %v`, err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err != nil {
+		t.Fatalf(`After switching frames using "", wd.FindElement(selenium.ByID, %q) returned error: %v`, outsideDivID, err)
+	}
+}
+
+func testFindElementInAnyFrame(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	nestedURL := c.ServerURL + "/nested"
+	if err := wd.Get(nestedURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", nestedURL, err)
+	}
+
+	// The checkbox is on the home page, which is framed by the frame page,
+	// which is in turn the second frame of the nested page.
+	const checkboxID = "chuk"
+	elem, path, err := wd.FindElementInAnyFrame(selenium.ByID, checkboxID, 1)
+	if err == nil {
+		t.Fatalf("wd.FindElementInAnyFrame(selenium.ByID, %q, 1) = %v, %v; want an error", checkboxID, elem, path)
+	}
+	if _, ok := err.(*selenium.FrameSearchError); !ok {
+		t.Fatalf("wd.FindElementInAnyFrame(selenium.ByID, %q, 1) returned error %v of type %T, want a *selenium.FrameSearchError", checkboxID, err, err)
+	}
+
+	elem, path, err = wd.FindElementInAnyFrame(selenium.ByID, checkboxID, 3)
+	if err != nil {
+		t.Fatalf("wd.FindElementInAnyFrame(selenium.ByID, %q, 3) returned error: %v", checkboxID, err)
+	}
+	if want := (selenium.FramePath{1, 0}); !reflect.DeepEqual(path, want) {
+		t.Fatalf("wd.FindElementInAnyFrame(selenium.ByID, %q, 3) returned path %v, want %v", checkboxID, path, want)
+	}
+
+	// The search must not change the current browsing context.
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrames"); err != nil {
+		t.Fatalf("after wd.FindElementInAnyFrame(), wd.FindElement(selenium.ByID, \"outsideOfFrames\") returned error: %v", err)
+	}
+
+	err = wd.WithFrame(path, func() error {
+		return elem.Click()
+	})
+	if err != nil {
+		t.Fatalf("wd.WithFrame(%v, elem.Click) returned error: %v", path, err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrames"); err != nil {
+		t.Fatalf("after wd.WithFrame(), wd.FindElement(selenium.ByID, \"outsideOfFrames\") returned error: %v", err)
+	}
+
+	// The previous context must be restored when the function fails.
+	fnErr := errors.New("failure")
+	if err := wd.WithFrame(path, func() error { return fnErr }); err != fnErr {
+		t.Fatalf("wd.WithFrame(%v, fn) returned error %v, want %v", path, err, fnErr)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "outsideOfFrames"); err != nil {
+		t.Fatalf("after a failed wd.WithFrame(), wd.FindElement(selenium.ByID, \"outsideOfFrames\") returned error: %v", err)
+	}
+}
+
+func testTabOrder(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	tabURL := c.ServerURL + "/tab"
+	if err := wd.Get(tabURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", tabURL, err)
+	}
+	input, err := wd.FindElement(selenium.ByID, "third")
+	if err != nil {
+		t.Fatalf("wd.FindElement(selenium.ByID, \"third\") returned error: %v", err)
+	}
+	if err := input.Click(); err != nil {
+		t.Fatalf("input.Click() returned error: %v", err)
+	}
+
+	stops, err := wd.TabOrder(10)
+	if err != nil {
+		t.Fatalf("wd.TabOrder(10) returned error: %v", err)
+	}
+	type stop struct{ Selector, Role, Name string }
+	var got []stop
+	for _, s := range stops {
+		got = append(got, stop{s.Selector, s.Role, s.Name})
+	}
+	want := []stop{
+		{"#first", "link", "First"},
+		{"#second", "button", "Second"},
+		{"#third", "checkbox", "Third"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("wd.TabOrder(10) = %+v, want %+v", got, want)
+	}
+
+	// The focus must be restored.
+	active, err := wd.ActiveElement()
+	if err != nil {
+		t.Fatalf("wd.ActiveElement() returned error: %v", err)
+	}
+	if id, err := active.GetAttribute("id"); err != nil || id != "third" {
+		t.Fatalf("after wd.TabOrder(), the focused element has id %q (error %v), want %q", id, err, "third")
+	}
+
+	if _, err := wd.ExecuteScript("document.getElementById('trap').style.display = 'block';", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	stops, err = wd.TabOrder(10)
+	trapErr, ok := err.(*selenium.FocusTrapError)
+	if !ok {
+		t.Fatalf("wd.TabOrder(10) with a focus trap returned %v, %v; want a *selenium.FocusTrapError", stops, err)
+	}
+	if len(trapErr.Trap) != 2 || trapErr.Trap[0].Selector != "#trapA" || trapErr.Trap[1].Selector != "#trapB" {
+		t.Fatalf("wd.TabOrder(10) reported the trap %v, want #trapA and #trapB", trapErr.Trap)
+	}
+}
+
+func testAuditPage(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	violations, err := wd.AuditPage(
+		selenium.MaxDOMNodes(3),
+		selenium.ImagesHaveDimensions(),
+		selenium.NoRenderBlockingScripts(),
+		selenium.MaxDocumentDepth(2),
+		selenium.MaxInlineStyleSize(0),
+	)
+	if err != nil {
+		t.Fatalf("wd.AuditPage() returned error: %v", err)
+	}
+	var rules []string
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	if want := []string{"max-dom-nodes", "max-document-depth"}; !reflect.DeepEqual(rules, want) {
+		t.Fatalf("wd.AuditPage() = %v, want violations of %v", violations, want)
+	}
+	// The selector of the deepest element must match it.
+	sel := violations[1].Selector
+	if _, err := wd.FindElement(selenium.ByCSSSelector, sel); err != nil {
+		t.Errorf("wd.FindElement(selenium.ByCSSSelector, %q) returned error: %v", sel, err)
+	}
+}
+
+const (
+	testUserAgentString = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 SeleniumTest"
+	testLanguage        = "fr-CH"
+)
+
+// checkUserAgent checks that the User-Agent and Accept-Language headers
+// received by the server, navigator.userAgent and navigator.languages agree
+// with testUserAgentString and testLanguage.
+func checkUserAgent(t *testing.T, wd selenium.WebDriver, c Config) {
+	t.Helper()
+	headersURL := c.ServerURL + "/headers"
+	if err := wd.Get(headersURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", headersURL, err)
+	}
+	for id, want := range map[string]string{"user-agent": testUserAgentString, "accept-language": testLanguage} {
+		elem, err := wd.FindElement(selenium.ByID, id)
+		if err != nil {
+			t.Fatalf("wd.FindElement(selenium.ByID, %q) returned error: %v", id, err)
+		}
+		got, err := elem.Text()
+		if err != nil {
+			t.Fatalf("elem.Text() returned error: %v", err)
+		}
+		if !strings.HasPrefix(got, want) {
+			t.Errorf("the %s header received by the server is %q, want it to start with %q", id, got, want)
+		}
+	}
+	got, err := wd.ExecuteScript("return [navigator.userAgent, navigator.languages[0]];", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if want := []interface{}{testUserAgentString, testLanguage}; !reflect.DeepEqual(got, want) {
+		t.Errorf("navigator.userAgent and navigator.languages[0] = %v, want %v", got, want)
+	}
+}
+
+func testUserAgent(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not take browser options")
+	}
+	caps := newTestCapabilities(t, c)
+	if err := caps.SetUserAgent(testUserAgentString); err != nil {
+		t.Fatalf("caps.SetUserAgent() returned error: %v", err)
+	}
+	if err := caps.SetAcceptLanguage(testLanguage, "fr"); err != nil {
+		t.Fatalf("caps.SetAcceptLanguage() returned error: %v", err)
+	}
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	checkUserAgent(t, wd, c)
+}
 
-	// Test with the ID of the iframe.
-	if err := wd.SwitchFrame(iframeID); err != nil {
-		t.Fatalf("wd.SwitchToFrame(%q) returned error: %v", iframeID, err)
+func testMapHosts(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not take browser options")
 	}
-	if _, err := wd.FindElement(selenium.ByID, insideFrameID); err != nil {
-		t.Fatalf("After switching frames using an ID, wd.FindElement(selenium.ByID, %q) returned error: %v", insideFrameID, err)
+	u, err := url.Parse(c.ServerURL)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) returned error: %v", c.ServerURL, err)
 	}
-	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err == nil {
-		t.Fatalf("After switching frames using an ID, wd.FindElement(selenium.ByID, %q) returned nil, expected an error", outsideDivID)
+	const host = "www.selenium.test"
+	caps := newTestCapabilities(t, c)
+	if err := caps.MapHosts(map[string]string{host: u.Hostname()}); err != nil {
+		t.Fatalf("caps.MapHosts() returned error: %v", err)
 	}
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
 
-	// Test with nil, to return to the top-level context.
-	if err := wd.SwitchFrame(nil); err != nil {
-		t.Fatalf("wd.SwitchToFrame(nil) returned error: %v", err)
+	mappedURL := "http://" + net.JoinHostPort(host, u.Port()) + "/"
+	if err := wd.Get(mappedURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", mappedURL, err)
 	}
-	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err != nil {
-		t.Fatalf("After switching frames using nil, wd.FindElement(selenium.ByID, %q) returned error: %v", outsideDivID, err)
+	title, err := wd.Title()
+	if err != nil {
+		t.Fatalf("wd.Title() returned error: %v", err)
+	}
+	if want := "Go Selenium Test Suite"; title != want {
+		t.Errorf("the page served for %s has title %q, want %q", host, title, want)
+	}
+}
+
+func testBrowserTransport(t *testing.T, c Config) {
+	if c.Browser == "htmlunit" {
+		t.Skip("HTMLUnit does not support fetch")
 	}
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
 
-	// Test with a WebElement.
-	iframe, err := wd.FindElement(selenium.ByID, iframeID)
-	if err != nil {
-		t.Fatalf("error finding iframe: %v", err)
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
 	}
-	if err := wd.SwitchFrame(iframe); err != nil {
-		t.Fatalf("wd.SwitchToFrame(nil) returned error: %v", err)
+	client := &http.Client{Transport: selenium.BrowserTransport(wd)}
+	otherURL := c.ServerURL + "/other"
+	resp, err := client.Get(otherURL)
+	if err != nil {
+		t.Fatalf("client.Get(%q) returned error: %v", otherURL, err)
 	}
-	if _, err := wd.FindElement(selenium.ByID, insideFrameID); err != nil {
-		t.Fatalf("After switching frames using a WebElement, wd.FindElement(selenium.ByID, %q) returned error: %v", insideFrameID, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatalf("reading the body of %s returned error: %v", otherURL, err)
 	}
-	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err == nil {
-		t.Fatalf("After switching frames using a WebElement, wd.FindElement(selenium.ByID, %q) returned nil, expected an error", outsideDivID)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "The other page.") {
+		t.Errorf("client.Get(%q) returned status %d and body %q, want the other page", otherURL, resp.StatusCode, body)
 	}
 
-	// Test with the empty string, to return to the top-level context.
-	if err := wd.SwitchFrame(""); err != nil {
-		t.Fatalf(`wd.SwitchToFrame("") returned error: %v`, err)
+	missingURL := c.ServerURL + "/missing"
+	resp, err = client.Get(missingURL)
+	if err != nil {
+		t.Fatalf("client.Get(%q) returned error: %v", missingURL, err)
 	}
-	if _, err := wd.FindElement(selenium.ByID, outsideDivID); err != nil {
-		t.Fatalf(`After switching frames using "", wd.FindElement(selenium.ByID, %q) returned error: %v`, outsideDivID, err)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("client.Get(%q) returned status %d, want %d", missingURL, resp.StatusCode, http.StatusNotFound)
 	}
 }
 
@@ -1560,6 +3103,173 @@ var logPage = `
 </html>
 `
 
+// relativePage lays out a grid of boxes around a center box, for relative
+// locators.
+var relativePage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Relative Page</title>
+	<style>
+		div { position: absolute; width: 40px; height: 40px; }
+	</style>
+</head>
+<body>
+	<div id="top" class="box" style="left: 100px; top: 20px"></div>
+	<div id="left" class="box" style="left: 40px; top: 100px"></div>
+	<div id="center" class="box" style="left: 100px; top: 100px"></div>
+	<div id="right" class="box" style="left: 160px; top: 100px"></div>
+	<div id="bottom" class="box" style="left: 100px; top: 180px"></div>
+	<div id="far" class="box" style="left: 400px; top: 400px"></div>
+</body>
+</html>
+`
+
+// shadowPage defines a custom element with an open shadow root.
+var shadowPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Shadow Page</title>
+</head>
+<body>
+	<test-widget></test-widget>
+	<div id="plain"></div>
+	<script>
+		customElements.define('test-widget', class extends HTMLElement {
+			constructor() {
+				super();
+				this.attachShadow({mode: 'open'}).innerHTML =
+					'<button>Inner</button><ul><li>One</li><li>Two</li></ul>';
+			}
+		});
+	</script>
+</body>
+</html>
+`
+
+var inputPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Input Page</title>
+	<meta charset="utf-8">
+</head>
+<body>
+	<textarea id="text"></textarea>
+	<div id="mirror" data-value=""></div>
+	<script>
+		document.getElementById('text').addEventListener('input', function(e) {
+			document.getElementById('mirror').setAttribute('data-value', e.target.value);
+		});
+	</script>
+</body>
+</html>
+`
+
+// clearPage counts the input and change events of editable elements. The
+// "tracked" input intercepts its value property like React does, and only
+// counts an input event as a change if the value differs from the one it
+// tracked.
+var clearPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Clear Page</title>
+</head>
+<body>
+	<input id="plain" value="previous">
+	<textarea id="area">previous</textarea>
+	<input id="tracked" value="previous" data-onchange="0">
+	<div id="editable" contenteditable="true">previous</div>
+	<script>
+		['plain', 'area', 'tracked', 'editable'].forEach(function(id) {
+			var el = document.getElementById(id);
+			el.setAttribute('data-input', '0');
+			el.setAttribute('data-change', '0');
+			['input', 'change'].forEach(function(type) {
+				el.addEventListener(type, function() {
+					el.setAttribute('data-' + type, +el.getAttribute('data-' + type) + 1);
+				});
+			});
+		});
+		var tracked = document.getElementById('tracked');
+		var native = Object.getOwnPropertyDescriptor(HTMLInputElement.prototype, 'value');
+		var last = tracked.value;
+		Object.defineProperty(tracked, 'value', {
+			configurable: true,
+			get: function() { return native.get.call(this); },
+			set: function(v) { last = '' + v; native.set.call(this, v); }
+		});
+		tracked.addEventListener('input', function() {
+			if (tracked.value !== last) {
+				last = tracked.value;
+				tracked.setAttribute('data-onchange', +tracked.getAttribute('data-onchange') + 1);
+			}
+		});
+	</script>
+</body>
+</html>
+`
+
+// maskPage has a green clock to mask and a hidden element.
+var maskPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Mask Page</title>
+</head>
+<body style="background: white">
+	<div id="clock" style="position: absolute; left: 10px; top: 10px; width: 40px; height: 20px; background: #00ff00"></div>
+	<div id="hidden" style="display: none">Hidden</div>
+</body>
+</html>
+`
+
+// statusPage replaces its status element, then changes its text, as an import
+// progresses.
+var statusPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Status Page</title>
+</head>
+<body>
+	<div id="area"><p id="status">Importing…</p></div>
+	<script>
+		setTimeout(function() {
+			document.getElementById("area").innerHTML = '<p id="status">  1 item\n imported </p>';
+		}, 200);
+		setTimeout(function() {
+			document.getElementById("status").textContent = "3 items   imported";
+		}, 400);
+	</script>
+</body>
+</html>
+`
+
+// routesPage is a single-page application that goes through a transient
+// route when its button is clicked.
+var routesPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Routes Page</title>
+</head>
+<body>
+	<button id="open" onclick="history.pushState({}, '', '/routes/items/3'); history.replaceState({}, '', '/routes/items/3/edit')">Open</button>
+</body>
+</html>
+`
+
+// jsErrorsPage has buttons that throw or reject a promise when clicked.
+var jsErrorsPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - JavaScript Errors Page</title>
+</head>
+<body>
+	<button id="throw" onclick="throw new Error('boom')">Throw</button>
+	<button id="noise" onclick="throw new Error('third-party noise')">Noise</button>
+	<button id="reject" onclick="Promise.reject(new Error('rejected'))">Reject</button>
+	<button id="quiet" onclick="this.textContent = 'Clicked'">Quiet</button>
+</body>
+</html>
+`
+
 var framePage = `
 <html>
 <head>
@@ -1574,6 +3284,90 @@ var framePage = `
 </html>
 `
 
+var nestedFramePage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Nested Frame Page</title>
+</head>
+<body>
+	This page contains nested frames.
+
+	<iframe id="otherFrame" src="/other"></iframe>
+	<iframe id="framesFrame" src="/frame"></iframe>
+	<div id="outsideOfFrames"></div>
+</body>
+</html>
+`
+
+// unicodeText contains characters outside the Basic Multilingual Plane, which
+// UTF-16 encodes as surrogate pairs, and a combining sequence.
+const unicodeText = "emoji \U0001F600, CJK \U0002000B, ZWJ \U0001F469\u200D\U0001F4BB, combining e\u0301"
+
+var unicodeTextPage = `
+<html>
+<head>
+	<meta charset="utf-8">
+	<title>Go Selenium Test Suite - Text Page</title>
+</head>
+<body>
+	<p id="text" title="` + unicodeText + `">` + unicodeText + `</p>
+	<p id="lone">lone surrogate</p>
+	<input id="input" type="text">
+	<script>
+		document.getElementById('lone').setAttribute('data-text', 'a\uD83Db');
+	</script>
+</body>
+</html>
+`
+
+var interactPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Interactability Page</title>
+	<style>
+		#overlay { position: fixed; top: 0; left: 0; width: 100%; height: 100px; background: white; }
+	</style>
+</head>
+<body>
+	<button id="covered" style="position: absolute; top: 20px;">Covered</button>
+	<div id="overlay"></div>
+	<div style="margin-top: 120px;">
+		<button id="ok"><span>Label</span></button>
+		<button id="disabled" disabled>Disabled</button>
+		<button id="hidden" style="visibility: hidden;">Hidden</button>
+		<button id="empty" style="width: 0; height: 0; padding: 0; border: 0;"></button>
+	</div>
+</body>
+</html>
+`
+
+var tabOrderPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Tab Order Page</title>
+</head>
+<body>
+	<p>Some text that cannot be focused.</p>
+	<a href="#top" id="first">First</a>
+	<button id="second" aria-label="Second">2</button>
+	<input id="third" type="checkbox"><label for="third">Third</label>
+	<div id="trap" style="display: none">
+		<button id="trapA">A</button>
+		<button id="trapB">B</button>
+	</div>
+	<script>
+		// When shown, the trap keeps the focus on its two buttons.
+		document.getElementById('trapB').addEventListener('keydown', function(e) {
+			if (e.key === 'Tab') {
+				e.preventDefault();
+				document.getElementById('trapA').focus();
+			}
+		});
+	</script>
+</body>
+</html>
+`
+
 var titleChangePage = `
 <html>
 <head>
@@ -1604,16 +3398,71 @@ var alertPage = `
 </html>
 `
 
+// encodedPage is a page served in a legacy character encoding.
+var headersPage = `
+<html>
+<head>
+	<title>Go Selenium Test Suite - Headers Page</title>
+</head>
+<body>
+	<div id="user-agent">%s</div>
+	<div id="accept-language">%s</div>
+</body>
+</html>
+`
+
+type encodedPage struct {
+	charset string
+	// body is the page in charset.
+	body string
+	// greeting is the text of the element with ID "greeting", in UTF-8.
+	greeting string
+}
+
+var encodedPages = map[string]encodedPage{
+	"/windows-1251": {
+		charset:  "windows-1251",
+		body:     "<html><head><meta charset=\"windows-1251\"><title>\xcf\xf0\xe8\xe2\xe5\xf2</title></head><body><p id=\"greeting\">\xcf\xf0\xe8\xe2\xe5\xf2, \xec\xe8\xf0</p></body></html>",
+		greeting: "Привет, мир",
+	},
+	"/shift_jis": {
+		charset:  "Shift_JIS",
+		body:     "<html><head><meta charset=\"shift_jis\"><title>\x88\xa5\x8eA</title></head><body><p id=\"greeting\">\x82\xb1\x82\xf1\x82\xc9\x82\xbf\x82\xcd\x90\xa2\x8aE</p></body></html>",
+		greeting: "こんにちは世界",
+	},
+}
+
 var Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 	path := r.URL.Path
+	if path == "/headers" {
+		fmt.Fprintf(w, headersPage, html.EscapeString(r.UserAgent()), html.EscapeString(r.Header.Get("Accept-Language")))
+		return
+	}
+	if p, ok := encodedPages[path]; ok {
+		w.Header().Set("Content-Type", "text/html; charset="+p.charset)
+		fmt.Fprint(w, p.body)
+		return
+	}
 	page, ok := map[string]string{
-		"/":       homePage,
-		"/other":  otherPage,
-		"/search": searchPage,
-		"/log":    logPage,
-		"/frame":  framePage,
-		"/title":  titleChangePage,
-		"/alert":  alertPage,
+		"/":         homePage,
+		"/other":    otherPage,
+		"/search":   searchPage,
+		"/log":      logPage,
+		"/frame":    framePage,
+		"/input":    inputPage,
+		"/clear":    clearPage,
+		"/jserrors": jsErrorsPage,
+		"/mask":     maskPage,
+		"/nested":   nestedFramePage,
+		"/tab":      tabOrderPage,
+		"/title":    titleChangePage,
+		"/alert":    alertPage,
+		"/text":     unicodeTextPage,
+		"/interact": interactPage,
+		"/status":   statusPage,
+		"/routes":   routesPage,
+		"/relative": relativePage,
+		"/shadow":   shadowPage,
 	}[path]
 	if !ok {
 		http.NotFound(w, r)
@@ -1749,4 +3598,176 @@ func testChromeExtension(t *testing.T, c Config) {
 func RunChromeTests(t *testing.T, c Config) {
 	// Chrome-specific tests.
 	t.Run("Extension", runTest(testChromeExtension, c))
+	t.Run("SeedLocalStorage", runTest(testChromeSeedLocalStorage, c))
+	t.Run("Detach", runTest(testChromeDetach, c))
+	t.Run("PageSourceRaw", runTest(testChromePageSourceRaw, c))
+	t.Run("UserAgentOverride", runTest(testChromeUserAgentOverride, c))
+}
+
+func testChromeUserAgentOverride(t *testing.T, c Config) {
+	wd := newRemote(t, newTestCapabilities(t, c), c)
+	defer quitRemote(t, wd)
+
+	if err := wd.SetUserAgentOverride(testUserAgentString, testLanguage+",fr"); err != nil {
+		t.Fatalf("wd.SetUserAgentOverride() returned error: %v", err)
+	}
+	checkUserAgent(t, wd, c)
+
+	// The client hints must agree with the user agent.
+	brands, err := wd.ExecuteScript("return navigator.userAgentData.brands.map(function(b) { return b.brand + '/' + b.version; });", nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	found := false
+	for _, b := range brands.([]interface{}) {
+		found = found || b == "Chromium/120"
+	}
+	if !found {
+		t.Errorf("navigator.userAgentData.brands = %v, want Chromium/120", brands)
+	}
+}
+
+func testChromePageSourceRaw(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	caps.SetLogLevel(log.Performance, log.All)
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	for path, page := range encodedPages {
+		u := c.ServerURL + path
+		if err := wd.Get(u); err != nil {
+			t.Fatalf("wd.Get(%q) returned error: %v", u, err)
+		}
+		body, charset, err := wd.PageSourceRaw()
+		if err != nil {
+			t.Fatalf("wd.PageSourceRaw() on %s returned error: %v", path, err)
+		}
+		if string(body) != page.body || !strings.EqualFold(charset, page.charset) {
+			t.Errorf("wd.PageSourceRaw() on %s = %q, %q, want %q, %q", path, body, charset, page.body, page.charset)
+		}
+	}
+}
+
+func testChromeSeedLocalStorage(t *testing.T, c Config) {
+	caps := newTestCapabilities(t, c)
+	co := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	dir, err := co.UseTempUserDataDir()
+	if err != nil {
+		t.Fatalf("co.UseTempUserDataDir() returned error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	seed := map[string]string{"cart": "3 items"}
+	if err := co.SeedLocalStorage(c.ServerURL, seed); err != nil {
+		t.Fatalf("co.SeedLocalStorage(%q, %v) returned error: %v", c.ServerURL, seed, err)
+	}
+	caps[chrome.CapabilitiesKey] = co
+
+	wd := newRemote(t, caps, c)
+	defer quitRemote(t, wd)
+
+	if err := wd.Get(c.ServerURL); err != nil {
+		t.Fatalf("wd.Get(%q) returned error: %v", c.ServerURL, err)
+	}
+	const getScript = "return window.localStorage.getItem('cart');"
+	got, err := wd.ExecuteScript(getScript, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", getScript, err)
+	}
+	if got != "3 items" {
+		t.Fatalf("localStorage value of %q = %v, want %q", "cart", got, "3 items")
+	}
+
+	// A value changed by the test must survive a reload.
+	if _, err := wd.ExecuteScript("window.localStorage.setItem('cart', 'empty');", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if err := wd.Refresh(); err != nil {
+		t.Fatalf("wd.Refresh() returned error: %v", err)
+	}
+	got, err = wd.ExecuteScript(getScript, nil)
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript(%q) returned error: %v", getScript, err)
+	}
+	if got != "empty" {
+		t.Fatalf("after a reload, localStorage value of %q = %v, want %q", "cart", got, "empty")
+	}
+}
+
+// browserPIDs returns the PIDs of the processes started with the user data
+// directory in their command line.
+func browserPIDs(t *testing.T, dir string) []int {
+	t.Helper()
+	cmdlines, err := filepath.Glob("/proc/[0-9]*/cmdline")
+	if err != nil {
+		t.Fatalf("listing processes returned error: %v", err)
+	}
+	var pids []int
+	for _, f := range cmdlines {
+		cmdline, err := ioutil.ReadFile(f)
+		if err != nil || !strings.Contains(string(cmdline), "--user-data-dir="+dir) {
+			continue
+		}
+		pid, err := strconv.Atoi(filepath.Base(filepath.Dir(f)))
+		if err != nil {
+			continue
+		}
+		pids = append(pids, pid)
+	}
+	return pids
+}
+
+func testChromeDetach(t *testing.T, c Config) {
+	if runtime.GOOS != "linux" {
+		t.Skip("finding the browser processes requires Linux")
+	}
+	detach := true
+	for _, test := range []struct {
+		desc         string
+		detach       *bool
+		closeBrowser bool
+		wantRunning  bool
+	}{
+		{"Quit", nil, false, false},
+		{"Quit with detach", &detach, false, true},
+		{"QuitAndCloseBrowser with detach", &detach, true, false},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			caps := newTestCapabilities(t, c)
+			co := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+			co.Detach = test.detach
+			dir, err := co.UseTempUserDataDir()
+			if err != nil {
+				t.Fatalf("co.UseTempUserDataDir() returned error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+			caps[chrome.CapabilitiesKey] = co
+
+			wd := newRemote(t, caps, c)
+			pids := browserPIDs(t, dir)
+			if len(pids) == 0 {
+				wd.QuitAndCloseBrowser()
+				t.Fatalf("no browser process found with user data directory %q", dir)
+			}
+			defer func() {
+				for _, pid := range browserPIDs(t, dir) {
+					if p, err := os.FindProcess(pid); err == nil {
+						p.Kill()
+					}
+				}
+			}()
+
+			quit := wd.Quit
+			if test.closeBrowser {
+				quit = wd.QuitAndCloseBrowser
+			}
+			if err := quit(); err != nil {
+				t.Fatalf("quitting returned error: %v", err)
+			}
+			// Give the browser time to exit.
+			time.Sleep(2 * time.Second)
+			if got := len(browserPIDs(t, dir)) > 0; got != test.wantRunning {
+				t.Fatalf("browser running after quitting: %t, want %t", got, test.wantRunning)
+			}
+		})
+	}
 }
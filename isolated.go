@@ -0,0 +1,228 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// IsolatedContext is a browser context of Chrome, created by
+// WebDriver.NewIsolatedContext, whose pages share no cookies, storage or
+// cache with the other pages of the browser, like an incognito window.
+type IsolatedContext interface {
+	// ID returns the DevTools ID of the browser context.
+	ID() string
+	// WindowHandle returns the handle of the window opened with the context.
+	WindowHandle() string
+	// Switch makes the window opened with the context the current window of
+	// the session, so that the commands of the session act on it.
+	Switch() error
+	// NewWindow opens another window in the context, on about:blank, and
+	// returns its handle. The current window is unchanged.
+	NewWindow() (string, error)
+	// Close closes the windows of the context and disposes of it. If the
+	// current window of the session was one of them, the session switches
+	// back to the window that was current when the context was created, or
+	// to another open window if that one was closed too.
+	Close() error
+}
+
+// contextTargetTimeout is how long to wait for the driver to list the window
+// of a new DevTools target.
+var contextTargetTimeout = 2 * time.Second
+
+// createTarget opens a page on about:blank in the browser context of the
+// given ID, and returns its window handle once the driver lists it.
+func (wd *remoteWD) createTarget(contextID string) (string, error) {
+	reply, err := wd.executeCDP("Target.createTarget", map[string]interface{}{
+		"url":              "about:blank",
+		"browserContextId": contextID,
+	})
+	if err != nil {
+		return "", err
+	}
+	var target struct{ TargetID string }
+	if err := json.Unmarshal(reply, &target); err != nil {
+		return "", err
+	}
+	// ChromeDriver names the windows after their DevTools target, once it has
+	// attached to them.
+	deadline := time.Now().Add(contextTargetTimeout)
+	for {
+		handles, err := wd.WindowHandles()
+		if err != nil {
+			return "", err
+		}
+		for _, h := range handles {
+			if h == target.TargetID {
+				return h, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			wd.executeCDP("Target.closeTarget", map[string]interface{}{"targetId": target.TargetID})
+			return "", fmt.Errorf("the driver does not list the windows of new browser contexts: %w", ErrUnsupported)
+		}
+		time.Sleep(wd.defaults.PollInterval)
+	}
+}
+
+// createBrowserContext creates a browser context with a page on about:blank,
+// and returns the ID of the context and the window handle of the page.
+func (wd *remoteWD) createBrowserContext() (id, handle string, err error) {
+	reply, err := wd.executeCDP("Target.createBrowserContext", map[string]interface{}{"disposeOnDetach": false})
+	if err != nil {
+		return "", "", err
+	}
+	var ctx struct{ BrowserContextID string }
+	if err := json.Unmarshal(reply, &ctx); err != nil {
+		return "", "", err
+	}
+	handle, err = wd.createTarget(ctx.BrowserContextID)
+	if err != nil {
+		wd.disposeBrowserContext(ctx.BrowserContextID)
+		return "", "", err
+	}
+	return ctx.BrowserContextID, handle, nil
+}
+
+// disposeBrowserContext closes the pages of the browser context of the given
+// ID and disposes of it.
+func (wd *remoteWD) disposeBrowserContext(id string) error {
+	_, err := wd.executeCDP("Target.disposeBrowserContext", map[string]interface{}{"browserContextId": id})
+	if err != nil {
+		debugLog("error disposing of browser context %s: %v", id, err)
+	}
+	return err
+}
+
+// contextTargets returns the IDs of the page targets of the browser context
+// of the given ID.
+func (wd *remoteWD) contextTargets(id string) (map[string]bool, error) {
+	reply, err := wd.executeCDP("Target.getTargets", nil)
+	if err != nil {
+		return nil, err
+	}
+	var targets struct {
+		TargetInfos []struct {
+			TargetID         string
+			Type             string
+			BrowserContextID string
+		}
+	}
+	if err := json.Unmarshal(reply, &targets); err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool)
+	for _, t := range targets.TargetInfos {
+		if t.Type == "page" && t.BrowserContextID == id {
+			ids[t.TargetID] = true
+		}
+	}
+	return ids, nil
+}
+
+// NewIsolatedContext creates a browser context with a window of its own.
+func (wd *remoteWD) NewIsolatedContext() (IsolatedContext, error) {
+	if !wd.Supports(FeatureCDP) {
+		return nil, fmt.Errorf("creating a browser context: %w", ErrUnsupported)
+	}
+	opener, err := wd.CurrentWindowHandle()
+	if err != nil && !isNoSuchWindowError(err) {
+		return nil, err
+	}
+	id, handle, err := wd.createBrowserContext()
+	if err != nil {
+		return nil, err
+	}
+	c := &isolatedContext{wd: wd, id: id, handle: handle, opener: opener}
+	wd.contextMu.Lock()
+	if wd.contexts == nil {
+		wd.contexts = make(map[string]*isolatedContext)
+	}
+	wd.contexts[id] = c
+	wd.contextMu.Unlock()
+	return c, nil
+}
+
+// closeContexts disposes of the browser contexts created by
+// NewIsolatedContext and not closed yet.
+func (wd *remoteWD) closeContexts() {
+	wd.contextMu.Lock()
+	var ids []string
+	for id := range wd.contexts {
+		ids = append(ids, id)
+	}
+	wd.contexts = nil
+	wd.contextMu.Unlock()
+	for _, id := range ids {
+		wd.disposeBrowserContext(id)
+	}
+}
+
+// isolatedContext is the IsolatedContext of a remoteWD.
+type isolatedContext struct {
+	wd         *remoteWD
+	id, handle string
+	// opener is the current window when the context was created.
+	opener string
+}
+
+func (c *isolatedContext) ID() string {
+	return c.id
+}
+
+func (c *isolatedContext) WindowHandle() string {
+	return c.handle
+}
+
+func (c *isolatedContext) Switch() error {
+	return c.wd.SwitchWindow(c.handle)
+}
+
+func (c *isolatedContext) NewWindow() (string, error) {
+	return c.wd.createTarget(c.id)
+}
+
+func (c *isolatedContext) Close() error {
+	wd := c.wd
+	wd.contextMu.Lock()
+	open := wd.contexts[c.id] == c
+	delete(wd.contexts, c.id)
+	wd.contextMu.Unlock()
+	if !open {
+		return nil
+	}
+
+	targets, err := wd.contextTargets(c.id)
+	if err != nil {
+		return err
+	}
+	current, err := wd.CurrentWindowHandle()
+	if err != nil && !isNoSuchWindowError(err) {
+		return err
+	}
+	if err := wd.disposeBrowserContext(c.id); err != nil {
+		return err
+	}
+	if current != "" && !targets[current] {
+		return nil
+	}
+	// ChromeDriver keeps the closed window current: switch to an open one.
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return err
+	}
+	next := ""
+	for _, h := range handles {
+		if targets[h] {
+			continue
+		}
+		if h == c.opener || next == "" {
+			next = h
+		}
+	}
+	if next == "" {
+		return nil
+	}
+	return wd.SwitchWindow(next)
+}
@@ -0,0 +1,240 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// fakeContexts serves the windows of a fake Chrome browser with browser
+// contexts, each window having the cookies of its context.
+type fakeContexts struct {
+	current string
+	// windows maps the window handles to their browser context, "" for the
+	// default one.
+	windows  map[string]string
+	cookies  map[string][]string
+	next     int
+	disposed []string
+}
+
+func newFakeContexts(d *fakeDriver) *fakeContexts {
+	f := &fakeContexts{
+		current: "main",
+		windows: map[string]string{"main": ""},
+		cookies: map[string][]string{},
+	}
+	d.handle("GET", "/window/handles", func([]byte) (int, interface{}) {
+		handles := []string{}
+		for h := range f.windows {
+			handles = append(handles, h)
+		}
+		sort.Strings(handles)
+		return http.StatusOK, handles
+	})
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		if _, ok := f.windows[f.current]; !ok {
+			return http.StatusNotFound, map[string]interface{}{"error": "no such window", "message": "no such window"}
+		}
+		return http.StatusOK, f.current
+	})
+	d.handle("POST", "/window", func(body []byte) (int, interface{}) {
+		var params struct{ Handle string }
+		json.Unmarshal(body, &params)
+		if _, ok := f.windows[params.Handle]; !ok {
+			return http.StatusNotFound, map[string]interface{}{"error": "no such window", "message": "no such window"}
+		}
+		f.current = params.Handle
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/cookie", func(body []byte) (int, interface{}) {
+		var params struct{ Cookie Cookie }
+		json.Unmarshal(body, &params)
+		ctx := f.windows[f.current]
+		f.cookies[ctx] = append(f.cookies[ctx], params.Cookie.Name)
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/cookie", func([]byte) (int, interface{}) {
+		cookies := []map[string]interface{}{}
+		for _, name := range f.cookies[f.windows[f.current]] {
+			cookies = append(cookies, map[string]interface{}{"name": name, "value": "1"})
+		}
+		return http.StatusOK, cookies
+	})
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params struct{ BrowserContextID string }
+		}
+		json.Unmarshal(body, &params)
+		switch params.Cmd {
+		case "Target.createBrowserContext":
+			f.next++
+			return http.StatusOK, map[string]interface{}{"browserContextId": fmt.Sprintf("context%d", f.next)}
+		case "Target.createTarget":
+			f.next++
+			h := fmt.Sprintf("target%d", f.next)
+			f.windows[h] = params.Params.BrowserContextID
+			return http.StatusOK, map[string]interface{}{"targetId": h}
+		case "Target.getTargets":
+			var infos []map[string]interface{}
+			for h, ctx := range f.windows {
+				infos = append(infos, map[string]interface{}{"targetId": h, "type": "page", "browserContextId": ctx})
+			}
+			return http.StatusOK, map[string]interface{}{"targetInfos": infos}
+		case "Target.disposeBrowserContext":
+			for h, ctx := range f.windows {
+				if ctx == params.Params.BrowserContextID {
+					delete(f.windows, h)
+				}
+			}
+			f.disposed = append(f.disposed, params.Params.BrowserContextID)
+			return http.StatusOK, map[string]interface{}{}
+		}
+		return http.StatusBadRequest, nil
+	})
+	return f
+}
+
+func (f *fakeContexts) handles() []string {
+	var handles []string
+	for h := range f.windows {
+		handles = append(handles, h)
+	}
+	sort.Strings(handles)
+	return handles
+}
+
+func TestNewIsolatedContext(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	f := newFakeContexts(d)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	if err := wd.AddCookie(&Cookie{Name: "default"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	c, err := wd.NewIsolatedContext()
+	if err != nil {
+		t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+	}
+	if f.current != "main" {
+		t.Errorf("wd.NewIsolatedContext() switched to window %q", f.current)
+	}
+	if ctx := f.windows[c.WindowHandle()]; ctx != c.ID() {
+		t.Errorf("c.WindowHandle() = %q, a window of context %q, want one of %q", c.WindowHandle(), ctx, c.ID())
+	}
+
+	if err := c.Switch(); err != nil {
+		t.Fatalf("c.Switch() returned error: %v", err)
+	}
+	if cookies, err := wd.GetCookies(); err != nil {
+		t.Fatalf("wd.GetCookies() returned error: %v", err)
+	} else if len(cookies) != 0 {
+		t.Errorf("wd.GetCookies() in the isolated context returned %+v, want none", cookies)
+	}
+	if err := wd.AddCookie(&Cookie{Name: "isolated"}); err != nil {
+		t.Fatalf("wd.AddCookie() returned error: %v", err)
+	}
+	if want := []string{"default"}; !reflect.DeepEqual(f.cookies[""], want) {
+		t.Errorf("the cookies of the default context are %q, want %q", f.cookies[""], want)
+	}
+
+	w, err := c.NewWindow()
+	if err != nil {
+		t.Fatalf("c.NewWindow() returned error: %v", err)
+	}
+	if f.windows[w] != c.ID() {
+		t.Errorf("c.NewWindow() opened window %q in context %q, want %q", w, f.windows[w], c.ID())
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("c.Close() returned error: %v", err)
+	}
+	if want := []string{"main"}; !reflect.DeepEqual(f.handles(), want) {
+		t.Errorf("c.Close() left windows %q, want %q", f.handles(), want)
+	}
+	if f.current != "main" {
+		t.Errorf("c.Close() left window %q current, want %q", f.current, "main")
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("c.Close() a second time returned error: %v", err)
+	}
+	if want := []string{c.ID()}; !reflect.DeepEqual(f.disposed, want) {
+		t.Errorf("the disposed contexts are %q, want %q", f.disposed, want)
+	}
+}
+
+func TestIsolatedContextCloseElsewhere(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	f := newFakeContexts(d)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	first, err := wd.NewIsolatedContext()
+	if err != nil {
+		t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+	}
+	second, err := wd.NewIsolatedContext()
+	if err != nil {
+		t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+	}
+	if err := second.Switch(); err != nil {
+		t.Fatalf("second.Switch() returned error: %v", err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatalf("first.Close() returned error: %v", err)
+	}
+	if f.current != second.WindowHandle() {
+		t.Errorf("closing another context left window %q current, want %q", f.current, second.WindowHandle())
+	}
+}
+
+func TestIsolatedContextQuit(t *testing.T) {
+	detach := true
+	for _, test := range []struct {
+		desc         string
+		detach       *bool
+		wantDisposed int
+	}{
+		{"Quit", nil, 0},
+		{"Quit with detach", &detach, 2},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			f := newFakeContexts(d)
+			wd := d.newRemote(t)
+			wd.SetSupport(FeatureCDP, true)
+			wd.capabilities = Capabilities{}
+			wd.capabilities.AddChrome(chrome.Capabilities{Detach: test.detach})
+
+			for i := 0; i < 2; i++ {
+				if _, err := wd.NewIsolatedContext(); err != nil {
+					t.Fatalf("wd.NewIsolatedContext() returned error: %v", err)
+				}
+			}
+			if err := wd.Quit(); err != nil {
+				t.Fatalf("wd.Quit() returned error: %v", err)
+			}
+			if len(f.disposed) != test.wantDisposed {
+				t.Errorf("wd.Quit() disposed of contexts %q, want %d", f.disposed, test.wantDisposed)
+			}
+		})
+	}
+}
+
+func TestNewIsolatedContextUnsupported(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, false)
+
+	if _, err := wd.NewIsolatedContext(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.NewIsolatedContext() without CDP returned %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,49 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+)
+
+// WithJSONNumbers makes the session decode the numbers in untyped results,
+// such as the values returned by ExecuteScript and ExecuteScriptAsync and the
+// values of Capabilities, as json.Number instead of float64, so that integers
+// above 2^53, e.g. database IDs, keep all their digits. Typed results are not
+// affected, and integer arguments of scripts are always sent as is.
+//
+// JavaScript numbers are doubles: to keep the digits of such an integer, a
+// script must have received it as an argument or return it as it was
+// received from the remote end, or use a string.
+func WithJSONNumbers() SessionOption {
+	return func(o *sessionOptions) {
+		o.jsonNumbers = true
+	}
+}
+
+// unmarshal decodes the JSON data into v, decoding numbers as json.Number if
+// the session was created with WithJSONNumbers.
+func (wd *remoteWD) unmarshal(data []byte, v interface{}) error {
+	if !wd.jsonNumbers {
+		return json.Unmarshal(data, v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// scriptInt returns the integer in a value decoded from a script result,
+// which is a float64 or, with WithJSONNumbers, a json.Number.
+func scriptInt(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return int64(n), true
+	case json.Number:
+		if i, err := n.Int64(); err == nil {
+			return i, true
+		}
+		f, err := strconv.ParseFloat(string(n), 64)
+		return int64(f), err == nil
+	}
+	return 0, false
+}
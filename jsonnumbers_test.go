@@ -0,0 +1,83 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// echoScriptArgs makes the fake driver return the arguments of the scripts it
+// executes, as they were sent.
+func echoScriptArgs(d *fakeDriver) {
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		params := new(struct{ Args []json.RawMessage })
+		if err := json.Unmarshal(body, params); err != nil {
+			return http.StatusBadRequest, nil
+		}
+		return http.StatusOK, params.Args
+	})
+}
+
+func TestJSONNumbers(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	echoScriptArgs(d)
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithJSONNumbers())
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	const id = int64(1234567890123456789)
+	const big = uint64(18446744073709551615)
+	got, err := wd.ExecuteScript("return arguments;", []interface{}{id, big})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	values, ok := got.([]interface{})
+	if !ok || len(values) != 2 {
+		t.Fatalf("wd.ExecuteScript() = %#v, want 2 values", got)
+	}
+	n, ok := values[0].(json.Number)
+	if !ok {
+		t.Fatalf("wd.ExecuteScript() returned %T, want json.Number", values[0])
+	}
+	if i, err := n.Int64(); err != nil || i != id {
+		t.Errorf("wd.ExecuteScript() returned %s, want %d", n, id)
+	}
+	if s := values[1].(json.Number).String(); s != "18446744073709551615" {
+		t.Errorf("wd.ExecuteScript() returned %s, want %d", s, big)
+	}
+}
+
+func TestJSONNumbersDefault(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	echoScriptArgs(d)
+	wd := d.newRemote(t)
+
+	got, err := wd.ExecuteScript("return arguments;", []interface{}{int64(1234567890123456789)})
+	if err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	if _, ok := got.([]interface{})[0].(float64); !ok {
+		t.Errorf("wd.ExecuteScript() returned %T, want float64", got.([]interface{})[0])
+	}
+}
+
+func TestJSONNumbersFrames(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, json.RawMessage("[0, 2]")
+	})
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithJSONNumbers())
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	path, err := wd.(*remoteWD).currentFramePath()
+	if err != nil {
+		t.Fatalf("wd.currentFramePath() returned error: %v", err)
+	}
+	if len(path) != 2 || path[0] != 0 || path[1] != 2 {
+		t.Errorf("wd.currentFramePath() = %v, want [0 2]", path)
+	}
+}
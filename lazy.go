@@ -0,0 +1,406 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// LazyOption configures a LazyElement.
+type LazyOption func(*LazyElement)
+
+// Within makes the element be looked up among the descendants of parent
+// rather than in the whole document.
+func Within(parent *LazyElement) LazyOption {
+	return func(l *LazyElement) {
+		l.parent = parent
+	}
+}
+
+// RequireVisible makes the element resolve only once it is displayed.
+func RequireVisible() LazyOption {
+	return func(l *LazyElement) {
+		l.visible = true
+	}
+}
+
+// WithResolveTimeout makes the element retry its lookup until it succeeds or
+// until timeout has elapsed. By default, the lookup is attempted once.
+func WithResolveTimeout(timeout time.Duration) LazyOption {
+	return func(l *LazyElement) {
+		l.timeout = timeout
+	}
+}
+
+// LazyElement is a WebElement bound to a locator rather than to a DOM node.
+// The locator is resolved when a method is called, and the element found is
+// kept until the remote end reports it as stale, at which point the locator is
+// resolved again and the method retried once. This lets page objects declare
+// their elements once, when the page may not even be loaded yet.
+//
+// FindElement and FindElements return LazyElements that are looked up within
+// this one. They inherit its resolution timeout.
+type LazyElement struct {
+	wd      WebDriver
+	locator Locator
+	parent  *LazyElement
+	// index is the position of the element among the matches of the locator
+	// for elements returned by FindElements, and -1 otherwise.
+	index   int
+	visible bool
+	timeout time.Duration
+
+	mu     sync.Mutex
+	cached WebElement
+}
+
+var _ WebElement = (*LazyElement)(nil)
+
+// Lazy returns an element that finds the first element matching the locator
+// each time it needs to.
+func Lazy(wd WebDriver, l Locator, opts ...LazyOption) *LazyElement {
+	e := &LazyElement{wd: wd, locator: l, index: -1}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// String returns the chain of locators of the element, for use in messages.
+func (l *LazyElement) String() string {
+	s := l.locator.String()
+	if l.index >= 0 {
+		s += fmt.Sprintf("[%d]", l.index)
+	}
+	if l.parent != nil {
+		s = l.parent.String() + " > " + s
+	}
+	return s
+}
+
+// Resolve returns the element currently designated by the locator.
+func (l *LazyElement) Resolve() (WebElement, error) {
+	l.mu.Lock()
+	cached := l.cached
+	l.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	polls := 0
+	if wd, ok := l.wd.(*remoteWD); ok && l.timeout > 0 {
+		end := wd.startWait()
+		defer func() { end(polls) }()
+	}
+	deadline := time.Now().Add(l.timeout)
+	for {
+		polls++
+		elem, err := l.find()
+		if err == nil {
+			l.mu.Lock()
+			l.cached = elem
+			l.mu.Unlock()
+			return elem, nil
+		}
+		if !isNoSuchElementError(err) && !isStaleElementError(err) && !isNotDisplayedError(err) || time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(l.wd.Defaults().PollInterval)
+	}
+}
+
+// invalidate forgets the element found, if it is still elem.
+func (l *LazyElement) invalidate(elem WebElement) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cached == elem {
+		l.cached = nil
+	}
+}
+
+// notDisplayedError is returned when a LazyElement created with
+// RequireVisible is found but not displayed.
+type notDisplayedError struct {
+	what string
+}
+
+func (e *notDisplayedError) Error() string {
+	return fmt.Sprintf("element %s is not displayed", e.what)
+}
+
+func isNotDisplayedError(err error) bool {
+	_, ok := err.(*notDisplayedError)
+	return ok
+}
+
+// isStaleElementError reports whether err indicates that an element is no
+// longer attached to the document.
+func isStaleElementError(err error) bool {
+	return errors.Is(err, werror.StaleElementReference)
+}
+
+// find looks the element up, without retrying.
+func (l *LazyElement) find() (WebElement, error) {
+	var elem WebElement
+	var err error
+	switch {
+	case l.parent != nil && l.index >= 0:
+		err = l.parent.do(func(p WebElement) error {
+			elem, err = nthOf(l.index)(p.FindElements(l.locator.By, l.locator.Value))
+			return err
+		})
+	case l.parent != nil:
+		err = l.parent.do(func(p WebElement) error {
+			elem, err = p.FindElement(l.locator.By, l.locator.Value)
+			return err
+		})
+	default:
+		elem, err = l.wd.FindElement(l.locator.By, l.locator.Value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if l.visible {
+		displayed, err := elem.IsDisplayed()
+		if err != nil {
+			return nil, err
+		}
+		if !displayed {
+			return nil, &notDisplayedError{what: l.String()}
+		}
+	}
+	return elem, nil
+}
+
+// nthOf returns a function that picks the element at index from the result
+// of FindElements.
+func nthOf(index int) func([]WebElement, error) (WebElement, error) {
+	return func(elems []WebElement, err error) (WebElement, error) {
+		if err != nil {
+			return nil, err
+		}
+		if index >= len(elems) {
+			return nil, &Error{Err: string(werror.NoSuchElement), Message: fmt.Sprintf("only %d elements match, not %d", len(elems), index+1)}
+		}
+		return elems[index], nil
+	}
+}
+
+// do calls f with the element, resolving it again and retrying once if the
+// element found before has become stale.
+func (l *LazyElement) do(f func(WebElement) error) error {
+	elem, err := l.Resolve()
+	if err != nil {
+		return err
+	}
+	if err := f(elem); !isStaleElementError(err) {
+		return err
+	}
+	l.invalidate(elem)
+	if elem, err = l.Resolve(); err != nil {
+		return err
+	}
+	return f(elem)
+}
+
+func (l *LazyElement) Click(opts ...ActionOption) error {
+	return l.do(func(e WebElement) error { return e.Click(opts...) })
+}
+
+func (l *LazyElement) SendKeys(keys string, opts ...ActionOption) error {
+	return l.do(func(e WebElement) error { return e.SendKeys(keys, opts...) })
+}
+
+func (l *LazyElement) SetText(text string) error {
+	return l.do(func(e WebElement) error { return e.SetText(text) })
+}
+
+func (l *LazyElement) AppendText(text string) error {
+	return l.do(func(e WebElement) error { return e.AppendText(text) })
+}
+
+func (l *LazyElement) UploadBytes(filename string, data []byte, mime string) error {
+	return l.do(func(e WebElement) error { return e.UploadBytes(filename, data, mime) })
+}
+
+func (l *LazyElement) Submit() error {
+	return l.do(func(e WebElement) error { return e.Submit() })
+}
+
+func (l *LazyElement) Clear() error {
+	return l.do(func(e WebElement) error { return e.Clear() })
+}
+
+func (l *LazyElement) ClearWithEvents() error {
+	return l.do(func(e WebElement) error { return e.ClearWithEvents() })
+}
+
+func (l *LazyElement) MoveTo(xOffset, yOffset int) error {
+	return l.do(func(e WebElement) error { return e.MoveTo(xOffset, yOffset) })
+}
+
+// FindElement returns a LazyElement looked up within this one. It does not
+// contact the remote end, so it never fails; lookup errors are returned by the
+// methods of the element returned.
+func (l *LazyElement) FindElement(by, value string) (WebElement, error) {
+	return &LazyElement{wd: l.wd, locator: Locator{By: by, Value: value}, parent: l, index: -1, timeout: l.timeout}, nil
+}
+
+// FindElements finds the elements matching the locator within this one, and
+// returns them as LazyElements that each resolve to the match at the same
+// position.
+func (l *LazyElement) FindElements(by, value string) ([]WebElement, error) {
+	var n int
+	err := l.do(func(e WebElement) error {
+		elems, err := e.FindElements(by, value)
+		n = len(elems)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	children := make([]WebElement, n)
+	for i := range children {
+		children[i] = &LazyElement{wd: l.wd, locator: Locator{By: by, Value: value}, parent: l, index: i, timeout: l.timeout}
+	}
+	return children, nil
+}
+
+func (l *LazyElement) TagName() (string, error) {
+	return l.stringQuery(func(e WebElement) (string, error) { return e.TagName() })
+}
+
+func (l *LazyElement) Text() (string, error) {
+	return l.stringQuery(func(e WebElement) (string, error) { return e.Text() })
+}
+
+func (l *LazyElement) IsSelected() (bool, error) {
+	return l.boolQuery(func(e WebElement) (bool, error) { return e.IsSelected() })
+}
+
+func (l *LazyElement) IsEnabled() (bool, error) {
+	return l.boolQuery(func(e WebElement) (bool, error) { return e.IsEnabled() })
+}
+
+func (l *LazyElement) IsDisplayed() (bool, error) {
+	return l.boolQuery(func(e WebElement) (bool, error) { return e.IsDisplayed() })
+}
+
+func (l *LazyElement) GetAttribute(name string) (string, error) {
+	return l.stringQuery(func(e WebElement) (string, error) { return e.GetAttribute(name) })
+}
+
+func (l *LazyElement) GetProperty(name string) (string, error) {
+	return l.stringQuery(func(e WebElement) (string, error) { return e.GetProperty(name) })
+}
+
+func (l *LazyElement) CSSProperty(name string) (string, error) {
+	return l.stringQuery(func(e WebElement) (string, error) { return e.CSSProperty(name) })
+}
+
+func (l *LazyElement) Location() (*Point, error) {
+	var p *Point
+	err := l.do(func(e WebElement) (err error) {
+		p, err = e.Location()
+		return err
+	})
+	return p, err
+}
+
+func (l *LazyElement) LocationInView() (*Point, error) {
+	var p *Point
+	err := l.do(func(e WebElement) (err error) {
+		p, err = e.LocationInView()
+		return err
+	})
+	return p, err
+}
+
+func (l *LazyElement) Size() (*Size, error) {
+	var s *Size
+	err := l.do(func(e WebElement) (err error) {
+		s, err = e.Size()
+		return err
+	})
+	return s, err
+}
+
+func (l *LazyElement) Screenshot(scroll bool) ([]byte, error) {
+	var b []byte
+	err := l.do(func(e WebElement) (err error) {
+		b, err = e.Screenshot(scroll)
+		return err
+	})
+	return b, err
+}
+
+func (l *LazyElement) ShadowRoot() (ShadowRoot, error) {
+	var r ShadowRoot
+	err := l.do(func(e WebElement) (err error) {
+		r, err = e.ShadowRoot()
+		return err
+	})
+	return r, err
+}
+
+func (l *LazyElement) EnsureInteractable() error {
+	return l.do(func(e WebElement) error { return e.EnsureInteractable() })
+}
+
+// ID returns the reference of the element currently designated by the
+// locator, or the empty string if it cannot be found.
+func (l *LazyElement) ID() string {
+	elem, err := l.Resolve()
+	if err != nil {
+		return ""
+	}
+	return elem.ID()
+}
+
+// MarshalJSON encodes the element currently designated by the locator, so
+// that a LazyElement can be passed to ExecuteScript.
+func (l *LazyElement) MarshalJSON() ([]byte, error) {
+	elem, err := l.Resolve()
+	if err != nil {
+		return nil, err
+	}
+	if m, ok := elem.(interface{ MarshalJSON() ([]byte, error) }); ok {
+		return m.MarshalJSON()
+	}
+	return nil, fmt.Errorf("element %s of type %T cannot be encoded", l, elem)
+}
+
+func (l *LazyElement) stringQuery(f func(WebElement) (string, error)) (string, error) {
+	var s string
+	err := l.do(func(e WebElement) (err error) {
+		s, err = f(e)
+		return err
+	})
+	return s, err
+}
+
+func (l *LazyElement) boolQuery(f func(WebElement) (bool, error)) (bool, error) {
+	var b bool
+	err := l.do(func(e WebElement) (err error) {
+		b, err = f(e)
+		return err
+	})
+	return b, err
+}
+
+// unwrapLazy returns the element designated by e if it is a LazyElement, and
+// e otherwise.
+func unwrapLazy(e WebElement) (WebElement, error) {
+	l, ok := e.(*LazyElement)
+	if !ok {
+		return e, nil
+	}
+	elem, err := l.Resolve()
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %v", l, err)
+	}
+	return unwrapLazy(elem)
+}
@@ -0,0 +1,120 @@
+package selenium
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func staleElementReply() (int, interface{}) {
+	return http.StatusNotFound, map[string]string{
+		"error":   "stale element reference",
+		"message": "element is not attached to the page document",
+	}
+}
+
+func TestLazyElementStale(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	found := 0
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		found++
+		if found == 1 {
+			return http.StatusOK, map[string]string{webElementIdentifier: "old"}
+		}
+		return http.StatusOK, map[string]string{webElementIdentifier: "new"}
+	})
+	d.handle("POST", "/element/old/click", func([]byte) (int, interface{}) {
+		return staleElementReply()
+	})
+	d.handle("POST", "/element/new/click", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	submit := Lazy(wd, ByCSS("#submit"))
+	if n := d.count("POST", "/element"); n != 0 {
+		t.Fatalf("Lazy() sent %d Find Element requests, want none", n)
+	}
+	if got := submit.ID(); got != "old" {
+		t.Fatalf("submit.ID() = %q, want %q", got, "old")
+	}
+	if err := submit.Click(); err != nil {
+		t.Fatalf("submit.Click() returned error: %v", err)
+	}
+	if got := submit.ID(); got != "new" {
+		t.Errorf("after a stale click, submit.ID() = %q, want %q", got, "new")
+	}
+	if err := submit.Click(); err != nil {
+		t.Fatalf("submit.Click() returned error: %v", err)
+	}
+	if n := d.count("POST", "/element"); n != 2 {
+		t.Errorf("sent %d Find Element requests, want 2", n)
+	}
+}
+
+func TestLazyElementChildren(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{webElementIdentifier: "form"}
+	})
+	d.handle("POST", "/element/form/elements", func([]byte) (int, interface{}) {
+		return http.StatusOK, []map[string]string{
+			{webElementIdentifier: "first"},
+			{webElementIdentifier: "second"},
+		}
+	})
+	d.handle("GET", "/element/second/text", func([]byte) (int, interface{}) {
+		return http.StatusOK, "Second field"
+	})
+	wd := d.newRemote(t)
+
+	form := Lazy(wd, ByCSS("form"))
+	inputs, err := form.FindElements(ByCSSSelector, "input")
+	if err != nil {
+		t.Fatalf("form.FindElements() returned error: %v", err)
+	}
+	if len(inputs) != 2 {
+		t.Fatalf("form.FindElements() returned %d elements, want 2", len(inputs))
+	}
+	got, err := inputs[1].Text()
+	if err != nil {
+		t.Fatalf("inputs[1].Text() returned error: %v", err)
+	}
+	if want := "Second field"; got != want {
+		t.Errorf("inputs[1].Text() = %q, want %q", got, want)
+	}
+	want := `css selector "form" > css selector "input"[1]`
+	if got := inputs[1].(*LazyElement).String(); got != want {
+		t.Errorf("inputs[1].String() = %q, want %q", got, want)
+	}
+
+	child, err := form.FindElement(ByCSSSelector, "button")
+	if err != nil {
+		t.Fatalf("form.FindElement() returned error: %v", err)
+	}
+	if _, err := child.Text(); err == nil {
+		t.Errorf("Text() of a child that cannot be found returned no error")
+	}
+}
+
+func TestLazyElementRequireVisible(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{webElementIdentifier: "dialog"}
+	})
+	checks := 0
+	d.handle("GET", "/element/dialog/displayed", func([]byte) (int, interface{}) {
+		checks++
+		return http.StatusOK, checks > 1
+	})
+	wd := d.newRemote(t)
+
+	if _, err := Lazy(wd, ByCSS(".dialog"), RequireVisible()).Resolve(); !isNotDisplayedError(err) {
+		t.Errorf("Resolve() of a hidden element returned %v, want a not displayed error", err)
+	}
+	checks = 0
+	dialog := Lazy(wd, ByCSS(".dialog"), RequireVisible(), WithResolveTimeout(time.Second))
+	if _, err := dialog.Resolve(); err != nil {
+		t.Errorf("Resolve() of an element that becomes visible returned error: %v", err)
+	}
+}
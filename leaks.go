@@ -0,0 +1,188 @@
+package selenium
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// ServiceLeak describes a service started but not stopped.
+type ServiceLeak struct {
+	// PID is the process ID of the service.
+	PID int
+	// Path is the path of the binary of the service.
+	Path string
+	// Started is when the service was started.
+	Started time.Time
+}
+
+// LeakReport lists the resources created by the package and not released, as
+// returned by LeakCheck.
+type LeakReport struct {
+	// Services are the services started and not stopped.
+	Services []ServiceLeak
+	// Processes are the processes found running by Service.Stop after it
+	// killed the processes of the service, and still running.
+	Processes []int
+	// TempPaths are the temporary files and directories created by the
+	// package, or by chrome.Capabilities.UseTempUserDataDir for a session,
+	// that still exist: upload directories, fixtures, user data directories
+	// and frame buffer authorization files.
+	TempPaths []string
+	// OpenPipes is the number of pipes still open to capture the output of
+	// services, which are closed when the service is stopped.
+	OpenPipes int
+}
+
+// Empty reports whether nothing leaked.
+func (r LeakReport) Empty() bool {
+	return len(r.Services) == 0 && len(r.Processes) == 0 && len(r.TempPaths) == 0 && r.OpenPipes == 0
+}
+
+func (r LeakReport) String() string {
+	if r.Empty() {
+		return "no leaks"
+	}
+	var b strings.Builder
+	for _, s := range r.Services {
+		fmt.Fprintf(&b, "service %s (pid %d) started at %s was not stopped\n", s.Path, s.PID, s.Started.Format(time.RFC3339))
+	}
+	for _, pid := range r.Processes {
+		fmt.Fprintf(&b, "process %d survived the service that started it\n", pid)
+	}
+	for _, p := range r.TempPaths {
+		fmt.Fprintf(&b, "temporary path %s was not removed\n", p)
+	}
+	if r.OpenPipes > 0 {
+		fmt.Fprintf(&b, "%d output pipes are still open\n", r.OpenPipes)
+	}
+	return b.String()
+}
+
+// leaks records the resources that LeakCheck reports.
+var leaks = struct {
+	sync.Mutex
+	services  map[*Service]ServiceLeak
+	processes map[int]bool
+	tempPaths map[string]bool
+}{
+	services:  make(map[*Service]ServiceLeak),
+	processes: make(map[int]bool),
+	tempPaths: make(map[string]bool),
+}
+
+func trackService(s *Service) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	leaks.services[s] = ServiceLeak{PID: s.cmd.Process.Pid, Path: s.cmd.Path, Started: time.Now()}
+}
+
+func untrackService(s *Service) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	delete(leaks.services, s)
+}
+
+// serviceAt returns the running service whose URLPrefix is urlPrefix, or
+// nil.
+func serviceAt(urlPrefix string) *Service {
+	urlPrefix = strings.TrimSuffix(urlPrefix, "/")
+	leaks.Lock()
+	defer leaks.Unlock()
+	for s := range leaks.services {
+		if strings.TrimSuffix(s.URLPrefix(), "/") == urlPrefix {
+			return s
+		}
+	}
+	return nil
+}
+
+func trackSurvivors(pids []int) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	for _, pid := range pids {
+		leaks.processes[pid] = true
+	}
+}
+
+func trackTempPath(path string) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	leaks.tempPaths[path] = true
+}
+
+func untrackTempPath(path string) {
+	leaks.Lock()
+	defer leaks.Unlock()
+	delete(leaks.tempPaths, path)
+}
+
+// trackTempUserDataDir records the temporary user data directory set in the
+// Chrome capabilities of the session, if any.
+func (wd *remoteWD) trackTempUserDataDir() {
+	var dir string
+	switch c := wd.capabilities[chrome.CapabilitiesKey].(type) {
+	case chrome.Capabilities:
+		dir = c.UserDataDir()
+	case *chrome.Capabilities:
+		dir = c.UserDataDir()
+	}
+	if chrome.IsTempUserDataDir(dir) {
+		trackTempPath(dir)
+	}
+}
+
+// LeakCheck reports the services started and not stopped, the processes that
+// survived their service, the temporary paths not removed and the pipes left
+// open since the program started. Resources that were released by other
+// means since, e.g. a temporary directory removed by the caller, are not
+// reported.
+func LeakCheck() LeakReport {
+	leaks.Lock()
+	defer leaks.Unlock()
+	var r LeakReport
+	for s, l := range leaks.services {
+		r.Services = append(r.Services, l)
+		r.OpenPipes += s.outputPipes()
+	}
+	sort.Slice(r.Services, func(i, j int) bool { return r.Services[i].PID < r.Services[j].PID })
+	for pid := range leaks.processes {
+		if processRunning(pid) {
+			r.Processes = append(r.Processes, pid)
+		} else {
+			delete(leaks.processes, pid)
+		}
+	}
+	sort.Ints(r.Processes)
+	for p := range leaks.tempPaths {
+		if _, err := os.Lstat(p); err == nil {
+			r.TempPaths = append(r.TempPaths, p)
+		} else {
+			delete(leaks.tempPaths, p)
+		}
+	}
+	sort.Strings(r.TempPaths)
+	return r
+}
+
+// VerifyNoLeaks runs the tests of m, a *testing.M, and exits with a failure
+// if LeakCheck then reports leaks, which are printed. Call it from TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		selenium.VerifyNoLeaks(m)
+//	}
+func VerifyNoLeaks(m interface{ Run() int }) {
+	code := m.Run()
+	if r := LeakCheck(); !r.Empty() {
+		fmt.Fprintf(os.Stderr, "selenium: leaks after the tests:\n%s", r)
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,86 @@
+package selenium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// hasTempPath reports whether LeakCheck reports path.
+func hasTempPath(path string) bool {
+	for _, p := range LeakCheck().TempPaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLeakCheckTempPaths(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-leak-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	trackTempPath(dir)
+	defer untrackTempPath(dir)
+
+	if !hasTempPath(dir) {
+		t.Fatalf("LeakCheck().TempPaths = %v, want it to include %s", LeakCheck().TempPaths, dir)
+	}
+	// Paths removed by other means are not reported.
+	os.RemoveAll(dir)
+	if hasTempPath(dir) {
+		t.Errorf("LeakCheck().TempPaths = %v after %s was removed, want it excluded", LeakCheck().TempPaths, dir)
+	}
+}
+
+func TestLeakCheckUploads(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var typed string
+	d.handle("POST", "/element/input/value", func(body []byte) (int, interface{}) {
+		var params struct{ Text string }
+		json.Unmarshal(body, &params)
+		typed = params.Text
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+	input := &remoteWE{parent: wd, id: "input"}
+	if err := input.UploadBytes("report.csv", []byte("a,b\n"), "text/csv"); err != nil {
+		t.Fatalf("input.UploadBytes() returned error: %v", err)
+	}
+	if dir := filepath.Dir(typed); !hasTempPath(dir) {
+		t.Errorf("LeakCheck().TempPaths = %v before wd.Quit(), want it to include %s", LeakCheck().TempPaths, dir)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if dir := filepath.Dir(typed); hasTempPath(dir) {
+		t.Errorf("LeakCheck().TempPaths = %v after wd.Quit(), want it to exclude %s", LeakCheck().TempPaths, dir)
+	}
+}
+
+func TestLeakReportString(t *testing.T) {
+	if r := (LeakReport{}); !r.Empty() || r.String() != "no leaks" {
+		t.Errorf("LeakReport{}: Empty() = %t, String() = %q; want true, \"no leaks\"", r.Empty(), r.String())
+	}
+	r := LeakReport{
+		Services:  []ServiceLeak{{PID: 12, Path: "/usr/bin/chromedriver"}},
+		Processes: []int{34},
+		TempPaths: []string{"/tmp/selenium-upload-1"},
+		OpenPipes: 1,
+	}
+	if r.Empty() {
+		t.Errorf("%+v.Empty() = true, want false", r)
+	}
+	s := r.String()
+	for _, want := range []string{"/usr/bin/chromedriver (pid 12)", "process 34", "/tmp/selenium-upload-1", "1 output pipes"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("LeakReport.String() = %q, want it to contain %q", s, want)
+		}
+	}
+}
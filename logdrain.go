@@ -0,0 +1,126 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// DrainOption configures DrainLogs.
+type DrainOption func(*drainOptions)
+
+type drainOptions struct {
+	interval  time.Duration
+	highWater int
+}
+
+const (
+	defaultDrainInterval  = 5 * time.Second
+	defaultDrainHighWater = 1000
+)
+
+// DrainInterval sets the interval between the pulls of DrainLogs. It
+// defaults to five seconds and cannot be shorter than 10ms.
+func DrainInterval(interval time.Duration) DrainOption {
+	return func(o *drainOptions) {
+		o.interval = interval
+	}
+}
+
+// DrainHighWater sets the number of entries of a single pull of DrainLogs
+// above which a warning is sent to the sink. It defaults to 1000.
+func DrainHighWater(entries int) DrainOption {
+	return func(o *drainOptions) {
+		o.highWater = entries
+	}
+}
+
+// DrainLogs pulls the logs of the given types periodically and sends their
+// entries to sink.
+func (wd *remoteWD) DrainLogs(ctx context.Context, types []log.Type, sink func(log.Type, log.Message), opts ...DrainOption) error {
+	o := drainOptions{interval: defaultDrainInterval, highWater: defaultDrainHighWater}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	url := wd.pollURL("/session/%s/log")
+	pull := func(tag commandTag) error {
+		for _, typ := range types {
+			data, err := json.Marshal(map[string]log.Type{"type": typ})
+			if err != nil {
+				return err
+			}
+			response, err := wd.executeTagged(tag, "POST", url, data)
+			if err != nil {
+				return fmt.Errorf("draining the %s log: %w", typ, err)
+			}
+			messages, err := decodeLog(response)
+			if err != nil {
+				return fmt.Errorf("draining the %s log: %w", typ, err)
+			}
+			if o.highWater > 0 && len(messages) > o.highWater {
+				warning := fmt.Sprintf("drained %d entries of the %s log at once, more than %d: shorten the interval of DrainLogs so that the driver does not buffer them", len(messages), typ, o.highWater)
+				debugLog("%s", warning)
+				sink(log.Client, log.Message{Timestamp: time.Now(), Level: log.Warning, Message: warning})
+			}
+			for _, m := range messages {
+				sink(typ, m)
+			}
+		}
+		return nil
+	}
+	// The first pull reports the errors, e.g. for a log type that the driver
+	// does not support.
+	if err := pull(tagTest); err != nil {
+		return err
+	}
+
+	d := wd.startLoop()
+	go func() {
+		defer close(d.done)
+		poll(ctx, d.stop, o.interval, "draining the logs", func() error {
+			return pull(tagBackground)
+		})
+		select {
+		case <-ctx.Done():
+		case <-d.stop:
+			if err := pull(tagBackground); err != nil {
+				debugLog("error draining the logs before quitting: %v", err)
+			}
+		default:
+		}
+	}()
+	return nil
+}
+
+// backgroundLoop is a loop started by DrainLogs or WithPeriodicSnapshots,
+// stopped by Quit.
+type backgroundLoop struct {
+	// stop is closed to make the loop exit, after pulling the logs one last
+	// time for DrainLogs, which closes done.
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startLoop registers a background loop, which must close done on exit.
+func (wd *remoteWD) startLoop() *backgroundLoop {
+	l := &backgroundLoop{stop: make(chan struct{}), done: make(chan struct{})}
+	wd.loopMu.Lock()
+	wd.loops = append(wd.loops, l)
+	wd.loopMu.Unlock()
+	return l
+}
+
+// stopLoops stops the background loops and waits for them to exit.
+func (wd *remoteWD) stopLoops() {
+	wd.loopMu.Lock()
+	loops := wd.loops
+	wd.loops = nil
+	wd.loopMu.Unlock()
+	for _, l := range loops {
+		close(l.stop)
+		<-l.done
+	}
+}
@@ -0,0 +1,132 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// fakeLogs is a driver log buffer, emptied by each pull.
+type fakeLogs struct {
+	mu      sync.Mutex
+	pending map[log.Type][]string
+}
+
+func (l *fakeLogs) add(typ log.Type, messages ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pending[typ] = append(l.pending[typ], messages...)
+}
+
+func (l *fakeLogs) install(d *fakeDriver) {
+	d.handle("POST", "/log", func(body []byte) (int, interface{}) {
+		params := new(struct{ Type log.Type })
+		json.Unmarshal(body, params)
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		var entries []map[string]interface{}
+		for _, m := range l.pending[params.Type] {
+			entries = append(entries, map[string]interface{}{"timestamp": 1, "level": "INFO", "message": m})
+		}
+		delete(l.pending, params.Type)
+		return http.StatusOK, entries
+	})
+}
+
+// logSink collects the entries sent by DrainLogs.
+type logSink struct {
+	mu      sync.Mutex
+	entries []string
+}
+
+func (s *logSink) add(typ log.Type, m log.Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, string(typ)+": "+m.Message)
+}
+
+func (s *logSink) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+func TestDrainLogs(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	logs := &fakeLogs{pending: map[log.Type][]string{log.Driver: {"started"}}}
+	logs.install(d)
+	wd := d.newRemote(t)
+
+	sink := new(logSink)
+	if err := wd.DrainLogs(context.Background(), []log.Type{log.Driver, log.Browser}, sink.add, DrainInterval(time.Millisecond)); err != nil {
+		t.Fatalf("wd.DrainLogs() returned error: %v", err)
+	}
+	// The first pull is done before DrainLogs returns.
+	if n := sink.len(); n != 1 {
+		t.Fatalf("after wd.DrainLogs(), the sink received %d entries, want 1", n)
+	}
+
+	logs.add(log.Browser, "console")
+	for deadline := time.Now().Add(5 * time.Second); sink.len() < 2; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the browser log was not drained after 5s")
+		}
+	}
+
+	// Quit pulls the entries logged since the last pull.
+	logs.add(log.Driver, "stopping")
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	want := []string{"driver: started", "browser: console", "driver: stopping"}
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.entries) != len(want) {
+		t.Fatalf("the sink received %q, want %q", sink.entries, want)
+	}
+	for i := range want {
+		if sink.entries[i] != want[i] {
+			t.Errorf("the sink received %q, want %q", sink.entries, want)
+			break
+		}
+	}
+}
+
+func TestDrainLogsHighWater(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	logs := &fakeLogs{pending: map[log.Type][]string{log.Driver: {"a", "b", "c"}}}
+	logs.install(d)
+	wd := d.newRemote(t)
+
+	var warnings []log.Message
+	sink := func(typ log.Type, m log.Message) {
+		if typ == log.Client {
+			warnings = append(warnings, m)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := wd.DrainLogs(ctx, []log.Type{log.Driver}, sink, DrainHighWater(2)); err != nil {
+		t.Fatalf("wd.DrainLogs() returned error: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Level != log.Warning {
+		t.Errorf("after a pull of 3 entries with a high water mark of 2, the sink received warnings %+v, want one", warnings)
+	}
+}
+
+func TestDrainLogsError(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	wd := d.newRemote(t)
+	// The fake driver does not implement the log command.
+	if err := wd.DrainLogs(context.Background(), []log.Type{log.Driver}, func(log.Type, log.Message) {}); err == nil {
+		t.Fatal("wd.DrainLogs() returned no error for an unsupported command")
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package matrix runs a test against a matrix of browser configurations, as
+// one subtest per configuration.
+//
+//	sets := []matrix.CapabilitySet{
+//		{Name: "chrome", Capabilities: selenium.Capabilities{"browserName": "chrome"}, URLPrefix: gridURL},
+//		{Name: "firefox", Capabilities: selenium.Capabilities{"browserName": "firefox"}, URLPrefix: gridURL},
+//	}
+//	matrix.Run(t, sets, func(t *testing.T, wd selenium.WebDriver) {
+//		// ...
+//	})
+package matrix
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// CapabilitySet is an entry of the matrix.
+type CapabilitySet struct {
+	// Name names the subtest of the entry.
+	Name string
+	// Capabilities are the capabilities requested for the session. Unless
+	// already set, the test name is added to them with
+	// selenium.Capabilities.SetTestName.
+	Capabilities selenium.Capabilities
+	// URLPrefix is the address of the remote end, e.g. a Selenium Grid.
+	URLPrefix string
+	// Service, if set, starts a remote end for the entry and returns its
+	// address, which overrides URLPrefix, and a function that stops it.
+	Service func() (urlPrefix string, stop func() error, err error)
+	// Preflight, if set, checks whether the environment can run the entry,
+	// e.g. whether the browser binary is installed. If it returns an error,
+	// the entry's subtest is skipped with that error as the reason.
+	Preflight func() error
+}
+
+// SessionPool is a source of reusable sessions.
+type SessionPool interface {
+	// Get returns a session with the capabilities at the remote end.
+	Get(caps selenium.Capabilities, urlPrefix string) (selenium.WebDriver, error)
+	// Put returns a session obtained from Get. ok is false if the test using
+	// it failed or panicked, in which case the session should not be reused.
+	Put(wd selenium.WebDriver, ok bool)
+}
+
+// Runner runs tests against matrices of capabilities.
+type Runner struct {
+	// Pool, if set, provides the sessions instead of creating a new session
+	// for each entry.
+	Pool SessionPool
+}
+
+// Run runs fn against each entry of sets, as a subtest named after the entry,
+// with a new session for each entry.
+func Run(t *testing.T, sets []CapabilitySet, fn func(t *testing.T, wd selenium.WebDriver)) {
+	t.Helper()
+	new(Runner).Run(t, sets, fn)
+}
+
+// Run runs fn against each entry of sets, as a subtest named after the entry.
+// The session is quit, or returned to the pool, when fn returns, fails or
+// panics.
+func (r *Runner) Run(t *testing.T, sets []CapabilitySet, fn func(t *testing.T, wd selenium.WebDriver)) {
+	t.Helper()
+	for _, set := range sets {
+		set := set
+		t.Run(set.Name, func(t *testing.T) {
+			r.runEntry(t, set, fn)
+		})
+	}
+}
+
+func (r *Runner) runEntry(t *testing.T, set CapabilitySet, fn func(t *testing.T, wd selenium.WebDriver)) {
+	if set.Preflight != nil {
+		if err := set.Preflight(); err != nil {
+			t.Skipf("%s cannot run in this environment: %v", set.Name, err)
+		}
+	}
+
+	urlPrefix := set.URLPrefix
+	if set.Service != nil {
+		prefix, stop, err := set.Service()
+		if err != nil {
+			t.Fatalf("%s: starting the service returned error: %v", set.Name, err)
+		}
+		defer func() {
+			if err := stop(); err != nil {
+				t.Errorf("%s: stopping the service returned error: %v", set.Name, err)
+			}
+		}()
+		urlPrefix = prefix
+	}
+
+	caps := make(selenium.Capabilities)
+	for k, v := range set.Capabilities {
+		caps[k] = v
+	}
+	if _, ok := caps[selenium.TestNameCapability]; !ok {
+		caps.SetTestName(t.Name())
+	}
+
+	wd, err := r.session(caps, urlPrefix)
+	if err != nil {
+		t.Fatalf("%s: creating the session returned error: %v", set.Name, err)
+	}
+	completed := false
+	defer func() {
+		if r.Pool != nil {
+			r.Pool.Put(wd, completed && !t.Failed())
+			return
+		}
+		if err := wd.Quit(); err != nil {
+			t.Errorf("%s: wd.Quit() returned error: %v", set.Name, err)
+		}
+	}()
+	if err := wd.SetTestName(t.Name()); err != nil {
+		t.Logf("%s: wd.SetTestName(%q) returned error: %v", set.Name, t.Name(), err)
+	}
+
+	fn(t, wd)
+	completed = true
+}
+
+func (r *Runner) session(caps selenium.Capabilities, urlPrefix string) (selenium.WebDriver, error) {
+	if r.Pool != nil {
+		wd, err := r.Pool.Get(caps, urlPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("getting a session from the pool: %v", err)
+		}
+		return wd, nil
+	}
+	return selenium.NewRemote(caps, urlPrefix)
+}
@@ -0,0 +1,133 @@
+package matrix
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// fakeWD records the calls made by Run. The embedded interface is nil, so
+// calling any other method panics.
+type fakeWD struct {
+	selenium.WebDriver
+	caps     selenium.Capabilities
+	testName string
+	quit     bool
+}
+
+func (wd *fakeWD) SetTestName(name string) error {
+	wd.testName = name
+	return nil
+}
+
+func (wd *fakeWD) Quit() error {
+	wd.quit = true
+	return nil
+}
+
+type fakePool struct {
+	got []*fakeWD
+	put map[*fakeWD]bool
+}
+
+func (p *fakePool) Get(caps selenium.Capabilities, urlPrefix string) (selenium.WebDriver, error) {
+	wd := &fakeWD{caps: caps}
+	p.got = append(p.got, wd)
+	return wd, nil
+}
+
+func (p *fakePool) Put(wd selenium.WebDriver, ok bool) {
+	if p.put == nil {
+		p.put = make(map[*fakeWD]bool)
+	}
+	p.put[wd.(*fakeWD)] = ok
+}
+
+func TestRunUsesPool(t *testing.T) {
+	pool := new(fakePool)
+	r := &Runner{Pool: pool}
+	shared := selenium.Capabilities{"browserName": "chrome"}
+	sets := []CapabilitySet{
+		{Name: "chrome", Capabilities: shared},
+		{Name: "firefox", Capabilities: selenium.Capabilities{"browserName": "firefox"}},
+	}
+	var ran []string
+	r.Run(t, sets, func(t *testing.T, wd selenium.WebDriver) {
+		ran = append(ran, t.Name())
+	})
+
+	want := []string{"TestRunUsesPool/chrome", "TestRunUsesPool/firefox"}
+	if len(ran) != len(want) || ran[0] != want[0] || ran[1] != want[1] {
+		t.Fatalf("Run() ran subtests %q, want %q", ran, want)
+	}
+	if len(pool.got) != 2 {
+		t.Fatalf("Run() got %d sessions from the pool, want 2", len(pool.got))
+	}
+	for i, wd := range pool.got {
+		if ok, put := pool.put[wd]; !put || !ok {
+			t.Errorf("session %d: Put(wd, %t) called: %t, want Put(wd, true)", i, ok, put)
+		}
+		if wd.testName != want[i] {
+			t.Errorf("session %d: test name = %q, want %q", i, wd.testName, want[i])
+		}
+		if got := wd.caps[selenium.TestNameCapability]; got != want[i] {
+			t.Errorf("session %d: capability %q = %v, want %q", i, selenium.TestNameCapability, got, want[i])
+		}
+	}
+	if _, ok := shared[selenium.TestNameCapability]; ok {
+		t.Errorf("Run() modified the capabilities of the entry: %v", shared)
+	}
+}
+
+func TestRunSkipsEntriesFailingPreflight(t *testing.T) {
+	pool := new(fakePool)
+	r := &Runner{Pool: pool}
+	sets := []CapabilitySet{
+		{Name: "safari", Preflight: func() error { return errors.New("safaridriver not found") }},
+	}
+	skipped := false
+	t.Run("matrix", func(t *testing.T) {
+		r.Run(t, sets, func(t *testing.T, wd selenium.WebDriver) {
+			t.Error("fn was called for an entry failing preflight")
+		})
+		skipped = true
+	})
+	if !skipped {
+		t.Fatal("the matrix test did not complete")
+	}
+	if len(pool.got) != 0 {
+		t.Errorf("Run() got %d sessions for a skipped entry, want 0", len(pool.got))
+	}
+}
+
+func TestRunQuitsSessionOnPanic(t *testing.T) {
+	pool := &singlePool{wd: new(fakeWD)}
+	r := &Runner{Pool: pool}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("runEntry() did not propagate the panic")
+			}
+		}()
+		r.runEntry(t, CapabilitySet{Name: "chrome"}, func(t *testing.T, wd selenium.WebDriver) {
+			panic("boom")
+		})
+	}()
+	if !pool.put || pool.ok {
+		t.Errorf("after a panic, Put called: %t with ok = %t, want called with ok = false", pool.put, pool.ok)
+	}
+}
+
+type singlePool struct {
+	wd      *fakeWD
+	put, ok bool
+}
+
+func (p *singlePool) Get(selenium.Capabilities, string) (selenium.WebDriver, error) {
+	return p.wd, nil
+}
+
+func (p *singlePool) Put(wd selenium.WebDriver, ok bool) {
+	p.put, p.ok = true, ok
+}
@@ -0,0 +1,151 @@
+package selenium
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// WithLabel labels the session, e.g. with the role of its user, to tell it
+// apart from the other sessions of a test: the label is included in the
+// errors returned by the session's commands and in their debug log entries,
+// after the test name if one is set.
+func WithLabel(label string) SessionOption {
+	return func(o *sessionOptions) {
+		o.label = label
+	}
+}
+
+// NamedSession describes a session of a MultiSession.
+type NamedSession struct {
+	// Name identifies the session in MultiSession.As, and labels it as with
+	// WithLabel.
+	Name string
+	// Capabilities are the capabilities of the session.
+	Capabilities Capabilities
+	// URLPrefix is the URL of the remote end, or DefaultURLPrefix if empty.
+	URLPrefix string
+	// Options are passed to NewRemoteContext.
+	Options []SessionOption
+	// Setup, if not nil, is called once the session is created, e.g. to log
+	// in with the role of the session.
+	Setup func(wd WebDriver) error
+}
+
+// MultiSession holds the named sessions of a test that drives several
+// browsers, such as an administrator approving what a user submitted.
+type MultiSession struct {
+	names    []string
+	sessions map[string]WebDriver
+}
+
+// NewMultiSession creates the sessions and runs their Setup functions, all
+// concurrently. If any of them fails, the Setup functions that did not start
+// yet are skipped, the sessions being created are waited for, as the remote
+// end may create them anyway, and all the sessions that were created are
+// quit; the error of the first failure is returned.
+func NewMultiSession(ctx context.Context, sessions ...NamedSession) (*MultiSession, error) {
+	m := &MultiSession{sessions: make(map[string]WebDriver)}
+	for _, s := range sessions {
+		if s.Name == "" {
+			return nil, fmt.Errorf("a session has no name")
+		}
+		if _, ok := m.sessions[s.Name]; ok {
+			return nil, fmt.Errorf("more than one session is named %q", s.Name)
+		}
+		m.sessions[s.Name] = nil
+		m.names = append(m.names, s.Name)
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	for _, s := range sessions {
+		wg.Add(1)
+		go func(s NamedSession) {
+			defer wg.Done()
+			opts := append(append([]SessionOption(nil), s.Options...), WithLabel(s.Name))
+			wd, err := NewRemoteContext(ctx, s.Capabilities, s.URLPrefix, opts...)
+			mu.Lock()
+			if wd != nil {
+				m.sessions[s.Name] = wd
+			}
+			failed := firstErr != nil
+			mu.Unlock()
+			if err == nil && s.Setup != nil && !failed {
+				if err = s.Setup(wd); err != nil {
+					err = fmt.Errorf("setting up: %w", err)
+				}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("session %q: %w", s.Name, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		if err := m.Quit(); err != nil {
+			debugLog("error quitting the sessions after a failed setup: %v", err)
+		}
+		return nil, firstErr
+	}
+	return m, nil
+}
+
+// Names returns the names of the sessions, in the order they were given to
+// NewMultiSession.
+func (m *MultiSession) Names() []string {
+	return append([]string(nil), m.names...)
+}
+
+// As returns the session with the given name. It panics if there is no such
+// session, as that is a mistake in the test.
+func (m *MultiSession) As(name string) WebDriver {
+	wd, ok := m.sessions[name]
+	if !ok || wd == nil {
+		panic(fmt.Sprintf("selenium: no session named %q", name))
+	}
+	return wd
+}
+
+// Broadcast calls fn with each session, in the order of Names, until fn
+// returns an error, which is returned with the name of the session.
+func (m *MultiSession) Broadcast(fn func(name string, wd WebDriver) error) error {
+	for _, name := range m.names {
+		if err := fn(name, m.As(name)); err != nil {
+			return fmt.Errorf("session %q: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// Quit quits all the sessions, concurrently. It returns the first error, with
+// the name of its session, after trying to quit all of them.
+func (m *MultiSession) Quit() error {
+	errs := make([]error, len(m.names))
+	var wg sync.WaitGroup
+	for i, name := range m.names {
+		wd := m.sessions[name]
+		if wd == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, name string, wd WebDriver) {
+			defer wg.Done()
+			if err := wd.Quit(); err != nil {
+				errs[i] = fmt.Errorf("session %q: %w", name, err)
+			}
+		}(i, name, wd)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
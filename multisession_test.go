@@ -0,0 +1,100 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMultiSession(t *testing.T) {
+	admin, user := newFakeDriver(t, nil), newFakeDriver(t, nil)
+	admin.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "https://example.com/admin"
+	})
+	user.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{
+			"error":   "no such window",
+			"message": "window was closed",
+		}
+	})
+	var mu sync.Mutex
+	var setUp []string
+	setup := func(role string) func(WebDriver) error {
+		return func(WebDriver) error {
+			mu.Lock()
+			defer mu.Unlock()
+			setUp = append(setUp, role)
+			return nil
+		}
+	}
+	m, err := NewMultiSession(context.Background(),
+		NamedSession{Name: "admin", URLPrefix: admin.URL, Setup: setup("admin")},
+		NamedSession{Name: "user", URLPrefix: user.URL, Setup: setup("user")},
+	)
+	if err != nil {
+		t.Fatalf("NewMultiSession() returned error: %v", err)
+	}
+	if len(setUp) != 2 {
+		t.Errorf("NewMultiSession() ran the setup of %q, want both sessions", setUp)
+	}
+	if got := m.As("admin").(*remoteWD).urlPrefix; got != admin.URL {
+		t.Errorf("m.As(%q) runs on %s, want %s", "admin", got, admin.URL)
+	}
+
+	_, err = m.As("user").CurrentURL()
+	if err == nil || !strings.Contains(err.Error(), "[user]") {
+		t.Errorf("m.As(%q).CurrentURL() returned error %v, want it labelled [user]", "user", err)
+	}
+	err = m.Broadcast(func(name string, wd WebDriver) error {
+		_, err := wd.CurrentURL()
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), `session "user"`) {
+		t.Errorf("m.Broadcast() returned error %v, want an error of the user session", err)
+	}
+
+	if err := m.Quit(); err != nil {
+		t.Fatalf("m.Quit() returned error: %v", err)
+	}
+	for name, d := range map[string]*fakeDriver{"admin": admin, "user": user} {
+		if n := d.count("DELETE", ""); n != 1 {
+			t.Errorf("m.Quit() sent %d Delete Session requests for %q, want 1", n, name)
+		}
+	}
+}
+
+func TestMultiSessionSetupFailure(t *testing.T) {
+	admin, user := newFakeDriver(t, nil), newFakeDriver(t, nil)
+	// The admin session is created only once the setup of the user session
+	// has failed, so that its New Session request is in flight then.
+	userFailed := make(chan struct{})
+	admin.newSession = func([]byte) (int, interface{}) {
+		<-userFailed
+		return http.StatusOK, map[string]interface{}{"sessionId": fakeSessionID, "capabilities": map[string]interface{}{}}
+	}
+	errLogin := errors.New("wrong password")
+	_, err := NewMultiSession(context.Background(),
+		NamedSession{Name: "admin", URLPrefix: admin.URL},
+		NamedSession{Name: "user", URLPrefix: user.URL, Setup: func(WebDriver) error {
+			close(userFailed)
+			return errLogin
+		}},
+	)
+	if !errors.Is(err, errLogin) {
+		t.Fatalf("NewMultiSession() returned error %v, want the setup error", err)
+	}
+	for name, d := range map[string]*fakeDriver{"admin": admin, "user": user} {
+		if n := d.count("DELETE", ""); n != 1 {
+			t.Errorf("after a failed setup, %d Delete Session requests were sent for %q, want 1", n, name)
+		}
+	}
+}
+
+func TestMultiSessionDuplicateName(t *testing.T) {
+	if _, err := NewMultiSession(context.Background(), NamedSession{Name: "user"}, NamedSession{Name: "user"}); err == nil {
+		t.Error("NewMultiSession() with two sessions named the same returned no error")
+	}
+}
@@ -0,0 +1,177 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// StatusUnknown is the NavigationResult.StatusCode reported when the HTTP
+// status of the document could not be determined.
+const StatusUnknown = 0
+
+// NavigationResult describes the outcome of the navigation that loaded the
+// current document.
+type NavigationResult struct {
+	// URL is the URL of the document, after redirects.
+	URL string
+	// StatusCode is the HTTP status of the response for the document, or
+	// StatusUnknown.
+	StatusCode int
+	// StatusText is the HTTP status text of the response, e.g. "Not Found".
+	// When the remote end does not report it, the standard text for the
+	// status code is used.
+	StatusText string
+	// ErrorPage is true if the browser displays one of its own error pages,
+	// e.g. because the server could not be reached or its certificate was
+	// rejected.
+	ErrorPage bool
+}
+
+// NavigationError is returned by Navigate in strict mode when the navigation
+// resulted in an error page or an HTTP error status.
+type NavigationError struct {
+	Result NavigationResult
+}
+
+func (e *NavigationError) Error() string {
+	if e.Result.ErrorPage {
+		return fmt.Sprintf("navigation to %q resulted in a browser error page", e.Result.URL)
+	}
+	return fmt.Sprintf("navigation to %q resulted in HTTP status %d %s", e.Result.URL, e.Result.StatusCode, e.Result.StatusText)
+}
+
+// NavigateOption configures a call to Navigate.
+type NavigateOption func(*navigateOptions)
+
+type navigateOptions struct {
+	strict bool
+}
+
+// FailOnHTTPError makes Navigate return a *NavigationError if the navigation
+// results in a browser error page or an HTTP status of 400 or above.
+func FailOnHTTPError() NavigateOption {
+	return func(o *navigateOptions) {
+		o.strict = true
+	}
+}
+
+// navigationResultScript gathers the navigation result from the document.
+// responseStatus is part of Navigation Timing Level 2 and is not yet reported
+// by all browsers.
+const navigationResultScript = `
+	var uri = document.documentURI || '';
+	var status = 0;
+	var perf = window.performance;
+	if (perf && perf.getEntriesByType) {
+		var nav = perf.getEntriesByType('navigation')[0];
+		if (nav && typeof nav.responseStatus === 'number') {
+			status = nav.responseStatus;
+		}
+	}
+	return {
+		url: window.location.href,
+		status: status,
+		errorPage: /^(chrome-error:|about:neterror|about:certerror|about:blocked)/.test(uri)
+	};`
+
+// Navigate loads url and returns the result of the navigation.
+func (wd *remoteWD) Navigate(url string, opts ...NavigateOption) (NavigationResult, error) {
+	var o navigateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if err := wd.Get(url); err != nil {
+		return NavigationResult{}, err
+	}
+	r, err := wd.NavigationResult()
+	if err != nil {
+		return r, err
+	}
+	if o.strict && (r.ErrorPage || r.StatusCode >= 400) {
+		return r, &NavigationError{Result: r}
+	}
+	return r, nil
+}
+
+// NavigationResult returns the result of the navigation that loaded the
+// current document.
+func (wd *remoteWD) NavigationResult() (NavigationResult, error) {
+	data, err := wd.ExecuteScriptRaw(navigationResultScript, nil)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	reply := new(struct {
+		Value struct {
+			URL       string
+			Status    int
+			ErrorPage bool
+		}
+	})
+	if err := json.Unmarshal(data, reply); err != nil {
+		return NavigationResult{}, err
+	}
+	r := NavigationResult{
+		URL:        reply.Value.URL,
+		StatusCode: reply.Value.Status,
+		ErrorPage:  reply.Value.ErrorPage,
+	}
+	if r.StatusCode == StatusUnknown && !r.ErrorPage && wd.performanceLogEnabled() {
+		if err := wd.statusFromPerformanceLog(&r); err != nil {
+			debugLog("error reading the performance log: %v\n", err)
+		}
+	}
+	if r.StatusText == "" {
+		r.StatusText = http.StatusText(r.StatusCode)
+	}
+	return r, nil
+}
+
+// performanceLogEnabled reports whether the session was requested with
+// ChromeDriver's performance log, which records the DevTools Network events.
+func (wd *remoteWD) performanceLogEnabled() bool {
+	prefs, ok := wd.capabilities[log.CapabilitiesKey].(log.Capabilities)
+	if !ok {
+		return false
+	}
+	level, ok := prefs[log.Performance]
+	return ok && level != log.Off
+}
+
+// statusFromPerformanceLog sets the status of r from the last DevTools
+// Network.responseReceived event for the document. Reading the log consumes
+// its entries.
+func (wd *remoteWD) statusFromPerformanceLog(r *NavigationResult) error {
+	messages, err := wd.Log(log.Performance)
+	if err != nil {
+		return err
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		entry := new(struct {
+			Message struct {
+				Method string
+				Params struct {
+					Type     string
+					Response struct {
+						URL        string
+						Status     int
+						StatusText string
+					}
+				}
+			}
+		})
+		if err := json.Unmarshal([]byte(messages[i].Message), entry); err != nil {
+			continue
+		}
+		m := entry.Message
+		if m.Method != "Network.responseReceived" || m.Params.Type != "Document" || m.Params.Response.URL != r.URL {
+			continue
+		}
+		r.StatusCode = m.Params.Response.Status
+		r.StatusText = m.Params.Response.StatusText
+		return nil
+	}
+	return nil
+}
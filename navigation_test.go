@@ -0,0 +1,100 @@
+package selenium
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// fakeNavigation makes the fake driver accept navigations and report the
+// given result from the navigation script.
+func fakeNavigation(d *fakeDriver, url string, status int, errorPage bool) {
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"url":       url,
+			"status":    status,
+			"errorPage": errorPage,
+		}
+	})
+}
+
+func TestNavigate(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		status    int
+		errorPage bool
+		want      NavigationResult
+		wantErr   bool
+	}{
+		{
+			desc:   "success",
+			status: http.StatusOK,
+			want:   NavigationResult{URL: "https://example.com/", StatusCode: 200, StatusText: "OK"},
+		},
+		{
+			desc:    "not found",
+			status:  http.StatusNotFound,
+			want:    NavigationResult{URL: "https://example.com/", StatusCode: 404, StatusText: "Not Found"},
+			wantErr: true,
+		},
+		{
+			desc:      "error page",
+			errorPage: true,
+			want:      NavigationResult{URL: "https://example.com/", ErrorPage: true},
+			wantErr:   true,
+		},
+		{
+			desc: "unknown status",
+			want: NavigationResult{URL: "https://example.com/", StatusCode: StatusUnknown},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			fakeNavigation(d, "https://example.com/", test.status, test.errorPage)
+			wd := d.newRemote(t)
+
+			got, err := wd.Navigate("https://example.com")
+			if err != nil {
+				t.Fatalf("wd.Navigate() returned error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("wd.Navigate() = %+v, want %+v", got, test.want)
+			}
+
+			_, err = wd.Navigate("https://example.com", FailOnHTTPError())
+			if _, ok := err.(*NavigationError); ok != test.wantErr {
+				t.Errorf("wd.Navigate(_, FailOnHTTPError()) returned error %v, want a *NavigationError: %t", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestNavigationResultFromPerformanceLog(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	fakeNavigation(d, "https://example.com/", StatusUnknown, false)
+	d.handle("POST", "/log", func([]byte) (int, interface{}) {
+		return http.StatusOK, []map[string]interface{}{
+			{"timestamp": 1, "level": "INFO", "message": `{"message":{"method":"Network.responseReceived","params":{"type":"Document","response":{"url":"https://example.com/","status":503,"statusText":"Busy"}}}}`},
+			{"timestamp": 2, "level": "INFO", "message": `{"message":{"method":"Network.responseReceived","params":{"type":"Script","response":{"url":"https://example.com/app.js","status":200,"statusText":"OK"}}}}`},
+		}
+	})
+	caps := Capabilities{}
+	caps.SetLogLevel(log.Performance, log.All)
+	wd, err := NewRemote(caps, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+
+	got, err := wd.NavigationResult()
+	if err != nil {
+		t.Fatalf("wd.NavigationResult() returned error: %v", err)
+	}
+	want := NavigationResult{URL: "https://example.com/", StatusCode: 503, StatusText: "Busy"}
+	if got != want {
+		t.Errorf("wd.NavigationResult() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,101 @@
+package selenium
+
+import (
+	"fmt"
+	"time"
+)
+
+// OpenError is returned by Open when the page loaded, but its ready element
+// did not appear in time, with what the browser showed instead.
+type OpenError struct {
+	// URL is the URL that was opened.
+	URL string
+	// Selector is the CSS selector of the ready element.
+	Selector string
+	// Timeout is how long Open waited for the element.
+	Timeout time.Duration
+	// Result describes the page shown when Open gave up: its URL after
+	// redirects, and whether it is a browser error page. It is empty if it
+	// could not be read.
+	Result NavigationResult
+	// Title is the title of the page shown when Open gave up.
+	Title string
+	// Err is the error that ended the wait.
+	Err error
+}
+
+func (e *OpenError) Error() string {
+	msg := fmt.Sprintf("opening %q: %q not ready after %v: %v", e.URL, e.Selector, e.Timeout, e.Err)
+	if e.Result.URL != "" {
+		msg += fmt.Sprintf(" (at %q, title %q", e.Result.URL, e.Title)
+		if e.Result.ErrorPage {
+			msg += ", browser error page"
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+func (e *OpenError) Unwrap() error {
+	return e.Err
+}
+
+// FailureHook is called by the helpers that give up waiting for the page,
+// such as Open, with the error they are about to return. Hooks may collect
+// diagnostics, e.g. save a screenshot and record it with RecordArtifact.
+type FailureHook func(wd WebDriver, err error)
+
+// WithFailureHook adds a hook called when the session gives up waiting for a
+// page; see FailureHook.
+func WithFailureHook(hook FailureHook) SessionOption {
+	return func(o *sessionOptions) {
+		o.failureHooks = append(o.failureHooks, hook)
+	}
+}
+
+// Open loads url and waits for the element matching readySelector to be
+// displayed.
+func (wd *remoteWD) Open(url, readySelector string, timeout time.Duration) (WebElement, error) {
+	if timeout == 0 {
+		timeout = wd.defaults.WaitTimeout
+	}
+	if err := wd.Get(url); err != nil {
+		return nil, err
+	}
+	var ready WebElement
+	err := wd.WaitWithTimeout(func(WebDriver) (bool, error) {
+		elem, err := wd.FindElement(ByCSSSelector, readySelector)
+		if isNoSuchElementError(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		displayed, err := elem.IsDisplayed()
+		if isStaleElementError(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		ready = elem
+		return displayed, nil
+	}, timeout)
+	if err == nil {
+		return ready, nil
+	}
+
+	e := &OpenError{URL: url, Selector: readySelector, Timeout: timeout, Err: err}
+	if r, err := wd.NavigationResult(); err == nil {
+		e.Result = r
+	} else {
+		debugLog("error reading the navigation result: %v", err)
+	}
+	if title, err := wd.Title(); err == nil {
+		e.Title = title
+	}
+	for _, hook := range wd.failureHooks {
+		hook(wd, e)
+	}
+	return nil, e
+}
@@ -0,0 +1,86 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func noSuchElementReply() (int, interface{}) {
+	return http.StatusNotFound, map[string]string{
+		"error":   "no such element",
+		"message": "Unable to locate element",
+	}
+}
+
+func TestOpen(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	finds := 0
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		finds++
+		if finds < 3 {
+			return noSuchElementReply()
+		}
+		return http.StatusOK, map[string]string{webElementIdentifier: "main"}
+	})
+	d.handle("GET", "/element/main/displayed", func([]byte) (int, interface{}) {
+		return http.StatusOK, true
+	})
+	wd := d.newRemote(t)
+
+	elem, err := wd.Open("https://example.com/", "main", time.Minute)
+	if err != nil {
+		t.Fatalf("wd.Open() returned error: %v", err)
+	}
+	if elem.(*remoteWE).id != "main" {
+		t.Errorf("wd.Open() returned element %q, want %q", elem.(*remoteWE).id, "main")
+	}
+}
+
+func TestOpenTimeout(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return noSuchElementReply()
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"url":       "chrome-error://chromewebdata/",
+			"status":    0,
+			"errorPage": true,
+		}
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "example.com"
+	})
+	var hooked error
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithFailureHook(func(_ WebDriver, err error) {
+		hooked = err
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	_, err = wd.Open("https://example.com/", "main", 10*time.Millisecond)
+	var openErr *OpenError
+	if !errors.As(err, &openErr) {
+		t.Fatalf("wd.Open() returned error %v, want an *OpenError", err)
+	}
+	if !openErr.Result.ErrorPage || openErr.Title != "example.com" {
+		t.Errorf("wd.Open() returned %+v, want the error page and its title", openErr)
+	}
+	if !strings.Contains(err.Error(), "browser error page") {
+		t.Errorf("wd.Open() returned error %q, want it to mention the error page", err)
+	}
+	if hooked != err {
+		t.Errorf("the failure hook was called with %v, want %v", hooked, err)
+	}
+}
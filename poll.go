@@ -0,0 +1,49 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// minWatchInterval is the shortest interval between the polls of a
+// background poller.
+const minWatchInterval = 10 * time.Millisecond
+
+// pollURL returns the URL of a command sent by a background poller, such as
+// those of WatchWindows, WatchRouteChanges, DrainLogs and
+// WithPeriodicSnapshots. The pollers send their commands with the URL of the
+// session as it is when they start, rather than reading wd.id concurrently
+// with the caller.
+func (wd *remoteWD) pollURL(format string) string {
+	return wd.requestURL(format, wd.id)
+}
+
+// poll calls f every interval, or every minWatchInterval if interval is
+// shorter, until ctx is done, stop is closed or f reports that the session
+// ended. The other errors of f are logged in debug mode, prefixed by what
+// the poller does, e.g. "polling the window handles". stop may be nil.
+func poll(ctx context.Context, stop <-chan struct{}, interval time.Duration, what string, f func() error) {
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		err := f()
+		switch {
+		case err == nil:
+		case ctx.Err() != nil, isInvalidSessionError(err), errors.Is(err, ErrBrowserCrashed):
+			return
+		default:
+			debugLog("error %s: %v", what, err)
+		}
+	}
+}
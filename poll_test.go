@@ -0,0 +1,57 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+func TestPoll(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		// errs are the errors of the successive calls, the last one repeated.
+		errs      []error
+		cancel    bool
+		stop      bool
+		wantCalls int
+	}{
+		{"session ended", []error{nil, errors.New("timeout"), fmt.Errorf("polling: %w", werror.InvalidSessionID)}, false, false, 3},
+		{"browser crashed", []error{ErrBrowserCrashed}, false, false, 1},
+		{"context done", []error{nil}, true, false, 0},
+		{"stopped", []error{nil}, false, true, 0},
+	} {
+		ctx, cancel := context.WithCancel(context.Background())
+		stop := make(chan struct{})
+		if test.cancel {
+			cancel()
+		}
+		if test.stop {
+			close(stop)
+		}
+		calls := 0
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			poll(ctx, stop, time.Millisecond, "testing", func() error {
+				calls++
+				if calls < len(test.errs) {
+					return test.errs[calls-1]
+				}
+				return test.errs[len(test.errs)-1]
+			})
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: poll() did not return", test.desc)
+		}
+		cancel()
+		if calls != test.wantCalls {
+			t.Errorf("%s: poll() called f %d times, want %d", test.desc, calls, test.wantCalls)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// preservedProfilePrefix starts the names of the directories of the profiles
+// kept by PreserveProfile.
+const preservedProfilePrefix = "profile-"
+
+// ProfileRetention limits the profiles kept by PreserveProfile.
+type ProfileRetention func(*profilePreserver)
+
+// KeepProfileOnFailure keeps the profile of a session only if the test
+// failed. failed is called by Quit; pass t.Failed for a *testing.T.
+func KeepProfileOnFailure(failed func() bool) ProfileRetention {
+	return func(p *profilePreserver) {
+		p.failed = failed
+	}
+}
+
+// KeepLastProfiles keeps only the last n profiles in the directory of
+// PreserveProfile: the older ones are removed by Quit. The profiles of the
+// sessions of this program that are still open are never removed.
+func KeepLastProfiles(n int) ProfileRetention {
+	return func(p *profilePreserver) {
+		p.keepLast = n
+	}
+}
+
+// profilePreserver keeps the profile of a session; see PreserveProfile.
+type profilePreserver struct {
+	dir      string
+	failed   func() bool
+	keepLast int
+	// path is the kept profile, if any: the user data directory of Chrome,
+	// set when the session is created, or the copy of the Firefox profile,
+	// made by Quit.
+	path string
+	// owned is true if path was created by the preserver, which may then
+	// remove it.
+	owned bool
+}
+
+// PreserveProfile keeps the profile of the browser of the session in a new
+// directory under dir, e.g. to inspect the extensions installed, the
+// preferences applied or the cache once the session behaved strangely. The
+// path of the profile is returned by WebDriver.ProfilePath.
+//
+// Chrome is given a user data directory under dir, unless one is set in its
+// capabilities already, in which case that directory is kept. The Firefox
+// profile, which GeckoDriver removes at the end of the session, is copied
+// under dir by Quit; start GeckoDriver with GeckoProfileRoot to keep the
+// temporary profiles out of the temporary directory of the system too. The
+// profiles must be on the local disk.
+//
+// Profiles take a lot of space: use the retentions to keep only those of the
+// failed tests, or only the last ones.
+func PreserveProfile(dir string, retention ...ProfileRetention) SessionOption {
+	return func(o *sessionOptions) {
+		p := &profilePreserver{dir: dir}
+		for _, r := range retention {
+			r(p)
+		}
+		o.preserveProfile = p
+	}
+}
+
+// liveProfiles are the profiles of the open sessions, which KeepLastProfiles
+// does not remove.
+var liveProfiles = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+func setProfileLive(path string, live bool) {
+	liveProfiles.Lock()
+	defer liveProfiles.Unlock()
+	if live {
+		liveProfiles.paths[path] = true
+	} else {
+		delete(liveProfiles.paths, path)
+	}
+}
+
+// newProfileDir creates the directory of a profile kept for browser.
+func (p *profilePreserver) newProfileDir(browser string) (string, error) {
+	if err := os.MkdirAll(p.dir, 0755); err != nil {
+		return "", err
+	}
+	// The names sort in the order of creation, for KeepLastProfiles.
+	prefix := fmt.Sprintf("%s%d-%s-", preservedProfilePrefix, time.Now().UnixNano(), browser)
+	return ioutil.TempDir(p.dir, prefix)
+}
+
+// preserveChromeProfile gives Chrome a user data directory under the directory
+// of PreserveProfile, unless one is set already. The capabilities of the
+// caller are left unchanged.
+func (wd *remoteWD) preserveChromeProfile(p *profilePreserver) error {
+	_, isChrome := wd.capabilities[chrome.CapabilitiesKey]
+	_, isEdge := wd.capabilities[EdgeCapabilitiesKey]
+	if !isChrome && !isEdge && wd.browser != "chrome" {
+		return nil
+	}
+	caps := make(Capabilities, len(wd.capabilities))
+	for k, v := range wd.capabilities {
+		if c, ok := v.(*chrome.Capabilities); ok {
+			v = *c
+		}
+		caps[k] = v
+	}
+	var err error
+	if uerr := caps.updateBrowserOptions(func(c *chrome.Capabilities) {
+		switch {
+		case err != nil:
+		case p.owned:
+			c.SetUserDataDir(p.path)
+		case c.UserDataDir() != "":
+			p.path = c.UserDataDir()
+		default:
+			if p.path, err = p.newProfileDir("chrome"); err == nil {
+				p.owned = true
+				c.SetUserDataDir(p.path)
+			}
+		}
+	}, func(*firefox.Capabilities) {}); uerr != nil {
+		return uerr
+	}
+	if err != nil {
+		return fmt.Errorf("preserving the profile: %v", err)
+	}
+	wd.capabilities = caps
+	if p.owned {
+		setProfileLive(p.path, true)
+	}
+	return nil
+}
+
+// preserveFirefoxProfile copies the Firefox profile of the session under the
+// directory of PreserveProfile, before GeckoDriver removes it. Errors are
+// logged in debug mode, as the session is ending.
+func (wd *remoteWD) preserveFirefoxProfile() {
+	p := wd.preserveProfile
+	if p == nil || p.path != "" {
+		return
+	}
+	src, _ := wd.sessionCapabilities["moz:profile"].(string)
+	if src == "" || (p.failed != nil && !p.failed()) {
+		return
+	}
+	dst, err := p.newProfileDir("firefox")
+	if err == nil {
+		err = copyProfile(src, dst)
+	}
+	if err != nil {
+		debugLog("error preserving the profile %s: %v", src, err)
+		return
+	}
+	p.path, p.owned = dst, true
+}
+
+// applyProfileRetention removes the profile of the ended session if
+// KeepProfileOnFailure says so, and the old profiles beyond the count of
+// KeepLastProfiles. Errors are logged in debug mode.
+func (wd *remoteWD) applyProfileRetention() {
+	p := wd.preserveProfile
+	if p == nil || !p.owned {
+		return
+	}
+	setProfileLive(p.path, false)
+	if p.failed != nil && !p.failed() {
+		if err := os.RemoveAll(p.path); err != nil {
+			debugLog("error removing the profile %s: %v", p.path, err)
+		}
+		p.path, p.owned = "", false
+	}
+	if p.keepLast > 0 {
+		if err := pruneProfiles(p.dir, p.keepLast); err != nil {
+			debugLog("error removing the old profiles of %s: %v", p.dir, err)
+		}
+	}
+}
+
+// pruneProfiles removes the profiles kept in dir but the last n, except those
+// of the open sessions.
+func pruneProfiles(dir string, n int) error {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() && strings.HasPrefix(e.Name(), preservedProfilePrefix) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	if len(names) <= n {
+		return nil
+	}
+	liveProfiles.Lock()
+	defer liveProfiles.Unlock()
+	for _, name := range names[:len(names)-n] {
+		path := filepath.Join(dir, name)
+		if liveProfiles.paths[path] {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyProfile copies the profile src, of a running browser, to dst. The files
+// that cannot be opened, such as locked ones, and those that are neither
+// regular files nor directories, such as the lock symlinks, are skipped.
+func copyProfile(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				// The browser removed the file meanwhile.
+				return nil
+			}
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, 0755)
+		case !info.Mode().IsRegular():
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			debugLog("skipping %s of the profile: %v", path, err)
+			return nil
+		}
+		defer in.Close()
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
+
+// ProfilePath returns the directory of the profile of the browser.
+func (wd *remoteWD) ProfilePath() (string, error) {
+	if p := wd.preserveProfile; p != nil && p.path != "" {
+		return p.path, nil
+	}
+	if wd.id == "" {
+		return "", errors.New("the session has ended and its profile was not preserved")
+	}
+	if path, _ := wd.sessionCapabilities["moz:profile"].(string); path != "" {
+		return path, nil
+	}
+	if info, err := chrome.SessionInfo(wd.sessionCapabilities); err == nil && info.UserDataDir != "" {
+		return info.UserDataDir, nil
+	}
+	return "", fmt.Errorf("%w: the remote end did not report the profile directory", ErrUnsupported)
+}
@@ -0,0 +1,143 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+func TestPreserveProfileChrome(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preserve-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	d := newFakeDriver(t, nil)
+
+	failed := false
+	chromeCaps := &chrome.Capabilities{Args: []string{"--headless"}}
+	caps := Capabilities{"browserName": "chrome", chrome.CapabilitiesKey: chromeCaps}
+	newSession := func() *remoteWD {
+		wd, err := NewRemoteContext(context.Background(), caps, d.URL, PreserveProfile(dir, KeepProfileOnFailure(func() bool { return failed })))
+		if err != nil {
+			t.Fatalf("NewRemoteContext() returned error: %v", err)
+		}
+		return wd.(*remoteWD)
+	}
+
+	wd := newSession()
+	path, err := wd.ProfilePath()
+	if err != nil {
+		t.Fatalf("wd.ProfilePath() returned error: %v", err)
+	}
+	if filepath.Dir(path) != dir || !strings.HasPrefix(filepath.Base(path), preservedProfilePrefix) {
+		t.Errorf("wd.ProfilePath() = %q, want a profile in %q", path, dir)
+	}
+	if got := wd.capabilities[chrome.CapabilitiesKey].(chrome.Capabilities); got.UserDataDir() != path {
+		t.Errorf("the session requested the user data directory %q, want %q", got.UserDataDir(), path)
+	}
+	if want := []string{"--headless"}; !reflect.DeepEqual(chromeCaps.Args, want) {
+		t.Errorf("PreserveProfile() changed the Chrome arguments of the caller to %q, want %q", chromeCaps.Args, want)
+	}
+
+	// The profile of a passing test is removed.
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the profile of a passing test was kept: os.Stat(%q) returned error %v", path, err)
+	}
+	if path, err := wd.ProfilePath(); err == nil {
+		t.Errorf("wd.ProfilePath() after a passing test = %q, want an error", path)
+	}
+
+	// The profile of a failing test is kept.
+	failed = true
+	wd = newSession()
+	path, err = wd.ProfilePath()
+	if err != nil {
+		t.Fatalf("wd.ProfilePath() returned error: %v", err)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if got, err := wd.ProfilePath(); err != nil || got != path {
+		t.Errorf("wd.ProfilePath() after a failing test = %q, %v; want %q", got, err, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("the profile of a failing test was removed: %v", err)
+	}
+}
+
+func TestPreserveProfileFirefox(t *testing.T) {
+	dir, err := ioutil.TempDir("", "preserve-profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "geckodriver")
+	if err := os.MkdirAll(filepath.Join(src, "extensions"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"prefs.js": "user_pref(...);", "extensions/ext.xpi": "xpi"} {
+		if err := ioutil.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink("127.0.0.1:+1234", filepath.Join(src, "lock")); err != nil {
+		t.Fatal(err)
+	}
+	kept := filepath.Join(dir, "kept")
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "firefox", "moz:profile": src})
+
+	var paths []string
+	for i := 0; i < 3; i++ {
+		wd, err := NewRemoteContext(context.Background(), nil, d.URL, PreserveProfile(kept, KeepLastProfiles(2)))
+		if err != nil {
+			t.Fatalf("NewRemoteContext() returned error: %v", err)
+		}
+		if path, err := wd.ProfilePath(); err != nil || path != src {
+			t.Errorf("wd.ProfilePath() during the session = %q, %v; want %q", path, err, src)
+		}
+		if err := wd.Quit(); err != nil {
+			t.Fatalf("wd.Quit() returned error: %v", err)
+		}
+		path, err := wd.ProfilePath()
+		if err != nil {
+			t.Fatalf("wd.ProfilePath() after Quit returned error: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	entries, err := ioutil.ReadDir(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{filepath.Base(paths[1]), filepath.Base(paths[2])}; !reflect.DeepEqual(names, want) {
+		t.Errorf("after three sessions, the kept profiles are %q, want the last two, %q", names, want)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(paths[2], "extensions", "ext.xpi")); err != nil || string(data) != "xpi" {
+		t.Errorf("the copy of the profile has extensions/ext.xpi = %q, %v; want %q", data, err, "xpi")
+	}
+	if _, err := os.Lstat(filepath.Join(paths[2], "lock")); !os.IsNotExist(err) {
+		t.Errorf("the lock of the profile was copied: os.Lstat() returned error %v", err)
+	}
+}
+
+func TestProfilePathUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if path, err := wd.ProfilePath(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.ProfilePath() without a reported profile = %q, %v; want ErrUnsupported", path, err)
+	}
+}
@@ -0,0 +1,119 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+// PrintOrientation is the orientation of the pages printed by PrintPage.
+type PrintOrientation string
+
+// Orientations of the printed pages.
+const (
+	PrintPortrait  PrintOrientation = "portrait"
+	PrintLandscape PrintOrientation = "landscape"
+)
+
+// PrintOptions are the options of PrintPage. The zero value prints all the
+// pages, in portrait, on US Letter paper with margins of 1cm and without
+// backgrounds, as the W3C specification prescribes.
+type PrintOptions struct {
+	// Orientation is PrintPortrait if empty.
+	Orientation PrintOrientation
+	// Scale scales the page, from 0.1 to 2. Zero means 1.
+	Scale float64
+	// Background prints the background colors and images.
+	Background bool
+	// PageRanges are the pages to print, e.g. "1", "3-5" or "7-" for the
+	// seventh page onwards, numbered from 1. No ranges print all the pages.
+	PageRanges []string
+	// Page is the size of the paper. Nil means US Letter, 21.59cm by
+	// 27.94cm.
+	Page *PrintPage
+	// Margins are the margins of the pages. Nil means 1cm on every side.
+	Margins *PrintMargins
+}
+
+// PrintPage is the size of the paper, in centimeters.
+type PrintPage struct {
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
+// Common paper sizes.
+var (
+	PrintA4     = &PrintPage{Width: 21, Height: 29.7}
+	PrintLetter = &PrintPage{Width: 21.59, Height: 27.94}
+	PrintLegal  = &PrintPage{Width: 21.59, Height: 35.56}
+)
+
+// PrintMargins are the margins of the pages, in centimeters.
+type PrintMargins struct {
+	Top    float64 `json:"top"`
+	Bottom float64 `json:"bottom"`
+	Left   float64 `json:"left"`
+	Right  float64 `json:"right"`
+}
+
+// params returns the parameters of the Print Page command, or an error for
+// options that the remote end would reject.
+func (o PrintOptions) params() (map[string]interface{}, error) {
+	params := map[string]interface{}{
+		"background": o.Background,
+	}
+	switch o.Orientation {
+	case "":
+	case PrintPortrait, PrintLandscape:
+		params["orientation"] = o.Orientation
+	default:
+		return nil, fmt.Errorf("invalid print orientation %q", o.Orientation)
+	}
+	if o.Scale != 0 {
+		if o.Scale < 0.1 || o.Scale > 2 {
+			return nil, fmt.Errorf("invalid print scale %g: it must be between 0.1 and 2", o.Scale)
+		}
+		params["scale"] = o.Scale
+	}
+	if len(o.PageRanges) > 0 {
+		params["pageRanges"] = o.PageRanges
+	}
+	if p := o.Page; p != nil {
+		// The specification requires at least 2.54/72cm, one point.
+		if p.Width < 2.54/72 || p.Height < 2.54/72 {
+			return nil, fmt.Errorf("invalid print page size %gcm by %gcm", p.Width, p.Height)
+		}
+		params["page"] = p
+	}
+	if m := o.Margins; m != nil {
+		if m.Top < 0 || m.Bottom < 0 || m.Left < 0 || m.Right < 0 {
+			return nil, fmt.Errorf("invalid print margins %+v: they must not be negative", *m)
+		}
+		params["margin"] = m
+	}
+	return params, nil
+}
+
+// PrintPage prints the current page to PDF.
+func (wd *remoteWD) PrintPage(opts PrintOptions) ([]byte, error) {
+	params, err := opts.params()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/print", wd.id), data)
+	if isUnknownCommand(err) {
+		return nil, fmt.Errorf("printing the page: %w", ErrUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(reply.Value)
+}
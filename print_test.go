@@ -0,0 +1,70 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestPrintPage(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var params map[string]interface{}
+	d.handle("POST", "/print", func(body []byte) (int, interface{}) {
+		params = nil
+		if err := json.Unmarshal(body, &params); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		return http.StatusOK, base64.StdEncoding.EncodeToString([]byte("%PDF-1.4"))
+	})
+	wd := d.newRemote(t)
+
+	pdf, err := wd.PrintPage(PrintOptions{})
+	if err != nil {
+		t.Fatalf("wd.PrintPage() returned error: %v", err)
+	}
+	if string(pdf) != "%PDF-1.4" {
+		t.Errorf("wd.PrintPage() = %q, want the decoded PDF", pdf)
+	}
+	if got := string(mustJSON(t, params)); got != `{"background":false}` {
+		t.Errorf("wd.PrintPage() with the zero options sent %s, want only background", got)
+	}
+
+	_, err = wd.PrintPage(PrintOptions{
+		Orientation: PrintLandscape,
+		Scale:       0.5,
+		Background:  true,
+		PageRanges:  []string{"1", "3-5"},
+		Page:        PrintA4,
+		Margins:     &PrintMargins{},
+	})
+	if err != nil {
+		t.Fatalf("wd.PrintPage() returned error: %v", err)
+	}
+	want := `{"background":true,"margin":{"bottom":0,"left":0,"right":0,"top":0},"orientation":"landscape","page":{"height":29.7,"width":21},"pageRanges":["1","3-5"],"scale":0.5}`
+	if got := string(mustJSON(t, params)); got != want {
+		t.Errorf("wd.PrintPage() sent %s, want %s", got, want)
+	}
+
+	for _, opts := range []PrintOptions{
+		{Scale: 3},
+		{Orientation: "sideways"},
+		{Page: &PrintPage{Width: 0, Height: 10}},
+		{Margins: &PrintMargins{Top: -1}},
+	} {
+		if _, err := wd.PrintPage(opts); err == nil {
+			t.Errorf("wd.PrintPage(%+v) returned no error", opts)
+		}
+	}
+	if n := d.count("POST", "/print"); n != 2 {
+		t.Errorf("the session sent %d Print Page commands, want 2", n)
+	}
+}
+
+func TestPrintPageUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.PrintPage(PrintOptions{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.PrintPage() returned error %v, want ErrUnsupported", err)
+	}
+}
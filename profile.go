@@ -0,0 +1,177 @@
+package selenium
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// commandTag tells on whose behalf a command is sent, so that the commands
+// issued internally can be told apart from those of the test.
+type commandTag int
+
+const (
+	// tagTest marks the commands sent by the test.
+	tagTest commandTag = iota
+	// tagWaitPoll marks the commands sent while a wait helper evaluates its
+	// condition.
+	tagWaitPoll
+	// tagBackground marks the commands sent by background pollers, such as
+	// WatchWindows.
+	tagBackground
+)
+
+// CommandClass is a class of commands accounted for by a Profile.
+type CommandClass string
+
+// Classes of commands.
+const (
+	// ClassNavigation is loading a page, going back or forward, or
+	// refreshing.
+	ClassNavigation CommandClass = "navigation"
+	// ClassFind is finding elements, including the time spent in implicit
+	// waits.
+	ClassFind CommandClass = "find"
+	// ClassScript is executing scripts.
+	ClassScript CommandClass = "script"
+	// ClassWaitPoll is any command sent while a wait helper, such as Wait or
+	// Open, evaluates its condition.
+	ClassWaitPoll CommandClass = "wait-poll"
+	// ClassOther is any other command.
+	ClassOther CommandClass = "other"
+)
+
+// commandClasses lists the classes in the order of the summary.
+var commandClasses = []CommandClass{ClassNavigation, ClassFind, ClassScript, ClassWaitPoll, ClassOther}
+
+// classify returns the class of a command, given its type as returned by
+// commandType.
+func classify(tag commandTag, typ string) CommandClass {
+	if tag == tagWaitPoll {
+		return ClassWaitPoll
+	}
+	switch typ {
+	case "POST /url", "POST /back", "POST /forward", "POST /refresh":
+		return ClassNavigation
+	case "POST /execute/sync", "POST /execute/async", "POST /execute", "POST /execute_async":
+		return ClassScript
+	}
+	if strings.HasSuffix(typ, "/element") || strings.HasSuffix(typ, "/elements") {
+		return ClassFind
+	}
+	return ClassOther
+}
+
+// ProfileReport is the time spent by the sessions of a Profile.
+type ProfileReport struct {
+	// Durations sums the time spent executing commands, by class.
+	Durations map[CommandClass]time.Duration
+	// Commands counts the commands, by class.
+	Commands map[CommandClass]int
+	// Waits counts the calls to the wait helpers, and WaitTime sums their
+	// duration, including the sleeps between polls. Nested waits are
+	// accounted for once.
+	Waits    int
+	WaitTime time.Duration
+	// PollIterations counts the evaluations of the conditions of the waits.
+	PollIterations int
+}
+
+// String summarizes the report, one line per class of commands, then the
+// waits.
+func (r ProfileReport) String() string {
+	var b strings.Builder
+	for _, c := range commandClasses {
+		if r.Commands[c] == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "%-10s %5d commands %12v\n", c, r.Commands[c], r.Durations[c])
+	}
+	fmt.Fprintf(&b, "%-10s %5d waits    %12v (%d polls)\n", "waits", r.Waits, r.WaitTime, r.PollIterations)
+	return b.String()
+}
+
+// Profile accumulates the time spent by sessions waiting and executing
+// commands, e.g. to find where a test suite spends its time. Attach it to
+// sessions with WithProfile. It is safe for concurrent use.
+type Profile struct {
+	mu     sync.Mutex
+	report ProfileReport
+}
+
+// NewProfile returns an empty Profile.
+func NewProfile() *Profile {
+	p := new(Profile)
+	p.Reset()
+	return p
+}
+
+// WithProfile makes the session account for its commands and waits in p.
+// Several sessions may share a Profile.
+func WithProfile(p *Profile) SessionOption {
+	return func(o *sessionOptions) {
+		o.profile = p
+	}
+}
+
+// Report returns a copy of the time accumulated so far.
+func (p *Profile) Report() ProfileReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r := p.report
+	r.Durations = make(map[CommandClass]time.Duration, len(p.report.Durations))
+	for c, d := range p.report.Durations {
+		r.Durations[c] = d
+	}
+	r.Commands = make(map[CommandClass]int, len(p.report.Commands))
+	for c, n := range p.report.Commands {
+		r.Commands[c] = n
+	}
+	return r
+}
+
+// Reset discards the time accumulated so far, e.g. between tests.
+func (p *Profile) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report = ProfileReport{
+		Durations: make(map[CommandClass]time.Duration),
+		Commands:  make(map[CommandClass]int),
+	}
+}
+
+// addCommand accounts for a command of the given type.
+func (p *Profile) addCommand(tag commandTag, typ string, d time.Duration) {
+	c := classify(tag, typ)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.Durations[c] += d
+	p.report.Commands[c]++
+}
+
+// addWait accounts for a call to a wait helper. The duration of nested waits
+// is already part of the outer one.
+func (p *Profile) addWait(nested bool, polls int, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report.PollIterations += polls
+	if !nested {
+		p.report.Waits++
+		p.report.WaitTime += d
+	}
+}
+
+// startWait marks the commands sent until end is called as sent by a wait
+// helper, and then accounts for the wait and its polls in the profile.
+func (wd *remoteWD) startWait() (end func(polls int)) {
+	nested := atomic.AddInt32(&wd.waiting, 1) > 1
+	start := time.Now()
+	return func(polls int) {
+		atomic.AddInt32(&wd.waiting, -1)
+		if wd.profile != nil {
+			wd.profile.addWait(nested, polls, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package selenium
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		tag  commandTag
+		typ  string
+		want CommandClass
+	}{
+		{tagTest, "POST /url", ClassNavigation},
+		{tagTest, "POST /back", ClassNavigation},
+		{tagTest, "POST /element", ClassFind},
+		{tagTest, "POST /element/:id/elements", ClassFind},
+		{tagTest, "POST /execute/sync", ClassScript},
+		{tagTest, "GET /title", ClassOther},
+		{tagWaitPoll, "POST /element", ClassWaitPoll},
+		{tagBackground, "GET /window/handles", ClassOther},
+	} {
+		if got := classify(tc.tag, tc.typ); got != tc.want {
+			t.Errorf("classify(%d, %q) = %q, want %q", tc.tag, tc.typ, got, tc.want)
+		}
+	}
+}
+
+func TestProfile(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	finds := 0
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		finds++
+		if finds < 3 {
+			return noSuchElementReply()
+		}
+		return http.StatusOK, map[string]string{webElementIdentifier: "main"}
+	})
+	p := NewProfile()
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithProfile(p))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	p.Reset()
+
+	if err := wd.Get("https://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.ExecuteScript("return 1;", nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	err = wd.WaitWithTimeoutAndInterval(func(wd WebDriver) (bool, error) {
+		_, err := wd.FindElement(ByCSSSelector, "main")
+		return err == nil, nil
+	}, time.Minute, time.Millisecond)
+	if err != nil {
+		t.Fatalf("wd.WaitWithTimeoutAndInterval() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(ByCSSSelector, "main"); err != nil {
+		t.Fatalf("wd.FindElement() returned error: %v", err)
+	}
+
+	r := p.Report()
+	want := map[CommandClass]int{ClassNavigation: 1, ClassScript: 1, ClassWaitPoll: 3, ClassFind: 1}
+	for c, n := range want {
+		if r.Commands[c] != n {
+			t.Errorf("Report().Commands[%q] = %d, want %d", c, r.Commands[c], n)
+		}
+	}
+	if r.Waits != 1 || r.PollIterations != 3 {
+		t.Errorf("Report() has %d waits and %d polls, want 1 and 3", r.Waits, r.PollIterations)
+	}
+	if r.WaitTime < r.Durations[ClassWaitPoll] {
+		t.Errorf("Report().WaitTime = %v, want at least the time of its commands, %v", r.WaitTime, r.Durations[ClassWaitPoll])
+	}
+	if s := r.String(); !strings.Contains(s, "wait-poll") || !strings.Contains(s, "(3 polls)") {
+		t.Errorf("Report().String() = %q, want the wait polls", s)
+	}
+}
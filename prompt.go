@@ -0,0 +1,152 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// PromptBehavior is what the remote end does with a user prompt that is open
+// when a command is sent; see the unhandledPromptBehavior capability.
+type PromptBehavior string
+
+// Behaviors of the user prompt handler.
+const (
+	PromptDismiss          PromptBehavior = "dismiss"
+	PromptAccept           PromptBehavior = "accept"
+	PromptDismissAndNotify PromptBehavior = "dismiss and notify"
+	PromptAcceptAndNotify  PromptBehavior = "accept and notify"
+	PromptIgnore           PromptBehavior = "ignore"
+)
+
+// PromptHandlerConfig is the user prompt handler of a session, with a
+// behavior per type of prompt. A type without behavior uses Default, and the
+// remote end's default if Default is empty too.
+type PromptHandlerConfig struct {
+	Alert        PromptBehavior `json:"alert,omitempty"`
+	Confirm      PromptBehavior `json:"confirm,omitempty"`
+	Prompt       PromptBehavior `json:"prompt,omitempty"`
+	BeforeUnload PromptBehavior `json:"beforeUnload,omitempty"`
+	Default      PromptBehavior `json:"default,omitempty"`
+}
+
+// behavior returns the behavior for a type of prompt given its own.
+func (h PromptHandlerConfig) behavior(b PromptBehavior) PromptBehavior {
+	if b != "" {
+		return b
+	}
+	return h.Default
+}
+
+// simple returns the behavior to send to remote ends that only accept a
+// string: Default, or the default of the specification.
+func (h PromptHandlerConfig) simple() PromptBehavior {
+	if h.Default != "" {
+		return h.Default
+	}
+	return PromptDismissAndNotify
+}
+
+// SetPromptHandler sets the unhandledPromptBehavior capability to h, e.g. to
+// accept the beforeunload prompts but leave the alerts to the test:
+//
+//	caps.SetPromptHandler(selenium.PromptHandlerConfig{
+//		BeforeUnload: selenium.PromptAccept,
+//		Default:      selenium.PromptIgnore,
+//	})
+//
+// The behaviors per type of prompt are recent in the specification: if the
+// remote end rejects them, the session is requested again with Default
+// only, or "dismiss and notify" if Default is empty. WithSimplePromptHandler
+// does so from the start.
+func (c Capabilities) SetPromptHandler(h PromptHandlerConfig) {
+	c["unhandledPromptBehavior"] = h
+}
+
+// WithSimplePromptHandler sends the unhandledPromptBehavior capability set
+// with Capabilities.SetPromptHandler as a string, Default, rather than as an
+// object, for remote ends that do not support behaviors per type of prompt.
+func WithSimplePromptHandler() SessionOption {
+	return func(o *sessionOptions) {
+		o.simplePromptHandler = true
+	}
+}
+
+// simplePromptHandler returns a copy of caps with the PromptHandlerConfig, if
+// any, replaced by its string form, and whether there was one.
+func simplePromptHandler(caps Capabilities) (Capabilities, bool) {
+	var h PromptHandlerConfig
+	switch v := caps["unhandledPromptBehavior"].(type) {
+	case PromptHandlerConfig:
+		h = v
+	case *PromptHandlerConfig:
+		h = *v
+	default:
+		return caps, false
+	}
+	out := make(Capabilities, len(caps))
+	for k, v := range caps {
+		out[k] = v
+	}
+	out["unhandledPromptBehavior"] = string(h.simple())
+	return out, true
+}
+
+// rejectsPromptHandler reports whether err is the rejection of the
+// unhandledPromptBehavior capability by the remote end.
+func rejectsPromptHandler(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && errors.Is(e, werror.InvalidArgument) && strings.Contains(e.Message, "unhandledPromptBehavior")
+}
+
+// PromptHandler returns the user prompt handler of the session.
+func (wd *remoteWD) PromptHandler() PromptHandlerConfig {
+	switch v := wd.sessionCapabilities["unhandledPromptBehavior"].(type) {
+	case string:
+		return PromptHandlerConfig{Default: PromptBehavior(v)}
+	case map[string]interface{}:
+		field := func(name string) PromptBehavior {
+			s, _ := v[name].(string)
+			return PromptBehavior(s)
+		}
+		return PromptHandlerConfig{
+			Alert:        field("alert"),
+			Confirm:      field("confirm"),
+			Prompt:       field("prompt"),
+			BeforeUnload: field("beforeUnload"),
+			Default:      field("default"),
+		}
+	}
+	return PromptHandlerConfig{}
+}
+
+// alertError explains a "no such alert" error by the prompt handler of the
+// session, if it handles the dialogs without the test.
+func (wd *remoteWD) alertError(err error) error {
+	if !errors.Is(err, werror.NoSuchAlert) {
+		return err
+	}
+	h := wd.PromptHandler()
+	var handled []string
+	for _, p := range []struct {
+		name     string
+		behavior PromptBehavior
+	}{
+		{"alerts", h.behavior(h.Alert)},
+		{"confirms", h.behavior(h.Confirm)},
+		{"prompts", h.behavior(h.Prompt)},
+	} {
+		switch p.behavior {
+		case PromptAccept, PromptAcceptAndNotify:
+			handled = append(handled, p.name+" are auto-accepted")
+		case PromptDismiss, PromptDismissAndNotify:
+			handled = append(handled, p.name+" are auto-dismissed")
+		}
+	}
+	if len(handled) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w; the dialog may have been handled by the session's prompt handler: %s", err, strings.Join(handled, ", "))
+}
@@ -0,0 +1,113 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// fakePromptHandlerDriver returns a fake driver that answers New Session
+// requests with the prompt handler requested, rejecting the object form if
+// rejectObject is true, and the list of prompt handlers requested.
+func fakePromptHandlerDriver(t *testing.T, rejectObject bool) (*fakeDriver, *[]interface{}) {
+	d := newFakeDriver(t, nil)
+	var requested []interface{}
+	d.newSession = func(body []byte) (int, interface{}) {
+		var params struct {
+			Capabilities struct {
+				AlwaysMatch map[string]interface{}
+			}
+		}
+		json.Unmarshal(body, &params)
+		h := params.Capabilities.AlwaysMatch["unhandledPromptBehavior"]
+		requested = append(requested, h)
+		if _, ok := h.(map[string]interface{}); ok && rejectObject {
+			return http.StatusBadRequest, map[string]string{
+				"error":   "invalid argument",
+				"message": "cannot parse capability: unhandledPromptBehavior",
+			}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"sessionId":    fakeSessionID,
+			"capabilities": map[string]interface{}{"unhandledPromptBehavior": h},
+		}
+	}
+	return d, &requested
+}
+
+func TestPromptHandler(t *testing.T) {
+	caps := Capabilities{}
+	caps.SetPromptHandler(PromptHandlerConfig{BeforeUnload: PromptAccept, Default: PromptIgnore})
+	want := PromptHandlerConfig{BeforeUnload: PromptAccept, Default: PromptIgnore}
+
+	d, requested := fakePromptHandlerDriver(t, false)
+	wd, err := NewRemoteContext(context.Background(), caps, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if got := wd.PromptHandler(); got != want {
+		t.Errorf("wd.PromptHandler() = %+v, want %+v", got, want)
+	}
+	if len(*requested) != 1 {
+		t.Errorf("NewRemoteContext() requested the prompt handlers %v, want the object only", *requested)
+	}
+
+	// The string form is requested from remote ends that reject the object.
+	d, requested = fakePromptHandlerDriver(t, true)
+	wd, err = NewRemoteContext(context.Background(), caps, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemoteContext() with a remote end that rejects the object returned error: %v", err)
+	}
+	if got, want := wd.PromptHandler(), (PromptHandlerConfig{Default: PromptIgnore}); got != want {
+		t.Errorf("wd.PromptHandler() = %+v, want %+v", got, want)
+	}
+	if len(*requested) != 2 || (*requested)[1] != "ignore" {
+		t.Errorf("NewRemoteContext() requested the prompt handlers %v, want the object, then %q", *requested, "ignore")
+	}
+
+	d, requested = fakePromptHandlerDriver(t, true)
+	if _, err := NewRemoteContext(context.Background(), caps, d.URL, WithSimplePromptHandler()); err != nil {
+		t.Fatalf("NewRemoteContext() with WithSimplePromptHandler returned error: %v", err)
+	}
+	if len(*requested) != 1 || (*requested)[0] != "ignore" {
+		t.Errorf("NewRemoteContext() with WithSimplePromptHandler requested the prompt handlers %v, want %q only", *requested, "ignore")
+	}
+}
+
+func TestAlertErrorPromptHandler(t *testing.T) {
+	noSuchAlert := map[string]string{"error": "no such alert", "message": "no such alert"}
+	for _, tc := range []struct {
+		handler interface{}
+		want    string
+	}{
+		{"dismiss and notify", "alerts are auto-dismissed, confirms are auto-dismissed, prompts are auto-dismissed"},
+		{map[string]string{"alert": "accept", "default": "ignore"}, "prompt handler: alerts are auto-accepted"},
+		{"ignore", ""},
+		{nil, ""},
+	} {
+		d := newFakeDriver(t, map[string]interface{}{"unhandledPromptBehavior": tc.handler})
+		d.handle("GET", "/alert/text", func([]byte) (int, interface{}) {
+			return http.StatusNotFound, noSuchAlert
+		})
+		d.handle("POST", "/alert/accept", func([]byte) (int, interface{}) {
+			return http.StatusNotFound, noSuchAlert
+		})
+		wd := d.newRemote(t)
+		_, textErr := wd.AlertText()
+		for _, err := range []error{textErr, wd.AcceptAlert()} {
+			var e *Error
+			if !errors.As(err, &e) || e.Err != "no such alert" {
+				t.Errorf("with the prompt handler %v, the error %v does not wrap the *Error of the remote end", tc.handler, err)
+			}
+			if tc.want == "" && strings.Contains(err.Error(), "prompt handler") {
+				t.Errorf("with the prompt handler %v, the error %q mentions the prompt handler", tc.handler, err)
+			}
+			if tc.want != "" && !strings.HasSuffix(err.Error(), tc.want) {
+				t.Errorf("with the prompt handler %v, the error %q does not end with %q", tc.handler, err, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// DirectProxy returns the proxy capability that makes the browser connect
+// directly, ignoring the proxy settings of the system.
+func DirectProxy() Proxy {
+	return Proxy{Type: Direct}
+}
+
+// SystemProxy returns the proxy capability that makes the browser use the
+// proxy settings of the system.
+func SystemProxy() Proxy {
+	return Proxy{Type: System}
+}
+
+// AutodetectProxy returns the proxy capability that makes the browser detect
+// its proxy on the network, with WPAD.
+func AutodetectProxy() Proxy {
+	return Proxy{Type: Autodetect}
+}
+
+// PACProxy returns the proxy capability that makes the browser choose its
+// proxies with the proxy auto-config file at autoconfigURL, e.g. one served
+// by ServePAC.
+func PACProxy(autoconfigURL string) Proxy {
+	return Proxy{Type: PAC, AutoconfigURL: autoconfigURL}
+}
+
+// HTTPProxy returns the proxy capability that sends the http and https
+// requests of the browser through the HTTP proxy at addr, a "host:port"
+// address, except those to the hosts in noProxy.
+func HTTPProxy(addr string, noProxy ...string) Proxy {
+	return Proxy{Type: Manual, HTTP: addr, SSL: addr, NoProxy: noProxy}
+}
+
+// SOCKSProxy returns the proxy capability that sends the traffic of the
+// browser through the SOCKS proxy at addr, a "host:port" address, of version
+// 4 or 5, except that to the hosts in noProxy. The credentials of the proxy,
+// if any, are set with the SOCKSUsername and SOCKSPassword fields.
+func SOCKSProxy(addr string, version int, noProxy ...string) Proxy {
+	return Proxy{Type: Manual, SOCKS: addr, SOCKSVersion: version, NoProxy: noProxy}
+}
+
+// Validate checks that p is a valid proxy object for the W3C specification,
+// which remote ends reject with an "invalid argument" error when creating the
+// session otherwise.
+func (p Proxy) Validate() error {
+	manual := p.FTP != "" || p.HTTP != "" || p.SSL != "" || p.SOCKS != "" || p.SOCKSVersion != 0 ||
+		p.SOCKSUsername != "" || p.SOCKSPassword != "" || len(p.NoProxy) > 0 ||
+		p.HTTPPort != 0 || p.SSLPort != 0 || p.SocksPort != 0
+	switch p.Type {
+	case Direct, System, Autodetect:
+	case PAC:
+		if u, err := url.Parse(p.AutoconfigURL); err != nil || !u.IsAbs() {
+			return fmt.Errorf("invalid proxy: autoconfig URL %q is not an absolute URL", p.AutoconfigURL)
+		}
+	case Manual:
+		if p.FTP == "" && p.HTTP == "" && p.SSL == "" && p.SOCKS == "" {
+			return errors.New("invalid proxy: manual proxy without any proxy set")
+		}
+		for _, addr := range []string{p.FTP, p.HTTP, p.SSL, p.SOCKS} {
+			if strings.Contains(addr, "://") {
+				return fmt.Errorf("invalid proxy: address %q has a scheme, want host:port", addr)
+			}
+		}
+		if p.SOCKS == "" {
+			if p.SOCKSVersion != 0 || p.SOCKSUsername != "" || p.SOCKSPassword != "" || p.SocksPort != 0 {
+				return errors.New("invalid proxy: SOCKS settings without a SOCKS proxy")
+			}
+		} else if p.SOCKSVersion != 4 && p.SOCKSVersion != 5 {
+			return fmt.Errorf("invalid proxy: SOCKS version %d, want 4 or 5", p.SOCKSVersion)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid proxy: unknown proxy type %q", p.Type)
+	}
+	if p.Type != PAC && p.AutoconfigURL != "" {
+		return fmt.Errorf("invalid proxy: autoconfig URL with proxy type %q", p.Type)
+	}
+	if manual {
+		return fmt.Errorf("invalid proxy: manual settings with proxy type %q", p.Type)
+	}
+	return nil
+}
+
+// MarshalJSON encodes p as a W3C proxy object, with the fields of its type
+// only. The ports of the legacy fields are added to the addresses.
+func (p Proxy) MarshalJSON() ([]byte, error) {
+	type w3cProxy struct {
+		Type          ProxyType `json:"proxyType"`
+		AutoconfigURL string    `json:"proxyAutoconfigUrl,omitempty"`
+		FTP           string    `json:"ftpProxy,omitempty"`
+		HTTP          string    `json:"httpProxy,omitempty"`
+		SSL           string    `json:"sslProxy,omitempty"`
+		SOCKS         string    `json:"socksProxy,omitempty"`
+		SOCKSVersion  int       `json:"socksVersion,omitempty"`
+		SOCKSUsername string    `json:"socksUsername,omitempty"`
+		SOCKSPassword string    `json:"socksPassword,omitempty"`
+		NoProxy       []string  `json:"noProxy,omitempty"`
+	}
+	w := w3cProxy{Type: p.Type}
+	switch p.Type {
+	case Direct, System, Autodetect:
+	case PAC:
+		w.AutoconfigURL = p.AutoconfigURL
+	default:
+		w.AutoconfigURL = p.AutoconfigURL
+		w.FTP = p.FTP
+		w.HTTP = withPort(p.HTTP, p.HTTPPort)
+		w.SSL = withPort(p.SSL, p.SSLPort)
+		w.SOCKS = withPort(p.SOCKS, p.SocksPort)
+		if w.SOCKS != "" {
+			w.SOCKSVersion = p.SOCKSVersion
+			w.SOCKSUsername, w.SOCKSPassword = p.SOCKSUsername, p.SOCKSPassword
+		}
+		w.NoProxy = p.NoProxy
+	}
+	return json.Marshal(w)
+}
+
+// withPort returns addr with port, unless addr is empty or has a port
+// already.
+func withPort(addr string, port int) string {
+	if addr == "" || port == 0 {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	return net.JoinHostPort(strings.Trim(addr, "[]"), strconv.Itoa(port))
+}
+
+// pacContentType is the media type of proxy auto-config files.
+const pacContentType = "application/x-ns-proxy-autoconfig"
+
+// ServePAC serves script, which must define FindProxyForURL, as a proxy
+// auto-config file on the loopback interface. It returns the URL of the file,
+// for Proxy.AutoconfigURL with a Proxy of type PAC, and a function that stops
+// serving it.
+func ServePAC(script string) (pacURL string, close func(), err error) {
+	if !strings.Contains(script, "FindProxyForURL") {
+		return "", nil, errors.New("the proxy auto-config script does not define FindProxyForURL")
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", nil, err
+	}
+	s := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", pacContentType)
+		w.Write([]byte(script))
+	}))
+	s.Listener.Close()
+	s.Listener = l
+	s.Start()
+	return s.URL + "/proxy.pac", s.Close, nil
+}
+
+// chromeProxy returns the W3C proxy capability that matches the proxy set
+// with chrome.Capabilities.SetProxy.
+func chromeProxy(proxyURL string, bypass []string) Proxy {
+	p := Proxy{Type: Manual, NoProxy: bypass}
+	if !strings.Contains(proxyURL, "=") {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return p
+		}
+		switch u.Scheme {
+		case "socks4":
+			p.SOCKS, p.SOCKSVersion = u.Host, 4
+		case "socks5":
+			p.SOCKS, p.SOCKSVersion = u.Host, 5
+		default:
+			p.HTTP, p.SSL = u.Host, u.Host
+		}
+		return p
+	}
+	for _, rule := range strings.Split(proxyURL, ";") {
+		i := strings.Index(rule, "=")
+		if i < 0 {
+			continue
+		}
+		host, version := rule[i+1:], 0
+		if u, err := url.Parse(host); err == nil && u.Host != "" {
+			host = u.Host
+			switch u.Scheme {
+			case "socks4":
+				version = 4
+			case "socks5":
+				version = 5
+			}
+		}
+		switch scheme := rule[:i]; {
+		case scheme == "socks" || version != 0:
+			if version == 0 {
+				version = 5
+			}
+			p.SOCKS, p.SOCKSVersion = host, version
+		case scheme == "http":
+			p.HTTP = host
+		case scheme == "https":
+			p.SSL = host
+		case scheme == "ftp":
+			p.FTP = host
+		}
+	}
+	return p
+}
+
+// ProxySpec describes a manual proxy configuration once for all browsers.
+// SetProxySpec translates it to the W3C proxy capability and to the switches
+// and preferences that each browser needs on top of it.
+type ProxySpec struct {
+	// HTTP and HTTPS are the "host:port" addresses of the HTTP proxies for
+	// the http and https URLs.
+	HTTP, HTTPS string
+	// SOCKS is the "host:port" address of a SOCKS proxy, used for the
+	// schemes without an HTTP proxy.
+	SOCKS string
+	// SOCKSVersion is 4 or 5, the default.
+	SOCKSVersion int
+	// SOCKSRemoteDNS makes the browser send host names to the SOCKS proxy,
+	// which resolves them, instead of resolving them itself. It requires
+	// SOCKS version 5.
+	SOCKSRemoteDNS bool
+	// NoProxy are the hosts reached without the proxy, e.g. "localhost" or
+	// "*.corp.example".
+	NoProxy []string
+	// ProxyLoopback sends the requests to localhost and loopback addresses
+	// through the proxy too, which browsers do not do by default.
+	ProxyLoopback bool
+}
+
+// socksVersion returns the SOCKS version of p, with its default.
+func (p ProxySpec) socksVersion() int {
+	if p.SOCKSVersion == 0 {
+		return 5
+	}
+	return p.SOCKSVersion
+}
+
+// validate checks the consistency of p.
+func (p ProxySpec) validate() error {
+	if p.HTTP == "" && p.HTTPS == "" && p.SOCKS == "" {
+		return errors.New("invalid proxy spec: no proxy set")
+	}
+	for _, addr := range []string{p.HTTP, p.HTTPS, p.SOCKS} {
+		if addr == "" {
+			continue
+		}
+		if _, port, err := net.SplitHostPort(addr); err != nil || port == "" {
+			return fmt.Errorf("invalid proxy spec: proxy address %q is not host:port", addr)
+		}
+	}
+	if v := p.socksVersion(); v != 4 && v != 5 {
+		return fmt.Errorf("invalid proxy spec: SOCKS version %d, want 4 or 5", p.SOCKSVersion)
+	}
+	if p.SOCKSRemoteDNS && (p.SOCKS == "" || p.socksVersion() != 5) {
+		return errors.New("invalid proxy spec: remote DNS requires a SOCKS5 proxy")
+	}
+	return nil
+}
+
+// Capability returns the W3C proxy capability of p.
+func (p ProxySpec) Capability() (Proxy, error) {
+	if err := p.validate(); err != nil {
+		return Proxy{}, err
+	}
+	c := Proxy{Type: Manual, HTTP: p.HTTP, SSL: p.HTTPS, NoProxy: p.NoProxy}
+	if p.SOCKS != "" {
+		c.SOCKS, c.SOCKSVersion = p.SOCKS, p.socksVersion()
+	}
+	return c, nil
+}
+
+// chromeRules returns the value of Chrome's --proxy-server switch for p: a
+// single proxy URL when one proxy serves all the schemes, else per-scheme
+// rules.
+func (p ProxySpec) chromeRules() string {
+	socks := ""
+	if p.SOCKS != "" {
+		socks = fmt.Sprintf("socks%d://%s", p.socksVersion(), p.SOCKS)
+	}
+	switch {
+	case p.HTTP == "" && p.HTTPS == "":
+		return socks
+	case p.HTTP == p.HTTPS && socks == "":
+		return "http://" + p.HTTP
+	}
+	var rules []string
+	if p.HTTP != "" {
+		rules = append(rules, "http="+p.HTTP)
+	}
+	if p.HTTPS != "" {
+		rules = append(rules, "https="+p.HTTPS)
+	}
+	if socks != "" {
+		rules = append(rules, "socks="+socks)
+	}
+	return strings.Join(rules, ";")
+}
+
+// Chrome applies p to the Chrome options c, with the --proxy-server,
+// --proxy-bypass-list and, for SOCKSRemoteDNS, --host-resolver-rules
+// switches. Chrome bypasses the proxy for loopback addresses unless the
+// bypass list contains "<-loopback>", which ProxyLoopback adds.
+func (p ProxySpec) Chrome(c *chrome.Capabilities) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	bypass := append([]string(nil), p.NoProxy...)
+	if p.ProxyLoopback {
+		bypass = append(bypass, "<-loopback>")
+	}
+	if err := c.SetProxy(p.chromeRules(), bypass); err != nil {
+		return err
+	}
+	host := ""
+	if p.SOCKSRemoteDNS {
+		host, _, _ = net.SplitHostPort(p.SOCKS)
+	}
+	c.SetSOCKSRemoteDNS(host)
+	return nil
+}
+
+// Firefox applies to the Firefox options c the preferences that p needs on
+// top of the W3C proxy capability, returned by Capability, which geckodriver
+// translates to the other preferences.
+func (p ProxySpec) Firefox(c *firefox.Capabilities) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	c.SetSOCKSRemoteDNS(p.SOCKSRemoteDNS)
+	c.SetProxyLoopback(p.ProxyLoopback)
+	return nil
+}
+
+// SetProxySpec sets the proxy capability of c to p and applies p to the
+// browser options of c, like SetUserAgent.
+func (c Capabilities) SetProxySpec(p ProxySpec) error {
+	proxy, err := p.Capability()
+	if err != nil {
+		return err
+	}
+	var chromeErr error
+	if err := c.updateBrowserOptions(
+		func(o *chrome.Capabilities) {
+			if err := p.Chrome(o); err != nil && chromeErr == nil {
+				chromeErr = err
+			}
+			// The capability must match the switches for ChromeDriver.
+			_, proxy.NoProxy = o.Proxy()
+		},
+		func(o *firefox.Capabilities) { p.Firefox(o) },
+	); err != nil {
+		return err
+	}
+	if chromeErr != nil {
+		return chromeErr
+	}
+	c["proxy"] = proxy
+	c.warnProxyConflict()
+	return nil
+}
+
+// warnProxyConflict logs a warning if c configures both a manual proxy and a
+// proxy auto-config file, of which browsers only honor one.
+func (c Capabilities) warnProxyConflict() {
+	var manual, pac bool
+	switch p := c["proxy"].(type) {
+	case Proxy:
+		manual, pac = p.Type == Manual, p.Type == PAC
+	case *Proxy:
+		manual, pac = p.Type == Manual, p.Type == PAC
+	}
+	if f, ok := c[chrome.CapabilitiesKey].(chrome.Capabilities); ok {
+		if u, _ := f.Proxy(); u != "" {
+			manual = true
+		}
+		for _, arg := range f.Args {
+			if strings.HasPrefix(arg, "--proxy-pac-url") {
+				pac = true
+			}
+		}
+	}
+	if manual && pac {
+		log.Printf("selenium: the capabilities configure both a manual proxy and a proxy auto-config file; only one will be used")
+	}
+}
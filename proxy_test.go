@@ -0,0 +1,233 @@
+package selenium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+	"github.com/google/go-cmp/cmp"
+)
+
+// The proxy types other than Direct are untyped constants, which code
+// written before ProxyType assigns to strings.
+var _ = []string{Manual, Autodetect, System, PAC}
+
+func TestServePAC(t *testing.T) {
+	const script = `function FindProxyForURL(url, host) { return "DIRECT"; }`
+	u, closePAC, err := ServePAC(script)
+	if err != nil {
+		t.Fatalf("ServePAC() returned error: %v", err)
+	}
+	resp, err := http.Get(u)
+	if err != nil {
+		t.Fatalf("http.Get(%q) returned error: %v", u, err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != script || resp.Header.Get("Content-Type") != pacContentType {
+		t.Errorf("GET %s = %q with content type %q, want %q with content type %q", u, body, resp.Header.Get("Content-Type"), script, pacContentType)
+	}
+	closePAC()
+	if _, err := http.Get(u); err == nil {
+		t.Errorf("http.Get(%q) succeeded after the server was closed", u)
+	}
+
+	if _, _, err := ServePAC("return 'DIRECT';"); err == nil {
+		t.Error("ServePAC() of a script without FindProxyForURL returned no error")
+	}
+}
+
+func TestAddChromeProxy(t *testing.T) {
+	for _, test := range []struct {
+		proxyURL string
+		want     Proxy
+	}{
+		{"http://proxy.example:3128", Proxy{Type: Manual, HTTP: "proxy.example:3128", SSL: "proxy.example:3128", NoProxy: []string{"localhost"}}},
+		{"socks5://127.0.0.1:1080", Proxy{Type: Manual, SOCKS: "127.0.0.1:1080", SOCKSVersion: 5, NoProxy: []string{"localhost"}}},
+	} {
+		var f chrome.Capabilities
+		if err := f.SetProxy(test.proxyURL, []string{"localhost"}); err != nil {
+			t.Fatalf("f.SetProxy(%q) returned error: %v", test.proxyURL, err)
+		}
+		caps := Capabilities{}
+		caps.AddChrome(f)
+		if diff := cmp.Diff(test.want, caps["proxy"]); diff != "" {
+			t.Errorf("after f.SetProxy(%q), the proxy capability returned diff (-want/+got):\n%s", test.proxyURL, diff)
+		}
+	}
+}
+
+func TestAddChromeProxyRules(t *testing.T) {
+	var f chrome.Capabilities
+	rules := "http=proxy.example:3128;https=proxy.example:3129;socks=socks4://127.0.0.1:1080"
+	if err := f.SetProxy(rules, nil); err != nil {
+		t.Fatalf("f.SetProxy(%q) returned error: %v", rules, err)
+	}
+	caps := Capabilities{}
+	caps.AddChrome(f)
+	want := Proxy{Type: Manual, HTTP: "proxy.example:3128", SSL: "proxy.example:3129", SOCKS: "127.0.0.1:1080", SOCKSVersion: 4}
+	if diff := cmp.Diff(want, caps["proxy"]); diff != "" {
+		t.Errorf("after f.SetProxy(%q), the proxy capability returned diff (-want/+got):\n%s", rules, diff)
+	}
+}
+
+func TestSetProxySpec(t *testing.T) {
+	spec := ProxySpec{
+		HTTP:           "proxy.example:3128",
+		HTTPS:          "proxy.example:3128",
+		SOCKS:          "socks.example:1080",
+		SOCKSRemoteDNS: true,
+		NoProxy:        []string{"*.corp.example"},
+		ProxyLoopback:  true,
+	}
+
+	caps := Capabilities{"browserName": "chrome"}
+	if err := caps.SetProxySpec(spec); err != nil {
+		t.Fatalf("caps.SetProxySpec() returned error: %v", err)
+	}
+	c := caps[chrome.CapabilitiesKey].(chrome.Capabilities)
+	wantArgs := []string{
+		"--proxy-server=http=proxy.example:3128;https=proxy.example:3128;socks=socks5://socks.example:1080",
+		"--proxy-bypass-list=*.corp.example;<-loopback>",
+		"--host-resolver-rules=MAP * ~NOTFOUND , EXCLUDE socks.example",
+	}
+	if diff := cmp.Diff(wantArgs, c.Args); diff != "" {
+		t.Errorf("Chrome Args returned diff (-want/+got):\n%s", diff)
+	}
+	want := Proxy{
+		Type:         Manual,
+		HTTP:         "proxy.example:3128",
+		SSL:          "proxy.example:3128",
+		SOCKS:        "socks.example:1080",
+		SOCKSVersion: 5,
+		NoProxy:      []string{"*.corp.example", "<-loopback>"},
+	}
+	if diff := cmp.Diff(want, caps["proxy"]); diff != "" {
+		t.Errorf("Chrome proxy capability returned diff (-want/+got):\n%s", diff)
+	}
+
+	f := &firefox.Capabilities{}
+	caps = Capabilities{"browserName": "firefox", firefox.CapabilitiesKey: f}
+	if err := caps.SetProxySpec(spec); err != nil {
+		t.Fatalf("caps.SetProxySpec() returned error: %v", err)
+	}
+	wantPrefs := map[string]interface{}{
+		"network.proxy.socks_remote_dns":          true,
+		"network.proxy.allow_hijacking_localhost": true,
+	}
+	if diff := cmp.Diff(wantPrefs, f.Prefs); diff != "" {
+		t.Errorf("Firefox prefs returned diff (-want/+got):\n%s", diff)
+	}
+	want.NoProxy = []string{"*.corp.example"}
+	if diff := cmp.Diff(want, caps["proxy"]); diff != "" {
+		t.Errorf("Firefox proxy capability returned diff (-want/+got):\n%s", diff)
+	}
+}
+
+func TestProxySpecChromeRules(t *testing.T) {
+	for _, test := range []struct {
+		spec ProxySpec
+		want string
+	}{
+		{ProxySpec{HTTP: "p:1", HTTPS: "p:1"}, "http://p:1"},
+		{ProxySpec{SOCKS: "s:2", SOCKSVersion: 4}, "socks4://s:2"},
+		{ProxySpec{HTTP: "p:1"}, "http=p:1"},
+		{ProxySpec{HTTPS: "p:1", SOCKS: "s:2"}, "https=p:1;socks=socks5://s:2"},
+	} {
+		if got := test.spec.chromeRules(); got != test.want {
+			t.Errorf("%+v.chromeRules() = %q, want %q", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestProxySpecInvalid(t *testing.T) {
+	for _, spec := range []ProxySpec{
+		{},
+		{HTTP: "proxy.example"},
+		{SOCKS: "s:2", SOCKSVersion: 6},
+		{SOCKS: "s:2", SOCKSVersion: 4, SOCKSRemoteDNS: true},
+		{HTTP: "p:1", SOCKSRemoteDNS: true},
+	} {
+		if _, err := spec.Capability(); err == nil {
+			t.Errorf("%+v.Capability() returned no error", spec)
+		}
+		caps := Capabilities{"browserName": "chrome"}
+		if err := caps.SetProxySpec(spec); err == nil {
+			t.Errorf("caps.SetProxySpec(%+v) returned no error", spec)
+		}
+		if _, ok := caps["proxy"]; ok {
+			t.Errorf("caps.SetProxySpec(%+v) failed but set the proxy capability", spec)
+		}
+	}
+}
+
+func TestProxyMarshalJSON(t *testing.T) {
+	socks := SOCKSProxy("127.0.0.1:1080", 5, "localhost")
+	socks.SOCKSUsername, socks.SOCKSPassword = "user", "secret"
+	for _, test := range []struct {
+		proxy Proxy
+		want  string
+	}{
+		{DirectProxy(), `{"proxyType":"direct"}`},
+		{SystemProxy(), `{"proxyType":"system"}`},
+		{AutodetectProxy(), `{"proxyType":"autodetect"}`},
+		{PACProxy("http://127.0.0.1:8000/proxy.pac"), `{"proxyType":"pac","proxyAutoconfigUrl":"http://127.0.0.1:8000/proxy.pac"}`},
+		{HTTPProxy("proxy.example:3128", "localhost", ".corp.example"), `{"proxyType":"manual","httpProxy":"proxy.example:3128","sslProxy":"proxy.example:3128","noProxy":["localhost",".corp.example"]}`},
+		{socks, `{"proxyType":"manual","socksProxy":"127.0.0.1:1080","socksVersion":5,"socksUsername":"user","socksPassword":"secret","noProxy":["localhost"]}`},
+		// The legacy ports are added to the addresses.
+		{Proxy{Type: Manual, HTTP: "proxy.example", HTTPPort: 3128, SSL: "proxy.example:3129", SSLPort: 1, SOCKS: "::1", SocksPort: 1080, SOCKSVersion: 4}, `{"proxyType":"manual","httpProxy":"proxy.example:3128","sslProxy":"proxy.example:3129","socksProxy":"[::1]:1080","socksVersion":4}`},
+		// The fields of other types are dropped.
+		{Proxy{Type: PAC, AutoconfigURL: "http://pac.example/p.pac", HTTP: "proxy.example:3128"}, `{"proxyType":"pac","proxyAutoconfigUrl":"http://pac.example/p.pac"}`},
+		{Proxy{Type: Manual, HTTP: "proxy.example:3128", SOCKSVersion: 5}, `{"proxyType":"manual","httpProxy":"proxy.example:3128"}`},
+	} {
+		for _, v := range []interface{}{test.proxy, &test.proxy} {
+			got, err := json.Marshal(v)
+			if err != nil {
+				t.Fatalf("json.Marshal(%+v) returned error: %v", test.proxy, err)
+			}
+			if string(got) != test.want {
+				t.Errorf("json.Marshal(%+v) = %s, want %s", test.proxy, got, test.want)
+			}
+		}
+	}
+}
+
+func TestProxyValidate(t *testing.T) {
+	for _, p := range []Proxy{
+		DirectProxy(),
+		SystemProxy(),
+		AutodetectProxy(),
+		PACProxy("http://127.0.0.1:8000/proxy.pac"),
+		HTTPProxy("proxy.example:3128", "localhost"),
+		SOCKSProxy("127.0.0.1:1080", 4),
+		{Type: Manual, SOCKS: "127.0.0.1", SocksPort: 1080, SOCKSVersion: 5, SOCKSUsername: "user", SOCKSPassword: "secret"},
+	} {
+		if err := p.Validate(); err != nil {
+			t.Errorf("%+v.Validate() returned error: %v", p, err)
+		}
+	}
+	for _, p := range []Proxy{
+		{},
+		{Type: "socks"},
+		PACProxy(""),
+		PACProxy("proxy.pac"),
+		{Type: Direct, HTTP: "proxy.example:3128"},
+		{Type: System, AutoconfigURL: "http://pac.example/p.pac"},
+		{Type: Manual},
+		{Type: Manual, NoProxy: []string{"localhost"}},
+		HTTPProxy("http://proxy.example:3128"),
+		SOCKSProxy("127.0.0.1:1080", 0),
+		SOCKSProxy("127.0.0.1:1080", 6),
+		{Type: Manual, HTTP: "proxy.example:3128", SOCKSUsername: "user"},
+	} {
+		if err := p.Validate(); err == nil {
+			t.Errorf("%+v.Validate() returned no error", p)
+		}
+	}
+}
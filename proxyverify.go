@@ -0,0 +1,338 @@
+package selenium
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"strconv"
+	"sync"
+
+	"github.com/armon/go-socks5"
+)
+
+// ErrProxyMismatch is matched by the errors of VerifyProxy when the browser
+// reached the test endpoint but not through the proxy as expected.
+var ErrProxyMismatch = errors.New("the browser did not use the proxy as expected")
+
+// testProxyVia is the Via header that TestProxy adds to the requests that
+// it forwards.
+const testProxyVia = "1.1 selenium-test-proxy"
+
+// ProxyRequest is a request received by a TestProxy.
+type ProxyRequest struct {
+	// Protocol is "http" for a request forwarded by URL, "connect" for a
+	// tunnel opened with the HTTP CONNECT method and "socks5" for a SOCKS5
+	// connection.
+	Protocol string
+	// Target is the "host:port" address that the client asked for.
+	Target string
+	// HostName reports whether the client sent a host name rather than an IP
+	// address, i.e. whether the proxy resolved it.
+	HostName bool
+}
+
+// TestProxy is a local proxy that accepts HTTP and SOCKS5 clients on the
+// same port and records their requests, to check the proxy settings of a
+// browser with VerifyProxy.
+type TestProxy struct {
+	// Addr is the "host:port" address of the proxy, on the loopback
+	// interface.
+	Addr string
+
+	listener net.Listener
+	conns    chan net.Conn
+	done     chan struct{}
+	socks    *socks5.Server
+	server   *http.Server
+
+	mu       sync.Mutex
+	requests []ProxyRequest
+}
+
+// NewTestProxy starts a TestProxy. Stop it with Close.
+func NewTestProxy() (*TestProxy, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &TestProxy{
+		Addr:     l.Addr().String(),
+		listener: l,
+		conns:    make(chan net.Conn),
+		done:     make(chan struct{}),
+	}
+	p.socks, err = socks5.New(&socks5.Config{
+		Rules:  proxyRecorder{p},
+		Logger: log.New(ioutil.Discard, "", 0),
+	})
+	if err != nil {
+		l.Close()
+		return nil, err
+	}
+	forward := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			r.Header.Add("Via", testProxyVia)
+		},
+		Transport: &http.Transport{},
+		ErrorLog:  log.New(ioutil.Discard, "", 0),
+	}
+	p.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodConnect:
+				p.record("connect", r.Host)
+				p.tunnel(w, r)
+			case r.URL.IsAbs():
+				p.record("http", r.URL.Host)
+				forward.ServeHTTP(w, r)
+			default:
+				http.Error(w, "not a proxy request", http.StatusBadRequest)
+			}
+		}),
+		ErrorLog: log.New(ioutil.Discard, "", 0),
+	}
+	go p.server.Serve(connListener{p})
+	go p.accept()
+	return p, nil
+}
+
+// accept dispatches the connections to the SOCKS5 server or to the HTTP
+// server according to their first byte.
+func (p *TestProxy) accept() {
+	for {
+		c, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			r := bufio.NewReader(c)
+			b, err := r.Peek(1)
+			if err != nil {
+				c.Close()
+				return
+			}
+			c = peekedConn{c, r}
+			if b[0] == 5 {
+				p.socks.ServeConn(c)
+				return
+			}
+			select {
+			case p.conns <- c:
+			case <-p.done:
+				c.Close()
+			}
+		}()
+	}
+}
+
+// tunnel serves a CONNECT request.
+func (p *TestProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	dst, err := net.Dial("tcp", r.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		dst.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	src, buf, err := hj.Hijack()
+	if err != nil {
+		dst.Close()
+		return
+	}
+	io.WriteString(src, "HTTP/1.1 200 Connection established\r\n\r\n")
+	go func() {
+		io.Copy(dst, buf)
+		dst.Close()
+	}()
+	io.Copy(src, dst)
+	src.Close()
+}
+
+func (p *TestProxy) record(protocol, target string) {
+	host, _, err := net.SplitHostPort(target)
+	if err != nil {
+		host = target
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests = append(p.requests, ProxyRequest{
+		Protocol: protocol,
+		Target:   target,
+		HostName: net.ParseIP(host) == nil,
+	})
+}
+
+// Requests returns the requests received by the proxy, in order.
+func (p *TestProxy) Requests() []ProxyRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ProxyRequest(nil), p.requests...)
+}
+
+// HTTPSpec returns the ProxySpec that sends the http and https traffic of a
+// browser, including to loopback addresses, through p.
+func (p *TestProxy) HTTPSpec() ProxySpec {
+	return ProxySpec{HTTP: p.Addr, HTTPS: p.Addr, ProxyLoopback: true}
+}
+
+// SOCKSSpec returns the ProxySpec that sends the traffic of a browser,
+// including to loopback addresses, through p as a SOCKS5 proxy.
+func (p *TestProxy) SOCKSSpec(remoteDNS bool) ProxySpec {
+	return ProxySpec{SOCKS: p.Addr, SOCKSVersion: 5, SOCKSRemoteDNS: remoteDNS, ProxyLoopback: true}
+}
+
+// Close stops the proxy. The tunnels already open are not closed.
+func (p *TestProxy) Close() error {
+	err := p.listener.Close()
+	close(p.done)
+	p.server.Close()
+	return err
+}
+
+// proxyRecorder records the SOCKS5 requests of a TestProxy, and allows them.
+type proxyRecorder struct {
+	p *TestProxy
+}
+
+func (r proxyRecorder) Allow(ctx context.Context, req *socks5.Request) (context.Context, bool) {
+	host := req.DestAddr.FQDN
+	if host == "" {
+		host = req.DestAddr.IP.String()
+	}
+	r.p.record("socks5", net.JoinHostPort(host, strconv.Itoa(req.DestAddr.Port)))
+	return ctx, true
+}
+
+// peekedConn is a connection whose first bytes were read into r.
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c peekedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// connListener passes the HTTP connections of a TestProxy to its HTTP
+// server.
+type connListener struct {
+	p *TestProxy
+}
+
+func (l connListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.p.conns:
+		return c, nil
+	case <-l.p.done:
+		return nil, errors.New("test proxy closed")
+	}
+}
+
+func (l connListener) Close() error   { return nil }
+func (l connListener) Addr() net.Addr { return l.p.listener.Addr() }
+
+// ProxyExpectation is what VerifyProxy expects of the browser.
+type ProxyExpectation struct {
+	// Proxy is the proxy that the request must go through, usually a
+	// TestProxy whose HTTPSpec or SOCKSSpec configures the session. If nil,
+	// the browser must reach the test endpoint directly.
+	Proxy *TestProxy
+	// Protocol, if set, is the ProxyRequest.Protocol that the request must
+	// use, e.g. "socks5".
+	Protocol string
+	// RemoteDNS requires the browser to send the host name of the test
+	// endpoint, "localhost", to the proxy instead of resolving it.
+	RemoteDNS bool
+}
+
+// VerifyProxy checks the proxy settings of the session: it serves a test
+// endpoint on the loopback interface, navigates wd to it and checks that the
+// request went through the expected proxy, or directly. Browsers bypass
+// proxies for loopback addresses unless told otherwise, e.g. with
+// ProxySpec.ProxyLoopback. The endpoint is served over http, so the http
+// proxy settings are the ones checked. The errors about the proxy match
+// ErrProxyMismatch. The browser is left on the test endpoint, which is
+// stopped.
+func VerifyProxy(wd WebDriver, expect ProxyExpectation) error {
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return err
+	}
+	path := "/proxy-check-" + hex.EncodeToString(token)
+
+	var mu sync.Mutex
+	var reached bool
+	var via string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		reached, via = true, r.Header.Get("Via")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<title>proxy check</title>")
+	}))
+	defer endpoint.Close()
+
+	_, port, err := net.SplitHostPort(endpoint.Listener.Addr().String())
+	if err != nil {
+		return err
+	}
+	host := "127.0.0.1"
+	if expect.RemoteDNS {
+		host = "localhost"
+	}
+	target := net.JoinHostPort(host, port)
+	var before int
+	if expect.Proxy != nil {
+		before = len(expect.Proxy.Requests())
+	}
+	if err := wd.Get("http://" + target + path); err != nil {
+		return fmt.Errorf("navigating to the proxy check endpoint: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reached {
+		return fmt.Errorf("the browser did not reach the proxy check endpoint at %s", target)
+	}
+	if expect.Proxy == nil {
+		if via != "" {
+			return fmt.Errorf("%w: the request went through a proxy (Via: %s), want a direct connection", ErrProxyMismatch, via)
+		}
+		return nil
+	}
+	var found []ProxyRequest
+	for _, r := range expect.Proxy.Requests()[before:] {
+		if _, p, err := net.SplitHostPort(r.Target); err == nil && p == port {
+			found = append(found, r)
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("%w: no request for %s went through the proxy at %s", ErrProxyMismatch, target, expect.Proxy.Addr)
+	}
+	r := found[len(found)-1]
+	if expect.Protocol != "" && r.Protocol != expect.Protocol {
+		return fmt.Errorf("%w: the request for %s used the %s protocol, want %s", ErrProxyMismatch, target, r.Protocol, expect.Protocol)
+	}
+	if expect.RemoteDNS && !r.HostName {
+		return fmt.Errorf("%w: the browser resolved %s itself and sent %s to the proxy", ErrProxyMismatch, host, r.Target)
+	}
+	return nil
+}
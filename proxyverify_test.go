@@ -0,0 +1,131 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"golang.org/x/net/proxy"
+)
+
+func TestTestProxy(t *testing.T) {
+	p, err := NewTestProxy()
+	if err != nil {
+		t.Fatalf("NewTestProxy() returned error: %v", err)
+	}
+	defer p.Close()
+
+	var via string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		via = r.Header.Get("Via")
+		w.Write([]byte("ok"))
+	}))
+	defer s.Close()
+
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: p.Addr})}}
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		t.Fatalf("GET %s through the proxy returned error: %v", s.URL, err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" || via != testProxyVia {
+		t.Errorf("GET %s through the proxy = %q with Via %q, want %q with Via %q", s.URL, body, via, "ok", testProxyVia)
+	}
+
+	dialer, err := proxy.SOCKS5("tcp", p.Addr, nil, proxy.Direct)
+	if err != nil {
+		t.Fatalf("proxy.SOCKS5() returned error: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(s.Listener.Addr().String())
+	target := net.JoinHostPort("localhost", port)
+	conn, err := dialer.Dial("tcp", target)
+	if err != nil {
+		t.Fatalf("dialing %s through the proxy returned error: %v", target, err)
+	}
+	conn.Close()
+
+	want := []ProxyRequest{
+		{Protocol: "http", Target: s.Listener.Addr().String()},
+		{Protocol: "socks5", Target: target, HostName: true},
+	}
+	if got := p.Requests(); len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("p.Requests() = %+v, want %+v", got, want)
+	}
+}
+
+func TestVerifyProxy(t *testing.T) {
+	p, err := NewTestProxy()
+	if err != nil {
+		t.Fatalf("NewTestProxy() returned error: %v", err)
+	}
+	defer p.Close()
+
+	// The fake browser loads the pages through the proxy set by the test.
+	var transport *http.Transport
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func(body []byte) (int, interface{}) {
+		var params struct{ URL string }
+		if err := json.Unmarshal(body, &params); err != nil {
+			return http.StatusBadRequest, nil
+		}
+		resp, err := (&http.Client{Transport: transport}).Get(params.URL)
+		if err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "unknown error", "message": err.Error()}
+		}
+		resp.Body.Close()
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	socks := func(dialHost string) *http.Transport {
+		dialer, err := proxy.SOCKS5("tcp", p.Addr, nil, proxy.Direct)
+		if err != nil {
+			t.Fatalf("proxy.SOCKS5() returned error: %v", err)
+		}
+		return &http.Transport{Dial: func(network, addr string) (net.Conn, error) {
+			if dialHost != "" {
+				_, port, _ := net.SplitHostPort(addr)
+				addr = net.JoinHostPort(dialHost, port)
+			}
+			return dialer.Dial(network, addr)
+		}}
+	}
+	viaHTTP := &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: p.Addr})}
+	direct := &http.Transport{Proxy: nil}
+
+	for _, test := range []struct {
+		name      string
+		transport *http.Transport
+		expect    ProxyExpectation
+		mismatch  bool
+	}{
+		{"http", viaHTTP, ProxyExpectation{Proxy: p, Protocol: "http"}, false},
+		{"direct", direct, ProxyExpectation{}, false},
+		{"socks remote DNS", socks(""), ProxyExpectation{Proxy: p, Protocol: "socks5", RemoteDNS: true}, false},
+		{"bypassed", direct, ProxyExpectation{Proxy: p}, true},
+		{"wrong protocol", viaHTTP, ProxyExpectation{Proxy: p, Protocol: "socks5"}, true},
+		{"local DNS", socks("127.0.0.1"), ProxyExpectation{Proxy: p, RemoteDNS: true}, true},
+		{"unexpected proxy", viaHTTP, ProxyExpectation{}, true},
+	} {
+		transport = test.transport
+		err := VerifyProxy(wd, test.expect)
+		if test.mismatch {
+			if !errors.Is(err, ErrProxyMismatch) {
+				t.Errorf("%s: VerifyProxy() returned error %v, want ErrProxyMismatch", test.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: VerifyProxy() returned error: %v", test.name, err)
+		}
+	}
+
+	transport = &http.Transport{Proxy: http.ProxyURL(&url.URL{Scheme: "http", Host: "127.0.0.1:1"})}
+	if err := VerifyProxy(wd, ProxyExpectation{Proxy: p}); err == nil || errors.Is(err, ErrProxyMismatch) {
+		t.Errorf("VerifyProxy() with an unreachable endpoint returned error %v, want a navigation error", err)
+	}
+}
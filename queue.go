@@ -0,0 +1,202 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ErrNoCapacity is matched by the *QueueError returned when a Selenium Grid
+// rejects the New Session request because it has no free slot and cannot
+// queue the request.
+var ErrNoCapacity = errors.New("no free slot for a new session")
+
+// ErrQueueTimeout is matched by the *QueueError returned when the New
+// Session request waited for a free slot longer than the timeout of the grid
+// or the one set with WithSessionRequestTimeout.
+var ErrQueueTimeout = errors.New("timed out waiting for a free slot")
+
+// QueueError is returned when a session could not be created for lack of a
+// free slot on the remote end.
+type QueueError struct {
+	// Reason is ErrNoCapacity or ErrQueueTimeout.
+	Reason error
+	// Attempts is the number of New Session requests sent.
+	Attempts int
+	// Elapsed is the time spent creating the session.
+	Elapsed time.Duration
+	// Err is the last error returned by the remote end, or the error of the
+	// context if the timeout set with WithSessionRequestTimeout expired.
+	Err error
+}
+
+func (e *QueueError) Error() string {
+	return fmt.Sprintf("%v after %d attempts in %v: %v", e.Reason, e.Attempts, e.Elapsed.Round(time.Millisecond), e.Err)
+}
+
+// Is makes errors.Is(err, e.Reason) true.
+func (e *QueueError) Is(target error) bool {
+	return target == e.Reason
+}
+
+func (e *QueueError) Unwrap() error {
+	return e.Err
+}
+
+// queueCallbackInterval is the interval between the calls to the function set
+// with WithQueueCallback. It is a variable for tests.
+var queueCallbackInterval = time.Second
+
+// sessionRetryBackoff is the delay before the first retry of a New Session
+// request rejected with ErrNoCapacity, unless the remote end sets another one
+// with a Retry-After header. It doubles after each retry, up to
+// maxSessionRetryBackoff. It is a variable for tests.
+var sessionRetryBackoff = time.Second
+
+const maxSessionRetryBackoff = 30 * time.Second
+
+// WithSessionRequestTimeout bounds the time that NewRemoteContext waits for
+// the session to be created, including the time spent in the queue of a
+// Selenium Grid and between retries, independently of HTTPClient's timeout.
+// The grid's own session request timeout is a setting of the grid, which
+// cannot be changed per request, so this timeout is enforced by the client;
+// either one expiring returns an error matching ErrQueueTimeout.
+func WithSessionRequestTimeout(d time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.sessionRequestTimeout = d
+	}
+}
+
+// WithQueueCallback calls f every second, from another goroutine, while the
+// New Session request is outstanding, e.g. to report that the test waits for
+// a slot on a saturated grid. elapsed is the time since the first request was
+// sent.
+func WithQueueCallback(f func(elapsed time.Duration)) SessionOption {
+	return func(o *sessionOptions) {
+		o.queueCallback = f
+	}
+}
+
+// WithNewSessionRetries makes NewRemoteContext send the New Session request
+// up to maxAttempts times while the remote end rejects it with ErrNoCapacity,
+// waiting between attempts for the delay requested by the remote end with a
+// Retry-After header or else for an exponential backoff starting at one
+// second.
+func WithNewSessionRetries(maxAttempts int) SessionOption {
+	return func(o *sessionOptions) {
+		o.sessionAttempts = maxAttempts
+	}
+}
+
+// queueErrorReason returns ErrNoCapacity or ErrQueueTimeout if err tells that
+// the remote end has no slot for a new session, or nil.
+func queueErrorReason(err error) error {
+	var e *Error
+	if !errors.As(err, &e) {
+		return nil
+	}
+	msg := strings.ToLower(e.Message)
+	switch {
+	case strings.Contains(msg, "timed out") && (strings.Contains(msg, "session request") || strings.Contains(msg, "queue")):
+		return ErrQueueTimeout
+	case strings.Contains(msg, "queue is full"), strings.Contains(msg, "queue full"),
+		strings.Contains(msg, "slot") && strings.Contains(msg, "could not start a new session"):
+		return ErrNoCapacity
+	case e.HTTPCode == http.StatusServiceUnavailable && errors.Is(e, werror.SessionNotCreated):
+		return ErrNoCapacity
+	}
+	return nil
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, given in
+// seconds or as an HTTP date, or zero.
+func parseRetryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// postNewSession sends the New Session request with postStreaming, enforcing
+// the queue options.
+func (wd *remoteWD) postNewSession(ctx context.Context, url string, params interface{}, o sessionOptions) (json.RawMessage, error) {
+	start := time.Now()
+	parent := ctx
+	if o.sessionRequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.sessionRequestTimeout)
+		defer cancel()
+	}
+	if o.queueCallback != nil {
+		done := make(chan struct{})
+		defer close(done)
+		go func() {
+			ticker := time.NewTicker(queueCallbackInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					o.queueCallback(time.Since(start))
+				}
+			}
+		}()
+	}
+
+	backoff := sessionRetryBackoff
+	for attempt := 1; ; attempt++ {
+		response, err := wd.postStreaming(ctx, url, params, o.progress)
+		if err == nil {
+			return response, nil
+		}
+		queueError := func(reason, err error) error {
+			return &QueueError{Reason: reason, Attempts: attempt, Elapsed: time.Since(start), Err: err}
+		}
+		if ctx.Err() != nil && parent.Err() == nil {
+			return nil, queueError(ErrQueueTimeout, err)
+		}
+		reason := queueErrorReason(err)
+		if reason == nil {
+			return nil, err
+		}
+		if reason != ErrNoCapacity || attempt >= o.sessionAttempts {
+			return nil, queueError(reason, err)
+		}
+
+		delay := backoff
+		var e *Error
+		if errors.As(err, &e) && e.RetryAfter > 0 {
+			delay = e.RetryAfter
+		}
+		if backoff *= 2; backoff > maxSessionRetryBackoff {
+			backoff = maxSessionRetryBackoff
+		}
+		debugLog("no free slot for a new session, retrying in %v\n", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			if parent.Err() != nil {
+				return nil, parent.Err()
+			}
+			return nil, queueError(ErrQueueTimeout, ctx.Err())
+		}
+	}
+}
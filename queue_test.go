@@ -0,0 +1,132 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func noSlotReply() (int, interface{}) {
+	return http.StatusInternalServerError, map[string]string{
+		"error":   "session not created",
+		"message": "Could not start a new session. No free slot available",
+	}
+}
+
+func TestNewSessionRetriesWithoutCapacity(t *testing.T) {
+	defer func(b time.Duration) { sessionRetryBackoff = b }(sessionRetryBackoff)
+	sessionRetryBackoff = time.Millisecond
+
+	d := newFakeDriver(t, nil)
+	attempts := 0
+	d.handlers["POST /session"] = func([]byte) (int, interface{}) {
+		attempts++
+		if attempts < 3 {
+			return noSlotReply()
+		}
+		return http.StatusOK, map[string]interface{}{
+			"sessionId":    fakeSessionID,
+			"capabilities": map[string]interface{}{},
+		}
+	}
+
+	if _, err := NewRemoteContext(context.Background(), nil, d.URL, WithNewSessionRetries(3)); err != nil {
+		t.Fatalf("NewRemoteContext(WithNewSessionRetries(3)) returned error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("NewRemoteContext(WithNewSessionRetries(3)) sent %d requests, want 3", attempts)
+	}
+
+	attempts = 0
+	_, err := NewRemoteContext(context.Background(), nil, d.URL, WithNewSessionRetries(2))
+	var qerr *QueueError
+	if !errors.As(err, &qerr) || !errors.Is(err, ErrNoCapacity) {
+		t.Fatalf("NewRemoteContext(WithNewSessionRetries(2)) returned error %v, want a *QueueError matching ErrNoCapacity", err)
+	}
+	if qerr.Attempts != 2 || attempts != 2 {
+		t.Errorf("NewRemoteContext(WithNewSessionRetries(2)) made %d attempts (%d reported), want 2", attempts, qerr.Attempts)
+	}
+
+	// Retries are opt-in.
+	attempts = 0
+	if _, err := NewRemote(nil, d.URL); !errors.Is(err, ErrNoCapacity) || attempts != 1 {
+		t.Errorf("NewRemote() returned error %v after %d requests, want ErrNoCapacity after 1", err, attempts)
+	}
+}
+
+func TestNewSessionGridQueueTimeout(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	attempts := 0
+	d.handlers["POST /session"] = func([]byte) (int, interface{}) {
+		attempts++
+		return http.StatusInternalServerError, map[string]string{
+			"error":   "session not created",
+			"message": "Could not start a new session. New session request timed out",
+		}
+	}
+	_, err := NewRemoteContext(context.Background(), nil, d.URL, WithNewSessionRetries(3))
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("NewRemoteContext() returned error %v, want ErrQueueTimeout", err)
+	}
+	// The grid already queued the request: it is not retried.
+	if attempts != 1 {
+		t.Errorf("NewRemoteContext() sent %d requests, want 1", attempts)
+	}
+}
+
+func TestNewSessionRequestTimeout(t *testing.T) {
+	defer func(i time.Duration) { queueCallbackInterval = i }(queueCallbackInterval)
+	queueCallbackInterval = 10 * time.Millisecond
+
+	d := newFakeDriver(t, nil)
+	release := make(chan struct{})
+	defer close(release)
+	d.handlers["POST /session"] = func([]byte) (int, interface{}) {
+		<-release
+		return http.StatusOK, nil
+	}
+
+	var mu sync.Mutex
+	var calls []time.Duration
+	callback := func(elapsed time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, elapsed)
+	}
+	_, err := NewRemoteContext(context.Background(), nil, d.URL, WithSessionRequestTimeout(100*time.Millisecond), WithQueueCallback(callback))
+	if !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("NewRemoteContext() returned error %v, want ErrQueueTimeout", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) < 2 {
+		t.Fatalf("the queue callback was called %d times, want it called while the request is outstanding", len(calls))
+	}
+	if calls[len(calls)-1] <= calls[0] {
+		t.Errorf("the queue callback reported elapsed times %v, want them increasing", calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	for _, test := range []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"soon", 0},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", 0},
+	} {
+		if got := parseRetryAfter(test.header); got != test.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", test.header, got, test.want)
+		}
+	}
+	future := time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)
+	if got := parseRetryAfter(future); got <= 50*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(%q) = %v, want about a minute", future, got)
+	}
+}
@@ -0,0 +1,375 @@
+package selenium
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/drivermanager"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// QuickOption overrides a decision made by QuickChrome and QuickFirefox.
+type QuickOption func(*quickOptions)
+
+type quickOptions struct {
+	headful     bool
+	container   *bool
+	driverPath  string
+	browserPath string
+	port        int
+	caps        Capabilities
+	sessionOpts []SessionOption
+	verbose     io.Writer
+}
+
+// WithHeadful shows the browser window instead of running the browser
+// headless. On Linux, a virtual frame buffer is started if DISPLAY is not
+// set.
+func WithHeadful() QuickOption {
+	return func(o *quickOptions) {
+		o.headful = true
+	}
+}
+
+// WithContainerArgs forces the use of the arguments that let Chrome run in a
+// container, see chrome.Capabilities.ForContainers, instead of detecting
+// whether the library runs in a container.
+func WithContainerArgs(enabled bool) QuickOption {
+	return func(o *quickOptions) {
+		o.container = &enabled
+	}
+}
+
+// WithDriverPath sets the path to the driver binary, instead of looking for
+// it with chrome.FindDriver or firefox.FindDriver, or downloading it with
+// drivermanager.Install.
+func WithDriverPath(path string) QuickOption {
+	return func(o *quickOptions) {
+		o.driverPath = path
+	}
+}
+
+// WithBrowserPath sets the path to the browser binary, instead of letting the
+// driver find it.
+func WithBrowserPath(path string) QuickOption {
+	return func(o *quickOptions) {
+		o.browserPath = path
+	}
+}
+
+// WithPort sets the port of the driver, instead of picking a free one.
+func WithPort(port int) QuickOption {
+	return func(o *quickOptions) {
+		o.port = port
+	}
+}
+
+// WithExtraCapabilities sets capabilities of the session, replacing those set
+// by QuickChrome or QuickFirefox under the same keys, including the browser
+// options.
+func WithExtraCapabilities(caps Capabilities) QuickOption {
+	return func(o *quickOptions) {
+		if o.caps == nil {
+			o.caps = make(Capabilities)
+		}
+		for k, v := range caps {
+			o.caps[k] = v
+		}
+	}
+}
+
+// WithSessionOptions passes options to NewRemoteContext when creating the
+// session.
+func WithSessionOptions(opts ...SessionOption) QuickOption {
+	return func(o *quickOptions) {
+		o.sessionOpts = append(o.sessionOpts, opts...)
+	}
+}
+
+// WithVerbose reports every decision made by QuickChrome and QuickFirefox,
+// and the output of the driver, to w.
+func WithVerbose(w io.Writer) QuickOption {
+	return func(o *quickOptions) {
+		o.verbose = w
+	}
+}
+
+func (o *quickOptions) logf(format string, args ...interface{}) {
+	if o.verbose != nil {
+		fmt.Fprintf(o.verbose, "selenium: "+format+"\n", args...)
+	}
+}
+
+// cgroupPath is the file read by inContainer; it is a variable for tests.
+var cgroupPath = "/proc/1/cgroup"
+
+// installDriver downloads the drivers that are not found locally; it is a
+// variable for tests.
+var installDriver = drivermanager.Install
+
+// inContainer reports whether the library runs in a Docker or Kubernetes
+// container, and why.
+func inContainer() (bool, string) {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return true, "/.dockerenv exists"
+	}
+	data, err := ioutil.ReadFile(cgroupPath)
+	if err != nil {
+		return false, "no container detected"
+	}
+	for _, marker := range []string{"docker", "kubepods", "containerd", "lxc"} {
+		if strings.Contains(string(data), marker) {
+			return true, fmt.Sprintf("%s mentions %s", cgroupPath, marker)
+		}
+	}
+	return false, "no container detected"
+}
+
+// freePort returns a TCP port that is free on the loopback interface.
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// quickOptionsOf applies opts.
+func quickOptionsOf(opts []QuickOption) *quickOptions {
+	o := new(quickOptions)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// chromeCapabilities returns the capabilities of QuickChrome.
+func (o *quickOptions) chromeCapabilities() Capabilities {
+	c := chrome.Capabilities{W3C: true}
+	if o.browserPath != "" {
+		c.Path = o.browserPath
+		o.logf("using Chrome at %s", c.Path)
+	}
+	if o.headful {
+		o.logf("running Chrome with a window (WithHeadful)")
+	} else {
+		c.Args = append(c.Args, "--headless=new")
+		o.logf("running Chrome headless; use WithHeadful to show the window")
+	}
+	container, why := inContainer()
+	if o.container != nil {
+		container, why = *o.container, "set by WithContainerArgs"
+	}
+	if container {
+		c.ForContainers()
+		o.logf("adding the container arguments of chrome.Capabilities.ForContainers (%s)", why)
+	} else {
+		o.logf("not adding the container arguments (%s)", why)
+	}
+	caps := Capabilities{"browserName": "chrome"}
+	caps.AddChrome(c)
+	return o.withExtraCapabilities(caps)
+}
+
+// firefoxCapabilities returns the capabilities of QuickFirefox.
+func (o *quickOptions) firefoxCapabilities() Capabilities {
+	var f firefox.Capabilities
+	if o.browserPath != "" {
+		f.Binary = o.browserPath
+		o.logf("using Firefox at %s", f.Binary)
+	}
+	if o.headful {
+		o.logf("running Firefox with a window (WithHeadful)")
+	} else {
+		f.Args = append(f.Args, "-headless")
+		o.logf("running Firefox headless; use WithHeadful to show the window")
+	}
+	caps := Capabilities{"browserName": "firefox"}
+	caps.AddFirefox(f)
+	return o.withExtraCapabilities(caps)
+}
+
+func (o *quickOptions) withExtraCapabilities(caps Capabilities) Capabilities {
+	for k, v := range o.caps {
+		caps[k] = v
+		o.logf("capability %q set by WithExtraCapabilities", k)
+	}
+	return caps
+}
+
+// serviceOptions returns the options of the driver service.
+func (o *quickOptions) serviceOptions() []ServiceOption {
+	var opts []ServiceOption
+	if o.verbose != nil {
+		opts = append(opts, Output(o.verbose))
+	}
+	if o.headful && runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" {
+		opts = append(opts, StartFrameBuffer())
+		o.logf("starting a virtual frame buffer, since DISPLAY is not set")
+	}
+	return opts
+}
+
+// quickService starts the driver with start.
+func (o *quickOptions) quickService(start func(port int, opts ...ServiceOption) (*Service, error)) (*Service, error) {
+	port := o.port
+	if port == 0 {
+		var err error
+		if port, err = freePort(); err != nil {
+			return nil, err
+		}
+		o.logf("using free port %d; use WithPort to choose it", port)
+	}
+	return start(port, o.serviceOptions()...)
+}
+
+// quickStart starts the driver with start and creates a session on it.
+func (o *quickOptions) quickStart(caps Capabilities, start func(port int, opts ...ServiceOption) (*Service, error)) (WebDriver, func(), error) {
+	s, err := o.quickService(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	wd, err := NewRemoteContext(context.Background(), caps, s.addr, append([]SessionOption{WithService(s)}, o.sessionOpts...)...)
+	if err != nil {
+		s.Stop()
+		return nil, nil, err
+	}
+	cleanup := func() {
+		if err := wd.Quit(); err != nil {
+			o.logf("error quitting the session: %v", err)
+		}
+		if err := s.Stop(); err != nil {
+			o.logf("error stopping the driver: %v", err)
+		}
+	}
+	return wd, cleanup, nil
+}
+
+// QuickChrome starts ChromeDriver and creates a Chrome session on it, with
+// sensible defaults that QuickOptions override:
+//
+//   - ChromeDriver is found with chrome.FindDriver, for the version of the
+//     Chrome binary if it is known, or downloaded with drivermanager.Install
+//     if none is found (WithDriverPath, WithBrowserPath);
+//   - it listens on a free port (WithPort);
+//   - Chrome runs headless (WithHeadful);
+//   - the arguments of chrome.Capabilities.ForContainers are added when the
+//     library runs in a Docker or Kubernetes container (WithContainerArgs).
+//
+// cleanup quits the session and stops ChromeDriver. WithVerbose reports each
+// decision.
+func QuickChrome(opts ...QuickOption) (wd WebDriver, cleanup func(), err error) {
+	o := quickOptionsOf(opts)
+	start, err := o.chromeStart()
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.quickStart(o.chromeCapabilities(), start)
+}
+
+// QuickChromeService starts ChromeDriver like QuickChrome, without creating
+// a session, e.g. to create several sessions on the same driver. It returns
+// the service, whose URLPrefix is the address of the driver, and the
+// capabilities that QuickChrome requests. The options passed by
+// WithSessionOptions are ignored.
+func QuickChromeService(opts ...QuickOption) (*Service, Capabilities, error) {
+	o := quickOptionsOf(opts)
+	start, err := o.chromeStart()
+	if err != nil {
+		return nil, nil, err
+	}
+	caps := o.chromeCapabilities()
+	s, err := o.quickService(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, caps, nil
+}
+
+// chromeStart finds ChromeDriver and returns the function that starts it.
+func (o *quickOptions) chromeStart() (func(port int, opts ...ServiceOption) (*Service, error), error) {
+	driver := o.driverPath
+	if driver == "" {
+		var version string
+		var err error
+		browser := o.browserPath
+		if browser == "" {
+			if installs := chrome.FindInstallations(); len(installs) > 0 {
+				browser = installs[0].Path
+			}
+		}
+		if browser != "" {
+			if version, err = chrome.BinaryVersion(browser); err != nil {
+				o.logf("%v; looking for any ChromeDriver", err)
+			}
+		}
+		if driver, err = chrome.FindDriver(version); err != nil {
+			o.logf("%v; downloading ChromeDriver", err)
+			d, err := installDriver(context.Background(), drivermanager.Chrome, &drivermanager.Options{BrowserPath: browser, BrowserVersion: version, Verbose: o.verbose})
+			if err != nil {
+				return nil, err
+			}
+			driver, version = d.Path, d.BrowserVersion
+		}
+		o.logf("using ChromeDriver at %s for Chrome %q; use WithDriverPath to choose it", driver, version)
+	}
+	return func(port int, opts ...ServiceOption) (*Service, error) {
+		return NewChromeDriverService(driver, port, opts...)
+	}, nil
+}
+
+// QuickFirefox is like QuickChrome, but starts GeckoDriver, found with
+// firefox.FindDriver or downloaded with drivermanager.Install, and creates a Firefox session on it. The container
+// arguments do not apply to Firefox.
+func QuickFirefox(opts ...QuickOption) (wd WebDriver, cleanup func(), err error) {
+	o := quickOptionsOf(opts)
+	start, err := o.firefoxStart()
+	if err != nil {
+		return nil, nil, err
+	}
+	return o.quickStart(o.firefoxCapabilities(), start)
+}
+
+// QuickFirefoxService is like QuickChromeService, but starts GeckoDriver like
+// QuickFirefox.
+func QuickFirefoxService(opts ...QuickOption) (*Service, Capabilities, error) {
+	o := quickOptionsOf(opts)
+	start, err := o.firefoxStart()
+	if err != nil {
+		return nil, nil, err
+	}
+	caps := o.firefoxCapabilities()
+	s, err := o.quickService(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, caps, nil
+}
+
+// firefoxStart finds GeckoDriver and returns the function that starts it.
+func (o *quickOptions) firefoxStart() (func(port int, opts ...ServiceOption) (*Service, error), error) {
+	driver := o.driverPath
+	if driver == "" {
+		var err error
+		if driver, err = firefox.FindDriver(); err != nil {
+			o.logf("%v; downloading GeckoDriver", err)
+			d, err := installDriver(context.Background(), drivermanager.Firefox, &drivermanager.Options{BrowserPath: o.browserPath, Verbose: o.verbose})
+			if err != nil {
+				return nil, err
+			}
+			driver = d.Path
+		}
+		o.logf("using GeckoDriver at %s; use WithDriverPath to choose it", driver)
+	}
+	return func(port int, opts ...ServiceOption) (*Service, error) {
+		return NewGeckoDriverService(driver, port, opts...)
+	}, nil
+}
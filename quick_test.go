@@ -0,0 +1,110 @@
+package selenium
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/drivermanager"
+)
+
+func TestQuickChromeCapabilities(t *testing.T) {
+	chromeArgs := func(caps Capabilities) []string {
+		return caps[chrome.CapabilitiesKey].(chrome.Capabilities).Args
+	}
+
+	var verbose bytes.Buffer
+	o := quickOptionsOf([]QuickOption{WithContainerArgs(true), WithVerbose(&verbose)})
+	caps := o.chromeCapabilities()
+	if want := []string{"--headless=new", "--no-sandbox", "--disable-dev-shm-usage"}; !reflect.DeepEqual(chromeArgs(caps), want) {
+		t.Errorf("chromeCapabilities() set args %q, want %q", chromeArgs(caps), want)
+	}
+	for _, want := range []string{"headless", "WithContainerArgs"} {
+		if !strings.Contains(verbose.String(), want) {
+			t.Errorf("the verbose output %q does not mention %s", verbose.String(), want)
+		}
+	}
+
+	o = quickOptionsOf([]QuickOption{WithHeadful(), WithContainerArgs(false), WithExtraCapabilities(Capabilities{"acceptInsecureCerts": true})})
+	caps = o.chromeCapabilities()
+	if args := chromeArgs(caps); len(args) != 0 {
+		t.Errorf("chromeCapabilities() with WithHeadful set args %q, want none", args)
+	}
+	if caps["acceptInsecureCerts"] != true {
+		t.Errorf("chromeCapabilities() = %v, want the extra capabilities", caps)
+	}
+}
+
+func TestInContainer(t *testing.T) {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		t.Skip("/.dockerenv exists")
+	}
+	dir, err := ioutil.TempDir("", "quick-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(p string) { cgroupPath = p }(cgroupPath)
+	cgroupPath = filepath.Join(dir, "cgroup")
+
+	for content, want := range map[string]bool{
+		"12:pids:/docker/8a3f0c\n":          true,
+		"0::/kubepods/besteffort/pod1234\n": true,
+		"0::/user.slice/user-1000.slice\n":  false,
+	} {
+		if err := ioutil.WriteFile(cgroupPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, why := inContainer(); got != want {
+			t.Errorf("inContainer() with cgroup %q = %t (%s), want %t", content, got, why, want)
+		}
+	}
+}
+
+func TestQuickDriverDownload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "quick-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for k, v := range map[string]string{"PATH": dir, "HOME": dir, "PUPPETEER_CACHE_DIR": "", "SE_CACHE_PATH": ""} {
+		defer os.Setenv(k, os.Getenv(k))
+		os.Setenv(k, v)
+	}
+	var installed []drivermanager.Browser
+	saved := installDriver
+	defer func() { installDriver = saved }()
+	installDriver = func(_ context.Context, browser drivermanager.Browser, opts *drivermanager.Options) (*drivermanager.Driver, error) {
+		installed = append(installed, browser)
+		if opts.BrowserPath != "/opt/browser" {
+			t.Errorf("the %s driver was installed for the browser %q, want /opt/browser", browser, opts.BrowserPath)
+		}
+		return &drivermanager.Driver{Browser: browser, Path: filepath.Join(dir, string(browser)+"-driver")}, nil
+	}
+
+	o := quickOptionsOf([]QuickOption{WithBrowserPath("/opt/browser")})
+	if _, err := o.chromeStart(); err != nil {
+		t.Fatalf("chromeStart() returned error: %v", err)
+	}
+	if _, err := o.firefoxStart(); err != nil {
+		t.Fatalf("firefoxStart() returned error: %v", err)
+	}
+	if want := []drivermanager.Browser{drivermanager.Chrome, drivermanager.Firefox}; !reflect.DeepEqual(installed, want) {
+		t.Errorf("the drivers of %q were installed, want %q", installed, want)
+	}
+
+	installed = nil
+	o = quickOptionsOf([]QuickOption{WithDriverPath(filepath.Join(dir, "chromedriver"))})
+	if _, err := o.chromeStart(); err != nil {
+		t.Fatalf("chromeStart() with WithDriverPath returned error: %v", err)
+	}
+	if len(installed) != 0 {
+		t.Errorf("chromeStart() with WithDriverPath installed the drivers of %q", installed)
+	}
+}
@@ -0,0 +1,229 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ByRelative is the method of the locators built with RelativeBy. Its values
+// are the encoded form of a RelativeLocator, produced by
+// RelativeLocator.ByValue and RelativeLocator.Locator.
+const ByRelative = "relative"
+
+// defaultNearDistance is the distance, in CSS pixels, of RelativeLocator.Near.
+const defaultNearDistance = 50
+
+// RelativeLocator finds elements by their position relative to other
+// elements, like the relative locators of Selenium 4: the elements found with
+// a locator are filtered by their bounding boxes, then sorted by the distance
+// of their center to the center of the element of the first filter. Its
+// methods return a new locator, so that it can be built in one expression and
+// passed to FindElement and FindElements with ByValue:
+//
+//	input, err := wd.FindElement(selenium.RelativeBy(selenium.ByTagName, "input").Below(label).ByValue())
+//
+// The elements are found with a script, in the current frame; those that are
+// not rendered, and the elements of the filters, never match.
+type RelativeLocator struct {
+	root    Locator
+	filters []relativeFilter
+}
+
+// relativeFilter is a filter of a RelativeLocator, in its encoded form.
+type relativeFilter struct {
+	Kind     string `json:"kind"`
+	Element  string `json:"element"`
+	Distance int    `json:"distance,omitempty"`
+}
+
+// relativeValue is the encoded form of a RelativeLocator.
+type relativeValue struct {
+	By      string           `json:"by"`
+	Value   string           `json:"value"`
+	Filters []relativeFilter `json:"filters"`
+}
+
+// RelativeBy returns a RelativeLocator of the elements found with the method
+// by, one of the By constants, and value, before any filter.
+func RelativeBy(by, value string) RelativeLocator {
+	return RelativeLocator{root: Locator{By: by, Value: value}}
+}
+
+func (r RelativeLocator) with(kind string, elem WebElement, distance int) RelativeLocator {
+	filters := make([]relativeFilter, len(r.filters), len(r.filters)+1)
+	copy(filters, r.filters)
+	r.filters = append(filters, relativeFilter{Kind: kind, Element: elem.ID(), Distance: distance})
+	return r
+}
+
+// Above keeps the elements whose bottom is at or above the top of elem.
+func (r RelativeLocator) Above(elem WebElement) RelativeLocator {
+	return r.with("above", elem, 0)
+}
+
+// Below keeps the elements whose top is at or below the bottom of elem.
+func (r RelativeLocator) Below(elem WebElement) RelativeLocator {
+	return r.with("below", elem, 0)
+}
+
+// LeftOf keeps the elements whose right edge is at or left of the left edge
+// of elem.
+func (r RelativeLocator) LeftOf(elem WebElement) RelativeLocator {
+	return r.with("left", elem, 0)
+}
+
+// RightOf keeps the elements whose left edge is at or right of the right
+// edge of elem.
+func (r RelativeLocator) RightOf(elem WebElement) RelativeLocator {
+	return r.with("right", elem, 0)
+}
+
+// Near keeps the elements within 50 CSS pixels of elem.
+func (r RelativeLocator) Near(elem WebElement) RelativeLocator {
+	return r.with("near", elem, defaultNearDistance)
+}
+
+// NearWithin keeps the elements within distance CSS pixels of elem.
+func (r RelativeLocator) NearWithin(elem WebElement, distance int) RelativeLocator {
+	return r.with("near", elem, distance)
+}
+
+// ByValue returns the method and the value of the locator, in the order of
+// the arguments of FindElement and FindElements.
+func (r RelativeLocator) ByValue() (by, value string) {
+	l := r.Locator()
+	return l.By, l.Value
+}
+
+// Locator returns the locator, e.g. for Lazy.
+func (r RelativeLocator) Locator() Locator {
+	filters := r.filters
+	if filters == nil {
+		filters = []relativeFilter{}
+	}
+	v, _ := json.Marshal(relativeValue{By: r.root.By, Value: r.root.Value, Filters: filters})
+	return Locator{By: ByRelative, Value: string(v)}
+}
+
+// String returns the locator in human-readable form, for use in messages.
+func (r RelativeLocator) String() string {
+	parts := []string{r.root.String()}
+	for _, f := range r.filters {
+		switch f.Kind {
+		case "near":
+			parts = append(parts, fmt.Sprintf("within %dpx of element %s", f.Distance, f.Element))
+		case "left", "right":
+			parts = append(parts, fmt.Sprintf("%s of element %s", f.Kind, f.Element))
+		default:
+			parts = append(parts, fmt.Sprintf("%s element %s", f.Kind, f.Element))
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// findRelativeScript returns the elements of a relative locator, in the
+// scope of an element, of a shadow root or of the document: arguments[0] and
+// arguments[1] are its method and value, arguments[2] the scope and
+// arguments[3] the filters, with their elements.
+const findRelativeScript = `
+var by = arguments[0], value = arguments[1], scope = arguments[2] || document, filters = arguments[3];
+function find() {
+	switch (by) {
+	case 'css selector':
+		return scope.querySelectorAll(value);
+	case 'id':
+		return scope.querySelectorAll('#' + CSS.escape(value));
+	case 'name':
+		return scope.querySelectorAll('[name="' + CSS.escape(value) + '"]');
+	case 'class name':
+		// Shadow roots have querySelectorAll only.
+		return scope.getElementsByClassName ? scope.getElementsByClassName(value) : scope.querySelectorAll('.' + CSS.escape(value));
+	case 'tag name':
+		return scope.getElementsByTagName ? scope.getElementsByTagName(value) : scope.querySelectorAll(value);
+	case 'xpath':
+		var result = document.evaluate(value, scope, null, XPathResult.ORDERED_NODE_SNAPSHOT_TYPE, null), nodes = [];
+		for (var i = 0; i < result.snapshotLength; i++) {
+			nodes.push(result.snapshotItem(i));
+		}
+		return nodes;
+	case 'link text':
+	case 'partial link text':
+		return Array.prototype.filter.call(scope.querySelectorAll('a'), function(a) {
+			var text = a.innerText.trim();
+			return by === 'link text' ? text === value : text.indexOf(value) >= 0;
+		});
+	}
+	throw new Error('unsupported locator method for a relative locator: ' + by);
+}
+function rect(e) { return e.getBoundingClientRect(); }
+var elems = Array.prototype.filter.call(find(), function(e) {
+	if (e.nodeType !== Node.ELEMENT_NODE) return false;
+	var r = rect(e);
+	if (r.width === 0 && r.height === 0) return false;
+	return filters.every(function(f) {
+		if (e === f.element) return false;
+		var a = rect(f.element);
+		switch (f.kind) {
+		case 'above': return r.bottom <= a.top;
+		case 'below': return r.top >= a.bottom;
+		case 'left': return r.right <= a.left;
+		case 'right': return r.left >= a.right;
+		case 'near':
+			var dx = Math.max(a.left - r.right, r.left - a.right, 0);
+			var dy = Math.max(a.top - r.bottom, r.top - a.bottom, 0);
+			return Math.sqrt(dx * dx + dy * dy) <= f.distance;
+		}
+		return false;
+	});
+});
+if (filters.length > 0) {
+	var a = rect(filters[0].element), ax = a.left + a.width / 2, ay = a.top + a.height / 2;
+	var distance = function(e) {
+		var r = rect(e);
+		return Math.hypot(r.left + r.width / 2 - ax, r.top + r.height / 2 - ay);
+	};
+	elems.sort(function(x, y) { return distance(x) - distance(y); });
+}
+return elems;
+`
+
+// findRelative finds the elements of the encoded relative locator value, in
+// the scope of the element or shadow root of url, if any. It returns the
+// reply of a Find Element command, or of Find Elements if suffix is "s".
+func (wd *remoteWD) findRelative(value, suffix, url string) ([]byte, error) {
+	var v relativeValue
+	if err := json.Unmarshal([]byte(value), &v); err != nil {
+		return nil, &Error{Err: string(werror.InvalidArgument), Message: fmt.Sprintf("invalid relative locator %q: %v", value, err)}
+	}
+	var scope interface{}
+	if id := commandElement(url); id != "" {
+		scope = wd.ElementFromID(id)
+	} else if id := commandShadowRoot(url); id != "" {
+		scope = &remoteShadowRoot{parent: wd, id: id}
+	}
+	filters := make([]interface{}, len(v.Filters))
+	for i, f := range v.Filters {
+		filters[i] = map[string]interface{}{
+			"kind":     f.Kind,
+			"element":  wd.ElementFromID(f.Element),
+			"distance": f.Distance,
+		}
+	}
+	response, err := wd.ExecuteScriptRaw(findRelativeScript, []interface{}{v.By, v.Value, scope, filters})
+	if err != nil || suffix == "s" {
+		return response, err
+	}
+
+	reply := new(struct{ Value []json.RawMessage })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	if len(reply.Value) == 0 {
+		r := RelativeLocator{root: Locator{By: v.By, Value: v.Value}, filters: v.Filters}
+		return nil, &Error{Err: string(werror.NoSuchElement), Message: fmt.Sprintf("no element matches %s", r)}
+	}
+	return json.Marshal(map[string]json.RawMessage{"value": reply.Value[0]})
+}
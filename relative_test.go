@@ -0,0 +1,67 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRelativeLocator(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var args []json.RawMessage
+	matches := []string{"e2", "e3"}
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var req struct{ Args []json.RawMessage }
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		args = req.Args
+		var elems []map[string]string
+		for _, id := range matches {
+			elems = append(elems, map[string]string{webElementIdentifier: id})
+		}
+		return http.StatusOK, elems
+	})
+	wd := d.newRemote(t)
+	label := wd.ElementFromID("label")
+	base := RelativeBy(ByTagName, "input")
+	l := base.Below(label).NearWithin(label, 100)
+
+	elems, err := wd.FindElements(l.ByValue())
+	if err != nil {
+		t.Fatalf("wd.FindElements() returned error: %v", err)
+	}
+	if len(elems) != 2 || elems[0].ID() != "e2" || elems[1].ID() != "e3" {
+		t.Errorf("wd.FindElements() returned %v, want e2 and e3", elems)
+	}
+	if len(args) != 4 || string(args[0]) != `"tag name"` || string(args[1]) != `"input"` || string(args[2]) != "null" {
+		t.Fatalf("the script received arguments %s, want the root locator and no scope", args)
+	}
+	ref := string(mustJSON(t, label))
+	wantFilters := `[{"distance":0,"element":` + ref + `,"kind":"below"},{"distance":100,"element":` + ref + `,"kind":"near"}]`
+	if string(args[3]) != wantFilters {
+		t.Errorf("the script received filters %s, want %s", args[3], wantFilters)
+	}
+	if len(base.filters) != 0 {
+		t.Errorf("adding filters changed the locator they were added to: %v", base)
+	}
+
+	elem, err := wd.ElementFromID("form").FindElement(l.ByValue())
+	if err != nil {
+		t.Fatalf("elem.FindElement() returned error: %v", err)
+	}
+	if elem.ID() != "e2" {
+		t.Errorf("elem.FindElement() returned %q, want the closest element e2", elem.ID())
+	}
+	if want := string(mustJSON(t, wd.ElementFromID("form"))); string(args[2]) != want {
+		t.Errorf("the script received scope %s, want %s", args[2], want)
+	}
+
+	matches = nil
+	if _, err := wd.FindElement(l.ByValue()); !isNoSuchElementError(err) {
+		t.Errorf("wd.FindElement() without matches returned error %v, want no such element", err)
+	}
+	if got, want := l.String(), `tag name "input" below element label within 100px of element label`; got != want {
+		t.Errorf("l.String() = %q, want %q", got, want)
+	}
+}
@@ -5,20 +5,26 @@ package selenium
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"mime"
 	"net/http"
 	"net/url"
-	"path"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
+	"github.com/LoveOyy/selenium/chrome"
 	"github.com/LoveOyy/selenium/firefox"
 	"github.com/LoveOyy/selenium/log"
+	"github.com/LoveOyy/selenium/werror"
 	"github.com/blang/semver"
 )
 
@@ -47,6 +53,15 @@ var remoteErrors = map[int]string{
 }
 
 type remoteWD struct {
+	*remoteSession
+	// ctx, if not nil, bounds the commands sent through this view of the
+	// session; see WithContext.
+	ctx context.Context
+}
+
+// remoteSession is the state of a session, shared by its views returned by
+// WithContext.
+type remoteSession struct {
 	id, urlPrefix string
 	capabilities  Capabilities
 	w3cCompatible bool
@@ -54,10 +69,108 @@ type remoteWD struct {
 	storedActions  Actions
 	browser        string
 	browserVersion semver.Version
+	// testName identifies the test using the session; see SetTestName.
+	// testNameScript is the identifier of the init script that exposes it to
+	// the pages, if any. Both are guarded by testNameMu, as background loops
+	// read the name.
+	testNameMu     sync.Mutex
+	testName       string
+	testNameScript string
+	// label tells the session apart from the other sessions of the test; see
+	// WithLabel.
+	label string
+	// sessionCapabilities are the capabilities returned by the remote end when
+	// the session was created.
+	sessionCapabilities Capabilities
+
+	// cmdLock serializes the commands of the session, which may be sent from
+	// other goroutines, e.g. by WatchWindows. It holds a value while a
+	// command is sent: a channel rather than a mutex, so that the commands
+	// of a view stop waiting for it when the context of the view is done.
+	cmdLock chan struct{}
+
+	supportMu sync.Mutex
+	// support caches the results of Supports.
+	support map[Feature]bool
+	// recorder, if not nil, accumulates the summary of the session; see
+	// WithSessionSummary.
+	recorder *sessionRecorder
+	// defaults are the settings passed to WithDefaults, completed with the
+	// library defaults.
+	defaults Defaults
+	// service, if not nil, runs the driver of the session; see WithService.
+	service *Service
+	// created is when the session was requested.
+	created time.Time
+	// removeUserDataDir makes Quit remove the temporary user data directory
+	// of the browser; see RemoveTempUserDataDirOnQuit.
+	removeUserDataDir bool
+	// preserveProfile, if not nil, keeps the profile of the browser; see
+	// PreserveProfile.
+	preserveProfile *profilePreserver
+	// tempDirs hold the files written for uploads, removed by Quit.
+	tempDirs []string
+	// failureHooks are called when the session gives up waiting for a page;
+	// see WithFailureHook.
+	failureHooks []FailureHook
+	// jsonNumbers decodes the numbers of untyped results as json.Number; see
+	// WithJSONNumbers.
+	jsonNumbers bool
+	// slowMotion and stepPause delay the commands that change the state of
+	// the page; see WithSlowMotion and WithStepPause. waiting counts the
+	// waits in progress, whose commands are not delayed.
+	slowMotion time.Duration
+	stepPause  func(cmd Command) bool
+	waiting    int32
+	// profile, if not nil, accounts for the time spent by the session; see
+	// WithProfile.
+	profile *Profile
+	// failed, if not nil, tells Quit the result to report; see
+	// ReportResultOnQuit. resultReported is set once a result was reported.
+	failed         func() bool
+	resultReported bool
+	// snapshot caches the state of the session for LastKnownState.
+	snapshot snapshotCache
+	// loops are the background loops started by DrainLogs and
+	// WithPeriodicSnapshots, stopped by Quit.
+	loopMu sync.Mutex
+	loops  []*backgroundLoop
+	// scriptErrorFilter, if not nil, tells which JavaScript errors of the
+	// page to ignore after the commands that change its state; see
+	// FailOnJSErrors.
+	scriptErrorFilter func(msg string) bool
+	// wire accumulates the sizes of the requests and responses; see
+	// WireStats.
+	wire wireCounter
+	// visited holds the origins navigated to since the last ResetState.
+	visitedMu sync.Mutex
+	visited   map[string]bool
+	// browserContext is the browser context created by the last ResetState
+	// with ResetFull, disposed of by the next one.
+	browserContext string
+	// contexts are the browser contexts created by NewIsolatedContext and not
+	// closed yet, disposed of by Quit.
+	contextMu sync.Mutex
+	contexts  map[string]*isolatedContext
+	// trace, if not nil, writes the records of the commands; see
+	// WithCommandTrace.
+	trace *commandTracer
+	// client, if not nil, sends the commands instead of HTTPClient; see
+	// WithHTTPClient.
+	client *http.Client
+	// retry, if not nil, retries the commands that fail with transient
+	// errors; see WithRetryPolicy.
+	retry *RetryPolicy
+	// fileDetector, if not nil, detects the files typed with SendKeys to
+	// upload them; see WithFileDetector.
+	fileDetector FileDetector
+	// spans, if not nil, starts the spans of the commands; see
+	// WithCommandSpans.
+	spans SpanFunc
 }
 
 // HTTPClient is the default client to use to communicate with the WebDriver
-// server.
+// server, for the sessions created without WithHTTPClient.
 var HTTPClient = http.DefaultClient
 
 // jsonContentType is JSON content type.
@@ -74,7 +187,7 @@ func newRequest(method string, url string, data []byte) (*http.Request, error) {
 }
 
 func (wd *remoteWD) requestURL(template string, args ...interface{}) string {
-	return wd.urlPrefix + fmt.Sprintf(template, args...)
+	return joinURL(wd.urlPrefix, fmt.Sprintf(template, args...))
 }
 
 // TODO(minusnine): provide a "sessionURL" function that prepends the
@@ -93,39 +206,98 @@ type serverReply struct {
 	Error
 }
 
-// Error contains information about a failure of a command. See the table of
-// these strings at https://www.w3.org/TR/webdriver/#handling-errors .
-//
-// This error type is only returned by servers that implement the W3C
-// specification.
-type Error struct {
-	// Err contains a general error string provided by the server.
-	Err string `json:"error"`
-	// Message is a detailed, human-readable message specific to the failure.
-	Message string `json:"message"`
-	// Stacktrace may contain the server-side stacktrace where the error occurred.
-	Stacktrace string `json:"stacktrace"`
-	// HTTPCode is the HTTP status code returned by the server.
-	HTTPCode int
-	// LegacyCode is the "Response Status Code" defined in the legacy Selenium
-	// WebDriver JSON wire protocol. This code is only produced by older
-	// Selenium WebDriver versions, Chromedriver, and InternetExplorerDriver.
-	LegacyCode int
-}
-
-// TODO(minusnine): Make Stacktrace more descriptive. Selenium emits a list of
-// objects that enumerate various fields. This is not standard, though.
-
-// Error implements the error interface.
-func (e *Error) Error() string {
-	return fmt.Sprintf("%s: %s", e.Err, e.Message)
-}
+// Error contains information about a failure of a command. It matches the
+// werror.Code of the failure with errors.Is; see the werror package.
+type Error = werror.Error
 
 // execute performs an HTTP request and inspects the returned data for an error
 // encoded by the remote end in a JSON structure. If no error is present, the
 // entire, raw request payload is returned.
 func (wd *remoteWD) execute(method, url string, data []byte) (json.RawMessage, error) {
-	return executeCommand(method, url, data)
+	tag := tagTest
+	if atomic.LoadInt32(&wd.waiting) > 0 {
+		tag = tagWaitPoll
+	}
+	return wd.executeTagged(tag, method, url, data)
+}
+
+// lockCommands waits until no other command of the session is being sent,
+// or until ctx is done, and then locks the commands of the session until
+// unlockCommands is called.
+func (wd *remoteWD) lockCommands(ctx context.Context) error {
+	select {
+	case wd.cmdLock <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlockCommands lets the other commands of the session be sent.
+func (wd *remoteWD) unlockCommands() {
+	<-wd.cmdLock
+}
+
+// executeTagged is like execute, but tells on whose behalf the command is
+// sent, for the step pause hooks and the profile of the session.
+func (wd *remoteWD) executeTagged(tag commandTag, method, url string, data []byte) (json.RawMessage, error) {
+	ctx := context.Background()
+	if wd.ctx != nil && tag != tagBackground {
+		ctx = wd.ctx
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	if wd.defaults.CommandTimeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wd.defaults.CommandTimeout)
+		defer cancel()
+	}
+	if err := wd.beforeStep(tag, method, url); err != nil {
+		return nil, err
+	}
+	command := commandType(method, url, wd.urlPrefix, wd.id)
+	ctx, tap := wd.wire.withTap(ctx, command)
+	if err := wd.lockCommands(ctx); err != nil {
+		if wd.ctx != nil && tag != tagBackground && wd.ctx.Err() != nil {
+			return nil, wd.ctx.Err()
+		}
+		return nil, fmt.Errorf("waiting for the other commands of the session: %w", err)
+	}
+	var seq int64
+	if wd.trace != nil {
+		seq = wd.trace.next()
+	}
+	start := time.Now()
+	spanCtx, endSpan := wd.startSpan(ctx, command, start)
+	buf, err := wd.executeWithRetries(spanCtx, command, method, url, data)
+	elapsed := time.Since(start)
+	tap.warn()
+	endSpan(elapsed, err)
+	if wd.trace != nil {
+		wd.trace.record(wd, seq, tag, method, url, data, start, elapsed, err)
+	}
+	if e, ok := err.(*Error); ok {
+		e.TestName = wd.currentTestName()
+		e.Label = wd.label
+	}
+	if wd.recorder != nil {
+		wd.recorder.record(wd, method, url, data, buf, err)
+	}
+	if wd.profile != nil {
+		wd.profile.addCommand(tag, command, elapsed)
+	}
+	if err != nil {
+		if wd.ctx != nil && tag != tagBackground && wd.ctx.Err() != nil {
+			return buf, wd.ctx.Err()
+		}
+		return buf, wd.crashError(err)
+	}
+	wd.snapshot.observe(command, data, buf)
+	if tag == tagTest && wd.scriptErrorFilter != nil && steppedCommands[command] {
+		err = wd.checkScriptErrors(command)
+	}
+	return buf, err
 }
 
 // execute performs an HTTP request and inspects the returned data for an error
@@ -134,50 +306,102 @@ func (wd *remoteWD) execute(method, url string, data []byte) (json.RawMessage, e
 func (wd *remoteWD) Execute(method, url string, data []byte) (json.RawMessage, error) {
 	return wd.execute(method, url, data)
 }
-func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
-	debugLog("-> %s %s\n%s", method, filteredURL(url), data)
+
+// executeCommand sends a command to the remote end. If label is not empty, it
+// identifies the command's test in the debug log.
+func executeCommand(label, method, url string, data []byte) (json.RawMessage, error) {
+	return executeCommandContext(context.Background(), nil, label, method, url, data)
+}
+
+// executeCommandContext is like executeCommand, but the command is canceled
+// when ctx is done, and sent with client, or HTTPClient if nil.
+func executeCommandContext(ctx context.Context, client *http.Client, label, method, url string, data []byte) (json.RawMessage, error) {
+	debugLog("%s-> %s %s\n%s", logLabel(label), method, filteredURL(url), data)
 	request, err := newRequest(method, url, data)
 	if err != nil {
 		return nil, err
 	}
+	return sendRequest(client, label, request.WithContext(ctx))
+}
+
+// joinLabels returns the name identifying a session in errors and in the
+// debug log, from the name of its test and its label.
+func joinLabels(testName, label string) string {
+	if testName != "" && label != "" {
+		return testName + "/" + label
+	}
+	return testName + label
+}
+
+// logLabel formats the label of a command for the debug log.
+func logLabel(label string) string {
+	if label == "" {
+		return ""
+	}
+	return "[" + label + "] "
+}
 
-	response, err := HTTPClient.Do(request)
+// sendRequest sends the request of a command with client, or HTTPClient if
+// nil, and decodes the reply.
+func sendRequest(client *http.Client, label string, request *http.Request) (json.RawMessage, error) {
+	label = logLabel(label)
+	if client == nil {
+		client = HTTPClient
+	}
+	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
 	}
+	defer response.Body.Close()
 
-	buf, err := ioutil.ReadAll(response.Body)
+	body := &countingReader{r: response.Body}
+	buf, err := ioutil.ReadAll(body)
+	if tap, ok := request.Context().Value(wireKey{}).(*wireTap); ok {
+		// The transport may still read the body of the request from another
+		// goroutine, so its declared length is accounted for.
+		sent := request.ContentLength
+		if sent < 0 {
+			sent = 0
+		}
+		tap.add(sent, body.n)
+	}
 	if debugFlag {
+		logged := buf
 		if err == nil {
 			// Pretty print the JSON response
 			var prettyBuf bytes.Buffer
-			if err = json.Indent(&prettyBuf, buf, "", "    "); err == nil && prettyBuf.Len() > 0 {
-				buf = prettyBuf.Bytes()
+			if json.Indent(&prettyBuf, buf, "", "    ") == nil && prettyBuf.Len() > 0 {
+				logged = prettyBuf.Bytes()
 			}
 		}
-		debugLog("<- %s [%s]\n%s", response.Status, response.Header["Content-Type"], buf)
+		debugLog("%s<- %s [%s]\n%s", label, response.Status, response.Header["Content-Type"], logged)
 	}
 	if err != nil {
 		return nil, errors.New(response.Status)
 	}
-
-	fullCType := response.Header.Get("Content-Type")
-	cType, _, err := mime.ParseMediaType(fullCType)
-	if err != nil {
-		return nil, fmt.Errorf("got content type header %q, expected %q", fullCType, jsonContentType)
+	if response.StatusCode == http.StatusRequestEntityTooLarge {
+		return nil, &Error{
+			Err:      "request entity too large",
+			Message:  strings.TrimSpace(string(buf)),
+			HTTPCode: response.StatusCode,
+		}
 	}
-	if cType != jsonContentType {
-		return nil, fmt.Errorf("got content type %q, expected %q", cType, jsonContentType)
+
+	if err := checkJSONResponse(response, buf); err != nil {
+		return nil, err
 	}
 
+	buf = normalizeUTF8(buf)
 	reply := new(serverReply)
 	if err := json.Unmarshal(buf, reply); err != nil {
 		if response.StatusCode != http.StatusOK {
-			return nil, fmt.Errorf("bad server reply status: %s", response.Status)
+			return nil, newNonJSONResponseError(response, buf)
 		}
 		return nil, err
 	}
+	retryAfter := parseRetryAfter(response.Header.Get("Retry-After"))
 	if reply.Err != "" {
+		reply.Error.RetryAfter = retryAfter
 		return nil, &reply.Error
 	}
 
@@ -187,6 +411,7 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 		respErr := new(Error)
 		if err := json.Unmarshal(reply.Value, respErr); err == nil && respErr.Err != "" {
 			respErr.HTTPCode = response.StatusCode
+			respErr.RetryAfter = retryAfter
 			return nil, respErr
 		}
 	}
@@ -201,6 +426,7 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 
 		longMsg := new(struct {
 			Message string
+			Screen  []byte
 		})
 		if err := json.Unmarshal(reply.Value, longMsg); err != nil {
 			return nil, errors.New(shortMsg)
@@ -208,14 +434,62 @@ func executeCommand(method, url string, data []byte) (json.RawMessage, error) {
 		return nil, &Error{
 			Err:        shortMsg,
 			Message:    longMsg.Message,
+			Screenshot: longMsg.Screen,
 			HTTPCode:   response.StatusCode,
 			LegacyCode: reply.Status,
+			RetryAfter: retryAfter,
 		}
 	}
 
 	return buf, nil
 }
 
+// normalizeUTF8 repairs replies from remote ends that encode the UTF-16
+// surrogate pairs of characters outside the Basic Multilingual Plane, such as
+// emoji, as two three-byte sequences (CESU-8) instead of a single four-byte
+// UTF-8 sequence. encoding/json would otherwise decode each byte of them as
+// U+FFFD. Pairs are combined into the character they encode; lone surrogates
+// and other invalid bytes are replaced with U+FFFD.
+func normalizeUTF8(b []byte) []byte {
+	if utf8.Valid(b) {
+		return b
+	}
+	out := make([]byte, 0, len(b))
+	var enc [utf8.UTFMax]byte
+	for len(b) > 0 {
+		if r1, ok := decodeSurrogate(b); ok {
+			if r2, ok := decodeSurrogate(b[3:]); ok {
+				if r := utf16.DecodeRune(r1, r2); r != utf8.RuneError {
+					n := utf8.EncodeRune(enc[:], r)
+					out = append(out, enc[:n]...)
+					b = b[6:]
+					continue
+				}
+			}
+			out = append(out, string(utf8.RuneError)...)
+			b = b[3:]
+			continue
+		}
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			out = append(out, string(utf8.RuneError)...)
+		} else {
+			out = append(out, b[:size]...)
+		}
+		b = b[size:]
+	}
+	return out
+}
+
+// decodeSurrogate decodes a UTF-16 surrogate encoded as three bytes at the
+// start of b.
+func decodeSurrogate(b []byte) (rune, bool) {
+	if len(b) < 3 || b[0] != 0xED || b[1]&0xE0 != 0xA0 || b[2]&0xC0 != 0x80 {
+		return 0, false
+	}
+	return rune(b[0]&0x0F)<<12 | rune(b[1]&0x3F)<<6 | rune(b[2]&0x3F), true
+}
+
 // DefaultURLPrefix is the default HTTP endpoint that offers the WebDriver API.
 const DefaultURLPrefix = "http://127.0.0.1:4444/wd/hub"
 
@@ -226,24 +500,188 @@ const DefaultURLPrefix = "http://127.0.0.1:4444/wd/hub"
 // Providing an empty string for urlPrefix causes the DefaultURLPrefix to be
 // used.
 func NewRemote(capabilities Capabilities, urlPrefix string) (WebDriver, error) {
+	return NewRemoteContext(context.Background(), capabilities, urlPrefix)
+}
+
+// NewRemoteContext is like NewRemote, but the creation of the session is
+// aborted when ctx is done.
+func NewRemoteContext(ctx context.Context, capabilities Capabilities, urlPrefix string, opts ...SessionOption) (WebDriver, error) {
 	if urlPrefix == "" {
 		urlPrefix = DefaultURLPrefix
 	}
+	var o sessionOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.basePath != nil {
+		var err error
+		if urlPrefix, err = withBasePath(urlPrefix, *o.basePath); err != nil {
+			return nil, err
+		}
+	}
 
-	wd := &remoteWD{
+	wd := &remoteWD{remoteSession: &remoteSession{
 		urlPrefix:    urlPrefix,
 		capabilities: capabilities,
-	}
+		defaults:     Defaults{}.withLibraryDefaults(),
+		cmdLock:      make(chan struct{}, 1),
+	}}
 	if b := capabilities["browserName"]; b != nil {
 		wd.browser = b.(string)
 	}
-
-	if _, err := wd.NewSession(); err != nil {
+	if o.summary != nil {
+		wd.recorder = newSessionRecorder(o.summary)
+	}
+	wd.defaults = o.defaults.withLibraryDefaults()
+	wd.service = o.service
+	if wd.service == nil {
+		wd.service = serviceAt(urlPrefix)
+	}
+	wd.failed = o.failed
+	wd.label = o.label
+	wd.removeUserDataDir = o.removeUserDataDir
+	wd.preserveProfile = o.preserveProfile
+	wd.failureHooks = o.failureHooks
+	wd.jsonNumbers = o.jsonNumbers
+	wd.slowMotion = o.slowMotion
+	wd.stepPause = o.stepPause
+	wd.profile = o.profile
+	wd.client = o.client
+	wd.retry = o.retry
+	wd.fileDetector = o.fileDetector
+	wd.spans = o.spans
+	wd.snapshot.maxBytes = o.snapshotBytes
+	wd.wire.limit, wd.wire.warn = o.wireLimit, o.wireWarn
+	wd.created = time.Now()
+	if o.preserveProfile != nil {
+		if err := wd.preserveChromeProfile(o.preserveProfile); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := wd.newSession(ctx, o); err != nil {
+		if wd.id != "" {
+			// The reply created a session but could not be decoded.
+			return nil, wd.discardSession(err)
+		}
+		wd.applyProfileRetention()
 		return nil, err
 	}
+	wd.trackTempUserDataDir()
+	if o.trace != nil {
+		wd.trace = newCommandTracer(o.trace)
+	}
+	if err := wd.checkVersionSkew(o); err != nil {
+		return nil, wd.discardSession(err)
+	}
+	if wd.defaults.ImplicitWait != 0 {
+		if err := wd.SetImplicitWaitTimeout(wd.defaults.ImplicitWait); err != nil {
+			return nil, wd.discardSession(err)
+		}
+	}
+	if err := wd.addChromeInitScripts(); err != nil {
+		return nil, wd.discardSession(err)
+	}
+	if o.snapshotInterval > 0 {
+		wd.takeSnapshots(o.snapshotInterval)
+	}
 	return wd, nil
 }
 
+// discardSession deletes the session created by NewRemoteContext when a later
+// step of its setup fails with err, which it returns, so that the remote end
+// does not keep it until it times out. The browser is closed even if it was
+// started with Chrome's Detach option, as nobody could use it.
+func (wd *remoteWD) discardSession(err error) error {
+	if wd.detached() {
+		if _, closeErr := wd.executeCDP("Browser.close", nil); closeErr != nil {
+			debugLog("error closing the browser of the session %s after its setup failed: %v\n", wd.id, closeErr)
+		}
+	}
+	if _, deleteErr := wd.execute("DELETE", wd.requestURL("/session/%s", wd.id), nil); deleteErr != nil {
+		debugLog("error deleting the session %s after its setup failed: %v\n", wd.id, deleteErr)
+	}
+	if wd.trace != nil {
+		wd.trace.close()
+	}
+	wd.id = ""
+	if wd.removeUserDataDir {
+		wd.removeTempUserDataDir()
+	}
+	wd.applyProfileRetention()
+	wd.removeTempDirs()
+	return err
+}
+
+// addChromeInitScripts installs the scripts requested by the Chrome
+// capabilities, such as localStorage seeds and origin trial tokens, for all
+// new documents.
+func (wd *remoteWD) addChromeInitScripts() error {
+	var scripts []string
+	switch c := wd.capabilities[chrome.CapabilitiesKey].(type) {
+	case chrome.Capabilities:
+		scripts = c.InitScripts()
+	case *chrome.Capabilities:
+		scripts = c.InitScripts()
+	}
+	for _, script := range scripts {
+		if wd.browser != "" && wd.browser != "chrome" {
+			return fmt.Errorf("profile seeding and origin trial tokens are only supported by Chrome, not %q", wd.browser)
+		}
+		if _, err := wd.addInitScript(script); err != nil {
+			return fmt.Errorf("profile seeding and origin trial tokens require ChromeDriver: %v", err)
+		}
+	}
+	return nil
+}
+
+// addInitScript makes the browser evaluate script in every new document,
+// before any of the document's own scripts, and returns the identifier of the
+// script for removeInitScript. It requires ChromeDriver.
+func (wd *remoteWD) addInitScript(script string) (string, error) {
+	params := map[string]interface{}{"source": script}
+	result, err := wd.executeCDP("Page.addScriptToEvaluateOnNewDocument", params)
+	if err != nil {
+		return "", err
+	}
+	reply := new(struct{ Identifier string })
+	if err := json.Unmarshal(result, reply); err != nil {
+		return "", err
+	}
+	return reply.Identifier, nil
+}
+
+// removeInitScript removes the init script with the identifier returned by
+// addInitScript.
+func (wd *remoteWD) removeInitScript(id string) error {
+	params := map[string]interface{}{"identifier": id}
+	_, err := wd.executeCDP("Page.removeScriptToEvaluateOnNewDocument", params)
+	return err
+}
+
+// executeCDP sends a Chrome DevTools Protocol command through ChromeDriver
+// and returns its result.
+func (wd *remoteWD) executeCDP(cmd string, params map[string]interface{}) (json.RawMessage, error) {
+	if params == nil {
+		params = map[string]interface{}{}
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"cmd":    cmd,
+		"params": params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/goog/cdp/execute", wd.id), data)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value json.RawMessage })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
 // DeleteSession deletes an existing session at the WebDriver instance
 // specified by the urlPrefix and the session ID.
 func DeleteSession(urlPrefix, id string) error {
@@ -251,8 +689,7 @@ func DeleteSession(urlPrefix, id string) error {
 	if err != nil {
 		return err
 	}
-	u.Path = path.Join(u.Path, "session", id)
-	return voidCommand("DELETE", u.String(), nil)
+	return voidCommand("DELETE", joinURL(u.String(), "/session/"+id), nil)
 }
 
 func (wd *remoteWD) stringCommand(urlTemplate string) (string, error) {
@@ -282,15 +719,23 @@ func voidCommand(method, url string, params interface{}) error {
 	if err != nil {
 		return err
 	}
-	_, err = executeCommand(method, url, data)
+	_, err = executeCommand("", method, url, data)
 	return err
 }
 
 func (wd *remoteWD) voidCommand(urlTemplate string, params interface{}) error {
-	return voidCommand("POST", wd.requestURL(urlTemplate, wd.id), params)
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL(urlTemplate, wd.id), data)
+	return err
 }
 
-func (wd remoteWD) stringsCommand(urlTemplate string) ([]string, error) {
+func (wd *remoteWD) stringsCommand(urlTemplate string) ([]string, error) {
 	url := wd.requestURL(urlTemplate, wd.id)
 	response, err := wd.execute("GET", url, nil)
 	if err != nil {
@@ -365,6 +810,7 @@ var w3cCapabilityNames = []string{
 	"setWindowRect",
 	"timeouts",
 	"unhandledPromptBehavior",
+	"webSocketUrl",
 }
 
 var chromeCapabilityNames = []string{
@@ -414,6 +860,10 @@ func newW3CCapabilities(caps Capabilities) Capabilities {
 }
 
 func (wd *remoteWD) NewSession() (string, error) {
+	return wd.newSession(context.Background(), sessionOptions{})
+}
+
+func (wd *remoteWD) newSession(ctx context.Context, o sessionOptions) (string, error) {
 	// Detect whether the remote end complies with the W3C specification:
 	// non-compliant implementations use the top-level 'desiredCapabilities' JSON
 	// key, whereas the specification mandates the 'capabilities' key.
@@ -423,29 +873,42 @@ func (wd *remoteWD) NewSession() (string, error) {
 	//
 	// TODO(minusnine): audit which ones of these are still relevant. The W3C
 	// standard switched to the "alwaysMatch" version in February 2017.
+	requested := wd.resolveChromeKeys()
+	w3c, err := translateCapabilities(requested, o)
+	if err != nil {
+		return "", err
+	}
+	if o.simplePromptHandler {
+		w3c, _ = simplePromptHandler(w3c)
+	}
+	// Remote ends that predate the W3C specification only know the string
+	// form of the prompt handler.
+	requested, _ = simplePromptHandler(requested)
 	attempts := []struct {
 		params map[string]interface{}
 	}{
 		{map[string]interface{}{
-			"capabilities":        newW3CCapabilities(wd.capabilities),
-			"desiredCapabilities": wd.capabilities,
+			"capabilities":        newW3CCapabilities(w3c),
+			"desiredCapabilities": requested,
 		}},
 		{map[string]interface{}{
 			"capabilities": map[string]interface{}{
-				"desiredCapabilities": wd.capabilities,
+				"desiredCapabilities": requested,
 			},
 		}},
 		{map[string]interface{}{
-			"desiredCapabilities": wd.capabilities,
+			"desiredCapabilities": requested,
 		}}}
 
 	for i, s := range attempts {
-		data, err := json.Marshal(s.params)
-		if err != nil {
-			return "", err
+		response, err := wd.postNewSession(ctx, wd.requestURL("/session"), s.params, o)
+		if err != nil && i == 0 && rejectsPromptHandler(err) {
+			if simple, ok := simplePromptHandler(w3c); ok {
+				debugLog("the remote end rejected the prompt handler, retrying with its string form: %v", err)
+				s.params["capabilities"] = newW3CCapabilities(simple)
+				response, err = wd.postNewSession(ctx, wd.requestURL("/session"), s.params, o)
+			}
 		}
-
-		response, err := wd.execute("POST", wd.requestURL("/session"), data)
 		if err != nil {
 			return "", err
 		}
@@ -507,6 +970,12 @@ func (wd *remoteWD) NewSession() (string, error) {
 			} else {
 				caps = value.returnedCapabilities
 			}
+			if err := wd.storeSessionCapabilities(reply.Value); err != nil {
+				debugLog("error unmarshalling capabilities: %v\n", err)
+			}
+			wd.supportMu.Lock()
+			wd.support = nil
+			wd.supportMu.Unlock()
 
 			for _, s := range []string{caps.Version, caps.BrowserVersion} {
 				if s == "" {
@@ -521,6 +990,9 @@ func (wd *remoteWD) NewSession() (string, error) {
 			}
 		}
 
+		if wd.detached() && wd.service != nil {
+			wd.service.noteDetachedSession()
+		}
 		return wd.id, nil
 	}
 	panic("unreachable")
@@ -543,6 +1015,87 @@ func (wd *remoteWD) SwitchSession(sessionID string) error {
 	return nil
 }
 
+// resolveChromeKeys returns the capabilities to request, with the Chrome
+// options placed under the keys selected by their KeyMode. The options are
+// either a chrome.Capabilities, as set by AddChrome, or a pointer to one;
+// options of another type, e.g. a map, are sent as they are.
+func (wd *remoteWD) resolveChromeKeys() Capabilities {
+	v := wd.capabilities[chrome.CapabilitiesKey]
+	if v == nil {
+		v = wd.capabilities[chrome.DeprecatedCapabilitiesKey]
+	}
+	var opts chrome.Capabilities
+	switch o := v.(type) {
+	case chrome.Capabilities:
+		opts = o
+	case *chrome.Capabilities:
+		if o == nil {
+			return wd.capabilities
+		}
+		opts = *o
+	default:
+		return wd.capabilities
+	}
+
+	mode := opts.KeyMode
+	if mode == chrome.AutoKey {
+		mode = chrome.BothKeys
+		if s, err := wd.Status(); err != nil {
+			debugLog("error querying the ChromeDriver version: %v\n", err)
+		} else if fields := strings.Fields(s.Build.Version); len(fields) > 0 {
+			if v, err := parseVersion(fields[0]); err != nil {
+				debugLog("error parsing the ChromeDriver version: %v\n", err)
+			} else if v.Major == 2 {
+				mode = chrome.LegacyKey
+			} else {
+				mode = chrome.ModernKey
+			}
+		}
+	}
+
+	caps := make(Capabilities)
+	for k, v := range wd.capabilities {
+		caps[k] = v
+	}
+	delete(caps, chrome.CapabilitiesKey)
+	delete(caps, chrome.DeprecatedCapabilitiesKey)
+	if mode != chrome.LegacyKey {
+		caps[chrome.CapabilitiesKey] = opts
+	}
+	if mode != chrome.ModernKey {
+		caps[chrome.DeprecatedCapabilitiesKey] = opts
+	}
+	return caps
+}
+
+// detached reports whether the session was requested with Chrome's detach
+// option, which keeps the browser running after ChromeDriver exits.
+func (wd *remoteWD) detached() bool {
+	var detach *bool
+	switch c := wd.capabilities[chrome.CapabilitiesKey].(type) {
+	case chrome.Capabilities:
+		detach = c.Detach
+	case *chrome.Capabilities:
+		detach = c.Detach
+	}
+	return detach != nil && *detach
+}
+
+// storeSessionCapabilities records the capabilities in the value of a New
+// Session reply.
+func (wd *remoteWD) storeSessionCapabilities(value json.RawMessage) error {
+	wd.sessionCapabilities = nil
+	if wd.w3cCompatible {
+		v := new(struct{ Capabilities Capabilities })
+		if err := wd.unmarshal(value, v); err != nil {
+			return err
+		}
+		wd.sessionCapabilities = v.Capabilities
+		return nil
+	}
+	return wd.unmarshal(value, &wd.sessionCapabilities)
+}
+
 func (wd *remoteWD) Capabilities() (Capabilities, error) {
 	url := wd.requestURL("/session/%s", wd.id)
 	response, err := wd.execute("GET", url, nil)
@@ -551,13 +1104,21 @@ func (wd *remoteWD) Capabilities() (Capabilities, error) {
 	}
 
 	c := new(struct{ Value Capabilities })
-	if err := json.Unmarshal(response, c); err != nil {
+	if err := wd.unmarshal(response, c); err != nil {
 		return nil, err
 	}
 
 	return c.Value, nil
 }
 
+func (wd *remoteWD) SessionCapabilities() Capabilities {
+	caps := make(Capabilities, len(wd.sessionCapabilities))
+	for k, v := range wd.sessionCapabilities {
+		caps[k] = v
+	}
+	return caps
+}
+
 func (wd *remoteWD) SetAsyncScriptTimeout(timeout time.Duration) error {
 	if !wd.w3cCompatible {
 		return wd.voidCommand("/session/%s/timeouts/async_script", map[string]uint{
@@ -593,14 +1154,58 @@ func (wd *remoteWD) SetPageLoadTimeout(timeout time.Duration) error {
 }
 
 func (wd *remoteWD) Quit() error {
+	return wd.quit(false)
+}
+
+func (wd *remoteWD) QuitAndCloseBrowser() error {
+	return wd.quit(true)
+}
+
+// quit deletes the session. ChromeDriver keeps the browser of a session
+// started with Chrome's Detach option running when the session is deleted,
+// so it is closed first if closeBrowser is true; otherwise the browser
+// contexts created by the session are disposed of, as the browser outlives
+// it.
+func (wd *remoteWD) quit(closeBrowser bool) error {
 	if wd.id == "" {
 		return nil
 	}
+	wd.stopLoops()
+	wd.reportResultOnQuit()
+	wd.preserveFirefoxProfile()
+	detached := wd.detached()
+	var closeErr error
+	switch {
+	case detached && closeBrowser:
+		_, closeErr = wd.executeCDP("Browser.close", nil)
+	case detached:
+		wd.closeContexts()
+		if wd.browserContext != "" {
+			wd.disposeBrowserContext(wd.browserContext)
+		}
+	}
 	_, err := wd.execute("DELETE", wd.requestURL("/session/%s", wd.id), nil)
-	if err == nil {
-		wd.id = ""
+	if err != nil && detached && closeBrowser && closeErr == nil {
+		// The session ended with the browser.
+		debugLog("error deleting the session %s of the closed browser: %v\n", wd.id, err)
+		err = nil
 	}
-	return err
+	if wd.recorder != nil {
+		wd.recorder.finish(wd, err, false)
+	}
+	if err != nil {
+		return err
+	}
+	if wd.trace != nil {
+		wd.trace.close()
+	}
+	wd.id = ""
+	if wd.removeUserDataDir && (!detached || closeBrowser) {
+		wd.removeTempUserDataDir()
+	}
+	wd.applyProfileRetention()
+	wd.removeTempDirs()
+	return nil
 }
 
 func (wd *remoteWD) CurrentWindowHandle() (string, error) {
@@ -640,8 +1245,11 @@ func (wd *remoteWD) Get(url string) error {
 	if err != nil {
 		return err
 	}
-	_, err = wd.execute("POST", requestURL, data)
-	return err
+	if _, err := wd.execute("POST", requestURL, data); err != nil {
+		return err
+	}
+	wd.visit(url)
+	return nil
 }
 
 func (wd *remoteWD) Forward() error {
@@ -665,6 +1273,10 @@ func (wd *remoteWD) PageSource() (string, error) {
 }
 
 func (wd *remoteWD) find(by, value, suffix, url string) ([]byte, error) {
+	if by == ByRelative {
+		return wd.findRelative(value, suffix, url)
+	}
+
 	// The W3C specification removed the specific ID and Name locator strategies,
 	// instead only providing a CSS-based strategy. Emulate the old behavior to
 	// maintain API compatibility.
@@ -920,7 +1532,7 @@ type cookie struct {
 	Secure   bool        `json:"secure"`
 	Expiry   interface{} `json:"expiry"`
 	HTTPOnly bool        `json:"httpOnly"`
-	SameSite string      `json:"sameSite",omitempty`
+	SameSite string      `json:"sameSite,omitempty"`
 }
 
 func (c cookie) sanitize() Cookie {
@@ -932,6 +1544,10 @@ func (c cookie) sanitize() Cookie {
 			}
 		case float64:
 			return uint(expiry)
+		case json.Number:
+			if n, ok := scriptInt(expiry); ok && n > 0 {
+				return uint(n)
+			}
 		}
 		return 0
 	}
@@ -961,20 +1577,12 @@ func (c cookie) sanitize() Cookie {
 }
 
 func (wd *remoteWD) GetCookie(name string) (Cookie, error) {
-	if wd.browser == "chrome" {
-		cs, err := wd.GetCookies()
-		if err != nil {
-			return Cookie{}, err
-		}
-		for _, c := range cs {
-			if c.Name == name {
-				return c, nil
-			}
-		}
-		return Cookie{}, errors.New("cookie not found")
+	data, err := wd.execute("GET", wd.requestURL("/session/%s/cookie/%s", wd.id, url.PathEscape(name)), nil)
+	if isUnknownCommand(err) {
+		// Remote ends predating the W3C specification only return all the
+		// cookies.
+		return wd.findCookie(name)
 	}
-	url := wd.requestURL("/session/%s/cookie/%s", wd.id, name)
-	data, err := wd.execute("GET", url, nil)
 	if err != nil {
 		return Cookie{}, err
 	}
@@ -984,15 +1592,15 @@ func (wd *remoteWD) GetCookie(name string) (Cookie, error) {
 	//
 	// https://github.com/mozilla/geckodriver/issues/761
 	reply := new(struct{ Value cookie })
-	if err := json.Unmarshal(data, reply); err == nil {
+	if err := wd.unmarshal(data, reply); err == nil {
 		return reply.Value.sanitize(), nil
 	}
 	listReply := new(struct{ Value []cookie })
-	if err := json.Unmarshal(data, listReply); err != nil {
+	if err := wd.unmarshal(data, listReply); err != nil {
 		return Cookie{}, err
 	}
 	if len(listReply.Value) == 0 {
-		return Cookie{}, errors.New("no cookies returned")
+		return Cookie{}, noSuchCookie(name)
 	}
 	return listReply.Value[0].sanitize(), nil
 }
@@ -1005,7 +1613,7 @@ func (wd *remoteWD) GetCookies() ([]Cookie, error) {
 	}
 
 	reply := new(struct{ Value []cookie })
-	if err := json.Unmarshal(data, reply); err != nil {
+	if err := wd.unmarshal(data, reply); err != nil {
 		return nil, err
 	}
 
@@ -1017,8 +1625,12 @@ func (wd *remoteWD) GetCookies() ([]Cookie, error) {
 }
 
 func (wd *remoteWD) AddCookie(cookie *Cookie) error {
+	cs, err := wd.normalizeCookies([]Cookie{*cookie})
+	if err != nil {
+		return err
+	}
 	return wd.voidCommand("/session/%s/cookie", map[string]*Cookie{
-		"cookie": cookie,
+		"cookie": &cs[0],
 	})
 }
 
@@ -1029,13 +1641,15 @@ func (wd *remoteWD) DeleteAllCookies() error {
 }
 
 func (wd *remoteWD) DeleteCookie(name string) error {
-	url := wd.requestURL("/session/%s/cookie/%s", wd.id, name)
-	_, err := wd.execute("DELETE", url, nil)
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s/cookie/%s", wd.id, url.PathEscape(name)), nil)
 	return err
 }
 
 // TODO(minusnine): add a test for Click.
 func (wd *remoteWD) Click(button int) error {
+	if wd.w3cCompatible {
+		return wd.PerformActionChain(NewActionChain().Click(MouseButton(button)))
+	}
 	return wd.voidCommand("/session/%s/click", map[string]int{
 		"button": button,
 	})
@@ -1043,16 +1657,25 @@ func (wd *remoteWD) Click(button int) error {
 
 // TODO(minusnine): add a test for DoubleClick.
 func (wd *remoteWD) DoubleClick() error {
+	if wd.w3cCompatible {
+		return wd.PerformActionChain(NewActionChain().DoubleClick())
+	}
 	return wd.voidCommand("/session/%s/doubleclick", nil)
 }
 
 // TODO(minusnine): add a test for ButtonDown.
 func (wd *remoteWD) ButtonDown() error {
+	if wd.w3cCompatible {
+		return wd.PerformActionChain(NewActionChain().PointerDown(LeftButton))
+	}
 	return wd.voidCommand("/session/%s/buttondown", nil)
 }
 
 // TODO(minusnine): add a test for ButtonUp.
 func (wd *remoteWD) ButtonUp() error {
+	if wd.w3cCompatible {
+		return wd.PerformActionChain(NewActionChain().PointerUp(LeftButton))
+	}
 	return wd.voidCommand("/session/%s/buttonup", nil)
 }
 
@@ -1079,7 +1702,7 @@ func (wd *remoteWD) keyAction(action, keys string) error {
 		"actions": []interface{}{
 			map[string]interface{}{
 				"type":    "key",
-				"id":      "default keyboard",
+				"id":      DefaultKeyboard,
 				"actions": actions,
 			}},
 	})
@@ -1198,24 +1821,26 @@ func (wd *remoteWD) PerformActions() error {
 }
 
 func (wd *remoteWD) ReleaseActions() error {
-	return voidCommand("DELETE", wd.requestURL("/session/%s/actions", wd.id), nil)
+	_, err := wd.execute("DELETE", wd.requestURL("/session/%s/actions", wd.id), nil)
+	return err
 }
 
 func (wd *remoteWD) DismissAlert() error {
-	return wd.voidCommand("/session/%s/alert/dismiss", nil)
+	return wd.alertError(wd.voidCommand("/session/%s/alert/dismiss", nil))
 }
 
 func (wd *remoteWD) AcceptAlert() error {
-	return wd.voidCommand("/session/%s/alert/accept", nil)
+	return wd.alertError(wd.voidCommand("/session/%s/alert/accept", nil))
 }
 
 func (wd *remoteWD) AlertText() (string, error) {
-	return wd.stringCommand("/session/%s/alert/text")
+	text, err := wd.stringCommand("/session/%s/alert/text")
+	return text, wd.alertError(err)
 }
 
 func (wd *remoteWD) SetAlertText(text string) error {
 	data := map[string]string{"text": text}
-	return wd.voidCommand("/session/%s/alert/text", data)
+	return wd.alertError(wd.voidCommand("/session/%s/alert/text", data))
 }
 
 func (wd *remoteWD) execScriptRaw(script string, args []interface{}, suffix string) ([]byte, error) {
@@ -1241,7 +1866,7 @@ func (wd *remoteWD) execScript(script string, args []interface{}, suffix string)
 	}
 
 	reply := new(struct{ Value interface{} })
-	if err = json.Unmarshal(response, reply); err != nil {
+	if err = wd.unmarshal(response, reply); err != nil {
 		return nil, err
 	}
 
@@ -1294,17 +1919,22 @@ type Condition func(wd WebDriver) (bool, error)
 
 const (
 	// DefaultWaitInterval is the default polling interval for selenium.Wait
-	// function.
+	// function. It can be changed per session with Defaults.PollInterval.
 	DefaultWaitInterval = 100 * time.Millisecond
 
 	// DefaultWaitTimeout is the default timeout for selenium.Wait function.
+	// It can be changed per session with Defaults.WaitTimeout.
 	DefaultWaitTimeout = 60 * time.Second
 )
 
 func (wd *remoteWD) WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error {
+	end := wd.startWait()
+	polls := 0
+	defer func() { end(polls) }()
 	startTime := time.Now()
 
 	for {
+		polls++
 		done, err := condition(wd)
 		if err != nil {
 			return err
@@ -1316,16 +1946,18 @@ func (wd *remoteWD) WaitWithTimeoutAndInterval(condition Condition, timeout, int
 		if elapsed := time.Since(startTime); elapsed > timeout {
 			return fmt.Errorf("timeout after %v", elapsed)
 		}
-		time.Sleep(interval)
+		if err := wd.sleep(interval); err != nil {
+			return err
+		}
 	}
 }
 
 func (wd *remoteWD) WaitWithTimeout(condition Condition, timeout time.Duration) error {
-	return wd.WaitWithTimeoutAndInterval(condition, timeout, DefaultWaitInterval)
+	return wd.WaitWithTimeoutAndInterval(condition, timeout, wd.defaults.PollInterval)
 }
 
 func (wd *remoteWD) Wait(condition Condition) error {
-	return wd.WaitWithTimeoutAndInterval(condition, DefaultWaitTimeout, DefaultWaitInterval)
+	return wd.WaitWithTimeoutAndInterval(condition, wd.defaults.WaitTimeout, wd.defaults.PollInterval)
 }
 
 func (wd *remoteWD) Log(typ log.Type) ([]log.Message, error) {
@@ -1341,7 +1973,11 @@ func (wd *remoteWD) Log(typ log.Type) ([]log.Message, error) {
 	if err != nil {
 		return nil, err
 	}
+	return decodeLog(response)
+}
 
+// decodeLog decodes the reply to the command that fetches the logs.
+func decodeLog(response []byte) ([]log.Message, error) {
 	c := new(struct {
 		Value []struct {
 			Timestamp int64
@@ -1349,7 +1985,7 @@ func (wd *remoteWD) Log(typ log.Type) ([]log.Message, error) {
 			Message   string
 		}
 	})
-	if err = json.Unmarshal(response, c); err != nil {
+	if err := json.Unmarshal(response, c); err != nil {
 		return nil, err
 	}
 
@@ -1375,23 +2011,166 @@ type remoteWE struct {
 	// that the value is called a "reference". For ease of transition, we store
 	// the "reference" in this now misnamed field.
 	id string
+	// frame is the path to the frame containing the element, if known.
+	frame FramePath
 }
 
-func (elem *remoteWE) Click() error {
+func (elem *remoteWE) Click(opts ...ActionOption) error {
+	if err := elem.precheck(opts); err != nil {
+		return err
+	}
+	retries := elem.parent.defaults.ClickRetries
+	if o := actionOptionsOf(opts); o.clickRetries != nil {
+		retries = *o.clickRetries
+	}
 	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/click", elem.id)
-	return elem.parent.voidCommand(urlTemplate, nil)
+	for {
+		err := elem.parent.voidCommand(urlTemplate, nil)
+		if retries <= 0 || !isClickInterceptedError(err) {
+			return err
+		}
+		retries--
+		time.Sleep(elem.parent.defaults.PollInterval)
+	}
 }
 
-func (elem *remoteWE) SendKeys(keys string) error {
+func (elem *remoteWE) SendKeys(keys string, opts ...ActionOption) error {
+	if err := elem.precheck(opts); err != nil {
+		return err
+	}
+	keys, err := elem.parent.uploadDetectedFiles(keys)
+	if err != nil {
+		return err
+	}
 	urlTemplate := fmt.Sprintf("/session/%%s/element/%s/value", elem.id)
 	return elem.parent.voidCommand(urlTemplate, elem.parent.processKeyString(keys))
 }
 
+// maxKeyActionText is the longest text, in characters, that SetText and
+// AppendText type with key actions. Longer text is inserted by script: typing
+// a paste-sized value one key at a time is slow and is not what a user would
+// do anyway.
+const maxKeyActionText = 256
+
+// isKeyActionText reports whether every character of text can be typed
+// reliably as an individual key press. Characters outside the Basic
+// Multilingual Plane are sent as surrogate halves by some drivers, combining
+// marks are not composed with the preceding character, control characters
+// have key semantics (e.g. a newline submits a form) and the Private Use Area
+// contains the WebDriver special keys.
+func isKeyActionText(text string) bool {
+	if utf8.RuneCountInString(text) > maxKeyActionText {
+		return false
+	}
+	for _, r := range text {
+		switch {
+		case r == utf8.RuneError, r > 0xFFFF:
+			return false
+		case r >= 0xE000 && r <= 0xF8FF:
+			return false
+		case unicode.IsControl(r), unicode.Is(unicode.M, r):
+			return false
+		}
+	}
+	return true
+}
+
+// focusEndScript focuses the element passed as the first argument and moves
+// the caret to the end of its value.
+const focusEndScript = `
+var el = arguments[0];
+el.focus();
+try {
+	if (typeof el.value === 'string' && el.setSelectionRange) {
+		el.setSelectionRange(el.value.length, el.value.length);
+	}
+} catch (e) {
+	// Some input types (e.g. email) do not support selection.
+}
+`
+
+// insertTextScript sets or appends to the value of the element passed as the
+// first argument and dispatches the events that a user typing the text would
+// cause. The native value setter is used so that frameworks that intercept
+// the value property (e.g. React) still see the change.
+const insertTextScript = `
+var el = arguments[0], text = arguments[1], append = arguments[2];
+el.focus();
+var proto = null;
+if (el instanceof HTMLTextAreaElement) {
+	proto = HTMLTextAreaElement.prototype;
+} else if (el instanceof HTMLInputElement) {
+	proto = HTMLInputElement.prototype;
+}
+if (proto) {
+	var setter = Object.getOwnPropertyDescriptor(proto, 'value').set;
+	setter.call(el, append ? el.value + text : text);
+} else if (el.isContentEditable) {
+	el.textContent = append ? el.textContent + text : text;
+} else {
+	throw new Error('element is not editable');
+}
+var input;
+if (typeof InputEvent === 'function') {
+	input = new InputEvent('input', {bubbles: true, inputType: 'insertText', data: text});
+} else {
+	input = document.createEvent('Event');
+	input.initEvent('input', true, false);
+}
+el.dispatchEvent(input);
+var change = document.createEvent('Event');
+change.initEvent('change', true, false);
+el.dispatchEvent(change);
+`
+
+func (elem *remoteWE) SetText(text string) error {
+	if err := elem.Clear(); err != nil {
+		return err
+	}
+	return elem.insertText(text, false)
+}
+
+func (elem *remoteWE) AppendText(text string) error {
+	return elem.insertText(text, true)
+}
+
+func (elem *remoteWE) insertText(text string, appendText bool) error {
+	wd := elem.parent
+	if text == "" {
+		return nil
+	}
+	if !isKeyActionText(text) {
+		_, err := wd.ExecuteScript(insertTextScript, []interface{}{elem, text, appendText})
+		return err
+	}
+	if _, err := wd.ExecuteScript(focusEndScript, []interface{}{elem}); err != nil {
+		return err
+	}
+	if !wd.w3cCompatible {
+		return elem.SendKeys(text)
+	}
+	actions := make([]map[string]interface{}, 0, 2*len(text))
+	for _, r := range text {
+		actions = append(actions, KeyDownAction(string(r)), KeyUpAction(string(r)))
+	}
+	return wd.voidCommand("/session/%s/actions", map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{
+				"type":    "key",
+				"id":      DefaultKeyboard,
+				"actions": actions,
+			}},
+	})
+}
+
 func (wd *remoteWD) processKeyString(keys string) interface{} {
 	if !wd.w3cCompatible {
-		chars := make([]string, len(keys))
-		for i, c := range keys {
-			chars[i] = string(c)
+		// Ranging over the string yields whole characters, so characters
+		// outside the Basic Multilingual Plane are not split into surrogates.
+		// Invalid bytes are sent as U+FFFD.
+		chars := make([]string, 0, len(keys))
+		for _, c := range keys {
+			chars = append(chars, string(c))
 		}
 		return map[string][]string{"value": chars}
 	}
@@ -1419,6 +2198,9 @@ func (elem *remoteWE) Clear() error {
 }
 
 func (elem *remoteWE) MoveTo(xOffset, yOffset int) error {
+	if elem.parent.w3cCompatible {
+		return elem.parent.PerformActionChain(NewActionChain().MoveToElement(elem, xOffset, yOffset))
+	}
 	return elem.parent.voidCommand("/session/%s/moveto", map[string]interface{}{
 		"element": elem.id,
 		"xoffset": xOffset,
@@ -0,0 +1,549 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// fakeDriver is a minimal WebDriver remote end for unit tests. It creates a
+// session with the configured capabilities and answers other commands from
+// handlers keyed by method and path, e.g. "GET /session/fake/url". Other
+// commands fail with an "unknown command" error.
+type fakeDriver struct {
+	*httptest.Server
+	capabilities map[string]interface{}
+	// legacy makes the New Session reply use the pre-W3C format.
+	legacy bool
+	// newSession, if not nil, answers the New Session requests.
+	newSession fakeHandler
+
+	mu       sync.Mutex
+	handlers map[string]fakeHandler
+	requests []string
+}
+
+// fakeHandler returns the HTTP status and the "value" of a reply.
+type fakeHandler func(body []byte) (int, interface{})
+
+const fakeSessionID = "fake"
+
+func newFakeDriver(t *testing.T, capabilities map[string]interface{}) *fakeDriver {
+	t.Helper()
+	if capabilities == nil {
+		capabilities = map[string]interface{}{}
+	}
+	d := &fakeDriver{
+		capabilities: capabilities,
+		handlers:     make(map[string]fakeHandler),
+	}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	t.Cleanup(d.Close)
+	return d
+}
+
+// handle registers a handler for a command on the fake session. path is
+// relative to the session, e.g. "/url".
+func (d *fakeDriver) handle(method, path string, h fakeHandler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[method+" /session/"+fakeSessionID+path] = h
+}
+
+// count returns how many requests were received for a command on the fake
+// session.
+func (d *fakeDriver) count(method, path string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for _, r := range d.requests {
+		if r == method+" /session/"+fakeSessionID+path {
+			n++
+		}
+	}
+	return n
+}
+
+func (d *fakeDriver) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	key := r.Method + " " + r.URL.EscapedPath()
+
+	d.mu.Lock()
+	d.requests = append(d.requests, key)
+	h, ok := d.handlers[key]
+	d.mu.Unlock()
+
+	status, value := http.StatusOK, interface{}(nil)
+	switch {
+	case ok:
+		status, value = h(body)
+	case key == "POST /session" && d.newSession != nil:
+		status, value = d.newSession(body)
+	case key == "POST /session" && d.legacy:
+		v := map[string]interface{}{"sessionId": fakeSessionID}
+		for k, c := range d.capabilities {
+			v[k] = c
+		}
+		value = v
+	case key == "POST /session":
+		value = map[string]interface{}{
+			"sessionId":    fakeSessionID,
+			"capabilities": d.capabilities,
+		}
+	case key == "DELETE /session/"+fakeSessionID:
+	default:
+		status, value = http.StatusNotFound, map[string]interface{}{
+			"error":   "unknown command",
+			"message": fmt.Sprintf("unknown command: %s", key),
+		}
+	}
+	w.Header().Set("Content-Type", jsonContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{"value": value})
+}
+
+// newRemote starts a session on the fake driver.
+func (d *fakeDriver) newRemote(t *testing.T) *remoteWD {
+	t.Helper()
+	wd, err := NewRemote(nil, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	return wd.(*remoteWD)
+}
+
+func TestIsKeyActionText(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   string
+		want bool
+	}{
+		{
+			desc: "ASCII",
+			in:   "golang",
+			want: true,
+		},
+		{
+			desc: "BMP CJK",
+			in:   "日本語",
+			want: true,
+		},
+		{
+			desc: "emoji outside the BMP",
+			in:   "ok \U0001F44D",
+			want: false,
+		},
+		{
+			desc: "combining acute accent",
+			in:   "cafe\u0301",
+			want: false,
+		},
+		{
+			desc: "precomposed accent",
+			in:   "caf\u00e9",
+			want: true,
+		},
+		{
+			desc: "newline",
+			in:   "a\nb",
+			want: false,
+		},
+		{
+			desc: "WebDriver special key",
+			in:   "a" + EnterKey,
+			want: false,
+		},
+		{
+			desc: "invalid UTF-8",
+			in:   "a\xffb",
+			want: false,
+		},
+		{
+			desc: "at the length limit",
+			in:   strings.Repeat("a", maxKeyActionText),
+			want: true,
+		},
+		{
+			desc: "10k character paste",
+			in:   strings.Repeat("0123456789", 1000),
+			want: false,
+		},
+	}
+
+	for _, test := range tests {
+		if got := isKeyActionText(test.in); got != test.want {
+			t.Errorf("%s: isKeyActionText(%q) = %t, want %t", test.desc, test.in, got, test.want)
+		}
+	}
+}
+
+func TestChromeKeyModes(t *testing.T) {
+	for _, test := range []struct {
+		desc          string
+		mode          chrome.KeyMode
+		driverVersion string
+		wantModern    bool
+		wantLegacy    bool
+	}{
+		{"both", chrome.BothKeys, "", true, true},
+		{"modern", chrome.ModernKey, "", true, false},
+		{"legacy", chrome.LegacyKey, "", false, true},
+		{"auto with ChromeDriver 2.x", chrome.AutoKey, "2.46.628388 (4a34a70827ac54148e092aafb70504c4ea7ae926)", false, true},
+		{"auto with ChromeDriver 114", chrome.AutoKey, "114.0.5735.90 (386bc09e8f4f2e025eddae123f36f6263096ae49-refs/branch-heads/5735@{#1052})", true, false},
+		{"auto without a version", chrome.AutoKey, "", true, true},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			var request struct {
+				Capabilities struct {
+					AlwaysMatch map[string]json.RawMessage
+				}
+				DesiredCapabilities map[string]json.RawMessage
+			}
+			d.handlers["POST /session"] = func(body []byte) (int, interface{}) {
+				if err := json.Unmarshal(body, &request); err != nil {
+					t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+				}
+				return http.StatusOK, map[string]interface{}{
+					"sessionId":    fakeSessionID,
+					"capabilities": map[string]interface{}{},
+				}
+			}
+			d.handlers["GET /status"] = func([]byte) (int, interface{}) {
+				return http.StatusOK, map[string]interface{}{
+					"ready": true,
+					"build": map[string]string{"version": test.driverVersion},
+				}
+			}
+
+			caps := Capabilities{"browserName": "chrome"}
+			caps.AddChrome(chrome.Capabilities{KeyMode: test.mode})
+			if _, err := NewRemote(caps, d.URL); err != nil {
+				t.Fatalf("NewRemote() returned error: %v", err)
+			}
+
+			if _, ok := request.Capabilities.AlwaysMatch[chrome.DeprecatedCapabilitiesKey]; ok {
+				t.Errorf("alwaysMatch contains %q, want it only in desiredCapabilities", chrome.DeprecatedCapabilitiesKey)
+			}
+			if _, got := request.Capabilities.AlwaysMatch[chrome.CapabilitiesKey]; got != test.wantModern {
+				t.Errorf("alwaysMatch contains %q: %t, want %t", chrome.CapabilitiesKey, got, test.wantModern)
+			}
+			if _, got := request.DesiredCapabilities[chrome.CapabilitiesKey]; got != test.wantModern {
+				t.Errorf("desiredCapabilities contains %q: %t, want %t", chrome.CapabilitiesKey, got, test.wantModern)
+			}
+			if _, got := request.DesiredCapabilities[chrome.DeprecatedCapabilitiesKey]; got != test.wantLegacy {
+				t.Errorf("desiredCapabilities contains %q: %t, want %t", chrome.DeprecatedCapabilitiesKey, got, test.wantLegacy)
+			}
+		})
+	}
+}
+
+func TestChromeDefaultKey(t *testing.T) {
+	added := Capabilities{"browserName": "chrome"}
+	added.AddChrome(chrome.Capabilities{Args: []string{"--headless=new"}})
+	for _, test := range []struct {
+		desc string
+		caps Capabilities
+	}{
+		{"AddChrome", added},
+		{"value", Capabilities{"browserName": "chrome", chrome.CapabilitiesKey: chrome.Capabilities{Args: []string{"--headless=new"}}}},
+		{"pointer", Capabilities{"browserName": "chrome", chrome.CapabilitiesKey: &chrome.Capabilities{Args: []string{"--headless=new"}}}},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			var body []byte
+			d.newSession = func(b []byte) (int, interface{}) {
+				body = b
+				return http.StatusOK, map[string]interface{}{
+					"sessionId":    fakeSessionID,
+					"capabilities": map[string]interface{}{},
+				}
+			}
+			if _, err := NewRemote(test.caps, d.URL); err != nil {
+				t.Fatalf("NewRemote() returned error: %v", err)
+			}
+			if strings.Contains(string(body), `"`+chrome.DeprecatedCapabilitiesKey+`"`) {
+				t.Errorf("the New Session request contains %q by default: %s", chrome.DeprecatedCapabilitiesKey, body)
+			}
+			if !strings.Contains(string(body), "--headless=new") {
+				t.Errorf("the New Session request does not contain the Chrome options: %s", body)
+			}
+		})
+	}
+}
+
+func TestQuitDetached(t *testing.T) {
+	detach := true
+	for _, test := range []struct {
+		desc         string
+		detach       *bool
+		closeBrowser bool
+		wantRunning  bool
+	}{
+		{"Quit", nil, false, false},
+		{"Quit with detach", &detach, false, true},
+		{"QuitAndCloseBrowser", nil, true, false},
+		{"QuitAndCloseBrowser with detach", &detach, true, false},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			// The fake driver behaves as ChromeDriver: deleting the session closes
+			// the browser, unless it was started detached.
+			d := newFakeDriver(t, nil)
+			running := true
+			d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+				var cmd struct{ Cmd string }
+				json.Unmarshal(body, &cmd)
+				if cmd.Cmd == "Browser.close" {
+					running = false
+				}
+				return http.StatusOK, map[string]interface{}{}
+			})
+			d.handle("DELETE", "", func([]byte) (int, interface{}) {
+				if !running {
+					return http.StatusInternalServerError, map[string]string{"error": "unknown error", "message": "chrome not reachable"}
+				}
+				if test.detach == nil {
+					running = false
+				}
+				return http.StatusOK, nil
+			})
+
+			caps := Capabilities{"browserName": "chrome"}
+			caps.AddChrome(chrome.Capabilities{Detach: test.detach})
+			s := new(Service)
+			wd, err := NewRemoteContext(context.Background(), caps, d.URL, WithService(s))
+			if err != nil {
+				t.Fatalf("NewRemoteContext() returned error: %v", err)
+			}
+			if got, want := s.hasDetachedSessions(), test.detach != nil; got != want {
+				t.Errorf("s.hasDetachedSessions() = %t, want %t", got, want)
+			}
+			dir, err := ioutil.TempDir("", "selenium-quit")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			wd.(*remoteWD).tempDirs = []string{dir}
+
+			quit := wd.Quit
+			if test.closeBrowser {
+				quit = wd.QuitAndCloseBrowser
+			}
+			if err := quit(); err != nil {
+				t.Fatalf("quitting returned error: %v", err)
+			}
+			if got := d.count("DELETE", ""); got != 1 {
+				t.Errorf("the session was deleted %d times, want 1", got)
+			}
+			if running != test.wantRunning {
+				t.Errorf("the browser is running = %t after quitting, want %t", running, test.wantRunning)
+			}
+			if id := wd.SessionID(); id != "" {
+				t.Errorf("wd.SessionID() = %q after quitting, want an empty ID", id)
+			}
+			if _, err := os.Stat(dir); !os.IsNotExist(err) {
+				t.Errorf("the temporary directory %s was not removed: %v", dir, err)
+			}
+		})
+	}
+}
+
+func TestNewRemoteDeletesSessionOnSetupError(t *testing.T) {
+	// The fake driver does not implement the DevTools commands that install
+	// the init scripts.
+	d := newFakeDriver(t, nil)
+	var c chrome.Capabilities
+	if err := c.SeedLocalStorage("https://app.example", map[string]string{"theme": "dark"}); err != nil {
+		t.Fatal(err)
+	}
+	caps := Capabilities{"browserName": "chrome"}
+	caps.AddChrome(c)
+	if _, err := NewRemote(caps, d.URL); err == nil {
+		t.Fatal("NewRemote() returned no error, want the init scripts to fail")
+	}
+	if n := d.count("DELETE", ""); n != 1 {
+		t.Errorf("NewRemote() deleted the session %d times after its setup failed, want once", n)
+	}
+}
+
+func TestDetachedSessionAtServiceURL(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	// The service is registered as running at the URL of the fake driver.
+	s := &Service{addr: d.URL}
+	leaks.Lock()
+	leaks.services[s] = ServiceLeak{}
+	leaks.Unlock()
+	defer untrackService(s)
+
+	detach := true
+	caps := Capabilities{"browserName": "chrome"}
+	caps.AddChrome(chrome.Capabilities{Detach: &detach})
+	if _, err := NewRemote(caps, d.URL+"/"); err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	if !s.hasDetachedSessions() {
+		t.Error("s.hasDetachedSessions() = false after a detached session was created at s.URLPrefix() without WithService, want true")
+	}
+}
+
+func TestNormalizeUTF8(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		in   string
+		want string
+	}{
+		{"ASCII", "abc", "abc"},
+		{"UTF-8 emoji", "a\U0001F600b", "a\U0001F600b"},
+		{"CESU-8 emoji", "a\xed\xa0\xbd\xed\xb8\x80b", "a\U0001F600b"},
+		{"CESU-8 CJK extension B", "\xed\xa1\x80\xed\xb0\x8b", "\U0002000B"},
+		{"lone high surrogate", "a\xed\xa0\xbdb", "a\uFFFDb"},
+		{"lone low surrogate", "a\xed\xb8\x80b", "a\uFFFDb"},
+		{"reversed pair", "\xed\xb8\x80\xed\xa0\xbd", "\uFFFD\uFFFD"},
+		{"invalid byte", "a\xffb", "a\uFFFDb"},
+	} {
+		if got := string(normalizeUTF8([]byte(test.in))); got != test.want {
+			t.Errorf("%s: normalizeUTF8(%q) = %q, want %q", test.desc, test.in, got, test.want)
+		}
+	}
+}
+
+func TestTextNonBMP(t *testing.T) {
+	for _, test := range []struct {
+		desc  string
+		reply string
+		want  string
+	}{
+		{"UTF-8", "\"\U0001F600 \U0002000B\"", "\U0001F600 \U0002000B"},
+		{"escaped surrogate pairs", `"\ud83d\ude00 \ud840\udc0b"`, "\U0001F600 \U0002000B"},
+		{"escaped lone surrogate", `"a\ud83db"`, "a\uFFFDb"},
+		{"CESU-8", "\"\xed\xa0\xbd\xed\xb8\x80\"", "\U0001F600"},
+	} {
+		for _, legacy := range []bool{false, true} {
+			t.Run(fmt.Sprintf("%s/legacy=%t", test.desc, legacy), func(t *testing.T) {
+				d := newFakeDriver(t, nil)
+				d.legacy = legacy
+				d.handle("GET", "/element/e/text", func([]byte) (int, interface{}) {
+					return http.StatusOK, json.RawMessage(test.reply)
+				})
+				d.handle("GET", "/element/e/attribute/title", func([]byte) (int, interface{}) {
+					return http.StatusOK, json.RawMessage(test.reply)
+				})
+				elem := d.newRemote(t).ElementFromID("e")
+
+				text, err := elem.Text()
+				if err != nil {
+					t.Fatalf("elem.Text() returned error: %v", err)
+				}
+				if text != test.want {
+					t.Errorf("elem.Text() = %q, want %q", text, test.want)
+				}
+				title, err := elem.GetAttribute("title")
+				if err != nil {
+					t.Fatalf("elem.GetAttribute(%q) returned error: %v", "title", err)
+				}
+				if title != test.want {
+					t.Errorf("elem.GetAttribute(%q) = %q, want %q", "title", title, test.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSendKeysNonBMP(t *testing.T) {
+	const keys = "a\U0001F600é"
+	for _, test := range []struct {
+		legacy bool
+		want   string
+	}{
+		{false, `{"text":"a` + "\U0001F600" + `é"}`},
+		{true, `{"value":["a","` + "\U0001F600" + `","é"]}`},
+	} {
+		d := newFakeDriver(t, nil)
+		d.legacy = test.legacy
+		var got string
+		d.handle("POST", "/element/e/value", func(body []byte) (int, interface{}) {
+			got = string(body)
+			return http.StatusOK, nil
+		})
+		if err := d.newRemote(t).ElementFromID("e").SendKeys(keys); err != nil {
+			t.Fatalf("legacy=%t: elem.SendKeys(%q) returned error: %v", test.legacy, keys, err)
+		}
+		if got != test.want {
+			t.Errorf("legacy=%t: elem.SendKeys(%q) sent %s, want %s", test.legacy, keys, got, test.want)
+		}
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, path, want string
+	}{
+		{"http://localhost:4444/wd/hub", "/status", "http://localhost:4444/wd/hub/status"},
+		{"http://localhost:4444/wd/hub/", "/status", "http://localhost:4444/wd/hub/status"},
+		{"http://localhost:9515", "/session", "http://localhost:9515/session"},
+		{"http://localhost:9515/", "/session", "http://localhost:9515/session"},
+		{"https://ci.example.com/selenium/wd/hub/", "/session/1/goog/cdp/execute", "https://ci.example.com/selenium/wd/hub/session/1/goog/cdp/execute"},
+		{"https://grid.example.com/wd/hub?token=s3cret", "/session/1/url", "https://grid.example.com/wd/hub/session/1/url?token=s3cret"},
+		{"http://localhost:4444/wd/hub", "/session/1/local_storage/key/a%2Fb", "http://localhost:4444/wd/hub/session/1/local_storage/key/a%2Fb"},
+	} {
+		if got := joinURL(tc.prefix, tc.path); got != tc.want {
+			t.Errorf("joinURL(%q, %q) = %q, want %q", tc.prefix, tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	for _, tc := range []struct {
+		desc, base, prefix string
+		opts               []SessionOption
+	}{
+		{desc: "root", base: "/", prefix: ""},
+		{desc: "root with a slash", base: "/", prefix: "/"},
+		{desc: "hub", base: "/wd/hub/", prefix: "/wd/hub"},
+		{desc: "hub with a slash", base: "/wd/hub/", prefix: "/wd/hub/"},
+		{desc: "reverse proxy", base: "/selenium/wd/hub/", prefix: "/selenium/wd/hub/"},
+		{desc: "base path", base: "/gateway/", prefix: "/public/wd/hub", opts: []SessionOption{WithBasePath("/gateway")}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+				return http.StatusOK, ""
+			})
+			d.handle("POST", "/goog/cdp/execute", func([]byte) (int, interface{}) {
+				return http.StatusOK, map[string]interface{}{}
+			})
+			mux := http.NewServeMux()
+			mux.Handle(tc.base, http.StripPrefix(strings.TrimSuffix(tc.base, "/"), http.HandlerFunc(d.serveHTTP)))
+			s := httptest.NewServer(mux)
+			defer s.Close()
+
+			wd, err := NewRemoteContext(context.Background(), nil, s.URL+tc.prefix, tc.opts...)
+			if err != nil {
+				t.Fatalf("NewRemoteContext() returned error: %v", err)
+			}
+			if _, err := wd.Screenshot(); err != nil {
+				t.Errorf("wd.Screenshot() returned error: %v", err)
+			}
+			if _, err := wd.(*remoteWD).executeCDP("Browser.getVersion", nil); err != nil {
+				t.Errorf("wd.executeCDP() returned error: %v", err)
+			}
+			if err := wd.Quit(); err != nil {
+				t.Errorf("wd.Quit() returned error: %v", err)
+			}
+			for _, r := range d.requests {
+				if strings.Contains(r, "//") {
+					t.Errorf("the remote end received a request with an empty path segment: %s", r)
+				}
+			}
+		})
+	}
+}
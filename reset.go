@@ -0,0 +1,231 @@
+package selenium
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ResetLevel is how thoroughly ResetState clears the state of the browser.
+type ResetLevel int
+
+// Levels of ResetState, each clearing what the previous one does and more.
+const (
+	// ResetLight closes the windows other than the current one and clears
+	// the cookies and the web storage of the current origin.
+	ResetLight ResetLevel = iota
+	// ResetStandard also clears the service workers, caches, IndexedDB and
+	// other storage of the origins visited since the last reset.
+	ResetStandard
+	// ResetFull also resets the permissions granted and moves the session to
+	// a new browser context, like a new incognito window.
+	ResetFull
+)
+
+func (l ResetLevel) String() string {
+	switch l {
+	case ResetLight:
+		return "light"
+	case ResetStandard:
+		return "standard"
+	case ResetFull:
+		return "full"
+	}
+	return fmt.Sprintf("ResetLevel(%d)", int(l))
+}
+
+// ResetError is returned by ResetState when some of the state could not be
+// cleared on the remote end, e.g. for lack of the Chrome DevTools Protocol.
+// The rest of the state was cleared: a session pool may use the session
+// anyway or replace it.
+type ResetError struct {
+	Level ResetLevel
+	// Uncleared describes the state left, e.g. "permissions: not supported
+	// by the remote end".
+	Uncleared []string
+}
+
+func (e *ResetError) Error() string {
+	return fmt.Sprintf("%s reset of the browser state left %s", e.Level, strings.Join(e.Uncleared, "; "))
+}
+
+// clearWebStorageScript clears the web storage of the current origin.
+const clearWebStorageScript = `
+	try {
+		window.localStorage.clear();
+		window.sessionStorage.clear();
+	} catch (e) {
+		// Pages without an origin, e.g. about:blank, have no storage.
+	}`
+
+// clearServiceWorkersScript unregisters the service workers and deletes the
+// caches of the current origin.
+const clearServiceWorkersScript = `
+	var done = arguments[arguments.length - 1];
+	var tasks = [];
+	if (navigator.serviceWorker && navigator.serviceWorker.getRegistrations) {
+		tasks.push(navigator.serviceWorker.getRegistrations().then(function(regs) {
+			return Promise.all(regs.map(function(r) { return r.unregister(); }));
+		}));
+	}
+	if (window.caches) {
+		tasks.push(caches.keys().then(function(keys) {
+			return Promise.all(keys.map(function(k) { return caches.delete(k); }));
+		}));
+	}
+	Promise.all(tasks).then(function() { done(null); }, function(e) { done(String(e)); });`
+
+// visit records the origin of url as visited, for ResetState.
+func (wd *remoteWD) visit(url string) {
+	origin, err := parseOrigin(url)
+	if err != nil {
+		return
+	}
+	wd.visitedMu.Lock()
+	defer wd.visitedMu.Unlock()
+	if wd.visited == nil {
+		wd.visited = make(map[string]bool)
+	}
+	wd.visited[origin] = true
+}
+
+// ResetState clears the state of the browser left by a test.
+func (wd *remoteWD) ResetState(level ResetLevel) error {
+	var uncleared []string
+	leave := func(what string, err error) {
+		uncleared = append(uncleared, fmt.Sprintf("%s: %v", what, err))
+	}
+	cdp := wd.Supports(FeatureCDP)
+
+	wd.closeContexts()
+	if err := wd.closeOtherWindows(); err != nil {
+		return err
+	}
+	if current, err := wd.CurrentURL(); err == nil {
+		wd.visit(current)
+	}
+	wd.visitedMu.Lock()
+	var origins []string
+	for o := range wd.visited {
+		origins = append(origins, o)
+	}
+	wd.visitedMu.Unlock()
+	sort.Strings(origins)
+
+	if _, err := wd.ExecuteScript(clearWebStorageScript, nil); err != nil {
+		leave("web storage", err)
+	}
+	if err := wd.DeleteAllCookies(); err != nil {
+		return err
+	}
+	if cdp {
+		// WebDriver only deletes the cookies of the current document.
+		if _, err := wd.executeCDP("Network.clearBrowserCookies", nil); err != nil {
+			leave("cookies of other sites", err)
+		}
+	}
+
+	if level >= ResetStandard {
+		if cdp {
+			for _, o := range origins {
+				if _, err := wd.executeCDP("Storage.clearDataForOrigin", map[string]interface{}{
+					"origin":       o,
+					"storageTypes": SiteDataAll,
+				}); err != nil {
+					leave("storage of "+o, err)
+				}
+			}
+			if _, err := wd.executeCDP("Network.clearBrowserCache", nil); err != nil {
+				leave("HTTP cache", err)
+			}
+		} else {
+			if msg, err := wd.ExecuteScriptAsync(clearServiceWorkersScript, nil); err != nil {
+				leave("service workers and caches", err)
+			} else if msg != nil {
+				leave("service workers and caches", fmt.Errorf("%v", msg))
+			}
+			if len(origins) > 1 {
+				leave("storage of the other origins visited", ErrUnsupported)
+			}
+			leave("HTTP cache", ErrUnsupported)
+		}
+	}
+
+	if level >= ResetFull {
+		if cdp {
+			if _, err := wd.executeCDP("Browser.resetPermissions", nil); err != nil {
+				leave("permissions", err)
+			}
+			if err := wd.newBrowserContext(); err != nil {
+				leave("browser context", err)
+			}
+		} else {
+			leave("permissions", ErrUnsupported)
+			leave("browser context", ErrUnsupported)
+		}
+	}
+
+	if err := wd.Get("about:blank"); err != nil {
+		return err
+	}
+	wd.visitedMu.Lock()
+	wd.visited = nil
+	wd.visitedMu.Unlock()
+	if len(uncleared) > 0 {
+		return &ResetError{Level: level, Uncleared: uncleared}
+	}
+	return nil
+}
+
+// closeOtherWindows closes the windows other than the current one, or than
+// the first one if the current window was closed.
+func (wd *remoteWD) closeOtherWindows() error {
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return err
+	}
+	current, err := wd.CurrentWindowHandle()
+	if err != nil && !isNoSuchWindowError(err) {
+		return err
+	}
+	if current == "" && len(handles) > 0 {
+		current = handles[0]
+	}
+	for _, h := range handles {
+		if h == current {
+			continue
+		}
+		if err := wd.SwitchWindow(h); err != nil {
+			if isNoSuchWindowError(err) {
+				continue
+			}
+			return err
+		}
+		if err := wd.Close(); err != nil && !isNoSuchWindowError(err) {
+			return err
+		}
+	}
+	return wd.SwitchWindow(current)
+}
+
+// newBrowserContext opens a window in a new browser context, switches to it
+// and closes the other windows, disposing of the browser context created by
+// the previous call, if any.
+func (wd *remoteWD) newBrowserContext() error {
+	id, handle, err := wd.createBrowserContext()
+	if err != nil {
+		return err
+	}
+	if err := wd.SwitchWindow(handle); err != nil {
+		wd.disposeBrowserContext(id)
+		return err
+	}
+	if err := wd.closeOtherWindows(); err != nil {
+		return err
+	}
+	if wd.browserContext != "" {
+		wd.disposeBrowserContext(wd.browserContext)
+	}
+	wd.browserContext = id
+	return nil
+}
@@ -0,0 +1,207 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeBrowserState serves the windows, navigation and DevTools commands of a
+// fake browser for ResetState.
+type fakeBrowserState struct {
+	url     string
+	current string
+	windows []string
+	closed  []string
+	cdp     []string
+	cleared []string
+	// listNewTargets makes the driver list the windows of new browser
+	// contexts.
+	listNewTargets bool
+}
+
+func (f *fakeBrowserState) handle(d *fakeDriver) {
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.url
+	})
+	d.handle("POST", "/url", func(body []byte) (int, interface{}) {
+		var params struct{ URL string }
+		json.Unmarshal(body, &params)
+		f.url = params.URL
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/window/handles", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.windows
+	})
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.current
+	})
+	d.handle("POST", "/window", func(body []byte) (int, interface{}) {
+		var params struct{ Handle string }
+		json.Unmarshal(body, &params)
+		f.current = params.Handle
+		return http.StatusOK, nil
+	})
+	d.handle("DELETE", "/window", func([]byte) (int, interface{}) {
+		var left []string
+		for _, w := range f.windows {
+			if w != f.current {
+				left = append(left, w)
+			}
+		}
+		f.windows = left
+		f.closed = append(f.closed, f.current)
+		return http.StatusOK, left
+	})
+	d.handle("DELETE", "/cookie", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/async", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params struct{ Origin string }
+		}
+		json.Unmarshal(body, &params)
+		f.cdp = append(f.cdp, params.Cmd)
+		switch params.Cmd {
+		case "Storage.clearDataForOrigin":
+			f.cleared = append(f.cleared, params.Params.Origin)
+		case "Target.createBrowserContext":
+			return http.StatusOK, map[string]interface{}{"browserContextId": "context"}
+		case "Target.createTarget":
+			if f.listNewTargets {
+				f.windows = append(f.windows, "target")
+			}
+			return http.StatusOK, map[string]interface{}{"targetId": "target"}
+		}
+		return http.StatusOK, map[string]interface{}{}
+	})
+}
+
+func TestResetState(t *testing.T) {
+	f := &fakeBrowserState{url: "about:blank", current: "main", windows: []string{"main", "popup"}}
+	d := newFakeDriver(t, nil)
+	f.handle(d)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, false)
+
+	if err := wd.Get("http://a.example/page"); err != nil {
+		t.Fatal(err)
+	}
+	f.url = "http://b.example/"
+
+	if err := wd.ResetState(ResetLight); err != nil {
+		t.Fatalf("wd.ResetState(ResetLight) returned error: %v", err)
+	}
+	if want := []string{"popup"}; !reflect.DeepEqual(f.closed, want) {
+		t.Errorf("wd.ResetState(ResetLight) closed windows %q, want %q", f.closed, want)
+	}
+	if f.current != "main" {
+		t.Errorf("wd.ResetState(ResetLight) left window %q current, want %q", f.current, "main")
+	}
+	if f.url != "about:blank" {
+		t.Errorf("wd.ResetState(ResetLight) left the browser on %q, want about:blank", f.url)
+	}
+	if n := d.count("DELETE", "/cookie"); n != 1 {
+		t.Errorf("wd.ResetState(ResetLight) deleted the cookies %d times, want 1", n)
+	}
+
+	if err := wd.Get("http://a.example/page"); err != nil {
+		t.Fatal(err)
+	}
+	f.url = "http://b.example/"
+	err := wd.ResetState(ResetFull)
+	var resetErr *ResetError
+	if !errors.As(err, &resetErr) {
+		t.Fatalf("wd.ResetState(ResetFull) without CDP returned %v, want a *ResetError", err)
+	}
+	var uncleared []string
+	for _, u := range resetErr.Uncleared {
+		uncleared = append(uncleared, strings.SplitN(u, ":", 2)[0])
+	}
+	want := []string{"storage of the other origins visited", "HTTP cache", "permissions", "browser context"}
+	if !reflect.DeepEqual(uncleared, want) {
+		t.Errorf("wd.ResetState(ResetFull) without CDP left %q, want %q", uncleared, want)
+	}
+	if n := d.count("POST", "/execute/async"); n != 1 {
+		t.Errorf("wd.ResetState(ResetFull) without CDP ran %d scripts to clear the service workers, want 1", n)
+	}
+}
+
+func TestResetStateCDP(t *testing.T) {
+	f := &fakeBrowserState{url: "about:blank", current: "main", windows: []string{"main"}, listNewTargets: true}
+	d := newFakeDriver(t, nil)
+	f.handle(d)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	if err := wd.Get("http://a.example/page"); err != nil {
+		t.Fatal(err)
+	}
+	f.url = "http://b.example/"
+
+	if err := wd.ResetState(ResetStandard); err != nil {
+		t.Fatalf("wd.ResetState(ResetStandard) returned error: %v", err)
+	}
+	if want := []string{"http://a.example", "http://b.example"}; !reflect.DeepEqual(f.cleared, want) {
+		t.Errorf("wd.ResetState(ResetStandard) cleared the storage of %q, want %q", f.cleared, want)
+	}
+
+	f.cdp, f.cleared = nil, nil
+	if err := wd.ResetState(ResetStandard); err != nil {
+		t.Fatalf("wd.ResetState(ResetStandard) returned error: %v", err)
+	}
+	if len(f.cleared) != 0 {
+		t.Errorf("wd.ResetState(ResetStandard) after a reset cleared the storage of %q, want none", f.cleared)
+	}
+
+	f.cdp = nil
+	if err := wd.ResetState(ResetFull); err != nil {
+		t.Fatalf("wd.ResetState(ResetFull) returned error: %v", err)
+	}
+	want := []string{
+		"Network.clearBrowserCookies",
+		"Network.clearBrowserCache",
+		"Browser.resetPermissions",
+		"Target.createBrowserContext",
+		"Target.createTarget",
+	}
+	if !reflect.DeepEqual(f.cdp, want) {
+		t.Errorf("wd.ResetState(ResetFull) sent %q, want %q", f.cdp, want)
+	}
+	if f.current != "target" || !reflect.DeepEqual(f.windows, []string{"target"}) {
+		t.Errorf("wd.ResetState(ResetFull) left windows %q with %q current, want only %q", f.windows, f.current, "target")
+	}
+}
+
+func TestResetStateUnlistedContext(t *testing.T) {
+	defer func(timeout time.Duration) { contextTargetTimeout = timeout }(contextTargetTimeout)
+	contextTargetTimeout = 0
+	f := &fakeBrowserState{url: "about:blank", current: "main", windows: []string{"main"}}
+	d := newFakeDriver(t, nil)
+	f.handle(d)
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	err := wd.ResetState(ResetFull)
+	var resetErr *ResetError
+	if !errors.As(err, &resetErr) || len(resetErr.Uncleared) != 1 || !strings.HasPrefix(resetErr.Uncleared[0], "browser context:") {
+		t.Fatalf("wd.ResetState(ResetFull) returned %v, want a *ResetError for the browser context", err)
+	}
+	if last := f.cdp[len(f.cdp)-1]; last != "Target.disposeBrowserContext" {
+		t.Errorf("wd.ResetState(ResetFull) did not dispose of the unused browser context; last command %q", last)
+	}
+	if f.current != "main" {
+		t.Errorf("wd.ResetState(ResetFull) left window %q current, want %q", f.current, "main")
+	}
+}
@@ -0,0 +1,271 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SlowestResources is the number of resources listed in
+// ResourceSummary.Slowest.
+var SlowestResources = 10
+
+// ResourceSummary describes the weight of the current page, as reported by
+// the browser's Resource Timing and Navigation Timing APIs.
+type ResourceSummary struct {
+	// Requests is the number of resources fetched by the page.
+	Requests int
+	// TransferSize is the number of bytes transferred over the network for
+	// the resources that report it, including headers. Cross-origin resources
+	// served without a Timing-Allow-Origin header do not report it.
+	TransferSize int64
+	// UnknownTransferSize is the number of resources that did not report
+	// their transfer size.
+	UnknownTransferSize int
+	// ByInitiator breaks down the resources by the type of element or API
+	// that requested them, e.g. "script", "img" or "fetch".
+	ByInitiator map[string]ResourceStats
+	// ByContentType breaks down the resources by the kind of content inferred
+	// from the extension of their URL, e.g. "script", "image" or "font".
+	ByContentType map[string]ResourceStats
+	// Slowest lists the resources that took longest to fetch, slowest first.
+	Slowest []ResourceTiming
+	// Navigation holds the milestones of the page load, if reported.
+	Navigation NavigationTiming
+}
+
+// ResourceStats aggregates a group of resources.
+type ResourceStats struct {
+	Requests     int
+	TransferSize int64
+}
+
+// ResourceTiming describes the fetch of a single resource.
+type ResourceTiming struct {
+	URL           string
+	InitiatorType string
+	ContentType   string
+	Duration      time.Duration
+	// TransferSize is -1 if the resource did not report it.
+	TransferSize int64
+}
+
+// NavigationTiming holds the milestones of a page load, relative to the
+// start of the navigation. Milestones that have not been reached are zero.
+type NavigationTiming struct {
+	ResponseStart    time.Duration
+	DOMInteractive   time.Duration
+	DOMContentLoaded time.Duration
+	Load             time.Duration
+}
+
+// resourceBufferScript raises the size of the resource timing buffer and
+// keeps the entries that the page clears or that overflow the buffer, so that
+// ResourceSummary sees every resource.
+const resourceBufferScript = `(function() {
+	var perf = window.performance;
+	if (!perf || !perf.getEntriesByType || window.__seleniumResourceTimings) {
+		return;
+	}
+	var saved = window.__seleniumResourceTimings = [];
+	var save = function() {
+		Array.prototype.push.apply(saved, perf.getEntriesByType('resource'));
+	};
+	if (perf.setResourceTimingBufferSize) {
+		perf.setResourceTimingBufferSize(10000);
+	}
+	var clear = perf.clearResourceTimings;
+	if (!clear) {
+		return;
+	}
+	perf.clearResourceTimings = function() {
+		save();
+		return clear.apply(perf, arguments);
+	};
+	if (perf.addEventListener) {
+		perf.addEventListener('resourcetimingbufferfull', function() {
+			save();
+			clear.apply(perf);
+		});
+	}
+})();`
+
+const resourceSummaryScript = `
+	var perf = window.performance;
+	if (!perf || !perf.getEntriesByType) {
+		return {resources: [], navigation: null};
+	}
+	var entries = (window.__seleniumResourceTimings || []).concat(perf.getEntriesByType('resource'));
+	var resources = entries.map(function(e) {
+		return {
+			name: e.name,
+			initiatorType: e.initiatorType,
+			duration: e.duration,
+			transferSize: typeof e.transferSize === 'number' ? e.transferSize : -1
+		};
+	});
+	var navigation = null;
+	var nav = perf.getEntriesByType('navigation')[0];
+	if (nav) {
+		navigation = {
+			responseStart: nav.responseStart,
+			domInteractive: nav.domInteractive,
+			domContentLoaded: nav.domContentLoadedEventEnd,
+			load: nav.loadEventEnd
+		};
+	} else if (perf.timing) {
+		var t = perf.timing;
+		var since = function(v) { return v > 0 ? v - t.navigationStart : 0; };
+		navigation = {
+			responseStart: since(t.responseStart),
+			domInteractive: since(t.domInteractive),
+			domContentLoaded: since(t.domContentLoadedEventEnd),
+			load: since(t.loadEventEnd)
+		};
+	}
+	return {resources: resources, navigation: navigation};`
+
+// EnableResourceSummary makes the browser record every resource of the pages
+// loaded from now on, for ResourceSummary.
+func (wd *remoteWD) EnableResourceSummary() error {
+	if wd.Supports(FeatureCDP) {
+		if _, err := wd.addInitScript(resourceBufferScript); err != nil {
+			return err
+		}
+	}
+	_, err := wd.ExecuteScript(resourceBufferScript, nil)
+	return err
+}
+
+// ResourceSummary returns a summary of the resources fetched by the current
+// page.
+func (wd *remoteWD) ResourceSummary() (ResourceSummary, error) {
+	data, err := wd.ExecuteScriptRaw(resourceSummaryScript, nil)
+	if err != nil {
+		return ResourceSummary{}, err
+	}
+	reply := new(struct {
+		Value struct {
+			Resources  []rawResourceTiming
+			Navigation *struct {
+				ResponseStart, DOMInteractive, DOMContentLoaded, Load float64
+			}
+		}
+	})
+	if err := json.Unmarshal(data, reply); err != nil {
+		return ResourceSummary{}, err
+	}
+	s := summarizeResources(reply.Value.Resources, SlowestResources)
+	if n := reply.Value.Navigation; n != nil {
+		s.Navigation = NavigationTiming{
+			ResponseStart:    millis(n.ResponseStart),
+			DOMInteractive:   millis(n.DOMInteractive),
+			DOMContentLoaded: millis(n.DOMContentLoaded),
+			Load:             millis(n.Load),
+		}
+	}
+	return s, nil
+}
+
+// rawResourceTiming is a resource timing entry as returned by
+// resourceSummaryScript.
+type rawResourceTiming struct {
+	Name          string
+	InitiatorType string
+	Duration      float64
+	TransferSize  int64
+}
+
+func summarizeResources(entries []rawResourceTiming, slowest int) ResourceSummary {
+	s := ResourceSummary{
+		Requests:      len(entries),
+		ByInitiator:   make(map[string]ResourceStats),
+		ByContentType: make(map[string]ResourceStats),
+	}
+	timings := make([]ResourceTiming, 0, len(entries))
+	for _, e := range entries {
+		t := ResourceTiming{
+			URL:           e.Name,
+			InitiatorType: e.InitiatorType,
+			ContentType:   contentTypeOf(e.Name),
+			Duration:      millis(e.Duration),
+			TransferSize:  e.TransferSize,
+		}
+		timings = append(timings, t)
+
+		size := t.TransferSize
+		if size < 0 {
+			s.UnknownTransferSize++
+			size = 0
+		}
+		s.TransferSize += size
+		addResourceStats(s.ByInitiator, t.InitiatorType, size)
+		addResourceStats(s.ByContentType, t.ContentType, size)
+	}
+
+	sort.SliceStable(timings, func(i, j int) bool {
+		return timings[i].Duration > timings[j].Duration
+	})
+	if len(timings) > slowest {
+		timings = timings[:slowest]
+	}
+	s.Slowest = timings
+	return s
+}
+
+func addResourceStats(m map[string]ResourceStats, key string, size int64) {
+	stats := m[key]
+	stats.Requests++
+	stats.TransferSize += size
+	m[key] = stats
+}
+
+// contentTypes maps file extensions to the kind of content they usually hold.
+var contentTypes = map[string]string{
+	".js":    "script",
+	".mjs":   "script",
+	".css":   "stylesheet",
+	".html":  "document",
+	".htm":   "document",
+	".json":  "data",
+	".xml":   "data",
+	".png":   "image",
+	".jpg":   "image",
+	".jpeg":  "image",
+	".gif":   "image",
+	".webp":  "image",
+	".avif":  "image",
+	".svg":   "image",
+	".ico":   "image",
+	".woff":  "font",
+	".woff2": "font",
+	".ttf":   "font",
+	".otf":   "font",
+	".eot":   "font",
+	".mp4":   "media",
+	".webm":  "media",
+	".mp3":   "media",
+	".ogg":   "media",
+	".wav":   "media",
+}
+
+// contentTypeOf infers the kind of content of a resource from the extension
+// of its URL.
+func contentTypeOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "other"
+	}
+	if t, ok := contentTypes[strings.ToLower(path.Ext(u.Path))]; ok {
+		return t
+	}
+	return "other"
+}
+
+// millis converts a DOMHighResTimeStamp to a Duration.
+func millis(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
@@ -0,0 +1,78 @@
+package selenium
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSummarizeResources(t *testing.T) {
+	entries := []rawResourceTiming{
+		{Name: "https://example.com/app.js?v=2", InitiatorType: "script", Duration: 120, TransferSize: 3000},
+		{Name: "https://example.com/style.CSS", InitiatorType: "link", Duration: 40, TransferSize: 500},
+		{Name: "https://cdn.example.net/logo.png", InitiatorType: "img", Duration: 300, TransferSize: -1},
+		{Name: "https://example.com/api/items", InitiatorType: "fetch", Duration: 80.5, TransferSize: 200},
+	}
+	s := summarizeResources(entries, 2)
+
+	if s.Requests != 4 {
+		t.Errorf("Requests = %d, want 4", s.Requests)
+	}
+	if s.TransferSize != 3700 {
+		t.Errorf("TransferSize = %d, want 3700", s.TransferSize)
+	}
+	if s.UnknownTransferSize != 1 {
+		t.Errorf("UnknownTransferSize = %d, want 1", s.UnknownTransferSize)
+	}
+	if got, want := s.ByInitiator["script"], (ResourceStats{Requests: 1, TransferSize: 3000}); got != want {
+		t.Errorf("ByInitiator[%q] = %+v, want %+v", "script", got, want)
+	}
+	for contentType, want := range map[string]ResourceStats{
+		"script":     {Requests: 1, TransferSize: 3000},
+		"stylesheet": {Requests: 1, TransferSize: 500},
+		"image":      {Requests: 1},
+		"other":      {Requests: 1, TransferSize: 200},
+	} {
+		if got := s.ByContentType[contentType]; got != want {
+			t.Errorf("ByContentType[%q] = %+v, want %+v", contentType, got, want)
+		}
+	}
+	if len(s.Slowest) != 2 {
+		t.Fatalf("len(Slowest) = %d, want 2", len(s.Slowest))
+	}
+	if s.Slowest[0].URL != entries[2].Name || s.Slowest[0].Duration != 300*time.Millisecond {
+		t.Errorf("Slowest[0] = %+v, want %s taking 300ms", s.Slowest[0], entries[2].Name)
+	}
+	if s.Slowest[1].URL != entries[0].Name {
+		t.Errorf("Slowest[1] = %+v, want %s", s.Slowest[1], entries[0].Name)
+	}
+}
+
+func TestResourceSummaryNavigationTiming(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"resources": []interface{}{},
+			"navigation": map[string]float64{
+				"responseStart":    12.5,
+				"domInteractive":   100,
+				"domContentLoaded": 150,
+				"load":             0,
+			},
+		}
+	})
+	wd := d.newRemote(t)
+
+	s, err := wd.ResourceSummary()
+	if err != nil {
+		t.Fatalf("wd.ResourceSummary() returned error: %v", err)
+	}
+	want := NavigationTiming{
+		ResponseStart:    12500 * time.Microsecond,
+		DOMInteractive:   100 * time.Millisecond,
+		DOMContentLoaded: 150 * time.Millisecond,
+	}
+	if s.Navigation != want {
+		t.Errorf("Navigation = %+v, want %+v", s.Navigation, want)
+	}
+}
@@ -0,0 +1,90 @@
+package selenium
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNonJSONResponse is matched by the *NonJSONResponseError returned when
+// the remote end, or something between it and the client, replies with
+// something other than JSON.
+var ErrNonJSONResponse = errors.New("non-JSON response")
+
+// maxNonJSONBody is the number of bytes of a non-JSON response body kept in a
+// NonJSONResponseError.
+const maxNonJSONBody = 500
+
+// NonJSONResponseError is returned when a reply is not JSON, which usually
+// means that a proxy or a load balancer answered instead of the remote end,
+// e.g. with a login page or a gateway error page.
+type NonJSONResponseError struct {
+	// StatusCode and Status are the HTTP status of the response.
+	StatusCode int
+	Status     string
+	// ContentType is the Content-Type header of the response.
+	ContentType string
+	// Body holds the start of the response body.
+	Body string
+	// Hint suggests a cause, if the body looks like a known kind of page.
+	Hint string
+}
+
+func (e *NonJSONResponseError) Error() string {
+	msg := fmt.Sprintf("expected a JSON response, got %s with content type %q", e.Status, e.ContentType)
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg + ": " + e.Body
+}
+
+// Is makes errors.Is(err, ErrNonJSONResponse) true.
+func (e *NonJSONResponseError) Is(target error) bool {
+	return target == ErrNonJSONResponse
+}
+
+// checkJSONResponse returns a *NonJSONResponseError if the response, whose
+// body is body, is not JSON.
+func checkJSONResponse(response *http.Response, body []byte) error {
+	contentType := response.Header.Get("Content-Type")
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err == nil && mediaType == jsonContentType {
+		trimmed := bytes.TrimSpace(body)
+		if len(trimmed) == 0 || trimmed[0] == '{' || trimmed[0] == '[' {
+			return nil
+		}
+	}
+	return newNonJSONResponseError(response, body)
+}
+
+func newNonJSONResponseError(response *http.Response, body []byte) *NonJSONResponseError {
+	if len(body) > maxNonJSONBody {
+		body = body[:maxNonJSONBody]
+		// Do not cut a character in half.
+		for len(body) > 0 && !utf8.Valid(body) {
+			body = body[:len(body)-1]
+		}
+	}
+	e := &NonJSONResponseError{
+		StatusCode:  response.StatusCode,
+		Status:      response.Status,
+		ContentType: response.Header.Get("Content-Type"),
+		Body:        string(body),
+	}
+	page := strings.ToLower(e.Body)
+	html := strings.Contains(page, "<html") || strings.Contains(page, "<!doctype html")
+	switch {
+	case html && (strings.Contains(page, "password") || strings.Contains(page, "login") || strings.Contains(page, "log in") || strings.Contains(page, "sign in")):
+		e.Hint = "this looks like a login page; a proxy may require authentication or the URL may point to the wrong service"
+	case response.StatusCode == http.StatusBadGateway || response.StatusCode == http.StatusServiceUnavailable || response.StatusCode == http.StatusGatewayTimeout ||
+		strings.Contains(page, "bad gateway") || strings.Contains(page, "gateway timeout"):
+		e.Hint = "this looks like a gateway error page; the driver may be down or unreachable from a proxy or load balancer"
+	case html:
+		e.Hint = "this is an HTML page; the URL may point to a web server or proxy rather than a WebDriver remote end"
+	}
+	return e
+}
@@ -0,0 +1,107 @@
+package selenium
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNonJSONResponse(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		status      int
+		contentType string
+		body        string
+		wantHint    string
+	}{
+		{
+			desc:        "login page",
+			status:      http.StatusOK,
+			contentType: "text/html",
+			body:        `<!DOCTYPE html><html><body><form><input type="password"></form>` + strings.Repeat("x", 1000) + `</body></html>`,
+			wantHint:    "login page",
+		},
+		{
+			desc:        "gateway error",
+			status:      http.StatusBadGateway,
+			contentType: "text/html",
+			body:        "<html><body><h1>502 Bad Gateway</h1></body></html>",
+			wantHint:    "gateway error",
+		},
+		{
+			desc:        "HTML labelled as JSON",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			body:        "<html><body>Hello</body></html>",
+			wantHint:    "HTML page",
+		},
+		{
+			desc:        "plain text",
+			status:      http.StatusInternalServerError,
+			contentType: "text/plain",
+			body:        "internal error",
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer s.Close()
+
+			_, err := NewRemote(nil, s.URL)
+			if !errors.Is(err, ErrNonJSONResponse) {
+				t.Fatalf("NewRemote() returned error %v, want %v", err, ErrNonJSONResponse)
+			}
+			var e *NonJSONResponseError
+			if !errors.As(err, &e) {
+				t.Fatalf("NewRemote() returned error %T, want a *NonJSONResponseError", err)
+			}
+			if e.StatusCode != test.status || e.ContentType != test.contentType {
+				t.Errorf("error has status %d and content type %q, want %d and %q", e.StatusCode, e.ContentType, test.status, test.contentType)
+			}
+			if len(e.Body) > maxNonJSONBody || !strings.HasPrefix(test.body, e.Body) {
+				t.Errorf("error body = %q, want the first %d bytes of %q", e.Body, maxNonJSONBody, test.body)
+			}
+			if !strings.Contains(e.Hint, test.wantHint) || (test.wantHint == "") != (e.Hint == "") {
+				t.Errorf("error hint = %q, want it to mention %q", e.Hint, test.wantHint)
+			}
+		})
+	}
+}
+
+func TestServiceReady(t *testing.T) {
+	for _, test := range []struct {
+		desc        string
+		status      int
+		contentType string
+		body        string
+		want        bool
+		wantNonJSON bool
+	}{
+		{"JSON status", http.StatusOK, "application/json; charset=utf-8", `{"value": {"ready": true}}`, true, false},
+		{"Selenium 2", http.StatusForbidden, "text/html", "<html></html>", true, false},
+		{"proxy page", http.StatusOK, "text/html", "<html><body>Please log in</body></html>", false, true},
+		{"not found", http.StatusNotFound, "text/plain", "not found", false, false},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", test.contentType)
+				w.WriteHeader(test.status)
+				w.Write([]byte(test.body))
+			}))
+			defer s.Close()
+
+			ready, err := serviceReady(s.URL + "/status")
+			if ready != test.want {
+				t.Errorf("serviceReady() = %t, %v, want %t", ready, err, test.want)
+			}
+			if got := errors.Is(err, ErrNonJSONResponse); got != test.wantNonJSON {
+				t.Errorf("serviceReady() returned error %v, want a non-JSON response error: %t", err, test.wantNonJSON)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrNoProvider is returned by ReportResult when the session does not run on
+// a cloud grid that the library knows how to report results to, such as a
+// local driver. It can be ignored.
+var ErrNoProvider = errors.New("no cloud provider detected")
+
+// Provider is a cloud grid that accepts test results through ExecuteScript.
+type Provider string
+
+// Providers detected by ReportResult.
+const (
+	SauceLabs    Provider = "Sauce Labs"
+	BrowserStack Provider = "BrowserStack"
+	LambdaTest   Provider = "LambdaTest"
+)
+
+// providers lists, for each provider, the capability holding its options and
+// the domain of its endpoints.
+var providers = []struct {
+	provider   Provider
+	capability string
+	domain     string
+}{
+	{SauceLabs, "sauce:options", "saucelabs.com"},
+	{BrowserStack, "bstack:options", "browserstack.com"},
+	{LambdaTest, "LT:Options", "lambdatest.com"},
+}
+
+// provider returns the cloud grid running the session, from its capabilities
+// or from the host of the remote end, or the empty string.
+func (wd *remoteWD) provider() Provider {
+	var host string
+	if u, err := url.Parse(wd.urlPrefix); err == nil {
+		host = u.Hostname()
+	}
+	for _, p := range providers {
+		if _, ok := wd.capabilities[p.capability]; ok {
+			return p.provider
+		}
+		if _, ok := wd.sessionCapabilities[p.capability]; ok {
+			return p.provider
+		}
+		if host == p.domain || strings.HasSuffix(host, "."+p.domain) {
+			return p.provider
+		}
+	}
+	return ""
+}
+
+// resultScripts returns the scripts that report a test result to the
+// provider.
+func resultScripts(p Provider, passed bool, reason string) ([]string, error) {
+	status := "failed"
+	if passed {
+		status = "passed"
+	}
+	switch p {
+	case SauceLabs:
+		scripts := []string{"sauce:job-result=" + status}
+		if reason != "" {
+			scripts = append(scripts, "sauce:context="+reason)
+		}
+		return scripts, nil
+	case BrowserStack:
+		cmd, err := json.Marshal(map[string]interface{}{
+			"action": "setSessionStatus",
+			"arguments": map[string]string{
+				"status": status,
+				"reason": reason,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		return []string{"browserstack_executor: " + string(cmd)}, nil
+	case LambdaTest:
+		scripts := []string{"lambda-status=" + status}
+		if reason != "" && !passed {
+			exceptions, err := json.Marshal([]string{reason})
+			if err != nil {
+				return nil, err
+			}
+			scripts = append(scripts, "lambda-exceptions="+string(exceptions))
+		}
+		return scripts, nil
+	}
+	return nil, ErrNoProvider
+}
+
+// ReportResult reports the outcome of the test to the cloud grid running the
+// session.
+func (wd *remoteWD) ReportResult(passed bool, reason string) error {
+	if wd.id == "" {
+		return errors.New("cannot report the result of a session that has ended")
+	}
+	scripts, err := resultScripts(wd.provider(), passed, reason)
+	if err != nil {
+		return err
+	}
+	for _, script := range scripts {
+		if _, err := wd.ExecuteScript(script, nil); err != nil {
+			return fmt.Errorf("reporting the result to %s: %v", wd.provider(), err)
+		}
+	}
+	wd.resultReported = true
+	return nil
+}
+
+// ReportResultOnQuit makes Quit report the result of the test with
+// ReportResult, unless it was reported already. failed is called by Quit to
+// tell whether the test failed; pass t.Failed for a *testing.T. Errors are
+// not returned by Quit, as the session is ending anyway; they are logged in
+// debug mode.
+func ReportResultOnQuit(failed func() bool) SessionOption {
+	return func(o *sessionOptions) {
+		o.failed = failed
+	}
+}
+
+// reportResultOnQuit reports the result of the test if the session was
+// created with ReportResultOnQuit.
+func (wd *remoteWD) reportResultOnQuit() {
+	if wd.failed == nil || wd.resultReported || wd.id == "" {
+		return
+	}
+	failed := wd.failed
+	wd.failed = nil
+	passed, reason := true, ""
+	if failed() {
+		passed, reason = false, "test failed"
+	}
+	if err := wd.ReportResult(passed, reason); err != nil && err != ErrNoProvider {
+		debugLog("error reporting the test result on quit: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordScripts makes d record the scripts executed on its session.
+func recordScripts(t *testing.T, d *fakeDriver) *[]string {
+	var scripts []string
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct{ Script string }
+		if err := json.Unmarshal(body, &params); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		scripts = append(scripts, params.Script)
+		return http.StatusOK, nil
+	})
+	return &scripts
+}
+
+func TestReportResult(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		caps   Capabilities
+		passed bool
+		want   []string
+	}{
+		{
+			desc:   "Sauce Labs",
+			caps:   Capabilities{"sauce:options": map[string]interface{}{}},
+			passed: false,
+			want:   []string{"sauce:job-result=failed", "sauce:context=element #total not found"},
+		},
+		{
+			desc:   "BrowserStack",
+			caps:   Capabilities{"bstack:options": map[string]interface{}{}},
+			passed: true,
+			want:   []string{`browserstack_executor: {"action":"setSessionStatus","arguments":{"reason":"element #total not found","status":"passed"}}`},
+		},
+		{
+			desc:   "LambdaTest",
+			caps:   Capabilities{"LT:Options": map[string]interface{}{}},
+			passed: false,
+			want:   []string{"lambda-status=failed", `lambda-exceptions=["element #total not found"]`},
+		},
+	} {
+		d := newFakeDriver(t, nil)
+		scripts := recordScripts(t, d)
+		wd, err := NewRemote(test.caps, d.URL)
+		if err != nil {
+			t.Fatalf("%s: NewRemote() returned error: %v", test.desc, err)
+		}
+		if err := wd.ReportResult(test.passed, "element #total not found"); err != nil {
+			t.Errorf("%s: wd.ReportResult() returned error: %v", test.desc, err)
+		}
+		if diff := cmp.Diff(test.want, *scripts); diff != "" {
+			t.Errorf("%s: wd.ReportResult() ran scripts with diff (-want/+got):\n%s", test.desc, diff)
+		}
+	}
+}
+
+func TestReportResultNoProvider(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	scripts := recordScripts(t, d)
+	wd := d.newRemote(t)
+	if err := wd.ReportResult(true, ""); err != ErrNoProvider {
+		t.Errorf("wd.ReportResult() on a local driver returned %v, want ErrNoProvider", err)
+	}
+	if len(*scripts) != 0 {
+		t.Errorf("wd.ReportResult() on a local driver ran scripts %q", *scripts)
+	}
+	wd.Quit()
+	if err := wd.ReportResult(true, ""); err == nil {
+		t.Error("wd.ReportResult() after Quit returned no error")
+	}
+}
+
+func TestReportResultOnQuit(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	scripts := recordScripts(t, d)
+	caps := Capabilities{"sauce:options": map[string]interface{}{}}
+	wd, err := NewRemoteContext(context.Background(), caps, d.URL, ReportResultOnQuit(func() bool { return true }))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if want := []string{"sauce:job-result=failed", "sauce:context=test failed"}; !cmp.Equal(want, *scripts) {
+		t.Errorf("wd.Quit() ran scripts %q, want %q", *scripts, want)
+	}
+
+	// A result reported explicitly is not reported again.
+	*scripts = nil
+	wd, err = NewRemoteContext(context.Background(), caps, d.URL, ReportResultOnQuit(func() bool { return true }))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if err := wd.ReportResult(true, ""); err != nil {
+		t.Fatalf("wd.ReportResult() returned error: %v", err)
+	}
+	wd.Quit()
+	if want := []string{"sauce:job-result=passed"}; !cmp.Equal(want, *scripts) {
+		t.Errorf("wd.ReportResult() and wd.Quit() ran scripts %q, want %q", *scripts, want)
+	}
+}
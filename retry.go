@@ -0,0 +1,141 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RetryPolicy makes a session send again the commands that fail with a
+// transient error, such as a connection reset by a proxy or a Selenium Grid
+// replying 502 Bad Gateway or 503 Service Unavailable while a node restarts.
+type RetryPolicy struct {
+	// MaxAttempts is the number of times a command is sent, including the
+	// first one. Commands are not retried if it is less than 2.
+	MaxAttempts int
+	// Backoff is the delay before the first retry, 200ms if zero, unless the
+	// remote end sets another one with a Retry-After header. It doubles
+	// after each retry, up to MaxBackoff, 5s if zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Retryable, if not nil, tells whether a command that failed with err is
+	// sent again, in place of DefaultRetryable.
+	Retryable func(cmd Command, err error) bool
+}
+
+const (
+	defaultRetryBackoff    = 200 * time.Millisecond
+	defaultMaxRetryBackoff = 5 * time.Second
+)
+
+// WithRetryPolicy makes the session retry its commands according to p. The
+// New Session request is retried with WithNewSessionRetries instead. The
+// retries count against the CommandTimeout of the Defaults of the session.
+func WithRetryPolicy(p RetryPolicy) SessionOption {
+	return func(o *sessionOptions) {
+		o.retry = &p
+	}
+}
+
+// idempotentCommands are the commands other than GET ones that can be sent
+// twice without changing the state of the page.
+var idempotentCommands = map[string]bool{
+	"POST /element":              true,
+	"POST /elements":             true,
+	"POST /element/:id/element":  true,
+	"POST /element/:id/elements": true,
+	"POST /shadow/:id/element":   true,
+	"POST /shadow/:id/elements":  true,
+}
+
+// DefaultRetryable tells whether a command that failed with err can be sent
+// again: the command must be idempotent, such as reading the state of the
+// page or finding elements, and err must be transient, i.e. the remote end
+// could not be reached or a gateway replied with HTTP 502 or 503. It is the
+// default of RetryPolicy.Retryable, which may call it for the commands it
+// does not decide on.
+func DefaultRetryable(cmd Command, err error) bool {
+	if !idempotentCommands[cmd.Type] && !strings.HasPrefix(cmd.Type, "GET ") {
+		return false
+	}
+	return isTransientError(err)
+}
+
+// isTransientError tells whether err is a failure to reach the remote end or
+// a reply of HTTP 502 or 503.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	code := 0
+	var e *Error
+	var nonJSON *NonJSONResponseError
+	var urlError *url.Error
+	switch {
+	case errors.As(err, &e):
+		code = e.HTTPCode
+	case errors.As(err, &nonJSON):
+		code = nonJSON.StatusCode
+	case errors.As(err, &urlError):
+		return true
+	}
+	return code == http.StatusBadGateway || code == http.StatusServiceUnavailable
+}
+
+// executeWithRetries sends a command with executeCommandContext, again while
+// it fails with an error that the retry policy of the session deems
+// retryable. It is called with the commands of the session locked, see
+// lockCommands, and unlocks them before it returns and while it waits to
+// retry, so that the other commands of the session are not held up.
+func (wd *remoteWD) executeWithRetries(ctx context.Context, command, method, url string, data []byte) (json.RawMessage, error) {
+	label := joinLabels(wd.currentTestName(), wd.label)
+	buf, err := executeCommandContext(ctx, wd.client, label, method, url, data)
+	wd.unlockCommands()
+	p := wd.retry
+	if err == nil || p == nil {
+		return buf, err
+	}
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	backoff, maxBackoff := p.Backoff, p.MaxBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxRetryBackoff
+	}
+	cmd := wd.newCommand(command, url)
+	for attempt := 2; attempt <= p.MaxAttempts && retryable(cmd, err); attempt++ {
+		delay := backoff
+		var e *Error
+		if errors.As(err, &e) && e.RetryAfter > 0 {
+			delay = e.RetryAfter
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		debugLog("%s%s failed with %v, retrying in %v\n", logLabel(label), command, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return buf, err
+		}
+		if wd.lockCommands(ctx) != nil {
+			return buf, err
+		}
+		buf, err = executeCommandContext(ctx, wd.client, label, method, url, data)
+		wd.unlockCommands()
+		if err == nil {
+			return buf, nil
+		}
+	}
+	return buf, err
+}
@@ -0,0 +1,138 @@
+package selenium
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	unavailable := func(n *int, value interface{}) fakeHandler {
+		return func([]byte) (int, interface{}) {
+			if *n++; *n < 3 {
+				return http.StatusServiceUnavailable, map[string]string{"error": "unknown error", "message": "no node"}
+			}
+			return http.StatusOK, value
+		}
+	}
+	var gets, posts int
+	d.handle("GET", "/url", unavailable(&gets, "about:blank"))
+	d.handle("POST", "/url", unavailable(&posts, nil))
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	if u, err := wd.CurrentURL(); err != nil || u != "about:blank" {
+		t.Errorf("wd.CurrentURL() = %q, %v, want it retried until it succeeds", u, err)
+	}
+	if gets != 3 {
+		t.Errorf("GET /url was sent %d times, want 3", gets)
+	}
+	if err := wd.Get("about:blank"); err == nil {
+		t.Error("wd.Get() returned no error, want the navigation not retried")
+	}
+	if posts != 1 {
+		t.Errorf("POST /url was sent %d times, want once", posts)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var posts int
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		posts++
+		return http.StatusBadGateway, map[string]string{"error": "unknown error", "message": "bad gateway"}
+	})
+	var retried []string
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 4,
+		Backoff:     time.Millisecond,
+		Retryable: func(cmd Command, err error) bool {
+			retried = append(retried, cmd.Type)
+			return cmd.Type == "POST /url" || DefaultRetryable(cmd, err)
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if err := wd.Get("about:blank"); err == nil {
+		t.Error("wd.Get() returned no error")
+	}
+	if posts != 4 {
+		t.Errorf("POST /url was sent %d times, want 4", posts)
+	}
+	if len(retried) != 3 || retried[0] != "POST /url" {
+		t.Errorf("Retryable was called for %q, want 3 calls for POST /url", retried)
+	}
+}
+
+func TestRetryBackoffReleasesSession(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var texts int
+	d.handle("GET", "/element/abc/text", func([]byte) (int, interface{}) {
+		if texts++; texts == 1 {
+			return http.StatusServiceUnavailable, map[string]string{"error": "unknown error", "message": "no node"}
+		}
+		return http.StatusOK, "total"
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "shop"
+	})
+	waiting := make(chan Command, 1)
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 2,
+		Backoff:     time.Second,
+		Retryable: func(cmd Command, err error) bool {
+			waiting <- cmd
+			return true
+		},
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := (&remoteWE{parent: wd.(*remoteWD), id: "abc"}).Text()
+		done <- err
+	}()
+	cmd := <-waiting
+	if e, ok := cmd.Element.(*remoteWE); !ok || e.id != "abc" || cmd.Type != "GET /element/:id/text" {
+		t.Errorf("Retryable got command %+v, want GET /element/:id/text on element abc", cmd)
+	}
+	if title, err := wd.Title(); err != nil || title != "shop" {
+		t.Errorf("wd.Title() during the backoff = %q, %v, want %q", title, err, "shop")
+	}
+	select {
+	case <-done:
+		t.Error("the retried command ended before the command sent during its backoff")
+	default:
+	}
+	if err := <-done; err != nil {
+		t.Errorf("the retried command returned error: %v", err)
+	}
+}
+
+func TestDefaultRetryable(t *testing.T) {
+	for _, test := range []struct {
+		cmd  string
+		err  error
+		want bool
+	}{
+		{"GET /title", &Error{Err: "unknown error", HTTPCode: http.StatusServiceUnavailable}, true},
+		{"POST /elements", &NonJSONResponseError{StatusCode: http.StatusBadGateway}, true},
+		{"GET /title", &Error{Err: "no such window", HTTPCode: http.StatusNotFound}, false},
+		{"POST /element/:id/click", &Error{Err: "unknown error", HTTPCode: http.StatusServiceUnavailable}, false},
+		{"GET /title", context.DeadlineExceeded, false},
+	} {
+		if got := DefaultRetryable(Command{Type: test.cmd}, test.err); got != test.want {
+			t.Errorf("DefaultRetryable(%q, %v) = %t, want %t", test.cmd, test.err, got, test.want)
+		}
+	}
+}
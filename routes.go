@@ -0,0 +1,430 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/LoveOyy/selenium/bidi"
+)
+
+// RouteMethod is how the URL of the page changed.
+type RouteMethod string
+
+// Methods of RouteChange.
+const (
+	// RoutePush is a call to history.pushState.
+	RoutePush RouteMethod = "push"
+	// RouteReplace is a call to history.replaceState.
+	RouteReplace RouteMethod = "replace"
+	// RoutePop is a traversal of the history within the document, e.g. with
+	// the Back button, or a navigation to a fragment.
+	RoutePop RouteMethod = "pop"
+	// RouteLoad is the load of a new document.
+	RouteLoad RouteMethod = "load"
+)
+
+// RouteChange is a change of the URL of the top-level document.
+type RouteChange struct {
+	Old, New string
+	Method   RouteMethod
+	Time     time.Time
+}
+
+// routesScript makes the top-level document record the changes of its URL
+// in window.__seleniumRoutes, numbered from 1, keeping the last 200 for the
+// watchers that have not read them yet. It is safe to evaluate more than
+// once in a document.
+const routesScript = `(function() {
+	if (window.__seleniumRoutes || window.top !== window) {
+		return;
+	}
+	var q = window.__seleniumRoutes = {
+		doc: Date.now().toString(36) + Math.random().toString(36).slice(2),
+		url: String(location.href),
+		current: String(location.href),
+		seq: 0,
+		events: []
+	};
+	var record = function(method) {
+		var old = q.current;
+		q.current = String(location.href);
+		q.events.push({seq: ++q.seq, method: method, old: old, 'new': q.current, time: Date.now()});
+		if (q.events.length > 200) {
+			q.events.shift();
+		}
+	};
+	['pushState', 'replaceState'].forEach(function(name) {
+		var original = history[name];
+		if (!original) {
+			return;
+		}
+		history[name] = function() {
+			var result = original.apply(this, arguments);
+			record(name === 'pushState' ? 'push' : 'replace');
+			return result;
+		};
+	});
+	window.addEventListener('popstate', function() {
+		record('pop');
+	});
+})();`
+
+// drainRoutesScript returns the changes recorded by routesScript after the
+// one numbered by its argument, and installs it in documents loaded without
+// it.
+const drainRoutesScript = routesScript + `
+	var q = window.__seleniumRoutes;
+	if (!q) {
+		return null;
+	}
+	var since = arguments[0];
+	return {
+		doc: q.doc,
+		url: q.url,
+		seq: q.seq,
+		events: q.events.filter(function(e) { return e.seq > since; })
+	};`
+
+// routesChannelScript is the function that makes the top-level document
+// send the changes of its URL, in JSON, to the BiDi channel passed as its
+// first argument. Unless its second argument is true, it reports the load of
+// the document first. It returns the URL of the document.
+const routesChannelScript = `function(send, installedLate) {
+	if (window.top !== window) {
+		return null;
+	}
+	var current = String(location.href);
+	var record = function(method) {
+		var old = current;
+		current = String(location.href);
+		send(JSON.stringify({method: method, old: old, 'new': current, time: Date.now()}));
+	};
+	if (!installedLate) {
+		send(JSON.stringify({method: 'load', 'new': current, time: Date.now()}));
+	}
+	['pushState', 'replaceState'].forEach(function(name) {
+		var original = history[name];
+		if (!original) {
+			return;
+		}
+		history[name] = function() {
+			var result = original.apply(this, arguments);
+			record(name === 'pushState' ? 'push' : 'replace');
+			return result;
+		};
+	});
+	window.addEventListener('popstate', function() {
+		record('pop');
+	});
+	return current;
+}`
+
+// routeChannels numbers the BiDi channels of the route watchers.
+var routeChannels int64
+
+// routeQueue is the reply of drainRoutesScript.
+type routeQueue struct {
+	Doc    string
+	URL    string
+	Seq    int
+	Events []struct {
+		Method   RouteMethod
+		Old, New string
+		Time     float64
+	}
+}
+
+// drainRoutes returns the route changes of the current document after the
+// one numbered since, with a request to url, the Execute Script endpoint.
+func (wd *remoteWD) drainRoutes(url string, since int) (*routeQueue, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"script": drainRoutesScript,
+		"args":   []interface{}{since},
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := wd.executeTagged(tagBackground, "POST", url, data)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value *routeQueue })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	if reply.Value == nil {
+		return nil, errors.New("the current browsing context is not a top-level document")
+	}
+	return reply.Value, nil
+}
+
+// WatchRouteChanges reports the changes of the URL of the top-level document
+// from now on, sent by the page through BiDi if it can, or else polled.
+func (wd *remoteWD) WatchRouteChanges(ctx context.Context, opts ...WatchOption) (<-chan RouteChange, error) {
+	o := watchOptions{interval: wd.defaults.PollInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if wd.Supports(FeatureBiDi) {
+		events, err := wd.watchRoutesBiDi(ctx)
+		if err == nil {
+			return events, nil
+		}
+		debugLog("error watching the routes with BiDi, polling instead: %v", err)
+	}
+	if wd.Supports(FeatureCDP) {
+		if _, err := wd.addInitScript(routesScript); err != nil {
+			return nil, err
+		}
+	}
+	suffix := "/sync"
+	if !wd.w3cCompatible {
+		suffix = ""
+	}
+	url := wd.pollURL("/session/%s/execute" + suffix)
+	q, err := wd.drainRoutes(url, 0)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan RouteChange, 16)
+	go wd.pollRoutes(ctx, url, o.interval, q, events)
+	return events, nil
+}
+
+// watchRoutesBiDi sends the route changes of the current window to the
+// returned channel, as routesChannelScript reports them through a BiDi
+// channel, until ctx is done or the BiDi connection is closed. The script is
+// a preload script of the window, so that it runs in every new document
+// before the scripts of the document, and is run at once in the current one.
+func (wd *remoteWD) watchRoutesBiDi(ctx context.Context) (<-chan RouteChange, error) {
+	window, err := wd.CurrentWindowHandle()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := wd.BiDi()
+	if err != nil {
+		return nil, err
+	}
+	var preload struct {
+		Script string `json:"script"`
+	}
+	// stop removes the preload script, which outlives the connection, and
+	// closes the connection, which ends the subscription.
+	stop := func() {
+		if preload.Script != "" {
+			if err := conn.Send(wd.Context(), "script.removePreloadScript", map[string]interface{}{"script": preload.Script}, nil); err != nil {
+				debugLog("error removing the route watcher: %v", err)
+			}
+		}
+		conn.Close()
+	}
+	sub, err := conn.Subscribe(ctx, []string{bidi.EventScriptMessage}, window)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	name := fmt.Sprintf("selenium-routes-%d", atomic.AddInt64(&routeChannels, 1))
+	channel := map[string]interface{}{
+		"type":  "channel",
+		"value": map[string]interface{}{"channel": name},
+	}
+	err = conn.Send(ctx, "script.addPreloadScript", map[string]interface{}{
+		"functionDeclaration": routesChannelScript,
+		"arguments":           []interface{}{channel},
+		"contexts":            []string{window},
+	}, &preload)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	var result struct {
+		Type   string `json:"type"`
+		Result struct {
+			Value string `json:"value"`
+		} `json:"result"`
+		ExceptionDetails struct {
+			Text string `json:"text"`
+		} `json:"exceptionDetails"`
+	}
+	err = conn.Send(ctx, "script.callFunction", map[string]interface{}{
+		"functionDeclaration": routesChannelScript,
+		"arguments":           []interface{}{channel, map[string]interface{}{"type": "boolean", "value": true}},
+		"target":              map[string]interface{}{"context": window},
+		"awaitPromise":        false,
+	}, &result)
+	if err == nil && result.Type == "exception" {
+		err = fmt.Errorf("installing the route watcher: %s", result.ExceptionDetails.Text)
+	}
+	if err != nil {
+		stop()
+		return nil, err
+	}
+
+	events := make(chan RouteChange, 16)
+	go func() {
+		defer close(events)
+		defer stop()
+		current := result.Result.Value
+		for {
+			var e bidi.Event
+			var ok bool
+			select {
+			case e, ok = <-sub.Events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			var msg struct {
+				Channel string `json:"channel"`
+				Data    struct {
+					Value string `json:"value"`
+				} `json:"data"`
+				Source struct {
+					Context string `json:"context"`
+				} `json:"source"`
+			}
+			if err := e.Decode(&msg); err != nil || msg.Channel != name || msg.Source.Context != window {
+				continue
+			}
+			var c struct {
+				Method   RouteMethod
+				Old, New string
+				Time     float64
+			}
+			if err := json.Unmarshal([]byte(msg.Data.Value), &c); err != nil {
+				debugLog("error decoding a route change: %v", err)
+				continue
+			}
+			if c.Method == RouteLoad {
+				c.Old = current
+			}
+			current = c.New
+			select {
+			case events <- RouteChange{Old: c.Old, New: c.New, Method: c.Method, Time: time.Unix(0, int64(c.Time*float64(time.Millisecond)))}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// pollRoutes sends the route changes recorded after q, fetched from url, to
+// events, until ctx is done or the session ends.
+func (wd *remoteWD) pollRoutes(ctx context.Context, url string, interval time.Duration, q *routeQueue, events chan<- RouteChange) {
+	defer close(events)
+	doc, seq, current := q.Doc, q.Seq, q.URL
+	if n := len(q.Events); n > 0 {
+		current = q.Events[n-1].New
+	}
+	poll(ctx, nil, interval, "polling the route changes", func() error {
+		q, err := wd.drainRoutes(url, seq)
+		if err == nil && q.Doc != doc {
+			// A new document: its changes are all new.
+			q, err = wd.drainRoutes(url, 0)
+		}
+		if err != nil {
+			return err
+		}
+		var changes []RouteChange
+		if q.Doc != doc {
+			changes = append(changes, RouteChange{Old: current, New: q.URL, Method: RouteLoad, Time: time.Now()})
+			doc, current = q.Doc, q.URL
+		}
+		for _, e := range q.Events {
+			changes = append(changes, RouteChange{
+				Old:    e.Old,
+				New:    e.New,
+				Method: e.Method,
+				Time:   time.Unix(0, int64(e.Time*float64(time.Millisecond))),
+			})
+			current = e.New
+		}
+		seq = q.Seq
+		for _, c := range changes {
+			select {
+			case events <- c:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		return nil
+	})
+}
+
+// RouteError is returned by ExpectRoute when the URL does not match the
+// pattern in time.
+type RouteError struct {
+	Pattern string
+	// URL is the URL of the page when ExpectRoute started.
+	URL string
+	// Changes are the route changes observed while waiting.
+	Changes []RouteChange
+}
+
+func (e *RouteError) Error() string {
+	if len(e.Changes) == 0 {
+		return fmt.Sprintf("timeout waiting for a route matching %q: the page stayed on %s", e.Pattern, e.URL)
+	}
+	var seen []string
+	for _, c := range e.Changes {
+		seen = append(seen, fmt.Sprintf("%s %s", c.Method, c.New))
+	}
+	return fmt.Sprintf("timeout waiting for a route matching %q from %s; the routes were: %s", e.Pattern, e.URL, strings.Join(seen, ", "))
+}
+
+// matchRoute reports whether the URL u matches pattern, which is matched
+// with path.Match against the path of u, or against its fragment if the
+// pattern starts with "#".
+func matchRoute(pattern, u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+	target := parsed.Path
+	if strings.HasPrefix(pattern, "#") {
+		target = "#" + parsed.Fragment
+	}
+	ok, _ := path.Match(pattern, target)
+	return ok
+}
+
+// ExpectRoute waits until the URL of the page matches pattern.
+func (wd *remoteWD) ExpectRoute(pattern string, timeout time.Duration) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid route pattern %q: %v", pattern, err)
+	}
+	if timeout == 0 {
+		timeout = wd.defaults.WaitTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	changes, err := wd.WatchRouteChanges(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := wd.CurrentURL()
+	if err != nil {
+		return err
+	}
+	if matchRoute(pattern, current) {
+		return nil
+	}
+	routeErr := &RouteError{Pattern: pattern, URL: current}
+	for c := range changes {
+		if matchRoute(pattern, c.New) {
+			return nil
+		}
+		routeErr.Changes = append(routeErr.Changes, c)
+	}
+	return routeErr
+}
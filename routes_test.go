@@ -0,0 +1,235 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// fakeRoutePage is the state of the route recording of a fake page.
+type fakeRoutePage struct {
+	mu     sync.Mutex
+	doc    string
+	url    string
+	events []map[string]interface{}
+}
+
+// navigate records a route change in the page.
+func (p *fakeRoutePage) navigate(method, to string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	old := p.url
+	if n := len(p.events); n > 0 {
+		old = p.events[n-1]["new"].(string)
+	}
+	p.events = append(p.events, map[string]interface{}{
+		"seq": len(p.events) + 1, "method": method, "old": old, "new": to, "time": 1.6e12,
+	})
+}
+
+// load replaces the document.
+func (p *fakeRoutePage) load(doc, to string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.doc, p.url, p.events = doc, to, nil
+}
+
+func (p *fakeRoutePage) current() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.events); n > 0 {
+		return p.events[n-1]["new"].(string)
+	}
+	return p.url
+}
+
+func fakeRouteDriver(t *testing.T) (*fakeDriver, *fakeRoutePage) {
+	d := newFakeDriver(t, nil)
+	p := &fakeRoutePage{doc: "doc1", url: "https://app.example/"}
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct{ Args []int }
+		json.Unmarshal(body, &params)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		var events []map[string]interface{}
+		for _, e := range p.events {
+			if e["seq"].(int) > params.Args[0] {
+				events = append(events, e)
+			}
+		}
+		return http.StatusOK, map[string]interface{}{"doc": p.doc, "url": p.url, "seq": len(p.events), "events": events}
+	})
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, p.current()
+	})
+	return d, p
+}
+
+func TestWatchRouteChanges(t *testing.T) {
+	d, p := fakeRouteDriver(t)
+	p.navigate("push", "https://app.example/before")
+	wd := d.newRemote(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := wd.WatchRouteChanges(ctx, WatchInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("wd.WatchRouteChanges() returned error: %v", err)
+	}
+	p.navigate("push", "https://app.example/items")
+	p.navigate("replace", "https://app.example/items?page=2")
+	p.navigate("pop", "https://app.example/before")
+	want := []RouteChange{
+		{Old: "https://app.example/before", New: "https://app.example/items", Method: RoutePush},
+		{Old: "https://app.example/items", New: "https://app.example/items?page=2", Method: RouteReplace},
+		{Old: "https://app.example/items?page=2", New: "https://app.example/before", Method: RoutePop},
+		{Old: "https://app.example/before", New: "https://app.example/login", Method: RouteLoad},
+		{Old: "https://app.example/login", New: "https://app.example/home", Method: RoutePush},
+	}
+	for i, w := range want {
+		if i == 3 {
+			p.load("doc2", "https://app.example/login")
+			p.navigate("push", "https://app.example/home")
+		}
+		select {
+		case got := <-changes:
+			if got.Old != w.Old || got.New != w.New || got.Method != w.Method || got.Time.IsZero() {
+				t.Errorf("route change %d is %+v, want %+v", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no route change %d, want %+v", i, w)
+		}
+	}
+
+	cancel()
+	for range changes {
+	}
+}
+
+func TestWatchRouteChangesBiDi(t *testing.T) {
+	removed := make(chan string, 1)
+	endpoint := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		message := func(channel, context, data string) {
+			websocket.JSON.Send(conn, map[string]interface{}{
+				"type":   "event",
+				"method": "script.message",
+				"params": map[string]interface{}{
+					"channel": channel,
+					"data":    map[string]interface{}{"type": "string", "value": data},
+					"source":  map[string]interface{}{"realm": "r", "context": context},
+				},
+			})
+		}
+		for {
+			var cmd struct {
+				ID     int64
+				Method string
+				Params map[string]interface{}
+			}
+			if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+				return
+			}
+			var result interface{} = map[string]interface{}{}
+			switch cmd.Method {
+			case "script.addPreloadScript":
+				result = map[string]interface{}{"script": "preload"}
+			case "script.callFunction":
+				result = map[string]interface{}{"type": "success", "result": map[string]interface{}{"type": "string", "value": "https://app.example/"}}
+			case "script.removePreloadScript":
+				removed <- cmd.Params["script"].(string)
+			}
+			websocket.JSON.Send(conn, map[string]interface{}{"id": cmd.ID, "type": "success", "result": result})
+			if cmd.Method == "script.callFunction" {
+				channel := cmd.Params["arguments"].([]interface{})[0].(map[string]interface{})["value"].(map[string]interface{})["channel"].(string)
+				message(channel, "main", `{"method":"push","old":"https://app.example/","new":"https://app.example/items","time":1}`)
+				message("another", "main", `{"method":"push","old":"x","new":"y","time":1}`)
+				message(channel, "main", `{"method":"load","new":"https://app.example/login","time":2}`)
+			}
+		}
+	}))
+	defer endpoint.Close()
+
+	d := newFakeDriver(t, map[string]interface{}{
+		BiDiCapability: "ws" + strings.TrimPrefix(endpoint.URL, "http") + "/session/" + fakeSessionID,
+	})
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		return http.StatusOK, "main"
+	})
+	wd := d.newRemote(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes, err := wd.WatchRouteChanges(ctx)
+	if err != nil {
+		t.Fatalf("wd.WatchRouteChanges() returned error: %v", err)
+	}
+	want := []RouteChange{
+		{Old: "https://app.example/", New: "https://app.example/items", Method: RoutePush},
+		{Old: "https://app.example/items", New: "https://app.example/login", Method: RouteLoad},
+	}
+	for i, w := range want {
+		select {
+		case got := <-changes:
+			if got.Old != w.Old || got.New != w.New || got.Method != w.Method || got.Time.IsZero() {
+				t.Errorf("route change %d is %+v, want %+v", i, got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no route change %d, want %+v", i, w)
+		}
+	}
+	if n := d.count("POST", "/execute/sync"); n != 0 {
+		t.Errorf("wd.WatchRouteChanges() polled the page %d times with BiDi, want 0", n)
+	}
+
+	cancel()
+	for range changes {
+	}
+	select {
+	case id := <-removed:
+		if id != "preload" {
+			t.Errorf("the watcher removed the preload script %q, want %q", id, "preload")
+		}
+	default:
+		t.Error("the watcher did not remove its preload script")
+	}
+}
+
+func TestExpectRoute(t *testing.T) {
+	d, p := fakeRouteDriver(t)
+	wd := d.newRemote(t)
+	wd.defaults.PollInterval = 10 * time.Millisecond
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		p.navigate("push", "https://app.example/items/3")
+		p.navigate("replace", "https://app.example/items/3/edit")
+	}()
+	// The route matching is brief: the page replaces it at once.
+	if err := wd.ExpectRoute("/items/*", 5*time.Second); err != nil {
+		t.Fatalf("wd.ExpectRoute() returned error: %v", err)
+	}
+	if err := wd.ExpectRoute("/items/*/edit", time.Second); err != nil {
+		t.Errorf("wd.ExpectRoute() of the current route returned error: %v", err)
+	}
+
+	p.navigate("push", "https://app.example/#/settings")
+	err := wd.ExpectRoute("#/profile", 100*time.Millisecond)
+	var e *RouteError
+	if !errors.As(err, &e) || e.URL != "https://app.example/#/settings" {
+		t.Errorf("wd.ExpectRoute() of another route returned error %v, want a *RouteError", err)
+	}
+	if err := wd.ExpectRoute("#/settings", time.Second); err != nil {
+		t.Errorf("wd.ExpectRoute() of a fragment route returned error: %v", err)
+	}
+	if err := wd.ExpectRoute("[", time.Second); err == nil {
+		t.Error("wd.ExpectRoute() of an invalid pattern returned no error")
+	}
+}
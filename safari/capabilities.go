@@ -0,0 +1,75 @@
+// Package safari provides Safari-specific types for WebDriver.
+//
+// Unlike the other drivers, safaridriver reads its options from top-level
+// capabilities with the "safari:" prefix, rather than from an object under a
+// single key; selenium.Capabilities.AddSafari sets them:
+//
+//	caps := selenium.Capabilities{"browserName": safari.BrowserName}
+//	caps.AddSafari(safari.Capabilities{AutomaticInspection: true})
+//	service, err := selenium.NewSafariDriverService("", port, selenium.SafariDiagnose())
+//	...
+//	wd, err := selenium.NewRemote(caps, service.URLPrefix())
+//
+// The safari.options capability of the former Safari extension driver is not
+// sent: safaridriver follows the W3C specification, which rejects
+// capabilities without a prefix.
+//
+// Intelligent Tracking Prevention has no capability: the sessions use the
+// setting of the "Prevent cross-site tracking" preference of Safari.
+package safari
+
+// BrowserName is the value of the browserName capability for Safari.
+const BrowserName = "safari"
+
+// TechnologyPreviewBrowserName is the value of the browserName capability for
+// Safari Technology Preview, which is driven by its own safaridriver.
+const TechnologyPreviewBrowserName = "Safari Technology Preview"
+
+// Keys of the Safari-specific capabilities.
+const (
+	AutomaticInspectionKey = "safari:automaticInspection"
+	AutomaticProfilingKey  = "safari:automaticProfiling"
+	WebRTCKey              = "webkit:WebRTC"
+)
+
+// Capabilities provides Safari-specific options to WebDriver.
+type Capabilities struct {
+	// AutomaticInspection opens the Web Inspector when the session starts and
+	// pauses it, so that the page can be debugged from its start.
+	AutomaticInspection bool
+	// AutomaticProfiling starts a timeline recording in the Web Inspector
+	// when the session starts.
+	AutomaticProfiling bool
+	// TechnologyPreview runs Safari Technology Preview instead of Safari. The
+	// service must run the safaridriver of Safari Technology Preview.
+	TechnologyPreview bool
+	// WebRTC relaxes the restrictions of Safari on WebRTC, e.g. for pages
+	// served over HTTP in tests.
+	WebRTC *WebRTC
+}
+
+// WebRTC specifies the WebRTC settings of Safari.
+type WebRTC struct {
+	// DisableInsecureMediaCapture allows capturing the camera and the
+	// microphone from pages that are not served over HTTPS.
+	DisableInsecureMediaCapture bool `json:"DisableInsecureMediaCapture,omitempty"`
+	// DisableICECandidateFiltering exposes the local IP addresses in the ICE
+	// candidates, which Safari filters by default.
+	DisableICECandidateFiltering bool `json:"DisableICECandidateFiltering,omitempty"`
+}
+
+// Map returns the top-level capabilities that encode c, without the
+// browserName capability.
+func (c Capabilities) Map() map[string]interface{} {
+	m := make(map[string]interface{})
+	if c.AutomaticInspection {
+		m[AutomaticInspectionKey] = true
+	}
+	if c.AutomaticProfiling {
+		m[AutomaticProfilingKey] = true
+	}
+	if c.WebRTC != nil {
+		m[WebRTCKey] = c.WebRTC
+	}
+	return m
+}
@@ -0,0 +1,32 @@
+package safari
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCapabilitiesMap(t *testing.T) {
+	for _, tc := range []struct {
+		caps Capabilities
+		want string
+	}{
+		{Capabilities{}, `{}`},
+		{Capabilities{TechnologyPreview: true}, `{}`},
+		{
+			Capabilities{AutomaticInspection: true, AutomaticProfiling: true},
+			`{"safari:automaticInspection":true,"safari:automaticProfiling":true}`,
+		},
+		{
+			Capabilities{WebRTC: &WebRTC{DisableInsecureMediaCapture: true}},
+			`{"webkit:WebRTC":{"DisableInsecureMediaCapture":true}}`,
+		},
+	} {
+		data, err := json.Marshal(tc.caps.Map())
+		if err != nil {
+			t.Fatalf("json.Marshal() returned error: %v", err)
+		}
+		if string(data) != tc.want {
+			t.Errorf("%+v.Map() = %s, want %s", tc.caps, data, tc.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+)
+
+// maskGeometryScript returns the device pixel ratio, the bounding rectangle,
+// relative to the viewport, of the target element in its first argument, if
+// any, and those of the visible mask elements in its second argument and of
+// the visible elements matching the CSS selectors in its third. The
+// rectangles of hidden elements are null.
+const maskGeometryScript = `
+	var box = function(el) {
+		var r = el.getBoundingClientRect();
+		var style = window.getComputedStyle(el);
+		if (r.width <= 0 || r.height <= 0 || style.visibility === 'hidden' || style.display === 'none') {
+			return null;
+		}
+		return {x: r.left, y: r.top, width: r.width, height: r.height};
+	};
+	var masks = (arguments[1] || []).map(box);
+	(arguments[2] || []).forEach(function(selector) {
+		var matches = document.querySelectorAll(selector);
+		for (var i = 0; i < matches.length; i++) {
+			masks.push(box(matches[i]));
+		}
+	});
+	var target = arguments[0];
+	return {
+		ratio: window.devicePixelRatio || 1,
+		target: target ? target.getBoundingClientRect().toJSON() : null,
+		masks: masks
+	};`
+
+// maskGeometry is the value returned by maskGeometryScript.
+type maskGeometry struct {
+	Ratio  float64
+	Target *rect
+	Masks  []*rect
+}
+
+// ScreenshotWithMasks takes a screenshot with opaque rectangles over the mask
+// elements.
+func (wd *remoteWD) ScreenshotWithMasks(target WebElement, masks []WebElement, c color.Color) ([]byte, error) {
+	if masks == nil {
+		masks = []WebElement{}
+	}
+	return wd.screenshotWithMasks(target, masks, []string{}, c)
+}
+
+// ScreenshotWithMaskSelectors takes a screenshot with opaque rectangles over
+// the elements matching the CSS selectors.
+func (wd *remoteWD) ScreenshotWithMaskSelectors(target WebElement, selectors []string, c color.Color) ([]byte, error) {
+	if selectors == nil {
+		selectors = []string{}
+	}
+	return wd.screenshotWithMasks(target, []WebElement{}, selectors, c)
+}
+
+func (wd *remoteWD) screenshotWithMasks(target WebElement, masks []WebElement, selectors []string, c color.Color) ([]byte, error) {
+	var (
+		data []byte
+		err  error
+	)
+	// The screenshot is taken first, as taking that of an element scrolls it
+	// into view, and the geometry after.
+	if target != nil {
+		data, err = target.Screenshot(true)
+	} else {
+		data, err = wd.Screenshot()
+	}
+	if err != nil {
+		return nil, err
+	}
+	var targetArg interface{}
+	if target != nil {
+		targetArg = target
+	}
+	raw, err := wd.ExecuteScriptRaw(maskGeometryScript, []interface{}{targetArg, masks, selectors})
+	if err != nil {
+		return nil, fmt.Errorf("measuring the masks: %w", err)
+	}
+	reply := new(struct{ Value maskGeometry })
+	if err := json.Unmarshal(raw, reply); err != nil {
+		return nil, err
+	}
+	return drawMasks(data, reply.Value, c)
+}
+
+// drawMasks draws opaque rectangles of color c, black if nil, over the masks
+// of g on the PNG screenshot data, and returns the image encoded as PNG.
+func drawMasks(data []byte, g maskGeometry, c color.Color) ([]byte, error) {
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding the screenshot: %v", err)
+	}
+	bounds := src.Bounds()
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, src, bounds.Min, draw.Src)
+
+	if c == nil {
+		c = color.Black
+	}
+	fill := color.NRGBAModel.Convert(c).(color.NRGBA)
+	fill.A = 0xff
+	ratio := g.Ratio
+	if ratio <= 0 {
+		ratio = 1
+	}
+	var originX, originY float64
+	if g.Target != nil {
+		originX, originY = g.Target.X, g.Target.Y
+	}
+	for _, m := range g.Masks {
+		if m == nil {
+			continue
+		}
+		// The rectangles are widened to whole pixels so that they cover the
+		// antialiased edges of the masks.
+		r := image.Rect(
+			int(math.Floor((m.X-originX)*ratio)),
+			int(math.Floor((m.Y-originY)*ratio)),
+			int(math.Ceil((m.X+m.Width-originX)*ratio)),
+			int(math.Ceil((m.Y+m.Height-originY)*ratio)),
+		).Add(bounds.Min).Intersect(bounds)
+		if r.Empty() {
+			continue
+		}
+		draw.Draw(img, r, image.NewUniform(fill), image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,124 @@
+package selenium
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// whitePNG returns a white PNG image of the given size.
+func whitePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// maskedPixels returns the number of pixels of the PNG data of color c, and
+// whether the pixel at p has that color.
+func maskedPixels(t *testing.T, data []byte, c color.Color, p image.Point) (int, bool) {
+	t.Helper()
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("the masked screenshot is not a PNG image: %v", err)
+	}
+	want := color.NRGBAModel.Convert(c)
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.NRGBAModel.Convert(img.At(x, y)) == want {
+				n++
+			}
+		}
+	}
+	return n, color.NRGBAModel.Convert(img.At(p.X, p.Y)) == want
+}
+
+func TestScreenshotWithMasks(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	// The viewport is 50x25 CSS pixels, with a device pixel ratio of 2.
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, base64.StdEncoding.EncodeToString(whitePNG(t, 100, 50))
+	})
+	d.handle("GET", "/element/target/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, base64.StdEncoding.EncodeToString(whitePNG(t, 40, 20))
+	})
+	var args []interface{}
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		params := new(struct{ Args []interface{} })
+		json.Unmarshal(body, params)
+		args = params.Args
+		var target interface{}
+		if args[0] != nil {
+			target = rect{X: 10, Y: 5, Width: 20, Height: 10}
+		}
+		return http.StatusOK, map[string]interface{}{
+			"ratio":  2,
+			"target": target,
+			"masks": []interface{}{
+				rect{X: 10, Y: 5, Width: 5, Height: 5},
+				// It overlaps the first mask.
+				rect{X: 12, Y: 5, Width: 5, Height: 5},
+				// It is hidden.
+				nil,
+				// It is outside of the viewport.
+				rect{X: 60, Y: 0, Width: 10, Height: 10},
+			},
+		}
+	})
+	wd := d.newRemote(t)
+	red := color.RGBA{R: 0xff, A: 0xff}
+
+	masks := []WebElement{&remoteWE{parent: wd, id: "a"}, &remoteWE{parent: wd, id: "b"}}
+	data, err := wd.ScreenshotWithMasks(nil, masks, red)
+	if err != nil {
+		t.Fatalf("wd.ScreenshotWithMasks() returned error: %v", err)
+	}
+	// The masks cover x from 20 to 34 and y from 10 to 20 in device pixels.
+	if n, ok := maskedPixels(t, data, red, image.Pt(20, 10)); n != 14*10 || !ok {
+		t.Errorf("wd.ScreenshotWithMasks() masked %d pixels, want %d from (20, 10)", n, 14*10)
+	}
+	if args[0] != nil || len(args[1].([]interface{})) != 2 || len(args[2].([]interface{})) != 0 {
+		t.Errorf("wd.ScreenshotWithMasks() measured the masks with arguments %v, want no target and the two masks", args)
+	}
+
+	// The masks of an element screenshot are relative to the element.
+	target := &remoteWE{parent: wd, id: "target"}
+	data, err = wd.ScreenshotWithMaskSelectors(target, []string{".clock", "#avatar"}, nil)
+	if err != nil {
+		t.Fatalf("wd.ScreenshotWithMaskSelectors() returned error: %v", err)
+	}
+	if n, ok := maskedPixels(t, data, color.Black, image.Pt(0, 0)); n != 14*10 || !ok {
+		t.Errorf("wd.ScreenshotWithMaskSelectors() masked %d black pixels, want %d from (0, 0)", n, 14*10)
+	}
+	if want := []interface{}{".clock", "#avatar"}; !reflect.DeepEqual(args[2], want) {
+		t.Errorf("wd.ScreenshotWithMaskSelectors() passed the selectors %v, want %v", args[2], want)
+	}
+}
+
+func TestDrawMasksFractional(t *testing.T) {
+	// A mask on fractional CSS pixels covers every device pixel it touches,
+	// and a translucent color is drawn opaque.
+	g := maskGeometry{Ratio: 1.5, Masks: []*rect{{X: 1.2, Y: 0, Width: 1, Height: 2}}}
+	data, err := drawMasks(whitePNG(t, 10, 10), g, color.NRGBA{B: 0xff, A: 0x10})
+	if err != nil {
+		t.Fatalf("drawMasks() returned error: %v", err)
+	}
+	// x from 1.8 to 3.3, y from 0 to 3.
+	if n, ok := maskedPixels(t, data, color.NRGBA{B: 0xff, A: 0xff}, image.Pt(1, 0)); n != 3*3 || !ok {
+		t.Errorf("drawMasks() masked %d pixels, want %d from (1, 0)", n, 3*3)
+	}
+}
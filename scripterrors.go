@@ -0,0 +1,186 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrPageScriptError is matched by the *PageScriptError returned by the
+// commands of a session set up with FailOnJSErrors when the page threw.
+var ErrPageScriptError = errors.New("the page threw a JavaScript error")
+
+// crossOriginScriptError is the message that browsers report instead of the
+// details of an error thrown by a script of another origin.
+const crossOriginScriptError = "Script error."
+
+// ScriptError is an uncaught exception or unhandled promise rejection of a
+// page.
+type ScriptError struct {
+	// Message is the message of the error. It is "Script error." for errors
+	// thrown by scripts loaded from another origin without CORS, whose
+	// details the browser hides.
+	Message string
+	// Source, Line and Column locate the code that threw, if known.
+	Source       string
+	Line, Column int
+	// Stack is the stack trace of the error, if any.
+	Stack string
+	// Rejection reports whether the error is an unhandled promise rejection.
+	Rejection bool
+	// Frame is the URL of the frame that threw, or empty for the top-level
+	// document.
+	Frame string
+}
+
+func (e ScriptError) String() string {
+	s := e.Message
+	if e.Rejection {
+		s = "unhandled rejection: " + s
+	}
+	if e.Source != "" {
+		s += fmt.Sprintf(" (%s:%d:%d)", e.Source, e.Line, e.Column)
+	}
+	if e.Frame != "" {
+		s += " in frame " + e.Frame
+	}
+	return s
+}
+
+// PageScriptError is returned by a command after which the page reported
+// JavaScript errors; see FailOnJSErrors. The command itself succeeded.
+type PageScriptError struct {
+	// Command is the type of the command, e.g. "POST /element/:id/click".
+	Command string
+	// Errors are the errors reported since the previous check, in order.
+	Errors []ScriptError
+}
+
+func (e *PageScriptError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	crossOrigin := false
+	for i, err := range e.Errors {
+		msgs[i] = err.String()
+		crossOrigin = crossOrigin || err.Message == crossOriginScriptError
+	}
+	s := fmt.Sprintf("%v after %s: %s", ErrPageScriptError, e.Command, strings.Join(msgs, "; "))
+	if crossOrigin {
+		s += ` (the browser hides the details of the errors thrown by cross-origin scripts, reported as "Script error.")`
+	}
+	return s
+}
+
+// Is makes errors.Is(err, ErrPageScriptError) true.
+func (e *PageScriptError) Is(target error) bool {
+	return target == ErrPageScriptError
+}
+
+// scriptErrorsScript makes the document record its uncaught errors and
+// unhandled rejections in window.__seleniumScriptErrors. Frames post theirs
+// to the top-level document, which may be of another origin. It is safe to
+// evaluate more than once in a document.
+const scriptErrorsScript = `(function() {
+	if (window.__seleniumScriptErrors) {
+		return;
+	}
+	window.__seleniumScriptErrors = [];
+	var top = window.top === window;
+	var record = function(e) {
+		if (!top) {
+			e.frame = String(location.href);
+			try {
+				window.top.postMessage({__seleniumScriptError: e}, '*');
+			} catch (err) {}
+			return;
+		}
+		if (window.__seleniumScriptErrors.length < 100) {
+			window.__seleniumScriptErrors.push(e);
+		}
+	};
+	if (top) {
+		window.addEventListener('message', function(event) {
+			if (event.data && event.data.__seleniumScriptError) {
+				record(event.data.__seleniumScriptError);
+			}
+		});
+	}
+	window.addEventListener('error', function(event) {
+		if (!(event instanceof ErrorEvent)) {
+			return;
+		}
+		record({
+			message: String(event.message),
+			source: String(event.filename || ''),
+			line: event.lineno || 0,
+			column: event.colno || 0,
+			stack: event.error && event.error.stack ? String(event.error.stack) : ''
+		});
+	});
+	window.addEventListener('unhandledrejection', function(event) {
+		var r = event.reason;
+		record({
+			message: r && r.message !== undefined ? String(r.message) : String(r),
+			stack: r && r.stack ? String(r.stack) : '',
+			rejection: true
+		});
+	});
+})();`
+
+// drainScriptErrorsScript returns and clears the errors recorded by
+// scriptErrorsScript, which it installs in documents loaded without it.
+const drainScriptErrorsScript = scriptErrorsScript + `
+	return window.__seleniumScriptErrors.splice(0);`
+
+// FailOnJSErrors makes the state-changing commands of the test fail with the
+// JavaScript errors that the page reported.
+func (wd *remoteWD) FailOnJSErrors(filter func(msg string) bool) error {
+	if wd.Supports(FeatureCDP) {
+		if _, err := wd.addInitScript(scriptErrorsScript); err != nil {
+			return err
+		}
+	}
+	if _, err := wd.ExecuteScript(scriptErrorsScript, nil); err != nil {
+		return err
+	}
+	if filter == nil {
+		filter = func(string) bool { return false }
+	}
+	wd.scriptErrorFilter = filter
+	return nil
+}
+
+// checkScriptErrors returns a *PageScriptError if the page reported errors
+// not ignored by the filter of FailOnJSErrors since the last check. Errors
+// from the check itself, e.g. because an alert is open, are ignored.
+func (wd *remoteWD) checkScriptErrors(command string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"script": drainScriptErrorsScript,
+		"args":   []interface{}{},
+	})
+	if err != nil {
+		return nil
+	}
+	suffix := "/sync"
+	if !wd.w3cCompatible {
+		suffix = ""
+	}
+	response, err := wd.executeTagged(tagBackground, "POST", wd.requestURL("/session/%s/execute"+suffix, wd.id), data)
+	if err != nil {
+		return nil
+	}
+	reply := new(struct{ Value []ScriptError })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil
+	}
+	var errs []ScriptError
+	for _, e := range reply.Value {
+		if !wd.scriptErrorFilter(e.Message) {
+			errs = append(errs, e)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &PageScriptError{Command: command, Errors: errs}
+}
@@ -0,0 +1,106 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFailOnJSErrors(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var pending []map[string]interface{}
+	installs, drains := 0, 0
+	drainFails := false
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		params := new(struct{ Script string })
+		json.Unmarshal(body, params)
+		switch params.Script {
+		case scriptErrorsScript:
+			installs++
+			return http.StatusOK, nil
+		case drainScriptErrorsScript:
+			drains++
+			if drainFails {
+				return http.StatusInternalServerError, map[string]string{"error": "unexpected alert open", "message": "alert"}
+			}
+			errs := pending
+			pending = nil
+			return http.StatusOK, errs
+		}
+		t.Errorf("unexpected script %q", params.Script)
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "title"
+	})
+	wd := d.newRemote(t)
+
+	pending = []map[string]interface{}{{"message": "boom"}}
+	if err := wd.Get("http://example.com"); err != nil {
+		t.Fatalf("wd.Get() before FailOnJSErrors returned error: %v", err)
+	}
+	if drains != 0 {
+		t.Fatalf("the page errors were checked %d times before FailOnJSErrors, want 0", drains)
+	}
+
+	pending = nil
+	noise := func(msg string) bool { return strings.HasPrefix(msg, "ResizeObserver") }
+	if err := wd.FailOnJSErrors(noise); err != nil {
+		t.Fatalf("wd.FailOnJSErrors() returned error: %v", err)
+	}
+	if installs != 1 {
+		t.Errorf("wd.FailOnJSErrors() installed the script %d times, want 1", installs)
+	}
+	if err := wd.Get("http://example.com"); err != nil {
+		t.Errorf("wd.Get() without page errors returned error: %v", err)
+	}
+
+	pending = []map[string]interface{}{
+		{"message": "ResizeObserver loop limit exceeded"},
+		{"message": "x is undefined", "source": "http://example.com/app.js", "line": 3, "column": 7},
+		{"message": "Script error.", "frame": "http://ads.example/"},
+		{"message": "rejected", "rejection": true},
+	}
+	err := wd.Get("http://example.com")
+	var perr *PageScriptError
+	if !errors.As(err, &perr) || !errors.Is(err, ErrPageScriptError) {
+		t.Fatalf("wd.Get() with page errors returned error %v, want a *PageScriptError matching ErrPageScriptError", err)
+	}
+	want := &PageScriptError{
+		Command: "POST /url",
+		Errors: []ScriptError{
+			{Message: "x is undefined", Source: "http://example.com/app.js", Line: 3, Column: 7},
+			{Message: "Script error.", Frame: "http://ads.example/"},
+			{Message: "rejected", Rejection: true},
+		},
+	}
+	if !reflect.DeepEqual(perr, want) {
+		t.Errorf("wd.Get() returned %+v, want %+v", perr, want)
+	}
+	for _, s := range []string{"x is undefined (http://example.com/app.js:3:7)", "in frame http://ads.example/", "unhandled rejection: rejected", "cross-origin"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("the error %q does not contain %q", err, s)
+		}
+	}
+
+	// Commands that only read the page are not checked.
+	drains = 0
+	if _, err := wd.Title(); err != nil {
+		t.Errorf("wd.Title() returned error: %v", err)
+	}
+	if drains != 0 {
+		t.Errorf("wd.Title() checked the page errors %d times, want 0", drains)
+	}
+
+	// The check is best effort.
+	drainFails = true
+	if err := wd.Get("http://example.com"); err != nil {
+		t.Errorf("wd.Get() with a failing check returned error: %v", err)
+	}
+}
@@ -1,12 +1,17 @@
 package selenium
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"image/color"
 	"time"
 
+	"github.com/LoveOyy/selenium/bidi"
 	"github.com/LoveOyy/selenium/chrome"
 	"github.com/LoveOyy/selenium/firefox"
 	"github.com/LoveOyy/selenium/log"
+	"github.com/LoveOyy/selenium/safari"
 )
 
 // TODO(minusnine): make an enum type called FindMethod.
@@ -23,6 +28,23 @@ const (
 	ByCSSSelector     = "css selector"
 )
 
+// Locator describes how to find an element: a method, one of the By
+// constants, and a value interpreted according to that method.
+type Locator struct {
+	By    string
+	Value string
+}
+
+// ByCSS returns a Locator that finds elements matching the CSS selector.
+func ByCSS(selector string) Locator {
+	return Locator{By: ByCSSSelector, Value: selector}
+}
+
+// String returns the locator in human-readable form, for use in messages.
+func (l Locator) String() string {
+	return fmt.Sprintf("%s %q", l.By, l.Value)
+}
+
 type MouseButton int
 
 // Mouse buttons.
@@ -95,10 +117,29 @@ const (
 // with standard and browser-specific options.
 type Capabilities map[string]interface{}
 
-// AddChrome adds Chrome-specific capabilities.
+// AddChrome adds Chrome-specific capabilities. The keys under which they are
+// sent to the remote end are chosen by f.KeyMode when the session is created.
+// If a proxy was set with f.SetProxy, the proxy capability is set to match.
 func (c Capabilities) AddChrome(f chrome.Capabilities) {
 	c[chrome.CapabilitiesKey] = f
 	c[chrome.DeprecatedCapabilitiesKey] = f
+	if u, bypass := f.Proxy(); u != "" {
+		c["proxy"] = chromeProxy(u, bypass)
+	}
+	c.warnProxyConflict()
+}
+
+// AddEdge adds Microsoft Edge-specific capabilities, which have the format of
+// Chrome's, under EdgeCapabilitiesKey; see the edge package. They are always
+// sent in W3C mode. If a proxy was set with f.SetProxy, the proxy capability
+// is set to match.
+func (c Capabilities) AddEdge(f chrome.Capabilities) {
+	f.W3C = true
+	c[EdgeCapabilitiesKey] = f
+	if u, bypass := f.Proxy(); u != "" {
+		c["proxy"] = chromeProxy(u, bypass)
+	}
+	c.warnProxyConflict()
 }
 
 // AddFirefox adds Firefox-specific capabilities.
@@ -106,9 +147,23 @@ func (c Capabilities) AddFirefox(f firefox.Capabilities) {
 	c[firefox.CapabilitiesKey] = f
 }
 
-// AddProxy adds proxy configuration to the capabilities.
+// AddSafari adds Safari-specific capabilities, which safaridriver reads from
+// top-level keys; see the safari package. If s.TechnologyPreview is set, the
+// browserName capability is set to Safari Technology Preview.
+func (c Capabilities) AddSafari(s safari.Capabilities) {
+	for k, v := range s.Map() {
+		c[k] = v
+	}
+	if s.TechnologyPreview {
+		c["browserName"] = safari.TechnologyPreviewBrowserName
+	}
+}
+
+// AddProxy adds proxy configuration to the capabilities. p can be checked
+// with p.Validate first, as the remote end rejects an invalid one.
 func (c Capabilities) AddProxy(p Proxy) {
 	c["proxy"] = p
+	c.warnProxyConflict()
 }
 
 // AddLogging adds logging configuration to the capabilities.
@@ -136,23 +191,30 @@ type Proxy struct {
 	// required if Type is set to PAC.
 	AutoconfigURL string `json:"proxyAutoconfigUrl,omitempty"`
 
-	// The following are used when Type is set to Manual.
+	// The following are used when Type is set to Manual. The addresses are
+	// "host:port" pairs, without a scheme.
 	//
 	// Note that in Firefox, connections to localhost are not proxied by default,
 	// even if a proxy is set. This can be overridden via a preference setting.
-	FTP           string   `json:"ftpProxy,omitempty"`
-	HTTP          string   `json:"httpProxy,omitempty"`
-	SSL           string   `json:"sslProxy,omitempty"`
-	SOCKS         string   `json:"socksProxy,omitempty"`
-	SOCKSVersion  int      `json:"socksVersion,omitempty"`
-	SOCKSUsername string   `json:"socksUsername,omitempty"`
-	SOCKSPassword string   `json:"socksPassword,omitempty"`
-	NoProxy       []string `json:"noProxy,omitempty"`
-
-	// The W3C draft spec includes port fields as well. According to the
-	// specification, ports can also be included in the above addresses. However,
-	// in the Geckodriver implementation, the ports must be specified by these
-	// additional fields.
+	FTP          string `json:"ftpProxy,omitempty"`
+	HTTP         string `json:"httpProxy,omitempty"`
+	SSL          string `json:"sslProxy,omitempty"`
+	SOCKS        string `json:"socksProxy,omitempty"`
+	SOCKSVersion int    `json:"socksVersion,omitempty"`
+	// SOCKSUsername and SOCKSPassword are the credentials of the SOCKS
+	// proxy. They are not part of the W3C proxy object: Selenium servers
+	// accept them, but geckodriver rejects the capability and ChromeDriver
+	// ignores them.
+	SOCKSUsername string `json:"socksUsername,omitempty"`
+	SOCKSPassword string `json:"socksPassword,omitempty"`
+	// NoProxy are the hosts reached without the proxy, e.g. "localhost" or
+	// ".example.com".
+	NoProxy []string `json:"noProxy,omitempty"`
+
+	// The ports of the proxies, for drivers that predate the W3C
+	// specification, which has them in the addresses above. When the Proxy is
+	// encoded to JSON, they are added to the addresses that have no port
+	// instead of being sent.
 	HTTPPort  int `json:"httpProxyPort,omitempty"`
 	SSLPort   int `json:"sslProxyPort,omitempty"`
 	SocksPort int `json:"socksProxyPort,omitempty"`
@@ -204,14 +266,24 @@ type Size struct {
 	Width, Height int
 }
 
-// Cookie represents an HTTP cookie.
+// Rect is the position and size of a window.
+type Rect struct {
+	X, Y, Width, Height int
+}
+
+// Cookie represents an HTTP cookie. An empty Path or Domain and a zero Expiry
+// are not sent to the remote end, which then uses the defaults of the W3C
+// specification: the path "/", the host of the current page and a session
+// cookie.
 type Cookie struct {
-	Name     string   `json:"name"`
-	Value    string   `json:"value"`
-	Path     string   `json:"path"`
-	Domain   string   `json:"domain"`
-	Secure   bool     `json:"secure"`
-	Expiry   uint     `json:"expiry"`
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Path   string `json:"path,omitempty"`
+	Domain string `json:"domain,omitempty"`
+	Secure bool   `json:"secure"`
+	// Expiry is the time when the cookie expires, in seconds since the Unix
+	// epoch, or zero for a session cookie; see ExpiryTime and SetExpiryTime.
+	Expiry   uint     `json:"expiry,omitempty"`
 	HTTPOnly bool     `json:"httpOnly"`
 	SameSite SameSite `json:"sameSite,omitempty"`
 }
@@ -282,8 +354,170 @@ type WebDriver interface {
 	// SwitchSession switches to the given session ID.
 	SwitchSession(sessionID string) error
 
+	// WithContext returns a view of the session whose commands are sent with
+	// ctx, so that they are canceled, and return ctx.Err(), when ctx is done,
+	// e.g. to give a step of a test a deadline:
+	//
+	//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	//	defer cancel()
+	//	err := wd.WithContext(ctx).Get(url)
+	//
+	// The view shares the state of the session with wd, and the elements
+	// found through it send their commands with ctx too. Waits return when
+	// ctx is done. The commands of background pollers, such as WatchWindows,
+	// are not bounded by ctx.
+	WithContext(ctx context.Context) WebDriver
+	// Context returns the context of the commands of the view returned by
+	// WithContext, or context.Background().
+	Context() context.Context
+
 	// Capabilities returns the current session's capabilities.
 	Capabilities() (Capabilities, error)
+	// SessionCapabilities returns the capabilities that the remote end
+	// returned when the session was created, without a request. For
+	// ChromeDriver sessions, chrome.SessionInfo reads the driver's details
+	// from them.
+	SessionCapabilities() Capabilities
+	// ProfilePath returns the directory of the profile of the browser: the
+	// one kept by PreserveProfile, if any, else the one reported by the
+	// remote end, i.e. the user data directory of Chrome or the profile of
+	// Firefox. After Quit, it returns the profile kept by PreserveProfile, or
+	// an error if none was kept. It returns an error wrapping ErrUnsupported
+	// if the remote end does not report the profile.
+	ProfilePath() (string, error)
+	// LocalStorage returns the localStorage of the current page's origin.
+	LocalStorage() Storage
+	// SessionStorage returns the sessionStorage of the current page's origin.
+	SessionStorage() Storage
+	// SaveStorageState saves the state of the browser to the file at path, as
+	// versioned JSON (see StorageState), to start later sessions already
+	// logged in with RestoreStorageState. It saves the cookies, the
+	// localStorage and sessionStorage of each origin set with StorageOrigins,
+	// or else of the origin of the current page, and the permissions granted
+	// to these origins that navigator.permissions.query reports. The browser
+	// navigates to the root of each origin, then back to the current page.
+	// On remote ends that support the Chrome DevTools Protocol, the cookies
+	// of all the domains are saved; elsewhere only those visible from the
+	// saved origins are. The file contains credentials, such as session
+	// cookies and tokens, in clear text: it is written readable by its owner
+	// only, and must not be published with other test artifacts.
+	SaveStorageState(path string, opts ...StorageStateOption) error
+	// RestoreStorageState restores the state saved by SaveStorageState, e.g.
+	// in a new session before the test starts: it sets the cookies, then
+	// navigates to the root of each saved origin to set its storage and
+	// grant its permissions, with the W3C Permissions command. The browser
+	// is left on the last origin. Without the Chrome DevTools Protocol, the
+	// cookies are set from the saved origins whose host they match.
+	RestoreStorageState(path string) error
+	// ClearSiteData clears data stored by the browser for the origin of the
+	// current page: by default all of it, otherwise the given SiteData types.
+	// It requires the Chrome DevTools Protocol (see FeatureCDP).
+	ClearSiteData(types ...string) error
+	// ResetState clears the state left in the browser by a test, so that the
+	// session can be reused by the next one, e.g. in a session pool: at
+	// ResetLight, the cookies and web storage, and the windows other than the
+	// current one; at ResetStandard, also the service workers, caches and
+	// other storage of the origins visited since the last reset; at
+	// ResetFull, also the permissions granted and the browser context. The
+	// browser is left on about:blank. What the remote end cannot clear, e.g.
+	// without the Chrome DevTools Protocol, is listed by a *ResetError, after
+	// the rest is cleared.
+	ResetState(level ResetLevel) error
+	// NewIsolatedContext creates a browser context in the browser of the
+	// session, whose pages share no cookies, storage or cache with the
+	// others, with a window on about:blank, without starting a new browser.
+	// The current window is unchanged: IsolatedContext.Switch makes the
+	// commands of the session act on the window of the context. The context
+	// is disposed of by IsolatedContext.Close, or with the session. It
+	// requires the Chrome DevTools Protocol (see FeatureCDP).
+	NewIsolatedContext() (IsolatedContext, error)
+	// Navigate loads url, like Get, and returns the result of the navigation.
+	// With the FailOnHTTPError option, a browser error page or an HTTP error
+	// status is reported as a *NavigationError.
+	Navigate(url string, opts ...NavigateOption) (NavigationResult, error)
+	// Open loads url, like Get, and waits until the element matching the CSS
+	// selector readySelector is displayed, then returns it. A zero timeout
+	// means Defaults.WaitTimeout. If the element does not appear in time,
+	// the error is an *OpenError describing the page shown instead, and the
+	// hooks set with WithFailureHook are called first, e.g. to save a
+	// screenshot.
+	Open(url, readySelector string, timeout time.Duration) (WebElement, error)
+	// ClickWhenReady waits for an element to match the locator, waits for
+	// it to pass the checks of WebElement.EnsureInteractable, scrolling it
+	// into view, and clicks it, retrying while the click is intercepted by
+	// another element. The phases share the timeout, a zero timeout meaning
+	// Defaults.WaitTimeout, and the element is looked up again if it goes
+	// stale. If the timeout elapses, it returns a *NotReadyError with the
+	// phase that exhausted it, after calling the failure hooks.
+	ClickWhenReady(by, value string, timeout time.Duration) error
+	// TypeWhenReady is like ClickWhenReady, but replaces the value of the
+	// element with text, with WebElement.SetText.
+	TypeWhenReady(by, value, text string, timeout time.Duration) error
+	// NavigationResult returns the final URL, the HTTP status and whether the
+	// browser shows an error page for the current document. The status is
+	// taken from the Navigation Timing API or, on ChromeDriver sessions
+	// created with the performance log enabled, from the DevTools Network
+	// events in that log. It is StatusUnknown if neither is available.
+	NavigationResult() (NavigationResult, error)
+	// DisableAnimations turns off CSS transitions and animations, finishes Web
+	// Animations and hides the blinking text caret in the current page, so
+	// that screenshots of it are stable. Where the remote end supports init
+	// scripts (currently ChromeDriver), pages loaded afterwards are affected
+	// too; otherwise it must be called again after each navigation.
+	DisableAnimations() error
+	// TabOrder presses Tab repeatedly, starting from the top of the page, and
+	// describes each element that receives the focus. It stops when the focus
+	// leaves the page or returns to the first element, or after limit
+	// elements. If the focus keeps cycling among some elements without
+	// returning to the first one, the elements visited so far are returned
+	// together with a *FocusTrapError. The focus and scroll position are
+	// restored afterwards.
+	TabOrder(limit int) ([]FocusStop, error)
+	// AuditPage checks the current page against rules, such as MaxDOMNodes
+	// and ImagesHaveDimensions, in a single script, and returns the
+	// violations in the order of the rules. Rules defined outside of this
+	// package implement PageRule or are created with NewPageRule. An error is
+	// returned if a rule throws.
+	AuditPage(rules ...PageRule) ([]Violation, error)
+	// EnableResourceSummary makes the browser record every resource fetched
+	// by the current page and, where the remote end supports init scripts
+	// (currently ChromeDriver), by all pages loaded afterwards. Without it,
+	// ResourceSummary misses the resources of pages that clear the resource
+	// timing buffer or fetch more than its default 250 entries.
+	EnableResourceSummary() error
+	// FailOnJSErrors makes the page record its uncaught exceptions and
+	// unhandled promise rejections, including those of its frames, and makes
+	// every command of the test that changes the state of the page, such as
+	// Get, Click or SendKeys, return a *PageScriptError matching
+	// ErrPageScriptError if the page recorded errors since the previous
+	// command. The errors for which filter returns true, e.g. known noise of
+	// third-party scripts, are ignored; filter may be nil. The browser hides
+	// the details of the errors thrown by cross-origin scripts, which are
+	// reported as "Script error.". Where the remote end supports init scripts
+	// (currently ChromeDriver), errors thrown while a page loads are recorded
+	// too; otherwise they are missed, as recording starts in each new page
+	// when the command that loaded it is checked.
+	FailOnJSErrors(filter func(msg string) bool) error
+	// ResourceSummary returns the number, size and timing of the resources
+	// fetched by the current page, using the Resource Timing API.
+	ResourceSummary() (ResourceSummary, error)
+	// SetTestName associates the session with a test: the name is included
+	// in the errors returned by the session's commands and in their debug
+	// log entries, and the current page can read it from the window property
+	// named by TestNameProperty, as can pages loaded afterwards where the
+	// remote end supports init scripts (currently ChromeDriver). On Sauce
+	// Labs, the job is renamed too. To name the session on Selenium Grid, set
+	// the name in the capabilities with Capabilities.SetTestName instead, as
+	// it cannot be changed after the session is created.
+	SetTestName(name string) error
+	// Supports reports whether the remote end supports an optional feature.
+	// The answer is derived from the capabilities negotiated for the session
+	// where possible. Otherwise the remote end is probed once with a harmless
+	// request and the result is cached for the session.
+	Supports(feature Feature) bool
+	// SetSupport overrides the result of Supports for a feature, for remote
+	// ends that are detected incorrectly.
+	SetSupport(feature Feature, supported bool)
 
 	// SetAsyncScriptTimeout sets the amount of time that asynchronous scripts
 	// are permitted to run before they are aborted. The timeout will be rounded
@@ -296,8 +530,17 @@ type WebDriver interface {
 	// loading a page. The timeout will be rounded to nearest millisecond.
 	SetPageLoadTimeout(timeout time.Duration) error
 
-	// Quit ends the current session. The browser instance will be closed.
+	// Quit ends the current session. The browser instance will be closed,
+	// unless the session was started with Chrome's Detach option, with which
+	// ChromeDriver keeps the browser running when the session is deleted.
 	Quit() error
+	// QuitAndCloseBrowser ends the current session and closes the browser
+	// instance, even if it was started with Chrome's Detach option.
+	QuitAndCloseBrowser() error
+	// RecordArtifact records an artifact of the session, such as a screenshot
+	// file, in its summary. It does nothing unless the session was created with
+	// WithSessionSummary.
+	RecordArtifact(name, location string)
 
 	// CurrentWindowHandle returns the ID of current window handle.
 	CurrentWindowHandle() (string, error)
@@ -307,18 +550,59 @@ type WebDriver interface {
 	CurrentURL() (string, error)
 	// Title returns the current page's title.
 	Title() (string, error)
-	// PageSource returns the current page's source.
+	// PageSource returns the current page's source. It is always UTF-8: the
+	// remote end returns the document as decoded by the browser, whatever
+	// the encoding the page was served in. See PageEncoding and
+	// PageSourceRaw.
 	PageSource() (string, error)
+	// PageEncoding returns the name of the character encoding of the current
+	// page, as determined by the browser (document.characterSet), e.g.
+	// "windows-1251" or "Shift_JIS".
+	PageEncoding() (string, error)
+	// PageSourceRaw returns the bytes of the current document as served, and
+	// the name of their character encoding, for when byte-exact content
+	// matters, such as checksums. It requires the Chrome DevTools Protocol
+	// (see FeatureCDP) and a session created with the performance log
+	// enabled, from which the document request is found, and consumes the
+	// log entries. Text documents are re-encoded from the text decoded by
+	// the browser, which matches the bytes served for well-formed content.
+	PageSourceRaw() ([]byte, string, error)
 	// Close closes the current window.
 	Close() error
 	// SwitchFrame switches to the given frame. The frame parameter can be the
 	// frame's ID as a string, its WebElement instance as returned by
 	// GetElement, or nil to switch to the current top-level browsing context.
 	SwitchFrame(frame interface{}) error
+	// FindElementInAnyFrame searches the top-level browsing context and then,
+	// depth-first, every frame nested up to maxDepth levels below it for an
+	// element. It returns the first element found together with the path of
+	// frames that contains it. The element can only be used from within that
+	// frame; use WithFrame to interact with it. The frame context that was
+	// current before the call is restored before returning.
+	//
+	// Frames that cannot be entered or searched, such as cross-origin frames
+	// that deny access, are skipped. If no element is found, the returned error
+	// is a *FrameSearchError that lists the skipped frames.
+	//
+	// The implicit wait timeout applies to the search in each frame, so it
+	// should be set to zero before calling this method on pages with many
+	// frames.
+	FindElementInAnyFrame(by, value string, maxDepth int) (WebElement, FramePath, error)
+	// WithFrame switches to the frame identified by path, calls fn and then
+	// switches back to the frame context that was current before the call, even
+	// if fn returns an error.
+	WithFrame(path FramePath, fn func() error) error
 	// SwitchWindow switches the context to the specified window.
 	SwitchWindow(name string) error
 	// CloseWindow closes the specified window.
 	CloseWindow(name string) error
+	// NewWindow opens a new tab or window on about:blank, with the W3C New
+	// Window command, and returns its handle. typ, WindowTypeTab or
+	// WindowTypeWindow, is a hint that the remote end may ignore; empty lets
+	// it choose. The current window does not change: switch to the new one
+	// with SwitchWindow. Remote ends that do not implement the command return
+	// ErrUnsupported.
+	NewWindow(typ string) (string, error)
 	// MaximizeWindow maximizes a window. If the name is empty, the current
 	// window will be maximized.
 	MaximizeWindow(name string) error
@@ -334,8 +618,23 @@ type WebDriver interface {
 	Back() error
 	// Refresh refreshes the page.
 	Refresh() error
+	// BackAndWait moves backward in history, like Back, then waits until the
+	// resulting document is loaded and its URL stayed the same for a short
+	// while, and returns its URL and title. A timeout of zero means
+	// Defaults.WaitTimeout. If there is no previous entry, the returned error
+	// wraps ErrNoHistory. If a beforeunload prompt blocks or cancels the
+	// navigation, a *BeforeUnloadError is returned: the unhandledPromptBehavior
+	// capability of the session decides what happens to the prompt.
+	BackAndWait(timeout time.Duration) (HistoryResult, error)
+	// ForwardAndWait is like BackAndWait, but moves forward in history.
+	ForwardAndWait(timeout time.Duration) (HistoryResult, error)
+	// RefreshAndWait is like BackAndWait, but refreshes the page and waits
+	// for the new document.
+	RefreshAndWait(timeout time.Duration) (HistoryResult, error)
 
-	// FindElement finds exactly one element in the current page's DOM.
+	// FindElement finds exactly one element in the current page's DOM. by is
+	// one of the By constants, or ByRelative with the value of a
+	// RelativeLocator.
 	FindElement(by, value string) (WebElement, error)
 	// FindElement finds potentially many elements in the current page's DOM.
 	FindElements(by, value string) ([]WebElement, error)
@@ -346,21 +645,48 @@ type WebDriver interface {
 	DecodeElement([]byte) (WebElement, error)
 	// DecodeElements decodes a multi-element response.
 	DecodeElements([]byte) ([]WebElement, error)
+	// ElementFromID returns the element of the session with the given
+	// reference, as returned by WebElement.ID or received in the result of a
+	// script. The reference is not checked: commands on an unknown element
+	// fail with a "no such element" error.
+	ElementFromID(id string) WebElement
 
 	// GetCookies returns all of the cookies in the browser's jar.
 	GetCookies() ([]Cookie, error)
-	// GetCookie returns the named cookie in the jar, if present. This method is
-	// only implemented for Firefox.
+	// GetCookie returns the named cookie in the jar, with the "Get Named
+	// Cookie" command of the W3C specification, or an *Error for "no such
+	// cookie" if it is absent. With remote ends that do not implement the
+	// command, all the cookies are fetched.
 	GetCookie(name string) (Cookie, error)
 	// AddCookie adds a cookie to the browser's jar.
+	//
+	// The cookie is checked first, as drivers handle malformed cookies
+	// differently: an error matching ErrInvalidCookie is returned for an
+	// empty name, a name or value that would not fit in a Cookie header, a
+	// path not starting with a slash or a domain that does not match the host
+	// of the current page. The domain is lower-cased and a leading dot, which
+	// GeckoDriver rejects, is removed; a trailing slash is removed from the
+	// path, other than "/". A path that does not match the current page, which
+	// some drivers reject, and a SameSite=None cookie that is not Secure,
+	// which Chrome drops, are logged in debug mode. The cookie passed is not
+	// changed.
 	AddCookie(cookie *Cookie) error
+	// AddCookies adds cookies to the browser's jar, with one "Add Cookie"
+	// command each, as the W3C specification has no command for several
+	// cookies. The cookies are all checked and normalized as by AddCookie
+	// before any is added, so that none is added if one is invalid.
+	AddCookies(cookies []Cookie) error
 	// DeleteAllCookies deletes all of the cookies in the browser's jar.
 	DeleteAllCookies() error
 	// DeleteCookie deletes a cookie to the browser's jar.
 	DeleteCookie(name string) error
 
 	// Click clicks a mouse button. The button should be one of RightButton,
-	// MiddleButton or LeftButton.
+	// MiddleButton or LeftButton. In W3C sessions, Click and the other mouse
+	// methods below perform actions of the DefaultMouse input source, which
+	// keeps its position and pressed buttons between calls until
+	// ReleaseActions; otherwise they send the legacy JSON Wire Protocol
+	// commands.
 	Click(button int) error
 	// DoubleClick clicks the left mouse button twice.
 	DoubleClick() error
@@ -388,6 +714,12 @@ type WebDriver interface {
 	// ReleaseActions releases keys and pointer buttons if they are pressed,
 	// triggering any events as if they were performed by a regular action.
 	ReleaseActions() error
+	// PerformActionChain performs the actions of chain, tick by tick, with
+	// the W3C Perform Actions command. It returns the first error in
+	// building the chain without sending the command. The input sources keep
+	// their state, such as pressed keys, after the command: use
+	// ReleaseActions to release them.
+	PerformActionChain(chain *ActionChain) error
 
 	// SendModifier sends the modifier key to the active element. The modifier
 	// can be one of ShiftKey, ControlKey, AltKey, MetaKey.
@@ -403,12 +735,58 @@ type WebDriver interface {
 	KeyUp(keys string) error
 	// Screenshot takes a screenshot of the browser window.
 	Screenshot() ([]byte, error)
+	// FullPageScreenshot takes a screenshot of the whole scrollable page and
+	// returns it in PNG format. Chrome and Edge render the page beyond the
+	// viewport with the DevTools Protocol, and Firefox with a command of
+	// GeckoDriver. Other browsers are scrolled one viewport at a time and the
+	// screenshots are stitched, which repeats the fixed and sticky elements
+	// of the page in each of them; the scroll position is restored after.
+	FullPageScreenshot() ([]byte, error)
+	// PrintPage prints the current page to PDF with the W3C Print Page
+	// command, and returns the PDF document. Invalid options return an error
+	// without sending the command; remote ends that do not implement it
+	// return ErrUnsupported.
+	PrintPage(opts PrintOptions) ([]byte, error)
 	// Log fetches the logs. Log types must be previously configured in the
 	// capabilities.
 	//
 	// NOTE: will return an error (not implemented) on IE11 or Edge drivers.
 	Log(typ log.Type) ([]log.Message, error)
+	// DrainLogs pulls the logs of the given types every five seconds, or as
+	// set with DrainInterval, between the other commands of the session, and
+	// sends their entries to sink, from another goroutine, so that they do
+	// not accumulate in the buffers of the driver during long sessions. The
+	// logs are pulled once before DrainLogs returns, which returns the error
+	// of that pull. The loop stops when ctx is done or the session ends; Quit
+	// pulls the logs one last time and waits for the loop to stop. If a pull
+	// returns more entries than DrainHighWater, a Warning message of type
+	// log.Client is sent to sink, as the driver may drop entries: shorten the
+	// interval.
+	//
+	// Drivers return each entry once, to whoever asks first: entries pulled
+	// by the loop are not returned by Log, and the other way around.
+	DrainLogs(ctx context.Context, types []log.Type, sink func(log.Type, log.Message), opts ...DrainOption) error
+	// LastKnownState returns the state of the session as last observed by
+	// its commands: the URL and title of the page, the last screenshots, the
+	// last entries of the browser log and the last output of the driver. It
+	// sends no command, so it works after the session ended or the browser
+	// crashed, e.g. in a failure hook, returning whatever was observed until
+	// then. Use WithPeriodicSnapshots to take screenshots in the background.
+	LastKnownState() SessionSnapshot
+	// WireStats returns the sizes of the bodies of the requests sent to the
+	// remote end and of its responses so far: the totals, the largest
+	// request and response, and the totals by type of command. They are
+	// also written in the SessionSummary of WithSessionSummary. Use
+	// WarnLargeRequests to learn of large requests as they are sent.
+	WireStats() WireStats
 
+	// PromptHandler returns the user prompt handler of the session, from the
+	// unhandledPromptBehavior capability returned by the remote end: a
+	// string is returned as Default. It is empty if the remote end did not
+	// return the capability. When no dialog is open, the alert methods
+	// below return an error that mentions the behaviors of the handler that
+	// may have closed it.
+	PromptHandler() PromptHandlerConfig
 	// DismissAlert dismisses current alert.
 	DismissAlert() error
 	// AcceptAlert accepts the current alert.
@@ -418,7 +796,8 @@ type WebDriver interface {
 	// SetAlertText sets the current alert text.
 	SetAlertText(text string) error
 
-	// ExecuteScript executes a script.
+	// ExecuteScript executes a script. The numbers in the result are float64,
+	// or json.Number if the session was created with WithJSONNumbers.
 	ExecuteScript(script string, args []interface{}) (interface{}, error)
 	// ExecuteScriptAsync asynchronously executes a script.
 	ExecuteScriptAsync(script string, args []interface{}) (interface{}, error)
@@ -432,23 +811,229 @@ type WebDriver interface {
 	// WaitWithTimeoutAndInterval waits for the condition to evaluate to true.
 	WaitWithTimeoutAndInterval(condition Condition, timeout, interval time.Duration) error
 
-	// WaitWithTimeout works like WaitWithTimeoutAndInterval, but with default polling interval.
+	// WaitWithTimeout works like WaitWithTimeoutAndInterval, but with the
+	// polling interval of Defaults.
 	WaitWithTimeout(condition Condition, timeout time.Duration) error
 
-	//Wait works like WaitWithTimeoutAndInterval, but using the default timeout and polling interval.
+	// Wait works like WaitWithTimeoutAndInterval, but using the timeout and
+	// polling interval of Defaults.
 	Wait(condition Condition) error
+	// WaitForText waits until the element matching the locator shows the
+	// text wanted, compared after collapsing white space and trimming: equal
+	// by default, containing it with TextContains, or matched by it as a
+	// regular expression with TextMatches. The element is looked up again
+	// whenever it is missing or has been replaced, like a LazyElement. The
+	// timeout and interval are those of Defaults, unless set with
+	// WaitTimeout and WaitInterval. In case of timeout, the error is a
+	// *WaitTextError reporting the last distinct texts shown.
+	WaitForText(by, value, want string, opts ...WaitOption) error
+
+	// Defaults returns the timeouts and polling settings of the session, with
+	// the library defaults filled in; see WithDefaults.
+	Defaults() Defaults
+
+	// WatchWindows reports the windows and tabs opened and closed from now
+	// on, until ctx is done or the session ends, when the channel is closed.
+	// If the session has a BiDi endpoint (see Capabilities.EnableBiDi), the
+	// windows are reported from the browsingContext events, so that a window
+	// closed right after it opened is reported as well, by an opened event
+	// followed by a closed one. Otherwise, the window handles are polled
+	// every Defaults.PollInterval, or as set with WatchInterval, between the
+	// other commands of the session, and a window opened and closed between
+	// two polls is not seen: use an interval shorter than the lifetime of the
+	// windows to observe, down to 10ms. Either way, the events are queued
+	// until they are received.
+	WatchWindows(ctx context.Context, opts ...WatchOption) (<-chan WindowEvent, error)
+	// WatchRouteChanges reports the changes of the URL of the top-level
+	// document of the current window from now on, until ctx is done or the
+	// session ends, when the channel is closed: the calls to
+	// history.pushState and replaceState and the popstate events of
+	// single-page applications, which polling CurrentURL misses, and the
+	// loads of new documents. The changes are recorded by the page. If the
+	// session has a BiDi endpoint (see Capabilities.EnableBiDi), the page
+	// sends them through a BiDi channel as they happen, and the recording is
+	// installed in every new document before its scripts run. Otherwise,
+	// they are polled like WatchWindows; with the Chrome DevTools Protocol
+	// (see FeatureCDP), the recording is installed in every new document
+	// before its scripts run, and without it, at the next poll, so that the
+	// changes made before are missed.
+	WatchRouteChanges(ctx context.Context, opts ...WatchOption) (<-chan RouteChange, error)
+	// ExpectRoute waits until the URL of the page matches pattern, or one of
+	// the routes it goes through does, even briefly. The pattern is matched
+	// with path.Match against the path of the URL, e.g. "/items/*", or
+	// against its fragment if it starts with "#", e.g. "#/items/*" for hash
+	// routers. A zero timeout means Defaults.WaitTimeout. In case of
+	// timeout, the error is a *RouteError listing the routes seen.
+	ExpectRoute(pattern string, timeout time.Duration) error
+	// SetTabActive makes the tab or window with the given handle behave as
+	// if it were focused and visible, even while it is in the background:
+	// its document keeps focus and its timers keep running. The current
+	// window is left unchanged. Combine it with
+	// chrome.Capabilities.DisableTabThrottling. It requires ChromeDriver and
+	// returns ErrUnsupported otherwise.
+	SetTabActive(handle string) error
+	// SetUserAgentOverride makes the current tab send ua as its user agent,
+	// in the User-Agent header and navigator.userAgent, with User-Agent
+	// Client Hints derived from ua, so that the two agree. If acceptLanguage
+	// is not empty, e.g. "fr-CH,fr", it sets the Accept-Language header and
+	// navigator.languages too. Use Capabilities.SetUserAgent to change the
+	// user agent of every tab from the start. It requires ChromeDriver and
+	// returns ErrUnsupported otherwise.
+	SetUserAgentOverride(ua, acceptLanguage string) error
+	// WindowRects returns the position and size of every window, by handle.
+	// ScreenshotWindow takes a screenshot of the window with the given
+	// handle, and ScreenshotAllWindows of every window, e.g. from a failure
+	// hook. They switch to each window in turn and always switch back to
+	// the current window, which may disturb focus-dependent state. If the
+	// window of ScreenshotWindow is closed meanwhile, it returns the "no such
+	// window" *Error; the batch methods skip windows closed meanwhile.
+	WindowRects() (map[string]Rect, error)
+	ScreenshotWindow(handle string) ([]byte, error)
+	ScreenshotAllWindows() (map[string][]byte, error)
+	// ScreenshotWithMasks takes a screenshot of the element target, or of
+	// the viewport if target is nil, as PNG, with opaque rectangles of color
+	// c, black if nil, drawn over the masks, e.g. over timestamps and avatars
+	// that would make visual comparisons fail. The rectangles are those of
+	// the masks in the captured image, scaled by the device pixel ratio.
+	// Masks that are hidden or outside of the captured area are skipped.
+	// ScreenshotWithMaskSelectors masks every element matching the CSS
+	// selectors when the screenshot is taken.
+	ScreenshotWithMasks(target WebElement, masks []WebElement, c color.Color) ([]byte, error)
+	ScreenshotWithMaskSelectors(target WebElement, selectors []string, c color.Color) ([]byte, error)
+	// UploadFile makes a file on the local disk available to the remote end,
+	// and returns the path under which the remote end can read it, e.g. to
+	// type it into a file input with SendKeys. A remote end on this machine
+	// reads the file where it is. Otherwise the file is uploaded, which
+	// Selenium Grid and older Selenium servers support; other remote ends
+	// return ErrUnsupported. See WithFileDetector to upload the files typed
+	// with SendKeys.
+	UploadFile(localPath string) (string, error)
+
+	// ReportResult reports whether the test passed, and why, to the cloud grid
+	// running the session, so that its dashboard shows the result: Sauce
+	// Labs, BrowserStack or LambdaTest, detected from the capabilities or
+	// the host of the remote end. It returns ErrNoProvider for other remote
+	// ends, such as local drivers. Call it after the test body and before
+	// Quit, or create the session with ReportResultOnQuit.
+	ReportResult(passed bool, reason string) error
+
+	// IsAlive reports whether the session still responds to commands. It
+	// sends a single cheap command, so that session pools can discard the
+	// sessions of crashed browsers. Commands of a session whose browser or
+	// tab crashed fail with a *CrashError.
+	IsAlive() bool
+
+	// ExtensionLogs returns the console messages logged so far by the
+	// service worker or background page of a Chrome extension, which do not
+	// appear in Log. It connects to the target through the DevTools endpoint
+	// of the browser, so it requires a local Chrome session and returns
+	// ErrUnsupported otherwise. A stopped service worker is started once;
+	// if the extension still has no running target, the error matches
+	// ErrExtensionNotRunning.
+	ExtensionLogs(extensionID string) ([]log.Message, error)
+	// StreamExtensionLogs works like ExtensionLogs, but sends the messages
+	// logged so far, then the new ones, to the returned channel until ctx is
+	// done or the target stops, when the channel is closed.
+	StreamExtensionLogs(ctx context.Context, extensionID string) (<-chan log.Message, error)
+
+	// BiDi connects to the WebDriver BiDi endpoint of the session, through
+	// which events, such as log entries, network requests and navigations,
+	// can be subscribed to without polling. The session must have been
+	// created with Capabilities.EnableBiDi; otherwise BiDi returns
+	// ErrUnsupported. The caller closes the connection, which leaves the
+	// session open.
+	BiDi() (*bidi.Conn, error)
+
+	// AddVirtualAuthenticator adds a virtual authenticator to the browser,
+	// through which the Web Authentication API of the pages creates and
+	// uses credentials, e.g. passkeys, without a physical security key or
+	// user interaction. It returns the ID of the authenticator, valid until
+	// it is removed or the session ends. It returns ErrUnsupported if the
+	// driver does not implement virtual authenticators, as only Chrome and
+	// Edge do.
+	AddVirtualAuthenticator(opts VirtualAuthenticatorOptions) (string, error)
+	// RemoveVirtualAuthenticator removes a virtual authenticator and its
+	// credentials.
+	RemoveVirtualAuthenticator(authenticatorID string) error
+	// AddCredential adds a credential to a virtual authenticator, e.g. to
+	// sign in with a passkey registered beforehand.
+	AddCredential(authenticatorID string, c Credential) error
+	// Credentials returns the credentials of a virtual authenticator,
+	// including those created by the page.
+	Credentials(authenticatorID string) ([]Credential, error)
+	// RemoveCredential removes the credential with the given ID from a
+	// virtual authenticator.
+	RemoveCredential(authenticatorID string, credentialID []byte) error
+	// RemoveAllCredentials removes the credentials of a virtual
+	// authenticator.
+	RemoveAllCredentials(authenticatorID string) error
+	// SetUserVerified sets whether the user verification of a virtual
+	// authenticator, created with HasUserVerification, succeeds.
+	SetUserVerified(authenticatorID string, verified bool) error
+}
+
+// ShadowRoot is the shadow root of an element, returned by
+// WebElement.ShadowRoot. Elements are found in its shadow tree with the By
+// methods that the driver supports in shadow roots; most support only
+// ByCSSSelector, not ByXPATH.
+type ShadowRoot interface {
+	// ID returns the reference of the shadow root.
+	ID() string
+	// FindElement finds an element in the shadow tree.
+	FindElement(by, value string) (WebElement, error)
+	// FindElements finds multiple elements in the shadow tree.
+	FindElements(by, value string) ([]WebElement, error)
 }
 
 // WebElement defines method supported by web elements.
 type WebElement interface {
-	// Click clicks on the element.
-	Click() error
-	// SendKeys types into the element.
-	SendKeys(keys string) error
+	// Click clicks on the element. With WithPrecheck, EnsureInteractable is
+	// called first.
+	Click(opts ...ActionOption) error
+	// SendKeys types into the element. The keys are passed to the driver's
+	// Element Send Keys command, which dispatches each character as a separate
+	// key event. Some driver and operating system combinations split or mangle
+	// characters outside the Basic Multilingual Plane (e.g. emoji), combining
+	// sequences and text that normally comes from an input method; use SetText
+	// or AppendText to enter such text reliably. With WithPrecheck,
+	// EnsureInteractable is called first.
+	SendKeys(keys string, opts ...ActionOption) error
+	// SetText clears the element and enters text into it. If every character
+	// of text can be typed as a simple key press, the text is typed using key
+	// actions. Otherwise, including for long text, the value is set by script
+	// and "input" and "change" events are dispatched, with InputEvent.data
+	// populated, so that framework bindings (e.g. React controlled inputs)
+	// observe the change.
+	SetText(text string) error
+	// AppendText enters text at the end of the element's current value,
+	// choosing between key actions and script in the same way as SetText.
+	AppendText(text string) error
+	// UploadBytes selects a file with the given name, content and MIME type
+	// in the element, which must be a file input, as if the user had picked
+	// it. The file is written to a temporary file, removed when the session
+	// ends, and made available to the remote end with WebDriver.UploadFile,
+	// then its path is typed into the input.
+	//
+	// If the driver cannot accept the file, e.g. because a remote grid does
+	// not support uploads, the file is instead set by script: a File is
+	// built in the page and assigned to the input through a DataTransfer,
+	// and synthetic "input" and "change" events are dispatched so that
+	// framework handlers run. This bypasses the browser's file selection
+	// entirely, so it does not exercise the path of a real user; it is
+	// logged in debug mode.
+	UploadBytes(filename string, data []byte, mime string) error
 	// Submit submits the button.
 	Submit() error
 	// Clear clears the element.
 	Clear() error
+	// ClearWithEvents clears the element like Clear, then dispatches the
+	// "input" and "change" events if the value changed and the driver did not
+	// fire them, as drivers differ: the W3C specification fires "change" when
+	// the element loses focus, while some drivers fire "input" too and older
+	// ones neither. The "input" event is dispatched so that frameworks that
+	// track the value property, such as React, see the change. Each event is
+	// thus received once.
+	ClearWithEvents() error
 	// MoveTo moves the mouse to relative coordinates from center of element, If
 	// the element is not visible, it will be scrolled into view.
 	MoveTo(xOffset, yOffset int) error
@@ -457,11 +1042,21 @@ type WebElement interface {
 	FindElement(by, value string) (WebElement, error)
 	// FindElement finds multiple children elements.
 	FindElements(by, value string) ([]WebElement, error)
+	// ShadowRoot returns the open shadow root attached to the element, to
+	// find elements in its shadow tree. It fails with the "no such shadow
+	// root" error if the element has no shadow root or a closed one, and
+	// with ErrUnsupported if the driver does not implement the W3C Get
+	// Element Shadow Root command.
+	ShadowRoot() (ShadowRoot, error)
 
 	// TagName returns the element's name.
 	TagName() (string, error)
 	// Text returns the text of the element.
 	Text() (string, error)
+	// WaitForText waits until the element shows the text wanted, like
+	// WebDriver.WaitForText. Only a LazyElement is looked up again if it
+	// is replaced; other elements fail with the stale element error.
+	WaitForText(want string, opts ...WaitOption) error
 	// IsSelected returns true if element is selected.
 	IsSelected() (bool, error)
 	// IsEnabled returns true if the element is enabled.
@@ -485,4 +1080,19 @@ type WebElement interface {
 	CSSProperty(name string) (string, error)
 	// Screenshot takes a screenshot of the attribute scroll'ing if necessary.
 	Screenshot(scroll bool) ([]byte, error)
+	// EnsureInteractable checks, without interacting with the element, that a
+	// user could interact with it: that it is displayed, enabled, has a
+	// non-zero size and is not covered by another element at its center
+	// point, where clicks land. The element is scrolled into view if needed.
+	// It returns a *NotInteractableError naming the first check that failed.
+	//
+	// Elements returned by FindElementInAnyFrame are checked in their frame,
+	// whatever the current browsing context, and the frames containing them
+	// must not be covered either. Other elements are checked in the current
+	// browsing context, which must contain them.
+	EnsureInteractable() error
+	// ID returns the reference of the element assigned by the remote end. It
+	// identifies the element within its session, e.g. in logs, and can be
+	// turned back into a WebElement with WebDriver.ElementFromID.
+	ID() string
 }
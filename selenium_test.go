@@ -171,6 +171,9 @@ func runChromeTests(t *testing.T, c seleniumtest.Config) {
 
 	seleniumtest.RunCommonTests(t, c)
 	seleniumtest.RunChromeTests(t, c)
+	t.Run("Conformance", func(t *testing.T) {
+		seleniumtest.RunConformance(t, seleniumtest.RemoteFactory(t, c))
+	})
 
 	if err := s.Stop(); err != nil {
 		t.Fatalf("Error stopping the ChromeDriver service: %v", err)
@@ -0,0 +1,82 @@
+// Package seleniumotel traces the commands of WebDriver sessions with
+// OpenTelemetry, so that they show in distributed traces along with the spans
+// of the application under test:
+//
+//	wd, err := selenium.NewRemoteContext(ctx, caps, urlPrefix, seleniumotel.WithTracing(nil))
+//	if err != nil {
+//		...
+//	}
+//	ctx, span := tracer.Start(ctx, "TestCheckout")
+//	defer span.End()
+//	wd = wd.WithContext(ctx)
+//
+// Each command is a client span, named after the command, e.g. "WebDriver
+// POST /element/:id/click", a child of the span in the context of the view of
+// the session that sends it. To propagate the spans to the remote end, such
+// as a Selenium Grid that exports its own traces, send the commands with an
+// instrumented HTTP client set with selenium.WithHTTPClient.
+package seleniumotel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/LoveOyy/selenium"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName is the name of the tracer of the spans.
+const instrumentationName = "github.com/LoveOyy/selenium/seleniumotel"
+
+// The attributes of the spans, in addition to http.status_code.
+const (
+	// CommandKey is the command, e.g. "POST /element/:id/click".
+	CommandKey = attribute.Key("webdriver.command")
+	// SessionIDKey is the ID of the WebDriver session.
+	SessionIDKey = attribute.Key("webdriver.session_id")
+	// ErrorKey is the WebDriver error code of a failed command, e.g. "no
+	// such element".
+	ErrorKey = attribute.Key("webdriver.error")
+)
+
+// httpStatusCodeKey is the attribute of the HTTP status code, from the
+// semantic conventions of OpenTelemetry.
+const httpStatusCodeKey = attribute.Key("http.status_code")
+
+// WithTracing makes the session record a span for each of its commands with
+// a tracer of tp, or of the global TracerProvider if tp is nil.
+func WithTracing(tp trace.TracerProvider) selenium.SessionOption {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	tracer := tp.Tracer(instrumentationName)
+	return selenium.WithCommandSpans(func(ctx context.Context, cmd selenium.CommandSpan) (context.Context, func(selenium.CommandSpan)) {
+		ctx, span := tracer.Start(ctx, "WebDriver "+cmd.Command,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithTimestamp(cmd.Start),
+			trace.WithAttributes(CommandKey.String(cmd.Command), SessionIDKey.String(cmd.SessionID)),
+		)
+		return ctx, func(cmd selenium.CommandSpan) {
+			endSpan(span, cmd)
+		}
+	})
+}
+
+// endSpan ends span with the result of the completed command cmd.
+func endSpan(span trace.Span, cmd selenium.CommandSpan) {
+	if cmd.HTTPStatus != 0 {
+		span.SetAttributes(httpStatusCodeKey.Int(cmd.HTTPStatus))
+	}
+	if cmd.Err != nil {
+		var e *selenium.Error
+		if errors.As(cmd.Err, &e) {
+			span.SetAttributes(ErrorKey.String(string(e.Code())))
+		}
+		span.RecordError(cmd.Err)
+		span.SetStatus(codes.Error, cmd.Err.Error())
+	}
+	span.End(trace.WithTimestamp(cmd.Start.Add(cmd.Duration)))
+}
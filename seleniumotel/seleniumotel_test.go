@@ -0,0 +1,167 @@
+package seleniumotel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordedSpan is a span ended by a recordingProvider.
+type recordedSpan struct {
+	name       string
+	kind       trace.SpanKind
+	parent     interface{}
+	attrs      map[attribute.Key]attribute.Value
+	status     codes.Code
+	err        error
+	start, end time.Time
+}
+
+// recordingProvider records the spans of its tracers when they end.
+type recordingProvider struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (p *recordingProvider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{p}
+}
+
+type recordingTracer struct{ p *recordingProvider }
+
+type parentKey struct{}
+
+func (t recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	config := trace.NewSpanStartConfig(opts...)
+	s := &recordingSpan{
+		Span: trace.SpanFromContext(ctx),
+		p:    t.p,
+		r: &recordedSpan{
+			name:   name,
+			kind:   config.SpanKind(),
+			parent: ctx.Value(parentKey{}),
+			attrs:  make(map[attribute.Key]attribute.Value),
+			start:  config.Timestamp(),
+		},
+	}
+	s.SetAttributes(config.Attributes()...)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// recordingSpan records its attributes, status and error; its other methods
+// are those of a non-recording span.
+type recordingSpan struct {
+	trace.Span
+	p *recordingProvider
+	r *recordedSpan
+}
+
+func (s *recordingSpan) SetAttributes(kvs ...attribute.KeyValue) {
+	for _, kv := range kvs {
+		s.r.attrs[kv.Key] = kv.Value
+	}
+}
+
+func (s *recordingSpan) SetStatus(code codes.Code, _ string) { s.r.status = code }
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) { s.r.err = err }
+
+func (s *recordingSpan) End(opts ...trace.SpanEndOption) {
+	config := trace.NewSpanEndConfig(opts...)
+	s.r.end = config.Timestamp()
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+	s.p.spans = append(s.p.spans, s.r)
+}
+
+// fakeDriver serves a session whose URL is about:blank and which has no
+// elements.
+func fakeDriver(t *testing.T) string {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /session":
+			json.NewEncoder(w).Encode(map[string]interface{}{"value": map[string]interface{}{
+				"sessionId":    "s1",
+				"capabilities": map[string]string{"browserName": "chrome"},
+			}})
+		case "GET /session/s1/url":
+			w.Write([]byte(`{"value": "about:blank"}`))
+		case "POST /session/s1/element":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"value": {"error": "no such element", "message": "not found"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"value": {"error": "unknown command", "message": ""}}`))
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s.URL
+}
+
+func TestWithTracing(t *testing.T) {
+	tp := new(recordingProvider)
+	wd, err := selenium.NewRemoteContext(context.Background(), nil, fakeDriver(t), WithTracing(tp))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	wd = wd.WithContext(context.WithValue(context.Background(), parentKey{}, "test"))
+	if _, err := wd.CurrentURL(); err != nil {
+		t.Fatalf("wd.CurrentURL() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(selenium.ByID, "missing"); err == nil {
+		t.Fatal("wd.FindElement() returned no error")
+	}
+
+	if len(tp.spans) != 2 {
+		t.Fatalf("%d spans were recorded, want 2", len(tp.spans))
+	}
+	for _, s := range tp.spans {
+		if s.kind != trace.SpanKindClient {
+			t.Errorf("span %q has kind %v, want %v", s.name, s.kind, trace.SpanKindClient)
+		}
+		if s.parent != "test" {
+			t.Errorf("span %q was not started in the context of the session", s.name)
+		}
+		if s.start.IsZero() || s.end.Before(s.start) {
+			t.Errorf("span %q lasted from %v to %v", s.name, s.start, s.end)
+		}
+		if got := s.attrs[SessionIDKey].AsString(); got != "s1" {
+			t.Errorf("span %q has session ID %q, want s1", s.name, got)
+		}
+	}
+
+	get := tp.spans[0]
+	if get.name != "WebDriver GET /url" {
+		t.Errorf("the span of wd.CurrentURL() is named %q, want %q", get.name, "WebDriver GET /url")
+	}
+	if got := get.attrs[httpStatusCodeKey].AsInt64(); got != http.StatusOK {
+		t.Errorf("the span of wd.CurrentURL() has HTTP status %d, want %d", got, http.StatusOK)
+	}
+	if get.status == codes.Error || get.err != nil {
+		t.Errorf("the span of wd.CurrentURL() has an error: %v", get.err)
+	}
+
+	find := tp.spans[1]
+	if got := find.attrs[CommandKey].AsString(); got != "POST /element" {
+		t.Errorf("the span of wd.FindElement() has command %q, want POST /element", got)
+	}
+	if got := find.attrs[ErrorKey].AsString(); got != "no such element" {
+		t.Errorf("the span of wd.FindElement() has error %q, want no such element", got)
+	}
+	if got := find.attrs[httpStatusCodeKey].AsInt64(); got != http.StatusNotFound {
+		t.Errorf("the span of wd.FindElement() has HTTP status %d, want %d", got, http.StatusNotFound)
+	}
+	if find.status != codes.Error || find.err == nil {
+		t.Errorf("the span of wd.FindElement() has status %v and error %v, want an error", find.status, find.err)
+	}
+}
@@ -0,0 +1,384 @@
+// Package seleniumtest provides the TestMain of packages of browser tests: it
+// starts the drivers and a pool of sessions shared by the tests, and tears
+// them down when the tests end or are interrupted.
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(seleniumtest.Main(m, seleniumtest.HarnessConfig{
+//			Browsers: []string{"chrome"},
+//			PoolSize: 2,
+//		}))
+//	}
+//
+//	func TestLogin(t *testing.T) {
+//		wd := seleniumtest.SharedPool().Session(t, "chrome")
+//		// ...
+//	}
+package seleniumtest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// RequireBrowserEnv is the environment variable that, when set to a
+// non-empty value, e.g. on CI, makes Main fail when a browser or its driver
+// is not available, instead of skipping the tests that need it.
+const RequireBrowserEnv = "SELENIUM_REQUIRE_BROWSER"
+
+// HarnessConfig configures Main.
+type HarnessConfig struct {
+	// Browsers are the browsers to start: "chrome", "firefox" or both. The
+	// default is Chrome.
+	Browsers []string
+	// Headful shows the browser windows instead of running the browsers
+	// headless. On Linux, a virtual frame buffer is started if DISPLAY is not
+	// set.
+	Headful bool
+	// PoolSize is the maximum number of sessions of each browser; parallel
+	// tests wait for a free one. The default is 1.
+	PoolSize int
+	// ArtifactDir, if set, is the directory where the screenshot, the page
+	// source and the URL of the session of a failed test are saved, in a
+	// subdirectory named after the test.
+	ArtifactDir string
+	// Reset, if set, is called on a session before each test it is given to,
+	// instead of deleting the cookies and navigating to about:blank, e.g. to
+	// clear more state with WebDriver.ResetState. If it returns an error, the
+	// session is replaced by a new one.
+	Reset func(wd selenium.WebDriver) error
+	// Options are passed to selenium.QuickChromeService and
+	// selenium.QuickFirefoxService, e.g. to choose the driver binaries.
+	Options []selenium.QuickOption
+}
+
+var (
+	sharedMu sync.Mutex
+	shared   *Pool
+)
+
+// SharedPool returns the pool of sessions started by Main.
+func SharedPool() *Pool {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	return shared
+}
+
+// Main starts the browsers of cfg, runs the tests of m and tears everything
+// down, then checks with selenium.LeakCheck that no process or temporary file
+// was left behind. It returns the exit code for os.Exit. On SIGINT or SIGTERM,
+// it tears everything down before exiting.
+//
+// If a browser or its driver is not available, the tests that ask for its
+// sessions are skipped, with the reason, unless RequireBrowserEnv is set, in
+// which case Main fails without running the tests.
+func Main(m *testing.M, cfg HarnessConfig) int {
+	p := newPool(cfg)
+	if len(p.skip) > 0 && os.Getenv(RequireBrowserEnv) != "" {
+		for _, name := range p.order {
+			if reason, ok := p.skip[name]; ok {
+				fmt.Fprintf(os.Stderr, "seleniumtest: %s\n", reason)
+			}
+		}
+		p.close()
+		return 1
+	}
+	for _, name := range p.order {
+		if reason, ok := p.skip[name]; ok {
+			fmt.Fprintf(os.Stderr, "seleniumtest: skipping the %s tests: %s (set %s to fail instead)\n", name, reason, RequireBrowserEnv)
+		}
+	}
+	sharedMu.Lock()
+	shared = p
+	sharedMu.Unlock()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			fmt.Fprintf(os.Stderr, "seleniumtest: %v received, stopping the browsers\n", sig)
+			p.close()
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+
+	code := m.Run()
+	signal.Stop(signals)
+	close(done)
+	p.close()
+	if r := selenium.LeakCheck(); !r.Empty() {
+		fmt.Fprintf(os.Stderr, "seleniumtest: leaks after the tests:\n%s", r)
+		if code == 0 {
+			code = 1
+		}
+	}
+	return code
+}
+
+// Pool lends the sessions of the browsers started by Main to the tests.
+type Pool struct {
+	cfg HarnessConfig
+	// skip holds the reasons why the tests of the browsers that are not
+	// available are skipped, by browser.
+	skip     map[string]string
+	browsers map[string]*browserPool
+	// order holds the browsers of the configuration, available or not.
+	order []string
+	once  sync.Once
+}
+
+// browserPool holds the sessions of a browser.
+type browserPool struct {
+	service *selenium.Service
+	caps    selenium.Capabilities
+	// slots limits the number of sessions in use.
+	slots chan struct{}
+
+	mu   sync.Mutex
+	idle []selenium.WebDriver
+	// all are the sessions not quit yet, idle or in use.
+	all map[selenium.WebDriver]bool
+}
+
+// newPool starts the drivers of cfg, with a session each to check that the
+// browsers work. The browsers that fail have a skip reason in the pool; the
+// others are available.
+func newPool(cfg HarnessConfig) *Pool {
+	if len(cfg.Browsers) == 0 {
+		cfg.Browsers = []string{"chrome"}
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 1
+	}
+	p := &Pool{cfg: cfg, skip: make(map[string]string), browsers: make(map[string]*browserPool)}
+	opts := cfg.Options
+	if cfg.Headful {
+		opts = append([]selenium.QuickOption{selenium.WithHeadful()}, opts...)
+	}
+	for _, name := range cfg.Browsers {
+		if p.configured(name) {
+			continue
+		}
+		p.order = append(p.order, name)
+		var start func(...selenium.QuickOption) (*selenium.Service, selenium.Capabilities, error)
+		switch name {
+		case "chrome":
+			start = selenium.QuickChromeService
+		case "firefox":
+			start = selenium.QuickFirefoxService
+		default:
+			p.skip[name] = fmt.Sprintf("unknown browser %q, want chrome or firefox", name)
+			continue
+		}
+		s, caps, err := start(opts...)
+		if err != nil {
+			p.skip[name] = fmt.Sprintf("%s is not available: %v", name, err)
+			continue
+		}
+		b := &browserPool{
+			service: s,
+			caps:    caps,
+			slots:   make(chan struct{}, cfg.PoolSize),
+			all:     make(map[selenium.WebDriver]bool),
+		}
+		wd, err := b.newSession()
+		if err != nil {
+			p.skip[name] = fmt.Sprintf("%s is not available: %v", name, err)
+			if err := s.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "seleniumtest: stopping the %s driver: %v\n", name, err)
+			}
+			continue
+		}
+		b.idle = append(b.idle, wd)
+		p.browsers[name] = b
+	}
+	return p
+}
+
+// configured reports whether browser is in the configuration of the pool.
+func (p *Pool) configured(browser string) bool {
+	for _, name := range p.order {
+		if name == browser {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *browserPool) newSession() (selenium.WebDriver, error) {
+	caps := make(selenium.Capabilities)
+	for k, v := range b.caps {
+		caps[k] = v
+	}
+	wd, err := selenium.NewRemote(caps, b.service.URLPrefix())
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	b.all[wd] = true
+	b.mu.Unlock()
+	return wd, nil
+}
+
+func (b *browserPool) quit(wd selenium.WebDriver) {
+	b.mu.Lock()
+	delete(b.all, wd)
+	b.mu.Unlock()
+	wd.Quit()
+}
+
+// Session returns a session of browser, "chrome" or "firefox", for the test
+// t, or of the first browser of the configuration if browser is empty. The
+// session is reset first, and returned to the pool when t ends; the session
+// of a failed test is quit, after its artifacts are saved. Session skips t if
+// the browser is not available, and waits for a session if all of them are
+// in use.
+func (p *Pool) Session(t testing.TB, browser string) selenium.WebDriver {
+	t.Helper()
+	if p == nil {
+		t.Skip("seleniumtest: no session pool; call seleniumtest.Main from TestMain")
+	}
+	if browser == "" {
+		browser = p.order[0]
+	}
+	if reason, ok := p.skip[browser]; ok {
+		t.Skipf("seleniumtest: %s", reason)
+	}
+	b, ok := p.browsers[browser]
+	if !ok {
+		t.Fatalf("seleniumtest: browser %q is not in the configuration, which has %q", browser, p.order)
+	}
+
+	b.slots <- struct{}{}
+	wd, err := p.get(b)
+	if err != nil {
+		<-b.slots
+		t.Fatalf("seleniumtest: getting a %s session: %v", browser, err)
+	}
+	if err := wd.SetTestName(t.Name()); err != nil {
+		t.Logf("seleniumtest: wd.SetTestName(%q) returned error: %v", t.Name(), err)
+	}
+	t.Cleanup(func() {
+		if err := p.release(b, wd, t.Name(), t.Failed()); err != nil {
+			t.Logf("seleniumtest: saving the artifacts: %v", err)
+		}
+	})
+	return wd
+}
+
+// release returns the session of the test named name to the pool, or quits it
+// after saving its artifacts if the test failed.
+func (p *Pool) release(b *browserPool, wd selenium.WebDriver, name string, failed bool) error {
+	defer func() { <-b.slots }()
+	if !failed {
+		b.mu.Lock()
+		b.idle = append(b.idle, wd)
+		b.mu.Unlock()
+		return nil
+	}
+	var err error
+	if p.cfg.ArtifactDir != "" {
+		err = saveArtifacts(wd, filepath.Join(p.cfg.ArtifactDir, artifactName(name)))
+	}
+	b.quit(wd)
+	return err
+}
+
+// get returns an idle session, reset, or a new one.
+func (p *Pool) get(b *browserPool) (selenium.WebDriver, error) {
+	for {
+		b.mu.Lock()
+		if len(b.idle) == 0 {
+			b.mu.Unlock()
+			return b.newSession()
+		}
+		wd := b.idle[len(b.idle)-1]
+		b.idle = b.idle[:len(b.idle)-1]
+		b.mu.Unlock()
+		if err := p.reset(wd); err != nil {
+			b.quit(wd)
+			continue
+		}
+		return wd, nil
+	}
+}
+
+func (p *Pool) reset(wd selenium.WebDriver) error {
+	if p.cfg.Reset != nil {
+		return p.cfg.Reset(wd)
+	}
+	if err := wd.DeleteAllCookies(); err != nil {
+		return err
+	}
+	return wd.Get("about:blank")
+}
+
+// close quits the sessions and stops the drivers.
+func (p *Pool) close() {
+	p.once.Do(func() {
+		for _, name := range p.order {
+			b, ok := p.browsers[name]
+			if !ok {
+				continue
+			}
+			b.mu.Lock()
+			var sessions []selenium.WebDriver
+			for wd := range b.all {
+				sessions = append(sessions, wd)
+			}
+			b.all = make(map[selenium.WebDriver]bool)
+			b.idle = nil
+			b.mu.Unlock()
+			for _, wd := range sessions {
+				wd.Quit()
+			}
+			if err := b.service.Stop(); err != nil {
+				fmt.Fprintf(os.Stderr, "seleniumtest: stopping the %s driver: %v\n", name, err)
+			}
+		}
+	})
+}
+
+// unsafeChars are the characters replaced in the names of the artifact
+// directories.
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// artifactName returns the name of the artifact directory of a test.
+func artifactName(test string) string {
+	return unsafeChars.ReplaceAllString(test, "_")
+}
+
+// saveArtifacts saves the screenshot, the page source and the URL of the
+// session to dir.
+func saveArtifacts(wd selenium.WebDriver, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	var firstErr error
+	save := func(name string, data []byte, err error) {
+		if err == nil {
+			err = ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	png, err := wd.Screenshot()
+	save("screenshot.png", png, err)
+	source, err := wd.PageSource()
+	save("source.html", []byte(source), err)
+	url, err := wd.CurrentURL()
+	save("url.txt", []byte(url+"\n"), err)
+	return firstErr
+}
@@ -0,0 +1,170 @@
+package seleniumtest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LoveOyy/selenium"
+)
+
+// fakeSession is a session whose commands used by the pool are recorded.
+type fakeSession struct {
+	selenium.WebDriver
+	calls     []string
+	quitCount int
+}
+
+func (s *fakeSession) DeleteAllCookies() error {
+	s.calls = append(s.calls, "DeleteAllCookies")
+	return nil
+}
+
+func (s *fakeSession) Get(url string) error {
+	s.calls = append(s.calls, "Get "+url)
+	return nil
+}
+
+func (s *fakeSession) SetTestName(name string) error {
+	s.calls = append(s.calls, "SetTestName "+name)
+	return nil
+}
+
+func (s *fakeSession) Quit() error {
+	s.quitCount++
+	return nil
+}
+
+func (s *fakeSession) Screenshot() ([]byte, error) { return []byte("png"), nil }
+func (s *fakeSession) PageSource() (string, error) { return "<html></html>", nil }
+func (s *fakeSession) CurrentURL() (string, error) { return "http://example.com/", nil }
+
+func newFakePool(cfg HarnessConfig, sessions ...selenium.WebDriver) *Pool {
+	b := &browserPool{
+		slots: make(chan struct{}, 1),
+		all:   make(map[selenium.WebDriver]bool),
+		idle:  sessions,
+	}
+	for _, wd := range sessions {
+		b.all[wd] = true
+	}
+	return &Pool{cfg: cfg, browsers: map[string]*browserPool{"chrome": b}, order: []string{"chrome"}}
+}
+
+func TestPoolSession(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seleniumtest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := new(fakeSession)
+	p := newFakePool(HarnessConfig{ArtifactDir: dir}, s)
+	t.Run("Passing", func(t *testing.T) {
+		if wd := p.Session(t, ""); wd != s {
+			t.Errorf("p.Session() = %v, want the idle session", wd)
+		}
+	})
+	want := []string{"DeleteAllCookies", "Get about:blank", "SetTestName TestPoolSession/Passing"}
+	if strings.Join(s.calls, ",") != strings.Join(want, ",") {
+		t.Errorf("p.Session() sent %q, want %q", s.calls, want)
+	}
+	if b := p.browsers["chrome"]; len(b.idle) != 1 || s.quitCount != 0 {
+		t.Errorf("after a passing test, the pool has %d idle sessions and the session was quit %d times, want 1 and 0", len(b.idle), s.quitCount)
+	}
+
+	// The artifacts of a failing test are saved and its session is quit.
+	b := p.browsers["chrome"]
+	b.slots <- struct{}{}
+	wd, err := p.get(b)
+	if err != nil {
+		t.Fatalf("p.get() returned error: %v", err)
+	}
+	if err := p.release(b, wd, "TestLogin/with SSO", true); err != nil {
+		t.Fatalf("p.release() of a failed test returned error: %v", err)
+	}
+	if s.quitCount != 1 || len(b.idle) != 0 || len(b.slots) != 0 {
+		t.Errorf("after a failing test, the session was quit %d times, the pool has %d idle sessions and %d used slots, want 1, 0 and 0", s.quitCount, len(b.idle), len(b.slots))
+	}
+	for _, name := range []string{"screenshot.png", "source.html", "url.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, "TestLogin_with_SSO", name)); err != nil {
+			t.Errorf("the artifact %s of the failing test was not saved: %v", name, err)
+		}
+	}
+}
+
+func TestPoolSessionReset(t *testing.T) {
+	s := &fakeSession{}
+	p := newFakePool(HarnessConfig{Reset: func(wd selenium.WebDriver) error {
+		wd.(*fakeSession).calls = append(wd.(*fakeSession).calls, "custom reset")
+		return nil
+	}}, s)
+	t.Run("Test", func(t *testing.T) {
+		p.Session(t, "chrome")
+	})
+	if s.calls[0] != "custom reset" {
+		t.Errorf("p.Session() sent %q, want the custom reset first", s.calls)
+	}
+}
+
+func TestPoolSkip(t *testing.T) {
+	p := newPool(HarnessConfig{Browsers: []string{"safari"}})
+	if !strings.Contains(p.skip["safari"], "safari") {
+		t.Errorf("newPool() for Safari has skip reason %q, want it to name Safari", p.skip["safari"])
+	}
+	p = newPool(HarnessConfig{Options: []selenium.QuickOption{selenium.WithDriverPath("/nonexistent/chromedriver")}})
+	if p.skip["chrome"] == "" {
+		t.Fatal("newPool() with a missing driver has no skip reason")
+	}
+	var sub *testing.T
+	t.Run("Skipped", func(t *testing.T) {
+		sub = t
+		p.Session(t, "chrome")
+		t.Error("p.Session() did not skip the test")
+	})
+	if !sub.Skipped() {
+		t.Error("p.Session() did not skip the test")
+	}
+	t.Run("NoPool", func(t *testing.T) {
+		sub = t
+		(*Pool)(nil).Session(t, "")
+	})
+	if !sub.Skipped() {
+		t.Error("Session() of a nil pool did not skip the test")
+	}
+}
+
+func TestPoolSkipBrowser(t *testing.T) {
+	s := new(fakeSession)
+	p := newFakePool(HarnessConfig{}, s)
+	p.skip = map[string]string{"firefox": "firefox is not available"}
+	p.order = append(p.order, "firefox")
+
+	for _, test := range []struct {
+		browser  string
+		wantSkip bool
+	}{
+		{"", false},
+		{"chrome", false},
+		{"firefox", true},
+	} {
+		var sub *testing.T
+		t.Run("Browser", func(t *testing.T) {
+			sub = t
+			if wd := p.Session(t, test.browser); wd != s {
+				t.Errorf("p.Session(%q) = %v, want the chrome session", test.browser, wd)
+			}
+		})
+		if got := sub.Skipped(); got != test.wantSkip {
+			t.Errorf("p.Session(%q) skipped the test = %t, want %t", test.browser, got, test.wantSkip)
+		}
+	}
+}
+
+func TestArtifactName(t *testing.T) {
+	if got, want := artifactName("TestLogin/with SSO#01"), "TestLogin_with_SSO_01"; got != want {
+		t.Errorf("artifactName() = %q, want %q", got, want)
+	}
+}
@@ -2,6 +2,7 @@ package selenium
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -12,6 +13,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -103,6 +106,16 @@ func Output(w io.Writer) ServiceOption {
 	}
 }
 
+// KeepOutput makes the service keep the last lines of the output of the
+// WebDriver service, returned by RecentOutput and included in the errors
+// reporting a browser crash in sessions created with WithService.
+func KeepOutput(lines int) ServiceOption {
+	return func(s *Service) error {
+		s.recent = &lineRing{max: lines}
+		return nil
+	}
+}
+
 // GeckoDriver sets the path to the geckodriver binary for the Selenium Server.
 // Unlike other drivers, Selenium Server does not support specifying the
 // geckodriver path at runtime. This ServiceOption is only useful when calling
@@ -142,12 +155,36 @@ func HTMLUnit(path string) ServiceOption {
 	}
 }
 
+// GeckoProfileRoot sets the directory in which GeckoDriver creates the
+// temporary profiles of the browsers, instead of the temporary directory of
+// the system. This ServiceOption is only useful when calling
+// NewGeckoDriverService.
+func GeckoProfileRoot(dir string) ServiceOption {
+	return func(s *Service) error {
+		s.profileRoot = dir
+		return nil
+	}
+}
+
+// SafariDiagnose makes safaridriver log the HTTP traffic of its sessions to
+// ~/Library/Logs/com.apple.WebDriver. This ServiceOption is only useful when
+// calling NewSafariDriverService.
+func SafariDiagnose() ServiceOption {
+	return func(s *Service) error {
+		s.diagnose = true
+		return nil
+	}
+}
+
 // Service controls a locally-running Selenium subprocess.
 type Service struct {
 	port            int
 	addr            string
 	cmd             *exec.Cmd
 	shutdownURLPath string
+	// processGroup is true if the service runs in its own process group, along
+	// with the browsers it starts.
+	processGroup bool
 
 	display, xauthPath string
 	xvfb               *FrameBuffer
@@ -155,8 +192,86 @@ type Service struct {
 	geckoDriverPath, javaPath string
 	chromeDriverPath          string
 	htmlUnitPath              string
+	// profileRoot is passed to GeckoDriver; see GeckoProfileRoot.
+	profileRoot string
+	// diagnose is passed to safaridriver; see SafariDiagnose.
+	diagnose bool
 
 	output io.Writer
+	// recent, if not nil, keeps the last lines of output; see KeepOutput.
+	recent *lineRing
+	// logFile, if not nil, receives the output too; see LogFile.
+	logFile *rotatingFile
+
+	// detached is 1 if sessions with a detached Chrome browser were created
+	// with the service, given to them with WithService, since it started. It
+	// is accessed atomically.
+	detached int32
+}
+
+// URLPrefix returns the address of the service, to create sessions on it
+// with NewRemote.
+func (s *Service) URLPrefix() string {
+	return s.addr
+}
+
+// RecentOutput returns the last lines of output of the service, if it was
+// started with KeepOutput.
+func (s *Service) RecentOutput() []string {
+	if s.recent == nil {
+		return nil
+	}
+	return s.recent.lines()
+}
+
+// lineRing is a writer that keeps the last max lines written to it.
+type lineRing struct {
+	max int
+
+	mu      sync.Mutex
+	ring    []string
+	next    int
+	partial []byte
+}
+
+func (r *lineRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data := append(r.partial, p...)
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		r.add(string(bytes.TrimRight(data[:i], "\r")))
+		data = data[i+1:]
+	}
+	r.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+func (r *lineRing) add(line string) {
+	if r.max <= 0 {
+		return
+	}
+	if len(r.ring) < r.max {
+		r.ring = append(r.ring, line)
+		return
+	}
+	r.ring[r.next] = line
+	r.next = (r.next + 1) % r.max
+}
+
+// lines returns the lines kept, oldest first, including an unterminated last
+// line.
+func (r *lineRing) lines() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lines := append(append([]string(nil), r.ring[r.next:]...), r.ring[:r.next]...)
+	if len(r.partial) > 0 {
+		lines = append(lines, string(r.partial))
+	}
+	return lines
 }
 
 // FrameBuffer returns the FrameBuffer if one was started by the service and nil otherwise.
@@ -202,6 +317,7 @@ func NewChromeDriverService(path string, port int, opts ...ServiceOption) (*Serv
 		return nil, err
 	}
 	s.shutdownURLPath = "/shutdown"
+	s.processGroup = setProcessGroup(s.cmd)
 	if err := s.start(port); err != nil {
 		return nil, err
 	}
@@ -215,6 +331,32 @@ func NewGeckoDriverService(path string, port int, opts ...ServiceOption) (*Servi
 	if err != nil {
 		return nil, err
 	}
+	if s.profileRoot != "" {
+		s.cmd.Args = append(s.cmd.Args, "--profile-root", s.profileRoot)
+	}
+	if err := s.start(port); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewSafariDriverService starts a safaridriver instance in the background. If
+// path is empty, the safaridriver of Safari, /usr/bin/safaridriver, is used;
+// Safari Technology Preview has its own, in
+// "/Applications/Safari Technology Preview.app/Contents/MacOS/safaridriver".
+// safaridriver must have been enabled once with "safaridriver --enable".
+func NewSafariDriverService(path string, port int, opts ...ServiceOption) (*Service, error) {
+	if path == "" {
+		path = "/usr/bin/safaridriver"
+	}
+	cmd := exec.Command(path, "--port", strconv.Itoa(port))
+	s, err := newService(cmd, "", port, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if s.diagnose {
+		s.cmd.Args = append(s.cmd.Args, "--diagnose")
+	}
 	if err := s.start(port); err != nil {
 		return nil, err
 	}
@@ -224,15 +366,36 @@ func NewGeckoDriverService(path string, port int, opts ...ServiceOption) (*Servi
 func newService(cmd *exec.Cmd, urlPrefix string, port int, opts ...ServiceOption) (*Service, error) {
 	s := &Service{
 		port: port,
-		addr: fmt.Sprintf("http://localhost:%d%s", port, urlPrefix),
+		addr: joinURL(fmt.Sprintf("http://localhost:%d", port), urlPrefix),
 	}
 	for _, opt := range opts {
 		if err := opt(s); err != nil {
+			if s.logFile != nil {
+				s.logFile.Close()
+			}
 			return nil, err
 		}
 	}
-	cmd.Stderr = s.output
-	cmd.Stdout = s.output
+	var writers []io.Writer
+	if s.output != nil {
+		writers = append(writers, s.output)
+	}
+	if s.recent != nil {
+		writers = append(writers, s.recent)
+	}
+	if s.logFile != nil {
+		writers = append(writers, s.logFile)
+	}
+	var output io.Writer
+	switch len(writers) {
+	case 0:
+	case 1:
+		output = writers[0]
+	default:
+		output = io.MultiWriter(writers...)
+	}
+	cmd.Stderr = output
+	cmd.Stdout = output
 	cmd.Env = os.Environ()
 	// TODO(minusnine): Pdeathsig is only supported on Linux. Somehow, make sure
 	// process cleanup happens as gracefully as possible.
@@ -248,27 +411,167 @@ func newService(cmd *exec.Cmd, urlPrefix string, port int, opts ...ServiceOption
 
 func (s *Service) start(port int) error {
 	if err := s.cmd.Start(); err != nil {
+		s.closeLogFile()
 		return err
 	}
+	trackService(s)
 
+	var lastErr error
 	for i := 0; i < 30; i++ {
 		time.Sleep(time.Second)
-		resp, err := http.Get(s.addr + "/status")
-		if err == nil {
-			resp.Body.Close()
-			switch resp.StatusCode {
-			// Selenium <3 returned Forbidden and BadRequest. ChromeDriver and
-			// Selenium 3 return OK.
-			case http.StatusForbidden, http.StatusBadRequest, http.StatusOK:
-				return nil
-			}
+		ready, err := serviceReady(joinURL(s.addr, "/status"))
+		if ready {
+			return nil
+		}
+		if err != nil {
+			lastErr = err
 		}
 	}
+	// The caller gets no Service to stop.
+	s.cmd.Process.Kill()
+	s.cmd.Wait()
+	if s.processGroup {
+		killProcessGroup(s.cmd.Process.Pid)
+	}
+	untrackService(s)
+	s.closeLogFile()
+	if _, ok := lastErr.(*NonJSONResponseError); ok {
+		return fmt.Errorf("server on port %d did not respond with JSON: %w", port, lastErr)
+	}
 	return fmt.Errorf("server did not respond on port %d", port)
 }
 
+// serviceReady reports whether the service answering statusURL is ready to
+// accept sessions. The error explains why it is not, if known.
+func serviceReady(statusURL string) (bool, error) {
+	resp, err := http.Get(statusURL)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	// Selenium <3 returned Forbidden and BadRequest. ChromeDriver and
+	// Selenium 3 return OK.
+	case http.StatusForbidden, http.StatusBadRequest:
+		return true, nil
+	case http.StatusOK:
+		// Something else than the service, such as a proxy, may answer on the
+		// port.
+		body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return false, err
+		}
+		if err := checkJSONResponse(resp, body); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, fmt.Errorf("unexpected status %s", resp.Status)
+}
+
+// noteDetachedSession records that a session with a detached browser was
+// created with the service.
+func (s *Service) noteDetachedSession() {
+	atomic.StoreInt32(&s.detached, 1)
+}
+
+// hasDetachedSessions reports whether sessions with a detached browser were
+// created with the service.
+func (s *Service) hasDetachedSessions() bool {
+	return atomic.LoadInt32(&s.detached) == 1
+}
+
+// processExitTimeout is how long Stop waits for the processes of the service
+// to exit once killed. It is a variable for tests.
+var processExitTimeout = 2 * time.Second
+
+// SurvivingProcessesError is returned by Service.Stop when processes started
+// by the service are still running after it killed them. They are reported by
+// LeakCheck as long as they run.
+type SurvivingProcessesError struct {
+	// Group is the process group of the service.
+	Group int
+	// PIDs are the processes still running, if they can be listed on this
+	// platform.
+	PIDs []int
+}
+
+func (e *SurvivingProcessesError) Error() string {
+	if len(e.PIDs) == 0 {
+		return fmt.Sprintf("processes of the service are still running in process group %d", e.Group)
+	}
+	return fmt.Sprintf("processes %v of the service are still running in process group %d", e.PIDs, e.Group)
+}
+
+// Processes returns the process ID of the service followed by those of the
+// processes it started that are still running, such as browsers, found through
+// the process group of the service. The latter are only found on Linux, for
+// ChromeDriver services.
+func (s *Service) Processes() []int {
+	pid := s.cmd.Process.Pid
+	pids := []int{pid}
+	if !s.processGroup {
+		return pids
+	}
+	for _, p := range processGroupPIDs(pid) {
+		if p != pid {
+			pids = append(pids, p)
+		}
+	}
+	return pids
+}
+
+// outputPipes returns the number of pipes open to copy the output of the
+// service to the writer set with Output or KeepOutput, which are closed once
+// the service is stopped.
+func (s *Service) outputPipes() int {
+	if s.cmd.ProcessState != nil || s.cmd.Stdout == nil {
+		return 0
+	}
+	if _, ok := s.cmd.Stdout.(*os.File); ok {
+		return 0
+	}
+	// Stdout and Stderr are the same writer, which share a pipe.
+	return 1
+}
+
+// closeLogFile closes the file set with LogFile, once the output of the
+// service was copied.
+func (s *Service) closeLogFile() {
+	if s.logFile == nil {
+		return
+	}
+	if err := s.logFile.Close(); err != nil {
+		debugLog("error closing the log file %s: %v", s.logFile.path, err)
+	}
+}
+
+// waitProcessGroup waits up to processExitTimeout for the process group pgid
+// to be empty, and returns an error listing the processes still running
+// otherwise.
+func waitProcessGroup(pgid int) error {
+	deadline := time.Now().Add(processExitTimeout)
+	for processGroupRunning(pgid) {
+		if time.Now().After(deadline) {
+			pids := processGroupPIDs(pgid)
+			trackSurvivors(pids)
+			return &SurvivingProcessesError{Group: pgid, PIDs: pids}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return nil
+}
+
 // Stop shuts down the WebDriver service, and the X virtual frame buffer
 // if one was started.
+//
+// On Unix systems, browsers left running by ChromeDriver are killed as well,
+// unless sessions with Chrome's Detach option were created with the service:
+// those browsers are meant to outlive it. Stop then checks that they exited,
+// and returns a *SurvivingProcessesError otherwise. The sessions created with
+// the service are those created at its URLPrefix, or given it with
+// WithService; a detached session created through another URL without
+// WithService has its browser killed.
 func (s *Service) Stop() error {
 	// Selenium 3 stopped supporting the shutdown URL by default.
 	// https://github.com/SeleniumHQ/selenium/issues/2852
@@ -277,7 +580,7 @@ func (s *Service) Stop() error {
 			return err
 		}
 	} else {
-		resp, err := http.Get(s.addr + s.shutdownURLPath)
+		resp, err := http.Get(joinURL(s.addr, s.shutdownURLPath))
 		if err != nil {
 			return err
 		}
@@ -286,10 +589,21 @@ func (s *Service) Stop() error {
 	if err := s.cmd.Wait(); err != nil && err.Error() != "signal: killed" {
 		return err
 	}
+	untrackService(s)
+	s.closeLogFile()
+	var survivors error
+	if detached := atomic.SwapInt32(&s.detached, 0) == 1; s.processGroup && !detached {
+		if err := killProcessGroup(s.cmd.Process.Pid); err != nil {
+			return err
+		}
+		survivors = waitProcessGroup(s.cmd.Process.Pid)
+	}
 	if s.xvfb != nil {
-		return s.xvfb.Stop()
+		if err := s.xvfb.Stop(); err != nil {
+			return err
+		}
 	}
-	return nil
+	return survivors
 }
 
 // FrameBuffer controls an X virtual frame buffer running as a background
@@ -327,6 +641,7 @@ func NewFrameBufferWithOptions(options FrameBufferOptions) (*FrameBuffer, error)
 		return nil, err
 	}
 	authPath := auth.Name()
+	trackTempPath(authPath)
 	if err := auth.Close(); err != nil {
 		return nil, err
 	}
@@ -397,6 +712,7 @@ func (f FrameBuffer) Stop() error {
 		return err
 	}
 	os.Remove(f.AuthPath) // best effort removal; ignore error
+	untrackTempPath(f.AuthPath)
 	if err := f.cmd.Wait(); err != nil && err.Error() != "signal: killed" {
 		return err
 	}
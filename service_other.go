@@ -0,0 +1,31 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package selenium
+
+import "os/exec"
+
+// setProcessGroup reports that process groups are not supported on this
+// platform.
+func setProcessGroup(cmd *exec.Cmd) bool {
+	return false
+}
+
+func killProcessGroup(pid int) error {
+	return nil
+}
+
+func processGroupRunning(pgid int) bool {
+	return false
+}
+
+func processGroupPIDs(pgid int) []int {
+	return nil
+}
+
+// processRunning reports that processes cannot be checked on this platform;
+// LeakCheck only reports the processes found by Stop, which needs process
+// groups.
+func processRunning(pid int) bool {
+	return false
+}
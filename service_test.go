@@ -145,3 +145,20 @@ func TestFrameBuffer(t *testing.T) {
 		}
 	})
 }
+
+func TestLineRing(t *testing.T) {
+	r := &lineRing{max: 3}
+	for _, s := range []string{"one\ntwo\n", "thr", "ee\r\nfour\nfi"} {
+		r.Write([]byte(s))
+	}
+	got := r.lines()
+	want := []string{"two", "three", "four", "fi"}
+	if len(got) != len(want) {
+		t.Fatalf("r.lines() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("r.lines() = %q, want %q", got, want)
+		}
+	}
+}
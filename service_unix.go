@@ -0,0 +1,99 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+// +build darwin dragonfly freebsd linux netbsd openbsd
+
+package selenium
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup makes cmd start in a new process group, which the processes
+// it starts inherit, and reports whether it could.
+func setProcessGroup(cmd *exec.Cmd) bool {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = new(syscall.SysProcAttr)
+	}
+	cmd.SysProcAttr.Setpgid = true
+	return true
+}
+
+// killProcessGroup kills the processes remaining in the process group led by
+// pid.
+func killProcessGroup(pid int) error {
+	if err := syscall.Kill(-pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return err
+	}
+	return nil
+}
+
+// procStat returns the state and the process group of the process pid, read
+// from /proc, or false if it has no entry.
+func procStat(pid string) (state string, pgid int, ok bool) {
+	data, err := ioutil.ReadFile("/proc/" + pid + "/stat")
+	if err != nil {
+		return "", 0, false
+	}
+	// The fields after the command name, which may contain spaces and
+	// parentheses, are the state, the parent PID and the process group.
+	stat := string(data)
+	fields := strings.Fields(stat[strings.LastIndexByte(stat, ')')+1:])
+	if len(fields) < 3 {
+		return "", 0, false
+	}
+	pgid, err = strconv.Atoi(fields[2])
+	return fields[0], pgid, err == nil
+}
+
+// hasProc reports whether processes can be listed through /proc.
+func hasProc() bool {
+	_, _, ok := procStat("self")
+	return ok
+}
+
+// processGroupRunning reports whether processes remain in the process group
+// pgid. Zombies, which only wait to be reaped by their parent, are not
+// counted where /proc is available.
+func processGroupRunning(pgid int) bool {
+	if hasProc() {
+		return len(processGroupPIDs(pgid)) > 0
+	}
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// processGroupPIDs returns the processes in the process group pgid, except
+// zombies, read from /proc, or nil where /proc is not available.
+func processGroupPIDs(pgid int) []int {
+	dirs, err := filepath.Glob("/proc/[0-9]*")
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, dir := range dirs {
+		name := filepath.Base(dir)
+		state, g, ok := procStat(name)
+		if !ok || g != pgid || state == "Z" {
+			continue
+		}
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// processRunning reports whether the process pid exists and, where /proc is
+// available, is not a zombie.
+func processRunning(pid int) bool {
+	if hasProc() {
+		state, _, ok := procStat(strconv.Itoa(pid))
+		return ok && state != "Z"
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,183 @@
+//go:build linux
+// +build linux
+
+package selenium
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startFakeBrowser starts a service whose process starts a long-running child,
+// standing in for the browser started by ChromeDriver, and returns the PID of
+// the child.
+func startFakeBrowser(t *testing.T, port int) (*Service, int) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "selenium-service-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "pid")
+
+	s := &Service{port: port, cmd: exec.Command("sh", "-c", "sleep 60 & echo $! > "+pidFile+"; wait")}
+	s.processGroup = setProcessGroup(s.cmd)
+	if err := s.cmd.Start(); err != nil {
+		t.Fatalf("starting %v returned error: %v", s.cmd.Args, err)
+	}
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		data, err := ioutil.ReadFile(pidFile)
+		if err != nil || !strings.HasSuffix(string(data), "\n") {
+			continue
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			t.Fatalf("reading the child PID returned error: %v", err)
+		}
+		return s, pid
+	}
+	s.Stop()
+	t.Fatal("the child process did not start")
+	return nil, 0
+}
+
+// running reports whether the process is alive, i.e. exists and is not a
+// zombie waiting to be reaped.
+func running(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return false
+	}
+	// The state follows the command name, which is in parentheses.
+	fields := strings.Fields(string(stat[strings.LastIndex(string(stat), ")")+1:]))
+	return len(fields) > 0 && fields[0] != "Z"
+}
+
+func TestServiceStopKillsBrowsers(t *testing.T) {
+	s, pid := startFakeBrowser(t, 1)
+	if err := s.Stop(); err != nil {
+		t.Fatalf("s.Stop() returned error: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); running(pid); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("process %d is still running after s.Stop()", pid)
+		}
+	}
+}
+
+func TestServiceStopSparesDetachedBrowsers(t *testing.T) {
+	s, pid := startFakeBrowser(t, 2)
+	defer syscall.Kill(pid, syscall.SIGKILL)
+	s.noteDetachedSession()
+	if err := s.Stop(); err != nil {
+		t.Fatalf("s.Stop() returned error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if !running(pid) {
+		t.Fatalf("process %d was killed by s.Stop(), want it to survive a detached session", pid)
+	}
+	if s.hasDetachedSessions() {
+		t.Error("s.hasDetachedSessions() = true after s.Stop(), want the state cleared")
+	}
+
+	// Another service on the same port is not affected.
+	other, otherPID := startFakeBrowser(t, 2)
+	if err := other.Stop(); err != nil {
+		t.Fatalf("other.Stop() returned error: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); running(otherPID); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			syscall.Kill(otherPID, syscall.SIGKILL)
+			t.Fatalf("process %d is still running after other.Stop(), want it killed", otherPID)
+		}
+	}
+}
+
+func TestServiceProcesses(t *testing.T) {
+	s, pid := startFakeBrowser(t, 3)
+	trackService(s)
+	if got := s.Processes(); len(got) != 2 || got[0] != s.cmd.Process.Pid || got[1] != pid {
+		t.Errorf("s.Processes() = %v, want [%d %d]", got, s.cmd.Process.Pid, pid)
+	}
+	tracked := func() bool {
+		for _, l := range LeakCheck().Services {
+			if l.PID == s.cmd.Process.Pid {
+				return true
+			}
+		}
+		return false
+	}
+	if !tracked() {
+		t.Errorf("LeakCheck().Services = %v before s.Stop(), want it to include PID %d", LeakCheck().Services, s.cmd.Process.Pid)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatalf("s.Stop() returned error: %v", err)
+	}
+	if tracked() {
+		t.Errorf("LeakCheck().Services = %v after s.Stop(), want it to exclude PID %d", LeakCheck().Services, s.cmd.Process.Pid)
+	}
+}
+
+func TestWaitProcessGroupReportsSurvivors(t *testing.T) {
+	defer func(d time.Duration) { processExitTimeout = d }(processExitTimeout)
+	processExitTimeout = 50 * time.Millisecond
+
+	cmd := exec.Command("sleep", "60")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting %v returned error: %v", cmd.Args, err)
+	}
+	pid := cmd.Process.Pid
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	err := waitProcessGroup(pid)
+	e, ok := err.(*SurvivingProcessesError)
+	if !ok || len(e.PIDs) != 1 || e.PIDs[0] != pid {
+		t.Fatalf("waitProcessGroup(%d) returned error %v, want a *SurvivingProcessesError for PID %d", pid, err, pid)
+	}
+	if got := LeakCheck().Processes; len(got) == 0 || got[0] != pid {
+		t.Errorf("LeakCheck().Processes = %v, want [%d]", got, pid)
+	}
+
+	cmd.Process.Kill()
+	cmd.Wait()
+	if got := LeakCheck().Processes; len(got) != 0 {
+		t.Errorf("LeakCheck().Processes = %v after the process exited, want none", got)
+	}
+}
+
+func TestServiceLogFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-service-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "driver.log")
+
+	s, err := newService(exec.Command("sh", "-c", "echo hello; echo world >&2"), "", 0, LogFile(path, 0, 0), KeepOutput(10))
+	if err != nil {
+		t.Fatalf("newService(LogFile()) returned error: %v", err)
+	}
+	if err := s.cmd.Run(); err != nil {
+		t.Fatalf("running %v returned error: %v", s.cmd.Args, err)
+	}
+	s.closeLogFile()
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != "hello\nworld\n" {
+		t.Errorf("the log file holds %q, %v; want the output of the service", got, err)
+	}
+	if got := s.RecentOutput(); len(got) != 2 {
+		t.Errorf("s.RecentOutput() = %q, want the output kept too", got)
+	}
+}
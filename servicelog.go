@@ -0,0 +1,104 @@
+package selenium
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// LogFile makes the service write its output to the file at path, in
+// addition to the writers set with Output and KeepOutput. Once the file would
+// grow beyond maxBytes, it is renamed to path.1, path.1 to path.2 and so on,
+// keeping keep rotated files, and a new file is started; a maxBytes of zero
+// or less disables rotation. The rotation is done by the copier of the output
+// of the service, between two writes, so it works with any driver, unlike
+// ChromeDriver's --log-path, which grows without bound.
+func LogFile(path string, maxBytes int64, keep int) ServiceOption {
+	return func(s *Service) error {
+		if s.logFile != nil {
+			return fmt.Errorf("service log file already set: %v", s.logFile.path)
+		}
+		f, err := openRotatingFile(path, maxBytes, keep)
+		if err != nil {
+			return err
+		}
+		s.logFile = f
+		return nil
+	}
+}
+
+// rotatingFile is a writer to a file that is rotated by size.
+type rotatingFile struct {
+	path    string
+	maxSize int64
+	keep    int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func openRotatingFile(path string, maxSize int64, keep int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, keep: keep}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.f, r.size = f, info.Size()
+	return r, nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the rotated files and starts a new file.
+func (r *rotatingFile) rotate() error {
+	if err := r.f.Close(); err != nil {
+		return err
+	}
+	r.f = nil
+	if r.keep > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.keep))
+		for i := r.keep - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	}
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	r.f, r.size = f, 0
+	return nil
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,60 @@
+package selenium
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "selenium-servicelog-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "driver.log")
+
+	f, err := openRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("openRotatingFile() returned error: %v", err)
+	}
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("f.Write(%q) returned error: %v", line, err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("f.Close() returned error: %v", err)
+	}
+
+	// Each line overflows the previous one; the first was dropped.
+	for name, want := range map[string]string{
+		"driver.log":   "fourth\n",
+		"driver.log.1": "third\n",
+		"driver.log.2": "second\n",
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(got) != want {
+			t.Errorf("%s holds %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists, want 2 rotated files kept: %v", path, err)
+	}
+
+	// Reopening appends to the file and accounts for its size.
+	if f, err = openRotatingFile(path, 10, 0); err != nil {
+		t.Fatalf("openRotatingFile() returned error: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("fifth\n")); err != nil {
+		t.Fatalf("f.Write() returned error: %v", err)
+	}
+	if got, _ := ioutil.ReadFile(path); string(got) != "fifth\n" {
+		t.Errorf("without rotated files kept, %s holds %q, want %q", path, got, "fifth\n")
+	}
+	if got, _ := ioutil.ReadFile(path + ".1"); string(got) != "third\n" {
+		t.Errorf("without rotated files kept, %s.1 holds %q, want it unchanged", path, got)
+	}
+}
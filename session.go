@@ -0,0 +1,208 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrCapabilitiesTooLarge is matched by the *CapabilitiesTooLargeError
+// returned when the remote end rejects the New Session request because of its
+// size.
+var ErrCapabilitiesTooLarge = errors.New("capabilities too large")
+
+// CapabilitiesTooLargeError is returned when the remote end rejects the New
+// Session request because of its size, which is usually due to the browser
+// extensions embedded in the capabilities.
+type CapabilitiesTooLargeError struct {
+	// Size is the size of the request, in bytes.
+	Size int64
+	// Err is the error returned by the remote end.
+	Err error
+}
+
+func (e *CapabilitiesTooLargeError) Error() string {
+	return fmt.Sprintf("the remote end rejected %d bytes of capabilities as too large, consider removing extensions: %v", e.Size, e.Err)
+}
+
+// Is makes errors.Is(err, ErrCapabilitiesTooLarge) true.
+func (e *CapabilitiesTooLargeError) Is(target error) bool {
+	return target == ErrCapabilitiesTooLarge
+}
+
+func (e *CapabilitiesTooLargeError) Unwrap() error {
+	return e.Err
+}
+
+// SessionOption configures the creation of a session by NewRemoteContext.
+type SessionOption func(*sessionOptions)
+
+type sessionOptions struct {
+	progress func(sentBytes, totalBytes int64)
+	summary  io.Writer
+	defaults Defaults
+	service  *Service
+	failed   func() bool
+	label    string
+
+	removeUserDataDir bool
+	preserveProfile   *profilePreserver
+	failureHooks      []FailureHook
+	basePath          *string
+	jsonNumbers       bool
+	slowMotion        time.Duration
+	stepPause         func(cmd Command) bool
+	profile           *Profile
+
+	sessionRequestTimeout time.Duration
+	queueCallback         func(elapsed time.Duration)
+	sessionAttempts       int
+
+	snapshotInterval time.Duration
+	snapshotBytes    int
+
+	noCompat     bool
+	strictCompat bool
+	compatReport func(CompatReport)
+
+	simplePromptHandler bool
+
+	wireLimit int64
+	wireWarn  func(WireRecord)
+
+	skewHandler func(SkewInfo)
+	strictSkew  bool
+	maxSkew     int
+
+	trace io.Writer
+
+	client *http.Client
+	retry  *RetryPolicy
+
+	fileDetector FileDetector
+
+	spans SpanFunc
+}
+
+// WithBasePath sets the path under which the commands are sent to the remote
+// end, in place of the path of the URL passed to NewRemoteContext, for
+// gateways that rewrite paths, e.g. WithBasePath("/") to drop "/wd/hub". The
+// URL passed to NewRemoteContext may or may not end with a slash, with or
+// without this option.
+func WithBasePath(p string) SessionOption {
+	return func(o *sessionOptions) {
+		o.basePath = &p
+	}
+}
+
+// WithUploadProgress calls f as the New Session request is sent, with the
+// number of bytes sent so far and the size of the request. Remote ends that do
+// not implement the W3C specification may need several requests, each
+// reported from zero.
+func WithUploadProgress(f func(sentBytes, totalBytes int64)) SessionOption {
+	return func(o *sessionOptions) {
+		o.progress = f
+	}
+}
+
+// WithHTTPClient makes the session send its commands, including the New
+// Session request, with client instead of HTTPClient, e.g. for the TLS
+// configuration, proxy, connection pool or tracing of the connections to one
+// remote end. To customize only the transport, pass
+// &http.Client{Transport: rt}.
+func WithHTTPClient(client *http.Client) SessionOption {
+	return func(o *sessionOptions) {
+		o.client = client
+	}
+}
+
+// postStreaming sends a POST command with params encoded as JSON while the
+// request is written, rather than held in memory, and reports the upload to
+// progress, if not nil.
+func (wd *remoteWD) postStreaming(ctx context.Context, url string, params interface{}, progress func(sentBytes, totalBytes int64)) (json.RawMessage, error) {
+	var size countingWriter
+	if err := json.NewEncoder(&size).Encode(params); err != nil {
+		return nil, err
+	}
+	if debugFlag {
+		data, _ := json.Marshal(params)
+		debugLog("%s-> POST %s\n%s", logLabel(joinLabels(wd.currentTestName(), wd.label)), filteredURL(url), data)
+	}
+
+	pr, pw := io.Pipe()
+	defer pr.Close()
+	go func() {
+		pw.CloseWithError(json.NewEncoder(pw).Encode(params))
+	}()
+	var body io.Reader = pr
+	if progress != nil {
+		body = &progressReader{r: pr, total: int64(size), progress: progress}
+	}
+
+	request, err := http.NewRequest("POST", url, body)
+	if err != nil {
+		return nil, err
+	}
+	request.ContentLength = int64(size)
+	request.Header.Add("Accept", jsonContentType)
+
+	ctx, tap := wd.wire.withTap(ctx, "POST /session")
+	buf, err := sendRequest(wd.client, joinLabels(wd.currentTestName(), wd.label), request.WithContext(ctx))
+	tap.warn()
+	if wd.recorder != nil {
+		wd.recorder.record(wd, "POST", url, nil, buf, err)
+	}
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		if e, ok := err.(*Error); ok {
+			e.TestName = wd.currentTestName()
+			e.Label = wd.label
+		}
+		if isTooLarge(err) {
+			return nil, &CapabilitiesTooLargeError{Size: int64(size), Err: err}
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// isTooLarge reports whether the remote end rejected a request because of its
+// size.
+func isTooLarge(err error) bool {
+	if e, ok := err.(*Error); ok && e.HTTPCode == http.StatusRequestEntityTooLarge {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "too large")
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
+
+// progressReader reports the bytes read from r.
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sentBytes, totalBytes int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.sent += int64(n)
+		r.progress(r.sent, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,143 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+func TestNewRemoteContextReportsUploadProgress(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var received int64
+	d.handlers["POST /session"] = func(body []byte) (int, interface{}) {
+		received = int64(len(body))
+		return http.StatusOK, map[string]interface{}{
+			"sessionId":    fakeSessionID,
+			"capabilities": map[string]interface{}{},
+		}
+	}
+
+	caps := Capabilities{"browserName": "chrome"}
+	caps.AddChrome(chrome.Capabilities{
+		KeyMode:    chrome.ModernKey,
+		Extensions: []string{strings.Repeat("A", 1<<20)},
+	})
+	var sent, total int64
+	calls := 0
+	progress := func(sentBytes, totalBytes int64) {
+		if sentBytes < sent {
+			t.Errorf("progress went from %d to %d bytes", sent, sentBytes)
+		}
+		sent, total = sentBytes, totalBytes
+		calls++
+	}
+	if _, err := NewRemoteContext(context.Background(), caps, d.URL, WithUploadProgress(progress)); err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if calls < 2 {
+		t.Errorf("the progress callback was called %d times, want it called as the body is written", calls)
+	}
+	if sent != total || total != received {
+		t.Errorf("last progress = %d of %d bytes, want %d of %d", sent, total, received, received)
+	}
+}
+
+func TestNewRemoteContextCancel(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	release := make(chan struct{})
+	defer close(release)
+	d.handlers["POST /session"] = func([]byte) (int, interface{}) {
+		<-release
+		return http.StatusOK, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := NewRemoteContext(ctx, nil, d.URL)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("NewRemoteContext() returned error %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestNewRemoteContextCapabilitiesTooLarge(t *testing.T) {
+	for _, test := range []struct {
+		desc    string
+		handler fakeHandler
+	}{
+		{
+			desc: "HTTP 413",
+			handler: func([]byte) (int, interface{}) {
+				return http.StatusRequestEntityTooLarge, nil
+			},
+		},
+		{
+			desc: "error message",
+			handler: func([]byte) (int, interface{}) {
+				return http.StatusBadRequest, map[string]string{
+					"error":   "invalid argument",
+					"message": "request body too large",
+				}
+			},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			d.handlers["POST /session"] = test.handler
+
+			_, err := NewRemoteContext(context.Background(), Capabilities{"browserName": "chrome"}, d.URL)
+			if !errors.Is(err, ErrCapabilitiesTooLarge) {
+				t.Fatalf("NewRemoteContext() returned error %v, want %v", err, ErrCapabilitiesTooLarge)
+			}
+			var e *CapabilitiesTooLargeError
+			if !errors.As(err, &e) || e.Size == 0 {
+				t.Errorf("NewRemoteContext() returned error %#v, want a *CapabilitiesTooLargeError with the payload size", err)
+			}
+		})
+	}
+}
+
+// roundTripperFunc is an http.RoundTripper implemented by a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "about:blank"
+	})
+	var sent []string
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		sent = append(sent, r.Method+" "+r.URL.Path)
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithHTTPClient(client))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if _, err := wd.CurrentURL(); err != nil {
+		t.Fatalf("wd.CurrentURL() returned error: %v", err)
+	}
+	want := []string{"POST /session", "GET /session/" + fakeSessionID + "/url"}
+	if strings.Join(sent, ", ") != strings.Join(want, ", ") {
+		t.Errorf("the client sent %q, want %q", sent, want)
+	}
+
+	other, err := NewRemote(nil, d.URL)
+	if err != nil {
+		t.Fatalf("NewRemote() returned error: %v", err)
+	}
+	if _, err := other.CurrentURL(); err != nil {
+		t.Fatalf("CurrentURL() returned error: %v", err)
+	}
+	if len(sent) != len(want) {
+		t.Errorf("the client of a session sent the commands of another: %q", sent)
+	}
+}
@@ -0,0 +1,76 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// shadowRootIdentifier is the key of the reference to a shadow root in the
+// W3C specification.
+const shadowRootIdentifier = "shadow-6066-11e4-a52e-4f735466cecf"
+
+// remoteShadowRoot is a ShadowRoot of a session.
+type remoteShadowRoot struct {
+	parent *remoteWD
+	id     string
+}
+
+// ShadowRoot returns the open shadow root of the element.
+func (elem *remoteWE) ShadowRoot() (ShadowRoot, error) {
+	wd := elem.parent
+	response, err := wd.execute("GET", wd.requestURL("/session/%s/element/%s/shadow", wd.id, elem.id), nil)
+	if isUnknownCommand(err) {
+		return nil, fmt.Errorf("getting the shadow root: %w", ErrUnsupported)
+	}
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value map[string]string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	id := reply.Value[shadowRootIdentifier]
+	if id == "" {
+		return nil, fmt.Errorf("invalid shadow root returned: %+v", reply.Value)
+	}
+	return &remoteShadowRoot{parent: wd, id: id}, nil
+}
+
+func (r *remoteShadowRoot) ID() string {
+	return r.id
+}
+
+func (r *remoteShadowRoot) FindElement(by, value string) (WebElement, error) {
+	url := fmt.Sprintf("/session/%%s/shadow/%s/element", r.id)
+	response, err := r.parent.find(by, value, "", url)
+	if err != nil {
+		return nil, err
+	}
+	return r.parent.DecodeElement(response)
+}
+
+func (r *remoteShadowRoot) FindElements(by, value string) ([]WebElement, error) {
+	url := fmt.Sprintf("/session/%%s/shadow/%s/element", r.id)
+	response, err := r.parent.find(by, value, "s", url)
+	if err != nil {
+		return nil, err
+	}
+	return r.parent.DecodeElements(response)
+}
+
+func (r *remoteShadowRoot) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{shadowRootIdentifier: r.id})
+}
+
+// commandShadowRoot returns the reference of the shadow root that the command
+// of rawURL is scoped to, or the empty string.
+func commandShadowRoot(rawURL string) string {
+	segments := strings.Split(rawURL, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "shadow" {
+			return segments[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestShadowRoot(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/element/host/shadow", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{shadowRootIdentifier: "root"}
+	})
+	d.handle("GET", "/element/plain/shadow", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{
+			"error":   "no such shadow root",
+			"message": "element has no shadow root",
+		}
+	})
+	var found string
+	d.handle("POST", "/shadow/root/element", func(body []byte) (int, interface{}) {
+		found = string(body)
+		return http.StatusOK, map[string]string{webElementIdentifier: "inner"}
+	})
+	d.handle("POST", "/shadow/root/elements", func([]byte) (int, interface{}) {
+		return http.StatusOK, []map[string]string{
+			{webElementIdentifier: "inner"},
+			{webElementIdentifier: "other"},
+		}
+	})
+	wd := d.newRemote(t)
+
+	root, err := wd.ElementFromID("host").ShadowRoot()
+	if err != nil {
+		t.Fatalf("elem.ShadowRoot() returned error: %v", err)
+	}
+	if root.ID() != "root" {
+		t.Errorf("root.ID() = %q, want %q", root.ID(), "root")
+	}
+	if got, want := string(mustJSON(t, root)), `{"`+shadowRootIdentifier+`":"root"}`; got != want {
+		t.Errorf("the shadow root is encoded as %s, want %s", got, want)
+	}
+
+	elem, err := root.FindElement(ByCSSSelector, "button")
+	if err != nil {
+		t.Fatalf("root.FindElement() returned error: %v", err)
+	}
+	if elem.ID() != "inner" {
+		t.Errorf("root.FindElement() returned %q, want %q", elem.ID(), "inner")
+	}
+	if want := `{"using":"css selector","value":"button"}`; found != want {
+		t.Errorf("root.FindElement() sent %s, want %s", found, want)
+	}
+	elems, err := root.FindElements(ByCSSSelector, "button")
+	if err != nil {
+		t.Fatalf("root.FindElements() returned error: %v", err)
+	}
+	if len(elems) != 2 || elems[0].ID() != "inner" || elems[1].ID() != "other" {
+		t.Errorf("root.FindElements() returned %v, want inner and other", elems)
+	}
+
+	_, err = wd.ElementFromID("plain").ShadowRoot()
+	if e, ok := err.(*Error); !ok || e.Err != "no such shadow root" {
+		t.Errorf("elem.ShadowRoot() without a shadow root returned error %v, want no such shadow root", err)
+	}
+}
+
+func TestShadowRootRelativeLocator(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var scope json.RawMessage
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var req struct{ Args []json.RawMessage }
+		if err := json.Unmarshal(body, &req); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		scope = req.Args[2]
+		return http.StatusOK, []map[string]string{{webElementIdentifier: "inner"}}
+	})
+	wd := d.newRemote(t)
+	root := &remoteShadowRoot{parent: wd, id: "root"}
+
+	if _, err := root.FindElement(RelativeBy(ByTagName, "input").Below(wd.ElementFromID("label")).ByValue()); err != nil {
+		t.Fatalf("root.FindElement() returned error: %v", err)
+	}
+	if want := string(mustJSON(t, root)); string(scope) != want {
+		t.Errorf("the script received scope %s, want %s", scope, want)
+	}
+}
+
+func TestShadowRootUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.ElementFromID("host").ShadowRoot(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("elem.ShadowRoot() returned error %v, want ErrUnsupported", err)
+	}
+}
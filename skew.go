@@ -0,0 +1,181 @@
+package selenium
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// SkewInfo describes the versions of the browser and of the driver of a
+// session, as reported by the remote end when the session was created.
+type SkewInfo struct {
+	// Browser is the name of the browser, "chrome" or "firefox".
+	Browser string
+	// BrowserVersion is the version of the browser, e.g. "120.0.6099.109".
+	BrowserVersion string
+	// Driver is the name of the driver, "chromedriver" or "geckodriver".
+	Driver string
+	// DriverVersion is the version of the driver, e.g. "118.0.5993.70" or
+	// "0.34.0".
+	DriverVersion string
+	// Skew is the number of major versions by which the browser is ahead of
+	// the driver, negative if it is behind. ChromeDriver is released with
+	// each major version of Chrome, which it must match. A geckodriver
+	// release supports the versions of Firefox from a minimum on: Skew is
+	// then how many major versions the browser is below that minimum,
+	// negative, or 0.
+	Skew int
+}
+
+func (s SkewInfo) String() string {
+	return fmt.Sprintf("%s %s with %s %s (skew %+d)", s.Browser, s.BrowserVersion, s.Driver, s.DriverVersion, s.Skew)
+}
+
+// VersionSkewError is returned by NewRemoteContext, for a session created
+// with StrictVersionSkew, when the versions of the browser and of the driver
+// are further apart than allowed.
+type VersionSkewError struct {
+	SkewInfo
+	// MaxSkew is the skew allowed.
+	MaxSkew int
+}
+
+func (e *VersionSkewError) Error() string {
+	return fmt.Sprintf("browser and driver versions too far apart: %s, at most %d allowed", e.SkewInfo, e.MaxSkew)
+}
+
+// WarnOnVersionSkew passes the versions of the browser and of the driver to
+// handler when the session is created, if their major versions differ, e.g.
+// Chrome 120 with ChromeDriver 118: such sessions are usually created
+// without error, but some commands then misbehave. If handler is nil, the
+// skew is logged, once for each pair of versions among the sessions created
+// with the returned option.
+func WarnOnVersionSkew(handler func(SkewInfo)) SessionOption {
+	if handler == nil {
+		handler = newSkewLogger()
+	}
+	return func(o *sessionOptions) {
+		o.skewHandler = handler
+	}
+}
+
+// StrictVersionSkew makes NewRemoteContext return a *VersionSkewError, and
+// quit the session, if the major versions of the browser and of the driver
+// are more than maxSkew apart, e.g. on CI to enforce matched versions.
+func StrictVersionSkew(maxSkew int) SessionOption {
+	return func(o *sessionOptions) {
+		o.strictSkew = true
+		o.maxSkew = maxSkew
+	}
+}
+
+// newSkewLogger returns a handler for WarnOnVersionSkew that logs each pair
+// of versions once.
+func newSkewLogger() func(SkewInfo) {
+	var mu sync.Mutex
+	logged := make(map[string]bool)
+	return func(s SkewInfo) {
+		key := strings.Join([]string{s.Browser, s.BrowserVersion, s.Driver, s.DriverVersion}, "\x00")
+		mu.Lock()
+		seen := logged[key]
+		logged[key] = true
+		mu.Unlock()
+		if !seen {
+			log.Printf("selenium: version skew between the browser and the driver: %s", s)
+		}
+	}
+}
+
+// geckodriverMinFirefox maps the minor versions of geckodriver 0.x releases
+// to the earliest major version of Firefox that they support.
+var geckodriverMinFirefox = map[uint64]uint64{
+	36: 128,
+	35: 115,
+	34: 115,
+	33: 102,
+	32: 102,
+	31: 91,
+	30: 78,
+	29: 60,
+	28: 60,
+	27: 60,
+	26: 60,
+	25: 57,
+	24: 57,
+	23: 57,
+	22: 57,
+	21: 57,
+	20: 55,
+	19: 55,
+	18: 53,
+	17: 52,
+}
+
+// VersionSkew returns the versions of the browser and of the driver reported
+// in the capabilities negotiated for a session, such as those returned by
+// WebDriver.SessionCapabilities. It returns false if the remote end did not
+// report both versions, or reported versions it cannot compare.
+func VersionSkew(caps Capabilities) (SkewInfo, bool) {
+	browserVersion, _ := caps["browserVersion"].(string)
+	if browserVersion == "" {
+		browserVersion, _ = caps["version"].(string)
+	}
+	browser, err := parseVersion(browserVersion)
+	if err != nil {
+		return SkewInfo{}, false
+	}
+
+	if v, _ := caps["moz:geckodriverVersion"].(string); v != "" {
+		s := SkewInfo{Browser: "firefox", BrowserVersion: browserVersion, Driver: "geckodriver", DriverVersion: v}
+		driver, err := parseVersion(v)
+		if err != nil || driver.Major != 0 {
+			return SkewInfo{}, false
+		}
+		min, ok := geckodriverMinFirefox[driver.Minor]
+		if !ok {
+			return SkewInfo{}, false
+		}
+		if browser.Major < min {
+			s.Skew = int(browser.Major) - int(min)
+		}
+		return s, true
+	}
+
+	info, err := chrome.SessionInfo(caps)
+	if err != nil || info.ChromedriverVersion == "" {
+		return SkewInfo{}, false
+	}
+	driver, err := parseVersion(info.ChromedriverVersion)
+	if err != nil {
+		return SkewInfo{}, false
+	}
+	return SkewInfo{
+		Browser:        "chrome",
+		BrowserVersion: browserVersion,
+		Driver:         "chromedriver",
+		DriverVersion:  info.ChromedriverVersion,
+		Skew:           int(browser.Major) - int(driver.Major),
+	}, true
+}
+
+// checkVersionSkew reports the version skew of the new session as configured
+// by WarnOnVersionSkew and StrictVersionSkew.
+func (wd *remoteWD) checkVersionSkew(o sessionOptions) error {
+	if o.skewHandler == nil && !o.strictSkew {
+		return nil
+	}
+	s, ok := VersionSkew(wd.sessionCapabilities)
+	if !ok {
+		return nil
+	}
+	if s.Skew != 0 && o.skewHandler != nil {
+		o.skewHandler(s)
+	}
+	if o.strictSkew && (s.Skew > o.maxSkew || -s.Skew > o.maxSkew) {
+		return &VersionSkewError{SkewInfo: s, MaxSkew: o.maxSkew}
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package selenium
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestVersionSkew(t *testing.T) {
+	for _, test := range []struct {
+		desc   string
+		caps   Capabilities
+		want   SkewInfo
+		wantOK bool
+	}{
+		{
+			desc: "matched ChromeDriver",
+			caps: Capabilities{
+				"browserVersion": "120.0.6099.109",
+				"chrome":         map[string]interface{}{"chromedriverVersion": "120.0.6099.71 (9729082fe6174c0a371fc66501f5efc5d69d3d2b-refs/branch-heads/6099_56@{#13})"},
+			},
+			want:   SkewInfo{Browser: "chrome", BrowserVersion: "120.0.6099.109", Driver: "chromedriver", DriverVersion: "120.0.6099.71"},
+			wantOK: true,
+		},
+		{
+			desc: "older ChromeDriver",
+			caps: Capabilities{
+				"browserVersion": "120.0.6099.109",
+				"chrome":         map[string]interface{}{"chromedriverVersion": "118.0.5993.70"},
+			},
+			want:   SkewInfo{Browser: "chrome", BrowserVersion: "120.0.6099.109", Driver: "chromedriver", DriverVersion: "118.0.5993.70", Skew: 2},
+			wantOK: true,
+		},
+		{
+			desc: "legacy version",
+			caps: Capabilities{
+				"version": "117.0.5938.62",
+				"chrome":  map[string]interface{}{"chromedriverVersion": "118.0.5993.70"},
+			},
+			want:   SkewInfo{Browser: "chrome", BrowserVersion: "117.0.5938.62", Driver: "chromedriver", DriverVersion: "118.0.5993.70", Skew: -1},
+			wantOK: true,
+		},
+		{
+			desc:   "supported Firefox",
+			caps:   Capabilities{"browserVersion": "121.0", "moz:geckodriverVersion": "0.34.0"},
+			want:   SkewInfo{Browser: "firefox", BrowserVersion: "121.0", Driver: "geckodriver", DriverVersion: "0.34.0"},
+			wantOK: true,
+		},
+		{
+			desc:   "old Firefox",
+			caps:   Capabilities{"browserVersion": "102.15.0esr", "moz:geckodriverVersion": "0.34.0"},
+			want:   SkewInfo{Browser: "firefox", BrowserVersion: "102.15.0esr", Driver: "geckodriver", DriverVersion: "0.34.0", Skew: -13},
+			wantOK: true,
+		},
+		{
+			desc: "unknown geckodriver",
+			caps: Capabilities{"browserVersion": "121.0", "moz:geckodriverVersion": "0.99.0"},
+		},
+		{
+			desc: "no driver version",
+			caps: Capabilities{"browserVersion": "121.0"},
+		},
+		{
+			desc: "no browser version",
+			caps: Capabilities{"chrome": map[string]interface{}{"chromedriverVersion": "118.0.5993.70"}},
+		},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			got, ok := VersionSkew(test.caps)
+			if ok != test.wantOK || got != test.want {
+				t.Errorf("VersionSkew() = %+v, %t, want %+v, %t", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestVersionSkewSessionOptions(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{
+		"browserName":    "chrome",
+		"browserVersion": "120.0.6099.109",
+		"chrome":         map[string]interface{}{"chromedriverVersion": "118.0.5993.70"},
+	})
+	caps := Capabilities{"browserName": "chrome"}
+
+	var got []SkewInfo
+	wd, err := NewRemoteContext(context.Background(), caps, d.URL, WarnOnVersionSkew(func(s SkewInfo) { got = append(got, s) }))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	wd.Quit()
+	if len(got) != 1 || got[0].Skew != 2 {
+		t.Errorf("WarnOnVersionSkew() got %+v, want one skew of 2", got)
+	}
+
+	deleted := d.count("DELETE", "")
+	_, err = NewRemoteContext(context.Background(), caps, d.URL, StrictVersionSkew(1))
+	var skewErr *VersionSkewError
+	if !errors.As(err, &skewErr) || skewErr.Skew != 2 || skewErr.MaxSkew != 1 {
+		t.Errorf("NewRemoteContext() with StrictVersionSkew(1) returned error %v, want a *VersionSkewError", err)
+	}
+	if n := d.count("DELETE", "") - deleted; n != 1 {
+		t.Errorf("NewRemoteContext() with StrictVersionSkew(1) deleted %d sessions, want 1", n)
+	}
+
+	wd, err = NewRemoteContext(context.Background(), caps, d.URL, StrictVersionSkew(2))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() with StrictVersionSkew(2) returned error: %v", err)
+	}
+	wd.Quit()
+}
+
+func TestLogVersionSkew(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	var o sessionOptions
+	WarnOnVersionSkew(nil)(&o)
+	s := SkewInfo{Browser: "chrome", BrowserVersion: "120.0.1", Driver: "chromedriver", DriverVersion: "118.0.1", Skew: 2}
+	o.skewHandler(s)
+	o.skewHandler(s)
+	if n := strings.Count(buf.String(), "version skew"); n != 1 {
+		t.Errorf("the default handler of WarnOnVersionSkew() logged the same skew %d times, want 1:\n%s", n, buf.String())
+	}
+}
@@ -0,0 +1,133 @@
+package selenium
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrStepAborted is returned by the commands that the hook of WithStepPause
+// did not let through.
+var ErrStepAborted = errors.New("command aborted by the step pause hook")
+
+// Command is a command of a session, passed to the hook of WithStepPause,
+// which only sees those that change the state of the page, and to
+// RetryPolicy.Retryable.
+type Command struct {
+	// Type is the method and the path of the command relative to its session,
+	// as in session summaries, e.g. "POST /element/:id/click".
+	Type string
+	// Element is the element the command acts on, or nil.
+	Element WebElement
+}
+
+// newCommand returns the Command of type typ, sent to url.
+func (wd *remoteWD) newCommand(typ, url string) Command {
+	cmd := Command{Type: typ}
+	i := strings.Index(typ, " ")
+	if i < 0 || !strings.HasPrefix(typ[i+1:], "/element/:id/") {
+		return cmd
+	}
+	// The path of the URL ends with "element/<id>/...", as the type does
+	// with "element/:id/...".
+	pattern := strings.Split(typ[i+1:], "/")
+	segments := strings.Split(strings.TrimSuffix(url, "/"), "/")
+	if len(segments) >= len(pattern) {
+		cmd.Element = &remoteWE{parent: wd, id: segments[len(segments)-len(pattern)+2]}
+	}
+	return cmd
+}
+
+// Highlight outlines the element of the command, if any, until restore is
+// called, e.g. to show which element a paused step is about to act on.
+func (c Command) Highlight() (restore func() error, err error) {
+	elem, ok := c.Element.(*remoteWE)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+	return elem.highlight()
+}
+
+// highlightScript outlines the element passed as the first argument and
+// returns its previous outline.
+const highlightScript = `
+	var el = arguments[0], previous = el.style.outline;
+	el.style.outline = '3px solid #ff00ff';
+	return previous;`
+
+// highlight outlines the element until restore is called.
+func (elem *remoteWE) highlight() (restore func() error, err error) {
+	previous, err := elem.parent.ExecuteScript(highlightScript, []interface{}{elem})
+	if err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := elem.parent.ExecuteScript("arguments[0].style.outline = arguments[1];", []interface{}{elem, previous})
+		return err
+	}, nil
+}
+
+// WithSlowMotion makes the session sleep for delay before each command that
+// changes the state of the page: navigation, clicks, key presses and actions.
+// Commands that read the page, and those sent while waiting for a condition,
+// are not delayed.
+func WithSlowMotion(delay time.Duration) SessionOption {
+	return func(o *sessionOptions) {
+		o.slowMotion = delay
+	}
+}
+
+// WithStepPause makes the session call resume before each command that
+// changes the state of the page, like WithSlowMotion, e.g. to wait for the
+// user to press Enter while debugging. The command is sent if resume returns
+// true; otherwise it returns ErrStepAborted.
+func WithStepPause(resume func(cmd Command) bool) SessionOption {
+	return func(o *sessionOptions) {
+		o.stepPause = resume
+	}
+}
+
+// steppedCommands are the types of the commands delayed by WithSlowMotion and
+// WithStepPause.
+var steppedCommands = map[string]bool{
+	"POST /url":                true,
+	"POST /back":               true,
+	"POST /forward":            true,
+	"POST /refresh":            true,
+	"POST /element/:id/click":  true,
+	"POST /element/:id/value":  true,
+	"POST /element/:id/clear":  true,
+	"POST /element/:id/submit": true,
+	"POST /actions":            true,
+	"POST /keys":               true,
+	"POST /click":              true,
+	"POST /doubleclick":        true,
+	"POST /buttondown":         true,
+	"POST /buttonup":           true,
+	"POST /moveto":             true,
+}
+
+// beforeStep delays or pauses a command that changes the state of the page;
+// see WithSlowMotion and WithStepPause.
+func (wd *remoteWD) beforeStep(tag commandTag, method, url string) error {
+	if wd.slowMotion == 0 && wd.stepPause == nil {
+		return nil
+	}
+	if tag != tagTest {
+		return nil
+	}
+	typ := commandType(method, url, wd.urlPrefix, wd.id)
+	if !steppedCommands[typ] {
+		return nil
+	}
+	if wd.slowMotion > 0 {
+		time.Sleep(wd.slowMotion)
+	}
+	if wd.stepPause == nil {
+		return nil
+	}
+	if !wd.stepPause(wd.newCommand(typ, url)) {
+		return ErrStepAborted
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStepPause(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "title"
+	})
+	d.handle("POST", "/element/btn/click", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	var paused []Command
+	resume := true
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithStepPause(func(cmd Command) bool {
+		paused = append(paused, cmd)
+		return resume
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	if err := wd.Get("https://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.Title(); err != nil {
+		t.Fatalf("wd.Title() returned error: %v", err)
+	}
+	btn := &remoteWE{parent: wd.(*remoteWD), id: "btn"}
+	if err := btn.Click(); err != nil {
+		t.Fatalf("btn.Click() returned error: %v", err)
+	}
+	if len(paused) != 2 {
+		t.Fatalf("the hook was called for %+v, want the navigation and the click", paused)
+	}
+	if paused[0].Type != "POST /url" || paused[0].Element != nil {
+		t.Errorf("the hook was called with %+v for the navigation", paused[0])
+	}
+	if paused[1].Type != "POST /element/:id/click" || paused[1].Element.(*remoteWE).id != "btn" {
+		t.Errorf("the hook was called with %+v for the click", paused[1])
+	}
+
+	paused = nil
+	err = wd.WaitWithTimeout(func(wd WebDriver) (bool, error) {
+		return true, btn.Click()
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("wd.WaitWithTimeout() returned error: %v", err)
+	}
+	if len(paused) != 0 {
+		t.Errorf("the hook was called for %+v in a wait, want no call", paused)
+	}
+
+	resume = false
+	if err := btn.Click(); !errors.Is(err, ErrStepAborted) {
+		t.Errorf("btn.Click() returned error %v, want ErrStepAborted", err)
+	}
+	if n := d.count("POST", "/element/btn/click"); n != 2 {
+		t.Errorf("the click was sent %d times, want 2", n)
+	}
+}
+
+func TestSlowMotion(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	const delay = 50 * time.Millisecond
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithSlowMotion(delay))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	start := time.Now()
+	if err := wd.Get("https://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < delay {
+		t.Errorf("wd.Get() took %v, want at least %v", elapsed, delay)
+	}
+}
@@ -0,0 +1,173 @@
+package selenium
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+// maxSnapshotConsoleEntries is the number of console log entries kept for
+// LastKnownState.
+const maxSnapshotConsoleEntries = 100
+
+// TimedScreenshot is a screenshot kept for LastKnownState.
+type TimedScreenshot struct {
+	// Time is when the screenshot was taken.
+	Time time.Time
+	// PNG is the image.
+	PNG []byte
+}
+
+// SessionSnapshot is the last known state of a session, as observed by the
+// commands that succeeded, returned by LastKnownState.
+type SessionSnapshot struct {
+	// URL and Title are the last URL and title of the current page, as
+	// loaded by Get or returned by CurrentURL and Title.
+	URL   string
+	Title string
+	// Screenshots are the last screenshots of the page taken by Screenshot
+	// or by WithPeriodicSnapshots, oldest first. Only the last one is kept
+	// unless WithPeriodicSnapshots allows more.
+	Screenshots []TimedScreenshot
+	// ConsoleLogs are the last 100 entries of the browser log returned by
+	// Log or DrainLogs.
+	ConsoleLogs []log.Message
+	// DriverOutput holds the last lines written by the driver, if the session
+	// was created with WithService for a service started with KeepOutput.
+	DriverOutput []string
+	// Updated is when the snapshot last changed.
+	Updated time.Time
+}
+
+// snapshotCache records the state of the session as its commands succeed.
+type snapshotCache struct {
+	mu       sync.Mutex
+	snapshot SessionSnapshot
+	// maxBytes bounds the size of the screenshots kept; the last one is kept
+	// regardless.
+	maxBytes int
+}
+
+// observe updates the cache with the successful command of the given type,
+// as returned by commandType.
+func (c *snapshotCache) observe(command string, data, reply []byte) {
+	value := new(struct{ Value json.RawMessage })
+	switch command {
+	case "POST /url", "GET /url", "GET /title", "GET /screenshot", "POST /log":
+		if json.Unmarshal(reply, value) != nil {
+			return
+		}
+	default:
+		return
+	}
+
+	switch command {
+	case "POST /url":
+		params := new(struct{ URL string })
+		if json.Unmarshal(data, params) == nil {
+			c.update(func(s *SessionSnapshot) { s.URL = params.URL })
+		}
+	case "GET /url", "GET /title":
+		var v string
+		if json.Unmarshal(value.Value, &v) != nil {
+			return
+		}
+		c.update(func(s *SessionSnapshot) {
+			if command == "GET /url" {
+				s.URL = v
+			} else {
+				s.Title = v
+			}
+		})
+	case "GET /screenshot":
+		var encoded string
+		if json.Unmarshal(value.Value, &encoded) != nil {
+			return
+		}
+		png, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return
+		}
+		c.addScreenshot(png)
+	case "POST /log":
+		params := new(struct{ Type log.Type })
+		if json.Unmarshal(data, params) != nil || params.Type != log.Browser {
+			return
+		}
+		messages, err := decodeLog(reply)
+		if err != nil || len(messages) == 0 {
+			return
+		}
+		c.update(func(s *SessionSnapshot) {
+			s.ConsoleLogs = append(s.ConsoleLogs, messages...)
+			if n := len(s.ConsoleLogs) - maxSnapshotConsoleEntries; n > 0 {
+				s.ConsoleLogs = append([]log.Message(nil), s.ConsoleLogs[n:]...)
+			}
+		})
+	}
+}
+
+func (c *snapshotCache) update(f func(s *SessionSnapshot)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f(&c.snapshot)
+	c.snapshot.Updated = time.Now()
+}
+
+// addScreenshot keeps png and drops the oldest screenshots beyond maxBytes.
+func (c *snapshotCache) addScreenshot(png []byte) {
+	c.update(func(s *SessionSnapshot) {
+		shots := append(s.Screenshots, TimedScreenshot{Time: time.Now(), PNG: png})
+		size := 0
+		first := len(shots) - 1
+		for ; first >= 0; first-- {
+			if size += len(shots[first].PNG); size > c.maxBytes && first < len(shots)-1 {
+				break
+			}
+		}
+		s.Screenshots = append([]TimedScreenshot(nil), shots[first+1:]...)
+	})
+}
+
+// WithPeriodicSnapshots makes the session take a screenshot every interval,
+// from another goroutine between the other commands of the session, and keep
+// the last ones in up to maxBytes for LastKnownState, e.g. to see what led to
+// a crash. The last screenshot is kept even if it is larger. The screenshots
+// stop when the session ends.
+func WithPeriodicSnapshots(interval time.Duration, maxBytes int) SessionOption {
+	return func(o *sessionOptions) {
+		o.snapshotInterval = interval
+		o.snapshotBytes = maxBytes
+	}
+}
+
+// takeSnapshots takes a screenshot every interval until the session ends.
+func (wd *remoteWD) takeSnapshots(interval time.Duration) {
+	url := wd.pollURL("/session/%s/screenshot")
+	l := wd.startLoop()
+	go func() {
+		defer close(l.done)
+		poll(context.Background(), l.stop, interval, "taking a periodic screenshot", func() error {
+			// The reply is cached by executeTagged.
+			_, err := wd.executeTagged(tagBackground, "GET", url, nil)
+			return err
+		})
+	}()
+}
+
+// LastKnownState returns the last known state of the session.
+func (wd *remoteWD) LastKnownState() SessionSnapshot {
+	wd.snapshot.mu.Lock()
+	s := wd.snapshot.snapshot
+	s.Screenshots = append([]TimedScreenshot(nil), s.Screenshots...)
+	s.ConsoleLogs = append([]log.Message(nil), s.ConsoleLogs...)
+	wd.snapshot.mu.Unlock()
+	if wd.service != nil {
+		s.DriverOutput = wd.service.RecentOutput()
+	}
+	return s
+}
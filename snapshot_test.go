@@ -0,0 +1,119 @@
+package selenium
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/LoveOyy/selenium/log"
+)
+
+func TestLastKnownState(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusOK, "Checkout"
+	})
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, base64.StdEncoding.EncodeToString([]byte("png"))
+	})
+	logs := &fakeLogs{pending: map[log.Type][]string{
+		log.Browser: {"console error"},
+		log.Driver:  {"driver entry"},
+	}}
+	logs.install(d)
+	wd := d.newRemote(t)
+
+	if err := wd.Get("http://example.com/checkout"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.Title(); err != nil {
+		t.Fatalf("wd.Title() returned error: %v", err)
+	}
+	if _, err := wd.Screenshot(); err != nil {
+		t.Fatalf("wd.Screenshot() returned error: %v", err)
+	}
+	for _, typ := range []log.Type{log.Browser, log.Driver} {
+		if _, err := wd.Log(typ); err != nil {
+			t.Fatalf("wd.Log(%q) returned error: %v", typ, err)
+		}
+	}
+
+	// The session ends: the commands fail, but the state is still known.
+	invalid := func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{"error": "invalid session id", "message": "session deleted"}
+	}
+	d.handle("GET", "/title", invalid)
+	d.handle("GET", "/screenshot", invalid)
+	if _, err := wd.Title(); err == nil {
+		t.Fatal("wd.Title() returned no error after the session ended")
+	}
+	s := wd.LastKnownState()
+	if s.URL != "http://example.com/checkout" || s.Title != "Checkout" {
+		t.Errorf("wd.LastKnownState() has URL %q and title %q, want the last ones observed", s.URL, s.Title)
+	}
+	if len(s.Screenshots) != 1 || string(s.Screenshots[0].PNG) != "png" {
+		t.Errorf("wd.LastKnownState().Screenshots = %v, want the last screenshot", s.Screenshots)
+	}
+	if len(s.ConsoleLogs) != 1 || s.ConsoleLogs[0].Message != "console error" {
+		t.Errorf("wd.LastKnownState().ConsoleLogs = %v, want the browser log entry only", s.ConsoleLogs)
+	}
+	if s.Updated.IsZero() {
+		t.Error("wd.LastKnownState().Updated is zero")
+	}
+}
+
+func TestSnapshotScreenshotCap(t *testing.T) {
+	c := &snapshotCache{maxBytes: 5}
+	for _, png := range []string{"aa", "bb", "cc", "dddddd"} {
+		c.addScreenshot([]byte(png))
+	}
+	var got []string
+	for _, s := range c.snapshot.Screenshots {
+		got = append(got, string(s.PNG))
+	}
+	// The last screenshot is kept even though it exceeds the cap.
+	if len(got) != 1 || got[0] != "dddddd" {
+		t.Errorf("screenshots kept = %q, want [dddddd]", got)
+	}
+
+	c = &snapshotCache{maxBytes: 5}
+	for _, png := range []string{"aa", "bb", "cc"} {
+		c.addScreenshot([]byte(png))
+	}
+	if n := len(c.snapshot.Screenshots); n != 2 {
+		t.Errorf("%d screenshots of 2 bytes kept within 5 bytes, want 2", n)
+	}
+}
+
+func TestPeriodicSnapshots(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, base64.StdEncoding.EncodeToString([]byte("png"))
+	})
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithPeriodicSnapshots(time.Millisecond, 10))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); len(wd.LastKnownState().Screenshots) < 3; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("no periodic screenshots after 5s")
+		}
+	}
+	if n := len(wd.LastKnownState().Screenshots); n > 3 {
+		t.Errorf("%d screenshots of 3 bytes kept within 10 bytes, want 3", n)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	// Quit stops the screenshots.
+	n := d.count("GET", "/screenshot")
+	time.Sleep(20 * time.Millisecond)
+	if m := d.count("GET", "/screenshot"); m != n {
+		t.Errorf("%d screenshots were taken after wd.Quit(), want none", m-n)
+	}
+}
@@ -0,0 +1,82 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// CommandSpan describes a command of a session, for the spans started by the
+// SpanFunc of WithCommandSpans.
+type CommandSpan struct {
+	// Command is the method and the path of the command relative to its
+	// session, as in CommandRecord, e.g. "POST /element/:id/click".
+	Command   string
+	SessionID string
+	// Start is when the command was sent.
+	Start time.Time
+
+	// The following fields are set when the command completes.
+
+	// Duration is how long the remote end took to reply, including the
+	// retries of WithRetryPolicy.
+	Duration time.Duration
+	// HTTPStatus is the HTTP status code of the reply, or 0 if the remote end
+	// did not reply.
+	HTTPStatus int
+	// Err is the error of the command, if it failed.
+	Err error
+}
+
+// SpanFunc starts a span for a command, as a child of the span in ctx if any,
+// and returns a context holding the span and the function that ends it. The
+// command is sent with the returned context, so that an HTTP client set with
+// WithHTTPClient can propagate the span to the remote end. end is called
+// once, with the completed CommandSpan.
+type SpanFunc func(ctx context.Context, span CommandSpan) (spanCtx context.Context, end func(CommandSpan))
+
+// WithCommandSpans makes the session call f for each command that it sends,
+// to trace the commands, e.g. with the OpenTelemetry spans of the
+// seleniumotel package. The parent of the spans is the span in the context
+// of the view of the session that sends the command, set with
+// WebDriver.WithContext, if any. The New Session request is not traced.
+func WithCommandSpans(f SpanFunc) SessionOption {
+	return func(o *sessionOptions) {
+		o.spans = f
+	}
+}
+
+// replyStatus returns the HTTP status code of the reply to a command that
+// returned err, or 0 if the remote end did not reply.
+func replyStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var e *Error
+	var nonJSON *NonJSONResponseError
+	switch {
+	case errors.As(err, &e):
+		return e.HTTPCode
+	case errors.As(err, &nonJSON):
+		return nonJSON.StatusCode
+	}
+	return 0
+}
+
+// startSpan starts the span of a command with the SpanFunc of the session, if
+// any, and returns the context with which to send the command and the
+// function that ends the span with the result of the command.
+func (wd *remoteWD) startSpan(ctx context.Context, command string, start time.Time) (context.Context, func(elapsed time.Duration, err error)) {
+	if wd.spans == nil {
+		return ctx, func(time.Duration, error) {}
+	}
+	span := CommandSpan{Command: command, SessionID: wd.id, Start: start}
+	ctx, end := wd.spans(ctx, span)
+	return ctx, func(elapsed time.Duration, err error) {
+		span.Duration = elapsed
+		span.HTTPStatus = replyStatus(err)
+		span.Err = err
+		end(span)
+	}
+}
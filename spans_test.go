@@ -0,0 +1,55 @@
+package selenium
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+func TestWithCommandSpans(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "about:blank"
+	})
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{"error": "no such element", "message": "not found"}
+	})
+
+	type key struct{}
+	parent := context.WithValue(context.Background(), key{}, "parent")
+	var ended []CommandSpan
+	session, err := NewRemoteContext(context.Background(), nil, d.URL, WithCommandSpans(func(ctx context.Context, span CommandSpan) (context.Context, func(CommandSpan)) {
+		if ctx.Value(key{}) != "parent" {
+			t.Errorf("the span of %s was started without the context of the session", span.Command)
+		}
+		if span.SessionID == "" || span.Start.IsZero() {
+			t.Errorf("the span of %s was started with %+v, want its session and start time", span.Command, span)
+		}
+		return ctx, func(span CommandSpan) {
+			ended = append(ended, span)
+		}
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	wd := session.WithContext(parent)
+	if _, err := wd.CurrentURL(); err != nil {
+		t.Fatalf("wd.CurrentURL() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(ByID, "missing"); err == nil {
+		t.Fatal("wd.FindElement() returned no error")
+	}
+
+	if len(ended) != 2 {
+		t.Fatalf("%d spans were ended, want 2: %+v", len(ended), ended)
+	}
+	if s := ended[0]; s.Command != "GET /url" || s.HTTPStatus != http.StatusOK || s.Err != nil || s.Duration <= 0 {
+		t.Errorf("the span of wd.CurrentURL() is %+v, want GET /url, 200 and no error", s)
+	}
+	if s := ended[1]; s.Command != "POST /element" || s.HTTPStatus != http.StatusNotFound || !errors.Is(s.Err, werror.NoSuchElement) {
+		t.Errorf("the span of wd.FindElement() is %+v, want POST /element, 404 and no such element", s)
+	}
+}
@@ -0,0 +1,204 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrKeyNotFound is returned by Storage.Get for keys that are not set.
+var ErrKeyNotFound = errors.New("key not found")
+
+// Storage gives access to one of the Web Storage areas, localStorage or
+// sessionStorage, of the current page's origin.
+type Storage interface {
+	// Get returns the value of a key, or ErrKeyNotFound.
+	Get(key string) (string, error)
+	// Set sets the value of a key.
+	Set(key, value string) error
+	// Remove removes a key. Removing a key that is not set is not an error.
+	Remove(key string) error
+	// Clear removes all keys.
+	Clear() error
+	// Keys returns all keys, in the order defined by the browser.
+	Keys() ([]string, error)
+	// Len returns the number of keys.
+	Len() (int, error)
+}
+
+// webStorage implements Storage with scripts, or with the legacy Selenium
+// endpoints on remote ends that support them.
+type webStorage struct {
+	wd *remoteWD
+	// name is the name of the storage area in the window: "localStorage" or
+	// "sessionStorage".
+	name string
+	// endpoint is the name of the legacy endpoint: "local_storage" or
+	// "session_storage".
+	endpoint string
+}
+
+func (wd *remoteWD) LocalStorage() Storage {
+	return &webStorage{wd: wd, name: "localStorage", endpoint: "local_storage"}
+}
+
+func (wd *remoteWD) SessionStorage() Storage {
+	return &webStorage{wd: wd, name: "sessionStorage", endpoint: "session_storage"}
+}
+
+func (s *webStorage) legacy() bool {
+	return s.wd.Supports(FeatureLegacyWebStorage)
+}
+
+// url returns the URL of a legacy endpoint. suffix is appended to the path of
+// the storage area and is escaped by the caller.
+func (s *webStorage) url(suffix string) string {
+	return s.wd.requestURL("/session/%s/%s%s", s.wd.id, s.endpoint, suffix)
+}
+
+// script runs a script with the storage area as its first argument and
+// unmarshals its result into v.
+func (s *webStorage) script(script string, v interface{}, args ...interface{}) error {
+	data, err := s.wd.ExecuteScriptRaw(script, append([]interface{}{s.name}, args...))
+	if err != nil {
+		return err
+	}
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *webStorage) Get(key string) (string, error) {
+	if s.legacy() {
+		response, err := s.wd.execute("GET", s.url("/key/"+url.PathEscape(key)), nil)
+		if err != nil {
+			return "", err
+		}
+		reply := new(struct{ Value *string })
+		if err := json.Unmarshal(response, reply); err != nil {
+			return "", err
+		}
+		if reply.Value == nil {
+			return "", ErrKeyNotFound
+		}
+		return *reply.Value, nil
+	}
+	reply := new(struct{ Value *string })
+	if err := s.script("return window[arguments[0]].getItem(arguments[1]);", reply, key); err != nil {
+		return "", err
+	}
+	if reply.Value == nil {
+		return "", ErrKeyNotFound
+	}
+	return *reply.Value, nil
+}
+
+func (s *webStorage) Set(key, value string) error {
+	if s.legacy() {
+		data, err := json.Marshal(map[string]string{"key": key, "value": value})
+		if err != nil {
+			return err
+		}
+		_, err = s.wd.execute("POST", s.url(""), data)
+		return err
+	}
+	return s.script("window[arguments[0]].setItem(arguments[1], arguments[2]);", nil, key, value)
+}
+
+func (s *webStorage) Remove(key string) error {
+	if s.legacy() {
+		_, err := s.wd.execute("DELETE", s.url("/key/"+url.PathEscape(key)), nil)
+		return err
+	}
+	return s.script("window[arguments[0]].removeItem(arguments[1]);", nil, key)
+}
+
+func (s *webStorage) Clear() error {
+	if s.legacy() {
+		_, err := s.wd.execute("DELETE", s.url(""), nil)
+		return err
+	}
+	return s.script("window[arguments[0]].clear();", nil)
+}
+
+func (s *webStorage) Keys() ([]string, error) {
+	reply := new(struct{ Value []string })
+	if s.legacy() {
+		response, err := s.wd.execute("GET", s.url(""), nil)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(response, reply); err != nil {
+			return nil, err
+		}
+		return reply.Value, nil
+	}
+	const script = `
+		var storage = window[arguments[0]];
+		var keys = [];
+		for (var i = 0; i < storage.length; i++) {
+			keys.push(storage.key(i));
+		}
+		return keys;`
+	if err := s.script(script, reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+func (s *webStorage) Len() (int, error) {
+	reply := new(struct{ Value int })
+	if s.legacy() {
+		response, err := s.wd.execute("GET", s.url("/size"), nil)
+		if err != nil {
+			return 0, err
+		}
+		if err := json.Unmarshal(response, reply); err != nil {
+			return 0, err
+		}
+		return reply.Value, nil
+	}
+	if err := s.script("return window[arguments[0]].length;", reply); err != nil {
+		return 0, err
+	}
+	return reply.Value, nil
+}
+
+// Site data types for ClearSiteData, as defined by the DevTools Protocol.
+const (
+	SiteDataCookies        = "cookies"
+	SiteDataLocalStorage   = "local_storage"
+	SiteDataIndexedDB      = "indexeddb"
+	SiteDataCacheStorage   = "cache_storage"
+	SiteDataServiceWorkers = "service_workers"
+	SiteDataFileSystems    = "file_systems"
+	SiteDataWebSQL         = "websql"
+	SiteDataAll            = "all"
+)
+
+// ClearSiteData clears the data stored by the browser for the origin of the
+// current page.
+func (wd *remoteWD) ClearSiteData(types ...string) error {
+	if !wd.Supports(FeatureCDP) {
+		return fmt.Errorf("clearing site data: %w", ErrUnsupported)
+	}
+	if len(types) == 0 {
+		types = []string{SiteDataAll}
+	}
+	origin, err := wd.ExecuteScript("return window.location.origin;", nil)
+	if err != nil {
+		return err
+	}
+	o, ok := origin.(string)
+	if !ok || o == "" || o == "null" {
+		return fmt.Errorf("the current page has no origin to clear data for: %v", origin)
+	}
+	_, err = wd.executeCDP("Storage.clearDataForOrigin", map[string]interface{}{
+		"origin":       o,
+		"storageTypes": strings.Join(types, ","),
+	})
+	return err
+}
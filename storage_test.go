@@ -0,0 +1,67 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestLegacyStorageEndpoints(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "htmlunit"})
+	d.legacy = true
+	values := map[string]string{}
+	d.handle("GET", "/local_storage/size", func([]byte) (int, interface{}) {
+		return http.StatusOK, len(values)
+	})
+	d.handle("POST", "/local_storage", func(body []byte) (int, interface{}) {
+		var kv struct{ Key, Value string }
+		if err := json.Unmarshal(body, &kv); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		values[kv.Key] = kv.Value
+		return http.StatusOK, nil
+	})
+	const key = `a "quoted"/key`
+	d.handle("GET", "/local_storage/key/a%20%22quoted%22%2Fkey", func([]byte) (int, interface{}) {
+		v, ok := values[key]
+		if !ok {
+			return http.StatusOK, nil
+		}
+		return http.StatusOK, v
+	})
+	d.handle("DELETE", "/local_storage/key/a%20%22quoted%22%2Fkey", func([]byte) (int, interface{}) {
+		delete(values, key)
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+	if !wd.Supports(FeatureLegacyWebStorage) {
+		t.Fatalf("wd.Supports(FeatureLegacyWebStorage) = false, want true")
+	}
+
+	s := wd.LocalStorage()
+	const value = "line 1\nline 2 \"quoted\" 日本 \U0001F600"
+	if err := s.Set(key, value); err != nil {
+		t.Fatalf("s.Set() returned error: %v", err)
+	}
+	if got, err := s.Get(key); err != nil || got != value {
+		t.Errorf("s.Get(%q) = %q, %v; want %q, nil", key, got, err, value)
+	}
+	if n, err := s.Len(); err != nil || n != 1 {
+		t.Errorf("s.Len() = %d, %v; want 1, nil", n, err)
+	}
+	if err := s.Remove(key); err != nil {
+		t.Fatalf("s.Remove(%q) returned error: %v", key, err)
+	}
+	if _, err := s.Get(key); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("s.Get(%q) after s.Remove() returned error %v, want ErrKeyNotFound", key, err)
+	}
+}
+
+func TestClearSiteDataRequiresCDP(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "firefox"})
+	wd := d.newRemote(t)
+	if err := wd.ClearSiteData(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.ClearSiteData() on Firefox returned error %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,381 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+)
+
+// StorageStateVersion is the version of the format of the files written by
+// SaveStorageState. RestoreStorageState rejects files of later versions.
+const StorageStateVersion = 1
+
+// StorageState is the state of a browser saved by SaveStorageState, encoded
+// as JSON. It contains credentials, such as session cookies and tokens, in
+// clear text.
+type StorageState struct {
+	// Version is the version of the format, StorageStateVersion.
+	Version int `json:"version"`
+	// Cookies are the cookies of all the domains on remote ends that support
+	// the Chrome DevTools Protocol, else those of the saved origins.
+	Cookies []Cookie `json:"cookies"`
+	// Origins are the states of the saved origins.
+	Origins []OriginState `json:"origins"`
+}
+
+// OriginState is the state of an origin in a StorageState.
+type OriginState struct {
+	// Origin is the origin, e.g. "https://app.example".
+	Origin string `json:"origin"`
+	// LocalStorage and SessionStorage are the contents of the storage areas
+	// of the origin.
+	LocalStorage   map[string]string `json:"localStorage,omitempty"`
+	SessionStorage map[string]string `json:"sessionStorage,omitempty"`
+	// Permissions are the names of the permissions granted to the origin,
+	// among those that navigator.permissions.query can introspect, e.g.
+	// "geolocation".
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// StorageStateOption is an option of SaveStorageState.
+type StorageStateOption func(*storageStateOptions)
+
+type storageStateOptions struct {
+	origins []string
+}
+
+// StorageOrigins sets the origins, e.g. "https://app.example", whose storage
+// and permissions SaveStorageState saves, instead of the origin of the
+// current page.
+func StorageOrigins(origins ...string) StorageStateOption {
+	return func(o *storageStateOptions) {
+		o.origins = origins
+	}
+}
+
+// storagePermissions are the permissions that SaveStorageState queries.
+var storagePermissions = []string{
+	"geolocation",
+	"notifications",
+	"camera",
+	"microphone",
+	"clipboard-read",
+	"clipboard-write",
+	"midi",
+	"persistent-storage",
+}
+
+// dumpStorageScript returns the origin and the storage areas of the page.
+const dumpStorageScript = `
+	var dump = function(s) {
+		var o = {};
+		for (var i = 0; i < s.length; i++) {
+			var k = s.key(i);
+			o[k] = s.getItem(k);
+		}
+		return o;
+	};
+	return {
+		origin: window.location.origin,
+		local: dump(window.localStorage),
+		session: dump(window.sessionStorage)
+	};`
+
+// restoreStorageScript sets the items of the storage areas of the page.
+const restoreStorageScript = `
+	var set = function(s, items) {
+		Object.keys(items || {}).forEach(function(k) {
+			s.setItem(k, items[k]);
+		});
+	};
+	set(window.localStorage, arguments[0]);
+	set(window.sessionStorage, arguments[1]);`
+
+// grantedPermissionsScript returns the names, among its first argument, of
+// the permissions granted to the page.
+const grantedPermissionsScript = `
+	var names = arguments[0], done = arguments[arguments.length - 1];
+	if (!navigator.permissions || !navigator.permissions.query) {
+		done([]);
+		return;
+	}
+	Promise.all(names.map(function(name) {
+		return navigator.permissions.query({name: name}).then(function(s) {
+			return s.state === 'granted' ? name : null;
+		}, function() {
+			return null;
+		});
+	})).then(function(granted) {
+		done(granted.filter(function(name) { return name; }));
+	});`
+
+// parseOrigin returns the origin of an http or https URL.
+func parseOrigin(s string) (string, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return "", err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https origin", s)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
+
+// visitOrigin navigates to the root of origin, unless the current page
+// already belongs to it.
+func (wd *remoteWD) visitOrigin(origin string) error {
+	if current, err := wd.CurrentURL(); err == nil {
+		if o, err := parseOrigin(current); err == nil && o == origin {
+			return nil
+		}
+	}
+	return wd.Get(origin + "/")
+}
+
+// SaveStorageState saves the cookies, storage and permissions of the browser
+// to a file.
+func (wd *remoteWD) SaveStorageState(path string, opts ...StorageStateOption) error {
+	var o storageStateOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	start, err := wd.CurrentURL()
+	if err != nil {
+		return err
+	}
+	origins := o.origins
+	if origins == nil {
+		if origin, err := parseOrigin(start); err == nil {
+			origins = []string{origin}
+		}
+	}
+
+	cdp := wd.Supports(FeatureCDP)
+	state := StorageState{Version: StorageStateVersion, Cookies: []Cookie{}, Origins: []OriginState{}}
+	seen := make(map[string]bool)
+	for _, origin := range origins {
+		origin, err := parseOrigin(origin)
+		if err != nil {
+			return fmt.Errorf("saving the storage state: %v", err)
+		}
+		if err := wd.visitOrigin(origin); err != nil {
+			return err
+		}
+		s, err := wd.originState(origin)
+		if err != nil {
+			return err
+		}
+		state.Origins = append(state.Origins, s)
+		if cdp {
+			continue
+		}
+		cookies, err := wd.GetCookies()
+		if err != nil {
+			return err
+		}
+		for _, c := range cookies {
+			key := c.Domain + "\x00" + c.Path + "\x00" + c.Name
+			if !seen[key] {
+				seen[key] = true
+				state.Cookies = append(state.Cookies, c)
+			}
+		}
+	}
+	if cdp {
+		if state.Cookies, err = wd.allCookies(); err != nil {
+			return err
+		}
+	}
+	if current, err := wd.CurrentURL(); err == nil && current != start {
+		if _, err := parseOrigin(start); err == nil {
+			if err := wd.Get(start); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The file contains credentials.
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// originState returns the state of origin, which is the origin of the current
+// page.
+func (wd *remoteWD) originState(origin string) (OriginState, error) {
+	data, err := wd.ExecuteScriptRaw(dumpStorageScript, nil)
+	if err != nil {
+		return OriginState{}, err
+	}
+	reply := new(struct {
+		Value struct {
+			Origin         string
+			Local, Session map[string]string
+		}
+	})
+	if err := json.Unmarshal(data, reply); err != nil {
+		return OriginState{}, err
+	}
+	if reply.Value.Origin != origin {
+		return OriginState{}, fmt.Errorf("saving the storage of %s: the browser is on %q", origin, reply.Value.Origin)
+	}
+	s := OriginState{Origin: origin}
+	if len(reply.Value.Local) > 0 {
+		s.LocalStorage = reply.Value.Local
+	}
+	if len(reply.Value.Session) > 0 {
+		s.SessionStorage = reply.Value.Session
+	}
+	granted, err := wd.ExecuteScriptAsync(grantedPermissionsScript, []interface{}{storagePermissions})
+	if err != nil {
+		return OriginState{}, err
+	}
+	if names, ok := granted.([]interface{}); ok {
+		for _, n := range names {
+			if name, ok := n.(string); ok {
+				s.Permissions = append(s.Permissions, name)
+			}
+		}
+	}
+	return s, nil
+}
+
+// cdpCookie is a cookie of the Chrome DevTools Protocol.
+type cdpCookie struct {
+	Name     string   `json:"name"`
+	Value    string   `json:"value"`
+	Domain   string   `json:"domain"`
+	Path     string   `json:"path"`
+	Expires  float64  `json:"expires,omitempty"`
+	HTTPOnly bool     `json:"httpOnly"`
+	Secure   bool     `json:"secure"`
+	SameSite SameSite `json:"sameSite,omitempty"`
+	Session  bool     `json:"session,omitempty"`
+}
+
+// allCookies returns the cookies of all the domains, with the DevTools
+// Protocol.
+func (wd *remoteWD) allCookies() ([]Cookie, error) {
+	data, err := wd.executeCDP("Network.getAllCookies", nil)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Cookies []cdpCookie })
+	if err := json.Unmarshal(data, reply); err != nil {
+		return nil, err
+	}
+	cookies := make([]Cookie, 0, len(reply.Cookies))
+	for _, c := range reply.Cookies {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HTTPOnly: c.HTTPOnly,
+			SameSite: c.SameSite,
+		}
+		if !c.Session && c.Expires > 0 {
+			cookie.Expiry = uint(c.Expires)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// cookieMatchesHost reports whether a cookie of domain is sent to host.
+func cookieMatchesHost(domain, host string) bool {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "."))
+	host = strings.ToLower(host)
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// RestoreStorageState restores the state saved by SaveStorageState.
+func (wd *remoteWD) RestoreStorageState(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var state StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("reading the storage state %s: %v", path, err)
+	}
+	if state.Version < 1 || state.Version > StorageStateVersion {
+		return fmt.Errorf("reading the storage state %s: unsupported version %d, want at most %d", path, state.Version, StorageStateVersion)
+	}
+
+	cdp := wd.Supports(FeatureCDP)
+	if cdp && len(state.Cookies) > 0 {
+		cookies := make([]cdpCookie, len(state.Cookies))
+		for i, c := range state.Cookies {
+			cookies[i] = cdpCookie{
+				Name:     c.Name,
+				Value:    c.Value,
+				Domain:   c.Domain,
+				Path:     c.Path,
+				Expires:  float64(c.Expiry),
+				HTTPOnly: c.HTTPOnly,
+				Secure:   c.Secure,
+				SameSite: c.SameSite,
+			}
+		}
+		if _, err := wd.executeCDP("Network.setCookies", map[string]interface{}{"cookies": cookies}); err != nil {
+			return err
+		}
+	}
+	restored := make([]bool, len(state.Cookies))
+	for _, s := range state.Origins {
+		origin, err := parseOrigin(s.Origin)
+		if err != nil {
+			return fmt.Errorf("reading the storage state %s: %v", path, err)
+		}
+		if err := wd.visitOrigin(origin); err != nil {
+			return err
+		}
+		if !cdp {
+			u, _ := url.Parse(origin)
+			for i, c := range state.Cookies {
+				if restored[i] || !cookieMatchesHost(c.Domain, u.Hostname()) || (c.Secure && u.Scheme != "https") {
+					continue
+				}
+				c := c
+				if err := wd.AddCookie(&c); err != nil {
+					return fmt.Errorf("restoring cookie %q: %w", c.Name, err)
+				}
+				restored[i] = true
+			}
+		}
+		if len(s.LocalStorage) > 0 || len(s.SessionStorage) > 0 {
+			if _, err := wd.ExecuteScript(restoreStorageScript, []interface{}{s.LocalStorage, s.SessionStorage}); err != nil {
+				return fmt.Errorf("restoring the storage of %s: %w", origin, err)
+			}
+		}
+		for _, name := range s.Permissions {
+			if err := wd.grantPermission(name); err != nil {
+				return fmt.Errorf("granting permission %q to %s: %w", name, origin, err)
+			}
+		}
+	}
+	return nil
+}
+
+// grantPermission grants a permission to the origin of the current page, with
+// the command of the W3C Permissions specification.
+func (wd *remoteWD) grantPermission(name string) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"descriptor": map[string]string{"name": name},
+		"state":      "granted",
+	})
+	if err != nil {
+		return err
+	}
+	_, err = wd.execute("POST", wd.requestURL("/session/%s/permissions", wd.id), data)
+	if isUnknownCommand(err) {
+		return fmt.Errorf("%w: %v", ErrUnsupported, err)
+	}
+	return err
+}
@@ -0,0 +1,233 @@
+package selenium
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeOrigins serves the pages of a fake browser whose origins have storage,
+// cookies and permissions.
+type fakeOrigins struct {
+	url         string
+	visits      []string
+	local       map[string]map[string]string
+	session     map[string]map[string]string
+	cookies     map[string][]Cookie
+	permissions map[string][]string
+	granted     []string
+}
+
+func (f *fakeOrigins) origin() string {
+	o, _ := parseOrigin(f.url)
+	return o
+}
+
+func (f *fakeOrigins) handle(d *fakeDriver) {
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.url
+	})
+	d.handle("POST", "/url", func(body []byte) (int, interface{}) {
+		var params struct{ URL string }
+		json.Unmarshal(body, &params)
+		f.url = params.URL
+		f.visits = append(f.visits, params.URL)
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct {
+			Script string
+			Args   []map[string]string
+		}
+		json.Unmarshal(body, &params)
+		o := f.origin()
+		switch params.Script {
+		case dumpStorageScript:
+			return http.StatusOK, map[string]interface{}{"origin": o, "local": f.local[o], "session": f.session[o]}
+		case restoreStorageScript:
+			for i, m := range []map[string]map[string]string{f.local, f.session} {
+				if len(params.Args[i]) > 0 {
+					m[o] = params.Args[i]
+				}
+			}
+			return http.StatusOK, nil
+		}
+		return http.StatusBadRequest, nil
+	})
+	d.handle("POST", "/execute/async", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.permissions[f.origin()]
+	})
+	d.handle("GET", "/cookie", func([]byte) (int, interface{}) {
+		return http.StatusOK, f.cookies[f.origin()]
+	})
+	d.handle("POST", "/cookie", func(body []byte) (int, interface{}) {
+		var params struct{ Cookie Cookie }
+		json.Unmarshal(body, &params)
+		f.cookies[f.origin()] = append(f.cookies[f.origin()], params.Cookie)
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/permissions", func(body []byte) (int, interface{}) {
+		var params struct {
+			Descriptor struct{ Name string }
+			State      string
+		}
+		json.Unmarshal(body, &params)
+		f.granted = append(f.granted, f.origin()+" "+params.Descriptor.Name+" "+params.State)
+		return http.StatusOK, nil
+	})
+}
+
+func TestStorageState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+
+	saved := &fakeOrigins{
+		url:         "https://app.example/inbox",
+		local:       map[string]map[string]string{"https://app.example": {"token": "secret"}},
+		session:     map[string]map[string]string{"https://auth.example": {"nonce": "1"}},
+		cookies:     map[string][]Cookie{"https://app.example": {{Name: "sid", Value: "s1", Domain: "app.example", Path: "/", Secure: true}}},
+		permissions: map[string][]string{"https://app.example": {"notifications"}},
+	}
+	d := newFakeDriver(t, nil)
+	saved.handle(d)
+	wd := d.newRemote(t)
+	if err := wd.SaveStorageState(path, StorageOrigins("https://app.example", "https://auth.example/login")); err != nil {
+		t.Fatalf("wd.SaveStorageState() returned error: %v", err)
+	}
+	if want := []string{"https://auth.example/", "https://app.example/inbox"}; !reflect.DeepEqual(saved.visits, want) {
+		t.Errorf("wd.SaveStorageState() visited %q, want %q", saved.visits, want)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("wd.SaveStorageState() wrote a file with mode %v, want 0600", perm)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state StorageState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("the saved state is not valid JSON: %v\n%s", err, data)
+	}
+	want := StorageState{
+		Version: StorageStateVersion,
+		Cookies: []Cookie{{Name: "sid", Value: "s1", Domain: "app.example", Path: "/", Secure: true}},
+		Origins: []OriginState{
+			{Origin: "https://app.example", LocalStorage: map[string]string{"token": "secret"}, Permissions: []string{"notifications"}},
+			{Origin: "https://auth.example", SessionStorage: map[string]string{"nonce": "1"}},
+		},
+	}
+	if !reflect.DeepEqual(state, want) {
+		t.Errorf("wd.SaveStorageState() saved %+v, want %+v", state, want)
+	}
+
+	restored := &fakeOrigins{
+		url:     "about:blank",
+		local:   map[string]map[string]string{},
+		session: map[string]map[string]string{},
+		cookies: map[string][]Cookie{},
+	}
+	d = newFakeDriver(t, nil)
+	restored.handle(d)
+	wd = d.newRemote(t)
+	if err := wd.RestoreStorageState(path); err != nil {
+		t.Fatalf("wd.RestoreStorageState() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(restored.local, saved.local) || !reflect.DeepEqual(restored.session, saved.session) {
+		t.Errorf("wd.RestoreStorageState() restored storage %v and %v, want %v and %v", restored.local, restored.session, saved.local, saved.session)
+	}
+	if !reflect.DeepEqual(restored.cookies, saved.cookies) {
+		t.Errorf("wd.RestoreStorageState() restored cookies %v, want %v", restored.cookies, saved.cookies)
+	}
+	if want := []string{"https://app.example notifications granted"}; !reflect.DeepEqual(restored.granted, want) {
+		t.Errorf("wd.RestoreStorageState() granted %q, want %q", restored.granted, want)
+	}
+}
+
+func TestStorageStateCDP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "state.json")
+	f := &fakeOrigins{
+		url:     "http://localhost:8080/",
+		local:   map[string]map[string]string{},
+		session: map[string]map[string]string{},
+		cookies: map[string][]Cookie{},
+	}
+	d := newFakeDriver(t, nil)
+	f.handle(d)
+	var setCookies []cdpCookie
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params struct{ Cookies []cdpCookie }
+		}
+		json.Unmarshal(body, &params)
+		switch params.Cmd {
+		case "Network.getAllCookies":
+			return http.StatusOK, map[string]interface{}{"cookies": []map[string]interface{}{
+				{"name": "a", "value": "1", "domain": ".other.example", "path": "/", "expires": 2000000000.5, "sameSite": "Lax"},
+				{"name": "b", "value": "2", "domain": "localhost", "path": "/", "expires": -1, "session": true},
+			}}
+		case "Network.setCookies":
+			setCookies = params.Params.Cookies
+			return http.StatusOK, map[string]interface{}{}
+		}
+		return http.StatusBadRequest, nil
+	})
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	if err := wd.SaveStorageState(path); err != nil {
+		t.Fatalf("wd.SaveStorageState() returned error: %v", err)
+	}
+	if len(f.visits) != 0 {
+		t.Errorf("wd.SaveStorageState() of the current origin navigated to %q", f.visits)
+	}
+	if err := wd.RestoreStorageState(path); err != nil {
+		t.Fatalf("wd.RestoreStorageState() returned error: %v", err)
+	}
+	want := []cdpCookie{
+		{Name: "a", Value: "1", Domain: ".other.example", Path: "/", Expires: 2000000000, SameSite: SameSiteLax},
+		{Name: "b", Value: "2", Domain: "localhost", Path: "/"},
+	}
+	if !reflect.DeepEqual(setCookies, want) {
+		t.Errorf("wd.RestoreStorageState() set cookies %+v, want %+v", setCookies, want)
+	}
+	if len(f.cookies) != 0 {
+		t.Errorf("wd.RestoreStorageState() added cookies with WebDriver commands: %v", f.cookies)
+	}
+}
+
+func TestRestoreStorageStateVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "storage-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd := newFakeDriver(t, nil).newRemote(t)
+	for _, content := range []string{`{"cookies": []}`, `{"version": 2}`, `not json`} {
+		path := filepath.Join(dir, "state.json")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := wd.RestoreStorageState(path); err == nil || !strings.Contains(err.Error(), "storage state") {
+			t.Errorf("wd.RestoreStorageState() of %s returned error %v, want an error about the storage state", content, err)
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// SessionSummary describes a session, as written by WithSessionSummary.
+type SessionSummary struct {
+	SessionID string    `json:"sessionId"`
+	TestName  string    `json:"testName,omitempty"`
+	Start     time.Time `json:"start"`
+	// DurationMS is the duration of the session, in milliseconds.
+	DurationMS int64 `json:"durationMs"`
+	// Commands counts the commands sent, by method and path relative to the
+	// session, e.g. "POST /url" or "GET /element/:id/text".
+	Commands map[string]int `json:"commands"`
+	// Errors counts the commands that failed, by error code, e.g. "no such
+	// element". Errors that did not come from the remote end are counted as
+	// "transport".
+	Errors map[string]int `json:"errors,omitempty"`
+	// Pages lists the URLs of the pages visited, in order.
+	Pages []string `json:"pages,omitempty"`
+	// Artifacts lists the artifacts recorded with WebDriver.RecordArtifact.
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Wire sums the sizes of the requests and responses of the session.
+	Wire *WireStats `json:"wire,omitempty"`
+	// QuitError is the error returned by Quit, if any.
+	QuitError string `json:"quitError,omitempty"`
+	// Dead is true if the summary was written because the remote end reported
+	// that the session no longer exists.
+	Dead bool `json:"dead,omitempty"`
+}
+
+// Artifact is a file or other resource captured during a session, such as a
+// screenshot taken when an assertion failed.
+type Artifact struct {
+	Name     string `json:"name"`
+	Location string `json:"location"`
+}
+
+// WithSessionSummary makes the session write a SessionSummary, as a line of
+// JSON, to w when it is quit, whether or not Quit succeeds, or when the remote
+// end reports that the session no longer exists.
+func WithSessionSummary(w io.Writer) SessionOption {
+	return func(o *sessionOptions) {
+		o.summary = w
+	}
+}
+
+// sessionRecorder accumulates the SessionSummary of a session from its
+// commands.
+type sessionRecorder struct {
+	w io.Writer
+
+	mu      sync.Mutex
+	summary SessionSummary
+	written bool
+}
+
+func newSessionRecorder(w io.Writer) *sessionRecorder {
+	return &sessionRecorder{
+		w: w,
+		summary: SessionSummary{
+			Start:    time.Now(),
+			Commands: make(map[string]int),
+			Errors:   make(map[string]int),
+		},
+	}
+}
+
+// record accounts for a command sent to the remote end.
+func (r *sessionRecorder) record(wd *remoteWD, method, rawURL string, data, reply []byte, err error) {
+	command := commandType(method, rawURL, wd.urlPrefix, wd.id)
+
+	r.mu.Lock()
+	r.summary.Commands[command]++
+	if err != nil {
+		code := "transport"
+		if e, ok := err.(*Error); ok {
+			code = e.Err
+		}
+		r.summary.Errors[code]++
+	} else {
+		switch command {
+		case "POST /url":
+			params := new(struct{ URL string })
+			if json.Unmarshal(data, params) == nil {
+				r.addPage(params.URL)
+			}
+		case "GET /url":
+			current := new(struct{ Value string })
+			if json.Unmarshal(reply, current) == nil {
+				r.addPage(current.Value)
+			}
+		}
+	}
+	r.mu.Unlock()
+
+	if isInvalidSessionError(err) {
+		r.finish(wd, nil, true)
+	}
+}
+
+// isInvalidSessionError reports whether err indicates that the session no
+// longer exists on the remote end.
+func isInvalidSessionError(err error) bool {
+	return errors.Is(err, werror.InvalidSessionID)
+}
+
+// addPage adds a page to the summary, unless it is the current one.
+func (r *sessionRecorder) addPage(u string) {
+	if u == "" {
+		return
+	}
+	if n := len(r.summary.Pages); n > 0 && r.summary.Pages[n-1] == u {
+		return
+	}
+	r.summary.Pages = append(r.summary.Pages, u)
+}
+
+func (r *sessionRecorder) addArtifact(a Artifact) {
+	r.mu.Lock()
+	r.summary.Artifacts = append(r.summary.Artifacts, a)
+	r.mu.Unlock()
+}
+
+// finish writes the summary, once.
+func (r *sessionRecorder) finish(wd *remoteWD, quitErr error, dead bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.written {
+		return
+	}
+	r.written = true
+
+	s := r.summary
+	s.SessionID = wd.id
+	s.TestName = wd.currentTestName()
+	s.DurationMS = int64(time.Since(s.Start) / time.Millisecond)
+	if quitErr != nil {
+		s.QuitError = quitErr.Error()
+	}
+	s.Dead = dead
+	if w := wd.WireStats(); w.SentBytes > 0 || w.ReceivedBytes > 0 {
+		s.Wire = &w
+	}
+	if err := json.NewEncoder(r.w).Encode(s); err != nil {
+		debugLog("error writing the session summary: %v\n", err)
+	}
+}
+
+// commandType returns the method and the path of a command relative to its
+// session, with element references and storage keys replaced by ":id" and
+// ":key".
+func commandType(method, rawURL, urlPrefix, sessionID string) string {
+	p := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		p = u.EscapedPath()
+	}
+	if base, err := url.Parse(urlPrefix); err == nil {
+		p = strings.TrimPrefix(p, strings.TrimSuffix(base.EscapedPath(), "/"))
+	}
+	if sessionID != "" {
+		if rest := strings.TrimPrefix(p, "/session/"+sessionID); rest != p {
+			p = rest
+			if p == "" {
+				p = "/"
+			}
+		}
+	}
+	segments := strings.Split(p, "/")
+	for i := 1; i < len(segments); i++ {
+		switch segments[i-1] {
+		case "element", "shadow":
+			if segments[i] != "active" {
+				segments[i] = ":id"
+			}
+		case "key":
+			segments[i] = ":key"
+		}
+	}
+	return method + " " + strings.Join(segments, "/")
+}
+
+func (wd *remoteWD) RecordArtifact(name, location string) {
+	if wd.recorder != nil {
+		wd.recorder.addArtifact(Artifact{Name: name, Location: location})
+	}
+}
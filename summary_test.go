@@ -0,0 +1,131 @@
+package selenium
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestCommandType(t *testing.T) {
+	const prefix = "http://localhost:4444/wd/hub"
+	for _, test := range []struct {
+		method, url, want string
+	}{
+		{"POST", prefix + "/session", "POST /session"},
+		{"DELETE", prefix + "/session/abc", "DELETE /"},
+		{"POST", prefix + "/session/abc/url", "POST /url"},
+		{"GET", prefix + "/session/abc/element/active", "GET /element/active"},
+		{"POST", prefix + "/session/abc/element/e-1/element", "POST /element/:id/element"},
+		{"GET", prefix + "/session/abc/element/e-1/attribute/value", "GET /element/:id/attribute/value"},
+		{"GET", prefix + "/session/abc/local_storage/key/cart%2Fsize", "GET /local_storage/key/:key"},
+	} {
+		if got := commandType(test.method, test.url, prefix, "abc"); got != test.want {
+			t.Errorf("commandType(%q, %q) = %q, want %q", test.method, test.url, got, test.want)
+		}
+	}
+}
+
+func TestSessionSummary(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		// The page redirected.
+		return http.StatusOK, "http://example.com/login"
+	})
+	d.handle("DELETE", "", func([]byte) (int, interface{}) {
+		return http.StatusInternalServerError, map[string]string{
+			"error":   "unknown error",
+			"message": "the browser crashed",
+		}
+	})
+
+	var buf bytes.Buffer
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithSessionSummary(&buf))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if err := wd.Get("http://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.CurrentURL(); err != nil {
+		t.Fatalf("wd.CurrentURL() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(ByID, "missing"); err == nil {
+		t.Fatal("wd.FindElement() returned no error for an unknown command")
+	}
+	wd.RecordArtifact("screenshot", "/tmp/failure.png")
+	if err := wd.Quit(); err == nil {
+		t.Fatal("wd.Quit() returned no error")
+	}
+
+	var got SessionSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%s) returned error: %v", buf.Bytes(), err)
+	}
+	want := SessionSummary{
+		SessionID: fakeSessionID,
+		Commands: map[string]int{
+			"POST /session": 1,
+			"POST /url":     1,
+			"GET /url":      1,
+			"POST /element": 1,
+			"DELETE /":      1,
+		},
+		Errors: map[string]int{
+			"unknown command": 1,
+			"unknown error":   1,
+		},
+		Pages:     []string{"http://example.com/", "http://example.com/login"},
+		Artifacts: []Artifact{{Name: "screenshot", Location: "/tmp/failure.png"}},
+		QuitError: "unknown error: the browser crashed",
+	}
+	if diff := cmp.Diff(want, got, cmpopts.IgnoreFields(SessionSummary{}, "Start", "DurationMS", "Wire")); diff != "" {
+		t.Errorf("session summary returned diff (-want/+got):\n%s", diff)
+	}
+	// The sizes depend on the encoding of the requests and replies; every
+	// command is accounted for.
+	if got.Wire == nil || len(got.Wire.ByCommand) != len(want.Commands) || got.Wire.ReceivedBytes == 0 {
+		t.Errorf("session summary has wire stats %+v, want the sizes of the commands %v", got.Wire, want.Commands)
+	}
+
+	// A second Quit must not write another summary.
+	n := buf.Len()
+	wd.Quit()
+	if buf.Len() != n {
+		t.Errorf("a second wd.Quit() wrote %q", buf.Bytes()[n:])
+	}
+}
+
+func TestSessionSummaryDeadSession(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("GET", "/title", func([]byte) (int, interface{}) {
+		return http.StatusNotFound, map[string]string{
+			"error":   "invalid session id",
+			"message": "session deleted because of page crash",
+		}
+	})
+
+	var buf bytes.Buffer
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithSessionSummary(&buf))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	if _, err := wd.Title(); err == nil {
+		t.Fatal("wd.Title() returned no error")
+	}
+
+	var got SessionSummary
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal(%q) returned error: %v", buf.Bytes(), err)
+	}
+	if !got.Dead || got.Errors["invalid session id"] != 1 {
+		t.Errorf("session summary = %+v, want a dead session with an %q error", got, "invalid session id")
+	}
+}
@@ -0,0 +1,277 @@
+package selenium
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FocusStop describes an element that received focus when pressing Tab.
+type FocusStop struct {
+	// Element is the focused element.
+	Element WebElement
+	// TagName is the lower-case tag name of the element.
+	TagName string
+	// Selector is a CSS selector that matches the element in its document.
+	Selector string
+	// Role is the ARIA role of the element, either explicit or implied by its
+	// tag. It is empty for elements without a role.
+	Role string
+	// Name is the accessible name of the element, computed from its ARIA
+	// attributes, labels and contents.
+	Name string
+	// FocusVisible reports whether the element has a visible focus indicator:
+	// an outline or a box shadow in its computed style.
+	FocusVisible bool
+}
+
+func (s FocusStop) String() string {
+	var b strings.Builder
+	b.WriteString(s.Selector)
+	if s.Role != "" {
+		fmt.Fprintf(&b, " [%s]", s.Role)
+	}
+	if s.Name != "" {
+		fmt.Fprintf(&b, " %q", s.Name)
+	}
+	return b.String()
+}
+
+// FocusTrapError is returned by TabOrder when focus cycles among a subset of
+// the elements without ever returning to the first one.
+type FocusTrapError struct {
+	// Trap lists the elements between which the focus cycles.
+	Trap []FocusStop
+}
+
+func (e *FocusTrapError) Error() string {
+	stops := make([]string, len(e.Trap))
+	for i, s := range e.Trap {
+		stops[i] = s.String()
+	}
+	return fmt.Sprintf("focus is trapped among %d elements: %s", len(e.Trap), strings.Join(stops, ", "))
+}
+
+// tabOrderStartScript saves the focus and scroll position so that they can be
+// restored by tabOrderEndScript, and moves the focus navigation starting point
+// to the top of the document.
+const tabOrderStartScript = `
+	var active = document.activeElement;
+	window.__seleniumTabOrder = {
+		active: active && active !== document.body ? active : null,
+		x: window.scrollX,
+		y: window.scrollY,
+		seen: []
+	};
+	var start = document.createElement('span');
+	start.tabIndex = -1;
+	start.id = '__selenium_tab_order_start';
+	document.body.insertBefore(start, document.body.firstChild);
+	start.focus();`
+
+const tabOrderEndScript = `
+	var state = window.__seleniumTabOrder;
+	var start = document.getElementById('__selenium_tab_order_start');
+	if (start) {
+		start.parentNode.removeChild(start);
+	}
+	if (!state) {
+		return;
+	}
+	delete window.__seleniumTabOrder;
+	if (state.active && state.active.focus) {
+		state.active.focus({preventScroll: true});
+	} else if (document.activeElement && document.activeElement.blur) {
+		document.activeElement.blur();
+	}
+	window.scrollTo(state.x, state.y);`
+
+// focusStopScript describes the focused element. seen is the index of the
+// element among those already described, or -1 if it is new; body is true if
+// the focus has left the elements of the document.
+const focusStopScript = `
+	var state = window.__seleniumTabOrder;
+	var start = document.getElementById('__selenium_tab_order_start');
+	if (start) {
+		start.parentNode.removeChild(start);
+	}
+	var el = document.activeElement;
+	if (!el || el === document.body || el === document.documentElement) {
+		return {body: true};
+	}
+	var seen = state.seen.indexOf(el);
+	if (seen < 0) {
+		state.seen.push(el);
+	}
+
+	var text = function(node) {
+		return (node.textContent || '').replace(/\s+/g, ' ').trim();
+	};
+	var tag = el.tagName.toLowerCase();
+
+	var selector = function(el) {
+		if (el.id && document.querySelectorAll('#' + CSS.escape(el.id)).length === 1) {
+			return '#' + CSS.escape(el.id);
+		}
+		var parts = [];
+		for (var node = el; node && node.nodeType === 1 && node !== document.documentElement; node = node.parentElement) {
+			var part = node.tagName.toLowerCase();
+			var index = 1;
+			for (var sib = node.previousElementSibling; sib; sib = sib.previousElementSibling) {
+				if (sib.tagName === node.tagName) {
+					index++;
+				}
+			}
+			parts.unshift(part + ':nth-of-type(' + index + ')');
+			if (node.id && document.querySelectorAll('#' + CSS.escape(node.id)).length === 1) {
+				parts[0] = '#' + CSS.escape(node.id);
+				break;
+			}
+		}
+		return parts.join(' > ');
+	};
+
+	var role = (el.getAttribute('role') || '').trim().split(/\s+/)[0];
+	if (!role) {
+		var type = (el.getAttribute('type') || 'text').toLowerCase();
+		switch (tag) {
+		case 'a':
+		case 'area':
+			role = el.hasAttribute('href') ? 'link' : '';
+			break;
+		case 'button':
+		case 'summary':
+			role = 'button';
+			break;
+		case 'textarea':
+			role = 'textbox';
+			break;
+		case 'select':
+			role = el.multiple || el.size > 1 ? 'listbox' : 'combobox';
+			break;
+		case 'input':
+			role = {
+				button: 'button', submit: 'button', reset: 'button', image: 'button',
+				checkbox: 'checkbox', radio: 'radio', range: 'slider',
+				number: 'spinbutton', search: 'searchbox',
+				text: 'textbox', email: 'textbox', tel: 'textbox', url: 'textbox'
+			}[type] || '';
+			break;
+		default:
+			role = el.isContentEditable ? 'textbox' : '';
+		}
+	}
+
+	var name = '';
+	var labelledBy = el.getAttribute('aria-labelledby');
+	if (labelledBy) {
+		name = labelledBy.trim().split(/\s+/).map(function(id) {
+			var label = document.getElementById(id);
+			return label ? text(label) : '';
+		}).join(' ').trim();
+	}
+	if (!name) {
+		name = (el.getAttribute('aria-label') || '').trim();
+	}
+	if (!name && el.labels && el.labels.length) {
+		name = Array.prototype.map.call(el.labels, text).join(' ').trim();
+	}
+	if (!name && tag === 'input' && /^(button|submit|reset)$/.test(el.type)) {
+		name = el.value;
+	}
+	if (!name) {
+		name = (el.getAttribute('alt') || '').trim();
+	}
+	if (!name && /^(link|button|checkbox|radio|tab|menuitem|option)$/.test(role)) {
+		name = text(el);
+	}
+	if (!name) {
+		name = (el.getAttribute('title') || el.getAttribute('placeholder') || '').trim();
+	}
+
+	var style = window.getComputedStyle(el);
+	var outline = style.outlineStyle !== 'none' && parseFloat(style.outlineWidth) > 0 &&
+		!/rgba\(.*,\s*0\)$|transparent/.test(style.outlineColor);
+	var shadow = style.boxShadow && style.boxShadow !== 'none';
+
+	return {
+		element: el,
+		seen: seen,
+		tagName: tag,
+		selector: selector(el),
+		role: role,
+		name: name,
+		focusVisible: !!(outline || shadow)
+	};`
+
+// TabOrder walks the keyboard focus order of the current page.
+func (wd *remoteWD) TabOrder(limit int) (stops []FocusStop, err error) {
+	if _, err := wd.ExecuteScript(tabOrderStartScript, nil); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if _, restoreErr := wd.ExecuteScript(tabOrderEndScript, nil); restoreErr != nil && err == nil {
+			err = restoreErr
+		}
+	}()
+
+	for len(stops) < limit {
+		if err := wd.pressKey(TabKey); err != nil {
+			return stops, err
+		}
+		data, err := wd.ExecuteScriptRaw(focusStopScript, nil)
+		if err != nil {
+			return stops, err
+		}
+		reply := new(struct {
+			Value struct {
+				Element      map[string]string
+				Body         bool
+				Seen         int
+				TagName      string
+				Selector     string
+				Role         string
+				Name         string
+				FocusVisible bool
+			}
+		})
+		if err := json.Unmarshal(data, reply); err != nil {
+			return stops, err
+		}
+		v := reply.Value
+		switch {
+		case v.Body:
+			// The focus has left the document after its last element.
+			return stops, nil
+		case v.Seen == 0 && len(stops) > 1:
+			// The focus has cycled back to the first element.
+			return stops, nil
+		case v.Seen >= 0:
+			return stops, &FocusTrapError{Trap: stops[v.Seen:]}
+		}
+		stops = append(stops, FocusStop{
+			Element:      &remoteWE{parent: wd, id: elementIDFromValue(v.Element)},
+			TagName:      v.TagName,
+			Selector:     v.Selector,
+			Role:         v.Role,
+			Name:         v.Name,
+			FocusVisible: v.FocusVisible,
+		})
+	}
+	return stops, nil
+}
+
+// pressKey presses and releases a single key, sent to the focused element.
+func (wd *remoteWD) pressKey(key string) error {
+	if !wd.w3cCompatible {
+		return wd.voidCommand("/session/%s/keys", wd.processKeyString(key))
+	}
+	return wd.voidCommand("/session/%s/actions", map[string]interface{}{
+		"actions": []interface{}{
+			map[string]interface{}{
+				"type":    "key",
+				"id":      DefaultKeyboard,
+				"actions": []KeyAction{KeyDownAction(key), KeyUpAction(key)},
+			}},
+	})
+}
@@ -0,0 +1,98 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+// fakeTabOrder answers the scripts of TabOrder on a fake driver, reporting
+// the focus on the given elements in turn.
+func fakeTabOrder(t *testing.T, d *fakeDriver, focused []map[string]interface{}) {
+	d.handle("POST", "/actions", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	next := 0
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var args struct{ Script string }
+		if err := json.Unmarshal(body, &args); err != nil {
+			t.Errorf("json.Unmarshal(%s) returned error: %v", body, err)
+		}
+		if args.Script != focusStopScript {
+			return http.StatusOK, nil
+		}
+		if next >= len(focused) {
+			t.Errorf("TabOrder pressed Tab more than %d times", len(focused))
+			return http.StatusOK, map[string]interface{}{"body": true}
+		}
+		next++
+		return http.StatusOK, focused[next-1]
+	})
+}
+
+func focusedElement(id string, seen int) map[string]interface{} {
+	return map[string]interface{}{
+		"element":  map[string]string{webElementIdentifier: id},
+		"seen":     seen,
+		"selector": "#" + id,
+		"role":     "button",
+	}
+}
+
+func TestTabOrderStopsWhenFocusCycles(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	fakeTabOrder(t, d, []map[string]interface{}{
+		focusedElement("a", -1),
+		focusedElement("b", -1),
+		focusedElement("a", 0),
+	})
+	wd := d.newRemote(t)
+
+	stops, err := wd.TabOrder(10)
+	if err != nil {
+		t.Fatalf("wd.TabOrder(10) returned error: %v", err)
+	}
+	if len(stops) != 2 || stops[0].Selector != "#a" || stops[1].Selector != "#b" {
+		t.Errorf("wd.TabOrder(10) = %v, want #a and #b", stops)
+	}
+}
+
+func TestTabOrderDetectsFocusTrap(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	fakeTabOrder(t, d, []map[string]interface{}{
+		focusedElement("a", -1),
+		focusedElement("b", -1),
+		focusedElement("c", -1),
+		focusedElement("b", 1),
+	})
+	wd := d.newRemote(t)
+
+	stops, err := wd.TabOrder(10)
+	trapErr, ok := err.(*FocusTrapError)
+	if !ok {
+		t.Fatalf("wd.TabOrder(10) returned error %v, want a *FocusTrapError", err)
+	}
+	if len(stops) != 3 {
+		t.Errorf("wd.TabOrder(10) returned %d stops, want 3", len(stops))
+	}
+	if len(trapErr.Trap) != 2 || trapErr.Trap[0].Selector != "#b" || trapErr.Trap[1].Selector != "#c" {
+		t.Errorf("FocusTrapError.Trap = %v, want #b and #c", trapErr.Trap)
+	}
+}
+
+func TestTabOrderStopsAtLimit(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	fakeTabOrder(t, d, []map[string]interface{}{
+		focusedElement("a", -1),
+		focusedElement("b", -1),
+	})
+	wd := d.newRemote(t)
+
+	stops, err := wd.TabOrder(2)
+	if err != nil {
+		t.Fatalf("wd.TabOrder(2) returned error: %v", err)
+	}
+	if len(stops) != 2 {
+		t.Errorf("wd.TabOrder(2) returned %d stops, want 2", len(stops))
+	}
+}
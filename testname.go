@@ -0,0 +1,87 @@
+package selenium
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// TestNameProperty is the property of the window object under which pages
+// can read the name set with WebDriver.SetTestName, e.g. to include it in
+// their own logs.
+const TestNameProperty = "__seleniumTestName"
+
+// TestNameCapability is the capability under which Selenium Grid and
+// compatible services expect the name of the test.
+const TestNameCapability = "se:name"
+
+// SetTestName sets the name under which the session is shown by Selenium Grid
+// and compatible services. Use WebDriver.SetTestName to set it once the session
+// has been created.
+func (c Capabilities) SetTestName(name string) {
+	c[TestNameCapability] = name
+}
+
+// UniqueTestName returns the name of the test t followed by a random suffix,
+// which tells apart sessions of the same test that run concurrently or
+// repeatedly:
+//
+//	caps.SetTestName(selenium.UniqueTestName(t))
+func UniqueTestName(t interface{ Name() string }) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return t.Name()
+	}
+	return t.Name() + "#" + hex.EncodeToString(b)
+}
+
+// SetTestName associates the session with a test.
+func (wd *remoteWD) SetTestName(name string) error {
+	wd.testNameMu.Lock()
+	wd.testName = name
+	previous := wd.testNameScript
+	wd.testNameScript = ""
+	wd.testNameMu.Unlock()
+	if wd.isSauceSession() {
+		if _, err := wd.ExecuteScript("sauce:job-name="+name, nil); err != nil {
+			return err
+		}
+	}
+
+	v, err := json.Marshal(name)
+	if err != nil {
+		return err
+	}
+	script := fmt.Sprintf("window[%q] = %s;", TestNameProperty, v)
+	if wd.Supports(FeatureCDP) {
+		// The script of the previous name would run in the new pages too.
+		if previous != "" {
+			if err := wd.removeInitScript(previous); err != nil {
+				return err
+			}
+		}
+		id, err := wd.addInitScript(script)
+		if err != nil {
+			return err
+		}
+		wd.testNameMu.Lock()
+		wd.testNameScript = id
+		wd.testNameMu.Unlock()
+	}
+	_, err = wd.ExecuteScript(script, nil)
+	return err
+}
+
+// currentTestName returns the name set with SetTestName.
+func (wd *remoteWD) currentTestName() string {
+	wd.testNameMu.Lock()
+	defer wd.testNameMu.Unlock()
+	return wd.testName
+}
+
+// isSauceSession reports whether the session runs on Sauce Labs, which
+// accepts annotations through ExecuteScript.
+func (wd *remoteWD) isSauceSession() bool {
+	return wd.provider() == SauceLabs
+}
@@ -0,0 +1,95 @@
+package selenium
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSetTestName(t *testing.T) {
+	d := newFakeDriver(t, map[string]interface{}{"browserName": "firefox"})
+	var script string
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		script = string(body)
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+
+	const name = "TestCheckout/guest"
+	if err := wd.SetTestName(name); err != nil {
+		t.Fatalf("wd.SetTestName(%q) returned error: %v", name, err)
+	}
+	if !strings.Contains(script, TestNameProperty) || !strings.Contains(script, "TestCheckout/guest") {
+		t.Errorf("wd.SetTestName(%q) ran the script %s, want it to set window.%s", name, script, TestNameProperty)
+	}
+
+	// The fake driver does not implement the title command.
+	_, err := wd.Title()
+	if err == nil {
+		t.Fatalf("wd.Title() returned nil error, want an unknown command error")
+	}
+	if want := "[" + name + "] unknown command"; !strings.HasPrefix(err.Error(), want) {
+		t.Errorf("wd.Title() returned error %q, want it to start with %q", err, want)
+	}
+}
+
+func TestSetTestNameReplacesInitScript(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	scripts := map[string]string{}
+	var added int
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct {
+			Cmd    string
+			Params struct {
+				Source     string
+				Identifier string
+			}
+		}
+		json.Unmarshal(body, &params)
+		switch params.Cmd {
+		case "Page.addScriptToEvaluateOnNewDocument":
+			added++
+			id := strconv.Itoa(added)
+			scripts[id] = params.Params.Source
+			return http.StatusOK, map[string]interface{}{"identifier": id}
+		case "Page.removeScriptToEvaluateOnNewDocument":
+			delete(scripts, params.Params.Identifier)
+		}
+		return http.StatusOK, map[string]interface{}{}
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	for _, name := range []string{"TestFirst", "TestSecond"} {
+		if err := wd.SetTestName(name); err != nil {
+			t.Fatalf("wd.SetTestName(%q) returned error: %v", name, err)
+		}
+	}
+	if len(scripts) != 1 {
+		t.Fatalf("wd.SetTestName() left %d init scripts registered, want 1: %v", len(scripts), scripts)
+	}
+	for _, script := range scripts {
+		if !strings.Contains(script, "TestSecond") {
+			t.Errorf("wd.SetTestName() left the init script %s registered, want the one of TestSecond", script)
+		}
+	}
+}
+
+type namedTest string
+
+func (n namedTest) Name() string { return string(n) }
+
+func TestUniqueTestName(t *testing.T) {
+	a, b := UniqueTestName(namedTest("TestLogin")), UniqueTestName(namedTest("TestLogin"))
+	if !strings.HasPrefix(a, "TestLogin#") || !strings.HasPrefix(b, "TestLogin#") {
+		t.Errorf("UniqueTestName() = %q, %q; want names starting with %q", a, b, "TestLogin#")
+	}
+	if a == b {
+		t.Errorf("UniqueTestName() returned %q twice, want unique names", a)
+	}
+}
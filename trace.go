@@ -0,0 +1,212 @@
+package selenium
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandTraceVersion is the version of the schema of the records written by
+// WithCommandTrace, in their "v" field. Fields may be added to the schema
+// within a version; it is increased if fields are removed or change meaning.
+const CommandTraceVersion = 1
+
+// Origins of the commands in a CommandRecord.
+const (
+	// OriginTest marks the commands sent by the test, directly or through a
+	// helper such as Open.
+	OriginTest = "test"
+	// OriginWait marks the commands sent while a wait helper, such as Wait or
+	// ClickWhenReady, polls the page.
+	OriginWait = "wait"
+	// OriginBackground marks the commands sent by background pollers, such
+	// as WatchWindows.
+	OriginBackground = "background"
+)
+
+// CommandRecord is a record of the trace written by WithCommandTrace, one per
+// command sent to the remote end, as a line of JSON. The schema is stable:
+// see CommandTraceVersion.
+type CommandRecord struct {
+	// Version is CommandTraceVersion.
+	Version int `json:"v"`
+	// Seq numbers the commands of the session from 1, in the order they were
+	// sent.
+	Seq       int64  `json:"seq"`
+	SessionID string `json:"sessionId"`
+	// Start is when the command was sent.
+	Start time.Time `json:"start"`
+	// DurationMS is how long the remote end took to reply, in milliseconds.
+	DurationMS float64 `json:"durationMs"`
+	// Command is the method and the path of the command relative to its
+	// session, as in session summaries, e.g. "POST /element/:id/click".
+	Command string `json:"command"`
+	// Element is the ID of the element of the command, if any.
+	Element string `json:"element,omitempty"`
+	// Params are the parameters of the command. Locators and URLs are kept;
+	// other values longer than 256 bytes of JSON are replaced by their
+	// ParamDigest.
+	Params map[string]interface{} `json:"params,omitempty"`
+	// Status is "ok" or "error".
+	Status string `json:"status"`
+	// Error is the WebDriver error code of a failed command, e.g. "no such
+	// element", or "transport" if the remote end did not reply.
+	Error string `json:"error,omitempty"`
+	// Origin is one of OriginTest, OriginWait and OriginBackground.
+	Origin string `json:"origin"`
+}
+
+// ParamDigest replaces a large parameter in a CommandRecord.
+type ParamDigest struct {
+	// Bytes is the size of the JSON value.
+	Bytes int `json:"bytes"`
+	// SHA256 is the hex-encoded SHA-256 hash of the JSON value.
+	SHA256 string `json:"sha256"`
+}
+
+// maxTraceParam is the size of the JSON of the largest parameter kept as is
+// in a CommandRecord.
+const maxTraceParam = 256
+
+// tracedParams are the parameters kept in a CommandRecord whatever their
+// size.
+var tracedParams = map[string]bool{
+	"using": true,
+	"url":   true,
+}
+
+// WithCommandTrace makes the session write a CommandRecord, as a line of
+// JSON, to w for each command, in order, e.g. for tools that analyze flaky
+// tests. The records are written by a single goroutine of the session, each
+// with one call to w.Write, as soon as the command completes; if w has a
+// Flush method, such as a *bufio.Writer, it is called after each record.
+// The trace ends when the session is quit.
+func WithCommandTrace(w io.Writer) SessionOption {
+	return func(o *sessionOptions) {
+		o.trace = w
+	}
+}
+
+// commandTracer writes the CommandRecords of a session.
+type commandTracer struct {
+	w       io.Writer
+	records chan CommandRecord
+	done    chan struct{}
+
+	mu     sync.Mutex
+	seq    int64
+	closed bool
+}
+
+func newCommandTracer(w io.Writer) *commandTracer {
+	t := &commandTracer{
+		w:       w,
+		records: make(chan CommandRecord, 64),
+		done:    make(chan struct{}),
+	}
+	go t.write()
+	return t
+}
+
+func (t *commandTracer) write() {
+	defer close(t.done)
+	flusher, _ := t.w.(interface{ Flush() error })
+	for r := range t.records {
+		line, err := json.Marshal(r)
+		if err != nil {
+			debugLog("error encoding the command trace: %v", err)
+			continue
+		}
+		if _, err := t.w.Write(append(line, '\n')); err != nil {
+			debugLog("error writing the command trace: %v", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+// next returns the sequence number of the next command.
+func (t *commandTracer) next() int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seq++
+	return t.seq
+}
+
+// record queues the record of a command sent to the remote end.
+func (t *commandTracer) record(wd *remoteWD, seq int64, tag commandTag, method, rawURL string, data []byte, start time.Time, elapsed time.Duration, err error) {
+	r := CommandRecord{
+		Version:    CommandTraceVersion,
+		Seq:        seq,
+		SessionID:  wd.id,
+		Start:      start,
+		DurationMS: float64(elapsed) / float64(time.Millisecond),
+		Command:    commandType(method, rawURL, wd.urlPrefix, wd.id),
+		Element:    commandElement(rawURL),
+		Params:     traceParams(data),
+		Status:     "ok",
+		Origin:     OriginTest,
+	}
+	switch tag {
+	case tagWaitPoll:
+		r.Origin = OriginWait
+	case tagBackground:
+		r.Origin = OriginBackground
+	}
+	if err != nil {
+		r.Status, r.Error = "error", "transport"
+		if e, ok := err.(*Error); ok {
+			r.Error = e.Err
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.closed {
+		t.records <- r
+	}
+}
+
+// close ends the trace once the queued records are written.
+func (t *commandTracer) close() {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.records)
+	}
+	t.mu.Unlock()
+	<-t.done
+}
+
+// commandElement returns the ID of the element in the URL of a command.
+func commandElement(rawURL string) string {
+	segments := strings.Split(rawURL, "/")
+	for i := 1; i < len(segments); i++ {
+		if segments[i-1] == "element" && segments[i] != "active" {
+			return segments[i]
+		}
+	}
+	return ""
+}
+
+// traceParams returns the parameters of a command for a CommandRecord.
+func traceParams(data []byte) map[string]interface{} {
+	var params map[string]json.RawMessage
+	if len(data) == 0 || json.Unmarshal(data, &params) != nil || len(params) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		if len(v) <= maxTraceParam || tracedParams[k] || (k == "value" && len(v) > 0 && v[0] == '"') {
+			out[k] = v
+			continue
+		}
+		sum := sha256.Sum256(v)
+		out[k] = ParamDigest{Bytes: len(v), SHA256: hex.EncodeToString(sum[:])}
+	}
+	return out
+}
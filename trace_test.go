@@ -0,0 +1,109 @@
+package selenium
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandTrace(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return noSuchElementReply()
+	})
+	d.handle("POST", "/execute/sync", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	var out bytes.Buffer
+	w := bufio.NewWriter(&out)
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WithCommandTrace(w))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	if err := wd.Get("https://example.com/"); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.FindElement(ByCSSSelector, "#missing"); err == nil {
+		t.Fatal("wd.FindElement() returned no error")
+	}
+	script := "return 1;" + strings.Repeat(" ", 1000)
+	if _, err := wd.ExecuteScript(script, nil); err != nil {
+		t.Fatalf("wd.ExecuteScript() returned error: %v", err)
+	}
+	wd.WaitWithTimeoutAndInterval(func(wd WebDriver) (bool, error) {
+		_, err := wd.FindElement(ByID, "later")
+		return err == nil, nil
+	}, 10*time.Millisecond, time.Millisecond)
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+
+	var records []CommandRecord
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var r CommandRecord
+		if err := json.Unmarshal([]byte(line), &r); err != nil {
+			t.Fatalf("json.Unmarshal(%q) returned error: %v", line, err)
+		}
+		records = append(records, r)
+	}
+	if len(records) < 5 {
+		t.Fatalf("the trace has %d records, want at least 5:\n%s", len(records), out.String())
+	}
+	for i, r := range records {
+		if r.Version != CommandTraceVersion || r.Seq != int64(i+1) || r.SessionID != fakeSessionID {
+			t.Errorf("record %d = %+v, want version %d, seq %d and session %q", i, r, CommandTraceVersion, i+1, fakeSessionID)
+		}
+	}
+
+	get, find, exec := records[0], records[1], records[2]
+	if get.Command != "POST /url" || get.Status != "ok" || get.Origin != OriginTest || string(mustJSON(t, get.Params["url"])) != `"https://example.com/"` {
+		t.Errorf("the record of Get is %+v", get)
+	}
+	if find.Command != "POST /element" || find.Status != "error" || find.Error != "no such element" || find.Params["value"] != "#missing" {
+		t.Errorf("the record of FindElement is %+v", find)
+	}
+	var digest ParamDigest
+	if err := json.Unmarshal(mustJSON(t, exec.Params["script"]), &digest); err != nil || digest.Bytes != len(mustJSON(t, script)) || len(digest.SHA256) != 64 {
+		t.Errorf("the record of ExecuteScript has script parameter %v, want a digest of %d bytes", exec.Params["script"], len(mustJSON(t, script)))
+	}
+	for _, r := range records[3 : len(records)-1] {
+		if r.Origin != OriginWait {
+			t.Errorf("the record of a wait poll is %+v, want origin %q", r, OriginWait)
+		}
+	}
+	if last := records[len(records)-1]; last.Command != "DELETE /" {
+		t.Errorf("the last record is %+v, want the deletion of the session", last)
+	}
+}
+
+func mustJSON(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("json.Marshal(%v) returned error: %v", v, err)
+	}
+	return b
+}
+
+func TestCommandElement(t *testing.T) {
+	for _, test := range []struct {
+		url, want string
+	}{
+		{"http://localhost:4444/session/s/element/e1/click", "e1"},
+		{"http://localhost:4444/session/s/element/active", ""},
+		{"http://localhost:4444/session/s/url", ""},
+	} {
+		if got := commandElement(test.url); got != test.want {
+			t.Errorf("commandElement(%q) = %q, want %q", test.url, got, test.want)
+		}
+	}
+}
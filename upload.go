@@ -0,0 +1,181 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/LoveOyy/selenium/archive"
+)
+
+// isLocalRemote reports whether the remote end runs on this machine, so that
+// it can read local files.
+func (wd *remoteWD) isLocalRemote() bool {
+	u, err := url.Parse(wd.urlPrefix)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// UploadFile makes a local file available to the remote end.
+func (wd *remoteWD) UploadFile(localPath string) (string, error) {
+	if wd.isLocalRemote() {
+		return localPath, nil
+	}
+	buf, err := archive.File(localPath, archive.ForGridUpload())
+	if err != nil {
+		return "", err
+	}
+	params, err := json.Marshal(map[string]string{
+		"file": base64.StdEncoding.EncodeToString(buf.Bytes()),
+	})
+	if err != nil {
+		return "", err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/se/file", wd.id), params)
+	if isUnknownCommand(err) {
+		// Selenium servers before Grid 4 take the file at the path of the
+		// JSON wire protocol.
+		response, err = wd.execute("POST", wd.requestURL("/session/%s/file", wd.id), params)
+	}
+	if err != nil {
+		if isUnknownCommand(err) {
+			return "", fmt.Errorf("uploading %s: %w", localPath, ErrUnsupported)
+		}
+		return "", err
+	}
+	reply := new(struct{ Value string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return "", err
+	}
+	return reply.Value, nil
+}
+
+// FileDetector returns the path of the local file named by the keys typed
+// into an element, or "" if they do not name one; see WithFileDetector.
+type FileDetector func(keys string) string
+
+// LocalFileDetector is a FileDetector that detects the paths of the existing
+// regular files.
+func LocalFileDetector(keys string) string {
+	info, err := os.Stat(keys)
+	if err != nil || !info.Mode().IsRegular() {
+		return ""
+	}
+	return keys
+}
+
+// WithFileDetector makes WebElement.SendKeys upload the local files named by
+// the keys, as detected by d, e.g. LocalFileDetector, with UploadFile, and
+// type the paths under which the remote end can read them instead, so that
+// tests filling file inputs run unchanged against a remote Selenium Grid.
+// Several paths separated by newlines, as typed into the file inputs with
+// the multiple attribute, are uploaded if they all are detected.
+func WithFileDetector(d FileDetector) SessionOption {
+	return func(o *sessionOptions) {
+		o.fileDetector = d
+	}
+}
+
+// uploadDetectedFiles returns the keys to type for keys, with the local
+// files detected by the FileDetector of the session replaced by their
+// uploaded copies.
+func (wd *remoteWD) uploadDetectedFiles(keys string) (string, error) {
+	if wd.fileDetector == nil || keys == "" {
+		return keys, nil
+	}
+	paths := strings.Split(keys, "\n")
+	for i, p := range paths {
+		if paths[i] = wd.fileDetector(p); paths[i] == "" {
+			return keys, nil
+		}
+	}
+	for i, p := range paths {
+		remote, err := wd.UploadFile(p)
+		if err != nil {
+			return "", err
+		}
+		paths[i] = remote
+	}
+	return strings.Join(paths, "\n"), nil
+}
+
+// tempFile writes data to a file named filename in a new temporary directory,
+// which is removed when the session ends.
+func (wd *remoteWD) tempFile(filename string, data []byte) (string, error) {
+	dir, err := ioutil.TempDir("", "selenium-upload-")
+	if err != nil {
+		return "", err
+	}
+	wd.tempDirs = append(wd.tempDirs, dir)
+	trackTempPath(dir)
+	path := filepath.Join(dir, filepath.Base(filename))
+	return path, ioutil.WriteFile(path, data, 0600)
+}
+
+// removeTempDirs removes the directories created by tempFile.
+func (wd *remoteWD) removeTempDirs() {
+	for _, dir := range wd.tempDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			debugLog("error removing %s: %v", dir, err)
+			continue
+		}
+		untrackTempPath(dir)
+	}
+	wd.tempDirs = nil
+}
+
+// uploadScript sets the file passed as arguments to the file input given as
+// the first argument, and dispatches the events of a user selection.
+const uploadScript = `
+	var input = arguments[0], name = arguments[1], data = arguments[2], type = arguments[3];
+	if (!(input instanceof HTMLInputElement) || input.type !== 'file') {
+		return 'the element is not a file input';
+	}
+	var bytes = atob(data);
+	var buf = new Uint8Array(bytes.length);
+	for (var i = 0; i < bytes.length; i++) {
+		buf[i] = bytes.charCodeAt(i);
+	}
+	var transfer = new DataTransfer();
+	transfer.items.add(new File([buf], name, {type: type}));
+	input.files = transfer.files;
+	input.dispatchEvent(new Event('input', {bubbles: true}));
+	input.dispatchEvent(new Event('change', {bubbles: true}));
+	return '';`
+
+// UploadBytes selects data as the file of a file input.
+func (elem *remoteWE) UploadBytes(filename string, data []byte, mime string) error {
+	wd := elem.parent
+	local, err := wd.tempFile(filename, data)
+	if err != nil {
+		return err
+	}
+	path, err := wd.UploadFile(local)
+	if err == nil {
+		if err = elem.SendKeys(path); err == nil {
+			return nil
+		}
+	}
+	debugLog("uploading %s through the driver failed, setting it by script: %v", filename, err)
+	result, err := wd.ExecuteScript(uploadScript, []interface{}{elem, filepath.Base(filename), base64.StdEncoding.EncodeToString(data), mime})
+	if err != nil {
+		return err
+	}
+	if msg, _ := result.(string); msg != "" {
+		return fmt.Errorf("uploading %s: %s", filename, msg)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsLocalRemote(t *testing.T) {
+	for prefix, want := range map[string]bool{
+		"http://localhost:4444/wd/hub":    true,
+		"http://127.0.0.1:9515":           true,
+		"http://[::1]:9515":               true,
+		"https://grid.example.com/wd/hub": false,
+		"http://10.0.0.5:4444/wd/hub":     false,
+	} {
+		wd := &remoteWD{remoteSession: &remoteSession{urlPrefix: prefix}}
+		if got := wd.isLocalRemote(); got != want {
+			t.Errorf("isLocalRemote() for %s = %t, want %t", prefix, got, want)
+		}
+	}
+}
+
+func TestUploadBytes(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var typed string
+	d.handle("POST", "/element/input/value", func(body []byte) (int, interface{}) {
+		var params struct{ Text string }
+		json.Unmarshal(body, &params)
+		typed = params.Text
+		return http.StatusOK, nil
+	})
+	wd := d.newRemote(t)
+	input := &remoteWE{parent: wd, id: "input"}
+
+	if err := input.UploadBytes("report.csv", []byte("a,b\n"), "text/csv"); err != nil {
+		t.Fatalf("input.UploadBytes() returned error: %v", err)
+	}
+	if filepath.Base(typed) != "report.csv" {
+		t.Fatalf("input.UploadBytes() typed %q, want the path of report.csv", typed)
+	}
+	if data, err := ioutil.ReadFile(typed); err != nil || string(data) != "a,b\n" {
+		t.Errorf("the uploaded file holds %q, %v; want the data", data, err)
+	}
+	if err := wd.Quit(); err != nil {
+		t.Fatalf("wd.Quit() returned error: %v", err)
+	}
+	if _, err := os.Stat(typed); !os.IsNotExist(err) {
+		t.Errorf("after wd.Quit(), the uploaded file still exists: %v", err)
+	}
+}
+
+func TestUploadBytesScriptFallback(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/element/input/value", func([]byte) (int, interface{}) {
+		return http.StatusBadRequest, map[string]string{
+			"error":   "invalid argument",
+			"message": "File not found",
+		}
+	})
+	var script string
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct{ Script string }
+		json.Unmarshal(body, &params)
+		script = params.Script
+		return http.StatusOK, ""
+	})
+	wd := d.newRemote(t)
+	input := &remoteWE{parent: wd, id: "input"}
+
+	if err := input.UploadBytes("photo.png", []byte{0x89, 'P', 'N', 'G'}, "image/png"); err != nil {
+		t.Fatalf("input.UploadBytes() returned error: %v", err)
+	}
+	if !strings.Contains(script, "DataTransfer") {
+		t.Errorf("input.UploadBytes() ran the script %q, want the DataTransfer fallback", script)
+	}
+}
+
+func TestFileDetector(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var uploads int
+	d.handle("POST", "/file", func(body []byte) (int, interface{}) {
+		var params struct{ File string }
+		json.Unmarshal(body, &params)
+		if params.File == "" {
+			return http.StatusBadRequest, map[string]string{"error": "invalid argument", "message": "no file"}
+		}
+		uploads++
+		return http.StatusOK, fmt.Sprintf("/remote/%d", uploads)
+	})
+	var typed []string
+	d.handle("POST", "/element/input/value", func(body []byte) (int, interface{}) {
+		var params struct{ Text string }
+		json.Unmarshal(body, &params)
+		typed = append(typed, params.Text)
+		return http.StatusOK, nil
+	})
+	// The session is created for a remote host, so that the files are
+	// uploaded, and the requests are sent to the fake driver.
+	client := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		r.URL.Host = d.Listener.Addr().String()
+		return http.DefaultTransport.RoundTrip(r)
+	})}
+	wd, err := NewRemoteContext(context.Background(), nil, "http://grid.example.com", WithHTTPClient(client), WithFileDetector(LocalFileDetector))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+	input := &remoteWE{parent: wd.(*remoteWD), id: "input"}
+
+	dir, err := ioutil.TempDir("", "selenium-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var files []string
+	for _, name := range []string{"a.txt", "b.txt"} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(name), 0600); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	for _, keys := range []string{files[0], strings.Join(files, "\n"), "hello", files[0] + "\nhello"} {
+		if err := input.SendKeys(keys); err != nil {
+			t.Fatalf("input.SendKeys(%q) returned error: %v", keys, err)
+		}
+	}
+	want := []string{"/remote/1", "/remote/2\n/remote/3", "hello", files[0] + "\nhello"}
+	if strings.Join(typed, "|") != strings.Join(want, "|") {
+		t.Errorf("input.SendKeys() typed %q, want %q", typed, want)
+	}
+	if n := d.count("POST", "/se/file"); n != 3 {
+		t.Errorf("the Grid 4 command was sent %d times, want 3", n)
+	}
+}
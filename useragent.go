@@ -0,0 +1,169 @@
+package selenium
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+// EdgeCapabilitiesKey is the key under which Microsoft Edge's driver expects
+// its browser-specific options, which have the same format as Chrome's.
+const EdgeCapabilitiesKey = "ms:edgeOptions"
+
+// updateBrowserOptions applies chromeFn to the Chrome and Edge options and
+// firefoxFn to the Firefox options of c. If c has none, the options of the
+// browser named by the browserName capability are added.
+func (c Capabilities) updateBrowserOptions(chromeFn func(*chrome.Capabilities), firefoxFn func(*firefox.Capabilities)) error {
+	found := false
+	for _, key := range []string{chrome.CapabilitiesKey, EdgeCapabilitiesKey} {
+		switch v := c[key].(type) {
+		case chrome.Capabilities:
+			chromeFn(&v)
+			c[key] = v
+			if key == chrome.CapabilitiesKey {
+				c[chrome.DeprecatedCapabilitiesKey] = v
+			}
+			found = true
+		case *chrome.Capabilities:
+			chromeFn(v)
+			found = true
+		}
+	}
+	switch v := c[firefox.CapabilitiesKey].(type) {
+	case firefox.Capabilities:
+		firefoxFn(&v)
+		c[firefox.CapabilitiesKey] = v
+		found = true
+	case *firefox.Capabilities:
+		firefoxFn(v)
+		found = true
+	}
+	if found {
+		return nil
+	}
+
+	name, _ := c["browserName"].(string)
+	switch name {
+	case "chrome":
+		o := chrome.Capabilities{W3C: true}
+		chromeFn(&o)
+		c.AddChrome(o)
+	case "MicrosoftEdge", "msedge":
+		o := chrome.Capabilities{W3C: true}
+		chromeFn(&o)
+		c[EdgeCapabilitiesKey] = o
+	case "firefox":
+		var o firefox.Capabilities
+		firefoxFn(&o)
+		c.AddFirefox(o)
+	default:
+		return fmt.Errorf("no browser options to change for browser %q; add them with AddChrome or AddFirefox first", name)
+	}
+	return nil
+}
+
+// SetUserAgent makes the browser send ua as its user agent, in the
+// User-Agent header and navigator.userAgent, with the mechanism of each
+// browser: a command-line switch for Chrome and Edge, a preference for
+// Firefox. It changes the browser options already in c, so call it after
+// AddChrome or AddFirefox; without them, the options of the browser named by
+// the browserName capability are added.
+func (c Capabilities) SetUserAgent(ua string) error {
+	return c.updateBrowserOptions(
+		func(o *chrome.Capabilities) { o.SetUserAgent(ua) },
+		func(o *firefox.Capabilities) { o.SetUserAgent(ua) },
+	)
+}
+
+// SetAcceptLanguage makes the browser prefer the given languages, e.g.
+// "fr-CH", "fr", in order: they are sent in the Accept-Language header and
+// returned by navigator.languages. It changes the browser options like
+// SetUserAgent.
+func (c Capabilities) SetAcceptLanguage(langs ...string) error {
+	return c.updateBrowserOptions(
+		func(o *chrome.Capabilities) { o.SetAcceptLanguage(langs...) },
+		func(o *firefox.Capabilities) { o.SetAcceptLanguage(langs...) },
+	)
+}
+
+// chromiumVersionRE matches the product tokens of Chromium-based browsers in
+// a user agent string.
+var chromiumVersionRE = regexp.MustCompile(`\b(Chrome|CriOS|Edg|EdgA)/(\d+)((?:\.\d+)*)`)
+
+// userAgentMetadata returns the User-Agent Client Hints that match ua, in the
+// format of the DevTools Emulation.setUserAgentOverride command. User agents
+// of browsers not based on Chromium have no brands, as these browsers do not
+// send client hints.
+func userAgentMetadata(ua string) map[string]interface{} {
+	type brand struct {
+		Brand   string `json:"brand"`
+		Version string `json:"version"`
+	}
+	brands, fullVersions := []brand{}, []brand{}
+	fullVersion := ""
+	if m := chromiumVersionRE.FindAllStringSubmatch(ua, -1); m != nil {
+		product := "Google Chrome"
+		major, full := m[0][2], m[0][2]+m[0][3]
+		for _, p := range m {
+			if p[1] == "Edg" || p[1] == "EdgA" {
+				product = "Microsoft Edge"
+				major, full = p[2], p[2]+p[3]
+			}
+		}
+		fullVersion = full
+		for _, b := range []brand{{"Not_A Brand", "8"}, {"Chromium", major}, {product, major}} {
+			brands = append(brands, b)
+			v := b.Version
+			if v == major {
+				v = full
+			}
+			fullVersions = append(fullVersions, brand{b.Brand, v})
+		}
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(ua, "Android"):
+		platform = "Android"
+	case strings.Contains(ua, "Windows"):
+		platform = "Windows"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		platform = "iOS"
+	case strings.Contains(ua, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(ua, "CrOS"):
+		platform = "Chrome OS"
+	case strings.Contains(ua, "Linux"):
+		platform = "Linux"
+	}
+	return map[string]interface{}{
+		"brands":          brands,
+		"fullVersionList": fullVersions,
+		"fullVersion":     fullVersion,
+		"platform":        platform,
+		"platformVersion": "",
+		"architecture":    "",
+		"model":           "",
+		"mobile":          strings.Contains(ua, "Mobile"),
+	}
+}
+
+// SetUserAgentOverride changes the user agent and the preferred languages of
+// the current tab.
+func (wd *remoteWD) SetUserAgentOverride(ua, acceptLanguage string) error {
+	if !wd.Supports(FeatureCDP) {
+		return fmt.Errorf("overriding the user agent: %w", ErrUnsupported)
+	}
+	params := map[string]interface{}{
+		"userAgent":         ua,
+		"userAgentMetadata": userAgentMetadata(ua),
+	}
+	if acceptLanguage != "" {
+		params["acceptLanguage"] = acceptLanguage
+	}
+	_, err := wd.executeCDP("Emulation.setUserAgentOverride", params)
+	return err
+}
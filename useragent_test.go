@@ -0,0 +1,125 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/LoveOyy/selenium/chrome"
+	"github.com/LoveOyy/selenium/firefox"
+)
+
+func TestCapabilitiesSetUserAgent(t *testing.T) {
+	const ua = "test-agent/1.0"
+
+	caps := Capabilities{"browserName": "chrome"}
+	caps.AddChrome(chrome.Capabilities{Args: []string{"--headless"}})
+	if err := caps.SetUserAgent(ua); err != nil {
+		t.Fatalf("caps.SetUserAgent() returned error: %v", err)
+	}
+	if err := caps.SetAcceptLanguage("fr-CH", "fr"); err != nil {
+		t.Fatalf("caps.SetAcceptLanguage() returned error: %v", err)
+	}
+	for _, key := range []string{chrome.CapabilitiesKey, chrome.DeprecatedCapabilitiesKey} {
+		c := caps[key].(chrome.Capabilities)
+		if want := []string{"--headless", "--user-agent=" + ua, "--lang=fr-CH"}; !reflect.DeepEqual(c.Args, want) {
+			t.Errorf("caps[%q].Args = %q, want %q", key, c.Args, want)
+		}
+	}
+
+	f := &firefox.Capabilities{}
+	caps = Capabilities{"browserName": "firefox", firefox.CapabilitiesKey: f}
+	if err := caps.SetUserAgent(ua); err != nil {
+		t.Fatalf("caps.SetUserAgent() returned error: %v", err)
+	}
+	if err := caps.SetAcceptLanguage("fr-CH", "fr"); err != nil {
+		t.Fatalf("caps.SetAcceptLanguage() returned error: %v", err)
+	}
+	if want := map[string]interface{}{"general.useragent.override": ua, "intl.accept_languages": "fr-CH,fr"}; !reflect.DeepEqual(f.Prefs, want) {
+		t.Errorf("Firefox prefs = %v, want %v", f.Prefs, want)
+	}
+
+	caps = Capabilities{"browserName": "MicrosoftEdge"}
+	if err := caps.SetUserAgent(ua); err != nil {
+		t.Fatalf("caps.SetUserAgent() returned error: %v", err)
+	}
+	edge, ok := caps[EdgeCapabilitiesKey].(chrome.Capabilities)
+	if !ok || !edge.W3C || !reflect.DeepEqual(edge.Args, []string{"--user-agent=" + ua}) {
+		t.Errorf("caps[%q] = %+v, want W3C options with the user agent switch", EdgeCapabilitiesKey, caps[EdgeCapabilitiesKey])
+	}
+
+	caps = Capabilities{"browserName": "safari"}
+	if err := caps.SetUserAgent(ua); err == nil {
+		t.Errorf("caps.SetUserAgent() for Safari returned no error")
+	}
+}
+
+func TestUserAgentMetadata(t *testing.T) {
+	type brand struct{ Brand, Version string }
+	for _, test := range []struct {
+		ua       string
+		brands   []brand
+		platform string
+		mobile   bool
+	}{
+		{
+			ua:       "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.71 Safari/537.36",
+			brands:   []brand{{"Not_A Brand", "8"}, {"Chromium", "120"}, {"Google Chrome", "120"}},
+			platform: "Windows",
+		},
+		{
+			ua:       "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Mobile Safari/537.36 EdgA/118.0.2088.66",
+			brands:   []brand{{"Not_A Brand", "8"}, {"Chromium", "118"}, {"Microsoft Edge", "118"}},
+			platform: "Android",
+			mobile:   true,
+		},
+		{
+			ua:       "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+			brands:   []brand{},
+			platform: "Linux",
+		},
+	} {
+		data, _ := json.Marshal(userAgentMetadata(test.ua))
+		var got struct {
+			Brands   []brand
+			Platform string
+			Mobile   bool
+		}
+		json.Unmarshal(data, &got)
+		if !reflect.DeepEqual(got.Brands, test.brands) || got.Platform != test.platform || got.Mobile != test.mobile {
+			t.Errorf("userAgentMetadata(%q) = %+v, want brands %v, platform %q, mobile %t", test.ua, got, test.brands, test.platform, test.mobile)
+		}
+	}
+}
+
+func TestSetUserAgentOverride(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var params struct {
+		Cmd    string
+		Params map[string]interface{}
+	}
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		json.Unmarshal(body, &params)
+		return http.StatusOK, map[string]interface{}{}
+	})
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	const ua = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	if err := wd.SetUserAgentOverride(ua, "fr-CH,fr"); err != nil {
+		t.Fatalf("wd.SetUserAgentOverride() returned error: %v", err)
+	}
+	if params.Cmd != "Emulation.setUserAgentOverride" {
+		t.Errorf("wd.SetUserAgentOverride() sent %q, want Emulation.setUserAgentOverride", params.Cmd)
+	}
+	if params.Params["userAgent"] != ua || params.Params["acceptLanguage"] != "fr-CH,fr" || params.Params["userAgentMetadata"] == nil {
+		t.Errorf("wd.SetUserAgentOverride() sent parameters %v, want the user agent, the languages and the metadata", params.Params)
+	}
+
+	wd.SetSupport(FeatureCDP, false)
+	if err := wd.SetUserAgentOverride(ua, ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.SetUserAgentOverride() without CDP returned error %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,37 @@
+package selenium
+
+import (
+	"os"
+
+	"github.com/LoveOyy/selenium/chrome"
+)
+
+// RemoveTempUserDataDirOnQuit makes Quit remove the user data directory of a
+// local Chrome browser once the session is deleted, if it is a temporary
+// directory created by chrome.Capabilities.UseTempUserDataDir or by
+// ChromeDriver; see chrome.IsTempUserDataDir. Other directories are kept, as
+// are the directories of browsers left running by Chrome's Detach option.
+func RemoveTempUserDataDirOnQuit() SessionOption {
+	return func(o *sessionOptions) {
+		o.removeUserDataDir = true
+	}
+}
+
+// removeTempUserDataDir removes the temporary user data directory of the
+// browser, as reported by ChromeDriver. Errors are logged in debug mode, as
+// the session has ended.
+func (wd *remoteWD) removeTempUserDataDir() {
+	info, err := chrome.SessionInfo(wd.sessionCapabilities)
+	if err != nil {
+		debugLog("error reading the user data directory: %v", err)
+		return
+	}
+	if !chrome.IsTempUserDataDir(info.UserDataDir) {
+		return
+	}
+	if err := os.RemoveAll(info.UserDataDir); err != nil {
+		debugLog("error removing the user data directory %s: %v", info.UserDataDir, err)
+		return
+	}
+	untrackTempPath(info.UserDataDir)
+}
@@ -0,0 +1,39 @@
+package selenium
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRemoveTempUserDataDirOnQuit(t *testing.T) {
+	for _, tc := range []struct {
+		prefix string
+		remove bool
+	}{
+		{".com.google.Chrome.", true},
+		{"my-profile-", false},
+	} {
+		dir, err := ioutil.TempDir("", tc.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		d := newFakeDriver(t, map[string]interface{}{
+			"chrome": map[string]interface{}{"userDataDir": dir},
+		})
+		wd, err := NewRemoteContext(context.Background(), nil, d.URL, RemoveTempUserDataDirOnQuit())
+		if err != nil {
+			t.Fatalf("NewRemote() returned error: %v", err)
+		}
+		if err := wd.Quit(); err != nil {
+			t.Fatalf("wd.Quit() returned error: %v", err)
+		}
+		_, err = os.Stat(dir)
+		if removed := os.IsNotExist(err); removed != tc.remove {
+			t.Errorf("after wd.Quit(), %s removed = %t, want %t", dir, removed, tc.remove)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package selenium
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WaitOption configures WaitForText.
+type WaitOption func(*waitOptions)
+
+type waitOptions struct {
+	timeout, interval time.Duration
+	substring, regexp bool
+}
+
+// WaitTimeout sets how long WaitForText waits. It defaults to
+// Defaults.WaitTimeout.
+func WaitTimeout(timeout time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WaitInterval sets the interval between the reads of the text. It defaults
+// to Defaults.PollInterval.
+func WaitInterval(interval time.Duration) WaitOption {
+	return func(o *waitOptions) {
+		o.interval = interval
+	}
+}
+
+// TextContains makes WaitForText wait for a text that contains the one
+// wanted, rather than one equal to it.
+func TextContains() WaitOption {
+	return func(o *waitOptions) {
+		o.substring = true
+	}
+}
+
+// TextMatches makes WaitForText take the text wanted as a regular
+// expression, and wait for a text that it matches.
+func TextMatches() WaitOption {
+	return func(o *waitOptions) {
+		o.regexp = true
+	}
+}
+
+// ObservedText is a text read while waiting, and when it was first read.
+type ObservedText struct {
+	Text string
+	At   time.Time
+}
+
+// maxObservedTexts is the number of texts a WaitTextError reports.
+const maxObservedTexts = 3
+
+// WaitTextError is returned by WaitForText when the element does not show
+// the text wanted in time.
+type WaitTextError struct {
+	// Element describes the element waited for.
+	Element string
+	// Want is the text wanted, and Match how it is compared: "exact",
+	// "substring" or "regexp".
+	Want, Match string
+	// Observed are the last distinct texts of the element, oldest first.
+	// It is empty if the element was never found.
+	Observed []ObservedText
+}
+
+func (e *WaitTextError) Error() string {
+	msg := fmt.Sprintf("timeout waiting for %s to show %s text %q", e.Element, e.Match, e.Want)
+	if len(e.Observed) == 0 {
+		return msg + "; the element was not found"
+	}
+	var seen []string
+	for _, o := range e.Observed {
+		seen = append(seen, fmt.Sprintf("%q at %s", o.Text, o.At.Format("15:04:05.000")))
+	}
+	return msg + "; last texts: " + strings.Join(seen, ", ")
+}
+
+// normalizeText collapses the runs of white space of s into single spaces
+// and trims it.
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// waitForText waits, with the Wait method of wd, until the text returned by
+// read matches want. If refind is true, read looks the element up again, so
+// elements that are missing or stale are waited for.
+func waitForText(wd WebDriver, what string, read func() (string, error), refind bool, want string, opts []WaitOption) error {
+	d := wd.Defaults()
+	o := waitOptions{timeout: d.WaitTimeout, interval: d.PollInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	match, kind := func(s string) bool { return s == normalizeText(want) }, "exact"
+	switch {
+	case o.regexp:
+		re, err := regexp.Compile(want)
+		if err != nil {
+			return err
+		}
+		match, kind = re.MatchString, "regexp"
+	case o.substring:
+		match, kind = func(s string) bool { return strings.Contains(s, normalizeText(want)) }, "substring"
+	}
+
+	var observed []ObservedText
+	var readErr error
+	err := wd.WaitWithTimeoutAndInterval(func(WebDriver) (bool, error) {
+		text, err := read()
+		if refind && (isNoSuchElementError(err) || isStaleElementError(err)) {
+			return false, nil
+		}
+		if err != nil {
+			readErr = err
+			return false, err
+		}
+		text = normalizeText(text)
+		if n := len(observed); n == 0 || observed[n-1].Text != text {
+			if n == maxObservedTexts {
+				observed = observed[1:]
+			}
+			observed = append(observed, ObservedText{Text: text, At: time.Now()})
+		}
+		return match(text), nil
+	}, o.timeout, o.interval)
+	if err == nil || readErr != nil {
+		return err
+	}
+	return &WaitTextError{Element: what, Want: want, Match: kind, Observed: observed}
+}
+
+// WaitForText waits until the element matching the locator shows the text
+// wanted.
+func (wd *remoteWD) WaitForText(by, value, want string, opts ...WaitOption) error {
+	return Lazy(wd, Locator{By: by, Value: value}).WaitForText(want, opts...)
+}
+
+// WaitForText waits until the element shows the text wanted.
+func (elem *remoteWE) WaitForText(want string, opts ...WaitOption) error {
+	return waitForText(elem.parent, "element "+elem.id, elem.Text, false, want, opts)
+}
+
+// WaitForText waits until the element shows the text wanted, looking it up
+// again whenever it has been replaced.
+func (l *LazyElement) WaitForText(want string, opts ...WaitOption) error {
+	read := func() (string, error) {
+		text, err := l.Text()
+		if isStaleElementError(err) {
+			l.mu.Lock()
+			l.cached = nil
+			l.mu.Unlock()
+		}
+		return text, err
+	}
+	return waitForText(l.wd, l.String(), read, true, want, opts)
+}
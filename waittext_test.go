@@ -0,0 +1,93 @@
+package selenium
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForText(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	found := 0
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		found++
+		if found == 1 {
+			return http.StatusOK, map[string]string{webElementIdentifier: "old"}
+		}
+		return http.StatusOK, map[string]string{webElementIdentifier: "new"}
+	})
+	d.handle("GET", "/element/old/text", func([]byte) (int, interface{}) {
+		if d.count("GET", "/element/old/text") == 1 {
+			return http.StatusOK, "Importing…"
+		}
+		return staleElementReply()
+	})
+	d.handle("GET", "/element/new/text", func([]byte) (int, interface{}) {
+		return http.StatusOK, "  3 items\n\timported "
+	})
+	wd := d.newRemote(t)
+
+	if err := wd.WaitForText(ByCSSSelector, "#status", "3 items imported", WaitInterval(time.Millisecond)); err != nil {
+		t.Fatalf("wd.WaitForText() returned error: %v", err)
+	}
+	if found != 2 {
+		t.Errorf("wd.WaitForText() looked the element up %d times, want 2", found)
+	}
+
+	status := Lazy(wd, ByCSS("#status"))
+	if err := status.WaitForText("items", TextContains(), WaitTimeout(0)); err != nil {
+		t.Errorf("status.WaitForText() of a substring returned error: %v", err)
+	}
+	if err := status.WaitForText("items", WaitTimeout(0)); err == nil {
+		t.Error("status.WaitForText() of a substring without TextContains returned no error")
+	}
+	if err := status.WaitForText(`^\d+ items imported$`, TextMatches(), WaitTimeout(0)); err != nil {
+		t.Errorf("status.WaitForText() of a regular expression returned error: %v", err)
+	}
+	if err := status.WaitForText("[", TextMatches()); err == nil {
+		t.Error("status.WaitForText() of an invalid regular expression returned no error")
+	}
+}
+
+func TestWaitForTextTimeout(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		return http.StatusOK, map[string]string{webElementIdentifier: "e1"}
+	})
+	texts := []string{"1 item", "2 items", "1 item", "loading", "loading"}
+	d.handle("GET", "/element/e1/text", func([]byte) (int, interface{}) {
+		n := d.count("GET", "/element/e1/text") - 1
+		if n >= len(texts) {
+			n = len(texts) - 1
+		}
+		return http.StatusOK, texts[n]
+	})
+	wd := d.newRemote(t)
+
+	err := wd.WaitForText(ByCSSSelector, "#status", "3 items", WaitTimeout(50*time.Millisecond), WaitInterval(time.Millisecond))
+	var e *WaitTextError
+	if !errors.As(err, &e) {
+		t.Fatalf("wd.WaitForText() returned error %v, want a *WaitTextError", err)
+	}
+	var got []string
+	for _, o := range e.Observed {
+		got = append(got, o.Text)
+	}
+	if want := "2 items,1 item,loading"; strings.Join(got, ",") != want {
+		t.Errorf("the error reports the texts %q, want %q", got, want)
+	}
+	if !strings.Contains(err.Error(), `"loading" at `) {
+		t.Errorf("the error %q does not report the last text", err)
+	}
+
+	// Elements that are not lazy fail when they become stale.
+	elem := &remoteWE{parent: wd, id: "gone"}
+	d.handle("GET", "/element/gone/text", func([]byte) (int, interface{}) {
+		return staleElementReply()
+	})
+	if err := elem.WaitForText("3 items", WaitTimeout(time.Second)); !isStaleElementError(err) {
+		t.Errorf("elem.WaitForText() of a stale element returned error %v, want the stale element error", err)
+	}
+}
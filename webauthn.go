@@ -0,0 +1,216 @@
+package selenium
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Protocols of virtual authenticators.
+const (
+	AuthenticatorCTAP2 = "ctap2"
+	AuthenticatorU2F   = "ctap1/u2f"
+)
+
+// Transports of virtual authenticators.
+const (
+	AuthenticatorUSB      = "usb"
+	AuthenticatorNFC      = "nfc"
+	AuthenticatorBLE      = "ble"
+	AuthenticatorInternal = "internal"
+	AuthenticatorHybrid   = "hybrid"
+)
+
+// VirtualAuthenticatorOptions are the properties of a virtual authenticator
+// added with AddVirtualAuthenticator. The zero value is a CTAP2 security key
+// on USB that consents to every request, without user verification.
+type VirtualAuthenticatorOptions struct {
+	// Protocol is AuthenticatorCTAP2 if empty.
+	Protocol string
+	// Transport is AuthenticatorUSB if empty; AuthenticatorInternal is a
+	// platform authenticator, such as a fingerprint reader.
+	Transport string
+	// HasResidentKey makes the authenticator store discoverable credentials,
+	// i.e. passkeys.
+	HasResidentKey bool
+	// HasUserVerification makes the authenticator able to verify the user,
+	// e.g. with a PIN or biometrics; IsUserVerified is the outcome.
+	HasUserVerification bool
+	IsUserVerified      bool
+	// DeclinesUserConsent makes the user decline the requests of the
+	// authenticator, such as touching the security key.
+	DeclinesUserConsent bool
+	// HasLargeBlob enables the largeBlob extension.
+	HasLargeBlob bool
+}
+
+// Credential is a credential of a virtual authenticator.
+type Credential struct {
+	// ID is the credential ID.
+	ID []byte
+	// IsResidentCredential makes the credential discoverable, for a
+	// passkey; otherwise the relying party must pass its ID.
+	IsResidentCredential bool
+	// RPID is the ID of the relying party, e.g. "example.com".
+	RPID string
+	// PrivateKey is the private key of the credential, in PKCS #8 form,
+	// e.g. from x509.MarshalPKCS8PrivateKey.
+	PrivateKey []byte
+	// UserHandle is the user handle of a resident credential.
+	UserHandle []byte
+	// SignCount is the initial value of the signature counter.
+	SignCount int
+	// LargeBlob is the large, per-credential blob of the largeBlob
+	// extension.
+	LargeBlob []byte
+}
+
+// credentialJSON is the encoding of a Credential, whose binary fields are
+// base64url-encoded without padding.
+type credentialJSON struct {
+	CredentialID         string `json:"credentialId"`
+	IsResidentCredential bool   `json:"isResidentCredential"`
+	RPID                 string `json:"rpId"`
+	PrivateKey           string `json:"privateKey"`
+	UserHandle           string `json:"userHandle,omitempty"`
+	SignCount            int    `json:"signCount"`
+	LargeBlob            string `json:"largeBlob,omitempty"`
+}
+
+func (c Credential) MarshalJSON() ([]byte, error) {
+	enc := base64.RawURLEncoding.EncodeToString
+	return json.Marshal(credentialJSON{
+		CredentialID:         enc(c.ID),
+		IsResidentCredential: c.IsResidentCredential,
+		RPID:                 c.RPID,
+		PrivateKey:           enc(c.PrivateKey),
+		UserHandle:           enc(c.UserHandle),
+		SignCount:            c.SignCount,
+		LargeBlob:            enc(c.LargeBlob),
+	})
+}
+
+func (c *Credential) UnmarshalJSON(data []byte) error {
+	var v credentialJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	dec := func(s string) ([]byte, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	var err error
+	*c = Credential{IsResidentCredential: v.IsResidentCredential, RPID: v.RPID, SignCount: v.SignCount}
+	if c.ID, err = dec(v.CredentialID); err != nil {
+		return fmt.Errorf("invalid credential ID %q: %v", v.CredentialID, err)
+	}
+	if c.PrivateKey, err = dec(v.PrivateKey); err != nil {
+		return fmt.Errorf("invalid private key of credential %q: %v", v.CredentialID, err)
+	}
+	if c.UserHandle, err = dec(v.UserHandle); err != nil {
+		return fmt.Errorf("invalid user handle of credential %q: %v", v.CredentialID, err)
+	}
+	if c.LargeBlob, err = dec(v.LargeBlob); err != nil {
+		return fmt.Errorf("invalid large blob of credential %q: %v", v.CredentialID, err)
+	}
+	return nil
+}
+
+// webAuthnCommand sends a command of the Web Authentication extension of
+// WebDriver, which the remote end may not implement.
+func (wd *remoteWD) webAuthnCommand(method, url string, params interface{}) (json.RawMessage, error) {
+	var data []byte
+	if params != nil {
+		var err error
+		if data, err = json.Marshal(params); err != nil {
+			return nil, err
+		}
+	}
+	response, err := wd.execute(method, url, data)
+	if isUnknownCommand(err) {
+		return nil, fmt.Errorf("virtual authenticators: %w", ErrUnsupported)
+	}
+	return response, err
+}
+
+// AddVirtualAuthenticator adds a virtual authenticator and returns its ID.
+func (wd *remoteWD) AddVirtualAuthenticator(opts VirtualAuthenticatorOptions) (string, error) {
+	params := map[string]interface{}{
+		"protocol":            AuthenticatorCTAP2,
+		"transport":           AuthenticatorUSB,
+		"hasResidentKey":      opts.HasResidentKey,
+		"hasUserVerification": opts.HasUserVerification,
+		"isUserConsenting":    !opts.DeclinesUserConsent,
+		"isUserVerified":      opts.IsUserVerified,
+	}
+	if opts.Protocol != "" {
+		params["protocol"] = opts.Protocol
+	}
+	if opts.Transport != "" {
+		params["transport"] = opts.Transport
+	}
+	if opts.HasLargeBlob {
+		params["hasLargeBlob"] = true
+	}
+	response, err := wd.webAuthnCommand("POST", wd.requestURL("/session/%s/webauthn/authenticator", wd.id), params)
+	if err != nil {
+		return "", err
+	}
+	reply := new(struct{ Value string })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return "", err
+	}
+	if reply.Value == "" {
+		return "", errors.New("the remote end returned no ID for the virtual authenticator")
+	}
+	return reply.Value, nil
+}
+
+// RemoveVirtualAuthenticator removes a virtual authenticator.
+func (wd *remoteWD) RemoveVirtualAuthenticator(authenticatorID string) error {
+	_, err := wd.webAuthnCommand("DELETE", wd.requestURL("/session/%s/webauthn/authenticator/%s", wd.id, authenticatorID), nil)
+	return err
+}
+
+// AddCredential adds a credential to a virtual authenticator.
+func (wd *remoteWD) AddCredential(authenticatorID string, c Credential) error {
+	_, err := wd.webAuthnCommand("POST", wd.requestURL("/session/%s/webauthn/authenticator/%s/credential", wd.id, authenticatorID), c)
+	return err
+}
+
+// Credentials returns the credentials of a virtual authenticator.
+func (wd *remoteWD) Credentials(authenticatorID string) ([]Credential, error) {
+	response, err := wd.webAuthnCommand("GET", wd.requestURL("/session/%s/webauthn/authenticator/%s/credentials", wd.id, authenticatorID), nil)
+	if err != nil {
+		return nil, err
+	}
+	reply := new(struct{ Value []Credential })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return nil, err
+	}
+	return reply.Value, nil
+}
+
+// RemoveCredential removes a credential of a virtual authenticator.
+func (wd *remoteWD) RemoveCredential(authenticatorID string, credentialID []byte) error {
+	id := base64.RawURLEncoding.EncodeToString(credentialID)
+	_, err := wd.webAuthnCommand("DELETE", wd.requestURL("/session/%s/webauthn/authenticator/%s/credentials/%s", wd.id, authenticatorID, id), nil)
+	return err
+}
+
+// RemoveAllCredentials removes the credentials of a virtual authenticator.
+func (wd *remoteWD) RemoveAllCredentials(authenticatorID string) error {
+	_, err := wd.webAuthnCommand("DELETE", wd.requestURL("/session/%s/webauthn/authenticator/%s/credentials", wd.id, authenticatorID), nil)
+	return err
+}
+
+// SetUserVerified sets the outcome of user verification of a virtual
+// authenticator.
+func (wd *remoteWD) SetUserVerified(authenticatorID string, verified bool) error {
+	params := map[string]bool{"isUserVerified": verified}
+	_, err := wd.webAuthnCommand("POST", wd.requestURL("/session/%s/webauthn/authenticator/%s/uv", wd.id, authenticatorID), params)
+	return err
+}
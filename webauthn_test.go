@@ -0,0 +1,111 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestVirtualAuthenticator(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var added map[string]interface{}
+	d.handle("POST", "/webauthn/authenticator", func(body []byte) (int, interface{}) {
+		added = nil
+		json.Unmarshal(body, &added)
+		return http.StatusOK, "auth1"
+	})
+	var credential string
+	d.handle("POST", "/webauthn/authenticator/auth1/credential", func(body []byte) (int, interface{}) {
+		credential = string(body)
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/webauthn/authenticator/auth1/credentials", func([]byte) (int, interface{}) {
+		return http.StatusOK, []json.RawMessage{json.RawMessage(credential)}
+	})
+	var verified string
+	d.handle("POST", "/webauthn/authenticator/auth1/uv", func(body []byte) (int, interface{}) {
+		verified = string(body)
+		return http.StatusOK, nil
+	})
+	for _, path := range []string{
+		"/webauthn/authenticator/auth1",
+		"/webauthn/authenticator/auth1/credentials",
+		"/webauthn/authenticator/auth1/credentials/AQID",
+	} {
+		d.handle("DELETE", path, func([]byte) (int, interface{}) { return http.StatusOK, nil })
+	}
+	wd := d.newRemote(t)
+
+	id, err := wd.AddVirtualAuthenticator(VirtualAuthenticatorOptions{
+		Transport:           AuthenticatorInternal,
+		HasResidentKey:      true,
+		HasUserVerification: true,
+	})
+	if err != nil {
+		t.Fatalf("wd.AddVirtualAuthenticator() returned error: %v", err)
+	}
+	if id != "auth1" {
+		t.Errorf("wd.AddVirtualAuthenticator() = %q, want %q", id, "auth1")
+	}
+	want := map[string]interface{}{
+		"protocol":            "ctap2",
+		"transport":           "internal",
+		"hasResidentKey":      true,
+		"hasUserVerification": true,
+		"isUserConsenting":    true,
+		"isUserVerified":      false,
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("wd.AddVirtualAuthenticator() sent %v, want %v", added, want)
+	}
+
+	c := Credential{
+		ID:                   []byte{1, 2, 3},
+		IsResidentCredential: true,
+		RPID:                 "example.com",
+		PrivateKey:           []byte("key"),
+		UserHandle:           []byte("user"),
+		SignCount:            7,
+	}
+	if err := wd.AddCredential(id, c); err != nil {
+		t.Fatalf("wd.AddCredential() returned error: %v", err)
+	}
+	if want := `{"credentialId":"AQID","isResidentCredential":true,"rpId":"example.com","privateKey":"a2V5","userHandle":"dXNlcg","signCount":7}`; credential != want {
+		t.Errorf("wd.AddCredential() sent %s, want %s", credential, want)
+	}
+	got, err := wd.Credentials(id)
+	if err != nil {
+		t.Fatalf("wd.Credentials() returned error: %v", err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], c) {
+		t.Errorf("wd.Credentials() = %+v, want %+v", got, c)
+	}
+
+	if err := wd.SetUserVerified(id, true); err != nil {
+		t.Errorf("wd.SetUserVerified() returned error: %v", err)
+	}
+	if verified != `{"isUserVerified":true}` {
+		t.Errorf("wd.SetUserVerified() sent %s", verified)
+	}
+	if err := wd.RemoveCredential(id, c.ID); err != nil {
+		t.Errorf("wd.RemoveCredential() returned error: %v", err)
+	}
+	if err := wd.RemoveAllCredentials(id); err != nil {
+		t.Errorf("wd.RemoveAllCredentials() returned error: %v", err)
+	}
+	if err := wd.RemoveVirtualAuthenticator(id); err != nil {
+		t.Errorf("wd.RemoveVirtualAuthenticator() returned error: %v", err)
+	}
+	if n := d.count("DELETE", "/webauthn/authenticator/auth1/credentials/AQID"); n != 1 {
+		t.Errorf("the credential was removed %d times, want once", n)
+	}
+}
+
+func TestVirtualAuthenticatorUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.AddVirtualAuthenticator(VirtualAuthenticatorOptions{}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.AddVirtualAuthenticator() returned error %v, want ErrUnsupported", err)
+	}
+}
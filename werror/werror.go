@@ -0,0 +1,151 @@
+// Package werror provides the errors returned by WebDriver remote ends.
+//
+// Each error code of the W3C specification is a Code, which is an error
+// matched with errors.Is by the *Error returned for a command, whichever way
+// it is wrapped:
+//
+//	if errors.Is(err, werror.NoSuchElement) {
+//		...
+//	}
+//	var e *werror.Error
+//	if errors.As(err, &e) {
+//		log.Printf("%s\n%s", e.Message, e.Stacktrace)
+//	}
+//
+// The numeric codes of the legacy JSON wire protocol, which ChromeDriver and
+// old Selenium servers still return, are mapped to the matching Code.
+package werror
+
+import (
+	"fmt"
+	"time"
+)
+
+// Code is an error code of the W3C specification, such as "no such element".
+// See https://www.w3.org/TR/webdriver/#errors.
+type Code string
+
+// Error implements the error interface.
+func (c Code) Error() string {
+	return string(c)
+}
+
+// The error codes of the W3C specification.
+const (
+	DetachedShadowRoot      Code = "detached shadow root"
+	ElementClickIntercepted Code = "element click intercepted"
+	ElementNotInteractable  Code = "element not interactable"
+	InsecureCertificate     Code = "insecure certificate"
+	InvalidArgument         Code = "invalid argument"
+	InvalidCookieDomain     Code = "invalid cookie domain"
+	InvalidElementState     Code = "invalid element state"
+	InvalidSelector         Code = "invalid selector"
+	InvalidSessionID        Code = "invalid session id"
+	JavascriptError         Code = "javascript error"
+	MoveTargetOutOfBounds   Code = "move target out of bounds"
+	NoSuchAlert             Code = "no such alert"
+	NoSuchCookie            Code = "no such cookie"
+	NoSuchElement           Code = "no such element"
+	NoSuchFrame             Code = "no such frame"
+	NoSuchShadowRoot        Code = "no such shadow root"
+	NoSuchWindow            Code = "no such window"
+	ScriptTimeout           Code = "script timeout"
+	SessionNotCreated       Code = "session not created"
+	StaleElementReference   Code = "stale element reference"
+	Timeout                 Code = "timeout"
+	UnableToCaptureScreen   Code = "unable to capture screen"
+	UnableToSetCookie       Code = "unable to set cookie"
+	UnexpectedAlertOpen     Code = "unexpected alert open"
+	UnknownCommand          Code = "unknown command"
+	UnknownError            Code = "unknown error"
+	UnknownMethod           Code = "unknown method"
+	UnsupportedOperation    Code = "unsupported operation"
+)
+
+// legacyCodes maps the status codes of the legacy JSON wire protocol to the
+// W3C error codes.
+var legacyCodes = map[int]Code{
+	6:  InvalidSessionID,
+	7:  NoSuchElement,
+	8:  NoSuchFrame,
+	9:  UnknownCommand,
+	10: StaleElementReference,
+	11: ElementNotInteractable,
+	12: InvalidElementState,
+	13: UnknownError,
+	17: JavascriptError,
+	19: InvalidSelector,
+	21: Timeout,
+	23: NoSuchWindow,
+	24: InvalidCookieDomain,
+	25: UnableToSetCookie,
+	26: UnexpectedAlertOpen,
+	27: NoSuchAlert,
+	28: ScriptTimeout,
+	32: InvalidSelector,
+	33: SessionNotCreated,
+	34: MoveTargetOutOfBounds,
+}
+
+// Error contains information about a failure of a command. See the table of
+// these strings at https://www.w3.org/TR/webdriver/#handling-errors .
+type Error struct {
+	// Err contains a general error string provided by the server.
+	Err string `json:"error"`
+	// Message is a detailed, human-readable message specific to the failure.
+	Message string `json:"message"`
+	// Stacktrace may contain the server-side stacktrace where the error occurred.
+	Stacktrace string `json:"stacktrace"`
+	// Data holds the additional, implementation-specific details of the
+	// error sent by the remote end, if any.
+	Data map[string]interface{} `json:"data,omitempty"`
+	// Screenshot is the screenshot, in PNG format, taken by the remote end
+	// when the command failed, if any; old Selenium servers attach one to
+	// their errors.
+	Screenshot []byte `json:"screen,omitempty"`
+	// HTTPCode is the HTTP status code returned by the server.
+	HTTPCode int
+	// LegacyCode is the "Response Status Code" defined in the legacy Selenium
+	// WebDriver JSON wire protocol. This code is only produced by older
+	// Selenium WebDriver versions, Chromedriver, and InternetExplorerDriver.
+	LegacyCode int
+	// TestName is the name of the test that sent the command, as set with
+	// WebDriver.SetTestName.
+	TestName string `json:"-"`
+	// Label is the label of the session that sent the command, as set with
+	// WithLabel.
+	Label string `json:"-"`
+	// RetryAfter is the delay requested by the Retry-After header of the
+	// reply, if any.
+	RetryAfter time.Duration `json:"-"`
+}
+
+// TODO(minusnine): Make Stacktrace more descriptive. Selenium emits a list of
+// objects that enumerate various fields. This is not standard, though.
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	name := e.TestName
+	if name != "" && e.Label != "" {
+		name += "/"
+	}
+	if name += e.Label; name != "" {
+		return fmt.Sprintf("[%s] %s: %s", name, e.Err, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Err, e.Message)
+}
+
+// Code returns the W3C error code of e, which is mapped from LegacyCode for
+// the remote ends that implement the legacy protocol.
+func (e *Error) Code() Code {
+	if c, ok := legacyCodes[e.LegacyCode]; ok {
+		return c
+	}
+	return Code(e.Err)
+}
+
+// Is makes errors.Is(err, c) true for the Code c of e.
+func (e *Error) Is(target error) bool {
+	c, ok := target.(Code)
+	return ok && c == e.Code()
+}
@@ -0,0 +1,80 @@
+package werror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestErrorIs(t *testing.T) {
+	for _, test := range []struct {
+		desc string
+		err  *Error
+		want Code
+	}{
+		{"W3C", &Error{Err: "no such element"}, NoSuchElement},
+		{"legacy", &Error{Err: "element not visible", LegacyCode: 11}, ElementNotInteractable},
+		{"legacy session", &Error{Err: "invalid session ID", LegacyCode: 6}, InvalidSessionID},
+		{"unknown legacy", &Error{Err: "unknown error - 99", LegacyCode: 99}, Code("unknown error - 99")},
+	} {
+		if got := test.err.Code(); got != test.want {
+			t.Errorf("%s: Code() = %q, want %q", test.desc, got, test.want)
+		}
+		wrapped := fmt.Errorf("clicking: %w", test.err)
+		if !errors.Is(wrapped, test.want) {
+			t.Errorf("%s: errors.Is(%v, %q) = false, want true", test.desc, wrapped, test.want)
+		}
+		if errors.Is(wrapped, Timeout) {
+			t.Errorf("%s: errors.Is(%v, Timeout) = true, want false", test.desc, wrapped)
+		}
+		var e *Error
+		if !errors.As(wrapped, &e) || e != test.err {
+			t.Errorf("%s: errors.As(%v) did not return the *Error", test.desc, wrapped)
+		}
+	}
+}
+
+func TestErrorJSON(t *testing.T) {
+	const reply = `{
+		"error": "unexpected alert open",
+		"message": "alert open",
+		"stacktrace": "at click",
+		"data": {"text": "Are you sure?"},
+		"screen": "iVBORw0K"
+	}`
+	var e Error
+	if err := json.Unmarshal([]byte(reply), &e); err != nil {
+		t.Fatalf("json.Unmarshal() returned error: %v", err)
+	}
+	if !errors.Is(&e, UnexpectedAlertOpen) {
+		t.Errorf("the error has code %q, want %q", e.Code(), UnexpectedAlertOpen)
+	}
+	if want := map[string]interface{}{"text": "Are you sure?"}; !reflect.DeepEqual(e.Data, want) {
+		t.Errorf("e.Data = %v, want %v", e.Data, want)
+	}
+	if want := "\x89PNG\r\n"; string(e.Screenshot) != want {
+		t.Errorf("e.Screenshot = %q, want %q", e.Screenshot, want)
+	}
+	if e.Stacktrace != "at click" {
+		t.Errorf("e.Stacktrace = %q, want %q", e.Stacktrace, "at click")
+	}
+}
+
+func TestErrorString(t *testing.T) {
+	e := &Error{Err: "timeout", Message: "page load"}
+	for _, test := range []struct {
+		testName, label, want string
+	}{
+		{"", "", "timeout: page load"},
+		{"TestLogin", "", "[TestLogin] timeout: page load"},
+		{"", "admin", "[admin] timeout: page load"},
+		{"TestLogin", "admin", "[TestLogin/admin] timeout: page load"},
+	} {
+		e.TestName, e.Label = test.testName, test.label
+		if got := e.Error(); got != test.want {
+			t.Errorf("Error() = %q, want %q", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package selenium
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// ReadyPhase is a phase of ClickWhenReady and TypeWhenReady.
+type ReadyPhase string
+
+// Phases of ClickWhenReady and TypeWhenReady, in order.
+const (
+	// PhaseFind waits for an element to match the locator.
+	PhaseFind ReadyPhase = "find"
+	// PhaseInteractable waits for the element to pass the checks of
+	// WebElement.EnsureInteractable, scrolling it into view.
+	PhaseInteractable ReadyPhase = "interactable"
+	// PhaseAct clicks or types into the element, retrying while the remote
+	// end reports that another element received the click or that the
+	// element cannot be interacted with.
+	PhaseAct ReadyPhase = "act"
+)
+
+// NotReadyError is returned by ClickWhenReady and TypeWhenReady when the
+// timeout elapsed before the action succeeded, with the phase that was in
+// progress.
+type NotReadyError struct {
+	// Action is "click" or "type".
+	Action    string
+	By, Value string
+	// Phase is the phase that exhausted the timeout.
+	Phase   ReadyPhase
+	Timeout time.Duration
+	// Err is the last error of the phase, e.g. a *NotInteractableError.
+	Err error
+}
+
+func (e *NotReadyError) Error() string {
+	what := fmt.Sprintf("%s %q", e.By, e.Value)
+	switch e.Phase {
+	case PhaseFind:
+		return fmt.Sprintf("%s: element %s never appeared within %v", e.Action, what, e.Timeout)
+	case PhaseInteractable:
+		return fmt.Sprintf("%s: element %s appeared but never became interactable within %v: %v", e.Action, what, e.Timeout, e.Err)
+	}
+	if e.Action == "click" {
+		return fmt.Sprintf("click: element %s was ready but the click kept being intercepted for %v: %v", what, e.Timeout, e.Err)
+	}
+	return fmt.Sprintf("%s: element %s was ready but kept rejecting the input for %v: %v", e.Action, what, e.Timeout, e.Err)
+}
+
+func (e *NotReadyError) Unwrap() error {
+	return e.Err
+}
+
+// isElementNotInteractableError reports whether the remote end refused to
+// interact with an element, e.g. because it was covered or hidden.
+func isElementNotInteractableError(err error) bool {
+	return errors.Is(err, werror.ElementNotInteractable)
+}
+
+// whenReady finds the element located by by and value, waits until it is
+// interactable and calls act with it, all within one timeout, a zero timeout
+// meaning Defaults.WaitTimeout. The lookup is repeated if the element goes
+// stale, and act is repeated while the remote end refuses the interaction.
+func (wd *remoteWD) whenReady(action, by, value string, timeout time.Duration, act func(WebElement) error) error {
+	if timeout == 0 {
+		timeout = wd.defaults.WaitTimeout
+	}
+	polls := 0
+	end := wd.startWait()
+	defer func() { end(polls) }()
+
+	deadline := time.Now().Add(timeout)
+	phase := PhaseFind
+	var elem WebElement
+	var last error
+	for {
+		polls++
+		var err error
+		switch phase {
+		case PhaseFind:
+			elem, err = wd.FindElement(by, value)
+			switch {
+			case err == nil:
+				phase = PhaseInteractable
+				continue
+			case !isNoSuchElementError(err):
+				return err
+			}
+		case PhaseInteractable:
+			err = elem.EnsureInteractable()
+			switch {
+			case err == nil:
+				phase = PhaseAct
+				continue
+			case isStaleElementError(err):
+				phase = PhaseFind
+				continue
+			case !isNotInteractable(err):
+				return err
+			}
+		case PhaseAct:
+			err = act(elem)
+			switch {
+			case err == nil:
+				return nil
+			case isStaleElementError(err):
+				phase = PhaseFind
+				continue
+			case !isClickInterceptedError(err) && !isElementNotInteractableError(err):
+				return err
+			}
+		}
+		last = err
+		if time.Now().After(deadline) {
+			break
+		}
+		if err := wd.sleep(wd.defaults.PollInterval); err != nil {
+			return err
+		}
+		if phase == PhaseAct {
+			// Something covers the element: wait for it to be interactable
+			// again before the next attempt.
+			phase = PhaseInteractable
+		}
+	}
+
+	e := &NotReadyError{Action: action, By: by, Value: value, Phase: phase, Timeout: timeout, Err: last}
+	for _, hook := range wd.failureHooks {
+		hook(wd, e)
+	}
+	return e
+}
+
+// isNotInteractable reports whether err is a failed check of
+// EnsureInteractable.
+func isNotInteractable(err error) bool {
+	_, ok := err.(*NotInteractableError)
+	return ok
+}
+
+// ClickWhenReady waits for the element and clicks it.
+func (wd *remoteWD) ClickWhenReady(by, value string, timeout time.Duration) error {
+	return wd.whenReady("click", by, value, timeout, func(elem WebElement) error {
+		return elem.Click(WithClickRetries(0))
+	})
+}
+
+// TypeWhenReady waits for the element and enters text into it.
+func (wd *remoteWD) TypeWhenReady(by, value, text string, timeout time.Duration) error {
+	return wd.whenReady("type", by, value, timeout, func(elem WebElement) error {
+		return elem.SetText(text)
+	})
+}
@@ -0,0 +1,132 @@
+package selenium
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeReadyElement serves an element "e" that appears after the given number
+// of lookups, is covered for the given number of checks, then refuses the
+// given number of interactions with the reply of refuse.
+type fakeReadyElement struct {
+	finds, covered, refused int
+	refuse                  func() (int, interface{})
+}
+
+func (f *fakeReadyElement) handle(d *fakeDriver) {
+	d.handle("POST", "/element", func([]byte) (int, interface{}) {
+		if f.finds > 0 {
+			f.finds--
+			return noSuchElementReply()
+		}
+		return http.StatusOK, map[string]string{webElementIdentifier: "e"}
+	})
+	d.handle("POST", "/execute/sync", func(body []byte) (int, interface{}) {
+		var params struct{ Script string }
+		json.Unmarshal(body, &params)
+		if params.Script != interactableScript {
+			return http.StatusOK, nil
+		}
+		if f.covered > 0 {
+			f.covered--
+			return http.StatusOK, map[string]interface{}{"check": CheckNotCovered, "detail": "covered by div#overlay"}
+		}
+		return http.StatusOK, map[string]interface{}{"x": 10, "y": 10}
+	})
+	interact := func([]byte) (int, interface{}) {
+		if f.refused > 0 {
+			f.refused--
+			return f.refuse()
+		}
+		return http.StatusOK, nil
+	}
+	d.handle("POST", "/element/e/click", interact)
+	d.handle("POST", "/element/e/clear", interact)
+	d.handle("POST", "/actions", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+}
+
+func clickInterceptedReply() (int, interface{}) {
+	return http.StatusBadRequest, map[string]string{
+		"error":   "element click intercepted",
+		"message": "Other element would receive the click: <div id=\"overlay\">",
+	}
+}
+
+func TestClickWhenReady(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	f := &fakeReadyElement{finds: 2, covered: 1, refused: 1, refuse: clickInterceptedReply}
+	f.handle(d)
+	wd := d.newRemote(t)
+	wd.defaults.PollInterval = time.Millisecond
+
+	if err := wd.ClickWhenReady(ByID, "e", time.Minute); err != nil {
+		t.Fatalf("wd.ClickWhenReady() returned error: %v", err)
+	}
+	if n := d.count("POST", "/element"); n != 3 {
+		t.Errorf("wd.ClickWhenReady() looked up the element %d times, want 3", n)
+	}
+	if n := d.count("POST", "/element/e/click"); n != 2 {
+		t.Errorf("wd.ClickWhenReady() clicked %d times, want 2", n)
+	}
+}
+
+func TestTypeWhenReady(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	f := &fakeReadyElement{refused: 1, refuse: func() (int, interface{}) {
+		return http.StatusBadRequest, map[string]string{"error": "element not interactable", "message": "element not interactable"}
+	}}
+	f.handle(d)
+	wd := d.newRemote(t)
+	wd.defaults.PollInterval = time.Millisecond
+
+	if err := wd.TypeWhenReady(ByID, "e", "hi", time.Minute); err != nil {
+		t.Fatalf("wd.TypeWhenReady() returned error: %v", err)
+	}
+	if n := d.count("POST", "/actions"); n != 1 {
+		t.Errorf("wd.TypeWhenReady() typed %d times, want 1", n)
+	}
+}
+
+func TestClickWhenReadyTimeout(t *testing.T) {
+	for _, test := range []struct {
+		desc      string
+		f         *fakeReadyElement
+		wantPhase ReadyPhase
+		wantMsg   string
+	}{
+		{"never appears", &fakeReadyElement{finds: 1 << 30}, PhaseFind, "never appeared"},
+		{"always covered", &fakeReadyElement{covered: 1 << 30}, PhaseInteractable, "covered by div#overlay"},
+		{"always intercepted", &fakeReadyElement{refused: 1 << 30, refuse: clickInterceptedReply}, PhaseAct, "kept being intercepted"},
+	} {
+		t.Run(test.desc, func(t *testing.T) {
+			d := newFakeDriver(t, nil)
+			test.f.handle(d)
+			wd := d.newRemote(t)
+			wd.defaults.PollInterval = time.Millisecond
+			var hooked error
+			wd.failureHooks = []FailureHook{func(_ WebDriver, err error) { hooked = err }}
+
+			start := time.Now()
+			err := wd.ClickWhenReady(ByID, "e", 50*time.Millisecond)
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("wd.ClickWhenReady() took %v with a timeout of 50ms", elapsed)
+			}
+			var e *NotReadyError
+			if !errors.As(err, &e) || e.Phase != test.wantPhase {
+				t.Fatalf("wd.ClickWhenReady() returned error %v, want a *NotReadyError in phase %q", err, test.wantPhase)
+			}
+			if !strings.Contains(err.Error(), test.wantMsg) {
+				t.Errorf("wd.ClickWhenReady() returned error %q, want it to contain %q", err, test.wantMsg)
+			}
+			if hooked != err {
+				t.Errorf("the failure hook got %v, want %v", hooked, err)
+			}
+		})
+	}
+}
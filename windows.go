@@ -0,0 +1,395 @@
+package selenium
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/LoveOyy/selenium/bidi"
+	"github.com/LoveOyy/selenium/werror"
+)
+
+// WindowEventType tells whether a window was opened or closed.
+type WindowEventType int
+
+// Types of window events.
+const (
+	WindowOpened WindowEventType = iota
+	WindowClosed
+)
+
+func (t WindowEventType) String() string {
+	switch t {
+	case WindowOpened:
+		return "opened"
+	case WindowClosed:
+		return "closed"
+	}
+	return fmt.Sprintf("WindowEventType(%d)", int(t))
+}
+
+// WindowEvent reports a window or tab opened or closed, by the test or by the
+// application.
+type WindowEvent struct {
+	Type WindowEventType
+	// Handle is the handle of the window, as returned by WindowHandles.
+	Handle string
+	// Time is when the change was observed.
+	Time time.Time
+}
+
+// WatchOption configures WatchWindows and WatchRouteChanges.
+type WatchOption func(*watchOptions)
+
+type watchOptions struct {
+	interval time.Duration
+}
+
+// WatchInterval sets the interval between the polls of WatchWindows and
+// WatchRouteChanges. It defaults to Defaults.PollInterval and cannot be
+// shorter than 10ms.
+func WatchInterval(interval time.Duration) WatchOption {
+	return func(o *watchOptions) {
+		o.interval = interval
+	}
+}
+
+// WatchWindows reports the windows opened and closed from now on, from the
+// BiDi events of the session if it can, or else by polling the handles.
+func (wd *remoteWD) WatchWindows(ctx context.Context, opts ...WatchOption) (<-chan WindowEvent, error) {
+	o := watchOptions{interval: wd.defaults.PollInterval}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if wd.Supports(FeatureBiDi) {
+		events, err := wd.watchWindowsBiDi(ctx)
+		if err == nil {
+			return events, nil
+		}
+		debugLog("error watching the windows with BiDi, polling instead: %v", err)
+	}
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return nil, err
+	}
+	urlTemplate := "/session/%s/window/handles"
+	if !wd.w3cCompatible {
+		urlTemplate = "/session/%s/window_handles"
+	}
+	url := wd.pollURL(urlTemplate)
+	events := make(chan WindowEvent, 16)
+	go wd.pollWindows(ctx, url, o.interval, handles, events)
+	return events, nil
+}
+
+// watchWindowsBiDi sends the creations and destructions of the top-level
+// browsing contexts, whose identifiers are the window handles, to the
+// returned channel, until ctx is done or the BiDi connection is closed.
+func (wd *remoteWD) watchWindowsBiDi(ctx context.Context) (<-chan WindowEvent, error) {
+	conn, err := wd.BiDi()
+	if err != nil {
+		return nil, err
+	}
+	sub, err := conn.Subscribe(ctx, []string{bidi.EventContextCreated, bidi.EventContextDestroyed})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	events := make(chan WindowEvent, 16)
+	go func() {
+		defer close(events)
+		// Closing the connection ends the subscription too.
+		defer conn.Close()
+		for {
+			var e bidi.Event
+			var ok bool
+			select {
+			case e, ok = <-sub.Events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+			var info struct {
+				Context string `json:"context"`
+				Parent  string `json:"parent"`
+			}
+			if err := e.Decode(&info); err != nil {
+				debugLog("error decoding %s: %v", e.Method, err)
+				continue
+			}
+			// Frames have a parent.
+			if info.Parent != "" {
+				continue
+			}
+			typ := WindowOpened
+			if e.Method == bidi.EventContextDestroyed {
+				typ = WindowClosed
+			}
+			// The subscription queues the events, so that none is lost
+			// while the receiver is busy.
+			select {
+			case events <- WindowEvent{Type: typ, Handle: info.Context, Time: time.Now()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// pollWindows sends the differences between successive lists of window
+// handles, fetched from url, to events, until ctx is done or the session
+// ends. The handles are still polled while events is full, and the changes
+// queued, so that a window opened and closed while the receiver is busy is
+// reported.
+func (wd *remoteWD) pollWindows(ctx context.Context, url string, interval time.Duration, handles []string, events chan<- WindowEvent) {
+	defer close(events)
+	known := make(map[string]bool)
+	for _, h := range handles {
+		known[h] = true
+	}
+	var pending []WindowEvent
+	defer func() {
+		for _, e := range pending {
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	poll(ctx, nil, interval, "polling the window handles", func() error {
+		response, err := wd.executeTagged(tagBackground, "GET", url, nil)
+		if err != nil {
+			return err
+		}
+		reply := new(struct{ Value []string })
+		if err := json.Unmarshal(response, reply); err != nil {
+			return fmt.Errorf("decoding the window handles: %v", err)
+		}
+		current := reply.Value
+		now := time.Now()
+		var changes []WindowEvent
+		seen := make(map[string]bool)
+		for _, h := range current {
+			seen[h] = true
+			if !known[h] {
+				changes = append(changes, WindowEvent{Type: WindowOpened, Handle: h, Time: now})
+			}
+		}
+		for _, h := range handles {
+			if !seen[h] {
+				changes = append(changes, WindowEvent{Type: WindowClosed, Handle: h, Time: now})
+			}
+		}
+		handles, known = current, seen
+		pending = append(pending, changes...)
+		for len(pending) > 0 {
+			select {
+			case events <- pending[0]:
+				pending = pending[1:]
+			default:
+				return nil
+			}
+		}
+		return nil
+	})
+}
+
+// SetTabActive makes the tab or window with the given handle behave as if it
+// were focused and visible.
+func (wd *remoteWD) SetTabActive(handle string) error {
+	if !wd.Supports(FeatureCDP) {
+		return fmt.Errorf("activating a background tab: %w", ErrUnsupported)
+	}
+	current, err := wd.CurrentWindowHandle()
+	if err != nil {
+		return err
+	}
+	if handle != current {
+		// ChromeDriver sends DevTools commands to the current tab.
+		if err := wd.SwitchWindow(handle); err != nil {
+			return err
+		}
+		defer func() {
+			if err := wd.SwitchWindow(current); err != nil {
+				debugLog("error switching back to window %s: %v", current, err)
+			}
+		}()
+	}
+	if _, err := wd.executeCDP("Emulation.setFocusEmulationEnabled", map[string]interface{}{"enabled": true}); err != nil {
+		return err
+	}
+	_, err = wd.executeCDP("Page.setWebLifecycleState", map[string]interface{}{"state": "active"})
+	return err
+}
+
+// isNoSuchWindowError reports whether err is the error returned for a closed
+// window.
+func isNoSuchWindowError(err error) bool {
+	return errors.Is(err, werror.NoSuchWindow)
+}
+
+// inWindow runs fn with the window with the given handle as the current
+// window, and switches back to the previous current window, even if fn fails.
+func (wd *remoteWD) inWindow(handle string, fn func() error) (err error) {
+	current, err := wd.CurrentWindowHandle()
+	if err != nil && !isNoSuchWindowError(err) {
+		return err
+	}
+	if handle != current {
+		if err := wd.SwitchWindow(handle); err != nil {
+			return err
+		}
+		// If the current window was closed, there is nothing to switch back
+		// to.
+		if current != "" {
+			defer func() {
+				if switchErr := wd.SwitchWindow(current); switchErr != nil && err == nil {
+					err = fmt.Errorf("switching back to window %s: %w", current, switchErr)
+				}
+			}()
+		}
+	}
+	return fn()
+}
+
+// windowRect returns the position and size of the current window, or of the
+// named window with legacy drivers.
+func (wd *remoteWD) windowRect(handle string) (Rect, error) {
+	if !wd.w3cCompatible {
+		var r Rect
+		var size Size
+		var pos Point
+		for _, c := range []struct {
+			command string
+			v       interface{}
+		}{{"size", &size}, {"position", &pos}} {
+			response, err := wd.execute("GET", wd.requestURL("/session/%s/window/%s/%s", wd.id, handle, c.command), nil)
+			if err != nil {
+				return r, err
+			}
+			reply := &struct{ Value interface{} }{c.v}
+			if err := json.Unmarshal(response, reply); err != nil {
+				return r, err
+			}
+		}
+		return Rect{X: pos.X, Y: pos.Y, Width: size.Width, Height: size.Height}, nil
+	}
+	response, err := wd.execute("GET", wd.requestURL("/session/%s/window/rect", wd.id), nil)
+	if err != nil {
+		return Rect{}, err
+	}
+	reply := new(struct{ Value rect })
+	if err := json.Unmarshal(response, reply); err != nil {
+		return Rect{}, err
+	}
+	r := reply.Value
+	return Rect{X: round(r.X), Y: round(r.Y), Width: round(r.Width), Height: round(r.Height)}, nil
+}
+
+// WindowRects returns the position and size of every window.
+func (wd *remoteWD) WindowRects() (map[string]Rect, error) {
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return nil, err
+	}
+	rects := make(map[string]Rect, len(handles))
+	for _, h := range handles {
+		var r Rect
+		get := func() error {
+			var err error
+			r, err = wd.windowRect(h)
+			return err
+		}
+		if wd.w3cCompatible {
+			err = wd.inWindow(h, get)
+		} else {
+			err = get()
+		}
+		if isNoSuchWindowError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rects[h] = r
+	}
+	return rects, nil
+}
+
+// ScreenshotWindow takes a screenshot of the window with the given handle.
+func (wd *remoteWD) ScreenshotWindow(handle string) ([]byte, error) {
+	var data []byte
+	err := wd.inWindow(handle, func() error {
+		var err error
+		data, err = wd.Screenshot()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ScreenshotAllWindows takes a screenshot of every window.
+func (wd *remoteWD) ScreenshotAllWindows() (map[string][]byte, error) {
+	handles, err := wd.WindowHandles()
+	if err != nil {
+		return nil, err
+	}
+	shots := make(map[string][]byte, len(handles))
+	for _, h := range handles {
+		data, err := wd.ScreenshotWindow(h)
+		if isNoSuchWindowError(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		shots[h] = data
+	}
+	return shots, nil
+}
+
+// Types of windows for NewWindow.
+const (
+	WindowTypeTab    = "tab"
+	WindowTypeWindow = "window"
+)
+
+// NewWindow opens a window or tab and returns its handle.
+func (wd *remoteWD) NewWindow(typ string) (string, error) {
+	params := map[string]interface{}{}
+	if typ != "" {
+		params["type"] = typ
+	}
+	data, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	response, err := wd.execute("POST", wd.requestURL("/session/%s/window/new", wd.id), data)
+	if isUnknownCommand(err) {
+		return "", fmt.Errorf("opening a window: %w", ErrUnsupported)
+	}
+	if err != nil {
+		return "", err
+	}
+	reply := new(struct {
+		Value struct {
+			Handle string
+			Type   string
+		}
+	})
+	if err := json.Unmarshal(response, reply); err != nil {
+		return "", err
+	}
+	if reply.Value.Handle == "" {
+		return "", errors.New("the remote end returned no handle for the new window")
+	}
+	return reply.Value.Handle, nil
+}
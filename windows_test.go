@@ -0,0 +1,325 @@
+package selenium
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+func TestWatchWindows(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var mu sync.Mutex
+	handles := []string{"main"}
+	setHandles := func(h ...string) {
+		mu.Lock()
+		defer mu.Unlock()
+		handles = h
+	}
+	d.handle("GET", "/window/handles", func([]byte) (int, interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		return http.StatusOK, handles
+	})
+	wd := d.newRemote(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := wd.WatchWindows(ctx, WatchInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("wd.WatchWindows() returned error: %v", err)
+	}
+	next := func() WindowEvent {
+		t.Helper()
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatal("the events channel was closed")
+			}
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("no window event after 5s")
+		}
+		return WindowEvent{}
+	}
+
+	setHandles("main", "popup")
+	if e := next(); e.Type != WindowOpened || e.Handle != "popup" {
+		t.Errorf("got event %+v, want popup opened", e)
+	}
+	setHandles("popup")
+	if e := next(); e.Type != WindowClosed || e.Handle != "main" {
+		t.Errorf("got event %+v, want main closed", e)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestWatchWindowsSessionEnded(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	polls := 0
+	d.handle("GET", "/window/handles", func([]byte) (int, interface{}) {
+		polls++
+		if polls == 1 {
+			return http.StatusOK, []string{"main"}
+		}
+		return http.StatusNotFound, map[string]string{
+			"error":   "invalid session id",
+			"message": "session deleted",
+		}
+	})
+	wd := d.newRemote(t)
+
+	events, err := wd.WatchWindows(context.Background(), WatchInterval(time.Millisecond))
+	if err != nil {
+		t.Fatalf("wd.WatchWindows() returned error: %v", err)
+	}
+	select {
+	case e, ok := <-events:
+		if ok {
+			t.Errorf("got event %+v, want the channel closed", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the events channel was not closed after the session ended")
+	}
+}
+
+func TestWatchWindowsBiDi(t *testing.T) {
+	endpoint := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		var cmd map[string]interface{}
+		if err := websocket.JSON.Receive(conn, &cmd); err != nil {
+			return
+		}
+		if cmd["method"] != "session.subscribe" {
+			t.Errorf("got command %v, want session.subscribe", cmd["method"])
+		}
+		websocket.JSON.Send(conn, map[string]interface{}{"id": cmd["id"], "type": "success", "result": map[string]interface{}{}})
+		for _, e := range []struct {
+			method, context string
+			parent          interface{}
+		}{
+			{"browsingContext.contextCreated", "popup", nil},
+			{"browsingContext.contextCreated", "frame", "popup"},
+			{"browsingContext.contextDestroyed", "popup", nil},
+		} {
+			websocket.JSON.Send(conn, map[string]interface{}{
+				"type":   "event",
+				"method": e.method,
+				"params": map[string]interface{}{"context": e.context, "parent": e.parent},
+			})
+		}
+		// Wait for the watcher to close the connection.
+		websocket.JSON.Receive(conn, &cmd)
+	}))
+	defer endpoint.Close()
+
+	d := newFakeDriver(t, map[string]interface{}{
+		BiDiCapability: "ws" + strings.TrimPrefix(endpoint.URL, "http") + "/session/" + fakeSessionID,
+	})
+	wd := d.newRemote(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := wd.WatchWindows(ctx)
+	if err != nil {
+		t.Fatalf("wd.WatchWindows() returned error: %v", err)
+	}
+	var got []WindowEvent
+	for len(got) < 2 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got events %+v after 5s, want 2", got)
+		}
+	}
+	if got[0].Type != WindowOpened || got[0].Handle != "popup" || got[1].Type != WindowClosed || got[1].Handle != "popup" {
+		t.Errorf("got events %+v, want popup opened then closed", got)
+	}
+	if n := d.count("GET", "/window/handles"); n != 0 {
+		t.Errorf("wd.WatchWindows() polled the window handles %d times with BiDi, want 0", n)
+	}
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestSetTabActive(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var switched []string
+	var commands []string
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		return http.StatusOK, "main"
+	})
+	d.handle("POST", "/window", func(body []byte) (int, interface{}) {
+		var params struct{ Handle string }
+		json.Unmarshal(body, &params)
+		switched = append(switched, params.Handle)
+		return http.StatusOK, nil
+	})
+	d.handle("POST", "/goog/cdp/execute", func(body []byte) (int, interface{}) {
+		var params struct{ Cmd string }
+		json.Unmarshal(body, &params)
+		commands = append(commands, params.Cmd)
+		return http.StatusOK, map[string]interface{}{}
+	})
+	wd := d.newRemote(t)
+	wd.SetSupport(FeatureCDP, true)
+
+	if err := wd.SetTabActive("background"); err != nil {
+		t.Fatalf("wd.SetTabActive() returned error: %v", err)
+	}
+	if want := []string{"background", "main"}; !reflect.DeepEqual(switched, want) {
+		t.Errorf("wd.SetTabActive() switched to windows %q, want %q", switched, want)
+	}
+	if want := []string{"Emulation.setFocusEmulationEnabled", "Page.setWebLifecycleState"}; !reflect.DeepEqual(commands, want) {
+		t.Errorf("wd.SetTabActive() sent %q, want %q", commands, want)
+	}
+}
+
+func TestSetTabActiveUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, map[string]interface{}{"browserName": "firefox"}).newRemote(t)
+	if err := wd.SetTabActive("main"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.SetTabActive() returned error %v, want ErrUnsupported", err)
+	}
+}
+
+// fakeWindows serves windows with the given rectangles, the first one being
+// current, and returns the handles switched to. The windows missing from
+// rects are reported as closed.
+func fakeWindows(d *fakeDriver, handles []string, rects map[string]rect) *[]string {
+	current := handles[0]
+	var switched []string
+	noSuchWindow := map[string]string{"error": "no such window", "message": "window was closed"}
+	d.handle("GET", "/window/handles", func([]byte) (int, interface{}) {
+		return http.StatusOK, handles
+	})
+	d.handle("GET", "/window", func([]byte) (int, interface{}) {
+		return http.StatusOK, current
+	})
+	d.handle("POST", "/window", func(body []byte) (int, interface{}) {
+		var params struct{ Handle string }
+		json.Unmarshal(body, &params)
+		switched = append(switched, params.Handle)
+		current = params.Handle
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/window/rect", func([]byte) (int, interface{}) {
+		r, ok := rects[current]
+		if !ok {
+			return http.StatusNotFound, noSuchWindow
+		}
+		return http.StatusOK, r
+	})
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		if _, ok := rects[current]; !ok {
+			return http.StatusNotFound, noSuchWindow
+		}
+		return http.StatusOK, base64.StdEncoding.EncodeToString([]byte("png of " + current))
+	})
+	return &switched
+}
+
+func TestWindowRects(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	switched := fakeWindows(d, []string{"main", "popup", "closed"}, map[string]rect{
+		"main":  {Width: 1024, Height: 768},
+		"popup": {X: 100, Y: 50.4, Width: 400, Height: 300},
+	})
+	wd := d.newRemote(t)
+
+	got, err := wd.WindowRects()
+	if err != nil {
+		t.Fatalf("wd.WindowRects() returned error: %v", err)
+	}
+	want := map[string]Rect{
+		"main":  {Width: 1024, Height: 768},
+		"popup": {X: 100, Y: 50, Width: 400, Height: 300},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("wd.WindowRects() = %v, want %v", got, want)
+	}
+	if last := (*switched)[len(*switched)-1]; last != "main" {
+		t.Errorf("wd.WindowRects() left window %q current, want %q", last, "main")
+	}
+}
+
+func TestScreenshotWindow(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	switched := fakeWindows(d, []string{"main", "popup", "closed"}, map[string]rect{
+		"main":  {},
+		"popup": {},
+	})
+	wd := d.newRemote(t)
+
+	got, err := wd.ScreenshotWindow("popup")
+	if err != nil {
+		t.Fatalf("wd.ScreenshotWindow() returned error: %v", err)
+	}
+	if string(got) != "png of popup" {
+		t.Errorf("wd.ScreenshotWindow() = %q, want the screenshot of the popup", got)
+	}
+	if want := []string{"popup", "main"}; !reflect.DeepEqual(*switched, want) {
+		t.Errorf("wd.ScreenshotWindow() switched to windows %q, want %q", *switched, want)
+	}
+
+	*switched = nil
+	_, err = wd.ScreenshotWindow("closed")
+	if !isNoSuchWindowError(err) {
+		t.Errorf("wd.ScreenshotWindow() of a closed window returned error %v, want no such window", err)
+	}
+	if want := []string{"closed", "main"}; !reflect.DeepEqual(*switched, want) {
+		t.Errorf("wd.ScreenshotWindow() switched to windows %q, want %q", *switched, want)
+	}
+
+	all, err := wd.ScreenshotAllWindows()
+	if err != nil {
+		t.Fatalf("wd.ScreenshotAllWindows() returned error: %v", err)
+	}
+	if len(all) != 2 || string(all["main"]) != "png of main" || string(all["popup"]) != "png of popup" {
+		t.Errorf("wd.ScreenshotAllWindows() = %q, want the screenshots of main and popup", all)
+	}
+}
+
+func TestNewWindow(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	var body string
+	d.handle("POST", "/window/new", func(b []byte) (int, interface{}) {
+		body = string(b)
+		return http.StatusOK, map[string]string{"handle": "w2", "type": "tab"}
+	})
+	wd := d.newRemote(t)
+
+	handle, err := wd.NewWindow(WindowTypeTab)
+	if err != nil {
+		t.Fatalf("wd.NewWindow() returned error: %v", err)
+	}
+	if handle != "w2" {
+		t.Errorf("wd.NewWindow() = %q, want %q", handle, "w2")
+	}
+	if body != `{"type":"tab"}` {
+		t.Errorf("wd.NewWindow() sent %s, want the type", body)
+	}
+	if _, err := wd.NewWindow(""); err != nil || body != `{}` {
+		t.Errorf("wd.NewWindow(\"\") returned error %v and sent %s, want no type", err, body)
+	}
+}
+
+func TestNewWindowUnsupported(t *testing.T) {
+	wd := newFakeDriver(t, nil).newRemote(t)
+	if _, err := wd.NewWindow(WindowTypeWindow); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("wd.NewWindow() returned error %v, want ErrUnsupported", err)
+	}
+}
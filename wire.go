@@ -0,0 +1,148 @@
+package selenium
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// WireRecord is the size of the body of a request or of a response.
+type WireRecord struct {
+	// Command is the method and path of the command, relative to its
+	// session, as in SessionSummary.Commands, e.g. "GET /screenshot".
+	Command string `json:"command"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// WireTotals sums the sizes of the bodies of the commands of a type.
+type WireTotals struct {
+	Count         int   `json:"count"`
+	SentBytes     int64 `json:"sentBytes"`
+	ReceivedBytes int64 `json:"receivedBytes"`
+}
+
+// WireStats sums the sizes of the bodies of the requests sent to the remote
+// end and of its responses, to tell which commands make the protocol
+// heavy, e.g. screenshots or capabilities full of extensions.
+type WireStats struct {
+	SentBytes     int64 `json:"sentBytes"`
+	ReceivedBytes int64 `json:"receivedBytes"`
+	// LargestRequest and LargestResponse are the largest request and
+	// response sent so far.
+	LargestRequest  WireRecord `json:"largestRequest"`
+	LargestResponse WireRecord `json:"largestResponse"`
+	// ByCommand sums the sizes by type of command.
+	ByCommand map[string]WireTotals `json:"byCommand"`
+}
+
+// WarnLargeRequests calls fn, from the goroutine of the command once it
+// completes, for each request whose body is larger than limit bytes, e.g. to
+// learn that the capabilities are about to exceed the body limit of a proxy.
+// fn may send commands to the session.
+func WarnLargeRequests(limit int64, fn func(WireRecord)) SessionOption {
+	return func(o *sessionOptions) {
+		o.wireLimit = limit
+		o.wireWarn = fn
+	}
+}
+
+// wireCounter accumulates the WireStats of a session.
+type wireCounter struct {
+	limit int64
+	warn  func(WireRecord)
+
+	mu    sync.Mutex
+	stats WireStats
+}
+
+// wireKey is the context key of the wireTap of a request.
+type wireKey struct{}
+
+// wireTap accounts for the requests of a command in the counter of its
+// session.
+type wireTap struct {
+	counter *wireCounter
+	command string
+	// large are the requests to warn about, larger than the limit of the
+	// counter.
+	large []WireRecord
+}
+
+// withTap returns a context that makes sendRequest account for the requests
+// of command in c, and their tap, whose warnings are to be sent once the
+// command completes.
+func (c *wireCounter) withTap(ctx context.Context, command string) (context.Context, *wireTap) {
+	tap := &wireTap{counter: c, command: command}
+	return context.WithValue(ctx, wireKey{}, tap), tap
+}
+
+// add accounts for a request and its response.
+func (t *wireTap) add(sent, received int64) {
+	t.counter.add(t.command, sent, received)
+	if t.counter.warn != nil && sent > t.counter.limit {
+		t.large = append(t.large, WireRecord{Command: t.command, Bytes: sent})
+	}
+}
+
+// warn calls the WarnLargeRequests function of the counter with the large
+// requests. It must not be called while the commands of the session are
+// serialized, as the function may send commands.
+func (t *wireTap) warn() {
+	for _, r := range t.large {
+		t.counter.warn(r)
+	}
+	t.large = nil
+}
+
+// add accounts for a request of command and its response.
+func (c *wireCounter) add(command string, sent, received int64) {
+	c.mu.Lock()
+	s := &c.stats
+	s.SentBytes += sent
+	s.ReceivedBytes += received
+	if sent > s.LargestRequest.Bytes {
+		s.LargestRequest = WireRecord{Command: command, Bytes: sent}
+	}
+	if received > s.LargestResponse.Bytes {
+		s.LargestResponse = WireRecord{Command: command, Bytes: received}
+	}
+	if s.ByCommand == nil {
+		s.ByCommand = make(map[string]WireTotals)
+	}
+	t := s.ByCommand[command]
+	t.Count++
+	t.SentBytes += sent
+	t.ReceivedBytes += received
+	s.ByCommand[command] = t
+	c.mu.Unlock()
+}
+
+// snapshot returns a copy of the stats.
+func (c *wireCounter) snapshot() WireStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s := c.stats
+	s.ByCommand = make(map[string]WireTotals, len(c.stats.ByCommand))
+	for k, v := range c.stats.ByCommand {
+		s.ByCommand[k] = v
+	}
+	return s
+}
+
+// countingReader counts the bytes read through it, so that the responses
+// are measured as they are read rather than buffered again.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// WireStats returns the sizes of the requests and responses of the session.
+func (wd *remoteWD) WireStats() WireStats {
+	return wd.wire.snapshot()
+}
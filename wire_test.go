@@ -0,0 +1,111 @@
+package selenium
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWireStats(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	png := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("x", 3000)))
+	d.handle("GET", "/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, png
+	})
+	d.handle("GET", "/element/e1/screenshot", func([]byte) (int, interface{}) {
+		return http.StatusOK, png
+	})
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	var warned []WireRecord
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WarnLargeRequests(1000, func(r WireRecord) {
+		warned = append(warned, r)
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	longURL := "http://example.com/?q=" + strings.Repeat("a", 2000)
+	if err := wd.Get(longURL); err != nil {
+		t.Fatalf("wd.Get() returned error: %v", err)
+	}
+	if _, err := wd.Screenshot(); err != nil {
+		t.Fatalf("wd.Screenshot() returned error: %v", err)
+	}
+	elem := &remoteWE{parent: wd.(*remoteWD), id: "e1"}
+	if _, err := elem.Screenshot(false); err != nil {
+		t.Fatalf("elem.Screenshot() returned error: %v", err)
+	}
+
+	s := wd.WireStats()
+	// The reply is {"value":"..."} and a newline.
+	screenshot := int64(len(png) + len(`{"value":""}`) + 1)
+	if got := s.ByCommand["GET /screenshot"]; got.Count != 1 || got.SentBytes != 0 || got.ReceivedBytes != screenshot {
+		t.Errorf("the stats of GET /screenshot are %+v, want one command that received %d bytes", got, screenshot)
+	}
+	if got := s.ByCommand["GET /element/:id/screenshot"]; got.ReceivedBytes != screenshot {
+		t.Errorf("the stats of the element screenshot are %+v, want %d bytes received", got, screenshot)
+	}
+	if s.LargestResponse.Command != "GET /screenshot" || s.LargestResponse.Bytes != screenshot {
+		t.Errorf("the largest response is %+v, want the screenshot", s.LargestResponse)
+	}
+	if s.LargestRequest.Command != "POST /url" || s.LargestRequest.Bytes <= 2000 {
+		t.Errorf("the largest request is %+v, want the navigation to the long URL", s.LargestRequest)
+	}
+	var sent, received int64
+	for _, c := range s.ByCommand {
+		sent += c.SentBytes
+		received += c.ReceivedBytes
+	}
+	if sent != s.SentBytes || received != s.ReceivedBytes {
+		t.Errorf("the totals are %d and %d bytes, want the sums of the commands, %d and %d", s.SentBytes, s.ReceivedBytes, sent, received)
+	}
+	if len(warned) != 1 || warned[0].Command != "POST /url" {
+		t.Errorf("WarnLargeRequests() reported %+v, want the navigation to the long URL", warned)
+	}
+
+	// The stats returned are a copy.
+	s.ByCommand["GET /screenshot"] = WireTotals{}
+	if wd.WireStats().ByCommand["GET /screenshot"].Count != 1 {
+		t.Error("changing the stats returned by wd.WireStats() changed those of the session")
+	}
+}
+
+func TestWarnLargeRequestsSendsCommand(t *testing.T) {
+	d := newFakeDriver(t, nil)
+	d.handle("POST", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, nil
+	})
+	d.handle("GET", "/url", func([]byte) (int, interface{}) {
+		return http.StatusOK, "about:blank"
+	})
+	var wd WebDriver
+	var current string
+	wd, err := NewRemoteContext(context.Background(), nil, d.URL, WarnLargeRequests(1000, func(r WireRecord) {
+		// The session is not locked by the command that is reported.
+		current, _ = wd.CurrentURL()
+	}))
+	if err != nil {
+		t.Fatalf("NewRemoteContext() returned error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- wd.Get("http://example.com/?q=" + strings.Repeat("a", 2000))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("wd.Get() returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("wd.Get() did not return: the WarnLargeRequests function deadlocked")
+	}
+	if current != "about:blank" {
+		t.Errorf("the WarnLargeRequests function got the URL %q, want about:blank", current)
+	}
+}